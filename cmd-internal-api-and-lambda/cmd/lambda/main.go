@@ -1,34 +1,76 @@
 package main
 
 import (
+	"context"
 	"fmt"
 	"log/slog"
 	"net/http"
+	"os"
+	"strings"
+	"time"
 
 	"github.com/aws/aws-lambda-go/lambda"
+	"github.com/aws/aws-lambda-go/lambdacontext"
 	"github.com/awslabs/aws-lambda-go-api-proxy/httpadapter"
 	"github.com/jha-captech/user-microservice/internal/config"
+	"github.com/jha-captech/user-microservice/internal/cursor"
 	"github.com/jha-captech/user-microservice/internal/database"
+	"github.com/jha-captech/user-microservice/internal/logging"
+	"github.com/jha-captech/user-microservice/internal/metrics"
+	"github.com/jha-captech/user-microservice/internal/middleware"
+	"github.com/jha-captech/user-microservice/internal/policy"
 	"github.com/jha-captech/user-microservice/internal/server"
+	"github.com/jha-captech/user-microservice/internal/tracing"
 	"github.com/jha-captech/user-microservice/internal/user"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
 )
 
 type configuration struct {
 	Env      string `env:"ENV"`
 	Database struct {
-		Name            string `env:"DATABASE_NAME,required"`
-		User            string `env:"DATABASE_USER,required"`
-		Password        string `env:"DATABASE_PASSWORD,required"`
-		Host            string `env:"DATABASE_HOST,required"`
-		Port            string `env:"DATABASE_PORT,required"`
-		ConnectionRetry int    `env:"DATABASE_CONNECTION_RETRY,required"`
+		Name             string `env:"DATABASE_NAME,required"`
+		User             string `env:"DATABASE_USER,required"`
+		Password         string `env:"DATABASE_PASSWORD,required"`
+		Host             string `env:"DATABASE_HOST,required"`
+		Port             string `env:"DATABASE_PORT,required"`
+		ConnectionRetry  int    `env:"DATABASE_CONNECTION_RETRY,required"`
+		MigrateOnStartup bool   `env:"DATABASE_MIGRATE_ON_STARTUP"`
+	}
+	Cursor struct {
+		Secret string        `env:"CURSOR_SECRET"`
+		TTL    time.Duration `env:"CURSOR_TTL"`
+	}
+	Auth struct {
+		Disabled   bool   `env:"AUTH_DISABLED"`
+		PolicyFile string `env:"AUTH_POLICY_FILE"`
+	}
+	Tracing struct {
+		Exporter    string  `env:"TRACING_EXPORTER"`
+		ServiceName string  `env:"TRACING_SERVICE_NAME"`
+		SampleRatio float64 `env:"TRACING_SAMPLE_RATIO"`
+	}
+	Metrics struct {
+		Namespace string `env:"METRICS_NAMESPACE"`
+	}
+	Log struct {
+		RedactPII    bool   `env:"LOG_REDACT_PII"`
+		RedactMode   string `env:"LOG_REDACT_MODE"`
+		RedactSecret string `env:"LOG_REDACT_SECRET"`
+		RedactKeys   string `env:"LOG_REDACT_KEYS"`
 	}
 }
 
 func main() {
 	cfg := config.MustNewConfiguration[configuration]()
 
-	logger := slog.Default()
+	logger := mustNewLogger(cfg)
+
+	provider := mustNewTracerProvider(cfg)
+
+	migrateOption := database.WithSchemaCheck()
+	if cfg.Database.MigrateOnStartup {
+		migrateOption = database.WithMigrations()
+	}
 
 	db := database.MustNewDatabase(
 		fmt.Sprintf(
@@ -41,21 +83,118 @@ func main() {
 		),
 		logger,
 		cfg.Database.ConnectionRetry,
+		database.WithTracing(provider),
+		migrateOption,
 	)
 
 	mux := http.NewServeMux()
 
-	us := user.NewService(db)
+	recorder := metrics.NewEMF(os.Stdout, metricsNamespace(cfg), lambdacontext.FunctionName, db.Session)
+
+	if cfg.Cursor.Secret == "" {
+		logger.Warn("CURSOR_SECRET is not set, cursors are only accepted by the instance that issued them")
+	}
+	us := user.NewService(
+		db,
+		cursor.NewCodec(
+			[]byte(cfg.Cursor.Secret),
+			cursorTTL(cfg),
+		),
+		user.WithChangeCounter(recorder),
+	)
 	h := server.NewHandler(logger, us)
-	server.RegisterRoutes(mux, h, server.WithEnableHealthCheck(false))
+	server.RegisterRoutes(mux, h)
+
+	var handler http.Handler = mux
+	if cfg.Auth.Disabled {
+		logger.Warn("AUTH_DISABLED is set, requests are not authenticated")
+	} else {
+		rules, err := policy.New(cfg.Auth.PolicyFile)
+		if err != nil {
+			panic(fmt.Sprintf("Error loading AUTH_POLICY_FILE: %v", err))
+		}
+		handler = middleware.CreateStack(
+			middleware.AuthorizerMiddleware(logger),
+			middleware.AuthorizeMiddleware(logger, us, rules),
+		)(mux)
+	}
+
+	handler = middleware.RequestIDMiddleware(logger, mux)(handler)
+	handler = middleware.MetricsMiddleware(recorder, mux)(handler)
+	handler = middleware.TracingMiddleware(provider, mux)(handler)
 
 	lambda.StartWithOptions(
-		httpadapter.New(mux).ProxyWithContext,
+		tracing.WrapAPIGateway(provider, httpadapter.New(handler).ProxyWithContext),
 		lambda.WithEnableSIGTERM(func() {
 			logger.Info("function container shutting down")
 			if err := db.Session.Close(); err != nil {
 				logger.Error("error closing database session", "err", err)
 			}
+			if err := provider.Shutdown(context.Background()); err != nil {
+				logger.Error("error shutting down tracer provider", "err", err)
+			}
 		}),
 	)
 }
+
+// mustNewLogger returns the default logger, which redacts personal data in LOG_REDACT_MODE,
+// `mask` if it is not set, when LOG_REDACT_PII is set. The attributes in LOG_REDACT_KEYS are
+// redacted, or `first_name` and `last_name` if it is not set. The hash mode is keyed by
+// LOG_REDACT_SECRET. It panics if the mode is unknown or the hash mode has no secret.
+func mustNewLogger(cfg configuration) *slog.Logger {
+	logger := slog.Default()
+	if !cfg.Log.RedactPII {
+		return logger
+	}
+
+	mode := cfg.Log.RedactMode
+	if mode == "" {
+		mode = logging.RedactMask
+	}
+	keys := []string{"first_name", "last_name"}
+	if cfg.Log.RedactKeys != "" {
+		keys = strings.Split(cfg.Log.RedactKeys, ",")
+	}
+
+	handler, err := logging.NewRedactHandler(logger.Handler(), mode, []byte(cfg.Log.RedactSecret), keys)
+	if err != nil {
+		panic(fmt.Sprintf("Error creating log redaction: %v", err))
+	}
+	return slog.New(handler)
+}
+
+// cursorTTL returns the CURSOR_TTL of cfg, or 24 hours if it is not set.
+func cursorTTL(cfg configuration) time.Duration {
+	if cfg.Cursor.TTL == 0 {
+		return 24 * time.Hour
+	}
+	return cfg.Cursor.TTL
+}
+
+// metricsNamespace returns the METRICS_NAMESPACE of cfg, or `UserMicroservice` if it is not set.
+func metricsNamespace(cfg configuration) string {
+	if cfg.Metrics.Namespace == "" {
+		return "UserMicroservice"
+	}
+	return cfg.Metrics.Namespace
+}
+
+// mustNewTracerProvider returns the tracer provider configured by cfg, which sends spans to the
+// TRACING_EXPORTER. It panics if the exporter is unknown or can not be created.
+func mustNewTracerProvider(cfg configuration) *sdktrace.TracerProvider {
+	serviceName := cfg.Tracing.ServiceName
+	if serviceName == "" {
+		serviceName = "user-microservice"
+	}
+
+	var opts []tracing.Option
+	if cfg.Tracing.SampleRatio > 0 {
+		opts = append(opts, tracing.WithSampleRatio(cfg.Tracing.SampleRatio))
+	}
+
+	provider, err := tracing.New(context.Background(), cfg.Tracing.Exporter, serviceName, opts...)
+	if err != nil {
+		panic(fmt.Sprintf("Error creating tracer provider: %v", err))
+	}
+	return provider
+}
@@ -0,0 +1,40 @@
+package auth
+
+import (
+	"errors"
+	"fmt"
+)
+
+// ErrNoAuthorizerClaims is returned by FromAuthorizer when the API Gateway authorizer context has
+// no subject, which means the route is not protected by an authorizer.
+var ErrNoAuthorizerClaims = errors.New("no authorizer claims")
+
+// FromAuthorizer returns the claims in the authorizer context API Gateway passes to a Lambda
+// integration, for a token the authorizer has already verified. Cognito and JWT authorizers pass the
+// claims in a claims map, while Lambda authorizers pass the context they returned, so the claims are
+// read from the context itself if there is no claims map. The principalId of a Lambda authorizer is
+// used as the subject if the context has no sub claim.
+func FromAuthorizer(authorizer map[string]any) (Claims, error) {
+	raw, ok := authorizer["claims"].(map[string]any)
+	if !ok {
+		raw = make(map[string]any, len(authorizer))
+		for name, value := range authorizer {
+			raw[name] = value
+		}
+		if _, ok = raw["sub"]; !ok {
+			if principal, ok := raw["principalId"].(string); ok {
+				raw["sub"] = principal
+			}
+		}
+	}
+
+	claims, err := newClaims(raw, false)
+	if err != nil {
+		return Claims{}, fmt.Errorf("in auth.FromAuthorizer: %w", err)
+	}
+	if claims.Subject == "" {
+		return Claims{}, fmt.Errorf("in auth.FromAuthorizer: %w", ErrNoAuthorizerClaims)
+	}
+
+	return claims, nil
+}
@@ -8,6 +8,7 @@ import (
 	"reflect"
 	"strconv"
 	"strings"
+	"time"
 
 	"github.com/joho/godotenv"
 )
@@ -102,6 +103,23 @@ func ParseStructFromEnv(obj any) error {
 				}
 				field.SetBool(value)
 
+			case reflect.Int64:
+				if field.Type() != reflect.TypeFor[time.Duration]() {
+					continue
+				}
+				value, err := time.ParseDuration(envValue)
+				if err != nil && required {
+					return newEnvVarParsingErr(key, err)
+				}
+				field.SetInt(int64(value))
+
+			case reflect.Float64:
+				value, err := strconv.ParseFloat(envValue, 64)
+				if err != nil && required {
+					return newEnvVarParsingErr(key, err)
+				}
+				field.SetFloat(value)
+
 			default:
 				continue
 			}
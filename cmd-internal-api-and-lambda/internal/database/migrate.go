@@ -0,0 +1,411 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"embed"
+	"errors"
+	"fmt"
+	"io/fs"
+	"log/slog"
+	"path"
+	"regexp"
+	"slices"
+	"strconv"
+	"time"
+)
+
+//go:embed migrations/*.sql
+var migrationFS embed.FS
+
+// migrationLockID is the key of the Postgres advisory lock held while migrations are read or
+// applied, so that concurrent instances (e.g. Lambda cold starts) can not race each other.
+const migrationLockID int64 = 7_261_045_318
+
+var (
+	// ErrSchemaBehind is returned when the database has migrations that have not been applied.
+	ErrSchemaBehind = errors.New("database schema is behind")
+
+	// ErrUnknownMigration is returned when a migration version is not one of the known migrations.
+	ErrUnknownMigration = errors.New("unknown migration")
+)
+
+// migrationFileName matches migration file names such as 0001_create_users_table.up.sql.
+var migrationFileName = regexp.MustCompile(`^(\d+)_(\w+)\.(up|down)\.sql$`)
+
+// Migration is a single versioned schema change.
+type Migration struct {
+	Version int
+	Name    string
+	Up      string
+	Down    string
+}
+
+// MigrationStatus is the state of a Migration in the database.
+type MigrationStatus struct {
+	Migration
+	Applied   bool
+	AppliedAt time.Time
+}
+
+// Migrator applies and reverts the SQL migrations embedded in the migrations directory. Every
+// operation holds a Postgres advisory lock for its duration.
+type Migrator struct {
+	db         *sql.DB
+	logger     *slog.Logger
+	migrations []Migration
+}
+
+// NewMigrator returns a Migrator for the migrations embedded in the migrations directory.
+func NewMigrator(db *sql.DB, logger *slog.Logger) (*Migrator, error) {
+	migrations, err := loadMigrations(migrationFS, "migrations")
+	if err != nil {
+		return nil, fmt.Errorf("in NewMigrator: %w", err)
+	}
+
+	return newMigrator(db, logger, migrations), nil
+}
+
+// newMigrator returns a Migrator for migrations, which must be sorted by version.
+func newMigrator(db *sql.DB, logger *slog.Logger, migrations []Migration) *Migrator {
+	return &Migrator{
+		db:         db,
+		logger:     logger,
+		migrations: migrations,
+	}
+}
+
+// Up applies all pending migrations in order of version. Each migration is applied in its own
+// transaction.
+func (m *Migrator) Up(ctx context.Context) error {
+	err := m.withLock(ctx, func(conn *sql.Conn) error {
+		applied, err := appliedMigrations(ctx, conn)
+		if err != nil {
+			return err
+		}
+
+		for _, migration := range m.migrations {
+			if _, ok := applied[migration.Version]; ok {
+				continue
+			}
+
+			m.logger.Info("Applying migration", "version", migration.Version, "name", migration.Name)
+			err = inTx(ctx, conn, func(tx *sql.Tx) error {
+				if _, err := tx.ExecContext(ctx, migration.Up); err != nil {
+					return err
+				}
+				_, err := tx.ExecContext(
+					ctx,
+					`INSERT INTO schema_migrations (version, name) VALUES ($1, $2)`,
+					migration.Version,
+					migration.Name,
+				)
+				return err
+			})
+			if err != nil {
+				return fmt.Errorf("apply migration %d_%s: %w", migration.Version, migration.Name, err)
+			}
+		}
+
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("in Migrator.Up: %w", err)
+	}
+
+	return nil
+}
+
+// Down reverts the last steps applied migrations in reverse order of version. Each migration is
+// reverted in its own transaction.
+func (m *Migrator) Down(ctx context.Context, steps int) error {
+	err := m.withLock(ctx, func(conn *sql.Conn) error {
+		applied, err := appliedMigrations(ctx, conn)
+		if err != nil {
+			return err
+		}
+
+		versions := make([]int, 0, len(applied))
+		for version := range applied {
+			versions = append(versions, version)
+		}
+		slices.Sort(versions)
+		slices.Reverse(versions)
+
+		for _, version := range versions[:min(steps, len(versions))] {
+			migration, ok := m.find(version)
+			if !ok {
+				return fmt.Errorf("%w: version %d is applied but has no migration", ErrUnknownMigration, version)
+			}
+
+			m.logger.Info("Reverting migration", "version", migration.Version, "name", migration.Name)
+			err = inTx(ctx, conn, func(tx *sql.Tx) error {
+				if _, err := tx.ExecContext(ctx, migration.Down); err != nil {
+					return err
+				}
+				_, err := tx.ExecContext(ctx, `DELETE FROM schema_migrations WHERE version = $1`, version)
+				return err
+			})
+			if err != nil {
+				return fmt.Errorf("revert migration %d_%s: %w", migration.Version, migration.Name, err)
+			}
+		}
+
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("in Migrator.Down: %w", err)
+	}
+
+	return nil
+}
+
+// Status returns the status of every known migration in order of version.
+func (m *Migrator) Status(ctx context.Context) ([]MigrationStatus, error) {
+	var statuses []MigrationStatus
+	err := m.withLock(ctx, func(conn *sql.Conn) error {
+		applied, err := appliedMigrations(ctx, conn)
+		if err != nil {
+			return err
+		}
+
+		statuses = make([]MigrationStatus, 0, len(m.migrations))
+		for _, migration := range m.migrations {
+			appliedAt, ok := applied[migration.Version]
+			statuses = append(statuses, MigrationStatus{
+				Migration: migration,
+				Applied:   ok,
+				AppliedAt: appliedAt,
+			})
+		}
+
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("in Migrator.Status: %w", err)
+	}
+
+	return statuses, nil
+}
+
+// Check returns ErrSchemaBehind if any known migration has not been applied.
+func (m *Migrator) Check(ctx context.Context) error {
+	statuses, err := m.Status(ctx)
+	if err != nil {
+		return fmt.Errorf("in Migrator.Check: %w", err)
+	}
+
+	var pending []int
+	for _, status := range statuses {
+		if !status.Applied {
+			pending = append(pending, status.Version)
+		}
+	}
+	if len(pending) > 0 {
+		return fmt.Errorf("in Migrator.Check: %w: pending migrations %v", ErrSchemaBehind, pending)
+	}
+
+	return nil
+}
+
+// Version returns the latest migration version applied to the database and the latest known
+// version. Unlike Check it does not take the migration lock, so it is cheap enough for a readiness
+// probe, and while migrations are being applied it reports the version reached so far.
+func (m *Migrator) Version(ctx context.Context) (applied int, latest int, err error) {
+	if len(m.migrations) > 0 {
+		latest = m.migrations[len(m.migrations)-1].Version
+	}
+
+	err = m.db.QueryRowContext(ctx, `SELECT COALESCE(MAX(version), 0) FROM schema_migrations`).Scan(&applied)
+	if err != nil {
+		return 0, latest, fmt.Errorf("in Migrator.Version: %w", err)
+	}
+
+	return applied, latest, nil
+}
+
+// Force records the migrations up to and including version as applied and all later migrations
+// as not applied, without running any SQL. A version of 0 marks every migration as not applied.
+// Force is used to baseline a database whose schema was created outside of the migrations.
+func (m *Migrator) Force(ctx context.Context, version int) error {
+	if _, ok := m.find(version); !ok && version != 0 {
+		return fmt.Errorf("in Migrator.Force: %w: version %d", ErrUnknownMigration, version)
+	}
+
+	err := m.withLock(ctx, func(conn *sql.Conn) error {
+		applied, err := appliedMigrations(ctx, conn)
+		if err != nil {
+			return err
+		}
+
+		return inTx(ctx, conn, func(tx *sql.Tx) error {
+			if _, err := tx.ExecContext(ctx, `DELETE FROM schema_migrations WHERE version > $1`, version); err != nil {
+				return err
+			}
+			for _, migration := range m.migrations {
+				if _, ok := applied[migration.Version]; ok || migration.Version > version {
+					continue
+				}
+				_, err := tx.ExecContext(
+					ctx,
+					`INSERT INTO schema_migrations (version, name) VALUES ($1, $2)`,
+					migration.Version,
+					migration.Name,
+				)
+				if err != nil {
+					return err
+				}
+			}
+			return nil
+		})
+	})
+	if err != nil {
+		return fmt.Errorf("in Migrator.Force: %w", err)
+	}
+
+	m.logger.Info("Forced migration version", "version", version)
+	return nil
+}
+
+// find returns the migration with the given version.
+func (m *Migrator) find(version int) (Migration, bool) {
+	for _, migration := range m.migrations {
+		if migration.Version == version {
+			return migration, true
+		}
+	}
+	return Migration{}, false
+}
+
+// withLock runs fn on a single connection that holds the migration advisory lock and on which the
+// schema_migrations table exists.
+func (m *Migrator) withLock(ctx context.Context, fn func(conn *sql.Conn) error) (err error) {
+	conn, err := m.db.Conn(ctx)
+	if err != nil {
+		return fmt.Errorf("get connection: %w", err)
+	}
+	defer conn.Close()
+
+	if _, err = conn.ExecContext(ctx, `SELECT pg_advisory_lock($1)`, migrationLockID); err != nil {
+		return fmt.Errorf("acquire migration lock: %w", err)
+	}
+	defer func() {
+		_, unlockErr := conn.ExecContext(context.Background(), `SELECT pg_advisory_unlock($1)`, migrationLockID)
+		if unlockErr != nil {
+			err = errors.Join(err, fmt.Errorf("release migration lock: %w", unlockErr))
+		}
+	}()
+
+	_, err = conn.ExecContext(ctx, `
+		CREATE TABLE IF NOT EXISTS schema_migrations
+		(
+			version    INTEGER PRIMARY KEY,
+			name       TEXT        NOT NULL,
+			applied_at TIMESTAMPTZ NOT NULL DEFAULT now()
+		)
+	`)
+	if err != nil {
+		return fmt.Errorf("create schema_migrations: %w", err)
+	}
+
+	return fn(conn)
+}
+
+// appliedMigrations returns the time each applied migration was applied, keyed by version.
+func appliedMigrations(ctx context.Context, conn *sql.Conn) (map[int]time.Time, error) {
+	rows, err := conn.QueryContext(ctx, `SELECT version, applied_at FROM schema_migrations`)
+	if err != nil {
+		return nil, fmt.Errorf("query schema_migrations: %w", err)
+	}
+	defer rows.Close()
+
+	applied := make(map[int]time.Time)
+	for rows.Next() {
+		var (
+			version   int
+			appliedAt time.Time
+		)
+		if err = rows.Scan(&version, &appliedAt); err != nil {
+			return nil, fmt.Errorf("scan schema_migrations: %w", err)
+		}
+		applied[version] = appliedAt
+	}
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("scan schema_migrations: %w", err)
+	}
+
+	return applied, nil
+}
+
+// inTx runs fn in a transaction on conn, committing if fn succeeds and rolling back otherwise.
+func inTx(ctx context.Context, conn *sql.Conn, fn func(tx *sql.Tx) error) error {
+	tx, err := conn.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("begin transaction: %w", err)
+	}
+
+	if err = fn(tx); err != nil {
+		_ = tx.Rollback()
+		return err
+	}
+
+	if err = tx.Commit(); err != nil {
+		return fmt.Errorf("commit transaction: %w", err)
+	}
+
+	return nil
+}
+
+// loadMigrations reads the migrations in dir of fsys. Every version must have both an up and a
+// down file. The migrations are returned sorted by version.
+func loadMigrations(fsys fs.FS, dir string) ([]Migration, error) {
+	entries, err := fs.ReadDir(fsys, dir)
+	if err != nil {
+		return nil, fmt.Errorf("read migrations: %w", err)
+	}
+
+	byVersion := make(map[int]*Migration)
+	for _, entry := range entries {
+		match := migrationFileName.FindStringSubmatch(entry.Name())
+		if entry.IsDir() || match == nil {
+			return nil, fmt.Errorf("invalid migration file name %q", entry.Name())
+		}
+
+		version, err := strconv.Atoi(match[1])
+		if err != nil || version < 1 {
+			return nil, fmt.Errorf("invalid migration version in %q", entry.Name())
+		}
+
+		contents, err := fs.ReadFile(fsys, path.Join(dir, entry.Name()))
+		if err != nil {
+			return nil, fmt.Errorf("read migration %q: %w", entry.Name(), err)
+		}
+
+		migration, ok := byVersion[version]
+		if !ok {
+			migration = &Migration{Version: version, Name: match[2]}
+			byVersion[version] = migration
+		}
+		if migration.Name != match[2] {
+			return nil, fmt.Errorf("migration version %d has more than one name", version)
+		}
+
+		switch match[3] {
+		case "up":
+			migration.Up = string(contents)
+		case "down":
+			migration.Down = string(contents)
+		}
+	}
+
+	migrations := make([]Migration, 0, len(byVersion))
+	for _, migration := range byVersion {
+		if migration.Up == "" || migration.Down == "" {
+			return nil, fmt.Errorf("migration %d_%s must have an up and a down file", migration.Version, migration.Name)
+		}
+		migrations = append(migrations, *migration)
+	}
+	slices.SortFunc(migrations, func(a, b Migration) int { return a.Version - b.Version })
+
+	return migrations, nil
+}
@@ -0,0 +1,34 @@
+package metrics
+
+import (
+	"net/http"
+	"time"
+)
+
+// UnmatchedRoute is the route of requests that matched no route, so that the paths of unknown
+// routes do not each get their own series.
+const UnmatchedRoute = "unmatched"
+
+// Recorder records the metrics of the service: the rate, errors and duration of requests by route,
+// the requests in flight, and the users that were created, updated and deleted. Prometheus exposes
+// them to be scraped and EMF writes them to stdout for CloudWatch.
+type Recorder interface {
+	// RequestStarted records that a request is being served.
+	RequestStarted()
+	// RequestFinished records that a request started with RequestStarted was served with status
+	// after duration. route is the pattern the request was routed to, such as `/api/user/{ID}`,
+	// never its path.
+	RequestFinished(method string, route string, status int, duration time.Duration)
+	// UsersCreated counts users that were created.
+	UsersCreated(count int)
+	// UsersUpdated counts users that were updated.
+	UsersUpdated(count int)
+	// UsersDeleted counts users that were deleted.
+	UsersDeleted(count int)
+}
+
+// isError reports whether a request that was served with status failed. Client errors are the
+// caller's and are not counted.
+func isError(status int) bool {
+	return status >= http.StatusInternalServerError
+}
@@ -0,0 +1,35 @@
+package middleware
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/jha-captech/user-microservice/internal/metrics"
+)
+
+// MetricsMiddleware records every request with recorder, by its method and the pattern of mux that
+// it matches. Requests that match no pattern are recorded as metrics.UnmatchedRoute, so that the
+// paths of unknown routes do not each get their own series.
+func MetricsMiddleware(recorder metrics.Recorder, mux *http.ServeMux) Middleware {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			start := time.Now()
+			recorder.RequestStarted()
+
+			route := muxRoute(mux, r)
+			if route == "" {
+				route = metrics.UnmatchedRoute
+			}
+
+			wrapped := &wrappedWriter{
+				ResponseWriter: w,
+				statusCode:     http.StatusOK,
+			}
+			defer func() {
+				recorder.RequestFinished(r.Method, route, wrapped.statusCode, time.Since(start))
+			}()
+
+			next.ServeHTTP(wrapped, r)
+		})
+	}
+}
@@ -1,9 +1,10 @@
 package middleware
 
 import (
-	"encoding/json"
 	"log/slog"
 	"net/http"
+
+	"github.com/jha-captech/user-microservice/internal/problem"
 )
 
 func RecoveryMiddleware(logger *slog.Logger) Middleware {
@@ -12,17 +13,12 @@ func RecoveryMiddleware(logger *slog.Logger) Middleware {
 			defer func() {
 				err := recover()
 				if err != nil {
-					logger.Error("panic recovered", "panic", err)
-
-					jsonBody, _ := json.Marshal(
-						map[string]string{
-							"error": "There was an internal server error",
-						},
-					)
+					requestLogger(r, logger).Error("panic recovered", "panic", err)
 
-					w.Header().Set("Content-Type", "application/json")
-					w.WriteHeader(http.StatusInternalServerError)
-					w.Write(jsonBody)
+					_ = problem.Write(w, r, problem.New(
+						http.StatusInternalServerError,
+						"There was an internal server error",
+					))
 				}
 			}()
 
@@ -2,7 +2,9 @@ package server
 
 import (
 	"log/slog"
+	"net/http"
 
+	"github.com/jha-captech/user-microservice/internal/logging"
 	"github.com/jha-captech/user-microservice/internal/user"
 )
 
@@ -19,3 +21,13 @@ func NewHandler(logger *slog.Logger, us user.Service) Handler {
 		userService: us,
 	}
 }
+
+// requestLogger returns the logger that middleware.RequestIDMiddleware put into the context of r,
+// which logs the request ID, trace ID, route and user of r with every line, or the logger of h if r
+// has none.
+func (h *Handler) requestLogger(r *http.Request) *slog.Logger {
+	if logger, ok := logging.FromContext(r.Context()); ok {
+		return logger
+	}
+	return h.logger
+}
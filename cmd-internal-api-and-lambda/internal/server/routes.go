@@ -2,36 +2,40 @@ package server
 
 import (
 	"net/http"
+
+	"github.com/jha-captech/user-microservice/internal/health"
+	"github.com/jha-captech/user-microservice/internal/policy"
 )
 
 type Options func(*routesOptions)
 
 type routesOptions struct {
-	useHealthCheck bool
+	healthChecker *health.Checker
 }
 
-func WithEnableHealthCheck(enableHealthCheck bool) Options {
+// WithHealthChecker registers the liveness probe and the readiness probe, which reports the
+// components checked by checker. If this function is not called, the probes are not registered.
+func WithHealthChecker(checker *health.Checker) Options {
 	return func(options *routesOptions) {
-		options.useHealthCheck = enableHealthCheck
+		options.healthChecker = checker
 	}
 }
 
 func RegisterRoutes(mux *http.ServeMux, h Handler, options ...Options) {
-	opts := routesOptions{
-		useHealthCheck: true,
-	}
+	opts := routesOptions{}
 
 	for _, fn := range options {
 		fn(&opts)
 	}
 
-	if opts.useHealthCheck {
-		mux.HandleFunc("GET /api/health-check", h.handleHealthCheck())
+	if opts.healthChecker != nil {
+		mux.HandleFunc("GET /api/health/live", h.handleLiveness())
+		mux.HandleFunc("GET /api/health/ready", h.handleReadiness(opts.healthChecker))
 	}
 
-	mux.HandleFunc("GET /api/user", h.handleListUsers())
-	mux.HandleFunc("GET /api/user/{id}", h.handleFetchUser())
-	mux.HandleFunc("PUT /api/user/{id}", h.handleUpdateUser())
-	mux.HandleFunc("POST /api/user", h.handleCreateUser())
-	mux.HandleFunc("DELETE /api/user/{id}", h.handleDeleteUser())
+	mux.HandleFunc("GET /api/user", h.permit(policy.ActionList, h.handleListUsers()))
+	mux.HandleFunc("GET /api/user/{id}", h.permit(policy.ActionRead, h.handleFetchUser()))
+	mux.HandleFunc("PUT /api/user/{id}", h.permit(policy.ActionUpdate, h.handleUpdateUser()))
+	mux.HandleFunc("POST /api/user", h.permit(policy.ActionCreate, h.handleCreateUser()))
+	mux.HandleFunc("DELETE /api/user/{id}", h.permit(policy.ActionDelete, h.handleDeleteUser()))
 }
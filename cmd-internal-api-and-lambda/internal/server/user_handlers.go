@@ -1,19 +1,25 @@
 package server
 
 import (
+	"errors"
+	"fmt"
 	"net/http"
 	"strconv"
 
 	"github.com/jha-captech/user-microservice/internal/models"
+	"github.com/jha-captech/user-microservice/internal/policy"
+	"github.com/jha-captech/user-microservice/internal/problem"
+	"github.com/jha-captech/user-microservice/internal/user"
 )
 
 type responseOneUser struct {
 	User models.User `json:"user"`
 }
 
-type responseAllUsers struct {
-	Users []models.User `json:"users"`
-}
+const (
+	defaultListLimit = 20
+	maxListLimit     = 100
+)
 
 type responseMessage struct {
 	Message string `json:"message"`
@@ -23,27 +29,47 @@ type responseID struct {
 	ObjectID int `json:"object_id"`
 }
 
-type responseError struct {
-	Error string `json:"error"`
-}
-
-// handleListUsers is a Handler that returns a list of all users.
+// handleListUsers is a Handler that returns a page of users. The `limit` query parameter sets the
+// page size and the `cursor` query parameter takes a `next_cursor` or `prev_cursor` from a previous
+// page.
 func (h *Handler) handleListUsers() http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
+		// get and validate query params
+		params := models.ListUsersParams{
+			Limit:  defaultListLimit,
+			Cursor: r.URL.Query().Get("cursor"),
+		}
+		if limitString := r.URL.Query().Get("limit"); limitString != "" {
+			limit, err := strconv.Atoi(limitString)
+			if err != nil || limit < 1 || limit > maxListLimit {
+				h.requestLogger(r).Error("error getting limit", "limit", limitString, "error", err)
+				h.encodeProblem(w, r, problem.
+					New(http.StatusBadRequest, "One or more query parameters are invalid").
+					WithFieldErrors(map[string]string{
+						"limit": fmt.Sprintf("must be a number between 1 and %d", maxListLimit),
+					}),
+				)
+				return
+			}
+			params.Limit = limit
+		}
+
 		// get values from Database
-		users, err := h.userService.ListUsers()
+		page, err := h.userService.ListUsers(r.Context(), params)
 		if err != nil {
-			h.logger.Error("error getting all locations", "error", err)
-			encodeResponse(
-				w,
-				http.StatusInternalServerError,
-				responseError{Error: "Error retrieving data"},
-			)
+			switch {
+			case errors.Is(err, user.ErrInvalidCursor):
+				h.requestLogger(r).Error("invalid cursor", "error", err)
+				h.encodeProblem(w, r, problem.New(http.StatusBadRequest, "Invalid or expired cursor"))
+			default:
+				h.requestLogger(r).Error("error getting all locations", "error", err)
+				h.encodeProblem(w, r, problem.New(http.StatusInternalServerError, "Error retrieving data"))
+			}
 			return
 		}
 
 		// return response
-		encodeResponse(w, http.StatusOK, responseAllUsers{Users: users})
+		encodeResponse(w, http.StatusOK, page)
 	}
 }
 
@@ -54,66 +80,91 @@ func (h *Handler) handleFetchUser() http.HandlerFunc {
 		idString := r.PathValue("id")
 		ID, err := strconv.Atoi(idString)
 		if err != nil {
-			h.logger.Error("error getting ID", "error", err)
-			encodeResponse(w, http.StatusBadRequest, responseError{Error: "Not a valid ID"})
+			h.requestLogger(r).Error("error getting ID", "error", err)
+			h.encodeProblem(w, r, problem.New(http.StatusBadRequest, "Not a valid ID"))
 			return
 		}
 
 		// get values from Database
-		user, err := h.userService.FetchUser(ID)
+		foundUser, err := h.userService.FetchUser(r.Context(), ID)
 		if err != nil {
-			h.logger.Error("error getting locations buy ID", "ID", ID, "error", err)
-			encodeResponse(
-				w,
-				http.StatusInternalServerError,
-				responseError{Error: "Error retrieving data"},
-			)
+			switch {
+			case errors.Is(err, user.ErrUserNotFound):
+				h.requestLogger(r).Error("object does not exist", "ID", ID, "error", err)
+				h.encodeProblem(w, r, problem.New(http.StatusNotFound, "Object does not exist"))
+			default:
+				h.requestLogger(r).Error("error getting locations buy ID", "ID", ID, "error", err)
+				h.encodeProblem(w, r, problem.New(http.StatusInternalServerError, "Error retrieving data"))
+			}
 			return
 		}
 
 		// return response
-		encodeResponse(w, http.StatusOK, responseOneUser{User: user})
+		encodeResponse(w, http.StatusOK, responseOneUser{User: foundUser})
 	}
 }
 
 // handleUpdateUser is a Handler that updates a user based on a user object from the request body.
+// Changes to fields that are read-only for the principal of the request are refused.
 func (h *Handler) handleUpdateUser() http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		// get and validate ID
 		idString := r.PathValue("id")
 		ID, err := strconv.Atoi(idString)
 		if err != nil {
-			h.logger.Error("error getting ID", "error", err)
-			encodeResponse(w, http.StatusBadRequest, responseError{Error: "Not a valid ID"})
+			h.requestLogger(r).Error("error getting ID", "error", err)
+			h.encodeProblem(w, r, problem.New(http.StatusBadRequest, "Not a valid ID"))
 			return
 		}
 
 		// get and validate body as object
 		inputUser, err := decodeToStruct[models.User](r)
 		if err != nil {
-			h.logger.Error("BodyParser error", "error", err)
-			encodeResponse(
-				w,
-				http.StatusBadRequest,
-				responseError{Error: "missing values or malformed body"},
-			)
+			h.requestLogger(r).Error("BodyParser error", "error", err)
+			h.encodeProblem(w, r, problem.New(http.StatusBadRequest, "missing values or malformed body"))
 			return
 		}
 
+		// refuse changes to fields that are read-only for the principal of the request
+		if principal, ok := policy.FromContext(r.Context()); ok && principal.HasReadOnlyFields() {
+			current, err := h.userService.FetchUser(r.Context(), ID)
+			if err != nil {
+				switch {
+				case errors.Is(err, user.ErrUserNotFound):
+					h.requestLogger(r).Error("object does not exist", "ID", ID, "error", err)
+					h.encodeProblem(w, r, problem.New(http.StatusNotFound, "Object does not exist"))
+				default:
+					h.requestLogger(r).Error("error getting object from Database", "ID", ID, "error", err)
+					h.encodeProblem(w, r, problem.New(http.StatusInternalServerError, "Error updating data"))
+				}
+				return
+			}
+			if err = authorizeChanges(r.Context(), current, inputUser); err != nil {
+				h.requestLogger(r).Error("request forbidden by policy", "ID", ID, "error", err)
+				h.encodeProblem(w, r, forbiddenProblem(err))
+				return
+			}
+		}
+
 		// update object in Database
-		user, err := h.userService.UpdateUser(ID, inputUser)
+		updatedUser, err := h.userService.UpdateUser(r.Context(), ID, inputUser)
 		if err != nil {
-			h.logger.Error("error updating object in Database", "error", err)
-			encodeResponse(
-				w,
-				http.StatusInternalServerError,
-				responseError{Error: "Error updating data"},
-			)
+			switch {
+			case errors.Is(err, user.ErrUserNotFound):
+				h.requestLogger(r).Error("object does not exist", "ID", ID, "error", err)
+				h.encodeProblem(w, r, problem.New(http.StatusNotFound, "Object does not exist"))
+			case errors.Is(err, user.ErrUserIDConflict):
+				h.requestLogger(r).Error("user_id already in use", "ID", ID, "error", err)
+				h.encodeProblem(w, r, problem.New(http.StatusConflict, "user_id already in use"))
+			default:
+				h.requestLogger(r).Error("error updating object in Database", "error", err)
+				h.encodeProblem(w, r, problem.New(http.StatusInternalServerError, "Error updating data"))
+			}
 			return
 		}
 
 		// return response
-		encodeResponse(w, http.StatusOK, responseOneUser{User: user})
+		encodeResponse(w, http.StatusOK, responseOneUser{User: updatedUser})
 	}
 }
 
@@ -123,24 +174,22 @@ func (h *Handler) handleCreateUser() http.HandlerFunc {
 		// get and validate body as object
 		inputUser, err := decodeToStruct[models.User](r)
 		if err != nil {
-			h.logger.Error("BodyParser error", "error", err)
-			encodeResponse(
-				w,
-				http.StatusBadRequest,
-				responseError{Error: "missing values or malformed body"},
-			)
+			h.requestLogger(r).Error("BodyParser error", "error", err)
+			h.encodeProblem(w, r, problem.New(http.StatusBadRequest, "missing values or malformed body"))
 			return
 		}
 
 		// create object in Database
-		ID, err := h.userService.CreateUser(inputUser)
+		ID, err := h.userService.CreateUser(r.Context(), inputUser)
 		if err != nil {
-			h.logger.Error("error creating object to Database", "error", err)
-			encodeResponse(
-				w,
-				http.StatusInternalServerError,
-				responseError{Error: "Error creating object"},
-			)
+			switch {
+			case errors.Is(err, user.ErrUserIDConflict):
+				h.requestLogger(r).Error("user_id already in use", "error", err)
+				h.encodeProblem(w, r, problem.New(http.StatusConflict, "user_id already in use"))
+			default:
+				h.requestLogger(r).Error("error creating object to Database", "error", err)
+				h.encodeProblem(w, r, problem.New(http.StatusInternalServerError, "Error creating object"))
+			}
 			return
 		}
 
@@ -156,35 +205,21 @@ func (h *Handler) handleDeleteUser() http.HandlerFunc {
 		idString := r.PathValue("id")
 		ID, err := strconv.Atoi(idString)
 		if err != nil {
-			h.logger.Error("error getting ID", "error", err)
-			encodeResponse(w, http.StatusBadRequest, responseError{Error: "Not a valid ID"})
-			return
-		}
-
-		// check that object exists
-		user, err := h.userService.FetchUser(ID)
-		if err != nil {
-			h.logger.Error("error getting object by ID", "error", err)
-			encodeResponse(
-				w,
-				http.StatusInternalServerError,
-				responseError{Error: "Error validating object"},
-			)
-			return
-		}
-		if user.ID == 0 {
-			encodeResponse(w, http.StatusBadRequest, responseError{Error: "Object does not exist"})
+			h.requestLogger(r).Error("error getting ID", "error", err)
+			h.encodeProblem(w, r, problem.New(http.StatusBadRequest, "Not a valid ID"))
 			return
 		}
 
 		// delete user
-		if err = h.userService.DeleteUser(ID); err != nil {
-			h.logger.Error("error deleting object by ID", "ID", ID, "error", err)
-			encodeResponse(
-				w,
-				http.StatusInternalServerError,
-				responseError{Error: "Error deleting object."},
-			)
+		if err = h.userService.DeleteUser(r.Context(), ID); err != nil {
+			switch {
+			case errors.Is(err, user.ErrUserNotFound):
+				h.requestLogger(r).Error("object does not exist", "ID", ID, "error", err)
+				h.encodeProblem(w, r, problem.New(http.StatusNotFound, "Object does not exist"))
+			default:
+				h.requestLogger(r).Error("error deleting object by ID", "ID", ID, "error", err)
+				h.encodeProblem(w, r, problem.New(http.StatusInternalServerError, "Error deleting object."))
+			}
 			return
 		}
 
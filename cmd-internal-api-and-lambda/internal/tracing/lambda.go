@@ -0,0 +1,85 @@
+package tracing
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/aws/aws-lambda-go/lambdacontext"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// APIGatewayHandler is a Lambda handler of API Gateway proxy events.
+type APIGatewayHandler func(ctx context.Context, req events.APIGatewayProxyRequest) (events.APIGatewayProxyResponse, error)
+
+// Provider is a TracerProvider whose buffered spans can be sent on demand.
+type Provider interface {
+	trace.TracerProvider
+	ForceFlush(ctx context.Context) error
+}
+
+// WrapAPIGateway returns a handler that runs every invocation of handler in a span of provider.
+// The span continues the trace of the W3C traceparent header of the event, is named after the
+// method and resource of the event, and is marked as failed if handler fails or responds with a 5xx
+// status. The spans of an invocation are sent before it returns, as the function may be frozen
+// until its next invocation.
+func WrapAPIGateway(provider Provider, handler APIGatewayHandler) APIGatewayHandler {
+	tracer := provider.Tracer(instrumentationName)
+
+	return func(ctx context.Context, req events.APIGatewayProxyRequest) (events.APIGatewayProxyResponse, error) {
+		ctx = propagator.Extract(ctx, propagation.HeaderCarrier(eventHeaders(req)))
+
+		attributes := []trace.SpanStartOption{
+			trace.WithSpanKind(trace.SpanKindServer),
+			trace.WithAttributes(
+				semconv.FaaSTriggerHTTP,
+				semconv.HTTPRequestMethodKey.String(req.HTTPMethod),
+				semconv.URLPath(req.Path),
+				semconv.HTTPRoute(req.Resource),
+			),
+		}
+		if lc, ok := lambdacontext.FromContext(ctx); ok {
+			attributes = append(attributes, trace.WithAttributes(semconv.FaaSInvocationID(lc.AwsRequestID)))
+		}
+
+		ctx, span := tracer.Start(ctx, req.HTTPMethod+" "+req.Resource, attributes...)
+		resp, err := handler(ctx, req)
+
+		switch {
+		case err != nil:
+			span.RecordError(err)
+			span.SetStatus(codes.Error, err.Error())
+		case resp.StatusCode >= http.StatusInternalServerError:
+			span.SetStatus(codes.Error, http.StatusText(resp.StatusCode))
+		}
+		if err == nil {
+			span.SetAttributes(semconv.HTTPResponseStatusCode(resp.StatusCode))
+		}
+		span.End()
+
+		if flushErr := provider.ForceFlush(ctx); flushErr != nil {
+			otel.Handle(flushErr)
+		}
+
+		return resp, err
+	}
+}
+
+// eventHeaders returns the headers of req, with the multi-value headers taking precedence.
+func eventHeaders(req events.APIGatewayProxyRequest) http.Header {
+	headers := make(http.Header, len(req.Headers))
+	for key, value := range req.Headers {
+		headers.Set(key, value)
+	}
+	for key, values := range req.MultiValueHeaders {
+		headers.Del(key)
+		for _, value := range values {
+			headers.Add(key, value)
+		}
+	}
+	return headers
+}
@@ -1,62 +1,177 @@
 package user
 
 import (
+	"context"
 	"database/sql"
 	"errors"
 	"fmt"
+	"slices"
 
+	"github.com/jha-captech/user-microservice/internal/cursor"
 	"github.com/jha-captech/user-microservice/internal/database"
 	"github.com/jha-captech/user-microservice/internal/models"
+	"github.com/lib/pq"
+)
+
+// pgUniqueViolation is the Postgres error code raised when a unique constraint is violated.
+const pgUniqueViolation = "23505"
+
+var (
+	// ErrInvalidCursor is returned when a pagination cursor is malformed, has been tampered with or
+	// has expired.
+	ErrInvalidCursor = errors.New("invalid or expired cursor")
+
+	// ErrUserNotFound is returned when no user exists with the requested ID.
+	ErrUserNotFound = errors.New("user not found")
+
+	// ErrUserIDConflict is returned when a user is created or updated with a user_id that already
+	// belongs to another user.
+	ErrUserIDConflict = errors.New("user_id already in use")
 )
 
 type Service struct {
 	Database database.Database
+	cursors  cursor.Codec
+	changes  changeCounter
+}
+
+// changeCounter counts the users that a Service changes, such as metrics.Recorder.
+type changeCounter interface {
+	UsersCreated(count int)
+	UsersUpdated(count int)
+	UsersDeleted(count int)
+}
+
+// uncounted is the changeCounter of a Service that counts no changes.
+type uncounted struct{}
+
+func (uncounted) UsersCreated(int) {}
+func (uncounted) UsersUpdated(int) {}
+func (uncounted) UsersDeleted(int) {}
+
+type Option func(*Service)
+
+// WithChangeCounter counts every user that the Service creates, updates or deletes with changes.
+func WithChangeCounter(changes changeCounter) Option {
+	return func(s *Service) {
+		s.changes = changes
+	}
 }
 
 // NewService returns a new Service struct.
-func NewService(db database.Database) Service {
-	return Service{
+func NewService(db database.Database, cursors cursor.Codec, opts ...Option) Service {
+	s := Service{
 		Database: db,
+		cursors:  cursors,
+		changes:  uncounted{},
+	}
+	for _, opt := range opts {
+		opt(&s)
 	}
+	return s
 }
 
-// ListUsers returns a list of all User objects from the Database.
-func (s Service) ListUsers() ([]models.User, error) {
-	rows, err := s.Database.Session.Query(
-		`
-		SELECT 
-		    * 
-		FROM
-		    "users" 
-		`,
+// ListUsers returns a page of User objects from the Database ordered by ID. Pages are found by
+// keyset, so paging does not skip or repeat rows while other rows are inserted or deleted.
+func (s Service) ListUsers(ctx context.Context, params models.ListUsersParams) (models.UserPage, error) {
+	var (
+		key   cursor.Key
+		where string
+		args  []any
+	)
+	order := "ASC"
+
+	if params.Cursor != "" {
+		var err error
+		key, err = s.cursors.Decode(params.Cursor)
+		if err != nil {
+			return models.UserPage{}, fmt.Errorf("in ListUsers: %w: %w", ErrInvalidCursor, err)
+		}
+		if key.SortBy != "id" {
+			return models.UserPage{}, fmt.Errorf("in ListUsers: %w: not sorted by id", ErrInvalidCursor)
+		}
+
+		args = append(args, key.ID)
+		where = `WHERE "id" > $1`
+		if key.Before {
+			where = `WHERE "id" < $1`
+			order = "DESC"
+		}
+	}
+
+	// fetch one extra row to find out if there are more rows past this page
+	args = append(args, params.Limit+1)
+	rows, err := s.Database.Session.QueryContext(
+		ctx,
+		fmt.Sprintf(
+			`
+			SELECT
+				"id", "first_name", "last_name", "role", "user_id"
+			FROM
+				"users"
+			%s
+			ORDER BY
+				"id" %s
+			LIMIT $%d
+			`,
+			where,
+			order,
+			len(args),
+		),
+		args...,
 	)
 	if err != nil {
-		return []models.User{}, fmt.Errorf("in ListUsers:, %w", err)
+		return models.UserPage{}, fmt.Errorf("in ListUsers:, %w", err)
 	}
 	defer rows.Close()
 
-	var users []models.User
+	users := []models.User{}
 	for rows.Next() {
 		var user models.User
 		err = rows.Scan(&user.ID, &user.FirstName, &user.LastName, &user.Role, &user.UserID)
 		if err != nil {
-			return []models.User{}, fmt.Errorf("in ListUsers:, %w", err)
+			return models.UserPage{}, fmt.Errorf("in ListUsers:, %w", err)
 		}
 		users = append(users, user)
 	}
 
 	if err = rows.Err(); err != nil {
-		return []models.User{}, fmt.Errorf("in ListUsers:, %w", err)
+		return models.UserPage{}, fmt.Errorf("in ListUsers:, %w", err)
+	}
+
+	hasMore := len(users) > params.Limit
+	if hasMore {
+		users = users[:params.Limit]
+	}
+	if key.Before {
+		slices.Reverse(users)
+	}
+
+	// work out which adjacent pages exist
+	hasNext, hasPrev := hasMore, params.Cursor != ""
+	if key.Before {
+		hasNext, hasPrev = true, hasMore
 	}
 
-	return users, nil
+	page := models.UserPage{Users: users}
+	if len(users) > 0 {
+		if hasNext {
+			page.NextCursor = s.cursors.Encode(cursor.Key{SortBy: "id", ID: users[len(users)-1].ID})
+		}
+		if hasPrev {
+			page.PrevCursor = s.cursors.Encode(cursor.Key{SortBy: "id", ID: users[0].ID, Before: true})
+		}
+	}
+
+	return page, nil
 }
 
 // FetchUser returns am User objects from the Database by ID.
-func (s Service) FetchUser(ID int) (models.User, error) {
+func (s Service) FetchUser(ctx context.Context, ID int) (models.User, error) {
 	var user models.User
 	err := s.Database.Session.
-		QueryRow(
+		QueryRowContext(
+			ctx,
 			`
 			SELECT
 				*
@@ -73,17 +188,46 @@ func (s Service) FetchUser(ID int) (models.User, error) {
 		Scan(&user.ID, &user.FirstName, &user.LastName, &user.Role, &user.UserID)
 	if err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
-			return models.User{}, nil
+			err = ErrUserNotFound
+		}
+		return models.User{}, fmt.Errorf("in FetchUser: %w", err)
+	}
+
+	return user, nil
+}
+
+// FetchUserByUserID returns the User object from the Database with the user_id userID.
+func (s Service) FetchUserByUserID(ctx context.Context, userID uint) (models.User, error) {
+	var user models.User
+	err := s.Database.Session.
+		QueryRowContext(
+			ctx,
+			`
+			SELECT
+				"id", "first_name", "last_name", "role", "user_id"
+			FROM
+				"users"
+			WHERE
+				"user_id" = $1
+			LIMIT 1
+			`,
+			userID,
+		).
+		Scan(&user.ID, &user.FirstName, &user.LastName, &user.Role, &user.UserID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			err = ErrUserNotFound
 		}
-		return models.User{}, fmt.Errorf("in ListUsers:, %w", err)
+		return models.User{}, fmt.Errorf("in FetchUserByUserID: %w", err)
 	}
 
 	return user, nil
 }
 
 // UpdateUser updates am User objects from the Database by ID.
-func (s Service) UpdateUser(ID int, user models.User) (models.User, error) {
-	_, err := s.Database.Session.Exec(
+func (s Service) UpdateUser(ctx context.Context, ID int, user models.User) (models.User, error) {
+	result, err := s.Database.Session.ExecContext(
+		ctx,
 		`
 		UPDATE
 			"users"
@@ -102,17 +246,22 @@ func (s Service) UpdateUser(ID int, user models.User) (models.User, error) {
 		ID,
 	)
 	if err != nil {
+		return models.User{}, fmt.Errorf("in UpdateUser: %w", mapUniqueViolation(err))
+	}
+	if err = requireRowsAffected(result); err != nil {
 		return models.User{}, fmt.Errorf("in UpdateUser: %w", err)
 	}
+	s.changes.UsersUpdated(1)
 
 	user.ID = uint(ID)
 	return user, nil
 }
 
 // CreateUser creates am User objects in the Database.
-func (s Service) CreateUser(user models.User) (int, error) {
+func (s Service) CreateUser(ctx context.Context, user models.User) (int, error) {
 	var ID int
-	err := s.Database.Session.QueryRow(
+	err := s.Database.Session.QueryRowContext(
+		ctx,
 		`
 		INSERT INTO "users" ("first_name", "last_name", "role", "user_id")
 			VALUES ($1, $2, $3, $4)
@@ -124,15 +273,17 @@ func (s Service) CreateUser(user models.User) (int, error) {
 		user.UserID,
 	).Scan(&ID)
 	if err != nil {
-		return 0, fmt.Errorf("in CreateUser: %w", err)
+		return 0, fmt.Errorf("in CreateUser: %w", mapUniqueViolation(err))
 	}
+	s.changes.UsersCreated(1)
 
 	return ID, nil
 }
 
 // DeleteUser deletes am User objects from the Database by ID.
-func (s Service) DeleteUser(ID int) error {
-	_, err := s.Database.Session.Exec(
+func (s Service) DeleteUser(ctx context.Context, ID int) error {
+	result, err := s.Database.Session.ExecContext(
+		ctx,
 		`
 		DELETE FROM "users"
 		WHERE "users"."id" = $1
@@ -140,8 +291,36 @@ func (s Service) DeleteUser(ID int) error {
 		ID,
 	)
 	if err != nil {
-		return fmt.Errorf("could not delete user: %v", err)
+		return fmt.Errorf("in DeleteUser: %w", err)
+	}
+	if err = requireRowsAffected(result); err != nil {
+		return fmt.Errorf("in DeleteUser: %w", err)
+	}
+	s.changes.UsersDeleted(1)
+
+	return nil
+}
+
+// requireRowsAffected returns ErrUserNotFound when result reports that no rows were changed.
+func requireRowsAffected(result sql.Result) error {
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rows == 0 {
+		return ErrUserNotFound
 	}
 
 	return nil
 }
+
+// mapUniqueViolation converts a Postgres unique violation into ErrUserIDConflict. Other errors are
+// returned unchanged.
+func mapUniqueViolation(err error) error {
+	var pqErr *pq.Error
+	if errors.As(err, &pqErr) && pqErr.Code == pgUniqueViolation {
+		return fmt.Errorf("%w: %w", ErrUserIDConflict, err)
+	}
+
+	return err
+}
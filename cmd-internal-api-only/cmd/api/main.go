@@ -9,13 +9,24 @@ import (
 	"net/http"
 	"os"
 	"os/signal"
+	"strings"
 	"syscall"
 	"time"
 
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+
+	"github.com/jha-captech/user-microservice/internal/auth"
 	"github.com/jha-captech/user-microservice/internal/config"
+	"github.com/jha-captech/user-microservice/internal/cursor"
 	"github.com/jha-captech/user-microservice/internal/database"
+	"github.com/jha-captech/user-microservice/internal/health"
+	"github.com/jha-captech/user-microservice/internal/logging"
+	"github.com/jha-captech/user-microservice/internal/metrics"
 	"github.com/jha-captech/user-microservice/internal/middleware"
+	"github.com/jha-captech/user-microservice/internal/policy"
+	"github.com/jha-captech/user-microservice/internal/ratelimit"
 	"github.com/jha-captech/user-microservice/internal/server"
+	"github.com/jha-captech/user-microservice/internal/tracing"
 	"github.com/jha-captech/user-microservice/internal/user"
 )
 
@@ -27,7 +38,22 @@ func run() {
 	// Setup
 	cfg := config.MustNewConfiguration()
 
-	logger := slog.Default()
+	logger := mustNewLogger(cfg)
+
+	provider := mustNewTracerProvider(cfg)
+	defer func() {
+		// spans that are still buffered are sent before exiting
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		if err := provider.Shutdown(shutdownCtx); err != nil {
+			logger.Error("Error shutting down tracer provider", "err", err)
+		}
+	}()
+
+	migrateOption := database.WithSchemaCheck()
+	if cfg.Database.MigrateOnStartup {
+		migrateOption = database.WithMigrations()
+	}
 
 	db := database.MustNewDatabase(
 		fmt.Sprintf(
@@ -40,22 +66,63 @@ func run() {
 		),
 		logger,
 		cfg.Database.ConnectionRetry,
+		database.WithTracing(provider),
+		migrateOption,
 	)
 	defer db.Session.Close()
+	if cfg.Database.MaxOpenConnections > 0 {
+		db.Session.SetMaxOpenConns(cfg.Database.MaxOpenConnections)
+	}
+
+	checker := mustNewHealthChecker(cfg, db, logger)
 
 	mux := http.NewServeMux()
 
-	stack := middleware.CreateStack(
+	recorder := metrics.NewPrometheus(db.Session, cfg.Database.Name)
+
+	us := user.NewService(
+		db,
+		cursor.NewCodec(
+			[]byte(cfg.Cursor.Secret),
+			cursorTTL(cfg),
+		),
+		user.WithChangeCounter(recorder),
+	)
+
+	middlewares := []middleware.Middleware{
+		middleware.TracingMiddleware(provider, mux),
+		middleware.MetricsMiddleware(recorder, mux),
+		middleware.RequestIDMiddleware(logger, mux),
 		middleware.CORSMiddleware(middleware.CORSOptions{
-			AllowedOrigins: []string{"*"},
+			AllowedOrigins: splitList(cfg.HTTP.CORSAllowedOrigins),
 			AllowedMethods: []string{"GET", "POST", "PUT", "DELETE"},
 		}),
 		middleware.LoggerMiddleware(logger),
 		middleware.RecoveryMiddleware(logger),
-	)
+	}
+	if cfg.Auth.Disabled {
+		logger.Warn("AUTH_DISABLED is set, requests are not authenticated")
+	} else {
+		middlewares = append(middlewares,
+			middleware.AuthMiddleware(logger, middleware.AuthOptions{
+				Verifier:    mustNewVerifier(cfg),
+				PublicPaths: []string{"/api/health/live", "/api/health/ready"},
+			}),
+			middleware.AuthorizeMiddleware(logger, us, mustNewPolicy(cfg.Auth.PolicyFile)),
+		)
+	}
+	if cfg.RateLimit.Disabled {
+		logger.Warn("RATE_LIMIT_DISABLED is set, requests are not rate limited")
+	} else {
+		middlewares = append(middlewares, middleware.RateLimitMiddleware(logger, mux, middleware.RateLimitOptions{
+			Store:          ratelimit.NewMemoryStore(),
+			Limits:         mustNewRateLimits(cfg),
+			UnlimitedPaths: []string{"/api/health/live", "/api/health/ready"},
+		}))
+	}
+	stack := middleware.CreateStack(middlewares...)
 
-	us := user.NewService(db)
-	h := server.NewHandler(logger, us)
+	h := server.NewHandler(logger, us, checker)
 	server.RegisterRoutes(mux, h)
 
 	serverInstance := &http.Server{
@@ -63,6 +130,21 @@ func run() {
 		Handler: stack(mux),
 	}
 
+	// metrics are served on their own port, so they are not exposed with the API
+	metricsMux := http.NewServeMux()
+	metricsMux.Handle("GET /metrics", recorder.Handler())
+	metricsServer := &http.Server{
+		Addr:    cfg.HTTP.Domain + metricsPort(cfg),
+		Handler: metricsMux,
+	}
+	go func() {
+		logger.Info(fmt.Sprintf("Metrics are served on %s", metricsServer.Addr))
+		err := metricsServer.ListenAndServe()
+		if err != nil && !errors.Is(err, http.ErrServerClosed) {
+			logger.Error("Metrics server stopped", "err", err)
+		}
+	}()
+
 	// Graceful shutdown
 	serverCtx, serverStopCtx := context.WithCancel(context.Background())
 
@@ -72,11 +154,17 @@ func run() {
 		<-sig
 
 		fmt.Println()
-		logger.Info("Shutdown signal received")
+		logger.Info("Shutdown signal received, draining", "delay seconds", cfg.HTTP.DrainDelaySeconds)
+
+		// readiness fails from here on, so load balancers stop sending requests before the server
+		// stops accepting them
+		checker.Drain()
+		time.Sleep(time.Duration(cfg.HTTP.DrainDelaySeconds) * time.Second)
 
-		shutdownCtx, _ := context.WithTimeout(
+		shutdownCtx, cancel := context.WithTimeout(
 			serverCtx, time.Duration(cfg.HTTP.ShutdownGracePeriod)*time.Second,
 		)
+		defer cancel()
 
 		go func() {
 			<-shutdownCtx.Done()
@@ -89,6 +177,9 @@ func run() {
 		if err != nil {
 			log.Fatal(err)
 		}
+		if err := metricsServer.Shutdown(shutdownCtx); err != nil {
+			logger.Error("Error shutting down metrics server", "err", err)
+		}
 		serverStopCtx()
 	}()
 
@@ -102,3 +193,157 @@ func run() {
 	<-serverCtx.Done()
 	logger.Info("Shutdown complete")
 }
+
+// mustNewLogger returns the default logger, which redacts personal data in LOG_REDACT_MODE,
+// `mask` if it is not set, when LOG_REDACT_PII is set. The attributes in LOG_REDACT_KEYS are
+// redacted, or `first_name` and `last_name` if it is not set. The hash mode is keyed by
+// LOG_REDACT_SECRET. It panics if the mode is unknown or the hash mode has no secret.
+func mustNewLogger(cfg config.Configuration) *slog.Logger {
+	logger := slog.Default()
+	if !cfg.Log.RedactPII {
+		return logger
+	}
+
+	mode := cfg.Log.RedactMode
+	if mode == "" {
+		mode = logging.RedactMask
+	}
+	keys := splitList(cfg.Log.RedactKeys)
+	if len(keys) == 0 {
+		keys = []string{"first_name", "last_name"}
+	}
+
+	handler, err := logging.NewRedactHandler(logger.Handler(), mode, []byte(cfg.Log.RedactSecret), keys)
+	if err != nil {
+		panic(fmt.Sprintf("Error creating log redaction: %v", err))
+	}
+	return slog.New(handler)
+}
+
+// mustNewVerifier returns the verifier of bearer tokens configured by cfg, which takes its keys from
+// the JWK Set at AUTH_JWKS_URL or, if that is not set, from the key file at AUTH_KEY_FILE. It panics
+// if authentication is not configured.
+func mustNewVerifier(cfg config.Configuration) *auth.Verifier {
+	if cfg.Auth.Issuer == "" || cfg.Auth.Audience == "" {
+		panic("AUTH_ISSUER and AUTH_AUDIENCE must be set unless AUTH_DISABLED is true")
+	}
+
+	var keys auth.KeySource
+	switch {
+	case cfg.Auth.JWKSURL != "":
+		var opts []auth.JWKSOption
+		if cfg.Auth.JWKSRefreshSeconds > 0 {
+			opts = append(opts, auth.WithRefreshInterval(time.Duration(cfg.Auth.JWKSRefreshSeconds)*time.Second))
+		}
+		keys = auth.NewJWKS(cfg.Auth.JWKSURL, opts...)
+	case cfg.Auth.KeyFile != "":
+		keyFile, err := auth.LoadKeyFile(cfg.Auth.KeyFile)
+		if err != nil {
+			panic(fmt.Sprintf("Error loading AUTH_KEY_FILE: %v", err))
+		}
+		keys = keyFile
+	default:
+		panic("AUTH_JWKS_URL or AUTH_KEY_FILE must be set unless AUTH_DISABLED is true")
+	}
+
+	var opts []auth.VerifierOption
+	if cfg.Auth.LeewaySeconds > 0 {
+		opts = append(opts, auth.WithLeeway(time.Duration(cfg.Auth.LeewaySeconds)*time.Second))
+	}
+	return auth.NewVerifier(keys, cfg.Auth.Issuer, cfg.Auth.Audience, opts...)
+}
+
+// mustNewPolicy returns the policy of the file at path, or the default policy if path is empty. It
+// panics if the file can not be loaded.
+func mustNewPolicy(path string) policy.Policy {
+	rules, err := policy.New(path)
+	if err != nil {
+		panic(fmt.Sprintf("Error loading AUTH_POLICY_FILE: %v", err))
+	}
+	return rules
+}
+
+// mustNewRateLimits returns the rate limits configured by cfg, RATE_LIMIT_DEFAULT for every client,
+// or 300/1m if it is not set, and the limits of single routes in RATE_LIMIT_ROUTES. It panics if a
+// limit can not be parsed.
+func mustNewRateLimits(cfg config.Configuration) ratelimit.Limits {
+	defaultValue := cfg.RateLimit.Default
+	if defaultValue == "" {
+		defaultValue = "300/1m"
+	}
+	defaultLimit, err := ratelimit.ParseLimit(defaultValue)
+	if err != nil {
+		panic(fmt.Sprintf("Error parsing RATE_LIMIT_DEFAULT: %v", err))
+	}
+	routeLimits, err := ratelimit.ParseRouteLimits(cfg.RateLimit.Routes)
+	if err != nil {
+		panic(fmt.Sprintf("Error parsing RATE_LIMIT_ROUTES: %v", err))
+	}
+	return ratelimit.Limits{Default: defaultLimit, Routes: routeLimits}
+}
+
+// mustNewHealthChecker returns the readiness checker of db, with the timeout and pool saturation of
+// cfg if they are set. It panics if the migrations the schema is checked against can not be loaded.
+func mustNewHealthChecker(cfg config.Configuration, db database.Database, logger *slog.Logger) *health.Checker {
+	var opts []health.Option
+	if cfg.Health.TimeoutSeconds > 0 {
+		opts = append(opts, health.WithTimeout(time.Duration(cfg.Health.TimeoutSeconds)*time.Second))
+	}
+	if cfg.Health.MaxPoolSaturation > 0 {
+		opts = append(opts, health.WithMaxPoolSaturation(cfg.Health.MaxPoolSaturation))
+	}
+
+	migrator, err := database.NewMigrator(db.Session, logger)
+	if err != nil {
+		panic(fmt.Sprintf("Error loading migrations: %v", err))
+	}
+	return health.NewChecker(db.Session, migrator, opts...)
+}
+
+// mustNewTracerProvider returns the tracer provider configured by cfg, which sends spans to the
+// TRACING_EXPORTER. It panics if the exporter is unknown or can not be created.
+func mustNewTracerProvider(cfg config.Configuration) *sdktrace.TracerProvider {
+	serviceName := cfg.Tracing.ServiceName
+	if serviceName == "" {
+		serviceName = "user-microservice"
+	}
+
+	var opts []tracing.Option
+	if cfg.Tracing.SampleRatio > 0 {
+		opts = append(opts, tracing.WithSampleRatio(cfg.Tracing.SampleRatio))
+	}
+
+	provider, err := tracing.New(context.Background(), cfg.Tracing.Exporter, serviceName, opts...)
+	if err != nil {
+		panic(fmt.Sprintf("Error creating tracer provider: %v", err))
+	}
+	return provider
+}
+
+// cursorTTL returns the CURSOR_TTL of cfg, or 24 hours if it is not set.
+func cursorTTL(cfg config.Configuration) time.Duration {
+	if cfg.Cursor.TTL == 0 {
+		return 24 * time.Hour
+	}
+	return cfg.Cursor.TTL
+}
+
+// metricsPort returns the METRICS_PORT of cfg, or `:9090` if it is not set.
+func metricsPort(cfg config.Configuration) string {
+	if cfg.Metrics.Port == "" {
+		return ":9090"
+	}
+	return cfg.Metrics.Port
+}
+
+// splitList returns the items of a comma separated list, or nil if it is empty.
+func splitList(list string) []string {
+	if strings.TrimSpace(list) == "" {
+		return nil
+	}
+	items := strings.Split(list, ",")
+	for i := range items {
+		items[i] = strings.TrimSpace(items[i])
+	}
+	return items
+}
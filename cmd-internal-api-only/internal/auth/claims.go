@@ -0,0 +1,138 @@
+package auth
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Claims are the claims of a verified token, or of the API Gateway authorizer that verified it.
+// Registered claims have fields of their own, and every claim, registered or not, is kept in Raw
+// for claims specific to an issuer.
+type Claims struct {
+	Subject   string
+	Issuer    string
+	Audience  []string
+	ExpiresAt time.Time
+	NotBefore time.Time
+	IssuedAt  time.Time
+	Raw       map[string]any
+}
+
+type claimsKey struct{}
+
+// WithClaims returns a copy of ctx carrying claims as the claims of the caller making the request.
+func WithClaims(ctx context.Context, claims Claims) context.Context {
+	return context.WithValue(ctx, claimsKey{}, claims)
+}
+
+// FromContext returns the claims set on ctx with WithClaims, and whether there are any.
+func FromContext(ctx context.Context) (Claims, bool) {
+	claims, ok := ctx.Value(claimsKey{}).(Claims)
+	return claims, ok
+}
+
+// UserID returns the user_id claim, which names the user_id of the user the token was issued to,
+// and whether it is set to a positive integer. API Gateway passes claims as strings, so numeric
+// strings are accepted as well as numbers.
+func (c Claims) UserID() (uint, bool) {
+	var text string
+	switch value := c.Raw["user_id"].(type) {
+	case json.Number:
+		text = value.String()
+	case float64:
+		text = strconv.FormatFloat(value, 'f', -1, 64)
+	case string:
+		text = value
+	default:
+		return 0, false
+	}
+
+	userID, err := strconv.ParseUint(text, 10, 0)
+	if err != nil || userID == 0 {
+		return 0, false
+	}
+	return uint(userID), true
+}
+
+// newClaims returns the Claims of the claim set raw. If strict is set, a time claim that is not a
+// NumericDate is an error, otherwise it is left as the zero time, and a string aud claim is split
+// into several audiences.
+func newClaims(raw map[string]any, strict bool) (Claims, error) {
+	claims := Claims{Raw: raw}
+	claims.Subject, _ = raw["sub"].(string)
+	claims.Issuer, _ = raw["iss"].(string)
+	claims.Audience = audience(raw["aud"], strict)
+
+	times := map[string]*time.Time{
+		"exp": &claims.ExpiresAt,
+		"nbf": &claims.NotBefore,
+		"iat": &claims.IssuedAt,
+	}
+	for name, field := range times {
+		value, ok := raw[name]
+		if !ok {
+			continue
+		}
+		date, ok := numericDate(value)
+		if !ok && strict {
+			return Claims{}, fmt.Errorf("claim %q is not a NumericDate", name)
+		}
+		*field = date
+	}
+
+	return claims, nil
+}
+
+// audience returns the audiences of an aud claim, which is either a single audience or a list of
+// them. API Gateway flattens lists into a single string, so unless strict is set, strings are split
+// on spaces and commas.
+func audience(value any, strict bool) []string {
+	switch value := value.(type) {
+	case string:
+		if strict {
+			return []string{value}
+		}
+		return strings.FieldsFunc(value, func(r rune) bool { return r == ' ' || r == ',' })
+	case []any:
+		audiences := make([]string, 0, len(value))
+		for _, item := range value {
+			if item, ok := item.(string); ok {
+				audiences = append(audiences, item)
+			}
+		}
+		return audiences
+	default:
+		return nil
+	}
+}
+
+// numericDate returns the time of a NumericDate claim. API Gateway passes claims as strings, which
+// may be a number of seconds or, for Cognito authorizers, a date in time.UnixDate format.
+func numericDate(value any) (time.Time, bool) {
+	var seconds float64
+	switch value := value.(type) {
+	case json.Number:
+		parsed, err := value.Float64()
+		if err != nil {
+			return time.Time{}, false
+		}
+		seconds = parsed
+	case float64:
+		seconds = value
+	case string:
+		parsed, err := strconv.ParseFloat(value, 64)
+		if err != nil {
+			date, err := time.Parse(time.UnixDate, value)
+			return date, err == nil
+		}
+		seconds = parsed
+	default:
+		return time.Time{}, false
+	}
+
+	return time.Unix(int64(seconds), 0), true
+}
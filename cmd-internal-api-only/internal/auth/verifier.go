@@ -0,0 +1,189 @@
+package auth
+
+import (
+	"bytes"
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"slices"
+	"strings"
+	"time"
+)
+
+// ErrInvalidToken is returned by Verifier.Verify for a token that is malformed, is not signed by a
+// trusted key or has claims that are not accepted. Other errors mean that the token could not be
+// verified, such as when the JWK Set can not be fetched.
+var ErrInvalidToken = errors.New("invalid token")
+
+const (
+	algRS256 = "RS256"
+	algES256 = "ES256"
+)
+
+type header struct {
+	Alg string `json:"alg"`
+	Kid string `json:"kid"`
+}
+
+// Verifier verifies JSON Web Tokens signed with RS256 or ES256, and checks that they were issued by
+// the expected issuer for the expected audience and are currently valid.
+type Verifier struct {
+	keys     KeySource
+	issuer   string
+	audience string
+	leeway   time.Duration
+	now      func() time.Time
+}
+
+// VerifierOption is a function that configures a Verifier.
+type VerifierOption func(*Verifier)
+
+// WithLeeway sets how much clock skew between the issuer and the service is tolerated when checking
+// the exp and nbf claims. The default is 1 minute.
+func WithLeeway(leeway time.Duration) VerifierOption {
+	return func(v *Verifier) {
+		v.leeway = leeway
+	}
+}
+
+// WithClock sets the function the current time is taken from, for tests.
+func WithClock(now func() time.Time) VerifierOption {
+	return func(v *Verifier) {
+		v.now = now
+	}
+}
+
+// NewVerifier returns a new Verifier that verifies tokens with the keys of keys, and accepts them
+// if their iss claim is issuer and their aud claim includes audience.
+func NewVerifier(keys KeySource, issuer string, audience string, opts ...VerifierOption) *Verifier {
+	verifier := &Verifier{
+		keys:     keys,
+		issuer:   issuer,
+		audience: audience,
+		leeway:   time.Minute,
+		now:      time.Now,
+	}
+	for _, opt := range opts {
+		opt(verifier)
+	}
+	return verifier
+}
+
+// Verify verifies the compact serialized token and returns its claims. The exp claim is required,
+// while nbf is only checked if it is present.
+func (v *Verifier) Verify(ctx context.Context, token string) (Claims, error) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return Claims{}, fmt.Errorf("in Verifier.Verify: not a JWS compact serialization: %w", ErrInvalidToken)
+	}
+
+	var head header
+	if err := decodeSegment(parts[0], &head); err != nil {
+		return Claims{}, fmt.Errorf("in Verifier.Verify: header: %w: %w", ErrInvalidToken, err)
+	}
+	if head.Alg != algRS256 && head.Alg != algES256 {
+		return Claims{}, fmt.Errorf("in Verifier.Verify: algorithm %q not allowed: %w", head.Alg, ErrInvalidToken)
+	}
+
+	signature, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil {
+		return Claims{}, fmt.Errorf("in Verifier.Verify: signature: %w: %w", ErrInvalidToken, err)
+	}
+	key, err := v.keys.Key(ctx, head.Kid)
+	if err != nil {
+		if errors.Is(err, ErrKeyNotFound) {
+			return Claims{}, fmt.Errorf("in Verifier.Verify: %w: %w", ErrInvalidToken, err)
+		}
+		return Claims{}, fmt.Errorf("in Verifier.Verify: %w", err)
+	}
+	if err = verifySignature(head.Alg, key, parts[0]+"."+parts[1], signature); err != nil {
+		return Claims{}, fmt.Errorf("in Verifier.Verify: %w: %w", ErrInvalidToken, err)
+	}
+
+	var raw map[string]any
+	if err = decodeSegment(parts[1], &raw); err != nil {
+		return Claims{}, fmt.Errorf("in Verifier.Verify: claims: %w: %w", ErrInvalidToken, err)
+	}
+	claims, err := newClaims(raw, true)
+	if err != nil {
+		return Claims{}, fmt.Errorf("in Verifier.Verify: %w: %w", ErrInvalidToken, err)
+	}
+	if err = v.check(claims); err != nil {
+		return Claims{}, fmt.Errorf("in Verifier.Verify: %w: %w", ErrInvalidToken, err)
+	}
+
+	return claims, nil
+}
+
+// check checks the registered claims of a token with a valid signature.
+func (v *Verifier) check(claims Claims) error {
+	now := v.now()
+
+	if claims.Issuer != v.issuer {
+		return fmt.Errorf("issuer %q not accepted", claims.Issuer)
+	}
+	if !slices.Contains(claims.Audience, v.audience) {
+		return fmt.Errorf("audience %q not accepted", claims.Audience)
+	}
+	if claims.ExpiresAt.IsZero() {
+		return errors.New("no expiration time")
+	}
+	if !now.Before(claims.ExpiresAt.Add(v.leeway)) {
+		return fmt.Errorf("expired at %s", claims.ExpiresAt.Format(time.RFC3339))
+	}
+	if !claims.NotBefore.IsZero() && now.Add(v.leeway).Before(claims.NotBefore) {
+		return fmt.Errorf("not valid before %s", claims.NotBefore.Format(time.RFC3339))
+	}
+	return nil
+}
+
+// verifySignature verifies the signature of signed, the header and claims of a token, with key.
+func verifySignature(alg string, key crypto.PublicKey, signed string, signature []byte) error {
+	digest := sha256.Sum256([]byte(signed))
+
+	switch alg {
+	case algRS256:
+		public, ok := key.(*rsa.PublicKey)
+		if !ok {
+			return errors.New("RS256 token signed with a key that is not an RSA key")
+		}
+		if err := rsa.VerifyPKCS1v15(public, crypto.SHA256, digest[:], signature); err != nil {
+			return fmt.Errorf("signature: %w", err)
+		}
+	case algES256:
+		public, ok := key.(*ecdsa.PublicKey)
+		if !ok || public.Curve != elliptic.P256() {
+			return errors.New("ES256 token signed with a key that is not a P-256 key")
+		}
+		// JWS signatures are the fixed size R and S values, rather than the ASN.1 encoding
+		if len(signature) != 64 {
+			return errors.New("signature: wrong length")
+		}
+		r := new(big.Int).SetBytes(signature[:32])
+		s := new(big.Int).SetBytes(signature[32:])
+		if !ecdsa.Verify(public, digest[:], r, s) {
+			return errors.New("signature: verification error")
+		}
+	}
+	return nil
+}
+
+// decodeSegment decodes the base64url encoded JSON segment of a token into v. Numbers are decoded
+// as json.Number, so that NumericDate claims keep their precision.
+func decodeSegment(segment string, v any) error {
+	data, err := base64.RawURLEncoding.DecodeString(segment)
+	if err != nil {
+		return err
+	}
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+	return decoder.Decode(v)
+}
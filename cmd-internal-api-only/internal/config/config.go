@@ -8,6 +8,7 @@ import (
 	"reflect"
 	"strconv"
 	"strings"
+	"time"
 
 	"github.com/joho/godotenv"
 )
@@ -15,17 +16,58 @@ import (
 type Configuration struct {
 	Env      string `env:"ENV"`
 	Database struct {
-		Name            string `env:"DATABASE_NAME,required"`
-		User            string `env:"DATABASE_USER,required"`
-		Password        string `env:"DATABASE_PASSWORD,required"`
-		Host            string `env:"DATABASE_HOST,required"`
-		Port            string `env:"DATABASE_PORT,required"`
-		ConnectionRetry int    `env:"DATABASE_CONNECTION_RETRY,required"`
+		Name               string `env:"DATABASE_NAME,required"`
+		User               string `env:"DATABASE_USER,required"`
+		Password           string `env:"DATABASE_PASSWORD,required"`
+		Host               string `env:"DATABASE_HOST,required"`
+		Port               string `env:"DATABASE_PORT,required"`
+		ConnectionRetry    int    `env:"DATABASE_CONNECTION_RETRY,required"`
+		MigrateOnStartup   bool   `env:"DATABASE_MIGRATE_ON_STARTUP"`
+		MaxOpenConnections int    `env:"DATABASE_MAX_OPEN_CONNECTIONS"`
+	}
+	Cursor struct {
+		Secret string        `env:"CURSOR_SECRET"`
+		TTL    time.Duration `env:"CURSOR_TTL"`
+	}
+	Auth struct {
+		Disabled           bool   `env:"AUTH_DISABLED"`
+		JWKSURL            string `env:"AUTH_JWKS_URL"`
+		KeyFile            string `env:"AUTH_KEY_FILE"`
+		Issuer             string `env:"AUTH_ISSUER"`
+		Audience           string `env:"AUTH_AUDIENCE"`
+		LeewaySeconds      int    `env:"AUTH_LEEWAY_SECONDS"`
+		JWKSRefreshSeconds int    `env:"AUTH_JWKS_REFRESH_SECONDS"`
+		PolicyFile         string `env:"AUTH_POLICY_FILE"`
+	}
+	Health struct {
+		TimeoutSeconds    int     `env:"HEALTH_TIMEOUT_SECONDS"`
+		MaxPoolSaturation float64 `env:"HEALTH_MAX_POOL_SATURATION"`
+	}
+	Tracing struct {
+		Exporter    string  `env:"TRACING_EXPORTER"`
+		ServiceName string  `env:"TRACING_SERVICE_NAME"`
+		SampleRatio float64 `env:"TRACING_SAMPLE_RATIO"`
+	}
+	Metrics struct {
+		Port string `env:"METRICS_PORT"`
+	}
+	RateLimit struct {
+		Disabled bool   `env:"RATE_LIMIT_DISABLED"`
+		Default  string `env:"RATE_LIMIT_DEFAULT"`
+		Routes   string `env:"RATE_LIMIT_ROUTES"`
+	}
+	Log struct {
+		RedactPII    bool   `env:"LOG_REDACT_PII"`
+		RedactMode   string `env:"LOG_REDACT_MODE"`
+		RedactSecret string `env:"LOG_REDACT_SECRET"`
+		RedactKeys   string `env:"LOG_REDACT_KEYS"`
 	}
 	HTTP struct {
 		Domain              string `env:"HTTP_DOMAIN,required"`
 		Port                string `env:"HTTP_PORT,required"`
 		ShutdownGracePeriod int    `env:"HTTP_SHUTDOWN_GRACE_PERIOD,required"`
+		DrainDelaySeconds   int    `env:"HTTP_DRAIN_DELAY_SECONDS"`
+		CORSAllowedOrigins  string `env:"HTTP_CORS_ALLOWED_ORIGINS"`
 	}
 }
 
@@ -113,6 +155,23 @@ func ParseStructFromEnv(obj any) error {
 				}
 				field.SetBool(value)
 
+			case reflect.Int64:
+				if field.Type() != reflect.TypeFor[time.Duration]() {
+					continue
+				}
+				value, err := time.ParseDuration(envValue)
+				if err != nil && required {
+					return newEnvVarParsingErr(key, err)
+				}
+				field.SetInt(int64(value))
+
+			case reflect.Float64:
+				value, err := strconv.ParseFloat(envValue, 64)
+				if err != nil && required {
+					return newEnvVarParsingErr(key, err)
+				}
+				field.SetFloat(value)
+
 			default:
 				continue
 			}
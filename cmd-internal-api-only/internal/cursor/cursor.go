@@ -0,0 +1,113 @@
+package cursor
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+var (
+	// ErrInvalid is returned when a cursor token is malformed or its signature does not match.
+	ErrInvalid = errors.New("invalid cursor")
+
+	// ErrExpired is returned when a cursor token is older than the Codec's time to live.
+	ErrExpired = errors.New("expired cursor")
+)
+
+// Key is the position in an ordered list that a cursor points to. It holds the value of the sort
+// field and the ID of the last (or first, when Before is set) row of a page.
+type Key struct {
+	SortBy string `json:"s"`
+	Desc   bool   `json:"d,omitempty"`
+	Value  string `json:"v"`
+	ID     uint   `json:"i"`
+	Before bool   `json:"b,omitempty"`
+}
+
+type payload struct {
+	Key
+	IssuedAt int64 `json:"t"`
+}
+
+// Codec encodes Key values as opaque, signed tokens and decodes them again.
+type Codec struct {
+	secret []byte
+	ttl    time.Duration
+	now    func() time.Time
+}
+
+// NewCodec returns a new Codec that signs tokens with secret and rejects tokens older than ttl. If
+// secret is empty, a random secret is generated, meaning that tokens will only be accepted by the
+// Codec that issued them. A ttl of 0 or less disables expiry.
+func NewCodec(secret []byte, ttl time.Duration) Codec {
+	if len(secret) == 0 {
+		secret = make([]byte, 32)
+		if _, err := rand.Read(secret); err != nil {
+			panic(fmt.Sprintf("in cursor.NewCodec: generate secret: %v", err))
+		}
+	}
+
+	return Codec{
+		secret: secret,
+		ttl:    ttl,
+		now:    time.Now,
+	}
+}
+
+// Encode returns an opaque token for key.
+func (c Codec) Encode(key Key) string {
+	data, err := json.Marshal(payload{Key: key, IssuedAt: c.now().Unix()})
+	if err != nil {
+		// payload only holds strings, numbers and booleans so marshaling can not fail.
+		panic(fmt.Sprintf("in cursor.Encode: marshal payload: %v", err))
+	}
+
+	body := base64.RawURLEncoding.EncodeToString(data)
+	return body + "." + base64.RawURLEncoding.EncodeToString(c.sign(body))
+}
+
+// Decode verifies token and returns the Key it holds. ErrInvalid is returned if the token has been
+// tampered with and ErrExpired is returned if it is older than the Codec's time to live.
+func (c Codec) Decode(token string) (Key, error) {
+	body, signature, found := strings.Cut(token, ".")
+	if !found {
+		return Key{}, fmt.Errorf("in cursor.Decode: missing signature: %w", ErrInvalid)
+	}
+
+	sig, err := base64.RawURLEncoding.DecodeString(signature)
+	if err != nil || !hmac.Equal(sig, c.sign(body)) {
+		return Key{}, fmt.Errorf("in cursor.Decode: signature mismatch: %w", ErrInvalid)
+	}
+
+	data, err := base64.RawURLEncoding.DecodeString(body)
+	if err != nil {
+		return Key{}, fmt.Errorf("in cursor.Decode: decode body: %w", ErrInvalid)
+	}
+
+	var p payload
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.DisallowUnknownFields()
+	if err = decoder.Decode(&p); err != nil {
+		return Key{}, fmt.Errorf("in cursor.Decode: unmarshal body: %w", ErrInvalid)
+	}
+
+	if c.ttl > 0 && c.now().Sub(time.Unix(p.IssuedAt, 0)) > c.ttl {
+		return Key{}, fmt.Errorf("in cursor.Decode: issued at %d: %w", p.IssuedAt, ErrExpired)
+	}
+
+	return p.Key, nil
+}
+
+// sign returns the HMAC-SHA256 signature of body.
+func (c Codec) sign(body string) []byte {
+	mac := hmac.New(sha256.New, c.secret)
+	mac.Write([]byte(body))
+	return mac.Sum(nil)
+}
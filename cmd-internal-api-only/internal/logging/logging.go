@@ -0,0 +1,46 @@
+package logging
+
+import (
+	"context"
+	"log/slog"
+)
+
+// RequestIDHeader is the header the ID of a request is read from, and echoed in on its response.
+const RequestIDHeader = "X-Request-ID"
+
+type (
+	loggerKey    struct{}
+	requestIDKey struct{}
+)
+
+// NewContext returns a copy of ctx carrying logger as the logger of its request.
+func NewContext(ctx context.Context, logger *slog.Logger) context.Context {
+	return context.WithValue(ctx, loggerKey{}, logger)
+}
+
+// FromContext returns the logger of the request of ctx, and whether ctx carries one.
+func FromContext(ctx context.Context) (*slog.Logger, bool) {
+	logger, ok := ctx.Value(loggerKey{}).(*slog.Logger)
+	return logger, ok
+}
+
+// With returns a copy of ctx whose request logger logs args with every line, such as the user of
+// the request once it is known. ctx is returned as is if it carries no logger.
+func With(ctx context.Context, args ...any) context.Context {
+	logger, ok := FromContext(ctx)
+	if !ok {
+		return ctx
+	}
+	return NewContext(ctx, logger.With(args...))
+}
+
+// WithRequestID returns a copy of ctx carrying id as the ID of its request.
+func WithRequestID(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, requestIDKey{}, id)
+}
+
+// RequestID returns the ID of the request of ctx, or an empty string if it has none.
+func RequestID(ctx context.Context) string {
+	id, _ := ctx.Value(requestIDKey{}).(string)
+	return id
+}
@@ -0,0 +1,57 @@
+package middleware
+
+import (
+	"errors"
+	"log/slog"
+	"net/http"
+	"slices"
+	"strings"
+
+	"github.com/jha-captech/user-microservice/internal/auth"
+	"github.com/jha-captech/user-microservice/internal/problem"
+)
+
+type AuthOptions struct {
+	Verifier *auth.Verifier
+	// PublicPaths are served without a token, such as the health check.
+	PublicPaths []string
+}
+
+// AuthMiddleware refuses requests without a bearer token that opts.Verifier accepts, and puts the
+// claims of the token into the request context, where they can be read with auth.FromContext.
+func AuthMiddleware(logger *slog.Logger, opts AuthOptions) Middleware {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			logger := requestLogger(r, logger)
+
+			if slices.Contains(opts.PublicPaths, r.URL.Path) {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			scheme, token, ok := strings.Cut(r.Header.Get("Authorization"), " ")
+			token = strings.TrimSpace(token)
+			if !ok || !strings.EqualFold(scheme, "Bearer") || token == "" {
+				logger.Error("request without bearer token", "path", r.URL.Path)
+				w.Header().Set("WWW-Authenticate", "Bearer")
+				_ = problem.Write(w, r, problem.New(http.StatusUnauthorized, "A bearer token is required"))
+				return
+			}
+
+			claims, err := opts.Verifier.Verify(r.Context(), token)
+			if err != nil {
+				if errors.Is(err, auth.ErrInvalidToken) {
+					logger.Error("invalid bearer token", "err", err)
+					w.Header().Set("WWW-Authenticate", `Bearer error="invalid_token"`)
+					_ = problem.Write(w, r, problem.New(http.StatusUnauthorized, "The bearer token is not valid"))
+					return
+				}
+				logger.Error("error verifying bearer token", "err", err)
+				_ = problem.Write(w, r, problem.New(http.StatusServiceUnavailable, "Unable to verify the bearer token"))
+				return
+			}
+
+			next.ServeHTTP(w, r.WithContext(auth.WithClaims(r.Context(), claims)))
+		})
+	}
+}
@@ -0,0 +1,61 @@
+package middleware
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"net/http"
+
+	"github.com/jha-captech/user-microservice/internal/auth"
+	"github.com/jha-captech/user-microservice/internal/logging"
+	"github.com/jha-captech/user-microservice/internal/models"
+	"github.com/jha-captech/user-microservice/internal/policy"
+	"github.com/jha-captech/user-microservice/internal/problem"
+	"github.com/jha-captech/user-microservice/internal/user"
+)
+
+type PrincipalFetcher interface {
+	FetchUserByUserID(ctx context.Context, userID uint) (models.User, error)
+}
+
+// AuthorizeMiddleware identifies the user making a request by the user_id claim of the token that
+// authenticated it, and puts them into the request context as a policy.Principal with the rule
+// that rules has for their role. Requests whose token has no user_id claim, or names a user that
+// does not exist, are refused. Requests without claims, such as those to public paths, are passed on
+// without a principal.
+func AuthorizeMiddleware(logger *slog.Logger, users PrincipalFetcher, rules policy.Policy) Middleware {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			logger := requestLogger(r, logger)
+
+			claims, ok := auth.FromContext(r.Context())
+			if !ok {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			userID, ok := claims.UserID()
+			if !ok {
+				logger.Error("token without user_id claim", "subject", claims.Subject)
+				_ = problem.Write(w, r, problem.New(http.StatusForbidden, "The bearer token does not identify a user"))
+				return
+			}
+
+			found, err := users.FetchUserByUserID(r.Context(), userID)
+			if err != nil {
+				if errors.Is(err, user.ErrUserNotFound) {
+					logger.Error("token for unknown user", "subject", claims.Subject, "user_id", userID)
+					_ = problem.Write(w, r, problem.New(http.StatusForbidden, "The bearer token does not identify a user"))
+					return
+				}
+				logger.Error("error getting user of token", "err", err)
+				_ = problem.Write(w, r, problem.New(http.StatusInternalServerError, "Internal server error"))
+				return
+			}
+
+			principal := rules.Principal(found.UserID, found.Role)
+			ctx := logging.With(r.Context(), "user_id", found.UserID)
+			next.ServeHTTP(w, r.WithContext(policy.WithPrincipal(ctx, principal)))
+		})
+	}
+}
@@ -2,6 +2,7 @@ package middleware
 
 import (
 	"net/http"
+	"slices"
 	"strings"
 )
 
@@ -25,7 +26,15 @@ func CORSMiddleware(opts CORSOptions) Middleware {
 
 	return func(next http.Handler) http.Handler {
 		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-			w.Header().Set("Access-Control-Allow-Origin", strings.Join(opts.AllowedOrigins, ","))
+			// a response can only allow a single origin, so the origin of the request is echoed if it is
+			// allowed
+			switch origin := r.Header.Get("Origin"); {
+			case slices.Contains(opts.AllowedOrigins, "*"):
+				w.Header().Set("Access-Control-Allow-Origin", "*")
+			case slices.Contains(opts.AllowedOrigins, origin):
+				w.Header().Set("Access-Control-Allow-Origin", origin)
+				w.Header().Add("Vary", "Origin")
+			}
 			w.Header().Set("Access-Control-Allow-Methods", strings.Join(opts.AllowedMethods, ","))
 			w.Header().Set("Access-Control-Allow-Headers", strings.Join(opts.AllowedHeaders, ","))
 
@@ -28,7 +28,7 @@ func LoggerMiddleware(logger *slog.Logger) Middleware {
 
 			next.ServeHTTP(wrapped, r)
 
-			logger.Info(
+			requestLogger(r, logger).Info(
 				"Incoming request",
 				"status code",
 				wrapped.statusCode,
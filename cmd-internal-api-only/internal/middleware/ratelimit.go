@@ -0,0 +1,90 @@
+package middleware
+
+import (
+	"log/slog"
+	"math"
+	"net"
+	"net/http"
+	"slices"
+	"strconv"
+	"time"
+
+	"github.com/jha-captech/user-microservice/internal/auth"
+	"github.com/jha-captech/user-microservice/internal/problem"
+	"github.com/jha-captech/user-microservice/internal/ratelimit"
+)
+
+type RateLimitOptions struct {
+	// Store keeps the token buckets of clients, such as a ratelimit.MemoryStore.
+	Store  ratelimit.Store
+	Limits ratelimit.Limits
+	// UnlimitedPaths are not rate limited, such as the health check.
+	UnlimitedPaths []string
+}
+
+// RateLimitMiddleware limits the requests of each client with the token buckets of opts.Limits,
+// keyed by the method and the pattern of mux that a request matches. Clients are told apart by the
+// subject of the token that authenticated them or, without one, by their IP, so it has to come after
+// AuthMiddleware. Every response carries the RateLimit-Limit, RateLimit-Remaining and RateLimit-Reset
+// headers of the bucket it took from, and requests finding it empty are refused with 429 Too Many
+// Requests and a Retry-After header. If the store fails, requests are let through, so the limiter
+// can not take the service down.
+func RateLimitMiddleware(logger *slog.Logger, mux *http.ServeMux, opts RateLimitOptions) Middleware {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			logger := requestLogger(r, logger)
+
+			if slices.Contains(opts.UnlimitedPaths, r.URL.Path) {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			limit, bucket := opts.Limits.Default, rateLimitKey(r)
+			route := r.Method + " " + muxRoute(mux, r)
+			if routeLimit, ok := opts.Limits.Routes[route]; ok {
+				limit, bucket = routeLimit, bucket+" "+route
+			}
+			if limit.Requests == 0 {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			result, err := opts.Store.Take(r.Context(), bucket, limit)
+			if err != nil {
+				logger.Error("error taking rate limit token, request let through", "bucket", bucket, "err", err)
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			w.Header().Set("RateLimit-Limit", strconv.Itoa(result.Limit))
+			w.Header().Set("RateLimit-Remaining", strconv.Itoa(result.Remaining))
+			w.Header().Set("RateLimit-Reset", ceilSeconds(result.Reset))
+			if !result.Allowed {
+				logger.Warn("rate limit exceeded", "bucket", bucket, "path", r.URL.Path)
+				w.Header().Set("Retry-After", ceilSeconds(result.RetryAfter))
+				_ = problem.Write(w, r, problem.New(http.StatusTooManyRequests, "Too many requests, retry later"))
+				return
+			}
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// rateLimitKey returns the client a request is made by: the subject of the token that authenticated
+// it or, for requests without one, the client IP.
+func rateLimitKey(r *http.Request) string {
+	if claims, ok := auth.FromContext(r.Context()); ok && claims.Subject != "" {
+		return "subject:" + claims.Subject
+	}
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		host = r.RemoteAddr
+	}
+	return "ip:" + host
+}
+
+// ceilSeconds returns d in whole seconds, rounded up, as a header value.
+func ceilSeconds(d time.Duration) string {
+	return strconv.Itoa(int(math.Ceil(d.Seconds())))
+}
@@ -0,0 +1,77 @@
+package middleware
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"log/slog"
+	"net/http"
+
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/jha-captech/user-microservice/internal/logging"
+)
+
+// maxRequestIDLength is the maximum length of a request ID taken from a request header, longer IDs
+// are replaced.
+const maxRequestIDLength = 128
+
+// RequestIDMiddleware identifies every request and puts a logger into its context that logs the
+// request ID, the trace ID and the pattern of mux that the request matches with every line. The ID
+// is taken from the X-Request-ID header of the request, or else generated, and is echoed in the
+// X-Request-ID header of the response. It has to come after TracingMiddleware for the trace ID to be
+// known.
+func RequestIDMiddleware(logger *slog.Logger, mux *http.ServeMux) Middleware {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			ctx := r.Context()
+
+			id := r.Header.Get(logging.RequestIDHeader)
+			if !validRequestID(id) {
+				id = newRequestID()
+			}
+			w.Header().Set(logging.RequestIDHeader, id)
+			ctx = logging.WithRequestID(ctx, id)
+
+			args := []any{"request_id", id}
+			if spanContext := trace.SpanContextFromContext(ctx); spanContext.HasTraceID() {
+				args = append(args, "trace_id", spanContext.TraceID().String())
+			}
+			if route := muxRoute(mux, r); route != "" {
+				args = append(args, "route", route)
+			}
+
+			next.ServeHTTP(w, r.WithContext(logging.NewContext(ctx, logger.With(args...))))
+		})
+	}
+}
+
+// requestLogger returns the logger that RequestIDMiddleware put into the context of r, or logger
+// if r has none.
+func requestLogger(r *http.Request, logger *slog.Logger) *slog.Logger {
+	if requestLogger, ok := logging.FromContext(r.Context()); ok {
+		return requestLogger
+	}
+	return logger
+}
+
+// newRequestID returns a new random request ID.
+func newRequestID() string {
+	// crypto/rand.Read never fails
+	b := make([]byte, 16)
+	_, _ = rand.Read(b)
+	return hex.EncodeToString(b)
+}
+
+// validRequestID reports whether id can be used as a request ID: it is not empty, not too long and
+// only has printable ASCII characters other than spaces, so it can not forge log lines.
+func validRequestID(id string) bool {
+	if id == "" || len(id) > maxRequestIDLength {
+		return false
+	}
+	for i := 0; i < len(id); i++ {
+		if id[i] <= ' ' || id[i] > '~' {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,73 @@
+package middleware
+
+import (
+	"net/http"
+	"strings"
+
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// TracingMiddleware serves every request in a span of provider. The span continues the trace of
+// the W3C traceparent header of the request, unless the request context already has a span, such as
+// that of a Lambda invocation. It is named after the method and the pattern of mux that the request
+// matches, and marked as failed if the response status is 5xx.
+func TracingMiddleware(provider trace.TracerProvider, mux *http.ServeMux) Middleware {
+	tracer := provider.Tracer("github.com/jha-captech/user-microservice/internal/middleware")
+	propagator := propagation.TraceContext{}
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			ctx := r.Context()
+			kind := trace.SpanKindInternal
+			if !trace.SpanContextFromContext(ctx).IsValid() {
+				ctx = propagator.Extract(ctx, propagation.HeaderCarrier(r.Header))
+				kind = trace.SpanKindServer
+			}
+
+			name := r.Method
+			route := muxRoute(mux, r)
+			if route != "" {
+				name += " " + route
+			}
+
+			ctx, span := tracer.Start(
+				ctx,
+				name,
+				trace.WithSpanKind(kind),
+				trace.WithAttributes(
+					semconv.HTTPRequestMethodKey.String(r.Method),
+					semconv.URLPath(r.URL.Path),
+				),
+			)
+			defer span.End()
+			if route != "" {
+				span.SetAttributes(semconv.HTTPRoute(route))
+			}
+
+			wrapped := &wrappedWriter{
+				ResponseWriter: w,
+				statusCode:     http.StatusOK,
+			}
+
+			next.ServeHTTP(wrapped, r.WithContext(ctx))
+
+			span.SetAttributes(semconv.HTTPResponseStatusCode(wrapped.statusCode))
+			if wrapped.statusCode >= http.StatusInternalServerError {
+				span.SetStatus(codes.Error, http.StatusText(wrapped.statusCode))
+			}
+		})
+	}
+}
+
+// muxRoute returns the path of the pattern of mux that r matches, such as `/api/user/{id}`, or an
+// empty string if it matches none.
+func muxRoute(mux *http.ServeMux, r *http.Request) string {
+	_, pattern := mux.Handler(r)
+	if i := strings.Index(pattern, "/"); i >= 0 {
+		return pattern[i:]
+	}
+	return ""
+}
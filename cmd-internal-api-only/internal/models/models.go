@@ -1,9 +1,26 @@
 package models
 
+// User is a user of the service. Fields tagged `pii:"true"` hold personal data, which
+// logging.RedactHandler keeps out of logs.
 type User struct {
 	ID        uint   `json:"id,omitempty"`
-	FirstName string `json:"first_name,omitempty"`
-	LastName  string `json:"last_name,omitempty"`
+	FirstName string `json:"first_name,omitempty" pii:"true"`
+	LastName  string `json:"last_name,omitempty" pii:"true"`
 	Role      string `json:"role,omitempty"`
 	UserID    uint   `json:"user_id,omitempty"`
 }
+
+// ListUsersParams holds the paging options used when listing users. Users are ordered by ID and,
+// when Cursor is set, the page starts directly after (or before) the user the cursor points to.
+type ListUsersParams struct {
+	Limit  int
+	Cursor string
+}
+
+// UserPage is a single page of users. NextCursor and PrevCursor are opaque tokens pointing to the
+// adjacent pages and are empty when there is no such page.
+type UserPage struct {
+	Users      []User `json:"users"`
+	NextCursor string `json:"next_cursor,omitempty"`
+	PrevCursor string `json:"prev_cursor,omitempty"`
+}
@@ -0,0 +1,108 @@
+package problem
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sort"
+
+	"github.com/jha-captech/user-microservice/internal/logging"
+)
+
+// ContentType is the media type of a problem details response as defined by RFC 7807.
+const ContentType = "application/problem+json"
+
+// RequestIDHeader is the request header read for the request ID when none has been set on the
+// request context by middleware.RequestIDMiddleware.
+const RequestIDHeader = "X-Request-Id"
+
+// FieldError is a single violation of a field in a request body or query.
+type FieldError struct {
+	Field  string `json:"field"`
+	Detail string `json:"detail"`
+}
+
+// Problem is an RFC 7807 problem details object.
+type Problem struct {
+	Type      string       `json:"type"`
+	Title     string       `json:"title"`
+	Status    int          `json:"status"`
+	Detail    string       `json:"detail,omitempty"`
+	Instance  string       `json:"instance,omitempty"`
+	Errors    []FieldError `json:"errors,omitempty"`
+	RequestID string       `json:"request_id,omitempty"`
+}
+
+// New returns a Problem for status with a human-readable detail. The type is "about:blank" and the
+// title is the standard text of status, as recommended by RFC 7807 for problems that have no more
+// specific type.
+func New(status int, detail string) Problem {
+	return Problem{
+		Type:   "about:blank",
+		Title:  http.StatusText(status),
+		Status: status,
+		Detail: detail,
+	}
+}
+
+// WithFieldErrors returns a copy of p with the given field violations, keyed by field name, added
+// to Errors. Errors are sorted by field so that responses are stable.
+func (p Problem) WithFieldErrors(problems map[string]string) Problem {
+	errs := make([]FieldError, 0, len(p.Errors)+len(problems))
+	errs = append(errs, p.Errors...)
+	for field, detail := range problems {
+		errs = append(errs, FieldError{Field: field, Detail: detail})
+	}
+	sort.SliceStable(errs, func(i, j int) bool { return errs[i].Field < errs[j].Field })
+
+	p.Errors = errs
+	return p
+}
+
+// WithInstance returns a copy of p with Instance set to instance.
+func (p Problem) WithInstance(instance string) Problem {
+	p.Instance = instance
+	return p
+}
+
+// WithRequestID returns a copy of p with RequestID set to requestID.
+func (p Problem) WithRequestID(requestID string) Problem {
+	p.RequestID = requestID
+	return p
+}
+
+// Write writes p to w as an application/problem+json response. If not already set, Instance is
+// set to the path of r and RequestID to the ID of r. r may be nil when there is no request to take
+// these from.
+func Write(w http.ResponseWriter, r *http.Request, p Problem) error {
+	if r != nil {
+		if p.Instance == "" {
+			p.Instance = r.URL.Path
+		}
+		if p.RequestID == "" {
+			p.RequestID = RequestID(r)
+		}
+	}
+
+	body, err := json.Marshal(p)
+	if err != nil {
+		return fmt.Errorf("in problem.Write: marshal: %w", err)
+	}
+
+	w.Header().Set("Content-Type", ContentType)
+	w.WriteHeader(p.Status)
+	if _, err = w.Write(append(body, '\n')); err != nil {
+		return fmt.Errorf("in problem.Write: write: %w", err)
+	}
+
+	return nil
+}
+
+// RequestID returns the ID of r set by middleware.RequestIDMiddleware or, if there is none, the
+// value of the RequestIDHeader header.
+func RequestID(r *http.Request) string {
+	if requestID := logging.RequestID(r.Context()); requestID != "" {
+		return requestID
+	}
+	return r.Header.Get(RequestIDHeader)
+}
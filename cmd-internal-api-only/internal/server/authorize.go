@@ -0,0 +1,115 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/jha-captech/user-microservice/internal/models"
+	"github.com/jha-captech/user-microservice/internal/policy"
+	"github.com/jha-captech/user-microservice/internal/problem"
+	"github.com/jha-captech/user-microservice/internal/user"
+)
+
+// permit wraps next so that requests whose principal may not take action are refused. On routes of
+// a single user, an action the principal may only take on their own record is refused if the user
+// belongs to someone else. Requests without a principal, which are only served when authentication
+// is disabled, are let through.
+func (h *Handler) permit(action policy.Action, next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		principal, ok := policy.FromContext(r.Context())
+		if !ok {
+			next(w, r)
+			return
+		}
+
+		scope, err := principal.Scope(action)
+		if err == nil && scope == policy.ScopeOwn {
+			owner, ok, ownerErr := h.userOwner(r.Context(), r.PathValue("id"))
+			if ownerErr != nil {
+				h.requestLogger(r).Error("error getting object by ID", "error", ownerErr)
+				h.encodeProblem(w, r, problem.New(http.StatusInternalServerError, "Internal server error"))
+				return
+			}
+			if !ok {
+				// the handler reports the ID as invalid or the user as not existing
+				next(w, r)
+				return
+			}
+			err = principal.Authorize(action, owner)
+		}
+		if err != nil {
+			h.requestLogger(r).Error("request forbidden by policy", "path", r.URL.Path, "user_id", principal.UserID, "error", err)
+			h.encodeProblem(w, r, forbiddenProblem(err))
+			return
+		}
+
+		next(w, r)
+	}
+}
+
+// userOwner returns the user_id of the user with the ID idString, and false if idString is not a
+// valid ID or no such user exists. Routes of a collection of users have no ID, and their owner is
+// 0, which is never the user_id of a principal.
+func (h *Handler) userOwner(ctx context.Context, idString string) (uint, bool, error) {
+	if idString == "" {
+		return 0, true, nil
+	}
+
+	ID, err := strconv.Atoi(idString)
+	if err != nil {
+		return 0, false, nil
+	}
+
+	found, err := h.userService.FetchUser(ctx, ID)
+	if err != nil {
+		if errors.Is(err, user.ErrUserNotFound) {
+			return 0, false, nil
+		}
+		return 0, false, err
+	}
+
+	return found.UserID, true, nil
+}
+
+// authorizeChanges returns a *policy.ForbiddenError if updated changes a field of current that is
+// read-only for the principal of ctx. Without a principal, every change is allowed.
+func authorizeChanges(ctx context.Context, current, updated models.User) error {
+	principal, ok := policy.FromContext(ctx)
+	if !ok {
+		return nil
+	}
+
+	var fields []string
+	if updated.FirstName != current.FirstName {
+		fields = append(fields, "first_name")
+	}
+	if updated.LastName != current.LastName {
+		fields = append(fields, "last_name")
+	}
+	if updated.Role != current.Role {
+		fields = append(fields, "role")
+	}
+	if updated.UserID != current.UserID {
+		fields = append(fields, "user_id")
+	}
+	return principal.AuthorizeChanges(fields)
+}
+
+// forbiddenProblem returns the problem reported for err, a *policy.ForbiddenError.
+func forbiddenProblem(err error) problem.Problem {
+	var forbidden *policy.ForbiddenError
+	if !errors.As(err, &forbidden) {
+		return problem.New(http.StatusForbidden, "Forbidden")
+	}
+
+	switch {
+	case forbidden.Field != "":
+		return problem.New(http.StatusForbidden, "You are not allowed to change "+forbidden.Field)
+	case forbidden.Owner:
+		return problem.New(http.StatusForbidden, "You are only allowed to "+string(forbidden.Action)+" your own user")
+	default:
+		return problem.New(http.StatusForbidden, "You are not allowed to "+string(forbidden.Action)+" users")
+	}
+}
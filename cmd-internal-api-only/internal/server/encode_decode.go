@@ -4,15 +4,28 @@ import (
 	"encoding/json"
 	"fmt"
 	"net/http"
+
+	"github.com/jha-captech/user-microservice/internal/problem"
 )
 
-// encodeResponse encodes a struct of type T as a JSON response.
+// encodeResponse encodes a struct of type T as a JSON response. If data can not be encoded, an
+// internal server error problem is written instead.
 func encodeResponse[T any](w http.ResponseWriter, status int, data T) {
+	body, err := json.Marshal(data)
+	if err != nil {
+		_ = problem.Write(w, nil, problem.New(http.StatusInternalServerError, "Internal error"))
+		return
+	}
+
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(status)
-	if err := json.NewEncoder(w).Encode(data); err != nil {
-		http.Error(w, "Internal error", http.StatusInternalServerError)
-		return
+	_, _ = w.Write(append(body, '\n'))
+}
+
+// encodeProblem encodes p as an application/problem+json response for r.
+func (h *Handler) encodeProblem(w http.ResponseWriter, r *http.Request, p problem.Problem) {
+	if err := problem.Write(w, r, p); err != nil {
+		h.requestLogger(r).Error("error writing problem", "error", err, "problem", p)
 	}
 }
 
@@ -2,20 +2,35 @@ package server
 
 import (
 	"log/slog"
+	"net/http"
 
+	"github.com/jha-captech/user-microservice/internal/health"
+	"github.com/jha-captech/user-microservice/internal/logging"
 	"github.com/jha-captech/user-microservice/internal/user"
 )
 
 // ── Handler Struct And Constructor ───────────────────────────────────────────────────────────────
 
 type Handler struct {
-	logger      *slog.Logger
-	userService user.Service
+	logger        *slog.Logger
+	userService   user.Service
+	healthChecker *health.Checker
 }
 
-func NewHandler(logger *slog.Logger, us user.Service) Handler {
+func NewHandler(logger *slog.Logger, us user.Service, checker *health.Checker) Handler {
 	return Handler{
-		logger:      logger,
-		userService: us,
+		logger:        logger,
+		userService:   us,
+		healthChecker: checker,
 	}
 }
+
+// requestLogger returns the logger that middleware.RequestIDMiddleware put into the context of r,
+// which logs the request ID, trace ID, route and user of r with every line, or the logger of h if r
+// has none.
+func (h *Handler) requestLogger(r *http.Request) *slog.Logger {
+	if logger, ok := logging.FromContext(r.Context()); ok {
+		return logger
+	}
+	return h.logger
+}
@@ -1,10 +1,31 @@
 package server
 
-import "net/http"
+import (
+	"net/http"
 
-func (h *Handler) handleHealthCheck() http.HandlerFunc {
+	"github.com/jha-captech/user-microservice/internal/health"
+)
+
+// handleLiveness reports the service as alive whenever it can serve a request at all. It does not
+// check any dependency, so a database outage does not get the service restarted.
+func (h *Handler) handleLiveness() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		encodeResponse(w, http.StatusOK, health.Report{Status: health.StatusOK})
+	}
+}
+
+// handleReadiness reports whether the service is ready to serve requests, with the health of each
+// of its components. A service that is not ready, including one that is draining on shutdown,
+// responds with 503 Service Unavailable.
+func (h *Handler) handleReadiness() http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
-		h.logger.Info("Health check called")
-		encodeResponse(w, http.StatusOK, map[string]string{"message": "hello world"})
+		report := h.healthChecker.Ready(r.Context())
+		if report.Status != health.StatusOK {
+			h.requestLogger(r).Warn("Service not ready", "report", report)
+			encodeResponse(w, http.StatusServiceUnavailable, report)
+			return
+		}
+
+		encodeResponse(w, http.StatusOK, report)
 	}
 }
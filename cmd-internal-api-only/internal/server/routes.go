@@ -2,13 +2,16 @@ package server
 
 import (
 	"net/http"
+
+	"github.com/jha-captech/user-microservice/internal/policy"
 )
 
 func RegisterRoutes(mux *http.ServeMux, h Handler) {
-	mux.HandleFunc("GET /api/health-check", h.handleHealthCheck())
-	mux.HandleFunc("GET /api/user", h.handleListUsers())
-	mux.HandleFunc("GET /api/user/{id}", h.handleFetchUser())
-	mux.HandleFunc("PUT /api/user/{id}", h.handleUpdateUser())
-	mux.HandleFunc("POST /api/user", h.handleCreateUser())
-	mux.HandleFunc("DELETE /api/user/{id}", h.handleDeleteUser())
+	mux.HandleFunc("GET /api/health/live", h.handleLiveness())
+	mux.HandleFunc("GET /api/health/ready", h.handleReadiness())
+	mux.HandleFunc("GET /api/user", h.permit(policy.ActionList, h.handleListUsers()))
+	mux.HandleFunc("GET /api/user/{id}", h.permit(policy.ActionRead, h.handleFetchUser()))
+	mux.HandleFunc("PUT /api/user/{id}", h.permit(policy.ActionUpdate, h.handleUpdateUser()))
+	mux.HandleFunc("POST /api/user", h.permit(policy.ActionCreate, h.handleCreateUser()))
+	mux.HandleFunc("DELETE /api/user/{id}", h.permit(policy.ActionDelete, h.handleDeleteUser()))
 }
@@ -0,0 +1,101 @@
+package tracing
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/exporters/stdout/stdouttrace"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+)
+
+// instrumentationName is the name of the tracer that the spans of this package are started with.
+const instrumentationName = "github.com/jha-captech/user-microservice/internal/tracing"
+
+// The exporters that spans can be sent to.
+const (
+	// ExporterOTLP sends spans to an OpenTelemetry collector over OTLP/HTTP. The collector is set
+	// with the standard OTEL_EXPORTER_OTLP_ENDPOINT and OTEL_EXPORTER_OTLP_HEADERS variables.
+	ExporterOTLP = "otlp"
+	// ExporterStdout writes spans to stdout as JSON.
+	ExporterStdout = "stdout"
+	// ExporterNone drops spans. Trace context is still propagated.
+	ExporterNone = "none"
+)
+
+// ErrUnknownExporter is returned when an exporter name is not one of the known exporters.
+var ErrUnknownExporter = errors.New("unknown exporter")
+
+type Option func(*providerOptions)
+
+type providerOptions struct {
+	sampleRatio float64
+}
+
+// WithSampleRatio sets the share of traces that are sampled, between 0 and 1. Traces that a caller
+// already sampled or dropped keep that decision. If this function is not called, the default is 1,
+// so every trace is sampled.
+func WithSampleRatio(ratio float64) Option {
+	return func(options *providerOptions) {
+		options.sampleRatio = ratio
+	}
+}
+
+// New returns a TracerProvider that sends the spans of serviceName to the exporter with the given
+// name, which is one of ExporterOTLP, ExporterStdout or ExporterNone. An empty name is the same as
+// ExporterNone. The provider must be shut down on exit, so spans that are still buffered are sent.
+func New(ctx context.Context, exporter string, serviceName string, opts ...Option) (*sdktrace.TracerProvider, error) {
+	spanExporter, err := NewExporter(ctx, exporter)
+	if err != nil {
+		return nil, fmt.Errorf("in tracing.New: %w", err)
+	}
+
+	return NewProvider(spanExporter, serviceName, opts...), nil
+}
+
+// NewExporter returns the span exporter with the given name, or nil for ExporterNone.
+func NewExporter(ctx context.Context, name string) (sdktrace.SpanExporter, error) {
+	switch strings.ToLower(strings.TrimSpace(name)) {
+	case ExporterOTLP:
+		exporter, err := otlptracehttp.New(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("in NewExporter: %q: %w", name, err)
+		}
+		return exporter, nil
+	case ExporterStdout:
+		exporter, err := stdouttrace.New()
+		if err != nil {
+			return nil, fmt.Errorf("in NewExporter: %q: %w", name, err)
+		}
+		return exporter, nil
+	case ExporterNone, "":
+		return nil, nil
+	default:
+		return nil, fmt.Errorf("in NewExporter: %q: %w", name, ErrUnknownExporter)
+	}
+}
+
+// NewProvider returns a TracerProvider that sends the spans of serviceName to exporter in batches.
+// If exporter is nil, spans are started and propagated but not sent anywhere.
+func NewProvider(exporter sdktrace.SpanExporter, serviceName string, opts ...Option) *sdktrace.TracerProvider {
+	options := providerOptions{
+		sampleRatio: 1,
+	}
+	for _, opt := range opts {
+		opt(&options)
+	}
+
+	providerOpts := []sdktrace.TracerProviderOption{
+		sdktrace.WithResource(resource.NewWithAttributes(semconv.SchemaURL, semconv.ServiceName(serviceName))),
+		sdktrace.WithSampler(sdktrace.ParentBased(sdktrace.TraceIDRatioBased(options.sampleRatio))),
+	}
+	if exporter != nil {
+		providerOpts = append(providerOpts, sdktrace.WithBatcher(exporter))
+	}
+
+	return sdktrace.NewTracerProvider(providerOpts...)
+}
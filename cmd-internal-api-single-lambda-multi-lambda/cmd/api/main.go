@@ -5,9 +5,11 @@ import (
 	"errors"
 	"fmt"
 	"log"
+	"log/slog"
 	"net/http"
 	"os"
 	"os/signal"
+	"sync"
 	"syscall"
 	"time"
 
@@ -17,10 +19,21 @@ import (
 	"github.com/go-chi/httplog/v2"
 	"github.com/jha-captech/user-microservice/internal/swagger"
 
+	"github.com/jha-captech/user-microservice/internal/auth"
 	"github.com/jha-captech/user-microservice/internal/config"
 	"github.com/jha-captech/user-microservice/internal/database"
+	"github.com/jha-captech/user-microservice/internal/handlers"
+	"github.com/jha-captech/user-microservice/internal/health"
+	"github.com/jha-captech/user-microservice/internal/logging"
+	"github.com/jha-captech/user-microservice/internal/metrics"
+	"github.com/jha-captech/user-microservice/internal/outbox"
+	"github.com/jha-captech/user-microservice/internal/policy"
+	"github.com/jha-captech/user-microservice/internal/ratelimit"
 	"github.com/jha-captech/user-microservice/internal/routes"
 	"github.com/jha-captech/user-microservice/internal/service"
+	"github.com/jha-captech/user-microservice/internal/stream"
+	"github.com/jha-captech/user-microservice/internal/tracing"
+	"github.com/jha-captech/user-microservice/internal/webhook"
 )
 
 func main() {
@@ -42,18 +55,52 @@ func run() error {
 		Concise:         true,
 		ResponseHeaders: false,
 	})
+	if cfg.Log.RedactPII {
+		redact, err := logging.NewRedactHandler(logger.Handler(), cfg.Log.RedactMode, []byte(cfg.Log.RedactSecret), cfg.Log.RedactKeys)
+		if err != nil {
+			return fmt.Errorf("[in run]: %w", err)
+		}
+		logger.Logger = slog.New(redact)
+	}
+
+	provider, err := tracing.New(
+		context.Background(),
+		cfg.Tracing.Exporter,
+		cfg.Tracing.ServiceName,
+		tracing.WithSampleRatio(cfg.Tracing.SampleRatio),
+	)
+	if err != nil {
+		return fmt.Errorf("[in run]: %w", err)
+	}
+
+	defer func() {
+		// spans that are still buffered are sent before exiting
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		if err := provider.Shutdown(shutdownCtx); err != nil {
+			logger.Error("Error shutting down tracer provider", "err", err)
+		}
+	}()
 
+	migrateOption := database.WithSchemaCheck()
+	if cfg.Database.MigrateOnStartup {
+		migrateOption = database.WithMigrations()
+	}
+
+	dsn := fmt.Sprintf(
+		"host=%s user=%s password=%s dbname=%s port=%s sslmode=disable",
+		cfg.Database.Host,
+		cfg.Database.User,
+		cfg.Database.Password,
+		cfg.Database.Name,
+		cfg.Database.Port,
+	)
 	db, err := database.New(
-		fmt.Sprintf(
-			"host=%s user=%s password=%s dbname=%s port=%s sslmode=disable",
-			cfg.Database.Host,
-			cfg.Database.User,
-			cfg.Database.Password,
-			cfg.Database.Name,
-			cfg.Database.Port,
-		),
+		dsn,
 		logger,
 		cfg.Database.ConnectionRetry,
+		migrateOption,
+		database.WithTracing(provider),
 	)
 	if err != nil {
 		return fmt.Errorf("[in run]: %w", err)
@@ -64,19 +111,128 @@ func run() error {
 			logger.Error("Error closing db connection", "err", err)
 		}
 	}()
+	if cfg.Database.MaxOpenConnections > 0 {
+		db.SetMaxOpenConns(cfg.Database.MaxOpenConnections)
+	}
+
+	migrator, err := database.NewMigrator(db, logger)
+	if err != nil {
+		return fmt.Errorf("[in run]: %w", err)
+	}
+	checker := health.NewChecker(
+		db,
+		migrator,
+		health.WithTimeout(cfg.Health.Timeout),
+		health.WithMaxPoolSaturation(cfg.Health.MaxPoolSaturation),
+	)
+
+	recorder := metrics.NewPrometheus(db, cfg.Database.Name)
 
 	r := chi.NewRouter()
 
+	r.Use(tracing.Middleware(provider, tracing.ChiRoute))
+	r.Use(metrics.Middleware(recorder, tracing.ChiRoute))
+	r.Use(logging.Middleware(logger.Logger, r))
 	r.Use(httplog.RequestLogger(logger))
 	r.Use(middleware.Recoverer)
 	r.Use(cors.Handler(cors.Options{
-		AllowedOrigins: []string{"*"},
-		AllowedMethods: []string{"GET", "PUT", "POST", "DELETE"},
+		AllowedOrigins: cfg.HTTP.CORSAllowedOrigins,
+		AllowedMethods: []string{"GET", "PUT", "PATCH", "POST", "DELETE"},
+		AllowedHeaders: []string{"Accept", "Authorization", "Content-Type", "If-Match", "If-None-Match", "Last-Event-ID", "X-Actor", "X-Admin-Token", "X-API-Key", "X-Request-ID", "traceparent", "tracestate"},
+		ExposedHeaders: []string{"ETag", "Content-Disposition", "RateLimit-Limit", "RateLimit-Remaining", "RateLimit-Reset", "Retry-After", "X-Request-ID"},
 		MaxAge:         300,
 	}))
 
-	svs := service.NewUser(db)
-	routes.RegisterRoutes(r, logger, svs, routes.WithRegisterHealthRoute(true))
+	userEvents := stream.NewBroker(cfg.Events.ReplayBuffer)
+	routeOptions := []routes.Option{
+		routes.WithHealthChecks(checker),
+		routes.WithAdminToken(cfg.AdminToken),
+		routes.WithWebhooks(service.NewWebhook(db)),
+		routes.WithAPIKeys(service.NewAPIKey(db)),
+		routes.WithUserEvents(userEvents, cfg.Events.Heartbeat),
+	}
+	if cfg.RateLimit.Disabled {
+		logger.Warn("RATE_LIMIT_DISABLED is set, requests are not rate limited")
+	} else {
+		limits, err := newRateLimits(cfg)
+		if err != nil {
+			return fmt.Errorf("[in run]: %w", err)
+		}
+		routeOptions = append(routeOptions, routes.WithRateLimit(ratelimit.NewMemoryStore(), limits))
+	}
+	if cfg.Auth.Disabled {
+		logger.Warn("AUTH_DISABLED is set, requests are not authenticated")
+	} else {
+		verifier, err := newVerifier(cfg)
+		if err != nil {
+			return fmt.Errorf("[in run]: %w", err)
+		}
+		rules, err := policy.New(cfg.Auth.PolicyFile)
+		if err != nil {
+			return fmt.Errorf("[in run]: %w", err)
+		}
+		routeOptions = append(
+			routeOptions,
+			routes.WithAuthentication(handlers.Authenticate(logger, verifier)),
+			routes.WithAuthorization(rules),
+		)
+	}
+
+	svs := service.NewUser(
+		db,
+		service.WithCursorSecret(cfg.Cursor.Secret),
+		service.WithCursorTTL(cfg.Cursor.TTL),
+		service.WithChangeCounter(recorder),
+	)
+	routes.RegisterRoutes(r, logger, svs, routeOptions...)
+
+	// Outbox relay, webhook worker and user change listener
+	backgroundCtx, stopBackground := context.WithCancel(context.Background())
+	var background sync.WaitGroup
+	defer func() {
+		stopBackground()
+		background.Wait()
+	}()
+
+	var publisher outbox.Publisher = webhook.NewPublisher(db)
+	if cfg.Outbox.WebhookURL != "" {
+		publisher = outbox.NewMultiPublisher(publisher, outbox.NewWebhookPublisher(cfg.Outbox.WebhookURL, nil))
+	} else {
+		logger.Info("OUTBOX_WEBHOOK_URL is not set, outbox events are only delivered to webhook subscriptions")
+	}
+	relay := outbox.NewRelay(
+		db,
+		publisher,
+		logger,
+		outbox.WithSource(cfg.Outbox.Source),
+		outbox.WithPollInterval(cfg.Outbox.PollInterval),
+		outbox.WithMaxAttempts(cfg.Outbox.MaxAttempts),
+	)
+	worker := webhook.NewWorker(
+		db,
+		logger,
+		webhook.WithHTTPClient(&http.Client{Timeout: cfg.Webhook.Timeout}),
+		webhook.WithPollInterval(cfg.Webhook.PollInterval),
+		webhook.WithMaxAttempts(cfg.Webhook.MaxAttempts),
+	)
+
+	listener := stream.NewListener(db, dsn, userEvents, logger)
+
+	background.Add(3)
+	go func() {
+		defer background.Done()
+		relay.Run(backgroundCtx)
+	}()
+	go func() {
+		defer background.Done()
+		worker.Run(backgroundCtx)
+	}()
+	go func() {
+		defer background.Done()
+		if err := listener.Run(backgroundCtx); err != nil {
+			logger.Error("User change listener stopped, the user event stream will not receive changes", "err", err)
+		}
+	}()
 
 	if cfg.UseSwagger {
 		swagger.RunSwagger(r, logger, cfg.HTTP.Domain+cfg.HTTP.Port)
@@ -90,6 +246,23 @@ func run() error {
 		WriteTimeout:      500 * time.Millisecond,
 		Handler:           r,
 	}
+	// event streams outlive the write timeout, so they are ended when the server shuts down
+	serverInstance.RegisterOnShutdown(userEvents.Close)
+
+	// metrics are served on their own port, so they are not exposed with the API
+	metricsMux := http.NewServeMux()
+	metricsMux.Handle("GET /metrics", recorder.Handler())
+	metricsServer := &http.Server{
+		Addr:              cfg.HTTP.Domain + cfg.Metrics.Port,
+		ReadHeaderTimeout: 500 * time.Millisecond,
+		Handler:           metricsMux,
+	}
+	go func() {
+		logger.Info(fmt.Sprintf("Metrics are served on %s", metricsServer.Addr))
+		if err := metricsServer.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			logger.Error("Metrics server stopped", "err", err)
+		}
+	}()
 
 	// Graceful shutdown
 	serverCtx, serverStopCtx := context.WithCancel(context.Background())
@@ -100,14 +273,17 @@ func run() error {
 		<-sig
 
 		fmt.Println()
-		logger.Info("Shutdown signal received")
+		logger.Info("Shutdown signal received, draining", "delay", cfg.HTTP.DrainDelay)
 
-		shutdownCtx, err := context.WithTimeout(
+		// readiness fails from here on, so load balancers stop sending requests before the server
+		// stops accepting them
+		checker.Drain()
+		time.Sleep(cfg.HTTP.DrainDelay)
+
+		shutdownCtx, cancel := context.WithTimeout(
 			serverCtx, time.Duration(cfg.HTTP.ShutdownGracePeriod)*time.Second,
 		)
-		if err != nil {
-			log.Fatalf("Error creating context.WithTimeout. err: %v", err)
-		}
+		defer cancel()
 
 		go func() {
 			<-shutdownCtx.Done()
@@ -119,6 +295,9 @@ func run() error {
 		if err := serverInstance.Shutdown(shutdownCtx); err != nil {
 			log.Fatalf("Error shutting down server. err: %v", err)
 		}
+		if err := metricsServer.Shutdown(shutdownCtx); err != nil {
+			logger.Error("Error shutting down metrics server", "err", err)
+		}
 		serverStopCtx()
 	}()
 
@@ -133,3 +312,42 @@ func run() error {
 	logger.Info("Shutdown complete")
 	return nil
 }
+
+// newVerifier returns the verifier of bearer tokens configured by cfg, which takes its keys from
+// the JWK Set at AUTH_JWKS_URL or, if that is not set, from the key file at AUTH_KEY_FILE.
+func newVerifier(cfg config.Configuration) (*auth.Verifier, error) {
+	if cfg.Auth.Issuer == "" || cfg.Auth.Audience == "" {
+		return nil, errors.New("[in newVerifier]: AUTH_ISSUER and AUTH_AUDIENCE must be set unless AUTH_DISABLED is true")
+	}
+
+	var keys auth.KeySource
+	switch {
+	case cfg.Auth.JWKSURL != "":
+		keys = auth.NewJWKS(cfg.Auth.JWKSURL, auth.WithRefreshInterval(cfg.Auth.JWKSRefresh))
+	case cfg.Auth.KeyFile != "":
+		keyFile, err := auth.LoadKeyFile(cfg.Auth.KeyFile)
+		if err != nil {
+			return nil, fmt.Errorf("[in newVerifier]: %w", err)
+		}
+		keys = keyFile
+	default:
+		return nil, errors.New("[in newVerifier]: AUTH_JWKS_URL or AUTH_KEY_FILE must be set unless AUTH_DISABLED is true")
+	}
+
+	return auth.NewVerifier(keys, cfg.Auth.Issuer, cfg.Auth.Audience, auth.WithLeeway(cfg.Auth.Leeway)), nil
+}
+
+// newRateLimits returns the rate limits configured by cfg, RATE_LIMIT_DEFAULT for every client and
+// the limits of single routes in RATE_LIMIT_ROUTES.
+func newRateLimits(cfg config.Configuration) (ratelimit.Limits, error) {
+	defaultLimit, err := ratelimit.ParseLimit(cfg.RateLimit.Default)
+	if err != nil {
+		return ratelimit.Limits{}, fmt.Errorf("[in newRateLimits] RATE_LIMIT_DEFAULT: %w", err)
+	}
+	routeLimits, err := ratelimit.ParseRouteLimits(cfg.RateLimit.Routes)
+	if err != nil {
+		return ratelimit.Limits{}, fmt.Errorf("[in newRateLimits] RATE_LIMIT_ROUTES: %w", err)
+	}
+
+	return ratelimit.Limits{Default: defaultLimit, Routes: routeLimits}, nil
+}
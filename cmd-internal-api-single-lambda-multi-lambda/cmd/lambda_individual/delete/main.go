@@ -1,19 +1,25 @@
 package main
 
 import (
+	"context"
 	"fmt"
 	"log"
 	"log/slog"
 	"os"
 
 	"github.com/aws/aws-lambda-go/lambda"
+	"github.com/aws/aws-lambda-go/lambdacontext"
 	"github.com/awslabs/aws-lambda-go-api-proxy/httpadapter"
 	"github.com/go-chi/chi/v5"
 	"github.com/go-chi/chi/v5/middleware"
 	"github.com/jha-captech/user-microservice/internal/config"
 	"github.com/jha-captech/user-microservice/internal/database"
 	"github.com/jha-captech/user-microservice/internal/handlers"
+	"github.com/jha-captech/user-microservice/internal/logging"
+	"github.com/jha-captech/user-microservice/internal/metrics"
+	"github.com/jha-captech/user-microservice/internal/policy"
 	"github.com/jha-captech/user-microservice/internal/service"
+	"github.com/jha-captech/user-microservice/internal/tracing"
 )
 
 func main() {
@@ -32,6 +38,29 @@ func run() error {
 		Level: cfg.LogLevel,
 	}))
 
+	if cfg.Log.RedactPII {
+		redact, err := logging.NewRedactHandler(logger.Handler(), cfg.Log.RedactMode, []byte(cfg.Log.RedactSecret), cfg.Log.RedactKeys)
+		if err != nil {
+			return fmt.Errorf("[in run]: %w", err)
+		}
+		logger = slog.New(redact)
+	}
+
+	provider, err := tracing.New(
+		context.Background(),
+		cfg.Tracing.Exporter,
+		cfg.Tracing.ServiceName,
+		tracing.WithSampleRatio(cfg.Tracing.SampleRatio),
+	)
+	if err != nil {
+		return fmt.Errorf("[in run]: %w", err)
+	}
+
+	migrateOption := database.WithSchemaCheck()
+	if cfg.Database.MigrateOnStartup {
+		migrateOption = database.WithMigrations()
+	}
+
 	db, err := database.New(
 		fmt.Sprintf(
 			"host=%s user=%s password=%s dbname=%s port=%s sslmode=disable",
@@ -43,6 +72,8 @@ func run() error {
 		),
 		logger,
 		cfg.Database.ConnectionRetry,
+		migrateOption,
+		database.WithTracing(provider),
 	)
 	if err != nil {
 		return fmt.Errorf("[in run]: %w", err)
@@ -54,15 +85,31 @@ func run() error {
 		}
 	}()
 
+	recorder := metrics.NewEMF(os.Stdout, cfg.Metrics.Namespace, lambdacontext.FunctionName, db)
+
 	r := chi.NewRouter()
 
+	r.Use(tracing.Middleware(provider, tracing.ChiRoute))
+	r.Use(metrics.Middleware(recorder, tracing.ChiRoute))
+	r.Use(logging.Middleware(logger, r))
 	r.Use(middleware.Recoverer)
+	r.Use(handlers.IdentifyActor)
 
-	svs := service.NewUser(db)
+	svs := service.NewUser(db, service.WithChangeCounter(recorder))
+
+	if !cfg.Auth.Disabled {
+		rules, err := policy.New(cfg.Auth.PolicyFile)
+		if err != nil {
+			return fmt.Errorf("[in run]: %w", err)
+		}
+		r.Use(handlers.AuthenticateAuthorizer(logger), handlers.Authorize(logger, svs, rules))
+	}
+	permit := handlers.Permit(logger, svs, policy.ActionDelete)
 
-	r.Delete("/api/user/{ID}", handlers.HandleDeleteUser(logger, svs))
+	r.With(permit, handlers.AuthenticateAdmin(cfg.AdminToken)).
+		Delete("/api/user/{ID}", handlers.HandleDeleteUser(logger, svs))
 
-	lambda.Start(httpadapter.New(r).ProxyWithContext)
+	lambda.Start(tracing.WrapAPIGateway(provider, httpadapter.New(r).ProxyWithContext))
 
 	return nil
 }
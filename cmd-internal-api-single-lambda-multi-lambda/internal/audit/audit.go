@@ -0,0 +1,33 @@
+package audit
+
+import (
+	"context"
+
+	"github.com/go-chi/chi/v5/middleware"
+)
+
+// Anonymous is the actor recorded for changes made by a request that did not identify its actor.
+const Anonymous = "anonymous"
+
+type actorKey struct{}
+
+// WithActor returns a copy of ctx carrying actor as the one making the request, which is recorded
+// in the created_by and updated_by columns of the users it changes.
+func WithActor(ctx context.Context, actor string) context.Context {
+	return context.WithValue(ctx, actorKey{}, actor)
+}
+
+// Actor returns the actor set on ctx with WithActor, or Anonymous if there is none.
+func Actor(ctx context.Context) string {
+	actor, _ := ctx.Value(actorKey{}).(string)
+	if actor == "" {
+		return Anonymous
+	}
+	return actor
+}
+
+// RequestID returns the ID that logging.Middleware gave the request of ctx, which is recorded in
+// the history of the users it changes, or an empty string if there is none.
+func RequestID(ctx context.Context) string {
+	return middleware.GetReqID(ctx)
+}
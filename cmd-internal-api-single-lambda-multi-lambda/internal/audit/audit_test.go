@@ -0,0 +1,55 @@
+package audit
+
+import (
+	"context"
+	"testing"
+
+	"github.com/go-chi/chi/v5/middleware"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestActor(t *testing.T) {
+	tests := map[string]struct {
+		ctx      context.Context
+		expected string
+	}{
+		"actor set": {
+			ctx:      WithActor(context.Background(), "jdoe"),
+			expected: "jdoe",
+		},
+		"no actor": {
+			ctx:      context.Background(),
+			expected: Anonymous,
+		},
+		"empty actor": {
+			ctx:      WithActor(context.Background(), ""),
+			expected: Anonymous,
+		},
+	}
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			assert.Equal(t, tc.expected, Actor(tc.ctx))
+		})
+	}
+}
+
+func TestRequestID(t *testing.T) {
+	tests := map[string]struct {
+		ctx      context.Context
+		expected string
+	}{
+		"request ID set": {
+			ctx:      context.WithValue(context.Background(), middleware.RequestIDKey, "host/abc-000001"),
+			expected: "host/abc-000001",
+		},
+		"no request ID": {
+			ctx:      context.Background(),
+			expected: "",
+		},
+	}
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			assert.Equal(t, tc.expected, RequestID(tc.ctx))
+		})
+	}
+}
@@ -0,0 +1,88 @@
+package auth
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestClaimsUserID(t *testing.T) {
+	tests := map[string]struct {
+		raw            map[string]any
+		expectedUserID uint
+		expectedOK     bool
+	}{
+		"number from a verified token": {
+			raw:            map[string]any{"user_id": json.Number("1001")},
+			expectedUserID: 1001,
+			expectedOK:     true,
+		},
+		"string from an authorizer": {
+			raw:            map[string]any{"user_id": "1001"},
+			expectedUserID: 1001,
+			expectedOK:     true,
+		},
+		"float": {
+			raw:            map[string]any{"user_id": float64(1001)},
+			expectedUserID: 1001,
+			expectedOK:     true,
+		},
+		"missing": {
+			raw: map[string]any{"sub": "jdoe"},
+		},
+		"zero": {
+			raw: map[string]any{"user_id": json.Number("0")},
+		},
+		"negative": {
+			raw: map[string]any{"user_id": "-1"},
+		},
+		"not a number": {
+			raw: map[string]any{"user_id": "jdoe"},
+		},
+		"fraction": {
+			raw: map[string]any{"user_id": 10.5},
+		},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			userID, ok := Claims{Raw: tc.raw}.UserID()
+
+			assert.Equal(t, tc.expectedUserID, userID, "Wrong user_id")
+			assert.Equal(t, tc.expectedOK, ok, "Wrong ok")
+		})
+	}
+}
+
+func TestAudience(t *testing.T) {
+	tests := map[string]struct {
+		value             any
+		strict            bool
+		expectedAudiences []string
+	}{
+		"string from a verified token": {
+			value:             "user-microservice, admin",
+			strict:            true,
+			expectedAudiences: []string{"user-microservice, admin"},
+		},
+		"string from an authorizer": {
+			value:             "user-microservice, admin",
+			expectedAudiences: []string{"user-microservice", "admin"},
+		},
+		"list": {
+			value:             []any{"user-microservice", "admin"},
+			strict:            true,
+			expectedAudiences: []string{"user-microservice", "admin"},
+		},
+		"missing": {
+			strict: true,
+		},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			assert.Equal(t, tc.expectedAudiences, audience(tc.value, tc.strict), "Wrong audiences")
+		})
+	}
+}
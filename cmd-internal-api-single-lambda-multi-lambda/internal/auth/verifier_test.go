@@ -0,0 +1,184 @@
+package auth
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+const (
+	testIssuer   = "https://issuer.example.com/"
+	testAudience = "user-microservice"
+)
+
+var testNow = time.Date(2024, 6, 1, 12, 0, 0, 0, time.UTC)
+
+// sign returns a token with header and claims, signed with key using alg.
+func sign(t *testing.T, alg string, key crypto.Signer, header map[string]any, claims map[string]any) string {
+	t.Helper()
+
+	head, err := json.Marshal(header)
+	assert.NoError(t, err)
+	body, err := json.Marshal(claims)
+	assert.NoError(t, err)
+	signed := base64.RawURLEncoding.EncodeToString(head) + "." + base64.RawURLEncoding.EncodeToString(body)
+	digest := sha256.Sum256([]byte(signed))
+
+	var signature []byte
+	switch alg {
+	case algRS256:
+		signature, err = rsa.SignPKCS1v15(rand.Reader, key.(*rsa.PrivateKey), crypto.SHA256, digest[:])
+		assert.NoError(t, err)
+	case algES256:
+		r, s, err := ecdsa.Sign(rand.Reader, key.(*ecdsa.PrivateKey), digest[:])
+		assert.NoError(t, err)
+		signature = make([]byte, 64)
+		r.FillBytes(signature[:32])
+		s.FillBytes(signature[32:])
+	}
+
+	return signed + "." + base64.RawURLEncoding.EncodeToString(signature)
+}
+
+// validClaims returns the claims of a token that is valid at testNow.
+func validClaims() map[string]any {
+	return map[string]any{
+		"sub":   "jdoe",
+		"iss":   testIssuer,
+		"aud":   []string{"other", testAudience},
+		"exp":   testNow.Add(time.Hour).Unix(),
+		"nbf":   testNow.Add(-time.Minute).Unix(),
+		"iat":   testNow.Add(-time.Minute).Unix(),
+		"roles": []string{"admin"},
+	}
+}
+
+// withClaim returns validClaims with name set to value, or removed if value is nil.
+func withClaim(name string, value any) map[string]any {
+	claims := validClaims()
+	if value == nil {
+		delete(claims, name)
+	} else {
+		claims[name] = value
+	}
+	return claims
+}
+
+func TestVerifierVerify(t *testing.T) {
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	assert.NoError(t, err)
+	ecKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	assert.NoError(t, err)
+	otherKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	assert.NoError(t, err)
+
+	keys := StaticKeys{"rsa": &rsaKey.PublicKey, "ec": &ecKey.PublicKey}
+	verifier := NewVerifier(keys, testIssuer, testAudience, WithClock(func() time.Time { return testNow }))
+
+	tests := map[string]struct {
+		token         string
+		expectedError bool
+	}{
+		"valid RS256 token": {
+			token: sign(t, algRS256, rsaKey, map[string]any{"alg": algRS256, "kid": "rsa"}, validClaims()),
+		},
+		"valid ES256 token": {
+			token: sign(t, algES256, ecKey, map[string]any{"alg": algES256, "kid": "ec"}, validClaims()),
+		},
+		"single audience": {
+			token: sign(t, algRS256, rsaKey, map[string]any{"alg": algRS256, "kid": "rsa"}, withClaim("aud", testAudience)),
+		},
+		"expired within leeway": {
+			token: sign(t, algRS256, rsaKey, map[string]any{"alg": algRS256, "kid": "rsa"}, withClaim("exp", testNow.Add(-30*time.Second).Unix())),
+		},
+		"expired": {
+			token:         sign(t, algRS256, rsaKey, map[string]any{"alg": algRS256, "kid": "rsa"}, withClaim("exp", testNow.Add(-time.Hour).Unix())),
+			expectedError: true,
+		},
+		"no expiration time": {
+			token:         sign(t, algRS256, rsaKey, map[string]any{"alg": algRS256, "kid": "rsa"}, withClaim("exp", nil)),
+			expectedError: true,
+		},
+		"not yet valid": {
+			token:         sign(t, algRS256, rsaKey, map[string]any{"alg": algRS256, "kid": "rsa"}, withClaim("nbf", testNow.Add(time.Hour).Unix())),
+			expectedError: true,
+		},
+		"wrong issuer": {
+			token:         sign(t, algRS256, rsaKey, map[string]any{"alg": algRS256, "kid": "rsa"}, withClaim("iss", "https://evil.example.com/")),
+			expectedError: true,
+		},
+		"wrong audience": {
+			token:         sign(t, algRS256, rsaKey, map[string]any{"alg": algRS256, "kid": "rsa"}, withClaim("aud", "other")),
+			expectedError: true,
+		},
+		"time claim not a number": {
+			token:         sign(t, algRS256, rsaKey, map[string]any{"alg": algRS256, "kid": "rsa"}, withClaim("exp", "tomorrow")),
+			expectedError: true,
+		},
+		"signed with untrusted key": {
+			token:         sign(t, algRS256, otherKey, map[string]any{"alg": algRS256, "kid": "rsa"}, validClaims()),
+			expectedError: true,
+		},
+		"unknown key ID": {
+			token:         sign(t, algRS256, rsaKey, map[string]any{"alg": algRS256, "kid": "missing"}, validClaims()),
+			expectedError: true,
+		},
+		"algorithm does not match key": {
+			token:         sign(t, algRS256, rsaKey, map[string]any{"alg": algES256, "kid": "rsa"}, validClaims()),
+			expectedError: true,
+		},
+		"algorithm not allowed": {
+			token:         sign(t, algRS256, rsaKey, map[string]any{"alg": "none", "kid": "rsa"}, validClaims()),
+			expectedError: true,
+		},
+		"not a token": {
+			token:         "abc.def",
+			expectedError: true,
+		},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			claims, err := verifier.Verify(context.Background(), tc.token)
+
+			if tc.expectedError {
+				assert.ErrorIs(t, err, ErrInvalidToken)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, "jdoe", claims.Subject)
+			assert.Equal(t, testIssuer, claims.Issuer)
+			assert.Contains(t, claims.Audience, testAudience)
+			assert.False(t, claims.ExpiresAt.IsZero(), "Expiration time not set")
+			assert.Equal(t, []any{"admin"}, claims.Raw["roles"], "Custom claim not kept")
+		})
+	}
+}
+
+func TestVerifierVerifyTamperedClaims(t *testing.T) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	assert.NoError(t, err)
+	verifier := NewVerifier(StaticKeys{"": &key.PublicKey}, testIssuer, testAudience, WithClock(func() time.Time { return testNow }))
+
+	token := sign(t, algES256, key, map[string]any{"alg": algES256}, validClaims())
+	parts := strings.Split(token, ".")
+	body, err := json.Marshal(withClaim("sub", "admin"))
+	assert.NoError(t, err)
+	tampered := parts[0] + "." + base64.RawURLEncoding.EncodeToString(body) + "." + parts[2]
+
+	_, err = verifier.Verify(context.Background(), token)
+	assert.NoError(t, err, "Token without key ID not verified with the only key")
+	_, err = verifier.Verify(context.Background(), tampered)
+	assert.ErrorIs(t, err, ErrInvalidToken, "Tampered token verified")
+}
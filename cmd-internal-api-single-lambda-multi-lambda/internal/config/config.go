@@ -3,6 +3,7 @@ package config
 import (
 	"fmt"
 	"log/slog"
+	"time"
 
 	"github.com/caarlos0/env/v11"
 	"github.com/joho/godotenv"
@@ -12,18 +13,76 @@ type Configuration struct {
 	Env        string     `env:"ENV,required"`
 	LogLevel   slog.Level `env:"LOG_LEVEL,required"`
 	UseSwagger bool       `env:"USE_SWAGGER" envDefault:"false"`
-	Database   struct {
-		Name            string `env:"DATABASE_NAME"`
-		User            string `env:"DATABASE_USER"`
-		Password        string `env:"DATABASE_PASSWORD"`
-		Host            string `env:"DATABASE_HOST"`
-		Port            string `env:"DATABASE_PORT"`
-		ConnectionRetry int    `env:"DATABASE_CONNECTION_RETRY"`
+	AdminToken string     `env:"ADMIN_TOKEN"`
+	Log        struct {
+		RedactPII    bool     `env:"LOG_REDACT_PII" envDefault:"true"`
+		RedactMode   string   `env:"LOG_REDACT_MODE" envDefault:"mask"`
+		RedactSecret string   `env:"LOG_REDACT_SECRET"`
+		RedactKeys   []string `env:"LOG_REDACT_KEYS" envDefault:"first_name,last_name"`
+	}
+	Database struct {
+		Name               string `env:"DATABASE_NAME"`
+		User               string `env:"DATABASE_USER"`
+		Password           string `env:"DATABASE_PASSWORD"`
+		Host               string `env:"DATABASE_HOST"`
+		Port               string `env:"DATABASE_PORT"`
+		ConnectionRetry    int    `env:"DATABASE_CONNECTION_RETRY"`
+		MigrateOnStartup   bool   `env:"DATABASE_MIGRATE_ON_STARTUP" envDefault:"false"`
+		MaxOpenConnections int    `env:"DATABASE_MAX_OPEN_CONNECTIONS" envDefault:"0"`
+	}
+	Auth struct {
+		Disabled    bool          `env:"AUTH_DISABLED" envDefault:"false"`
+		JWKSURL     string        `env:"AUTH_JWKS_URL"`
+		KeyFile     string        `env:"AUTH_KEY_FILE"`
+		Issuer      string        `env:"AUTH_ISSUER"`
+		Audience    string        `env:"AUTH_AUDIENCE"`
+		Leeway      time.Duration `env:"AUTH_LEEWAY" envDefault:"1m"`
+		JWKSRefresh time.Duration `env:"AUTH_JWKS_REFRESH" envDefault:"1h"`
+		PolicyFile  string        `env:"AUTH_POLICY_FILE"`
+	}
+	Cursor struct {
+		Secret string        `env:"CURSOR_SECRET"`
+		TTL    time.Duration `env:"CURSOR_TTL" envDefault:"24h"`
+	}
+	Outbox struct {
+		WebhookURL   string        `env:"OUTBOX_WEBHOOK_URL"`
+		Source       string        `env:"OUTBOX_SOURCE" envDefault:"/user-microservice"`
+		PollInterval time.Duration `env:"OUTBOX_POLL_INTERVAL" envDefault:"1s"`
+		MaxAttempts  int           `env:"OUTBOX_MAX_ATTEMPTS" envDefault:"10"`
+	}
+	Events struct {
+		ReplayBuffer int           `env:"EVENTS_REPLAY_BUFFER" envDefault:"1000"`
+		Heartbeat    time.Duration `env:"EVENTS_HEARTBEAT" envDefault:"15s"`
+	}
+	RateLimit struct {
+		Disabled bool              `env:"RATE_LIMIT_DISABLED" envDefault:"false"`
+		Default  string            `env:"RATE_LIMIT_DEFAULT" envDefault:"300/1m"`
+		Routes   map[string]string `env:"RATE_LIMIT_ROUTES" envSeparator:";" envKeyValSeparator:"="`
+	}
+	Health struct {
+		Timeout           time.Duration `env:"HEALTH_TIMEOUT" envDefault:"2s"`
+		MaxPoolSaturation float64       `env:"HEALTH_MAX_POOL_SATURATION" envDefault:"1"`
+	}
+	Tracing struct {
+		Exporter    string  `env:"TRACING_EXPORTER" envDefault:"none"`
+		ServiceName string  `env:"TRACING_SERVICE_NAME" envDefault:"user-microservice"`
+		SampleRatio float64 `env:"TRACING_SAMPLE_RATIO" envDefault:"1"`
+	}
+	Metrics struct {
+		Port      string `env:"METRICS_PORT" envDefault:":9090"`
+		Namespace string `env:"METRICS_NAMESPACE" envDefault:"UserMicroservice"`
+	}
+	Webhook struct {
+		PollInterval time.Duration `env:"WEBHOOK_POLL_INTERVAL" envDefault:"1s"`
+		MaxAttempts  int           `env:"WEBHOOK_MAX_ATTEMPTS" envDefault:"10"`
+		Timeout      time.Duration `env:"WEBHOOK_TIMEOUT" envDefault:"10s"`
 	}
 	HTTP struct {
-		Domain              string `env:"HTTP_DOMAIN"`
-		Port                string `env:"HTTP_PORT"`
-		ShutdownGracePeriod int    `env:"HTTP_SHUTDOWN_GRACE_PERIOD"`
+		Domain              string        `env:"HTTP_DOMAIN"`
+		Port                string        `env:"HTTP_PORT"`
+		ShutdownGracePeriod int           `env:"HTTP_SHUTDOWN_GRACE_PERIOD"`
+		DrainDelay          time.Duration `env:"HTTP_DRAIN_DELAY" envDefault:"0s"`
+		CORSAllowedOrigins  []string      `env:"HTTP_CORS_ALLOWED_ORIGINS" envDefault:"*"`
 	}
 }
 
@@ -0,0 +1,78 @@
+package cursor
+
+import (
+	"encoding/base64"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCodec(t *testing.T) {
+	issuedAt := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	key := Key{SortBy: "last_name", Desc: true, Value: "Smith", ID: 2, Before: true}
+
+	codec := NewCodec([]byte("secret"), time.Hour)
+	codec.now = func() time.Time { return issuedAt }
+	token := codec.Encode(key)
+
+	body, _, _ := strings.Cut(token, ".")
+	forgedBody := base64.RawURLEncoding.EncodeToString([]byte(`{"s":"last_name","v":"Smith","i":999,"t":1704110400}`))
+
+	testCases := map[string]struct {
+		token         string
+		secret        string
+		decodedAt     time.Time
+		expectedKey   Key
+		expectedError error
+	}{
+		"round trip": {
+			token:       token,
+			secret:      "secret",
+			decodedAt:   issuedAt.Add(time.Minute),
+			expectedKey: key,
+		},
+		"expired": {
+			token:         token,
+			secret:        "secret",
+			decodedAt:     issuedAt.Add(2 * time.Hour),
+			expectedError: ErrExpired,
+		},
+		"signed with a different secret": {
+			token:         token,
+			secret:        "other secret",
+			decodedAt:     issuedAt,
+			expectedError: ErrInvalid,
+		},
+		"tampered body": {
+			token:         forgedBody + strings.TrimPrefix(token, body),
+			secret:        "secret",
+			decodedAt:     issuedAt,
+			expectedError: ErrInvalid,
+		},
+		"missing signature": {
+			token:         body,
+			secret:        "secret",
+			decodedAt:     issuedAt,
+			expectedError: ErrInvalid,
+		},
+		"garbage": {
+			token:         "not-a-cursor",
+			secret:        "secret",
+			decodedAt:     issuedAt,
+			expectedError: ErrInvalid,
+		},
+	}
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			decoder := NewCodec([]byte(tc.secret), time.Hour)
+			decoder.now = func() time.Time { return tc.decodedAt }
+
+			actualKey, err := decoder.Decode(tc.token)
+
+			assert.ErrorIs(t, err, tc.expectedError, "errors did not match")
+			assert.Equal(t, tc.expectedKey, actualKey, "returned key does not match")
+		})
+	}
+}
@@ -0,0 +1,264 @@
+package database
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"regexp"
+	"testing"
+	"testing/fstest"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+)
+
+var testMigrations = []Migration{
+	{Version: 1, Name: "create_users_table", Up: "CREATE TABLE users", Down: "DROP TABLE users"},
+	{Version: 2, Name: "add_email", Up: "ALTER TABLE users ADD email", Down: "ALTER TABLE users DROP email"},
+}
+
+func TestLoadMigrations(t *testing.T) {
+	testCases := map[string]struct {
+		files          fstest.MapFS
+		expectedReturn []Migration
+		expectedError  bool
+	}{
+		"Migrations are sorted by version": {
+			files: fstest.MapFS{
+				"migrations/0002_add_email.up.sql":            {Data: []byte("ALTER TABLE users ADD email")},
+				"migrations/0002_add_email.down.sql":          {Data: []byte("ALTER TABLE users DROP email")},
+				"migrations/0001_create_users_table.up.sql":   {Data: []byte("CREATE TABLE users")},
+				"migrations/0001_create_users_table.down.sql": {Data: []byte("DROP TABLE users")},
+			},
+			expectedReturn: testMigrations,
+		},
+		"Missing down file": {
+			files: fstest.MapFS{
+				"migrations/0001_create_users_table.up.sql": {Data: []byte("CREATE TABLE users")},
+			},
+			expectedError: true,
+		},
+		"Invalid file name": {
+			files: fstest.MapFS{
+				"migrations/create_users_table.sql": {Data: []byte("CREATE TABLE users")},
+			},
+			expectedError: true,
+		},
+		"Version with two names": {
+			files: fstest.MapFS{
+				"migrations/0001_create_users_table.up.sql": {Data: []byte("CREATE TABLE users")},
+				"migrations/0001_create_users.down.sql":     {Data: []byte("DROP TABLE users")},
+			},
+			expectedError: true,
+		},
+	}
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			migrations, err := loadMigrations(tc.files, "migrations")
+
+			assert.Equal(t, tc.expectedError, err != nil, "Wrong error")
+			assert.Equal(t, tc.expectedReturn, migrations, "Wrong migrations")
+		})
+	}
+}
+
+func TestEmbeddedMigrations(t *testing.T) {
+	migrations, err := loadMigrations(migrationFS, "migrations")
+
+	assert.NoError(t, err)
+	assert.NotEmpty(t, migrations)
+}
+
+func TestMigratorUp(t *testing.T) {
+	migrator, mock := newTestMigrator(t)
+
+	expectLock(mock, sqlmock.NewRows([]string{"version", "applied_at"}).AddRow(1, time.Now()))
+	mock.ExpectBegin()
+	mock.ExpectExec(regexp.QuoteMeta("ALTER TABLE users ADD email")).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectExec(regexp.QuoteMeta("INSERT INTO schema_migrations (version, name) VALUES ($1, $2)")).
+		WithArgs(2, "add_email").
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+	expectUnlock(mock)
+
+	err := migrator.Up(context.Background())
+
+	assert.NoError(t, err)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestMigratorUpRollsBackFailedMigration(t *testing.T) {
+	migrator, mock := newTestMigrator(t)
+
+	expectLock(mock, sqlmock.NewRows([]string{"version", "applied_at"}))
+	mock.ExpectBegin()
+	mock.ExpectExec(regexp.QuoteMeta("CREATE TABLE users")).
+		WillReturnError(errors.New("syntax error"))
+	mock.ExpectRollback()
+	expectUnlock(mock)
+
+	err := migrator.Up(context.Background())
+
+	assert.ErrorContains(t, err, "apply migration 1_create_users_table")
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestMigratorDown(t *testing.T) {
+	migrator, mock := newTestMigrator(t)
+
+	expectLock(mock, sqlmock.NewRows([]string{"version", "applied_at"}).
+		AddRow(1, time.Now()).
+		AddRow(2, time.Now()),
+	)
+	mock.ExpectBegin()
+	mock.ExpectExec(regexp.QuoteMeta("ALTER TABLE users DROP email")).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectExec(regexp.QuoteMeta("DELETE FROM schema_migrations WHERE version = $1")).
+		WithArgs(2).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+	expectUnlock(mock)
+
+	err := migrator.Down(context.Background(), 1)
+
+	assert.NoError(t, err)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestMigratorCheck(t *testing.T) {
+	testCases := map[string]struct {
+		applied       *sqlmock.Rows
+		expectedError error
+	}{
+		"Schema is current": {
+			applied: sqlmock.NewRows([]string{"version", "applied_at"}).
+				AddRow(1, time.Now()).
+				AddRow(2, time.Now()),
+		},
+		"Schema is behind": {
+			applied:       sqlmock.NewRows([]string{"version", "applied_at"}).AddRow(1, time.Now()),
+			expectedError: ErrSchemaBehind,
+		},
+	}
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			migrator, mock := newTestMigrator(t)
+
+			expectLock(mock, tc.applied)
+			expectUnlock(mock)
+
+			err := migrator.Check(context.Background())
+
+			assert.ErrorIs(t, err, tc.expectedError)
+			assert.NoError(t, mock.ExpectationsWereMet())
+		})
+	}
+}
+
+func TestMigratorVersion(t *testing.T) {
+	const selectVersion = `SELECT COALESCE(MAX(version), 0) FROM schema_migrations`
+
+	testCases := map[string]struct {
+		mockReturn      *sqlmock.Rows
+		mockReturnErr   error
+		expectedApplied int
+		expectedError   error
+	}{
+		"Schema is current": {
+			mockReturn:      sqlmock.NewRows([]string{"version"}).AddRow(2),
+			expectedApplied: 2,
+		},
+		"Schema is behind": {
+			mockReturn:      sqlmock.NewRows([]string{"version"}).AddRow(1),
+			expectedApplied: 1,
+		},
+		"Schema not migrated": {
+			mockReturnErr: errors.New(`relation "schema_migrations" does not exist`),
+			expectedError: fmt.Errorf("[in Migrator.Version]: %w", errors.New(`relation "schema_migrations" does not exist`)),
+		},
+	}
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			migrator, mock := newTestMigrator(t)
+
+			mockReturn := tc.mockReturn
+			if mockReturn == nil {
+				mockReturn = &sqlmock.Rows{}
+			}
+			mock.ExpectQuery(regexp.QuoteMeta(selectVersion)).
+				WillReturnRows(mockReturn).
+				WillReturnError(tc.mockReturnErr)
+
+			applied, latest, err := migrator.Version(context.Background())
+
+			assert.Equal(t, tc.expectedError, err, "errors did not match")
+			assert.Equal(t, tc.expectedApplied, applied, "Wrong applied version")
+			assert.Equal(t, 2, latest, "Wrong latest version")
+			assert.NoError(t, mock.ExpectationsWereMet())
+		})
+	}
+}
+
+func TestMigratorForce(t *testing.T) {
+	t.Run("Unknown version", func(t *testing.T) {
+		migrator, mock := newTestMigrator(t)
+
+		err := migrator.Force(context.Background(), 3)
+
+		assert.ErrorIs(t, err, ErrUnknownMigration)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("Baseline existing schema", func(t *testing.T) {
+		migrator, mock := newTestMigrator(t)
+
+		expectLock(mock, sqlmock.NewRows([]string{"version", "applied_at"}))
+		mock.ExpectBegin()
+		mock.ExpectExec(regexp.QuoteMeta("DELETE FROM schema_migrations WHERE version > $1")).
+			WithArgs(1).
+			WillReturnResult(sqlmock.NewResult(0, 0))
+		mock.ExpectExec(regexp.QuoteMeta("INSERT INTO schema_migrations (version, name) VALUES ($1, $2)")).
+			WithArgs(1, "create_users_table").
+			WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectCommit()
+		expectUnlock(mock)
+
+		err := migrator.Force(context.Background(), 1)
+
+		assert.NoError(t, err)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+}
+
+// newTestMigrator returns a Migrator for testMigrations backed by a sqlmock database.
+func newTestMigrator(t *testing.T) (*Migrator, sqlmock.Sqlmock) {
+	db, mock, err := sqlmock.New()
+	assert.NoError(t, err)
+	t.Cleanup(func() { _ = db.Close() })
+
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	return newMigrator(db, logger, testMigrations), mock
+}
+
+// expectLock adds the expectations for acquiring the migration lock, creating the
+// schema_migrations table and reading the applied migrations.
+func expectLock(mock sqlmock.Sqlmock, applied *sqlmock.Rows) {
+	mock.ExpectExec(regexp.QuoteMeta("SELECT pg_advisory_lock($1)")).
+		WithArgs(migrationLockID).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectExec(regexp.QuoteMeta("CREATE TABLE IF NOT EXISTS schema_migrations")).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectQuery(regexp.QuoteMeta("SELECT version, applied_at FROM schema_migrations")).
+		WillReturnRows(applied)
+}
+
+// expectUnlock adds the expectation for releasing the migration lock.
+func expectUnlock(mock sqlmock.Sqlmock) {
+	mock.ExpectExec(regexp.QuoteMeta("SELECT pg_advisory_unlock($1)")).
+		WithArgs(migrationLockID).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+}
@@ -0,0 +1,7 @@
+DELETE FROM users
+WHERE deleted_at IS NOT NULL;
+DROP INDEX users_user_id_key;
+ALTER TABLE users
+    ADD CONSTRAINT users_user_id_key UNIQUE (user_id);
+ALTER TABLE users
+    DROP COLUMN deleted_at;
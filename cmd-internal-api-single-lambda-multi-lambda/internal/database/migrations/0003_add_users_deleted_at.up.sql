@@ -0,0 +1,5 @@
+ALTER TABLE users
+    ADD COLUMN deleted_at TIMESTAMPTZ;
+ALTER TABLE users
+    DROP CONSTRAINT users_user_id_key;
+CREATE UNIQUE INDEX users_user_id_key ON users (user_id) WHERE deleted_at IS NULL;
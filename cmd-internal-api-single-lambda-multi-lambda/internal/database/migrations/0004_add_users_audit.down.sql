@@ -0,0 +1,6 @@
+DROP INDEX users_updated_at_idx;
+ALTER TABLE users
+    DROP COLUMN created_at,
+    DROP COLUMN updated_at,
+    DROP COLUMN created_by,
+    DROP COLUMN updated_by;
@@ -0,0 +1,6 @@
+ALTER TABLE users
+    ADD COLUMN created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    ADD COLUMN updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    ADD COLUMN created_by TEXT        NOT NULL DEFAULT 'system',
+    ADD COLUMN updated_by TEXT        NOT NULL DEFAULT 'system';
+CREATE INDEX users_updated_at_idx ON users (updated_at);
@@ -0,0 +1,12 @@
+CREATE TABLE user_history
+(
+    id         INTEGER     NOT NULL,
+    version    INTEGER     NOT NULL,
+    operation  TEXT        NOT NULL,
+    actor      TEXT        NOT NULL,
+    request_id TEXT        NOT NULL DEFAULT '',
+    changed_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    before     JSONB,
+    after      JSONB,
+    PRIMARY KEY (id, version)
+);
@@ -0,0 +1,14 @@
+CREATE TABLE user_outbox
+(
+    id              BIGSERIAL PRIMARY KEY,
+    type            TEXT        NOT NULL,
+    subject         TEXT        NOT NULL,
+    data            JSONB       NOT NULL,
+    created_at      TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    status          TEXT        NOT NULL DEFAULT 'pending',
+    attempts        INTEGER     NOT NULL DEFAULT 0,
+    next_attempt_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    last_error      TEXT        NOT NULL DEFAULT '',
+    delivered_at    TIMESTAMPTZ
+);
+CREATE INDEX user_outbox_pending_idx ON user_outbox (next_attempt_at, id) WHERE status = 'pending';
@@ -0,0 +1,29 @@
+CREATE TABLE webhook_subscriptions
+(
+    id         SERIAL PRIMARY KEY,
+    url        TEXT        NOT NULL,
+    events     TEXT[]      NOT NULL DEFAULT '{}',
+    secret     TEXT        NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE TABLE webhook_deliveries
+(
+    id              BIGSERIAL PRIMARY KEY,
+    subscription_id INTEGER     NOT NULL REFERENCES webhook_subscriptions (id) ON DELETE CASCADE,
+    event_id        TEXT        NOT NULL,
+    event_type      TEXT        NOT NULL,
+    payload         JSONB       NOT NULL,
+    redelivery_of   BIGINT REFERENCES webhook_deliveries (id) ON DELETE SET NULL,
+    created_at      TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    status          TEXT        NOT NULL DEFAULT 'pending',
+    attempts        INTEGER     NOT NULL DEFAULT 0,
+    next_attempt_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    response_status INTEGER,
+    last_error      TEXT        NOT NULL DEFAULT '',
+    delivered_at    TIMESTAMPTZ
+);
+CREATE UNIQUE INDEX webhook_deliveries_event_idx ON webhook_deliveries (subscription_id, event_id) WHERE redelivery_of IS NULL;
+CREATE INDEX webhook_deliveries_subscription_idx ON webhook_deliveries (subscription_id, id);
+CREATE INDEX webhook_deliveries_pending_idx ON webhook_deliveries (next_attempt_at, id) WHERE status = 'pending';
@@ -0,0 +1,3 @@
+DROP TRIGGER users_notify_change ON users;
+DROP FUNCTION notify_user_change();
+DROP SEQUENCE user_events_seq;
@@ -0,0 +1,40 @@
+CREATE SEQUENCE user_events_seq;
+
+CREATE FUNCTION notify_user_change() RETURNS TRIGGER AS
+$$
+DECLARE
+    event_type TEXT;
+    changed    users;
+BEGIN
+    IF TG_OP = 'INSERT' THEN
+        event_type := 'user.created';
+        changed := NEW;
+    ELSIF TG_OP = 'DELETE' THEN
+        event_type := 'user.deleted';
+        changed := OLD;
+    ELSIF NEW.deleted_at IS NOT NULL AND OLD.deleted_at IS NULL THEN
+        event_type := 'user.deleted';
+        changed := NEW;
+    ELSE
+        event_type := 'user.updated';
+        changed := NEW;
+    END IF;
+
+    PERFORM pg_notify('user_changes', json_build_object(
+        'seq', nextval('user_events_seq'),
+        'type', event_type,
+        'id', changed.id,
+        'role', changed.role,
+        'old_role', CASE WHEN TG_OP = 'UPDATE' THEN OLD.role END,
+        'user', to_jsonb(changed)
+    )::TEXT);
+
+    RETURN NULL;
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE TRIGGER users_notify_change
+    AFTER INSERT OR UPDATE OR DELETE
+    ON users
+    FOR EACH ROW
+EXECUTE FUNCTION notify_user_change();
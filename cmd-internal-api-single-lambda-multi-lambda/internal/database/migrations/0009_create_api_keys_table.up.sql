@@ -0,0 +1,13 @@
+CREATE TABLE api_keys
+(
+    id           SERIAL PRIMARY KEY,
+    name         TEXT        NOT NULL,
+    prefix       TEXT        NOT NULL,
+    key_hash     TEXT        NOT NULL,
+    scopes       TEXT[]      NOT NULL DEFAULT '{}',
+    expires_at   TIMESTAMPTZ,
+    last_used_at TIMESTAMPTZ,
+    revoked_at   TIMESTAMPTZ,
+    created_at   TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+CREATE UNIQUE INDEX api_keys_key_hash_idx ON api_keys (key_hash);
@@ -0,0 +1,31 @@
+package handlers
+
+import (
+	"net/http"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/jha-captech/user-microservice/internal/audit"
+)
+
+// actorHeader is the request header a caller names the actor it acts for in.
+const actorHeader = "X-Actor"
+
+// maxActorLength is the maximum length of an actor name, longer names are cut short.
+const maxActorLength = 255
+
+// IdentifyActor is a middleware that records the actor named in the X-Actor header of a request in
+// its context, so that the users changed by the request are attributed to them. Requests without
+// the header are recorded as made by audit.Anonymous.
+func IdentifyActor(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		actor := strings.TrimSpace(r.Header.Get(actorHeader))
+		if utf8.RuneCountInString(actor) > maxActorLength {
+			actor = string([]rune(actor)[:maxActorLength])
+		}
+		if actor != "" {
+			r = r.WithContext(audit.WithActor(r.Context(), actor))
+		}
+		next.ServeHTTP(w, r)
+	})
+}
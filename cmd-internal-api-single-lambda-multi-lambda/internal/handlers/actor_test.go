@@ -0,0 +1,57 @@
+package handlers
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/jha-captech/user-microservice/internal/audit"
+)
+
+func TestIdentifyActor(t *testing.T) {
+	tests := map[string]struct {
+		header        string
+		expectedActor string
+	}{
+		"actor header set": {
+			header:        "jdoe",
+			expectedActor: "jdoe",
+		},
+		"actor header padded": {
+			header:        "  jdoe ",
+			expectedActor: "jdoe",
+		},
+		"actor header too long": {
+			header:        strings.Repeat("é", maxActorLength+10),
+			expectedActor: strings.Repeat("é", maxActorLength),
+		},
+		"actor header blank": {
+			header:        "   ",
+			expectedActor: audit.Anonymous,
+		},
+		"no actor header": {
+			expectedActor: audit.Anonymous,
+		},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			req, err := http.NewRequest(http.MethodPost, "/api/user", nil)
+			assert.NoError(t, err)
+			if tc.header != "" {
+				req.Header.Set(actorHeader, tc.header)
+			}
+
+			var actualActor string
+			handler := IdentifyActor(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				actualActor = audit.Actor(r.Context())
+			}))
+			handler.ServeHTTP(httptest.NewRecorder(), req)
+
+			assert.Equal(t, tc.expectedActor, actualActor, "Wrong actor")
+		})
+	}
+}
@@ -0,0 +1,54 @@
+package handlers
+
+import (
+	"context"
+	"crypto/subtle"
+	"net/http"
+
+	"github.com/jha-captech/user-microservice/internal/problem"
+)
+
+// adminTokenHeader is the request header an administrator passes the admin token in.
+const adminTokenHeader = "X-Admin-Token"
+
+type adminContextKey struct{}
+
+// AuthenticateAdmin returns a middleware that marks requests whose X-Admin-Token header matches
+// token as made by an administrator, which is required for admin-only operations such as purging a
+// user. Requests without a matching header are passed on unchanged, so the middleware does not
+// reject anything itself. If token is empty, no request is treated as made by an administrator.
+func AuthenticateAdmin(token string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			header := r.Header.Get(adminTokenHeader)
+			if token != "" && subtle.ConstantTimeCompare([]byte(header), []byte(token)) == 1 {
+				r = r.WithContext(context.WithValue(r.Context(), adminContextKey{}, true))
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// isAdmin reports whether ctx belongs to a request that AuthenticateAdmin found to be made by an
+// administrator.
+func isAdmin(ctx context.Context) bool {
+	admin, _ := ctx.Value(adminContextKey{}).(bool)
+	return admin
+}
+
+// RequireAdmin returns a middleware that refuses requests that AuthenticateAdmin did not find to be
+// made by an administrator, for routes that are admin-only as a whole.
+func RequireAdmin(logger sLogger) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			logger := requestLogger(r, logger)
+
+			if !isAdmin(r.Context()) {
+				logger.Error("Admin-only route requested by non-admin", "path", r.URL.Path)
+				encodeProblem(w, r, logger, problem.New(http.StatusForbidden, "Only administrators can use this route"))
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
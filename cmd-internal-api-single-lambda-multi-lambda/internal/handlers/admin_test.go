@@ -0,0 +1,58 @@
+package handlers
+
+import (
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/jha-captech/user-microservice/internal/problem"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRequireAdmin(t *testing.T) {
+	next := http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.WriteHeader(http.StatusNoContent)
+	})
+
+	tests := map[string]struct {
+		token        string
+		header       string
+		expectedCode int
+		expectedBody string
+	}{
+		"admin passed on": {
+			token:        "secret",
+			header:       "secret",
+			expectedCode: http.StatusNoContent,
+		},
+		"wrong token refused": {
+			token:        "secret",
+			header:       "guess",
+			expectedCode: http.StatusForbidden,
+			expectedBody: toJSONString(problem.New(http.StatusForbidden, "Only administrators can use this route").WithInstance("/api/webhook")),
+		},
+		"refused without admin token configured": {
+			expectedCode: http.StatusForbidden,
+			expectedBody: toJSONString(problem.New(http.StatusForbidden, "Only administrators can use this route").WithInstance("/api/webhook")),
+		},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			req, err := http.NewRequest(http.MethodGet, "/api/webhook", nil)
+			assert.NoError(t, err)
+			if tc.header != "" {
+				req.Header.Set(adminTokenHeader, tc.header)
+			}
+
+			rr := httptest.NewRecorder()
+			AuthenticateAdmin(tc.token)(RequireAdmin(slog.Default())(next)).ServeHTTP(rr, req)
+
+			assert.Equal(t, tc.expectedCode, rr.Code, "Wrong code received")
+			if tc.expectedBody != "" {
+				assert.JSONEq(t, tc.expectedBody, rr.Body.String(), "Wrong response body")
+			}
+		})
+	}
+}
@@ -0,0 +1,102 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strings"
+
+	"github.com/jha-captech/user-microservice/internal/audit"
+	"github.com/jha-captech/user-microservice/internal/logging"
+	"github.com/jha-captech/user-microservice/internal/models"
+	"github.com/jha-captech/user-microservice/internal/problem"
+	svc "github.com/jha-captech/user-microservice/internal/service"
+)
+
+// apiKeyHeader is the request header an API key can be passed in, in place of the Authorization
+// header.
+const apiKeyHeader = "X-API-Key"
+
+type apiKeyContextKey struct{}
+
+type apiKeyAuthenticator interface {
+	AuthenticateKey(ctx context.Context, secret string) (models.APIKey, error)
+}
+
+// AuthenticateAPIKey returns a middleware that authenticates requests carrying an API key, in an
+// `Authorization: ApiKey <key>` or X-API-Key header, with keys. The key is put into the request
+// context, where RequireScope checks its scopes, and its prefix is recorded as the actor of the
+// request. Requests without an API key are passed to fallback, the middleware of the other
+// authentication configured, or on unchanged if fallback is nil.
+func AuthenticateAPIKey(
+	logger sLogger,
+	keys apiKeyAuthenticator,
+	fallback func(http.Handler) http.Handler,
+) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		otherwise := next
+		if fallback != nil {
+			otherwise = fallback(next)
+		}
+
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			logger := requestLogger(r, logger)
+
+			secret, ok := apiKeyOf(r)
+			if !ok {
+				otherwise.ServeHTTP(w, r)
+				return
+			}
+
+			key, err := keys.AuthenticateKey(r.Context(), secret)
+			if err != nil {
+				switch {
+				case errors.Is(err, svc.ErrInvalidAPIKey):
+					logger.Error("Invalid API key", "path", r.URL.Path, "error", err)
+					w.Header().Set("WWW-Authenticate", `ApiKey`)
+					encodeProblem(w, r, logger, problem.New(http.StatusUnauthorized, "The API key is not valid"))
+				default:
+					logger.Error("Error authenticating API key", "error", err)
+					encodeProblem(w, r, logger, problem.New(http.StatusInternalServerError, "Internal server error"))
+				}
+				return
+			}
+
+			ctx := context.WithValue(r.Context(), apiKeyContextKey{}, key)
+			ctx = logging.With(ctx, "api_key", key.Prefix)
+			next.ServeHTTP(w, r.WithContext(audit.WithActor(ctx, "api-key:"+key.Prefix)))
+		})
+	}
+}
+
+// RequireScope returns a middleware that refuses requests authenticated with an API key that does
+// not grant scope. Requests authenticated otherwise are passed on, as their access is decided by
+// the authorization policy.
+func RequireScope(logger sLogger, scope models.APIKeyScope) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			logger := requestLogger(r, logger)
+
+			key, ok := r.Context().Value(apiKeyContextKey{}).(models.APIKey)
+			if ok && !key.HasScope(scope) {
+				logger.Error("API key without required scope", "path", r.URL.Path, "prefix", key.Prefix, "scope", scope)
+				encodeProblem(w, r, logger, problem.New(
+					http.StatusForbidden, "The API key does not have the "+string(scope)+" scope",
+				))
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// apiKeyOf returns the API key of r, from an Authorization header using the ApiKey scheme or the
+// X-API-Key header.
+func apiKeyOf(r *http.Request) (string, bool) {
+	scheme, secret, ok := strings.Cut(r.Header.Get("Authorization"), " ")
+	if !ok || !strings.EqualFold(scheme, "ApiKey") {
+		secret = r.Header.Get(apiKeyHeader)
+	}
+	secret = strings.TrimSpace(secret)
+	return secret, secret != ""
+}
@@ -0,0 +1,196 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+
+	"github.com/jha-captech/user-microservice/internal/audit"
+	"github.com/jha-captech/user-microservice/internal/models"
+	"github.com/jha-captech/user-microservice/internal/problem"
+	"github.com/jha-captech/user-microservice/internal/service"
+
+	serviceMock "github.com/jha-captech/user-microservice/internal/handlers/mock"
+)
+
+func TestAuthenticateAPIKey(t *testing.T) {
+	key := models.APIKey{ID: 1, Name: "nightly export", Prefix: "umk_abcdefgh", Scopes: []models.APIKeyScope{models.ScopeUsersRead}}
+
+	// fallback stands in for bearer authentication, and refuses every request it is passed
+	fallback := func(http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+			w.WriteHeader(http.StatusTeapot)
+		})
+	}
+
+	tests := map[string]struct {
+		headers              map[string]string
+		fallback             func(http.Handler) http.Handler
+		mockCalled           bool
+		mockOutput           []any
+		expectedCode         int
+		expectedAuthenticate string
+		expectedBody         string
+		expectedKey          models.APIKey
+		expectedActor        string
+	}{
+		"key in authorization header": {
+			headers:       map[string]string{"Authorization": "ApiKey umk_secret"},
+			fallback:      fallback,
+			mockCalled:    true,
+			mockOutput:    []any{key, nil},
+			expectedCode:  http.StatusNoContent,
+			expectedKey:   key,
+			expectedActor: "api-key:umk_abcdefgh",
+		},
+		"key in X-API-Key header": {
+			headers:       map[string]string{"X-API-Key": "umk_secret"},
+			fallback:      fallback,
+			mockCalled:    true,
+			mockOutput:    []any{key, nil},
+			expectedCode:  http.StatusNoContent,
+			expectedKey:   key,
+			expectedActor: "api-key:umk_abcdefgh",
+		},
+		"key next to bearer token": {
+			headers:       map[string]string{"Authorization": "Bearer token", "X-API-Key": "umk_secret"},
+			fallback:      fallback,
+			mockCalled:    true,
+			mockOutput:    []any{key, nil},
+			expectedCode:  http.StatusNoContent,
+			expectedKey:   key,
+			expectedActor: "api-key:umk_abcdefgh",
+		},
+		"no key passed to fallback": {
+			headers:      map[string]string{"Authorization": "Bearer token"},
+			fallback:     fallback,
+			expectedCode: http.StatusTeapot,
+		},
+		"no key without fallback": {
+			expectedCode:  http.StatusNoContent,
+			expectedActor: "anonymous",
+		},
+		"invalid key": {
+			headers:              map[string]string{"X-API-Key": "umk_secret"},
+			fallback:             fallback,
+			mockCalled:           true,
+			mockOutput:           []any{models.APIKey{}, fmt.Errorf("[in AuthenticateKey]: %w", service.ErrInvalidAPIKey)},
+			expectedCode:         http.StatusUnauthorized,
+			expectedAuthenticate: "ApiKey",
+			expectedBody:         toJSONString(problem.New(http.StatusUnauthorized, "The API key is not valid").WithInstance("/api/user")),
+		},
+		"error authenticating key": {
+			headers:      map[string]string{"X-API-Key": "umk_secret"},
+			mockCalled:   true,
+			mockOutput:   []any{models.APIKey{}, errors.New("database error")},
+			expectedCode: http.StatusInternalServerError,
+			expectedBody: toJSONString(problem.New(http.StatusInternalServerError, "Internal server error").WithInstance("/api/user")),
+		},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			mockKeys := new(serviceMock.MockApiKeyAuthenticator)
+			if tc.mockCalled {
+				mockKeys.
+					On("AuthenticateKey", mock.Anything, "umk_secret").
+					Return(tc.mockOutput...).
+					Once()
+			}
+
+			req, err := http.NewRequest(http.MethodGet, "/api/user", nil)
+			assert.NoError(t, err)
+			for header, value := range tc.headers {
+				req.Header.Set(header, value)
+			}
+
+			var (
+				actualKey   models.APIKey
+				actualActor string
+			)
+			next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				actualKey, _ = r.Context().Value(apiKeyContextKey{}).(models.APIKey)
+				actualActor = audit.Actor(r.Context())
+				w.WriteHeader(http.StatusNoContent)
+			})
+			rr := httptest.NewRecorder()
+			AuthenticateAPIKey(slog.Default(), mockKeys, tc.fallback)(next).ServeHTTP(rr, req)
+
+			assert.Equal(t, tc.expectedCode, rr.Code, "Wrong code received")
+			assert.Equal(t, tc.expectedAuthenticate, rr.Header().Get("WWW-Authenticate"), "Wrong WWW-Authenticate header")
+			if tc.expectedBody != "" {
+				assert.JSONEq(t, tc.expectedBody, rr.Body.String(), "Wrong response body")
+			}
+			assert.Equal(t, tc.expectedKey, actualKey, "Wrong key in context")
+			assert.Equal(t, tc.expectedActor, actualActor, "Wrong actor")
+			mockKeys.AssertExpectations(t)
+		})
+	}
+}
+
+func TestRequireScope(t *testing.T) {
+	next := http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.WriteHeader(http.StatusNoContent)
+	})
+
+	tests := map[string]struct {
+		scopes       []models.APIKeyScope
+		withoutKey   bool
+		required     models.APIKeyScope
+		expectedCode int
+		expectedBody string
+	}{
+		"scope granted": {
+			scopes:       []models.APIKeyScope{models.ScopeUsersWrite},
+			required:     models.ScopeUsersWrite,
+			expectedCode: http.StatusNoContent,
+		},
+		"scope included in granted scope": {
+			scopes:       []models.APIKeyScope{models.ScopeUsersAdmin},
+			required:     models.ScopeUsersRead,
+			expectedCode: http.StatusNoContent,
+		},
+		"scope missing": {
+			scopes:       []models.APIKeyScope{models.ScopeUsersRead},
+			required:     models.ScopeUsersWrite,
+			expectedCode: http.StatusForbidden,
+			expectedBody: toJSONString(problem.New(http.StatusForbidden, "The API key does not have the users:write scope").WithInstance("/api/user")),
+		},
+		"not authenticated with key": {
+			withoutKey:   true,
+			required:     models.ScopeUsersAdmin,
+			expectedCode: http.StatusNoContent,
+		},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			mockKeys := new(serviceMock.MockApiKeyAuthenticator)
+			mockKeys.
+				On("AuthenticateKey", mock.Anything, "umk_secret").
+				Return(models.APIKey{Prefix: "umk_abcdefgh", Scopes: tc.scopes}, nil).
+				Maybe()
+
+			req, err := http.NewRequest(http.MethodPost, "/api/user", nil)
+			assert.NoError(t, err)
+			if !tc.withoutKey {
+				req.Header.Set(apiKeyHeader, "umk_secret")
+			}
+
+			rr := httptest.NewRecorder()
+			handler := AuthenticateAPIKey(slog.Default(), mockKeys, nil)(RequireScope(slog.Default(), tc.required)(next))
+			handler.ServeHTTP(rr, req)
+
+			assert.Equal(t, tc.expectedCode, rr.Code, "Wrong code received")
+			if tc.expectedBody != "" {
+				assert.JSONEq(t, tc.expectedBody, rr.Body.String(), "Wrong response body")
+			}
+		})
+	}
+}
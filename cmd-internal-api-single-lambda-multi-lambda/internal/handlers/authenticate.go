@@ -0,0 +1,92 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strings"
+
+	"github.com/awslabs/aws-lambda-go-api-proxy/core"
+
+	"github.com/jha-captech/user-microservice/internal/audit"
+	"github.com/jha-captech/user-microservice/internal/auth"
+	"github.com/jha-captech/user-microservice/internal/problem"
+)
+
+type tokenVerifier interface {
+	Verify(ctx context.Context, token string) (auth.Claims, error)
+}
+
+// Authenticate returns a middleware that refuses requests without a bearer token that verifier
+// accepts. The claims of the token are put into the request context, where they can be read with
+// auth.FromContext, and its subject is recorded as the actor of the request in place of the X-Actor
+// header.
+func Authenticate(logger sLogger, verifier tokenVerifier) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			logger := requestLogger(r, logger)
+
+			token, ok := bearerToken(r)
+			if !ok {
+				logger.Error("Request without bearer token", "path", r.URL.Path)
+				w.Header().Set("WWW-Authenticate", `Bearer`)
+				encodeProblem(w, r, logger, problem.New(http.StatusUnauthorized, "A bearer token is required"))
+				return
+			}
+
+			claims, err := verifier.Verify(r.Context(), token)
+			if err != nil {
+				switch {
+				case errors.Is(err, auth.ErrInvalidToken):
+					logger.Error("Invalid bearer token", "error", err)
+					w.Header().Set("WWW-Authenticate", `Bearer error="invalid_token"`)
+					encodeProblem(w, r, logger, problem.New(http.StatusUnauthorized, "The bearer token is not valid"))
+				default:
+					logger.Error("Error verifying bearer token", "error", err)
+					encodeProblem(w, r, logger, problem.New(http.StatusServiceUnavailable, "Unable to verify the bearer token"))
+				}
+				return
+			}
+
+			next.ServeHTTP(w, r.WithContext(withClaims(r.Context(), claims)))
+		})
+	}
+}
+
+// AuthenticateAuthorizer is a middleware for Lambda functions behind an API Gateway authorizer,
+// which has verified the token of a request before it reaches the function. It puts the claims of
+// the authorizer context into the request context, as Authenticate does for verified tokens, and
+// refuses requests without them, which only happens if the route has no authorizer.
+func AuthenticateAuthorizer(logger sLogger) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			logger := requestLogger(r, logger)
+
+			gateway, _ := core.GetAPIGatewayContextFromContext(r.Context())
+			claims, err := auth.FromAuthorizer(gateway.Authorizer)
+			if err != nil {
+				logger.Error("Request without authorizer claims", "path", r.URL.Path, "error", err)
+				w.Header().Set("WWW-Authenticate", `Bearer`)
+				encodeProblem(w, r, logger, problem.New(http.StatusUnauthorized, "A bearer token is required"))
+				return
+			}
+
+			next.ServeHTTP(w, r.WithContext(withClaims(r.Context(), claims)))
+		})
+	}
+}
+
+// withClaims returns a copy of ctx carrying claims, with their subject as the actor.
+func withClaims(ctx context.Context, claims auth.Claims) context.Context {
+	return audit.WithActor(auth.WithClaims(ctx, claims), claims.Subject)
+}
+
+// bearerToken returns the token of the Authorization header of r, if it uses the Bearer scheme.
+func bearerToken(r *http.Request) (string, bool) {
+	scheme, token, ok := strings.Cut(r.Header.Get("Authorization"), " ")
+	if !ok || !strings.EqualFold(scheme, "Bearer") {
+		return "", false
+	}
+	token = strings.TrimSpace(token)
+	return token, token != ""
+}
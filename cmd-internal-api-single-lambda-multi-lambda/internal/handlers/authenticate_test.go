@@ -0,0 +1,165 @@
+package handlers
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/awslabs/aws-lambda-go-api-proxy/core"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+
+	"github.com/jha-captech/user-microservice/internal/audit"
+	"github.com/jha-captech/user-microservice/internal/auth"
+	"github.com/jha-captech/user-microservice/internal/problem"
+
+	serviceMock "github.com/jha-captech/user-microservice/internal/handlers/mock"
+)
+
+// claimsRecorder returns a handler that records the claims and actor of the requests it serves.
+func claimsRecorder(claims *auth.Claims, actor *string) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		*claims, _ = auth.FromContext(r.Context())
+		*actor = audit.Actor(r.Context())
+		w.WriteHeader(http.StatusNoContent)
+	})
+}
+
+func TestAuthenticate(t *testing.T) {
+	claims := auth.Claims{Subject: "jdoe", Issuer: "https://issuer.example.com/", Audience: []string{"user-microservice"}}
+
+	tests := map[string]struct {
+		header               string
+		mockCalled           bool
+		mockOutput           []any
+		expectedCode         int
+		expectedAuthenticate string
+		expectedBody         string
+		expectedClaims       auth.Claims
+		expectedActor        string
+	}{
+		"valid token": {
+			header:         "Bearer token",
+			mockCalled:     true,
+			mockOutput:     []any{claims, nil},
+			expectedCode:   http.StatusNoContent,
+			expectedClaims: claims,
+			expectedActor:  "jdoe",
+		},
+		"scheme is case insensitive": {
+			header:         "bearer token",
+			mockCalled:     true,
+			mockOutput:     []any{claims, nil},
+			expectedCode:   http.StatusNoContent,
+			expectedClaims: claims,
+			expectedActor:  "jdoe",
+		},
+		"no token": {
+			expectedCode:         http.StatusUnauthorized,
+			expectedAuthenticate: "Bearer",
+			expectedBody:         toJSONString(problem.New(http.StatusUnauthorized, "A bearer token is required").WithInstance("/api/user")),
+		},
+		"not a bearer token": {
+			header:               "Basic dXNlcjpwYXNz",
+			expectedCode:         http.StatusUnauthorized,
+			expectedAuthenticate: "Bearer",
+			expectedBody:         toJSONString(problem.New(http.StatusUnauthorized, "A bearer token is required").WithInstance("/api/user")),
+		},
+		"invalid token": {
+			header:               "Bearer token",
+			mockCalled:           true,
+			mockOutput:           []any{auth.Claims{}, fmt.Errorf("expired: %w", auth.ErrInvalidToken)},
+			expectedCode:         http.StatusUnauthorized,
+			expectedAuthenticate: `Bearer error="invalid_token"`,
+			expectedBody:         toJSONString(problem.New(http.StatusUnauthorized, "The bearer token is not valid").WithInstance("/api/user")),
+		},
+		"keys unavailable": {
+			header:       "Bearer token",
+			mockCalled:   true,
+			mockOutput:   []any{auth.Claims{}, fmt.Errorf("fetch JWK Set: unexpected status 503")},
+			expectedCode: http.StatusServiceUnavailable,
+			expectedBody: toJSONString(problem.New(http.StatusServiceUnavailable, "Unable to verify the bearer token").WithInstance("/api/user")),
+		},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			mockVerifier := new(serviceMock.MockTokenVerifier)
+			if tc.mockCalled {
+				mockVerifier.
+					On("Verify", mock.Anything, "token").
+					Return(tc.mockOutput...).
+					Once()
+			}
+
+			req, err := http.NewRequest(http.MethodGet, "/api/user", nil)
+			assert.NoError(t, err)
+			req.Header.Set(actorHeader, "spoofed")
+			if tc.header != "" {
+				req.Header.Set("Authorization", tc.header)
+			}
+
+			var (
+				actualClaims auth.Claims
+				actualActor  string
+			)
+			handler := IdentifyActor(Authenticate(slog.Default(), mockVerifier)(claimsRecorder(&actualClaims, &actualActor)))
+			rr := httptest.NewRecorder()
+			handler.ServeHTTP(rr, req)
+
+			assert.Equal(t, tc.expectedCode, rr.Code, "Wrong code received")
+			assert.Equal(t, tc.expectedAuthenticate, rr.Header().Get("WWW-Authenticate"), "Wrong WWW-Authenticate header")
+			if tc.expectedBody != "" {
+				assert.JSONEq(t, tc.expectedBody, rr.Body.String(), "Wrong response body")
+			}
+			assert.Equal(t, tc.expectedClaims, actualClaims, "Wrong claims in context")
+			assert.Equal(t, tc.expectedActor, actualActor, "Wrong actor")
+			mockVerifier.AssertExpectations(t)
+		})
+	}
+}
+
+func TestAuthenticateAuthorizer(t *testing.T) {
+	tests := map[string]struct {
+		authorizer      map[string]any
+		expectedCode    int
+		expectedSubject string
+	}{
+		"authorizer claims": {
+			authorizer:      map[string]any{"claims": map[string]any{"sub": "jdoe", "aud": "user-microservice"}},
+			expectedCode:    http.StatusNoContent,
+			expectedSubject: "jdoe",
+		},
+		"no authorizer": {
+			expectedCode: http.StatusUnauthorized,
+		},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			req, err := new(core.RequestAccessor).EventToRequestWithContext(context.Background(), events.APIGatewayProxyRequest{
+				HTTPMethod:     http.MethodGet,
+				Path:           "/api/user",
+				RequestContext: events.APIGatewayProxyRequestContext{Authorizer: tc.authorizer},
+			})
+			assert.NoError(t, err)
+
+			var (
+				actualClaims auth.Claims
+				actualActor  string
+			)
+			rr := httptest.NewRecorder()
+			AuthenticateAuthorizer(slog.Default())(claimsRecorder(&actualClaims, &actualActor)).ServeHTTP(rr, req)
+
+			assert.Equal(t, tc.expectedCode, rr.Code, "Wrong code received")
+			assert.Equal(t, tc.expectedSubject, actualClaims.Subject, "Wrong claims in context")
+			if tc.expectedSubject != "" {
+				assert.Equal(t, tc.expectedSubject, actualActor, "Wrong actor")
+			}
+		})
+	}
+}
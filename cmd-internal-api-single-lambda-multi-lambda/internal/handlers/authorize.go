@@ -0,0 +1,185 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/go-chi/chi/v5"
+
+	"github.com/jha-captech/user-microservice/internal/auth"
+	"github.com/jha-captech/user-microservice/internal/logging"
+	"github.com/jha-captech/user-microservice/internal/models"
+	"github.com/jha-captech/user-microservice/internal/policy"
+	"github.com/jha-captech/user-microservice/internal/problem"
+	svc "github.com/jha-captech/user-microservice/internal/service"
+)
+
+type principalFetcher interface {
+	FetchUserByUserID(ctx context.Context, userID uint) (models.User, error)
+}
+
+// Authorize returns a middleware that identifies the user making a request by the user_id claim of
+// the token that authenticated it, and puts them into the request context as a policy.Principal
+// with the rule that rules has for their role. Requests whose token has no user_id claim, or names a
+// user that does not exist, are refused. Requests without claims, which are only served when
+// authentication is disabled, are passed on without a principal, and Permit lets them through.
+func Authorize(logger sLogger, service principalFetcher, rules policy.Policy) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			logger := requestLogger(r, logger)
+
+			ctx := r.Context()
+
+			claims, ok := auth.FromContext(ctx)
+			if !ok {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			userID, ok := claims.UserID()
+			if !ok {
+				logger.Error("Token without user_id claim", "subject", claims.Subject)
+				encodeProblem(w, r, logger, problem.New(http.StatusForbidden, "The bearer token does not identify a user"))
+				return
+			}
+
+			user, err := service.FetchUserByUserID(ctx, userID)
+			if err != nil {
+				switch {
+				case errors.Is(err, svc.ErrUserNotFound):
+					logger.Error("Token for unknown user", "subject", claims.Subject, "user_id", userID)
+					encodeProblem(w, r, logger, problem.New(http.StatusForbidden, "The bearer token does not identify a user"))
+				default:
+					logger.Error("error getting user of token", "error", err)
+					encodeProblem(w, r, logger, problem.New(http.StatusInternalServerError, "Internal server error"))
+				}
+				return
+			}
+
+			principal := rules.Principal(user.UserID, user.Role)
+			ctx = logging.With(ctx, "user_id", user.UserID)
+			next.ServeHTTP(w, r.WithContext(policy.WithPrincipal(ctx, principal)))
+		})
+	}
+}
+
+// Permit returns a middleware that refuses requests whose principal may not take every one of
+// actions. On routes of a single user, with an ID URL parameter, actions the principal may only
+// take on their own record are refused if the user belongs to someone else. Routes of a collection
+// of users can only be used by principals that may take every one of actions on all users. Requests
+// without a principal are let through, see Authorize.
+func Permit(logger sLogger, service userFetcher, actions ...policy.Action) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			logger := requestLogger(r, logger)
+
+			ctx := r.Context()
+
+			principal, ok := policy.FromContext(ctx)
+			if !ok {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			// the owner of the user is only looked up once an action is limited to the own record
+			var (
+				owner       uint
+				ownerLoaded bool
+			)
+			for _, action := range actions {
+				scope, err := principal.Scope(action)
+				if err == nil && scope == policy.ScopeOwn {
+					if !ownerLoaded {
+						owner, ok, err = userOwner(ctx, service, chi.URLParam(r, "ID"))
+						if err != nil {
+							logger.Error("error getting object by ID", "error", err)
+							encodeProblem(w, r, logger, problem.New(http.StatusInternalServerError, "Internal server error"))
+							return
+						}
+						if !ok {
+							// the handler reports the ID as invalid or the user as not existing
+							next.ServeHTTP(w, r)
+							return
+						}
+						ownerLoaded = true
+					}
+					err = principal.Authorize(action, owner)
+				}
+				if err != nil {
+					logger.Error("Request forbidden by policy", "path", r.URL.Path, "user_id", principal.UserID, "error", err)
+					encodeProblem(w, r, logger, forbiddenProblem(err))
+					return
+				}
+			}
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// userOwner returns the user_id of the user with the ID idString, and false if idString is not a
+// valid ID or no such user exists. Routes of a collection of users have no ID, and their owner is
+// 0, which is never the user_id of a principal.
+func userOwner(ctx context.Context, service userFetcher, idString string) (uint, bool, error) {
+	if idString == "" {
+		return 0, true, nil
+	}
+
+	ID, err := strconv.Atoi(idString)
+	if err != nil {
+		return 0, false, nil
+	}
+
+	user, err := service.FetchUser(ctx, ID, true)
+	if err != nil {
+		if errors.Is(err, svc.ErrUserNotFound) {
+			return 0, false, nil
+		}
+		return 0, false, err
+	}
+
+	return user.UserID, true, nil
+}
+
+// authorizeChanges returns a *policy.ForbiddenError if changes sets a field that is read-only for
+// the principal of ctx. Without a principal, every change is allowed.
+func authorizeChanges(ctx context.Context, changes models.UserChanges) error {
+	principal, ok := policy.FromContext(ctx)
+	if !ok {
+		return nil
+	}
+
+	var fields []string
+	if changes.FirstName != nil {
+		fields = append(fields, "first_name")
+	}
+	if changes.LastName != nil {
+		fields = append(fields, "last_name")
+	}
+	if changes.Role != nil {
+		fields = append(fields, "role")
+	}
+	if changes.UserID != nil {
+		fields = append(fields, "user_id")
+	}
+	return principal.AuthorizeChanges(fields)
+}
+
+// forbiddenProblem returns the problem reported for err, a *policy.ForbiddenError.
+func forbiddenProblem(err error) problem.Problem {
+	var forbidden *policy.ForbiddenError
+	if !errors.As(err, &forbidden) {
+		return problem.New(http.StatusForbidden, "Forbidden")
+	}
+
+	switch {
+	case forbidden.Field != "":
+		return problem.New(http.StatusForbidden, "You are not allowed to change "+forbidden.Field)
+	case forbidden.Owner:
+		return problem.New(http.StatusForbidden, "You are only allowed to "+string(forbidden.Action)+" your own user")
+	default:
+		return problem.New(http.StatusForbidden, "You are not allowed to "+string(forbidden.Action)+" users")
+	}
+}
@@ -0,0 +1,238 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+
+	"github.com/jha-captech/user-microservice/internal/auth"
+	"github.com/jha-captech/user-microservice/internal/models"
+	"github.com/jha-captech/user-microservice/internal/policy"
+	"github.com/jha-captech/user-microservice/internal/problem"
+	"github.com/jha-captech/user-microservice/internal/service"
+
+	serviceMock "github.com/jha-captech/user-microservice/internal/handlers/mock"
+)
+
+// principalRecorder returns a handler that records the principal of the requests it serves.
+func principalRecorder(principal *policy.Principal, ok *bool) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		*principal, *ok = policy.FromContext(r.Context())
+		w.WriteHeader(http.StatusNoContent)
+	})
+}
+
+func TestAuthorize(t *testing.T) {
+	customer := models.User{ID: 1, FirstName: "John", LastName: "Doe", Role: "Customer", UserID: 1001}
+
+	tests := map[string]struct {
+		claims            *auth.Claims
+		mockCalled        bool
+		mockOutput        []any
+		expectedCode      int
+		expectedBody      string
+		expectedPrincipal *policy.Principal
+	}{
+		"known user": {
+			claims:            &auth.Claims{Subject: "jdoe", Raw: map[string]any{"user_id": json.Number("1001")}},
+			mockCalled:        true,
+			mockOutput:        []any{customer, nil},
+			expectedCode:      http.StatusNoContent,
+			expectedPrincipal: ptr(policy.Default().Principal(1001, "Customer")),
+		},
+		"no claims": {
+			expectedCode: http.StatusNoContent,
+		},
+		"no user_id claim": {
+			claims:       &auth.Claims{Subject: "jdoe", Raw: map[string]any{}},
+			expectedCode: http.StatusForbidden,
+			expectedBody: toJSONString(problem.New(http.StatusForbidden, "The bearer token does not identify a user").WithInstance("/api/user")),
+		},
+		"unknown user": {
+			claims:       &auth.Claims{Subject: "jdoe", Raw: map[string]any{"user_id": "1001"}},
+			mockCalled:   true,
+			mockOutput:   []any{models.User{}, fmt.Errorf("[in FetchUserByUserID]: %w", service.ErrUserNotFound)},
+			expectedCode: http.StatusForbidden,
+			expectedBody: toJSONString(problem.New(http.StatusForbidden, "The bearer token does not identify a user").WithInstance("/api/user")),
+		},
+		"error getting user": {
+			claims:       &auth.Claims{Subject: "jdoe", Raw: map[string]any{"user_id": "1001"}},
+			mockCalled:   true,
+			mockOutput:   []any{models.User{}, errors.New("connection refused")},
+			expectedCode: http.StatusInternalServerError,
+			expectedBody: toJSONString(problem.New(http.StatusInternalServerError, "Internal server error").WithInstance("/api/user")),
+		},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			mockService := new(serviceMock.MockPrincipalFetcher)
+			if tc.mockCalled {
+				mockService.
+					On("FetchUserByUserID", mock.Anything, uint(1001)).
+					Return(tc.mockOutput...).
+					Once()
+			}
+
+			req, err := http.NewRequest(http.MethodGet, "/api/user", nil)
+			assert.NoError(t, err)
+			if tc.claims != nil {
+				req = req.WithContext(auth.WithClaims(req.Context(), *tc.claims))
+			}
+
+			var (
+				actualPrincipal policy.Principal
+				actualOK        bool
+			)
+			rr := httptest.NewRecorder()
+			Authorize(slog.Default(), mockService, policy.Default())(principalRecorder(&actualPrincipal, &actualOK)).ServeHTTP(rr, req)
+
+			assert.Equal(t, tc.expectedCode, rr.Code, "Wrong code received")
+			if tc.expectedBody != "" {
+				assert.JSONEq(t, tc.expectedBody, rr.Body.String(), "Wrong response body")
+			}
+			if tc.expectedPrincipal != nil {
+				assert.True(t, actualOK, "No principal in context")
+				assert.Equal(t, *tc.expectedPrincipal, actualPrincipal, "Wrong principal in context")
+			} else {
+				assert.False(t, actualOK, "Unexpected principal in context")
+			}
+			mockService.AssertExpectations(t)
+		})
+	}
+}
+
+func TestPermit(t *testing.T) {
+	rules := policy.Default()
+	customer := rules.Principal(1001, "Customer")
+	employee := rules.Principal(2001, "Employee")
+	ownUser := models.User{ID: 1, FirstName: "John", LastName: "Doe", Role: "Customer", UserID: 1001}
+	otherUser := models.User{ID: 2, FirstName: "Jane", LastName: "Doe", Role: "Customer", UserID: 1002}
+
+	tests := map[string]struct {
+		principal    *policy.Principal
+		actions      []policy.Action
+		IDParam      string
+		mockCalled   bool
+		mockOutput   []any
+		expectedCode int
+		expectedBody string
+	}{
+		"no principal": {
+			actions:      []policy.Action{policy.ActionDelete},
+			IDParam:      "2",
+			expectedCode: http.StatusNoContent,
+		},
+		"employee updates any user": {
+			principal:    &employee,
+			actions:      []policy.Action{policy.ActionUpdate},
+			IDParam:      "2",
+			expectedCode: http.StatusNoContent,
+		},
+		"employee lists users": {
+			principal:    &employee,
+			actions:      []policy.Action{policy.ActionList},
+			expectedCode: http.StatusNoContent,
+		},
+		"customer reads own user": {
+			principal:    &customer,
+			actions:      []policy.Action{policy.ActionRead},
+			IDParam:      "1",
+			mockCalled:   true,
+			mockOutput:   []any{ownUser, nil},
+			expectedCode: http.StatusNoContent,
+		},
+		"customer reads other user": {
+			principal:    &customer,
+			actions:      []policy.Action{policy.ActionRead},
+			IDParam:      "2",
+			mockCalled:   true,
+			mockOutput:   []any{otherUser, nil},
+			expectedCode: http.StatusForbidden,
+			expectedBody: toJSONString(problem.New(http.StatusForbidden, "You are only allowed to read your own user").WithInstance("/api/user/2")),
+		},
+		"customer reads user that does not exist": {
+			principal:    &customer,
+			actions:      []policy.Action{policy.ActionRead},
+			IDParam:      "2",
+			mockCalled:   true,
+			mockOutput:   []any{models.User{}, fmt.Errorf("[in FetchUser]: %w", service.ErrUserNotFound)},
+			expectedCode: http.StatusNoContent,
+		},
+		"customer deletes own user": {
+			principal:    &customer,
+			actions:      []policy.Action{policy.ActionDelete},
+			IDParam:      "1",
+			expectedCode: http.StatusForbidden,
+			expectedBody: toJSONString(problem.New(http.StatusForbidden, "You are not allowed to delete users").WithInstance("/api/user/1")),
+		},
+		"customer lists users": {
+			principal:    &customer,
+			actions:      []policy.Action{policy.ActionList},
+			expectedCode: http.StatusForbidden,
+			expectedBody: toJSONString(problem.New(http.StatusForbidden, "You are not allowed to list users").WithInstance("/api/user")),
+		},
+		"error getting user": {
+			principal:    &customer,
+			actions:      []policy.Action{policy.ActionUpdate},
+			IDParam:      "1",
+			mockCalled:   true,
+			mockOutput:   []any{models.User{}, errors.New("connection refused")},
+			expectedCode: http.StatusInternalServerError,
+			expectedBody: toJSONString(problem.New(http.StatusInternalServerError, "Internal server error").WithInstance("/api/user/1")),
+		},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			mockService := new(serviceMock.MockUserFetcher)
+
+			path := "/api/user"
+			rctx := chi.NewRouteContext()
+			if tc.IDParam != "" {
+				path += "/" + tc.IDParam
+				rctx.URLParams.Add("ID", tc.IDParam)
+			}
+			req, err := http.NewRequest(http.MethodGet, path, nil)
+			assert.NoError(t, err)
+			ctx := context.WithValue(req.Context(), chi.RouteCtxKey, rctx)
+			if tc.principal != nil {
+				ctx = policy.WithPrincipal(ctx, *tc.principal)
+			}
+			req = req.WithContext(ctx)
+
+			if tc.mockCalled {
+				ID, _ := strconv.Atoi(tc.IDParam)
+				mockService.
+					On("FetchUser", ctx, ID, true).
+					Return(tc.mockOutput...).
+					Once()
+			}
+
+			next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(http.StatusNoContent)
+			})
+			rr := httptest.NewRecorder()
+			Permit(slog.Default(), mockService, tc.actions...)(next).ServeHTTP(rr, req)
+
+			assert.Equal(t, tc.expectedCode, rr.Code, "Wrong code received")
+			if tc.expectedBody != "" {
+				assert.JSONEq(t, tc.expectedBody, rr.Body.String(), "Wrong response body")
+			}
+			mockService.AssertExpectations(t)
+			if !tc.mockCalled {
+				mockService.AssertNotCalled(t, "FetchUser")
+			}
+		})
+	}
+}
@@ -0,0 +1,138 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"net/http"
+
+	"github.com/jha-captech/user-microservice/internal/models"
+	"github.com/jha-captech/user-microservice/internal/problem"
+	svc "github.com/jha-captech/user-microservice/internal/service"
+)
+
+type userBatcher interface {
+	BatchUsers(ctx context.Context, operations []models.BatchOperation, atomic bool) ([]models.BatchResult, error)
+}
+
+// HandleBatchUsers is a Handler that creates, updates and deletes users in bulk. Every operation is
+// validated on its own and the response has a result with a status for each of them, in the order of
+// the request. In atomic mode, which is the default, nothing is changed unless every operation
+// succeeds. In best_effort mode, the valid operations are applied even when others fail.
+//
+// @Summary		Create, update and delete users in bulk
+// @Description	Create, update and delete users in bulk
+// @Tags		user
+// @Accept		json
+// @Produce		json
+// @Param		batch		body		handlers.inputBatch	true	"Batch of operations"
+// @Success		207			{object}	handlers.responseBatch
+// @Failure		400			{object}	problem.Problem
+// @Failure		500			{object}	problem.Problem
+// @Router		/user:batch	[POST]
+func HandleBatchUsers(logger sLogger, service userBatcher) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		// setup
+		logger := requestLogger(r, logger)
+		ctx := r.Context()
+
+		// get and validate body as object
+		batch, problems, err := decodeValidateBody[inputBatch, batchOperations](r)
+		if err != nil {
+			switch {
+			case len(problems) > 0:
+				logger.Error("Problems validating input", "error", err, "problems", problems)
+				encodeProblem(w, r, logger, problem.
+					New(http.StatusBadRequest, "One or more fields in the request body are invalid").
+					WithFieldErrors(problems),
+				)
+			default:
+				logger.Error("BodyParser error", "error", err)
+				encodeProblem(w, r, logger, problem.New(http.StatusBadRequest, "missing values or malformed body"))
+			}
+			return
+		}
+
+		// an atomic batch with invalid operations is not applied at all
+		results := make([]outputBatchResult, batch.Size)
+		for i := range results {
+			results[i] = outputBatchResult{
+				Index:  i,
+				Status: http.StatusFailedDependency,
+				Detail: "Not applied because another operation in the batch failed",
+			}
+		}
+		for i, itemProblems := range batch.Problems {
+			results[i].Status = http.StatusBadRequest
+			results[i].Detail = "One or more fields in the operation are invalid"
+			results[i].Errors = problem.New(http.StatusBadRequest, "").WithFieldErrors(itemProblems).Errors
+		}
+		if len(batch.Problems) > 0 {
+			logger.Error("Problems validating batch operations", "invalid", len(batch.Problems), "atomic", batch.Atomic)
+		}
+		if len(batch.Operations) == 0 || (batch.Atomic && len(batch.Problems) > 0) {
+			encodeResponse(w, logger, http.StatusMultiStatus, responseBatch{
+				Results: results,
+			})
+			return
+		}
+
+		// apply operations in database
+		batchResults, err := service.BatchUsers(ctx, batch.Operations, batch.Atomic)
+		if err != nil {
+			logger.Error("error applying batch to database", "error", err)
+			encodeProblem(w, r, logger, problem.New(http.StatusInternalServerError, "Error applying batch"))
+			return
+		}
+
+		for i, result := range batchResults {
+			index := batch.Indexes[i]
+			results[index] = mapBatchResult(logger, index, batch.Operations[i].Op, result)
+		}
+
+		// return response
+		encodeResponse(w, logger, http.StatusMultiStatus, responseBatch{
+			Results: results,
+		})
+	}
+}
+
+// mapBatchResult returns the result at index in the response for the outcome of an operation op.
+// The status of an operation matches what the single user endpoints return.
+func mapBatchResult(logger sLogger, index int, op models.BatchOp, result models.BatchResult) outputBatchResult {
+	output := outputBatchResult{
+		Index: index,
+	}
+
+	switch {
+	case result.Err == nil:
+		output.ID = result.ID
+		output.Version = int(result.Version)
+		switch op {
+		case models.BatchCreate:
+			output.Status = http.StatusCreated
+		case models.BatchDelete:
+			output.Status = http.StatusAccepted
+			output.Version = 0
+		default:
+			output.Status = http.StatusOK
+		}
+	case errors.Is(result.Err, svc.ErrUserNotFound):
+		output.Status = http.StatusNotFound
+		output.Detail = "Object does not exist"
+	case errors.Is(result.Err, svc.ErrUserIDConflict):
+		output.Status = http.StatusConflict
+		output.Detail = "user_id already in use"
+	case errors.Is(result.Err, svc.ErrBatchDuplicateID):
+		output.Status = http.StatusConflict
+		output.Detail = "User is changed by more than one operation in the batch"
+	case errors.Is(result.Err, svc.ErrBatchAborted):
+		output.Status = http.StatusFailedDependency
+		output.Detail = "Not applied because another operation in the batch failed"
+	default:
+		logger.Error("error applying batch operation", "index", index, "op", op, "error", result.Err)
+		output.Status = http.StatusInternalServerError
+		output.Detail = "Error applying operation"
+	}
+
+	return output
+}
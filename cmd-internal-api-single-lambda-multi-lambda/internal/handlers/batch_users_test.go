@@ -0,0 +1,200 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+
+	serviceMock "github.com/jha-captech/user-microservice/internal/handlers/mock"
+	"github.com/jha-captech/user-microservice/internal/models"
+	"github.com/jha-captech/user-microservice/internal/problem"
+	"github.com/jha-captech/user-microservice/internal/service"
+)
+
+func TestHandleBatchUsers(t *testing.T) {
+	mockService := new(serviceMock.MockUserBatcher)
+	logger := slog.Default()
+	handler := HandleBatchUsers(logger, mockService)
+
+	john := models.User{FirstName: "John", LastName: "Doe", Role: "Customer", UserID: 1001}
+	johnIn := inputUser{FirstName: "John", LastName: "Doe", Role: "Customer", UserID: 1001}
+	aborted := outputBatchResult{
+		Status: http.StatusFailedDependency,
+		Detail: "Not applied because another operation in the batch failed",
+	}
+	withIndex := func(result outputBatchResult, index int) outputBatchResult {
+		result.Index = index
+		return result
+	}
+
+	tests := map[string]struct {
+		mockCalled   bool
+		mockInput    []any
+		mockOutput   []any
+		requestBody  string
+		expectedCode int
+		expectedBody string
+	}{
+		"atomic batch applied": {
+			mockCalled: true,
+			mockInput: []any{[]models.BatchOperation{
+				{Op: models.BatchCreate, User: john},
+				{Op: models.BatchUpdate, ID: 5, User: john},
+				{Op: models.BatchDelete, ID: 7},
+			}, true},
+			mockOutput: []any{[]models.BatchResult{{ID: 10, Version: 1}, {ID: 5, Version: 4}, {ID: 7, Version: 2}}, nil},
+			requestBody: toJSONString(inputBatch{Operations: []inputBatchOperation{
+				{Op: "create", User: &johnIn},
+				{Op: "update", ID: 5, User: &johnIn},
+				{Op: "delete", ID: 7},
+			}}),
+			expectedCode: http.StatusMultiStatus,
+			expectedBody: toJSONString(responseBatch{Results: []outputBatchResult{
+				{Index: 0, Status: http.StatusCreated, ID: 10, Version: 1},
+				{Index: 1, Status: http.StatusOK, ID: 5, Version: 4},
+				{Index: 2, Status: http.StatusAccepted, ID: 7},
+			}}),
+		},
+		"best effort batch skips invalid operations": {
+			mockCalled: true,
+			mockInput: []any{[]models.BatchOperation{
+				{Op: models.BatchCreate, User: john},
+				{Op: models.BatchDelete, ID: 8},
+				{Op: models.BatchDelete, ID: 9},
+				{Op: models.BatchDelete, ID: 10},
+			}, false},
+			mockOutput: []any{[]models.BatchResult{
+				{ID: 11, Version: 1},
+				{Err: service.ErrUserNotFound},
+				{Err: fmt.Errorf("%w: duplicate key", service.ErrUserIDConflict)},
+				{Err: errors.New("connection reset")},
+			}, nil},
+			requestBody: toJSONString(inputBatch{Mode: "best_effort", Operations: []inputBatchOperation{
+				{Op: "create", User: &johnIn},
+				{Op: "update", User: &inputUser{Role: "Admin"}},
+				{Op: "delete", ID: 8},
+				{Op: "upsert", ID: 8},
+				{Op: "delete", ID: 9},
+				{Op: "delete", ID: 10},
+			}}),
+			expectedCode: http.StatusMultiStatus,
+			expectedBody: toJSONString(responseBatch{Results: []outputBatchResult{
+				{Index: 0, Status: http.StatusCreated, ID: 11, Version: 1},
+				{
+					Index:  1,
+					Status: http.StatusBadRequest,
+					Detail: "One or more fields in the operation are invalid",
+					Errors: []problem.FieldError{
+						{Field: "id", Detail: "must be more than 0"},
+						{Field: "user.first_name", Detail: "must not be blank"},
+						{Field: "user.role", Detail: "must be 'Customer' or 'Employee'"},
+						{Field: "user.user_id", Detail: "must be more than 0"},
+					},
+				},
+				{Index: 2, Status: http.StatusNotFound, Detail: "Object does not exist"},
+				{
+					Index:  3,
+					Status: http.StatusBadRequest,
+					Detail: "One or more fields in the operation are invalid",
+					Errors: []problem.FieldError{{Field: "op", Detail: "must be one of [create update delete]"}},
+				},
+				{Index: 4, Status: http.StatusConflict, Detail: "user_id already in use"},
+				{Index: 5, Status: http.StatusInternalServerError, Detail: "Error applying operation"},
+			}}),
+		},
+		"atomic batch with invalid operation is not applied": {
+			mockCalled: false,
+			requestBody: toJSONString(inputBatch{Mode: "atomic", Operations: []inputBatchOperation{
+				{Op: "create", User: &johnIn},
+				{Op: "create"},
+			}}),
+			expectedCode: http.StatusMultiStatus,
+			expectedBody: toJSONString(responseBatch{Results: []outputBatchResult{
+				withIndex(aborted, 0),
+				{
+					Index:  1,
+					Status: http.StatusBadRequest,
+					Detail: "One or more fields in the operation are invalid",
+					Errors: []problem.FieldError{{Field: "user", Detail: "must be set"}},
+				},
+			}}),
+		},
+		"atomic batch aborted by service": {
+			mockCalled: true,
+			mockInput: []any{[]models.BatchOperation{
+				{Op: models.BatchDelete, ID: 7},
+				{Op: models.BatchDelete, ID: 7},
+			}, true},
+			mockOutput: []any{[]models.BatchResult{{Err: service.ErrBatchAborted}, {Err: service.ErrBatchDuplicateID}}, nil},
+			requestBody: toJSONString(inputBatch{Operations: []inputBatchOperation{
+				{Op: "delete", ID: 7},
+				{Op: "delete", ID: 7},
+			}}),
+			expectedCode: http.StatusMultiStatus,
+			expectedBody: toJSONString(responseBatch{Results: []outputBatchResult{
+				withIndex(aborted, 0),
+				{Index: 1, Status: http.StatusConflict, Detail: "User is changed by more than one operation in the batch"},
+			}}),
+		},
+		"invalid batch": {
+			mockCalled:   false,
+			requestBody:  `{"mode":"sometimes","operations":[]}`,
+			expectedCode: http.StatusBadRequest,
+			expectedBody: toJSONString(problem.
+				New(http.StatusBadRequest, "One or more fields in the request body are invalid").
+				WithInstance("/api/user:batch").
+				WithFieldErrors(map[string]string{
+					"mode":       "must be 'atomic' or 'best_effort'",
+					"operations": fmt.Sprintf("must have between 1 and %d operations", maxBatchOperations),
+				}),
+			),
+		},
+		"malformed body": {
+			mockCalled:   false,
+			requestBody:  `{"operations":`,
+			expectedCode: http.StatusBadRequest,
+			expectedBody: toJSONString(problem.New(http.StatusBadRequest, "missing values or malformed body").WithInstance("/api/user:batch")),
+		},
+		"error applying batch": {
+			mockCalled:   true,
+			mockInput:    []any{[]models.BatchOperation{{Op: models.BatchDelete, ID: 7}}, true},
+			mockOutput:   []any{[]models.BatchResult(nil), errors.New("begin error")},
+			requestBody:  toJSONString(inputBatch{Operations: []inputBatchOperation{{Op: "delete", ID: 7}}}),
+			expectedCode: http.StatusInternalServerError,
+			expectedBody: toJSONString(problem.New(http.StatusInternalServerError, "Error applying batch").WithInstance("/api/user:batch")),
+		},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			req, err := http.NewRequest(http.MethodPost, "/api/user:batch", strings.NewReader(tc.requestBody))
+			assert.NoError(t, err)
+
+			if tc.mockCalled {
+				mockService.
+					On("BatchUsers", append([]any{mock.Anything}, tc.mockInput...)...).
+					Return(tc.mockOutput...).
+					Once()
+			}
+
+			rr := httptest.NewRecorder()
+			handler.ServeHTTP(rr, req)
+
+			assert.Equal(t, tc.expectedCode, rr.Code, "Wrong code received")
+			assert.JSONEq(t, tc.expectedBody, rr.Body.String(), "Wrong response body")
+
+			if tc.mockCalled {
+				mockService.AssertExpectations(t)
+			} else {
+				mockService.AssertNotCalled(t, "BatchUsers")
+			}
+		})
+	}
+}
@@ -0,0 +1,67 @@
+package handlers
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/jha-captech/user-microservice/internal/models"
+	"github.com/jha-captech/user-microservice/internal/problem"
+)
+
+type apiKeyCreator interface {
+	CreateKey(ctx context.Context, key models.APIKey) (models.APIKey, string, error)
+}
+
+// HandleCreateAPIKey is a Handler that mints an API key with the name, scopes and optional expiry
+// of the request body. The key is only returned in the response, as just its hash is stored.
+//
+// @Summary		Create an API key
+// @Description	Mint an API key for a service or batch job
+// @Tags		api-key
+// @Accept		json
+// @Produce		json
+// @Param		api_key			body		handlers.inputAPIKey	true	"API Key Object"
+// @Param		X-Admin-Token	header		string					true	"Admin token"
+// @Success		201				{object}	handlers.responseCreatedAPIKey
+// @Failure		400				{object}	problem.Problem
+// @Failure		403				{object}	problem.Problem
+// @Failure		500				{object}	problem.Problem
+// @Router		/api-key		[POST]
+func HandleCreateAPIKey(logger sLogger, service apiKeyCreator) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		// setup
+		logger := requestLogger(r, logger)
+		ctx := r.Context()
+
+		// get and validate body as object
+		keyIn, problems, err := decodeValidateBody[inputAPIKey, models.APIKey](r)
+		if err != nil {
+			switch {
+			case len(problems) > 0:
+				logger.Error("Problems validating input", "error", err, "problems", problems)
+				encodeProblem(w, r, logger, problem.
+					New(http.StatusBadRequest, "One or more fields in the request body are invalid").
+					WithFieldErrors(problems),
+				)
+			default:
+				logger.Error("BodyParser error", "error", err)
+				encodeProblem(w, r, logger, problem.New(http.StatusBadRequest, "missing values or malformed body"))
+			}
+			return
+		}
+
+		// create key in database
+		key, secret, err := service.CreateKey(ctx, keyIn)
+		if err != nil {
+			logger.Error("error creating api key", "error", err)
+			encodeProblem(w, r, logger, problem.New(http.StatusInternalServerError, "Error creating object"))
+			return
+		}
+
+		// return response
+		encodeResponse(w, logger, http.StatusCreated, responseCreatedAPIKey{
+			APIKey: mapAPIKeyOutput(key),
+			Key:    secret,
+		})
+	}
+}
@@ -0,0 +1,121 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/jha-captech/user-microservice/internal/models"
+	"github.com/jha-captech/user-microservice/internal/problem"
+	"github.com/stretchr/testify/assert"
+
+	serviceMock "github.com/jha-captech/user-microservice/internal/handlers/mock"
+)
+
+func TestHandleCreateAPIKey(t *testing.T) {
+	mockService := new(serviceMock.MockApiKeyCreator)
+	logger := slog.Default()
+	handler := HandleCreateAPIKey(logger, mockService)
+
+	createdAt := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	expiresAt := time.Date(2999, 1, 1, 0, 0, 0, 0, time.UTC)
+	keyIn := inputAPIKey{Name: "nightly export", Scopes: []string{"users:read"}, ExpiresAt: &expiresAt}
+	key := models.APIKey{Name: "nightly export", Scopes: []models.APIKeyScope{models.ScopeUsersRead}, ExpiresAt: &expiresAt}
+	created := models.APIKey{
+		ID: 1, Name: "nightly export", Prefix: "umk_abcdefgh", Scopes: []models.APIKeyScope{models.ScopeUsersRead},
+		ExpiresAt: &expiresAt, CreatedAt: createdAt,
+	}
+
+	tests := map[string]struct {
+		mockCalled   bool
+		mockInput    []any
+		mockOutput   []any
+		requestBody  string
+		expectedCode int
+		expectedBody string
+	}{
+		"valid request, key created": {
+			mockCalled:   true,
+			mockInput:    []any{key},
+			mockOutput:   []any{created, "umk_abcdefghijkl", nil},
+			requestBody:  toJSONString(keyIn),
+			expectedCode: http.StatusCreated,
+			expectedBody: toJSONString(responseCreatedAPIKey{APIKey: mapAPIKeyOutput(created), Key: "umk_abcdefghijkl"}),
+		},
+		"invalid request body": {
+			mockCalled:   false,
+			requestBody:  `{"name": " ", "scopes": ["users:read", "users:delete"], "expires_at": "2020-01-01T00:00:00Z"}`,
+			expectedCode: http.StatusBadRequest,
+			expectedBody: toJSONString(problem.
+				New(http.StatusBadRequest, "One or more fields in the request body are invalid").
+				WithInstance("/api/api-key").
+				WithFieldErrors(map[string]string{
+					"name":       "must not be blank",
+					"scopes":     "must only contain each of 'users:read', 'users:write' and 'users:admin' at most once",
+					"expires_at": "must be in the future",
+				}),
+			),
+		},
+		"without scopes": {
+			mockCalled:   false,
+			requestBody:  `{"name": "nightly export"}`,
+			expectedCode: http.StatusBadRequest,
+			expectedBody: toJSONString(problem.
+				New(http.StatusBadRequest, "One or more fields in the request body are invalid").
+				WithInstance("/api/api-key").
+				WithFieldErrors(map[string]string{"scopes": "must contain at least one scope"}),
+			),
+		},
+		"malformed request body": {
+			mockCalled:   false,
+			requestBody:  `{"name":`,
+			expectedCode: http.StatusBadRequest,
+			expectedBody: toJSONString(problem.New(http.StatusBadRequest, "missing values or malformed body").WithInstance("/api/api-key")),
+		},
+		"error creating key": {
+			mockCalled:   true,
+			mockInput:    []any{key},
+			mockOutput:   []any{models.APIKey{}, "", errors.New("creation error")},
+			requestBody:  toJSONString(keyIn),
+			expectedCode: http.StatusInternalServerError,
+			expectedBody: toJSONString(problem.New(http.StatusInternalServerError, "Error creating object").WithInstance("/api/api-key")),
+		},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			req, err := http.NewRequest(http.MethodPost, "/api/api-key", strings.NewReader(tc.requestBody))
+			assert.NoError(t, err)
+
+			// Add chi URLParam
+			rctx := chi.NewRouteContext()
+			ctx := context.WithValue(req.Context(), chi.RouteCtxKey, rctx)
+			req = req.WithContext(ctx)
+
+			if tc.mockCalled {
+				mockService.
+					On("CreateKey", append([]any{ctx}, tc.mockInput...)...).
+					Return(tc.mockOutput...).
+					Once()
+			}
+
+			rr := httptest.NewRecorder()
+			handler.ServeHTTP(rr, req)
+
+			assert.Equal(t, tc.expectedCode, rr.Code, "Wrong code received")
+			assert.JSONEq(t, tc.expectedBody, rr.Body.String(), "Wrong response body")
+
+			if tc.mockCalled {
+				mockService.AssertExpectations(t)
+			} else {
+				mockService.AssertNotCalled(t, "CreateKey")
+			}
+		})
+	}
+}
@@ -2,9 +2,12 @@ package handlers
 
 import (
 	"context"
+	"errors"
 	"net/http"
 
 	"github.com/jha-captech/user-microservice/internal/models"
+	"github.com/jha-captech/user-microservice/internal/problem"
+	svc "github.com/jha-captech/user-microservice/internal/service"
 )
 
 type userCreator interface {
@@ -20,13 +23,14 @@ type userCreator interface {
 // @Produce		json
 // @Param		user		body		handlers.inputUser	true	"User Object"
 // @Success		201			{object}	handlers.responseID
-// @Failure		400			{object}	handlers.responseErr
-// @Failure		500			{object}	handlers.responseErr
-// @Failure		409			{object}	handlers.responseErr
+// @Failure		400			{object}	problem.Problem
+// @Failure		409			{object}	problem.Problem
+// @Failure		500			{object}	problem.Problem
 // @Router		/user		[POST]
 func HandleCreateUser(logger sLogger, service userCreator) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		// setup
+		logger := requestLogger(r, logger)
 		ctx := r.Context()
 
 		// get and validate body as object
@@ -35,14 +39,13 @@ func HandleCreateUser(logger sLogger, service userCreator) http.HandlerFunc {
 			switch {
 			case len(problems) > 0:
 				logger.Error("Problems validating input", "error", err, "problems", problems)
-				encodeResponse(w, logger, http.StatusBadRequest, responseErr{
-					ValidationErrors: problems,
-				})
+				encodeProblem(w, r, logger, problem.
+					New(http.StatusBadRequest, "One or more fields in the request body are invalid").
+					WithFieldErrors(problems),
+				)
 			default:
 				logger.Error("BodyParser error", "error", err)
-				encodeResponse(w, logger, http.StatusBadRequest, responseErr{
-					Error: "missing values or malformed body",
-				})
+				encodeProblem(w, r, logger, problem.New(http.StatusBadRequest, "missing values or malformed body"))
 			}
 			return
 		}
@@ -50,10 +53,14 @@ func HandleCreateUser(logger sLogger, service userCreator) http.HandlerFunc {
 		// create object in database
 		ID, err := service.CreateUser(ctx, userIn)
 		if err != nil {
-			logger.Error("error creating object to database", "error", err)
-			encodeResponse(w, logger, http.StatusInternalServerError, responseErr{
-				Error: "Error creating object",
-			})
+			switch {
+			case errors.Is(err, svc.ErrUserIDConflict):
+				logger.Error("user_id already in use", "error", err)
+				encodeProblem(w, r, logger, problem.New(http.StatusConflict, "user_id already in use"))
+			default:
+				logger.Error("error creating object to database", "error", err)
+				encodeProblem(w, r, logger, problem.New(http.StatusInternalServerError, "Error creating object"))
+			}
 			return
 		}
 
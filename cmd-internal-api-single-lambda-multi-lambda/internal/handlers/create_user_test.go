@@ -3,6 +3,7 @@ package handlers
 import (
 	"context"
 	"errors"
+	"fmt"
 	"log/slog"
 	"net/http"
 	"net/http/httptest"
@@ -11,6 +12,8 @@ import (
 
 	"github.com/go-chi/chi/v5"
 	"github.com/jha-captech/user-microservice/internal/models"
+	"github.com/jha-captech/user-microservice/internal/problem"
+	"github.com/jha-captech/user-microservice/internal/service"
 	"github.com/stretchr/testify/assert"
 
 	serviceMock "github.com/jha-captech/user-microservice/internal/handlers/mock"
@@ -46,13 +49,15 @@ func TestHandleCreateUser(t *testing.T) {
 			mockOutput:   nil,
 			requestBody:  `{"FirstName":"John","LastName":"Doe","Role":"Admin"}`,
 			expectedCode: http.StatusBadRequest,
-			expectedBody: toJSONString(responseErr{
-				ValidationErrors: map[string]string{
+			expectedBody: toJSONString(problem.
+				New(http.StatusBadRequest, "One or more fields in the request body are invalid").
+				WithInstance("/api/user").
+				WithFieldErrors(map[string]string{
 					"first_name": "must not be blank",
 					"role":       "must be 'Customer' or 'Employee'",
 					"user_id":    "must be more than 0",
-				},
-			}),
+				}),
+			),
 		},
 		"error creating user": {
 			mockCalled:   true,
@@ -60,7 +65,15 @@ func TestHandleCreateUser(t *testing.T) {
 			mockOutput:   []any{0, errors.New("creation error")},
 			requestBody:  toJSONString(userIn),
 			expectedCode: http.StatusInternalServerError,
-			expectedBody: toJSONString(responseErr{Error: "Error creating object"}),
+			expectedBody: toJSONString(problem.New(http.StatusInternalServerError, "Error creating object").WithInstance("/api/user")),
+		},
+		"user_id already in use": {
+			mockCalled:   true,
+			mockInput:    []any{user},
+			mockOutput:   []any{0, fmt.Errorf("[in CreateUser]: %w", service.ErrUserIDConflict)},
+			requestBody:  toJSONString(userIn),
+			expectedCode: http.StatusConflict,
+			expectedBody: toJSONString(problem.New(http.StatusConflict, "user_id already in use").WithInstance("/api/user")),
 		},
 	}
 
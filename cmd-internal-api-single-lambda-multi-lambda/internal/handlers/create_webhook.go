@@ -0,0 +1,68 @@
+package handlers
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/jha-captech/user-microservice/internal/models"
+	"github.com/jha-captech/user-microservice/internal/problem"
+)
+
+type webhookCreator interface {
+	CreateSubscription(ctx context.Context, subscription models.WebhookSubscription) (int, error)
+}
+
+// HandleCreateWebhook is a Handler that creates a webhook subscription based on a subscription
+// object from the request body. Events written after the subscription is created are delivered to
+// its URL, signed with its secret in the X-Signature header. An empty event filter subscribes to
+// every event type.
+//
+// @Summary		Create a webhook subscription
+// @Description	Subscribe a URL to user events
+// @Tags		webhook
+// @Accept		json
+// @Produce		json
+// @Param		webhook			body		handlers.inputWebhookSubscription	true	"Subscription Object"
+// @Param		X-Admin-Token	header		string								true	"Admin token"
+// @Success		201				{object}	handlers.responseID
+// @Failure		400				{object}	problem.Problem
+// @Failure		403				{object}	problem.Problem
+// @Failure		500				{object}	problem.Problem
+// @Router		/webhook		[POST]
+func HandleCreateWebhook(logger sLogger, service webhookCreator) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		// setup
+		logger := requestLogger(r, logger)
+		ctx := r.Context()
+
+		// get and validate body as object
+		subscriptionIn, problems, err := decodeValidateBody[inputWebhookSubscription, models.WebhookSubscription](r)
+		if err != nil {
+			switch {
+			case len(problems) > 0:
+				logger.Error("Problems validating input", "error", err, "problems", problems)
+				encodeProblem(w, r, logger, problem.
+					New(http.StatusBadRequest, "One or more fields in the request body are invalid").
+					WithFieldErrors(problems),
+				)
+			default:
+				logger.Error("BodyParser error", "error", err)
+				encodeProblem(w, r, logger, problem.New(http.StatusBadRequest, "missing values or malformed body"))
+			}
+			return
+		}
+
+		// create object in database
+		ID, err := service.CreateSubscription(ctx, subscriptionIn)
+		if err != nil {
+			logger.Error("error creating webhook subscription", "error", err)
+			encodeProblem(w, r, logger, problem.New(http.StatusInternalServerError, "Error creating object"))
+			return
+		}
+
+		// return response
+		encodeResponse(w, logger, http.StatusCreated, responseID{
+			ObjectID: ID,
+		})
+	}
+}
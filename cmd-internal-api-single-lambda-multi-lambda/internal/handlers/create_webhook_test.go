@@ -0,0 +1,134 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/jha-captech/user-microservice/internal/models"
+	"github.com/jha-captech/user-microservice/internal/problem"
+	"github.com/stretchr/testify/assert"
+
+	serviceMock "github.com/jha-captech/user-microservice/internal/handlers/mock"
+)
+
+func TestHandleCreateWebhook(t *testing.T) {
+	mockService := new(serviceMock.MockWebhookCreator)
+	logger := slog.Default()
+	handler := HandleCreateWebhook(logger, mockService)
+
+	subscriptionIn := inputWebhookSubscription{
+		URL:    "https://example.com/hook",
+		Events: []string{"user.created", "user.deleted"},
+		Secret: "0123456789abcdef",
+	}
+	subscription := models.WebhookSubscription{
+		URL:    "https://example.com/hook",
+		Events: []models.EventType{models.EventUserCreated, models.EventUserDeleted},
+		Secret: "0123456789abcdef",
+	}
+
+	tests := map[string]struct {
+		mockCalled   bool
+		mockInput    []any
+		mockOutput   []any
+		requestBody  string
+		expectedCode int
+		expectedBody string
+	}{
+		"valid request, subscription created": {
+			mockCalled:   true,
+			mockInput:    []any{subscription},
+			mockOutput:   []any{1, nil},
+			requestBody:  toJSONString(subscriptionIn),
+			expectedCode: http.StatusCreated,
+			expectedBody: toJSONString(responseID{ObjectID: 1}),
+		},
+		"without event filter": {
+			mockCalled: true,
+			mockInput: []any{models.WebhookSubscription{
+				URL: "http://localhost:9000/hook", Events: []models.EventType{}, Secret: "0123456789abcdef",
+			}},
+			mockOutput:   []any{2, nil},
+			requestBody:  `{"url": "http://localhost:9000/hook", "secret": "0123456789abcdef"}`,
+			expectedCode: http.StatusCreated,
+			expectedBody: toJSONString(responseID{ObjectID: 2}),
+		},
+		"invalid request body": {
+			mockCalled:   false,
+			requestBody:  `{"url": "ftp://example.com", "events": ["user.created", "user.created"], "secret": "short"}`,
+			expectedCode: http.StatusBadRequest,
+			expectedBody: toJSONString(problem.
+				New(http.StatusBadRequest, "One or more fields in the request body are invalid").
+				WithInstance("/api/webhook").
+				WithFieldErrors(map[string]string{
+					"url":    "must be an absolute http or https URL",
+					"events": "must only contain each of 'user.created', 'user.updated' and 'user.deleted' at most once",
+					"secret": "must be at least 16 characters",
+				}),
+			),
+		},
+		"unknown event type": {
+			mockCalled:   false,
+			requestBody:  `{"url": "https://example.com/hook", "events": ["user.viewed"], "secret": "0123456789abcdef"}`,
+			expectedCode: http.StatusBadRequest,
+			expectedBody: toJSONString(problem.
+				New(http.StatusBadRequest, "One or more fields in the request body are invalid").
+				WithInstance("/api/webhook").
+				WithFieldErrors(map[string]string{
+					"events": "must only contain each of 'user.created', 'user.updated' and 'user.deleted' at most once",
+				}),
+			),
+		},
+		"malformed request body": {
+			mockCalled:   false,
+			requestBody:  `{"url":`,
+			expectedCode: http.StatusBadRequest,
+			expectedBody: toJSONString(problem.New(http.StatusBadRequest, "missing values or malformed body").WithInstance("/api/webhook")),
+		},
+		"error creating subscription": {
+			mockCalled:   true,
+			mockInput:    []any{subscription},
+			mockOutput:   []any{0, errors.New("creation error")},
+			requestBody:  toJSONString(subscriptionIn),
+			expectedCode: http.StatusInternalServerError,
+			expectedBody: toJSONString(problem.New(http.StatusInternalServerError, "Error creating object").WithInstance("/api/webhook")),
+		},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			req, err := http.NewRequest(http.MethodPost, "/api/webhook", strings.NewReader(tc.requestBody))
+			assert.NoError(t, err)
+
+			// Add chi URLParam
+			rctx := chi.NewRouteContext()
+			ctx := context.WithValue(req.Context(), chi.RouteCtxKey, rctx)
+			req = req.WithContext(ctx)
+
+			if tc.mockCalled {
+				mockService.
+					On("CreateSubscription", append([]any{ctx}, tc.mockInput...)...).
+					Return(tc.mockOutput...).
+					Once()
+			}
+
+			rr := httptest.NewRecorder()
+			handler.ServeHTTP(rr, req)
+
+			assert.Equal(t, tc.expectedCode, rr.Code, "Wrong code received")
+			assert.JSONEq(t, tc.expectedBody, rr.Body.String(), "Wrong response body")
+
+			if tc.mockCalled {
+				mockService.AssertExpectations(t)
+			} else {
+				mockService.AssertNotCalled(t, "CreateSubscription")
+			}
+		})
+	}
+}
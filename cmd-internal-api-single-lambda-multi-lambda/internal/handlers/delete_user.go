@@ -2,35 +2,45 @@ package handlers
 
 import (
 	"context"
-	"database/sql"
 	"errors"
 	"net/http"
 	"strconv"
 
 	"github.com/go-chi/chi/v5"
-	"github.com/jha-captech/user-microservice/internal/models"
+	"github.com/jha-captech/user-microservice/internal/problem"
+	svc "github.com/jha-captech/user-microservice/internal/service"
 )
 
 type userDeleter interface {
-	FetchUser(ctx context.Context, ID int) (models.User, error)
-	DeleteUser(ctx context.Context, ID int) error
+	DeleteUser(ctx context.Context, ID int, matchVersions []uint) error
+	PurgeUser(ctx context.Context, ID int, matchVersions []uint) error
 }
 
-// HandleDeleteUser is a Handler that deletes a user based on an ID.
+// HandleDeleteUser is a Handler that soft deletes a user based on an ID, so that it can be restored
+// later. With purge, the user is deleted permanently instead, which is only allowed for
+// administrators. If the request has an If-Match header, the user is only deleted if its ETag
+// matches.
 //
 // @Summary		Delete a user by ID
-// @Description	Delete a user by ID
+// @Description	Soft delete a user by ID, or permanently delete it with purge
 // @Tags		user
 // @Accept		json
 // @Produce		json
-// @Param		id			path		int	true				"User ID"
-// @Success		202			{object}	handlers.responseMsg
-// @Failure		400			{object}	handlers.responseErr
-// @Failure		500			{object}	handlers.responseErr
-// @Router		/user/{ID}	[DELETE]
+// @Param		id				path		int		true	"User ID"
+// @Param		purge			query		bool	false	"Permanently delete the user, which requires X-Admin-Token"	default(false)
+// @Param		If-Match		header		string	false	"ETag the user must currently have"
+// @Param		X-Admin-Token	header		string	false	"Admin token, required to purge"
+// @Success		202				{object}	handlers.responseMsg
+// @Failure		400				{object}	problem.Problem
+// @Failure		403				{object}	problem.Problem
+// @Failure		404				{object}	problem.Problem
+// @Failure		412				{object}	problem.Problem
+// @Failure		500				{object}	problem.Problem
+// @Router		/user/{ID}		[DELETE]
 func HandleDeleteUser(logger sLogger, service userDeleter) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		// setup
+		logger := requestLogger(r, logger)
 		ctx := r.Context()
 
 		// get and validate ID
@@ -38,42 +48,61 @@ func HandleDeleteUser(logger sLogger, service userDeleter) http.HandlerFunc {
 		ID, err := strconv.Atoi(idString)
 		if err != nil {
 			logger.Error("error getting ID", "error", err)
-			encodeResponse(w, logger, http.StatusBadRequest, responseErr{
-				Error: "Not a valid ID",
-			})
+			encodeProblem(w, r, logger, problem.New(http.StatusBadRequest, "Not a valid ID"))
 			return
 		}
 
-		// check that object exists
-		_, err = service.FetchUser(ctx, ID)
+		// get and validate query params
+		purge, err := parseBoolQuery(r.URL.Query().Get("purge"))
 		if err != nil {
-			switch {
-			case errors.Is(err, sql.ErrNoRows):
-				logger.Error("Object does not exist", "error", err)
-				encodeResponse(w, logger, http.StatusBadRequest, responseErr{
-					Error: "Object does not exist",
-				})
-			default:
-				logger.Error("error getting object by ID", "error", err)
-				encodeResponse(w, logger, http.StatusInternalServerError, responseErr{
-					Error: "Error validating object",
-				})
-			}
+			logger.Error("Query parse error", "error", err)
+			encodeProblem(w, r, logger, problem.
+				New(http.StatusBadRequest, "One or more query parameters are invalid").
+				WithFieldErrors(map[string]string{"purge": "must be true or false"}),
+			)
+			return
+		}
+		if purge && !isAdmin(ctx) {
+			logger.Error("Purge by non-admin", "ID", ID)
+			encodeProblem(w, r, logger, problem.New(http.StatusForbidden, "Only administrators can purge users"))
+			return
+		}
+
+		// get precondition
+		matchVersions, ok := ifMatchVersions(r)
+		if !ok {
+			logger.Error("If-Match can not be met", "ID", ID, "If-Match", r.Header.Get("If-Match"))
+			encodeProblem(w, r, logger, problem.New(http.StatusPreconditionFailed, "User has been modified"))
 			return
 		}
 
 		// delete user
-		if err = service.DeleteUser(ctx, ID); err != nil {
-			logger.Error("error deleting object by ID", "ID", ID, "error", err)
-			encodeResponse(w, logger, http.StatusInternalServerError, responseErr{
-				Error: "Error deleting object.",
-			})
+		deleteUser := service.DeleteUser
+		if purge {
+			deleteUser = service.PurgeUser
+		}
+		if err = deleteUser(ctx, ID, matchVersions); err != nil {
+			switch {
+			case errors.Is(err, svc.ErrUserNotFound):
+				logger.Error("Object does not exist", "ID", ID, "error", err)
+				encodeProblem(w, r, logger, problem.New(http.StatusNotFound, "Object does not exist"))
+			case errors.Is(err, svc.ErrVersionMismatch):
+				logger.Error("Object has been modified", "ID", ID, "error", err)
+				encodeProblem(w, r, logger, problem.New(http.StatusPreconditionFailed, "User has been modified"))
+			default:
+				logger.Error("error deleting object by ID", "ID", ID, "error", err)
+				encodeProblem(w, r, logger, problem.New(http.StatusInternalServerError, "Error deleting object."))
+			}
 			return
 		}
 
 		// return message
+		message := "object successful deleted"
+		if purge {
+			message = "object successful purged"
+		}
 		encodeResponse(w, logger, http.StatusAccepted, responseMsg{
-			Message: "object successful deleted",
+			Message: message,
 		})
 	}
 }
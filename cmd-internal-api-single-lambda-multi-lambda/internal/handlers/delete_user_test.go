@@ -2,16 +2,18 @@ package handlers
 
 import (
 	"context"
-	"database/sql"
 	"errors"
+	"fmt"
 	"log/slog"
 	"net/http"
 	"net/http/httptest"
 	"testing"
 
 	"github.com/go-chi/chi/v5"
-	"github.com/jha-captech/user-microservice/internal/models"
+	"github.com/jha-captech/user-microservice/internal/problem"
+	"github.com/jha-captech/user-microservice/internal/service"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
 
 	serviceMock "github.com/jha-captech/user-microservice/internal/handlers/mock"
 )
@@ -19,69 +21,111 @@ import (
 func TestHandleDeleteUser(t *testing.T) {
 	mockService := new(serviceMock.MockUserDeleter)
 	logger := slog.Default()
-	handler := HandleDeleteUser(logger, mockService)
+	handler := AuthenticateAdmin("secret")(HandleDeleteUser(logger, mockService))
 
 	tests := map[string]struct {
-		mockFetchCalled  bool
-		mockFetchInput   []any
-		mockFetchOutput  []any
 		mockDeleteCalled bool
+		mockPurge        bool
 		mockDeleteInput  []any
 		mockDeleteOutput []any
 		urlParam         string
+		query            string
+		ifMatch          string
+		adminToken       string
 		expectedCode     int
 		expectedBody     string
 	}{
 		"valid request, user deleted": {
-			mockFetchCalled:  true,
-			mockFetchInput:   []any{1},
-			mockFetchOutput:  []any{models.User{}, nil},
 			mockDeleteCalled: true,
-			mockDeleteInput:  []any{1},
+			mockDeleteInput:  []any{1, []uint(nil)},
 			mockDeleteOutput: []any{nil},
 			urlParam:         "1",
 			expectedCode:     http.StatusAccepted,
 			expectedBody:     toJSONString(responseMsg{Message: "object successful deleted"}),
 		},
 		"invalid ID format": {
-			mockFetchCalled:  false,
-			mockFetchInput:   nil,
-			mockFetchOutput:  nil,
 			mockDeleteCalled: false,
 			mockDeleteInput:  nil,
 			mockDeleteOutput: nil,
 			urlParam:         "abc",
 			expectedCode:     http.StatusBadRequest,
-			expectedBody:     toJSONString(responseErr{Error: "Not a valid ID"}),
+			expectedBody:     toJSONString(problem.New(http.StatusBadRequest, "Not a valid ID").WithInstance("/api/user/abc")),
 		},
 		"user does not exist": {
-			mockFetchCalled:  true,
-			mockFetchInput:   []any{1},
-			mockFetchOutput:  []any{models.User{}, sql.ErrNoRows},
+			mockDeleteCalled: true,
+			mockDeleteInput:  []any{1, []uint(nil)},
+			mockDeleteOutput: []any{fmt.Errorf("[in DeleteUser]: %w", service.ErrUserNotFound)},
+			urlParam:         "1",
+			expectedCode:     http.StatusNotFound,
+			expectedBody:     toJSONString(problem.New(http.StatusNotFound, "Object does not exist").WithInstance("/api/user/1")),
+		},
+		"If-Match matches, user deleted": {
+			mockDeleteCalled: true,
+			mockDeleteInput:  []any{1, []uint{5}},
+			mockDeleteOutput: []any{nil},
+			urlParam:         "1",
+			ifMatch:          `"5"`,
+			expectedCode:     http.StatusAccepted,
+			expectedBody:     toJSONString(responseMsg{Message: "object successful deleted"}),
+		},
+		"If-Match does not match": {
+			mockDeleteCalled: true,
+			mockDeleteInput:  []any{1, []uint{4}},
+			mockDeleteOutput: []any{fmt.Errorf("[in DeleteUser]: %w", service.ErrVersionMismatch)},
+			urlParam:         "1",
+			ifMatch:          `"4"`,
+			expectedCode:     http.StatusPreconditionFailed,
+			expectedBody:     toJSONString(problem.New(http.StatusPreconditionFailed, "User has been modified").WithInstance("/api/user/1")),
+		},
+		"admin purge, user purged": {
+			mockDeleteCalled: true,
+			mockPurge:        true,
+			mockDeleteInput:  []any{1, []uint(nil)},
+			mockDeleteOutput: []any{nil},
+			urlParam:         "1",
+			query:            "?purge=true",
+			adminToken:       "secret",
+			expectedCode:     http.StatusAccepted,
+			expectedBody:     toJSONString(responseMsg{Message: "object successful purged"}),
+		},
+		"purge with wrong admin token": {
+			mockDeleteCalled: false,
+			urlParam:         "1",
+			query:            "?purge=true",
+			adminToken:       "guess",
+			expectedCode:     http.StatusForbidden,
+			expectedBody:     toJSONString(problem.New(http.StatusForbidden, "Only administrators can purge users").WithInstance("/api/user/1")),
+		},
+		"invalid purge": {
 			mockDeleteCalled: false,
-			mockDeleteInput:  nil,
-			mockDeleteOutput: nil,
 			urlParam:         "1",
+			query:            "?purge=maybe",
 			expectedCode:     http.StatusBadRequest,
-			expectedBody:     toJSONString(responseErr{Error: "Object does not exist"}),
+			expectedBody: toJSONString(problem.
+				New(http.StatusBadRequest, "One or more query parameters are invalid").
+				WithInstance("/api/user/1").
+				WithFieldErrors(map[string]string{"purge": "must be true or false"}),
+			),
 		},
 		"error deleting user": {
-			mockFetchCalled:  true,
-			mockFetchInput:   []any{1},
-			mockFetchOutput:  []any{models.User{}, nil},
 			mockDeleteCalled: true,
-			mockDeleteInput:  []any{1},
+			mockDeleteInput:  []any{1, []uint(nil)},
 			mockDeleteOutput: []any{errors.New("deletion error")},
 			urlParam:         "1",
 			expectedCode:     http.StatusInternalServerError,
-			expectedBody:     toJSONString(responseErr{Error: "Error deleting object."}),
+			expectedBody:     toJSONString(problem.New(http.StatusInternalServerError, "Error deleting object.").WithInstance("/api/user/1")),
 		},
 	}
-
 	for name, tc := range tests {
 		t.Run(name, func(t *testing.T) {
-			req, err := http.NewRequest(http.MethodDelete, "/api/user/"+tc.urlParam, nil)
+			req, err := http.NewRequest(http.MethodDelete, "/api/user/"+tc.urlParam+tc.query, nil)
 			assert.NoError(t, err)
+			if tc.ifMatch != "" {
+				req.Header.Set("If-Match", tc.ifMatch)
+			}
+			if tc.adminToken != "" {
+				req.Header.Set("X-Admin-Token", tc.adminToken)
+			}
 
 			// Add chi URLParam
 			rctx := chi.NewRouteContext()
@@ -90,15 +134,13 @@ func TestHandleDeleteUser(t *testing.T) {
 			req = req.WithContext(ctx)
 
 			// set mock expectations and context
-			if tc.mockFetchCalled {
-				mockService.
-					On("FetchUser", append([]any{ctx}, tc.mockFetchInput...)...).
-					Return(tc.mockFetchOutput...).
-					Once()
+			method := "DeleteUser"
+			if tc.mockPurge {
+				method = "PurgeUser"
 			}
 			if tc.mockDeleteCalled {
 				mockService.
-					On("DeleteUser", append([]any{ctx}, tc.mockDeleteInput...)...).
+					On(method, append([]any{mock.Anything}, tc.mockDeleteInput...)...).
 					Return(tc.mockDeleteOutput...).
 					Once()
 			}
@@ -109,16 +151,11 @@ func TestHandleDeleteUser(t *testing.T) {
 			assert.Equal(t, tc.expectedCode, rr.Code, "Wrong code received")
 			assert.JSONEq(t, tc.expectedBody, rr.Body.String(), "Wrong response body")
 
-			if tc.mockFetchCalled {
-				mockService.AssertExpectations(t)
-			} else {
-				mockService.AssertNotCalled(t, "FetchUser")
-			}
-
 			if tc.mockDeleteCalled {
 				mockService.AssertExpectations(t)
 			} else {
 				mockService.AssertNotCalled(t, "DeleteUser")
+				mockService.AssertNotCalled(t, "PurgeUser")
 			}
 		})
 	}
@@ -0,0 +1,67 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/jha-captech/user-microservice/internal/problem"
+	svc "github.com/jha-captech/user-microservice/internal/service"
+)
+
+type webhookDeleter interface {
+	DeleteSubscription(ctx context.Context, ID int) error
+}
+
+// HandleDeleteWebhook is a Handler that deletes a webhook subscription based on an ID, along with
+// its delivery log. Pending deliveries to the subscription are dropped.
+//
+// @Summary		Delete a webhook subscription by ID
+// @Description	Delete a webhook subscription and its deliveries by ID
+// @Tags		webhook
+// @Accept		json
+// @Produce		json
+// @Param		id				path		int		true	"Subscription ID"
+// @Param		X-Admin-Token	header		string	true	"Admin token"
+// @Success		202				{object}	handlers.responseMsg
+// @Failure		400				{object}	problem.Problem
+// @Failure		403				{object}	problem.Problem
+// @Failure		404				{object}	problem.Problem
+// @Failure		500				{object}	problem.Problem
+// @Router		/webhook/{ID}	[DELETE]
+func HandleDeleteWebhook(logger sLogger, service webhookDeleter) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		// setup
+		logger := requestLogger(r, logger)
+		ctx := r.Context()
+
+		// get and validate ID
+		idString := chi.URLParam(r, "ID")
+		ID, err := strconv.Atoi(idString)
+		if err != nil {
+			logger.Error("error getting ID", "error", err)
+			encodeProblem(w, r, logger, problem.New(http.StatusBadRequest, "Not a valid ID"))
+			return
+		}
+
+		// delete subscription
+		if err = service.DeleteSubscription(ctx, ID); err != nil {
+			switch {
+			case errors.Is(err, svc.ErrSubscriptionNotFound):
+				logger.Error("Object does not exist", "ID", ID, "error", err)
+				encodeProblem(w, r, logger, problem.New(http.StatusNotFound, "Object does not exist"))
+			default:
+				logger.Error("error deleting webhook subscription", "ID", ID, "error", err)
+				encodeProblem(w, r, logger, problem.New(http.StatusInternalServerError, "Error deleting object."))
+			}
+			return
+		}
+
+		// return message
+		encodeResponse(w, logger, http.StatusAccepted, responseMsg{
+			Message: "object successful deleted",
+		})
+	}
+}
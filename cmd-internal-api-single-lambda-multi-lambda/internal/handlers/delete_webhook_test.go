@@ -0,0 +1,96 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/jha-captech/user-microservice/internal/problem"
+	"github.com/jha-captech/user-microservice/internal/service"
+	"github.com/stretchr/testify/assert"
+
+	serviceMock "github.com/jha-captech/user-microservice/internal/handlers/mock"
+)
+
+func TestHandleDeleteWebhook(t *testing.T) {
+	mockService := new(serviceMock.MockWebhookDeleter)
+	logger := slog.Default()
+	handler := HandleDeleteWebhook(logger, mockService)
+
+	tests := map[string]struct {
+		mockCalled     bool
+		mockInput      []any
+		mockOutput     []any
+		requestIDParam string
+		expectedCode   int
+		expectedBody   string
+	}{
+		"subscription deleted": {
+			mockCalled:     true,
+			mockInput:      []any{1},
+			mockOutput:     []any{nil},
+			requestIDParam: "1",
+			expectedCode:   http.StatusAccepted,
+			expectedBody:   toJSONString(responseMsg{Message: "object successful deleted"}),
+		},
+		"invalid ID": {
+			mockCalled:     false,
+			requestIDParam: "abc",
+			expectedCode:   http.StatusBadRequest,
+			expectedBody:   toJSONString(problem.New(http.StatusBadRequest, "Not a valid ID").WithInstance("/api/webhook/abc")),
+		},
+		"subscription not found": {
+			mockCalled:     true,
+			mockInput:      []any{2},
+			mockOutput:     []any{fmt.Errorf("[in DeleteSubscription]: %w", service.ErrSubscriptionNotFound)},
+			requestIDParam: "2",
+			expectedCode:   http.StatusNotFound,
+			expectedBody:   toJSONString(problem.New(http.StatusNotFound, "Object does not exist").WithInstance("/api/webhook/2")),
+		},
+		"error deleting subscription": {
+			mockCalled:     true,
+			mockInput:      []any{1},
+			mockOutput:     []any{errors.New("delete error")},
+			requestIDParam: "1",
+			expectedCode:   http.StatusInternalServerError,
+			expectedBody:   toJSONString(problem.New(http.StatusInternalServerError, "Error deleting object.").WithInstance("/api/webhook/1")),
+		},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			req, err := http.NewRequest(http.MethodDelete, "/api/webhook/"+tc.requestIDParam, nil)
+			assert.NoError(t, err)
+
+			// Add chi URLParam
+			rctx := chi.NewRouteContext()
+			rctx.URLParams.Add("ID", tc.requestIDParam)
+			ctx := context.WithValue(req.Context(), chi.RouteCtxKey, rctx)
+			req = req.WithContext(ctx)
+
+			if tc.mockCalled {
+				mockService.
+					On("DeleteSubscription", append([]any{ctx}, tc.mockInput...)...).
+					Return(tc.mockOutput...).
+					Once()
+			}
+
+			rr := httptest.NewRecorder()
+			handler.ServeHTTP(rr, req)
+
+			assert.Equal(t, tc.expectedCode, rr.Code, "Wrong code received")
+			assert.JSONEq(t, tc.expectedBody, rr.Body.String(), "Wrong response body")
+
+			if tc.mockCalled {
+				mockService.AssertExpectations(t)
+			} else {
+				mockService.AssertNotCalled(t, "DeleteSubscription")
+			}
+		})
+	}
+}
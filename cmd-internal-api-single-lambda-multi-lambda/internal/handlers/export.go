@@ -0,0 +1,158 @@
+package handlers
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"mime"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/jha-captech/user-microservice/internal/models"
+)
+
+const (
+	contentTypeJSON   = "application/json"
+	contentTypeCSV    = "text/csv"
+	contentTypeNDJSON = "application/x-ndjson"
+)
+
+// userExporter writes users one at a time to a streamed response body.
+type userExporter interface {
+	// Write writes a single user.
+	Write(user models.User) error
+	// Close writes anything still buffered.
+	Close() error
+}
+
+// newUserExporter returns the userExporter for contentType, which is either contentTypeCSV or
+// contentTypeNDJSON. A CSV export starts with a header row.
+func newUserExporter(w http.ResponseWriter, contentType string) (userExporter, error) {
+	if contentType == contentTypeNDJSON {
+		return ndjsonExporter{encoder: json.NewEncoder(w)}, nil
+	}
+
+	exporter := csvExporter{writer: csv.NewWriter(w)}
+	if err := exporter.writer.Write(userCSVHeader); err != nil {
+		return nil, fmt.Errorf("[in newUserExporter] write header: %w", err)
+	}
+	return exporter, nil
+}
+
+// userCSVHeader is the header row of a CSV export. Its columns match the fields of outputUser.
+var userCSVHeader = []string{
+	"id", "first_name", "last_name", "role", "user_id", "version",
+	"deleted_at", "created_at", "updated_at", "created_by", "updated_by",
+}
+
+type csvExporter struct {
+	writer *csv.Writer
+}
+
+func (exporter csvExporter) Write(user models.User) error {
+	return exporter.writer.Write([]string{
+		strconv.FormatUint(uint64(user.ID), 10),
+		user.FirstName,
+		user.LastName,
+		user.Role,
+		strconv.FormatUint(uint64(user.UserID), 10),
+		strconv.FormatUint(uint64(user.Version), 10),
+		formatCSVTime(user.DeletedAt),
+		user.CreatedAt.Format(time.RFC3339Nano),
+		user.UpdatedAt.Format(time.RFC3339Nano),
+		user.CreatedBy,
+		user.UpdatedBy,
+	})
+}
+
+// formatCSVTime formats t for a CSV export, leaving the column empty if t is nil.
+func formatCSVTime(t *time.Time) string {
+	if t == nil {
+		return ""
+	}
+	return t.Format(time.RFC3339Nano)
+}
+
+func (exporter csvExporter) Close() error {
+	exporter.writer.Flush()
+	return exporter.writer.Error()
+}
+
+type ndjsonExporter struct {
+	encoder *json.Encoder
+}
+
+func (exporter ndjsonExporter) Write(user models.User) error {
+	return exporter.encoder.Encode(mapOutput(user))
+}
+
+func (exporter ndjsonExporter) Close() error {
+	return nil
+}
+
+// exportFilename returns the file name a download of users as contentType is saved as.
+func exportFilename(contentType string) string {
+	if contentType == contentTypeNDJSON {
+		return "users.ndjson"
+	}
+	return "users.csv"
+}
+
+// negotiateContentType returns the media type in offers that best matches the Accept header of r,
+// taking quality values and wildcards into account. The first offer is returned when the header is
+// absent or matches none of them, so clients that do not negotiate keep getting the default.
+func negotiateContentType(r *http.Request, offers ...string) string {
+	accept := r.Header.Get("Accept")
+	if accept == "" {
+		return offers[0]
+	}
+
+	best, bestQuality, bestSpecificity := offers[0], 0.0, -1
+	for _, part := range strings.Split(accept, ",") {
+		mediaType, params, err := mime.ParseMediaType(strings.TrimSpace(part))
+		if err != nil {
+			continue
+		}
+
+		quality := 1.0
+		if q, ok := params["q"]; ok {
+			if quality, err = strconv.ParseFloat(q, 64); err != nil {
+				continue
+			}
+		}
+		if quality <= 0 {
+			continue
+		}
+
+		for _, offer := range offers {
+			specificity := mediaRangeSpecificity(mediaType, offer)
+			if specificity < 0 {
+				continue
+			}
+			// prefer the higher quality, then the more specific range, then the earlier offer
+			if quality > bestQuality || (quality == bestQuality && specificity > bestSpecificity) {
+				best, bestQuality, bestSpecificity = offer, quality, specificity
+			}
+		}
+	}
+
+	return best
+}
+
+// mediaRangeSpecificity returns how specifically mediaRange matches mediaType: 2 for an exact match,
+// 1 for a type/* match and 0 for */*. It returns -1 when mediaRange does not match mediaType.
+func mediaRangeSpecificity(mediaRange, mediaType string) int {
+	switch {
+	case mediaRange == mediaType:
+		return 2
+	case mediaRange == "*/*":
+		return 0
+	case strings.HasSuffix(mediaRange, "/*") &&
+		strings.HasPrefix(mediaType, strings.TrimSuffix(mediaRange, "*")):
+		return 1
+	default:
+		return -1
+	}
+}
@@ -2,34 +2,42 @@ package handlers
 
 import (
 	"context"
-	"database/sql"
 	"errors"
 	"net/http"
 	"strconv"
 
 	"github.com/go-chi/chi/v5"
 	"github.com/jha-captech/user-microservice/internal/models"
+	"github.com/jha-captech/user-microservice/internal/problem"
+	svc "github.com/jha-captech/user-microservice/internal/service"
 )
 
 type userFetcher interface {
-	FetchUser(ctx context.Context, ID int) (models.User, error)
+	FetchUser(ctx context.Context, ID int, includeDeleted bool) (models.User, error)
 }
 
-// HandleFetchUser is a Handler that returns a single user by ID.
+// HandleFetchUser is a Handler that returns a single user by ID. A soft deleted user is only
+// returned if include_deleted is set.
 //
 // @Summary		Fetch a user by ID
 // @Description	Fetch a user by ID
 // @Tags		user
 // @Accept		json
 // @Produce		json
-// @Param		id			path		int	true				"User ID"
-// @Success		200			{object}	handlers.responseUser
-// @Failure		400			{object}	handlers.responseErr
-// @Failure		500			{object}	handlers.responseErr
+// @Param		id				path		int		true	"User ID"
+// @Param		include_deleted	query		bool	false	"Return the user even if it is soft deleted"	default(false)
+// @Param		If-None-Match	header		string	false	"ETag of a cached copy of the user"
+// @Success		200				{object}	handlers.responseUser
+// @Header		200				{string}	ETag	"Version of the user"
+// @Success		304				"The user has not changed since the ETag in If-None-Match"
+// @Failure		400			{object}	problem.Problem
+// @Failure		404			{object}	problem.Problem
+// @Failure		500			{object}	problem.Problem
 // @Router		/user/{ID}	[GET]
 func HandleFetchUser(logger sLogger, service userFetcher) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		// setup
+		logger := requestLogger(r, logger)
 		ctx := r.Context()
 
 		// get and validate ID
@@ -37,29 +45,42 @@ func HandleFetchUser(logger sLogger, service userFetcher) http.HandlerFunc {
 		ID, err := strconv.Atoi(idString)
 		if err != nil {
 			logger.Error("error getting ID", "error", err)
-			encodeResponse(w, logger, http.StatusBadRequest, responseErr{
-				Error: "Not a valid ID",
-			})
+			encodeProblem(w, r, logger, problem.New(http.StatusBadRequest, "Not a valid ID"))
+			return
+		}
+
+		// get and validate query params
+		includeDeleted, err := parseBoolQuery(r.URL.Query().Get("include_deleted"))
+		if err != nil {
+			logger.Error("Query parse error", "error", err)
+			encodeProblem(w, r, logger, problem.
+				New(http.StatusBadRequest, "One or more query parameters are invalid").
+				WithFieldErrors(map[string]string{"include_deleted": "must be true or false"}),
+			)
 			return
 		}
 
 		// get values from database
-		user, err := service.FetchUser(ctx, ID)
+		user, err := service.FetchUser(ctx, ID, includeDeleted)
 		if err != nil {
 			switch {
-			case errors.Is(err, sql.ErrNoRows):
-				// no user found
-				encodeResponse(w, logger, http.StatusOK, responseUser{})
+			case errors.Is(err, svc.ErrUserNotFound):
+				logger.Error("Object does not exist", "ID", ID, "error", err)
+				encodeProblem(w, r, logger, problem.New(http.StatusNotFound, "Object does not exist"))
 			default:
 				logger.Error("error getting object by ID", "error", err)
-				encodeResponse(w, logger, http.StatusInternalServerError, responseErr{
-					Error: "Internal server error",
-				})
+				encodeProblem(w, r, logger, problem.New(http.StatusInternalServerError, "Internal server error"))
 			}
 			return
 		}
 
-		// return response
+		// return response, or nothing if the client has the current version
+		w.Header().Set("ETag", etag(user.Version))
+		if ifNoneMatch(r, user.Version) {
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+
 		userOut := mapOutput(user)
 		encodeResponse(w, logger, http.StatusOK, responseUser{
 			User: userOut,
@@ -0,0 +1,76 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/jha-captech/user-microservice/internal/models"
+	"github.com/jha-captech/user-microservice/internal/problem"
+	svc "github.com/jha-captech/user-microservice/internal/service"
+)
+
+type userHistoryFetcher interface {
+	FetchUserHistory(ctx context.Context, ID int, version uint) (models.UserHistoryEntry, error)
+}
+
+// HandleFetchUserHistory is a Handler that returns the entry of the history of a user for the change
+// that brought it to a version. The purge of a user is recorded at the version after its last one.
+//
+// @Summary		Fetch a version of a user from its history
+// @Description	Fetch the change that brought a user to a version
+// @Tags		user
+// @Accept		json
+// @Produce		json
+// @Param		id			path		int		true	"User ID"
+// @Param		version		path		int		true	"Version of the user"
+// @Success		200			{object}	handlers.responseHistoryEntry
+// @Failure		400			{object}	problem.Problem
+// @Failure		404			{object}	problem.Problem
+// @Failure		500			{object}	problem.Problem
+// @Router		/user/{ID}/history/{version}	[GET]
+func HandleFetchUserHistory(logger sLogger, service userHistoryFetcher) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		// setup
+		logger := requestLogger(r, logger)
+		ctx := r.Context()
+
+		// get and validate ID
+		idString := chi.URLParam(r, "ID")
+		ID, err := strconv.Atoi(idString)
+		if err != nil {
+			logger.Error("error getting ID", "error", err)
+			encodeProblem(w, r, logger, problem.New(http.StatusBadRequest, "Not a valid ID"))
+			return
+		}
+
+		// get and validate version
+		version, err := strconv.ParseUint(chi.URLParam(r, "version"), 10, 0)
+		if err != nil || version < 1 {
+			logger.Error("error getting version", "error", err)
+			encodeProblem(w, r, logger, problem.New(http.StatusBadRequest, "Not a valid version"))
+			return
+		}
+
+		// get values from database
+		entry, err := service.FetchUserHistory(ctx, ID, uint(version))
+		if err != nil {
+			switch {
+			case errors.Is(err, svc.ErrHistoryNotFound):
+				logger.Error("Object does not exist", "ID", ID, "version", version, "error", err)
+				encodeProblem(w, r, logger, problem.New(http.StatusNotFound, "Object does not exist"))
+			default:
+				logger.Error("error getting history entry", "error", err)
+				encodeProblem(w, r, logger, problem.New(http.StatusInternalServerError, "Internal server error"))
+			}
+			return
+		}
+
+		// return response
+		encodeResponse(w, logger, http.StatusOK, responseHistoryEntry{
+			Entry: mapHistoryOutput(entry),
+		})
+	}
+}
@@ -0,0 +1,124 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/jha-captech/user-microservice/internal/models"
+	"github.com/jha-captech/user-microservice/internal/problem"
+	"github.com/jha-captech/user-microservice/internal/service"
+	"github.com/stretchr/testify/assert"
+	"golang.org/x/net/context"
+
+	serviceMock "github.com/jha-captech/user-microservice/internal/handlers/mock"
+)
+
+func TestHandleFetchUserHistory(t *testing.T) {
+	mockService := new(serviceMock.MockUserHistoryFetcher)
+	logger := slog.Default()
+	handler := HandleFetchUserHistory(logger, mockService)
+
+	changedAt := time.Date(2024, 5, 2, 12, 0, 0, 0, time.UTC)
+	before := models.User{ID: 1, FirstName: "John", LastName: "Doe", Role: "Customer", UserID: 1001, Version: 1}
+	after := models.User{ID: 1, FirstName: "John", LastName: "Smith", Role: "Customer", UserID: 1001, Version: 2}
+	entry := models.UserHistoryEntry{
+		ID: 1, Version: 2, Operation: models.HistoryPatch, Actor: "jdoe", RequestID: "req-1",
+		ChangedAt: changedAt, Before: &before, After: &after,
+	}
+	beforeOut := mapOutput(before)
+	afterOut := mapOutput(after)
+
+	tests := map[string]struct {
+		mockCalled     bool
+		mockInput      []any
+		mockOutput     []any
+		requestIDParam string
+		requestVersion string
+		expectedCode   int
+		expectedBody   string
+	}{
+		"history entry found": {
+			mockCalled:     true,
+			mockInput:      []any{1, uint(2)},
+			mockOutput:     []any{entry, nil},
+			requestIDParam: "1",
+			requestVersion: "2",
+			expectedCode:   http.StatusOK,
+			expectedBody: toJSONString(responseHistoryEntry{Entry: outputHistoryEntry{
+				ID: 1, Version: 2, Operation: "patch", Actor: "jdoe", RequestID: "req-1",
+				ChangedAt: changedAt, Before: &beforeOut, After: &afterOut,
+			}}),
+		},
+		"invalid ID": {
+			mockCalled:     false,
+			requestIDParam: "abc",
+			requestVersion: "2",
+			expectedCode:   http.StatusBadRequest,
+			expectedBody:   toJSONString(problem.New(http.StatusBadRequest, "Not a valid ID").WithInstance("/api/user/abc/history/2")),
+		},
+		"invalid version": {
+			mockCalled:     false,
+			requestIDParam: "1",
+			requestVersion: "0",
+			expectedCode:   http.StatusBadRequest,
+			expectedBody:   toJSONString(problem.New(http.StatusBadRequest, "Not a valid version").WithInstance("/api/user/1/history/0")),
+		},
+		"history entry not found": {
+			mockCalled:     true,
+			mockInput:      []any{1, uint(9)},
+			mockOutput:     []any{models.UserHistoryEntry{}, fmt.Errorf("[in FetchUserHistory]: %w", service.ErrHistoryNotFound)},
+			requestIDParam: "1",
+			requestVersion: "9",
+			expectedCode:   http.StatusNotFound,
+			expectedBody:   toJSONString(problem.New(http.StatusNotFound, "Object does not exist").WithInstance("/api/user/1/history/9")),
+		},
+		"internal server error": {
+			mockCalled:     true,
+			mockInput:      []any{1, uint(2)},
+			mockOutput:     []any{models.UserHistoryEntry{}, errors.New("")},
+			requestIDParam: "1",
+			requestVersion: "2",
+			expectedCode:   http.StatusInternalServerError,
+			expectedBody:   toJSONString(problem.New(http.StatusInternalServerError, "Internal server error").WithInstance("/api/user/1/history/2")),
+		},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			req, err := http.NewRequest(http.MethodGet, "/api/user/"+tc.requestIDParam+"/history/"+tc.requestVersion, nil)
+			assert.NoError(t, err)
+
+			// Add chi URLParams
+			rctx := chi.NewRouteContext()
+			rctx.URLParams.Add("ID", tc.requestIDParam)
+			rctx.URLParams.Add("version", tc.requestVersion)
+			ctx := context.WithValue(req.Context(), chi.RouteCtxKey, rctx)
+			req = req.WithContext(ctx)
+
+			if tc.mockCalled {
+				mockService.
+					On("FetchUserHistory", append([]any{ctx}, tc.mockInput...)...).
+					Return(tc.mockOutput...).
+					Once()
+			}
+
+			rr := httptest.NewRecorder()
+			handler.ServeHTTP(rr, req)
+
+			assert.Equal(t, tc.expectedCode, rr.Code, "Wrong code received")
+			assert.JSONEq(t, tc.expectedBody, rr.Body.String(), "Wrong response body")
+
+			if tc.mockCalled {
+				mockService.AssertExpectations(t)
+			} else {
+				mockService.AssertNotCalled(t, "FetchUserHistory")
+			}
+		})
+	}
+}
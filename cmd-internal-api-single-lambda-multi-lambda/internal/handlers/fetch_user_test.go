@@ -1,7 +1,6 @@
 package handlers
 
 import (
-	"database/sql"
 	"encoding/json"
 	"errors"
 	"fmt"
@@ -10,9 +9,12 @@ import (
 	"net/http/httptest"
 	"strconv"
 	"testing"
+	"time"
 
 	"github.com/go-chi/chi/v5"
 	"github.com/jha-captech/user-microservice/internal/models"
+	"github.com/jha-captech/user-microservice/internal/problem"
+	"github.com/jha-captech/user-microservice/internal/service"
 	"github.com/stretchr/testify/assert"
 	"golang.org/x/net/context"
 
@@ -32,9 +34,11 @@ func TestHandleFetchUser(t *testing.T) {
 	logger := slog.Default()
 	handler := HandleFetchUser(logger, mockService)
 
+	deletedAt := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
 	users := []models.User{
-		{ID: 1, FirstName: "John", LastName: "Doe", Role: "Admin", UserID: 1001},
-		{ID: 2, FirstName: "Jane", LastName: "Smith", Role: "User", UserID: 1002},
+		{ID: 1, FirstName: "John", LastName: "Doe", Role: "Admin", UserID: 1001, Version: 3},
+		{ID: 2, FirstName: "Jane", LastName: "Smith", Role: "User", UserID: 1002, Version: 1},
+		{ID: 3, FirstName: "Jim", LastName: "Smith", Role: "User", UserID: 1003, Version: 2, DeletedAt: &deletedAt},
 	}
 
 	usersOut := make([]outputUser, len(users))
@@ -48,47 +52,95 @@ func TestHandleFetchUser(t *testing.T) {
 		mockInput      []any
 		mockOutput     []any
 		requestIDParam string
+		requestQuery   string
+		ifNoneMatch    string
 		expectedCode   int
+		expectedETag   string
 		expectedBody   string
 	}{
 		"valid ID, user found": {
 			mockCalled:     true,
-			mockInput:      []any{int(users[0].ID)},
+			mockInput:      []any{int(users[0].ID), false},
 			mockOutput:     []any{users[0], nil},
 			requestIDParam: strconv.Itoa(int(users[0].ID)),
 			expectedCode:   http.StatusOK,
+			expectedETag:   `"3"`,
 			expectedBody:   toJSONString(responseUser{User: usersOut[0]}),
 		},
+		"If-None-Match matches, user not modified": {
+			mockCalled:     true,
+			mockInput:      []any{int(users[0].ID), false},
+			mockOutput:     []any{users[0], nil},
+			requestIDParam: strconv.Itoa(int(users[0].ID)),
+			ifNoneMatch:    `"2", W/"3"`,
+			expectedCode:   http.StatusNotModified,
+			expectedETag:   `"3"`,
+			expectedBody:   "",
+		},
+		"If-None-Match does not match, user found": {
+			mockCalled:     true,
+			mockInput:      []any{int(users[1].ID), false},
+			mockOutput:     []any{users[1], nil},
+			requestIDParam: strconv.Itoa(int(users[1].ID)),
+			ifNoneMatch:    `"3"`,
+			expectedCode:   http.StatusOK,
+			expectedETag:   `"1"`,
+			expectedBody:   toJSONString(responseUser{User: usersOut[1]}),
+		},
+		"include_deleted, deleted user found": {
+			mockCalled:     true,
+			mockInput:      []any{int(users[2].ID), true},
+			mockOutput:     []any{users[2], nil},
+			requestIDParam: strconv.Itoa(int(users[2].ID)),
+			requestQuery:   "?include_deleted=true",
+			expectedCode:   http.StatusOK,
+			expectedETag:   `"2"`,
+			expectedBody:   toJSONString(responseUser{User: usersOut[2]}),
+		},
+		"invalid include_deleted": {
+			mockCalled:     false,
+			requestIDParam: "3",
+			requestQuery:   "?include_deleted=maybe",
+			expectedCode:   http.StatusBadRequest,
+			expectedBody: toJSONString(problem.
+				New(http.StatusBadRequest, "One or more query parameters are invalid").
+				WithInstance("/api/user/3").
+				WithFieldErrors(map[string]string{"include_deleted": "must be true or false"}),
+			),
+		},
 		"invalid ID": {
 			mockCalled:     false,
 			mockInput:      nil,
 			mockOutput:     nil,
 			requestIDParam: "abc",
 			expectedCode:   http.StatusBadRequest,
-			expectedBody:   toJSONString(responseErr{Error: "Not a valid ID"}),
+			expectedBody:   toJSONString(problem.New(http.StatusBadRequest, "Not a valid ID").WithInstance("/api/user/abc")),
 		},
 		"user not found": {
 			mockCalled:     true,
-			mockInput:      []any{3},
-			mockOutput:     []any{models.User{}, sql.ErrNoRows},
+			mockInput:      []any{3, false},
+			mockOutput:     []any{models.User{}, fmt.Errorf("[in FetchUser]: %w", service.ErrUserNotFound)},
 			requestIDParam: "3",
-			expectedCode:   http.StatusOK,
-			expectedBody:   toJSONString(responseUser{}),
+			expectedCode:   http.StatusNotFound,
+			expectedBody:   toJSONString(problem.New(http.StatusNotFound, "Object does not exist").WithInstance("/api/user/3")),
 		},
 		"internal server error": {
 			mockCalled:     true,
-			mockInput:      []any{3},
+			mockInput:      []any{3, false},
 			mockOutput:     []any{models.User{}, errors.New("")},
 			requestIDParam: "3",
 			expectedCode:   http.StatusInternalServerError,
-			expectedBody:   toJSONString(responseErr{Error: "Internal server error"}),
+			expectedBody:   toJSONString(problem.New(http.StatusInternalServerError, "Internal server error").WithInstance("/api/user/3")),
 		},
 	}
 
 	for name, tc := range tests {
 		t.Run(name, func(t *testing.T) {
-			req, err := http.NewRequest(http.MethodGet, "/api/user/"+tc.requestIDParam, nil)
+			req, err := http.NewRequest(http.MethodGet, "/api/user/"+tc.requestIDParam+tc.requestQuery, nil)
 			assert.NoError(t, err)
+			if tc.ifNoneMatch != "" {
+				req.Header.Set("If-None-Match", tc.ifNoneMatch)
+			}
 
 			// Add chi URLParam
 			rctx := chi.NewRouteContext()
@@ -107,7 +159,12 @@ func TestHandleFetchUser(t *testing.T) {
 			handler.ServeHTTP(rr, req)
 
 			assert.Equal(t, tc.expectedCode, rr.Code, "Wrong code received")
-			assert.JSONEq(t, tc.expectedBody, rr.Body.String(), "Wrong response body")
+			assert.Equal(t, tc.expectedETag, rr.Header().Get("ETag"), "Wrong ETag")
+			if tc.expectedBody == "" {
+				assert.Empty(t, rr.Body.String(), "Wrong response body")
+			} else {
+				assert.JSONEq(t, tc.expectedBody, rr.Body.String(), "Wrong response body")
+			}
 
 			if tc.mockCalled {
 				mockService.AssertExpectations(t)
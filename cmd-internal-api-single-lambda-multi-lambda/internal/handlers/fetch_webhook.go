@@ -0,0 +1,68 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/jha-captech/user-microservice/internal/models"
+	"github.com/jha-captech/user-microservice/internal/problem"
+	svc "github.com/jha-captech/user-microservice/internal/service"
+)
+
+type webhookFetcher interface {
+	FetchSubscription(ctx context.Context, ID int) (models.WebhookSubscription, error)
+}
+
+// HandleFetchWebhook is a Handler that returns a webhook subscription based on an ID.
+//
+// @Summary		Fetch a webhook subscription by ID
+// @Description	Fetch a webhook subscription by ID
+// @Tags		webhook
+// @Accept		json
+// @Produce		json
+// @Param		id				path		int		true	"Subscription ID"
+// @Param		X-Admin-Token	header		string	true	"Admin token"
+// @Success		200				{object}	handlers.responseWebhook
+// @Failure		400				{object}	problem.Problem
+// @Failure		403				{object}	problem.Problem
+// @Failure		404				{object}	problem.Problem
+// @Failure		500				{object}	problem.Problem
+// @Router		/webhook/{ID}	[GET]
+func HandleFetchWebhook(logger sLogger, service webhookFetcher) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		// setup
+		logger := requestLogger(r, logger)
+		ctx := r.Context()
+
+		// get and validate ID
+		idString := chi.URLParam(r, "ID")
+		ID, err := strconv.Atoi(idString)
+		if err != nil {
+			logger.Error("error getting ID", "error", err)
+			encodeProblem(w, r, logger, problem.New(http.StatusBadRequest, "Not a valid ID"))
+			return
+		}
+
+		// get values from database
+		subscription, err := service.FetchSubscription(ctx, ID)
+		if err != nil {
+			switch {
+			case errors.Is(err, svc.ErrSubscriptionNotFound):
+				logger.Error("Object does not exist", "ID", ID, "error", err)
+				encodeProblem(w, r, logger, problem.New(http.StatusNotFound, "Object does not exist"))
+			default:
+				logger.Error("error getting webhook subscription", "ID", ID, "error", err)
+				encodeProblem(w, r, logger, problem.New(http.StatusInternalServerError, "Internal server error"))
+			}
+			return
+		}
+
+		// return response
+		encodeResponse(w, logger, http.StatusOK, responseWebhook{
+			Webhook: mapWebhookOutput(subscription),
+		})
+	}
+}
@@ -0,0 +1,106 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/jha-captech/user-microservice/internal/models"
+	"github.com/jha-captech/user-microservice/internal/problem"
+	"github.com/jha-captech/user-microservice/internal/service"
+	"github.com/stretchr/testify/assert"
+
+	serviceMock "github.com/jha-captech/user-microservice/internal/handlers/mock"
+)
+
+func TestHandleFetchWebhook(t *testing.T) {
+	mockService := new(serviceMock.MockWebhookFetcher)
+	logger := slog.Default()
+	handler := HandleFetchWebhook(logger, mockService)
+
+	createdAt := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	subscription := models.WebhookSubscription{
+		ID: 1, URL: "https://example.com/hook", Secret: "0123456789abcdef",
+		Events: []models.EventType{models.EventUserCreated}, CreatedAt: createdAt, UpdatedAt: createdAt,
+	}
+
+	tests := map[string]struct {
+		mockCalled     bool
+		mockInput      []any
+		mockOutput     []any
+		requestIDParam string
+		expectedCode   int
+		expectedBody   string
+	}{
+		"subscription found": {
+			mockCalled:     true,
+			mockInput:      []any{1},
+			mockOutput:     []any{subscription, nil},
+			requestIDParam: "1",
+			expectedCode:   http.StatusOK,
+			expectedBody: toJSONString(responseWebhook{Webhook: outputWebhookSubscription{
+				ID: 1, URL: "https://example.com/hook", Events: []string{"user.created"}, CreatedAt: createdAt, UpdatedAt: createdAt,
+			}}),
+		},
+		"invalid ID": {
+			mockCalled:     false,
+			requestIDParam: "abc",
+			expectedCode:   http.StatusBadRequest,
+			expectedBody:   toJSONString(problem.New(http.StatusBadRequest, "Not a valid ID").WithInstance("/api/webhook/abc")),
+		},
+		"subscription not found": {
+			mockCalled:     true,
+			mockInput:      []any{2},
+			mockOutput:     []any{models.WebhookSubscription{}, fmt.Errorf("[in FetchSubscription]: %w", service.ErrSubscriptionNotFound)},
+			requestIDParam: "2",
+			expectedCode:   http.StatusNotFound,
+			expectedBody:   toJSONString(problem.New(http.StatusNotFound, "Object does not exist").WithInstance("/api/webhook/2")),
+		},
+		"internal server error": {
+			mockCalled:     true,
+			mockInput:      []any{1},
+			mockOutput:     []any{models.WebhookSubscription{}, errors.New("")},
+			requestIDParam: "1",
+			expectedCode:   http.StatusInternalServerError,
+			expectedBody:   toJSONString(problem.New(http.StatusInternalServerError, "Internal server error").WithInstance("/api/webhook/1")),
+		},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			req, err := http.NewRequest(http.MethodGet, "/api/webhook/"+tc.requestIDParam, nil)
+			assert.NoError(t, err)
+
+			// Add chi URLParam
+			rctx := chi.NewRouteContext()
+			rctx.URLParams.Add("ID", tc.requestIDParam)
+			ctx := context.WithValue(req.Context(), chi.RouteCtxKey, rctx)
+			req = req.WithContext(ctx)
+
+			if tc.mockCalled {
+				mockService.
+					On("FetchSubscription", append([]any{ctx}, tc.mockInput...)...).
+					Return(tc.mockOutput...).
+					Once()
+			}
+
+			rr := httptest.NewRecorder()
+			handler.ServeHTTP(rr, req)
+
+			assert.Equal(t, tc.expectedCode, rr.Code, "Wrong code received")
+			assert.JSONEq(t, tc.expectedBody, rr.Body.String(), "Wrong response body")
+
+			if tc.mockCalled {
+				mockService.AssertExpectations(t)
+			} else {
+				mockService.AssertNotCalled(t, "FetchSubscription")
+			}
+		})
+	}
+}
@@ -1,8 +1,37 @@
 package handlers
 
+import (
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/jha-captech/user-microservice/internal/logging"
+)
+
 type sLogger interface {
 	Debug(msg string, args ...any)
 	Info(msg string, args ...any)
 	Warn(msg string, args ...any)
 	Error(msg string, args ...any)
 }
+
+// requestLogger returns the logger that logging.Middleware put into the context of r, which logs
+// the request ID, trace ID, route and user of r with every line, or logger if r has none.
+func requestLogger(r *http.Request, logger sLogger) sLogger {
+	if requestLogger, ok := logging.FromContext(r.Context()); ok {
+		return requestLogger
+	}
+	return logger
+}
+
+// extendDeadlines replaces the server's read and write timeouts, which are meant for regular
+// requests, with deadline for a request that takes longer, such as an export, an import or an event
+// stream. A zero deadline lifts them. Not every ResponseWriter supports deadlines, and as the request
+// may then be cut off by the server's timeouts, that is logged rather than ignored.
+func extendDeadlines(w http.ResponseWriter, logger sLogger, deadline time.Time) {
+	rc := http.NewResponseController(w)
+	err := errors.Join(rc.SetReadDeadline(deadline), rc.SetWriteDeadline(deadline))
+	if err != nil {
+		logger.Warn("Error extending deadlines of request, server timeouts still apply", "deadline", deadline, "error", err)
+	}
+}
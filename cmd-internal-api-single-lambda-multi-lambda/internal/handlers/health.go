@@ -1,23 +1,57 @@
 package handlers
 
 import (
+	"context"
 	"net/http"
+
+	"github.com/jha-captech/user-microservice/internal/health"
 )
 
-// HandleHealth is a health check handler
+type readinessChecker interface {
+	Ready(ctx context.Context) health.Report
+}
+
+// HandleLiveness is a Handler that reports the service as alive whenever it can serve a request at
+// all. It does not check any dependency, so a database outage does not get the service restarted.
+//
+// @Summary		Liveness probe
+// @Description	Report that the service is alive
+// @Tags		health-check
+// @Accept		json
+// @Produce		json
+// @Success		200				{object}	health.Report
+// @Router		/health/live	[GET]
+func HandleLiveness(logger sLogger) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		logger := requestLogger(r, logger)
+
+		encodeResponse(w, logger, http.StatusOK, health.Report{Status: health.StatusOK})
+	}
+}
+
+// HandleReadiness is a Handler that reports whether the service is ready to serve requests, with
+// the health of each of its components. A service that is not ready, including one that is draining
+// on shutdown, responds with 503 Service Unavailable.
 //
-// @Summary		Health check response
-// @Description	Health check response
+// @Summary		Readiness probe
+// @Description	Report whether the service and its components are ready
 // @Tags		health-check
 // @Accept		json
 // @Produce		json
-// @Success		200				{object}	handlers.responseMsg
-// @Router		/health-check	[GET]
-func HandleHealth(logger sLogger) http.HandlerFunc {
+// @Success		200				{object}	health.Report
+// @Failure		503				{object}	health.Report
+// @Router		/health/ready	[GET]
+func HandleReadiness(logger sLogger, checker readinessChecker) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
-		logger.Info("Health check called")
-		encodeResponse(w, logger, http.StatusOK, responseMsg{
-			Message: "hello world",
-		})
+		logger := requestLogger(r, logger)
+
+		report := checker.Ready(r.Context())
+		if report.Status != health.StatusOK {
+			logger.Warn("Service not ready", "report", report)
+			encodeResponse(w, logger, http.StatusServiceUnavailable, report)
+			return
+		}
+
+		encodeResponse(w, logger, http.StatusOK, report)
 	}
 }
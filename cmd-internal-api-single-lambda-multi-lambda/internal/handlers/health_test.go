@@ -0,0 +1,94 @@
+package handlers
+
+import (
+	"context"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/jha-captech/user-microservice/internal/health"
+	"github.com/stretchr/testify/assert"
+
+	serviceMock "github.com/jha-captech/user-microservice/internal/handlers/mock"
+)
+
+func TestHandleLiveness(t *testing.T) {
+	handler := HandleLiveness(slog.Default())
+
+	req, err := http.NewRequest(http.MethodGet, "/api/health/live", nil)
+	assert.NoError(t, err)
+
+	rr := httptest.NewRecorder()
+	handler.ServeHTTP(rr, req)
+
+	assert.Equal(t, http.StatusOK, rr.Code, "Wrong code received")
+	assert.JSONEq(t, `{"status": "ok"}`, rr.Body.String(), "Wrong response body")
+}
+
+func TestHandleReadiness(t *testing.T) {
+	mockChecker := new(serviceMock.MockReadinessChecker)
+	logger := slog.Default()
+	handler := HandleReadiness(logger, mockChecker)
+
+	ready := health.Report{
+		Status: health.StatusOK,
+		Components: map[string]health.Component{
+			"database":   {Status: health.StatusOK},
+			"migrations": {Status: health.StatusOK, Details: map[string]any{"applied": 9, "expected": 9}},
+		},
+	}
+	notReady := health.Report{
+		Status: health.StatusFail,
+		Components: map[string]health.Component{
+			"database":   {Status: health.StatusFail, Error: "connection refused"},
+			"migrations": {Status: health.StatusOK, Details: map[string]any{"applied": 9, "expected": 9}},
+		},
+	}
+
+	tests := map[string]struct {
+		report       health.Report
+		expectedCode int
+		expectedBody string
+	}{
+		"ready": {
+			report:       ready,
+			expectedCode: http.StatusOK,
+			expectedBody: toJSONString(ready),
+		},
+		"component failed": {
+			report:       notReady,
+			expectedCode: http.StatusServiceUnavailable,
+			expectedBody: toJSONString(notReady),
+		},
+		"draining": {
+			report:       health.Report{Status: health.StatusFail, Draining: true},
+			expectedCode: http.StatusServiceUnavailable,
+			expectedBody: `{"status": "fail", "draining": true}`,
+		},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			req, err := http.NewRequest(http.MethodGet, "/api/health/ready", nil)
+			assert.NoError(t, err)
+
+			rctx := chi.NewRouteContext()
+			ctx := context.WithValue(req.Context(), chi.RouteCtxKey, rctx)
+			req = req.WithContext(ctx)
+
+			mockChecker.
+				On("Ready", ctx).
+				Return(tc.report).
+				Once()
+
+			rr := httptest.NewRecorder()
+			handler.ServeHTTP(rr, req)
+
+			assert.Equal(t, tc.expectedCode, rr.Code, "Wrong code received")
+			assert.JSONEq(t, tc.expectedBody, rr.Body.String(), "Wrong response body")
+			mockChecker.AssertExpectations(t)
+		})
+	}
+}
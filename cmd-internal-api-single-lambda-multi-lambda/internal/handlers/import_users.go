@@ -0,0 +1,323 @@
+package handlers
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/jha-captech/user-microservice/internal/models"
+	"github.com/jha-captech/user-microservice/internal/problem"
+	svc "github.com/jha-captech/user-microservice/internal/service"
+)
+
+type userImporter interface {
+	ImportUsers(
+		ctx context.Context,
+		rows []models.ImportRow,
+		onConflict models.ImportConflict,
+		dryRun bool,
+	) (models.ImportResult, error)
+}
+
+var (
+	// errTooManyRows is returned when an import file has more than maxImportRows rows.
+	errTooManyRows = fmt.Errorf("more than %d rows", maxImportRows)
+
+	// errMissingColumn is returned when the header of a CSV import file is missing a column.
+	errMissingColumn = errors.New("header is missing a column")
+)
+
+// HandleImportUsers is a Handler that creates users from an uploaded CSV or NDJSON file, which has
+// the same format as an export. Every row is validated as a user and nothing is imported if any row
+// is invalid. Rows whose user_id is already in use are resolved by on_conflict. With dry_run, the
+// import is validated and reported without anything being written.
+//
+// Problems are reported by row, counting from 1. For a CSV file, the header is not counted, while
+// for an NDJSON file the row is the line number.
+//
+// @Summary		Import users
+// @Description	Import users from a CSV or NDJSON file
+// @Tags		user
+// @Accept		text/csv,application/x-ndjson
+// @Produce		json
+// @Param		file			body		string	true	"CSV with a header row, or one JSON user per line"
+// @Param		on_conflict		query		string	false	"How to resolve rows with a user_id already in use"	Enums(fail, skip, update)	default(fail)
+// @Param		dry_run			query		bool	false	"Validate and report without importing"	default(false)
+// @Success		200				{object}	handlers.responseImport
+// @Failure		400				{object}	problem.Problem
+// @Failure		409				{object}	problem.Problem
+// @Failure		415				{object}	problem.Problem
+// @Failure		500				{object}	problem.Problem
+// @Router		/user/import	[POST]
+func HandleImportUsers(logger sLogger, service userImporter) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		// setup
+		logger := requestLogger(r, logger)
+		ctx := r.Context()
+
+		extendDeadlines(w, logger, time.Now().Add(importTimeout))
+
+		// get and validate query params
+		params, problems, err := validateInput[inputImportUsers, importParams](
+			newInputImportUsers(r.URL.Query()),
+		)
+		if err != nil {
+			switch {
+			case len(problems) > 0:
+				logger.Error("Problems validating query", "error", err, "problems", problems)
+				encodeProblem(w, r, logger, problem.
+					New(http.StatusBadRequest, "One or more query parameters are invalid").
+					WithFieldErrors(problems),
+				)
+			default:
+				logger.Error("Query parse error", "error", err)
+				encodeProblem(w, r, logger, problem.New(http.StatusBadRequest, "malformed query parameters"))
+			}
+			return
+		}
+
+		// get file format
+		contentType, _, err := mime.ParseMediaType(r.Header.Get("Content-Type"))
+		if err != nil || (contentType != contentTypeCSV && contentType != contentTypeNDJSON) {
+			logger.Error("Unsupported import format", "Content-Type", r.Header.Get("Content-Type"))
+			encodeProblem(w, r, logger, problem.New(
+				http.StatusUnsupportedMediaType,
+				fmt.Sprintf("Content-Type must be %s or %s", contentTypeCSV, contentTypeNDJSON),
+			))
+			return
+		}
+
+		// get and validate rows
+		rows, problems, err := decodeValidateImport(r.Body, contentType)
+		if err != nil {
+			switch {
+			case len(problems) > 0:
+				logger.Error("Problems validating import", "error", err, "problems", len(problems))
+				encodeProblem(w, r, logger, problem.
+					New(http.StatusBadRequest, "One or more rows are invalid").
+					WithFieldErrors(problems),
+				)
+			case errors.Is(err, errMissingColumn):
+				logger.Error("Import header error", "error", err)
+				encodeProblem(w, r, logger, problem.New(
+					http.StatusBadRequest,
+					"CSV header must have the columns first_name, last_name, role and user_id",
+				))
+			case errors.Is(err, errTooManyRows):
+				logger.Error("Import too large", "error", err)
+				encodeProblem(w, r, logger, problem.New(
+					http.StatusBadRequest,
+					fmt.Sprintf("Import must have at most %d rows", maxImportRows),
+				))
+			default:
+				logger.Error("Import parse error", "error", err)
+				encodeProblem(w, r, logger, problem.New(http.StatusBadRequest, "missing values or malformed file"))
+			}
+			return
+		}
+
+		// import rows into database
+		result, err := service.ImportUsers(ctx, rows, params.OnConflict, params.DryRun)
+		if err != nil {
+			switch {
+			case errors.Is(err, svc.ErrUserIDConflict):
+				logger.Error("user_id already in use", "error", err)
+				encodeProblem(w, r, logger, problem.New(http.StatusConflict, "user_id already in use"))
+			default:
+				logger.Error("error importing objects to database", "error", err)
+				encodeProblem(w, r, logger, problem.New(http.StatusInternalServerError, "Error importing objects"))
+			}
+			return
+		}
+		if params.OnConflict == models.ImportConflictFail && len(result.ConflictRows) > 0 {
+			conflicts := make(map[string]string, len(result.ConflictRows))
+			for _, row := range result.ConflictRows {
+				conflicts[rowField(row, "user_id")] = "already in use"
+			}
+			logger.Error("Import has conflicts", "conflicts", len(result.ConflictRows))
+			encodeProblem(w, r, logger, problem.
+				New(http.StatusConflict, "One or more rows have a user_id already in use").
+				WithFieldErrors(conflicts),
+			)
+			return
+		}
+
+		// return response
+		encodeResponse(w, logger, http.StatusOK, responseImport{
+			DryRun:     params.DryRun,
+			OnConflict: string(params.OnConflict),
+			Rows:       len(rows),
+			Created:    result.Created,
+			Updated:    result.Updated,
+			Skipped:    result.Skipped,
+			Conflicts:  result.ConflictRows,
+		})
+	}
+}
+
+// inputImportRow is a single row of an import file. Problems holds any problems found while reading
+// the row, before it is validated as a user.
+type inputImportRow struct {
+	Row      int
+	User     inputUser
+	Problems map[string]string
+}
+
+// decodeValidateImport reads the rows of an import file in the format contentType from body and
+// validates each of them. Problems are keyed by rowField. A user_id used by an earlier row is a
+// problem, as the rows are imported together.
+func decodeValidateImport(body io.Reader, contentType string) ([]models.ImportRow, map[string]string, error) {
+	decode := decodeImportCSV
+	if contentType == contentTypeNDJSON {
+		decode = decodeImportNDJSON
+	}
+
+	inputRows, err := decode(body)
+	if err != nil {
+		return nil, nil, fmt.Errorf("[in decodeValidateImport] decode: %w", err)
+	}
+	if len(inputRows) == 0 {
+		return nil, map[string]string{"rows": "must have at least one row"}, errors.New(
+			"[in decodeValidateImport] no rows",
+		)
+	}
+
+	var (
+		rows      = make([]models.ImportRow, 0, len(inputRows))
+		problems  = make(map[string]string)
+		userIDRow = make(map[int]int)
+	)
+	for _, inputRow := range inputRows {
+		for field, detail := range inputRow.Problems {
+			problems[rowField(inputRow.Row, field)] = detail
+		}
+		if _, malformed := inputRow.Problems[""]; malformed {
+			continue
+		}
+
+		user, rowProblems, err := validateInput[inputUser, models.User](inputRow.User)
+		if err != nil && len(rowProblems) == 0 {
+			return nil, nil, fmt.Errorf("[in decodeValidateImport] row %d: %w", inputRow.Row, err)
+		}
+		for field, detail := range rowProblems {
+			if _, ok := inputRow.Problems[field]; !ok {
+				problems[rowField(inputRow.Row, field)] = detail
+			}
+		}
+
+		if firstRow, ok := userIDRow[inputRow.User.UserID]; ok && inputRow.User.UserID > 0 {
+			problems[rowField(inputRow.Row, "user_id")] = fmt.Sprintf("already used by row %d", firstRow)
+		} else {
+			userIDRow[inputRow.User.UserID] = inputRow.Row
+		}
+
+		rows = append(rows, models.ImportRow{Row: inputRow.Row, User: user})
+	}
+
+	if len(problems) > 0 {
+		return nil, problems, fmt.Errorf("[in decodeValidateImport] %d problems", len(problems))
+	}
+
+	return rows, nil, nil
+}
+
+// decodeImportCSV reads the rows of a CSV import file. The first record is a header naming the
+// columns, which may be in any order. Columns other than the fields of inputUser, such as the id and
+// version of an export, are ignored.
+func decodeImportCSV(body io.Reader) ([]inputImportRow, error) {
+	reader := csv.NewReader(body)
+	reader.ReuseRecord = true
+
+	header, err := reader.Read()
+	if err != nil {
+		return nil, fmt.Errorf("read header: %w", err)
+	}
+	columns := make(map[string]int, len(header))
+	for i, name := range header {
+		columns[strings.TrimSpace(name)] = i
+	}
+	for _, name := range []string{"first_name", "last_name", "role", "user_id"} {
+		if _, ok := columns[name]; !ok {
+			return nil, fmt.Errorf("%w: %q", errMissingColumn, name)
+		}
+	}
+
+	var rows []inputImportRow
+	for {
+		record, err := reader.Read()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("read row %d: %w", len(rows)+1, err)
+		}
+		if len(rows) == maxImportRows {
+			return nil, errTooManyRows
+		}
+
+		row := inputImportRow{
+			Row: len(rows) + 1,
+			User: inputUser{
+				FirstName: record[columns["first_name"]],
+				LastName:  record[columns["last_name"]],
+				Role:      record[columns["role"]],
+			},
+		}
+		if row.User.UserID, err = strconv.Atoi(strings.TrimSpace(record[columns["user_id"]])); err != nil {
+			row.Problems = map[string]string{"user_id": "must be a number"}
+		}
+		rows = append(rows, row)
+	}
+
+	return rows, nil
+}
+
+// decodeImportNDJSON reads the rows of an NDJSON import file, which has a JSON user on each line.
+// Blank lines are skipped and members other than the fields of inputUser are ignored.
+func decodeImportNDJSON(body io.Reader) ([]inputImportRow, error) {
+	scanner := bufio.NewScanner(body)
+
+	var (
+		rows []inputImportRow
+		line int
+	)
+	for scanner.Scan() {
+		line++
+		if len(bytes.TrimSpace(scanner.Bytes())) == 0 {
+			continue
+		}
+		if len(rows) == maxImportRows {
+			return nil, errTooManyRows
+		}
+
+		row := inputImportRow{Row: line}
+		if err := json.Unmarshal(scanner.Bytes(), &row.User); err != nil {
+			row.Problems = map[string]string{"": "must be a JSON user object"}
+		}
+		rows = append(rows, row)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("read line %d: %w", line+1, err)
+	}
+
+	return rows, nil
+}
+
+// rowField returns the name a problem with field of row is reported under. An empty field is a
+// problem with the row as a whole.
+func rowField(row int, field string) string {
+	if field == "" {
+		return fmt.Sprintf("rows[%d]", row)
+	}
+	return fmt.Sprintf("rows[%d].%s", row, field)
+}
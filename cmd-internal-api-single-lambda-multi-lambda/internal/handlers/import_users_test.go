@@ -0,0 +1,204 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+
+	serviceMock "github.com/jha-captech/user-microservice/internal/handlers/mock"
+	"github.com/jha-captech/user-microservice/internal/models"
+	"github.com/jha-captech/user-microservice/internal/problem"
+	"github.com/jha-captech/user-microservice/internal/service"
+)
+
+func TestHandleImportUsers(t *testing.T) {
+	mockService := new(serviceMock.MockUserImporter)
+	logger := slog.Default()
+	handler := HandleImportUsers(logger, mockService)
+
+	rows := []models.ImportRow{
+		{Row: 1, User: models.User{FirstName: "John", LastName: "Doe", Role: "Customer", UserID: 1001}},
+		{Row: 2, User: models.User{FirstName: "Jane", LastName: "Roe", Role: "Employee", UserID: 1002}},
+	}
+
+	tests := map[string]struct {
+		mockCalled   bool
+		mockInput    []any
+		mockOutput   []any
+		query        string
+		contentType  string
+		requestBody  string
+		expectedCode int
+		expectedBody string
+	}{
+		"csv imported": {
+			mockCalled:  true,
+			mockInput:   []any{rows, models.ImportConflictFail, false},
+			mockOutput:  []any{models.ImportResult{Created: 2}, nil},
+			contentType: "text/csv; charset=utf-8",
+			requestBody: "id,first_name,last_name,role,user_id,version\n" +
+				"1,John,Doe,Customer,1001,3\n" +
+				"2,Jane,Roe,Employee,1002,1\n",
+			expectedCode: http.StatusOK,
+			expectedBody: toJSONString(responseImport{OnConflict: "fail", Rows: 2, Created: 2}),
+		},
+		"ndjson dry run with updates": {
+			mockCalled:  true,
+			mockInput:   []any{[]models.ImportRow{rows[0], {Row: 3, User: rows[1].User}}, models.ImportConflictUpdate, true},
+			mockOutput:  []any{models.ImportResult{Created: 1, Updated: 1, ConflictRows: []int{3}}, nil},
+			query:       "?on_conflict=update&dry_run=true",
+			contentType: "application/x-ndjson",
+			requestBody: `{"first_name":"John","last_name":"Doe","role":"Customer","user_id":1001}` + "\n\n" +
+				`{"id":2,"first_name":"Jane","last_name":"Roe","role":"Employee","user_id":1002}` + "\n",
+			expectedCode: http.StatusOK,
+			expectedBody: toJSONString(responseImport{
+				DryRun:     true,
+				OnConflict: "update",
+				Rows:       2,
+				Created:    1,
+				Updated:    1,
+				Conflicts:  []int{3},
+			}),
+		},
+		"invalid rows": {
+			mockCalled:  false,
+			contentType: "text/csv",
+			requestBody: "user_id,role,first_name,last_name\n" +
+				"1001,Customer,John,Doe\n" +
+				"abc,Customer,Jane,Roe\n" +
+				"1001,Admin,Jim,Poe\n",
+			expectedCode: http.StatusBadRequest,
+			expectedBody: toJSONString(problem.
+				New(http.StatusBadRequest, "One or more rows are invalid").
+				WithInstance("/api/user/import").
+				WithFieldErrors(map[string]string{
+					"rows[2].user_id": "must be a number",
+					"rows[3].role":    "must be 'Customer' or 'Employee'",
+					"rows[3].user_id": "already used by row 1",
+				}),
+			),
+		},
+		"malformed ndjson row": {
+			mockCalled:   false,
+			contentType:  "application/x-ndjson",
+			requestBody:  `{"first_name":"John","last_name":"Doe","role":"Customer","user_id":1001}` + "\n" + `{"first_name":` + "\n",
+			expectedCode: http.StatusBadRequest,
+			expectedBody: toJSONString(problem.
+				New(http.StatusBadRequest, "One or more rows are invalid").
+				WithInstance("/api/user/import").
+				WithFieldErrors(map[string]string{"rows[2]": "must be a JSON user object"}),
+			),
+		},
+		"missing csv column": {
+			mockCalled:   false,
+			contentType:  "text/csv",
+			requestBody:  "first_name,last_name,role\nJohn,Doe,Customer\n",
+			expectedCode: http.StatusBadRequest,
+			expectedBody: toJSONString(problem.
+				New(http.StatusBadRequest, "CSV header must have the columns first_name, last_name, role and user_id").
+				WithInstance("/api/user/import"),
+			),
+		},
+		"empty file": {
+			mockCalled:   false,
+			contentType:  "text/csv",
+			requestBody:  "first_name,last_name,role,user_id\n",
+			expectedCode: http.StatusBadRequest,
+			expectedBody: toJSONString(problem.
+				New(http.StatusBadRequest, "One or more rows are invalid").
+				WithInstance("/api/user/import").
+				WithFieldErrors(map[string]string{"rows": "must have at least one row"}),
+			),
+		},
+		"invalid query": {
+			mockCalled:   false,
+			query:        "?on_conflict=merge&dry_run=maybe",
+			contentType:  "text/csv",
+			expectedCode: http.StatusBadRequest,
+			expectedBody: toJSONString(problem.
+				New(http.StatusBadRequest, "One or more query parameters are invalid").
+				WithInstance("/api/user/import").
+				WithFieldErrors(map[string]string{
+					"on_conflict": "must be one of [fail skip update]",
+					"dry_run":     "must be true or false",
+				}),
+			),
+		},
+		"unsupported content type": {
+			mockCalled:   false,
+			contentType:  "application/json",
+			requestBody:  `[]`,
+			expectedCode: http.StatusUnsupportedMediaType,
+			expectedBody: toJSONString(problem.
+				New(http.StatusUnsupportedMediaType, "Content-Type must be text/csv or application/x-ndjson").
+				WithInstance("/api/user/import"),
+			),
+		},
+		"conflicts with fail": {
+			mockCalled:   true,
+			mockInput:    []any{rows, models.ImportConflictFail, false},
+			mockOutput:   []any{models.ImportResult{ConflictRows: []int{2}}, nil},
+			contentType:  "text/csv",
+			requestBody:  "first_name,last_name,role,user_id\nJohn,Doe,Customer,1001\nJane,Roe,Employee,1002\n",
+			expectedCode: http.StatusConflict,
+			expectedBody: toJSONString(problem.
+				New(http.StatusConflict, "One or more rows have a user_id already in use").
+				WithInstance("/api/user/import").
+				WithFieldErrors(map[string]string{"rows[2].user_id": "already in use"}),
+			),
+		},
+		"user_id conflict while importing": {
+			mockCalled:   true,
+			mockInput:    []any{rows, models.ImportConflictSkip, false},
+			mockOutput:   []any{models.ImportResult{}, fmt.Errorf("%w: duplicate key", service.ErrUserIDConflict)},
+			query:        "?on_conflict=skip",
+			contentType:  "text/csv",
+			requestBody:  "first_name,last_name,role,user_id\nJohn,Doe,Customer,1001\nJane,Roe,Employee,1002\n",
+			expectedCode: http.StatusConflict,
+			expectedBody: toJSONString(problem.New(http.StatusConflict, "user_id already in use").WithInstance("/api/user/import")),
+		},
+		"error importing": {
+			mockCalled:   true,
+			mockInput:    []any{rows, models.ImportConflictFail, false},
+			mockOutput:   []any{models.ImportResult{}, errors.New("copy error")},
+			contentType:  "text/csv",
+			requestBody:  "first_name,last_name,role,user_id\nJohn,Doe,Customer,1001\nJane,Roe,Employee,1002\n",
+			expectedCode: http.StatusInternalServerError,
+			expectedBody: toJSONString(problem.New(http.StatusInternalServerError, "Error importing objects").WithInstance("/api/user/import")),
+		},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			req, err := http.NewRequest(http.MethodPost, "/api/user/import"+tc.query, strings.NewReader(tc.requestBody))
+			assert.NoError(t, err)
+			req.Header.Set("Content-Type", tc.contentType)
+
+			if tc.mockCalled {
+				mockService.
+					On("ImportUsers", append([]any{mock.Anything}, tc.mockInput...)...).
+					Return(tc.mockOutput...).
+					Once()
+			}
+
+			rr := httptest.NewRecorder()
+			handler.ServeHTTP(rr, req)
+
+			assert.Equal(t, tc.expectedCode, rr.Code, "Wrong code received")
+			assert.JSONEq(t, tc.expectedBody, rr.Body.String(), "Wrong response body")
+
+			if tc.mockCalled {
+				mockService.AssertExpectations(t)
+			} else {
+				mockService.AssertNotCalled(t, "ImportUsers")
+			}
+		})
+	}
+}
@@ -0,0 +1,47 @@
+package handlers
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/jha-captech/user-microservice/internal/models"
+	"github.com/jha-captech/user-microservice/internal/problem"
+)
+
+type apiKeyLister interface {
+	ListKeys(ctx context.Context) ([]models.APIKey, error)
+}
+
+// HandleListAPIKeys is a Handler that returns all API keys, including expired and revoked ones. The
+// keys themselves are never returned, only their prefixes.
+//
+// @Summary		List API keys
+// @Description	List all API keys
+// @Tags		api-key
+// @Accept		json
+// @Produce		json
+// @Param		X-Admin-Token	header		string	true	"Admin token"
+// @Success		200				{object}	handlers.responseAPIKeys
+// @Failure		403				{object}	problem.Problem
+// @Failure		500				{object}	problem.Problem
+// @Router		/api-key		[GET]
+func HandleListAPIKeys(logger sLogger, service apiKeyLister) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		// setup
+		logger := requestLogger(r, logger)
+		ctx := r.Context()
+
+		// get values from database
+		keys, err := service.ListKeys(ctx)
+		if err != nil {
+			logger.Error("error getting api keys", "error", err)
+			encodeProblem(w, r, logger, problem.New(http.StatusInternalServerError, "Error retrieving data"))
+			return
+		}
+
+		// return response
+		encodeResponse(w, logger, http.StatusOK, responseAPIKeys{
+			APIKeys: mapMultipleAPIKeyOutput(keys),
+		})
+	}
+}
@@ -0,0 +1,83 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/jha-captech/user-microservice/internal/models"
+	"github.com/jha-captech/user-microservice/internal/problem"
+	"github.com/stretchr/testify/assert"
+
+	serviceMock "github.com/jha-captech/user-microservice/internal/handlers/mock"
+)
+
+func TestHandleListAPIKeys(t *testing.T) {
+	mockService := new(serviceMock.MockApiKeyLister)
+	logger := slog.Default()
+	handler := HandleListAPIKeys(logger, mockService)
+
+	createdAt := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	expiresAt := time.Date(2025, 5, 1, 12, 0, 0, 0, time.UTC)
+	keys := []models.APIKey{
+		{
+			ID: 1, Name: "nightly export", Prefix: "umk_abcdefgh", Scopes: []models.APIKeyScope{models.ScopeUsersRead},
+			ExpiresAt: &expiresAt, CreatedAt: createdAt,
+		},
+		{
+			ID: 2, Name: "billing", Prefix: "umk_hgfedcba", Scopes: []models.APIKeyScope{models.ScopeUsersWrite},
+			RevokedAt: &createdAt, CreatedAt: createdAt,
+		},
+	}
+
+	tests := map[string]struct {
+		mockOutput   []any
+		expectedCode int
+		expectedBody string
+	}{
+		"keys returned": {
+			mockOutput:   []any{keys, nil},
+			expectedCode: http.StatusOK,
+			expectedBody: toJSONString(responseAPIKeys{APIKeys: mapMultipleAPIKeyOutput(keys)}),
+		},
+		"no keys": {
+			mockOutput:   []any{[]models.APIKey{}, nil},
+			expectedCode: http.StatusOK,
+			expectedBody: `{"api_keys": []}`,
+		},
+		"error getting keys": {
+			mockOutput:   []any{nil, errors.New("list error")},
+			expectedCode: http.StatusInternalServerError,
+			expectedBody: toJSONString(problem.New(http.StatusInternalServerError, "Error retrieving data").WithInstance("/api/api-key")),
+		},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			req, err := http.NewRequest(http.MethodGet, "/api/api-key", nil)
+			assert.NoError(t, err)
+
+			// Add chi URLParam
+			rctx := chi.NewRouteContext()
+			ctx := context.WithValue(req.Context(), chi.RouteCtxKey, rctx)
+			req = req.WithContext(ctx)
+
+			mockService.
+				On("ListKeys", ctx).
+				Return(tc.mockOutput...).
+				Once()
+
+			rr := httptest.NewRecorder()
+			handler.ServeHTTP(rr, req)
+
+			assert.Equal(t, tc.expectedCode, rr.Code, "Wrong code received")
+			assert.JSONEq(t, tc.expectedBody, rr.Body.String(), "Wrong response body")
+			mockService.AssertExpectations(t)
+		})
+	}
+}
@@ -0,0 +1,93 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/jha-captech/user-microservice/internal/models"
+	"github.com/jha-captech/user-microservice/internal/problem"
+	svc "github.com/jha-captech/user-microservice/internal/service"
+)
+
+type userHistoryLister interface {
+	ListUserHistory(ctx context.Context, ID int, params models.ListHistoryParams) (models.UserHistoryPage, error)
+}
+
+// HandleListUserHistory is a Handler that returns a page of the change history of a user, newest
+// change first. Each entry holds the user before and after the change, so the history of a purged
+// user can still be read.
+//
+// @Summary		List the history of a user
+// @Description	List the changes made to a user, newest first
+// @Tags		user
+// @Accept		json
+// @Produce		json
+// @Param		id			path		int		true	"User ID"
+// @Param		limit		query		int		false	"Maximum number of entries to return (1-100)"	default(20)
+// @Param		offset		query		int		false	"Number of entries to skip"						default(0)
+// @Success		200			{object}	handlers.responseUserHistory
+// @Failure		400			{object}	problem.Problem
+// @Failure		404			{object}	problem.Problem
+// @Failure		500			{object}	problem.Problem
+// @Router		/user/{ID}/history	[GET]
+func HandleListUserHistory(logger sLogger, service userHistoryLister) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		// setup
+		logger := requestLogger(r, logger)
+		ctx := r.Context()
+
+		// get and validate ID
+		idString := chi.URLParam(r, "ID")
+		ID, err := strconv.Atoi(idString)
+		if err != nil {
+			logger.Error("error getting ID", "error", err)
+			encodeProblem(w, r, logger, problem.New(http.StatusBadRequest, "Not a valid ID"))
+			return
+		}
+
+		// get and validate query params
+		params, problems, err := validateInput[inputListHistory, models.ListHistoryParams](
+			newInputListHistory(r.URL.Query()),
+		)
+		if err != nil {
+			switch {
+			case len(problems) > 0:
+				logger.Error("Problems validating query", "error", err, "problems", problems)
+				encodeProblem(w, r, logger, problem.
+					New(http.StatusBadRequest, "One or more query parameters are invalid").
+					WithFieldErrors(problems),
+				)
+			default:
+				logger.Error("Query parse error", "error", err)
+				encodeProblem(w, r, logger, problem.New(http.StatusBadRequest, "malformed query parameters"))
+			}
+			return
+		}
+
+		// get values from database
+		page, err := service.ListUserHistory(ctx, ID, params)
+		if err != nil {
+			switch {
+			case errors.Is(err, svc.ErrUserNotFound):
+				logger.Error("Object does not exist", "ID", ID, "error", err)
+				encodeProblem(w, r, logger, problem.New(http.StatusNotFound, "Object does not exist"))
+			default:
+				logger.Error("error getting history", "ID", ID, "error", err)
+				encodeProblem(w, r, logger, problem.New(http.StatusInternalServerError, "Error retrieving data"))
+			}
+			return
+		}
+
+		// return response
+		encodeResponse(w, logger, http.StatusOK, responseUserHistory{
+			History: mapMultipleHistoryOutput(page.Entries),
+			Total:   page.Total,
+			Limit:   params.Limit,
+			Offset:  params.Offset,
+			Next:    nextOffsetURL(r.URL, params.Limit, params.Offset, page.Total),
+		})
+	}
+}
@@ -0,0 +1,137 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/jha-captech/user-microservice/internal/models"
+	"github.com/jha-captech/user-microservice/internal/problem"
+	"github.com/jha-captech/user-microservice/internal/service"
+	"github.com/stretchr/testify/assert"
+	"golang.org/x/net/context"
+
+	serviceMock "github.com/jha-captech/user-microservice/internal/handlers/mock"
+)
+
+func TestHandleListUserHistory(t *testing.T) {
+	mockService := new(serviceMock.MockUserHistoryLister)
+	logger := slog.Default()
+	handler := HandleListUserHistory(logger, mockService)
+
+	createdAt := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	deletedAt := time.Date(2024, 5, 2, 12, 0, 0, 0, time.UTC)
+	created := models.User{ID: 1, FirstName: "John", LastName: "Doe", Role: "Customer", UserID: 1001, Version: 1}
+	deleted := models.User{ID: 1, FirstName: "John", LastName: "Doe", Role: "Customer", UserID: 1001, Version: 2, DeletedAt: &deletedAt}
+	entries := []models.UserHistoryEntry{
+		{ID: 1, Version: 2, Operation: models.HistoryDelete, Actor: "jdoe", ChangedAt: deletedAt, Before: &created, After: &deleted},
+		{ID: 1, Version: 1, Operation: models.HistoryCreate, Actor: "system", ChangedAt: createdAt, After: &created},
+	}
+	entriesOut := mapMultipleHistoryOutput(entries)
+
+	tests := map[string]struct {
+		mockCalled     bool
+		mockInput      []any
+		mockOutput     []any
+		requestIDParam string
+		requestQuery   string
+		expectedCode   int
+		expectedBody   string
+	}{
+		"history found": {
+			mockCalled:     true,
+			mockInput:      []any{1, models.ListHistoryParams{Limit: 20}},
+			mockOutput:     []any{models.UserHistoryPage{Entries: entries, Total: 2}, nil},
+			requestIDParam: "1",
+			expectedCode:   http.StatusOK,
+			expectedBody:   toJSONString(responseUserHistory{History: entriesOut, Total: 2, Limit: 20}),
+		},
+		"page of history with next page": {
+			mockCalled:     true,
+			mockInput:      []any{1, models.ListHistoryParams{Limit: 1}},
+			mockOutput:     []any{models.UserHistoryPage{Entries: entries[:1], Total: 2}, nil},
+			requestIDParam: "1",
+			requestQuery:   "?limit=1",
+			expectedCode:   http.StatusOK,
+			expectedBody: toJSONString(responseUserHistory{
+				History: entriesOut[:1],
+				Total:   2,
+				Limit:   1,
+				Next:    "/api/user/1/history?limit=1&offset=1",
+			}),
+		},
+		"invalid query": {
+			mockCalled:     false,
+			requestIDParam: "1",
+			requestQuery:   "?limit=0&offset=-1",
+			expectedCode:   http.StatusBadRequest,
+			expectedBody: toJSONString(problem.
+				New(http.StatusBadRequest, "One or more query parameters are invalid").
+				WithInstance("/api/user/1/history").
+				WithFieldErrors(map[string]string{
+					"limit":  "must be a number between 1 and 100",
+					"offset": "must be a number of 0 or more",
+				}),
+			),
+		},
+		"invalid ID": {
+			mockCalled:     false,
+			requestIDParam: "abc",
+			expectedCode:   http.StatusBadRequest,
+			expectedBody:   toJSONString(problem.New(http.StatusBadRequest, "Not a valid ID").WithInstance("/api/user/abc/history")),
+		},
+		"user not found": {
+			mockCalled:     true,
+			mockInput:      []any{3, models.ListHistoryParams{Limit: 20}},
+			mockOutput:     []any{models.UserHistoryPage{}, fmt.Errorf("[in ListUserHistory]: %w", service.ErrUserNotFound)},
+			requestIDParam: "3",
+			expectedCode:   http.StatusNotFound,
+			expectedBody:   toJSONString(problem.New(http.StatusNotFound, "Object does not exist").WithInstance("/api/user/3/history")),
+		},
+		"internal server error": {
+			mockCalled:     true,
+			mockInput:      []any{3, models.ListHistoryParams{Limit: 20}},
+			mockOutput:     []any{models.UserHistoryPage{}, errors.New("")},
+			requestIDParam: "3",
+			expectedCode:   http.StatusInternalServerError,
+			expectedBody:   toJSONString(problem.New(http.StatusInternalServerError, "Error retrieving data").WithInstance("/api/user/3/history")),
+		},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			req, err := http.NewRequest(http.MethodGet, "/api/user/"+tc.requestIDParam+"/history"+tc.requestQuery, nil)
+			assert.NoError(t, err)
+
+			// Add chi URLParam
+			rctx := chi.NewRouteContext()
+			rctx.URLParams.Add("ID", tc.requestIDParam)
+			ctx := context.WithValue(req.Context(), chi.RouteCtxKey, rctx)
+			req = req.WithContext(ctx)
+
+			if tc.mockCalled {
+				mockService.
+					On("ListUserHistory", append([]any{ctx}, tc.mockInput...)...).
+					Return(tc.mockOutput...).
+					Once()
+			}
+
+			rr := httptest.NewRecorder()
+			handler.ServeHTTP(rr, req)
+
+			assert.Equal(t, tc.expectedCode, rr.Code, "Wrong code received")
+			assert.JSONEq(t, tc.expectedBody, rr.Body.String(), "Wrong response body")
+
+			if tc.mockCalled {
+				mockService.AssertExpectations(t)
+			} else {
+				mockService.AssertNotCalled(t, "ListUserHistory")
+			}
+		})
+	}
+}
@@ -2,14 +2,19 @@ package handlers
 
 import (
 	"errors"
+	"fmt"
 	"log/slog"
 	"net/http"
 	"net/http/httptest"
 	"testing"
+	"time"
 
 	"github.com/go-chi/chi/v5"
 	"github.com/jha-captech/user-microservice/internal/models"
+	"github.com/jha-captech/user-microservice/internal/problem"
+	"github.com/jha-captech/user-microservice/internal/service"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
 	"golang.org/x/net/context"
 
 	serviceMock "github.com/jha-captech/user-microservice/internal/handlers/mock"
@@ -27,35 +32,149 @@ func TestHandleListUsers(t *testing.T) {
 
 	usersOut := mapMultipleOutput(users)
 
+	defaultParams := models.ListUsersParams{Limit: defaultListLimit, SortBy: models.UserSortID}
+
 	tests := map[string]struct {
+		requestQuery string
 		mockCalled   bool
+		mockInput    models.ListUsersParams
 		mockOutput   []any
 		expectedCode int
 		expectedBody string
 	}{
 		"users returned": {
 			mockCalled:   true,
-			mockOutput:   []any{users, nil},
+			mockInput:    defaultParams,
+			mockOutput:   []any{models.UserPage{Users: users, Total: 2}, nil},
 			expectedCode: http.StatusOK,
-			expectedBody: toJSONString(responseUsers{Users: usersOut}),
+			expectedBody: toJSONString(responseUsers{
+				Users: usersOut,
+				Total: 2,
+				Limit: defaultListLimit,
+			}),
+		},
+		"users returned with next page": {
+			requestQuery: "?limit=2&offset=2&role=Employee&last_name=Sm&user_id_min=1000&user_id_max=2000&sort=-last_name",
+			mockCalled:   true,
+			mockInput: models.ListUsersParams{
+				Limit:          2,
+				Offset:         2,
+				Role:           "Employee",
+				LastNamePrefix: "Sm",
+				UserIDMin:      1000,
+				UserIDMax:      2000,
+				SortBy:         models.UserSortLastName,
+				SortDesc:       true,
+			},
+			mockOutput: []any{
+				models.UserPage{Users: users, Total: 5, NextCursor: "next", PrevCursor: "prev"},
+				nil,
+			},
+			expectedCode: http.StatusOK,
+			expectedBody: toJSONString(responseUsers{
+				Users:      usersOut,
+				Total:      5,
+				Limit:      2,
+				Offset:     2,
+				Next:       "/api/user?last_name=Sm&limit=2&offset=4&role=Employee&sort=-last_name&user_id_max=2000&user_id_min=1000",
+				NextCursor: "next",
+				PrevCursor: "prev",
+			}),
+		},
+		"users returned by cursor": {
+			requestQuery: "?limit=2&cursor=abc",
+			mockCalled:   true,
+			mockInput:    models.ListUsersParams{Limit: 2, Cursor: "abc", SortBy: models.UserSortID},
+			mockOutput: []any{
+				models.UserPage{Users: users, Total: 5, NextCursor: "next", PrevCursor: "prev"},
+				nil,
+			},
+			expectedCode: http.StatusOK,
+			expectedBody: toJSONString(responseUsers{
+				Users:      usersOut,
+				Total:      5,
+				Limit:      2,
+				NextCursor: "next",
+				PrevCursor: "prev",
+			}),
+		},
+		"deleted users included": {
+			requestQuery: "?include_deleted=true",
+			mockCalled:   true,
+			mockInput:    models.ListUsersParams{Limit: defaultListLimit, SortBy: models.UserSortID, IncludeDeleted: true},
+			mockOutput:   []any{models.UserPage{Users: users, Total: 2}, nil},
+			expectedCode: http.StatusOK,
+			expectedBody: toJSONString(responseUsers{
+				Users: usersOut,
+				Total: 2,
+				Limit: defaultListLimit,
+			}),
+		},
+		"users updated since": {
+			requestQuery: "?updated_since=2024-05-01T12:00:00%2B02:00",
+			mockCalled:   true,
+			mockInput: models.ListUsersParams{
+				Limit:        defaultListLimit,
+				SortBy:       models.UserSortID,
+				UpdatedSince: time.Date(2024, 5, 1, 10, 0, 0, 0, time.UTC),
+			},
+			mockOutput:   []any{models.UserPage{Users: users, Total: 2}, nil},
+			expectedCode: http.StatusOK,
+			expectedBody: toJSONString(responseUsers{
+				Users: usersOut,
+				Total: 2,
+				Limit: defaultListLimit,
+			}),
+		},
+		"invalid cursor": {
+			requestQuery: "?cursor=abc",
+			mockCalled:   true,
+			mockInput:    models.ListUsersParams{Limit: defaultListLimit, Cursor: "abc", SortBy: models.UserSortID},
+			mockOutput:   []any{models.UserPage{}, fmt.Errorf("[in ListUsers]: %w", service.ErrInvalidCursor)},
+			expectedCode: http.StatusBadRequest,
+			expectedBody: toJSONString(problem.New(http.StatusBadRequest, "Invalid or expired cursor").WithInstance("/api/user")),
 		},
 		"no users found": {
 			mockCalled:   true,
-			mockOutput:   []any{[]models.User{}, nil},
+			mockInput:    defaultParams,
+			mockOutput:   []any{models.UserPage{Users: []models.User{}}, nil},
 			expectedCode: http.StatusOK,
-			expectedBody: toJSONString(responseUsers{Users: []outputUser{}}),
+			expectedBody: toJSONString(responseUsers{
+				Users: []outputUser{},
+				Limit: defaultListLimit,
+			}),
+		},
+		"invalid query params": {
+			requestQuery: "?limit=500&offset=-1&cursor=abc&role=Admin&user_id_min=10&user_id_max=5&sort=password&include_deleted=maybe&updated_since=yesterday",
+			mockCalled:   false,
+			expectedCode: http.StatusBadRequest,
+			expectedBody: toJSONString(problem.
+				New(http.StatusBadRequest, "One or more query parameters are invalid").
+				WithInstance("/api/user").
+				WithFieldErrors(map[string]string{
+					"limit":           "must be a number between 1 and 100",
+					"offset":          "must be a number of 0 or more",
+					"cursor":          "can not be combined with offset",
+					"role":            "must be 'Customer' or 'Employee'",
+					"user_id_max":     "must not be less than user_id_min",
+					"sort":            "must be one of [id first_name last_name role user_id], optionally prefixed with '-'",
+					"include_deleted": "must be true or false",
+					"updated_since":   "must be an RFC 3339 timestamp, such as 2024-05-01T12:00:00Z",
+				}),
+			),
 		},
 		"internal server error": {
 			mockCalled:   true,
-			mockOutput:   []any{[]models.User{}, errors.New("teat error")},
+			mockInput:    defaultParams,
+			mockOutput:   []any{models.UserPage{}, errors.New("teat error")},
 			expectedCode: http.StatusInternalServerError,
-			expectedBody: toJSONString(responseErr{Error: "Error retrieving data"}),
+			expectedBody: toJSONString(problem.New(http.StatusInternalServerError, "Error retrieving data").WithInstance("/api/user")),
 		},
 	}
 
 	for name, tc := range tests {
 		t.Run(name, func(t *testing.T) {
-			req, err := http.NewRequest(http.MethodGet, "/api/user", nil)
+			req, err := http.NewRequest(http.MethodGet, "/api/user"+tc.requestQuery, nil)
 			assert.NoError(t, err)
 
 			// Add chi URLParam
@@ -65,7 +184,7 @@ func TestHandleListUsers(t *testing.T) {
 
 			if tc.mockCalled {
 				mockService.
-					On("ListUsers", ctx).
+					On("ListUsers", ctx, tc.mockInput).
 					Return(tc.mockOutput...).
 					Once()
 			}
@@ -84,3 +203,151 @@ func TestHandleListUsers(t *testing.T) {
 		})
 	}
 }
+
+func TestHandleListUsersExport(t *testing.T) {
+	mockService := new(serviceMock.MockUserLister)
+	logger := slog.Default()
+	handler := HandleListUsers(logger, mockService)
+
+	createdAt := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	updatedAt := time.Date(2024, 5, 2, 8, 30, 0, 0, time.UTC)
+	users := []models.User{
+		{
+			ID: 1, FirstName: "John", LastName: "Doe, Jr.", Role: "Customer", UserID: 1001, Version: 2,
+			DeletedAt: &updatedAt, CreatedAt: createdAt, UpdatedAt: updatedAt, CreatedBy: "system", UpdatedBy: "jdoe",
+		},
+		{
+			ID: 2, FirstName: "Jane", LastName: "Smith", Role: "Employee", UserID: 1002, Version: 1,
+			CreatedAt: createdAt, UpdatedAt: createdAt, CreatedBy: "jdoe", UpdatedBy: "jdoe",
+		},
+	}
+	usersOut := mapMultipleOutput(users)
+
+	const csvHeader = "id,first_name,last_name,role,user_id,version,deleted_at,created_at,updated_at,created_by,updated_by\n"
+
+	defaultParams := models.ListUsersParams{Limit: defaultListLimit, SortBy: models.UserSortID}
+
+	tests := map[string]struct {
+		requestQuery        string
+		accept              string
+		mockInput           models.ListUsersParams
+		mockUsers           []models.User
+		mockErr             error
+		expectedCode        int
+		expectedContentType string
+		expectedDisposition string
+		expectedBody        string
+	}{
+		"users exported as CSV": {
+			accept:              "text/csv",
+			mockInput:           defaultParams,
+			mockUsers:           users,
+			expectedCode:        http.StatusOK,
+			expectedContentType: contentTypeCSV,
+			expectedDisposition: `attachment; filename="users.csv"`,
+			expectedBody: csvHeader +
+				"1,John,\"Doe, Jr.\",Customer,1001,2,2024-05-02T08:30:00Z,2024-05-01T12:00:00Z,2024-05-02T08:30:00Z,system,jdoe\n" +
+				"2,Jane,Smith,Employee,1002,1,,2024-05-01T12:00:00Z,2024-05-01T12:00:00Z,jdoe,jdoe\n",
+		},
+		"filtered users exported as NDJSON": {
+			requestQuery: "?role=Employee&sort=-last_name",
+			accept:       "application/json;q=0.5, application/x-ndjson",
+			mockInput: models.ListUsersParams{
+				Limit:    defaultListLimit,
+				Role:     "Employee",
+				SortBy:   models.UserSortLastName,
+				SortDesc: true,
+			},
+			mockUsers:           users,
+			expectedCode:        http.StatusOK,
+			expectedContentType: contentTypeNDJSON,
+			expectedDisposition: `attachment; filename="users.ndjson"`,
+			expectedBody:        toJSONString(usersOut[0]) + "\n" + toJSONString(usersOut[1]) + "\n",
+		},
+		"no users exported as CSV": {
+			accept:              "text/*",
+			mockInput:           defaultParams,
+			expectedCode:        http.StatusOK,
+			expectedContentType: contentTypeCSV,
+			expectedDisposition: `attachment; filename="users.csv"`,
+			expectedBody:        csvHeader,
+		},
+		"error before export started": {
+			accept:              "text/csv",
+			mockInput:           defaultParams,
+			mockErr:             errors.New("test error"),
+			expectedCode:        http.StatusInternalServerError,
+			expectedContentType: problem.ContentType,
+			expectedBody: toJSONString(problem.New(http.StatusInternalServerError, "Error retrieving data").WithInstance("/api/user")) +
+				"\n",
+		},
+		"error after export started": {
+			accept:              "text/csv",
+			mockInput:           defaultParams,
+			mockUsers:           users[1:],
+			mockErr:             errors.New("test error"),
+			expectedCode:        http.StatusOK,
+			expectedContentType: contentTypeCSV,
+			expectedDisposition: `attachment; filename="users.csv"`,
+			expectedBody:        csvHeader + "2,Jane,Smith,Employee,1002,1,,2024-05-01T12:00:00Z,2024-05-01T12:00:00Z,jdoe,jdoe\n",
+		},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			req, err := http.NewRequest(http.MethodGet, "/api/user"+tc.requestQuery, nil)
+			assert.NoError(t, err)
+			req.Header.Set("Accept", tc.accept)
+
+			mockService.
+				On("ExportUsers", req.Context(), tc.mockInput, mock.Anything).
+				Run(func(args mock.Arguments) {
+					fn := args.Get(2).(func(models.User) error)
+					for _, user := range tc.mockUsers {
+						assert.NoError(t, fn(user))
+					}
+				}).
+				Return(tc.mockErr).
+				Once()
+
+			rr := httptest.NewRecorder()
+			handler.ServeHTTP(rr, req)
+
+			assert.Equal(t, tc.expectedCode, rr.Code, "Wrong code received")
+			assert.Equal(t, tc.expectedContentType, rr.Header().Get("Content-Type"), "Wrong content type")
+			assert.Equal(t, tc.expectedDisposition, rr.Header().Get("Content-Disposition"), "Wrong content disposition")
+			assert.Equal(t, tc.expectedBody, rr.Body.String(), "Wrong response body")
+
+			mockService.AssertExpectations(t)
+			mockService.AssertNotCalled(t, "ListUsers")
+		})
+	}
+}
+
+func TestNegotiateContentType(t *testing.T) {
+	offers := []string{contentTypeJSON, contentTypeCSV, contentTypeNDJSON}
+
+	tests := map[string]struct {
+		accept   string
+		expected string
+	}{
+		"no Accept header":         {accept: "", expected: contentTypeJSON},
+		"any type":                 {accept: "*/*", expected: contentTypeJSON},
+		"exact match":              {accept: "text/csv", expected: contentTypeCSV},
+		"type wildcard":            {accept: "text/*", expected: contentTypeCSV},
+		"higher quality wins":      {accept: "text/csv;q=0.4, application/x-ndjson;q=0.9", expected: contentTypeNDJSON},
+		"more specific range wins": {accept: "*/*, application/x-ndjson", expected: contentTypeNDJSON},
+		"rejected types skipped":   {accept: "application/json;q=0, text/csv;q=0.1", expected: contentTypeCSV},
+		"no offer matches":         {accept: "text/html", expected: contentTypeJSON},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			req, err := http.NewRequest(http.MethodGet, "/api/user", nil)
+			assert.NoError(t, err)
+			req.Header.Set("Accept", tc.accept)
+
+			assert.Equal(t, tc.expected, negotiateContentType(req, offers...), "Wrong content type")
+		})
+	}
+}
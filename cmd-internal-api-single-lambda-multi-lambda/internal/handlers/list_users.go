@@ -2,44 +2,190 @@ package handlers
 
 import (
 	"context"
+	"errors"
+	"fmt"
 	"net/http"
+	"net/url"
+	"strconv"
+	"time"
 
 	"github.com/jha-captech/user-microservice/internal/models"
+	"github.com/jha-captech/user-microservice/internal/problem"
+	svc "github.com/jha-captech/user-microservice/internal/service"
 )
 
 type userLister interface {
-	ListUsers(ctx context.Context) ([]models.User, error)
+	ListUsers(ctx context.Context, params models.ListUsersParams) (models.UserPage, error)
+	ExportUsers(ctx context.Context, params models.ListUsersParams, fn func(models.User) error) error
 }
 
-// HandleListUsers is a Handler that returns a list of all users.
+// HandleListUsers is a Handler that returns a page of users matching the given filters. Pages can be
+// requested by offset or by the opaque cursors returned in `next_cursor` and `prev_cursor`.
 //
-// @Summary		List all users
-// @Description	List all users
+// When the Accept header asks for text/csv or application/x-ndjson, every user matching the filters
+// is streamed as a download instead, and the paging parameters are ignored.
+//
+// @Summary		List users
+// @Description	List users with paging, filtering and sorting
 // @Tags		users
 // @Accept		json
-// @Produce		json
-// @Success		200		{object}	handlers.responseUsers
-// @Failure		500		{object}	handlers.responseErr
-// @Router		/user	[GET]
+// @Produce		json,text/csv,application/x-ndjson
+// @Param		limit		query		int		false	"Maximum number of users to return (1-100)"	default(20)
+// @Param		offset		query		int		false	"Number of users to skip"					default(0)
+// @Param		cursor		query		string	false	"Opaque cursor from a previous next_cursor or prev_cursor, can not be combined with offset"
+// @Param		role		query		string	false	"Filter by role"							Enums(Customer, Employee)
+// @Param		last_name	query		string	false	"Filter by last name prefix"
+// @Param		user_id_min	query		int		false	"Filter by minimum user_id (inclusive)"
+// @Param		user_id_max	query		int		false	"Filter by maximum user_id (inclusive)"
+// @Param		sort		query		string	false	"Sort field, prefix with '-' for descending"	Enums(id, -id, first_name, -first_name, last_name, -last_name, role, -role, user_id, -user_id)
+// @Param		include_deleted	query	bool	false	"Include soft deleted users"	default(false)
+// @Param		updated_since	query	string	false	"Only users updated at or after this RFC 3339 timestamp"	format(date-time)
+// @Success		200			{object}	handlers.responseUsers
+// @Header		200			{string}	Content-Disposition	"Download file name of a CSV or NDJSON export"
+// @Failure		400			{object}	problem.Problem
+// @Failure		500			{object}	problem.Problem
+// @Router		/user		[GET]
 func HandleListUsers(logger sLogger, service userLister) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		// setup
+		logger := requestLogger(r, logger)
 		ctx := r.Context()
 
+		// get and validate query params
+		params, problems, err := validateInput[inputListUsers, models.ListUsersParams](
+			newInputListUsers(r.URL.Query()),
+		)
+		if err != nil {
+			switch {
+			case len(problems) > 0:
+				logger.Error("Problems validating query", "error", err, "problems", problems)
+				encodeProblem(w, r, logger, problem.
+					New(http.StatusBadRequest, "One or more query parameters are invalid").
+					WithFieldErrors(problems),
+				)
+			default:
+				logger.Error("Query parse error", "error", err)
+				encodeProblem(w, r, logger, problem.New(http.StatusBadRequest, "malformed query parameters"))
+			}
+			return
+		}
+
+		// stream all matching users if an export format was asked for
+		if contentType := negotiateContentType(
+			r, contentTypeJSON, contentTypeCSV, contentTypeNDJSON,
+		); contentType != contentTypeJSON {
+			exportUsers(w, r, logger, service, params, contentType)
+			return
+		}
+
 		// get values from database
-		users, err := service.ListUsers(ctx)
+		page, err := service.ListUsers(ctx, params)
 		if err != nil {
-			logger.Error("error getting all locations", "error", err)
-			encodeResponse(w, logger, http.StatusInternalServerError, responseErr{
-				Error: "Error retrieving data",
-			})
+			switch {
+			case errors.Is(err, svc.ErrInvalidCursor):
+				logger.Error("invalid cursor", "error", err)
+				encodeProblem(w, r, logger, problem.New(http.StatusBadRequest, "Invalid or expired cursor"))
+			default:
+				logger.Error("error getting all locations", "error", err)
+				encodeProblem(w, r, logger, problem.New(http.StatusInternalServerError, "Error retrieving data"))
+			}
 			return
 		}
 
 		// return response
-		usersOut := mapMultipleOutput(users)
+		usersOut := mapMultipleOutput(page.Users)
 		encodeResponse(w, logger, http.StatusOK, responseUsers{
-			Users: usersOut,
+			Users:      usersOut,
+			Total:      page.Total,
+			Limit:      params.Limit,
+			Offset:     params.Offset,
+			Next:       nextPageURL(r.URL, params, page.Total),
+			NextCursor: page.NextCursor,
+			PrevCursor: page.PrevCursor,
 		})
 	}
 }
+
+// exportUsers streams the users matching params to w as contentType. The response is only started
+// once the first user has been read, so that an error querying the database can still be returned
+// as a problem. An error after that can only be logged and leaves the download truncated.
+func exportUsers(
+	w http.ResponseWriter,
+	r *http.Request,
+	logger sLogger,
+	service userLister,
+	params models.ListUsersParams,
+	contentType string,
+) {
+	extendDeadlines(w, logger, time.Time{})
+
+	var exporter userExporter
+	start := func() error {
+		// the exporter is built before the header is written, so that an error building it can
+		// still be returned as a problem
+		started, err := newUserExporter(w, contentType)
+		if err != nil {
+			return err
+		}
+
+		w.Header().Set("Content-Type", contentType)
+		w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", exportFilename(contentType)))
+		w.WriteHeader(http.StatusOK)
+
+		exporter = started
+		return nil
+	}
+
+	err := service.ExportUsers(r.Context(), params, func(user models.User) error {
+		if exporter == nil {
+			if err := start(); err != nil {
+				return err
+			}
+		}
+		return exporter.Write(user)
+	})
+	if err == nil && exporter == nil {
+		err = start()
+	}
+	if err != nil {
+		if exporter == nil {
+			logger.Error("error exporting users", "error", err)
+			encodeProblem(w, r, logger, problem.New(http.StatusInternalServerError, "Error retrieving data"))
+			return
+		}
+		logger.Error("error streaming users, export is truncated", "error", err)
+		if err = exporter.Close(); err != nil {
+			logger.Error("error streaming users", "error", err)
+		}
+		return
+	}
+
+	if err = exporter.Close(); err != nil {
+		logger.Error("error streaming users, export is truncated", "error", err)
+	}
+}
+
+// nextPageURL returns the URL of the page following the one described by params, or an empty string
+// if there are no more users or the page was requested by cursor.
+func nextPageURL(current *url.URL, params models.ListUsersParams, total int) string {
+	if params.Cursor != "" {
+		return ""
+	}
+	return nextOffsetURL(current, params.Limit, params.Offset, total)
+}
+
+// nextOffsetURL returns the URL of the page following the page of limit items at offset, or an empty
+// string if there are no more than total items.
+func nextOffsetURL(current *url.URL, limit, offset, total int) string {
+	nextOffset := offset + limit
+	if nextOffset >= total {
+		return ""
+	}
+
+	query := current.Query()
+	query.Set("limit", strconv.Itoa(limit))
+	query.Set("offset", strconv.Itoa(nextOffset))
+
+	next := url.URL{Path: current.Path, RawQuery: query.Encode()}
+	return next.String()
+}
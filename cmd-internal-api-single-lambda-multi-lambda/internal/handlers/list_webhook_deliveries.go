@@ -0,0 +1,95 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/jha-captech/user-microservice/internal/models"
+	"github.com/jha-captech/user-microservice/internal/problem"
+	svc "github.com/jha-captech/user-microservice/internal/service"
+)
+
+type webhookDeliveryLister interface {
+	ListDeliveries(ctx context.Context, ID int, params models.ListDeliveriesParams) (models.WebhookDeliveryPage, error)
+}
+
+// HandleListWebhookDeliveries is a Handler that returns a page of the delivery log of a webhook
+// subscription, newest delivery first. Each delivery holds its status, the number of attempts made
+// and the last response of the subscriber.
+//
+// @Summary		List the deliveries of a webhook subscription
+// @Description	List the deliveries made to a webhook subscription, newest first
+// @Tags		webhook
+// @Accept		json
+// @Produce		json
+// @Param		id				path		int		true	"Subscription ID"
+// @Param		limit			query		int		false	"Maximum number of deliveries to return (1-100)"	default(20)
+// @Param		offset			query		int		false	"Number of deliveries to skip"						default(0)
+// @Param		X-Admin-Token	header		string	true	"Admin token"
+// @Success		200				{object}	handlers.responseWebhookDeliveries
+// @Failure		400				{object}	problem.Problem
+// @Failure		403				{object}	problem.Problem
+// @Failure		404				{object}	problem.Problem
+// @Failure		500				{object}	problem.Problem
+// @Router		/webhook/{ID}/deliveries	[GET]
+func HandleListWebhookDeliveries(logger sLogger, service webhookDeliveryLister) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		// setup
+		logger := requestLogger(r, logger)
+		ctx := r.Context()
+
+		// get and validate ID
+		idString := chi.URLParam(r, "ID")
+		ID, err := strconv.Atoi(idString)
+		if err != nil {
+			logger.Error("error getting ID", "error", err)
+			encodeProblem(w, r, logger, problem.New(http.StatusBadRequest, "Not a valid ID"))
+			return
+		}
+
+		// get and validate query params
+		params, problems, err := validateInput[inputListHistory, models.ListHistoryParams](
+			newInputListHistory(r.URL.Query()),
+		)
+		if err != nil {
+			switch {
+			case len(problems) > 0:
+				logger.Error("Problems validating query", "error", err, "problems", problems)
+				encodeProblem(w, r, logger, problem.
+					New(http.StatusBadRequest, "One or more query parameters are invalid").
+					WithFieldErrors(problems),
+				)
+			default:
+				logger.Error("Query parse error", "error", err)
+				encodeProblem(w, r, logger, problem.New(http.StatusBadRequest, "malformed query parameters"))
+			}
+			return
+		}
+
+		// get values from database
+		page, err := service.ListDeliveries(ctx, ID, models.ListDeliveriesParams(params))
+		if err != nil {
+			switch {
+			case errors.Is(err, svc.ErrSubscriptionNotFound):
+				logger.Error("Object does not exist", "ID", ID, "error", err)
+				encodeProblem(w, r, logger, problem.New(http.StatusNotFound, "Object does not exist"))
+			default:
+				logger.Error("error getting webhook deliveries", "ID", ID, "error", err)
+				encodeProblem(w, r, logger, problem.New(http.StatusInternalServerError, "Error retrieving data"))
+			}
+			return
+		}
+
+		// return response
+		encodeResponse(w, logger, http.StatusOK, responseWebhookDeliveries{
+			Deliveries: mapMultipleDeliveryOutput(page.Deliveries),
+			Total:      page.Total,
+			Limit:      params.Limit,
+			Offset:     params.Offset,
+			Next:       nextOffsetURL(r.URL, params.Limit, params.Offset, page.Total),
+		})
+	}
+}
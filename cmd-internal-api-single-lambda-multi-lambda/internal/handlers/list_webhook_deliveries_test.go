@@ -0,0 +1,146 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/jha-captech/user-microservice/internal/models"
+	"github.com/jha-captech/user-microservice/internal/problem"
+	"github.com/jha-captech/user-microservice/internal/service"
+	"github.com/stretchr/testify/assert"
+
+	serviceMock "github.com/jha-captech/user-microservice/internal/handlers/mock"
+)
+
+func TestHandleListWebhookDeliveries(t *testing.T) {
+	mockService := new(serviceMock.MockWebhookDeliveryLister)
+	logger := slog.Default()
+	handler := HandleListWebhookDeliveries(logger, mockService)
+
+	createdAt := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	deliveredAt := time.Date(2024, 5, 1, 12, 0, 1, 0, time.UTC)
+	ok := http.StatusOK
+	unavailable := http.StatusServiceUnavailable
+	deliveries := []models.WebhookDelivery{
+		{
+			ID: 2, SubscriptionID: 1, EventID: "8", EventType: models.EventUserUpdated, Status: "pending", Attempts: 1,
+			ResponseStatus: &unavailable, LastError: "subscriber responded with 503 Service Unavailable",
+			CreatedAt: createdAt, NextAttemptAt: deliveredAt,
+		},
+		{
+			ID: 1, SubscriptionID: 1, EventID: "7", EventType: models.EventUserCreated, Status: "delivered", Attempts: 1,
+			ResponseStatus: &ok, CreatedAt: createdAt, NextAttemptAt: createdAt, DeliveredAt: &deliveredAt,
+		},
+	}
+
+	tests := map[string]struct {
+		mockCalled     bool
+		mockInput      []any
+		mockOutput     []any
+		requestIDParam string
+		requestQuery   string
+		expectedCode   int
+		expectedBody   string
+	}{
+		"deliveries found": {
+			mockCalled:     true,
+			mockInput:      []any{1, models.ListDeliveriesParams{Limit: 2, Offset: 0}},
+			mockOutput:     []any{models.WebhookDeliveryPage{Deliveries: deliveries, Total: 3}, nil},
+			requestIDParam: "1",
+			requestQuery:   "?limit=2",
+			expectedCode:   http.StatusOK,
+			expectedBody: toJSONString(responseWebhookDeliveries{
+				Deliveries: []outputWebhookDelivery{
+					{
+						ID: 2, SubscriptionID: 1, EventID: "8", EventType: "user.updated", Status: "pending", Attempts: 1,
+						ResponseStatus: &unavailable, LastError: "subscriber responded with 503 Service Unavailable",
+						CreatedAt: createdAt, NextAttemptAt: deliveredAt,
+					},
+					{
+						ID: 1, SubscriptionID: 1, EventID: "7", EventType: "user.created", Status: "delivered", Attempts: 1,
+						ResponseStatus: &ok, CreatedAt: createdAt, NextAttemptAt: createdAt, DeliveredAt: &deliveredAt,
+					},
+				},
+				Total:  3,
+				Limit:  2,
+				Offset: 0,
+				Next:   "/api/webhook/1/deliveries?limit=2&offset=2",
+			}),
+		},
+		"invalid ID": {
+			mockCalled:     false,
+			requestIDParam: "abc",
+			expectedCode:   http.StatusBadRequest,
+			expectedBody:   toJSONString(problem.New(http.StatusBadRequest, "Not a valid ID").WithInstance("/api/webhook/abc/deliveries")),
+		},
+		"invalid query parameters": {
+			mockCalled:     false,
+			requestIDParam: "1",
+			requestQuery:   "?limit=0&offset=-1",
+			expectedCode:   http.StatusBadRequest,
+			expectedBody: toJSONString(problem.
+				New(http.StatusBadRequest, "One or more query parameters are invalid").
+				WithInstance("/api/webhook/1/deliveries").
+				WithFieldErrors(map[string]string{
+					"limit":  "must be a number between 1 and 100",
+					"offset": "must be a number of 0 or more",
+				}),
+			),
+		},
+		"subscription not found": {
+			mockCalled:     true,
+			mockInput:      []any{2, models.ListDeliveriesParams{Limit: 20}},
+			mockOutput:     []any{models.WebhookDeliveryPage{}, fmt.Errorf("[in ListDeliveries]: %w", service.ErrSubscriptionNotFound)},
+			requestIDParam: "2",
+			expectedCode:   http.StatusNotFound,
+			expectedBody:   toJSONString(problem.New(http.StatusNotFound, "Object does not exist").WithInstance("/api/webhook/2/deliveries")),
+		},
+		"internal server error": {
+			mockCalled:     true,
+			mockInput:      []any{1, models.ListDeliveriesParams{Limit: 20}},
+			mockOutput:     []any{models.WebhookDeliveryPage{}, errors.New("")},
+			requestIDParam: "1",
+			expectedCode:   http.StatusInternalServerError,
+			expectedBody:   toJSONString(problem.New(http.StatusInternalServerError, "Error retrieving data").WithInstance("/api/webhook/1/deliveries")),
+		},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			req, err := http.NewRequest(http.MethodGet, "/api/webhook/"+tc.requestIDParam+"/deliveries"+tc.requestQuery, nil)
+			assert.NoError(t, err)
+
+			// Add chi URLParam
+			rctx := chi.NewRouteContext()
+			rctx.URLParams.Add("ID", tc.requestIDParam)
+			ctx := context.WithValue(req.Context(), chi.RouteCtxKey, rctx)
+			req = req.WithContext(ctx)
+
+			if tc.mockCalled {
+				mockService.
+					On("ListDeliveries", append([]any{ctx}, tc.mockInput...)...).
+					Return(tc.mockOutput...).
+					Once()
+			}
+
+			rr := httptest.NewRecorder()
+			handler.ServeHTTP(rr, req)
+
+			assert.Equal(t, tc.expectedCode, rr.Code, "Wrong code received")
+			assert.JSONEq(t, tc.expectedBody, rr.Body.String(), "Wrong response body")
+
+			if tc.mockCalled {
+				mockService.AssertExpectations(t)
+			} else {
+				mockService.AssertNotCalled(t, "ListDeliveries")
+			}
+		})
+	}
+}
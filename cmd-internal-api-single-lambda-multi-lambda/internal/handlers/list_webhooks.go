@@ -0,0 +1,47 @@
+package handlers
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/jha-captech/user-microservice/internal/models"
+	"github.com/jha-captech/user-microservice/internal/problem"
+)
+
+type webhookLister interface {
+	ListSubscriptions(ctx context.Context) ([]models.WebhookSubscription, error)
+}
+
+// HandleListWebhooks is a Handler that returns all webhook subscriptions. The secrets of the
+// subscriptions are never returned.
+//
+// @Summary		List webhook subscriptions
+// @Description	List all webhook subscriptions
+// @Tags		webhook
+// @Accept		json
+// @Produce		json
+// @Param		X-Admin-Token	header		string	true	"Admin token"
+// @Success		200				{object}	handlers.responseWebhooks
+// @Failure		403				{object}	problem.Problem
+// @Failure		500				{object}	problem.Problem
+// @Router		/webhook		[GET]
+func HandleListWebhooks(logger sLogger, service webhookLister) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		// setup
+		logger := requestLogger(r, logger)
+		ctx := r.Context()
+
+		// get values from database
+		subscriptions, err := service.ListSubscriptions(ctx)
+		if err != nil {
+			logger.Error("error getting webhook subscriptions", "error", err)
+			encodeProblem(w, r, logger, problem.New(http.StatusInternalServerError, "Error retrieving data"))
+			return
+		}
+
+		// return response
+		encodeResponse(w, logger, http.StatusOK, responseWebhooks{
+			Webhooks: mapMultipleWebhookOutput(subscriptions),
+		})
+	}
+}
@@ -0,0 +1,84 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/jha-captech/user-microservice/internal/models"
+	"github.com/jha-captech/user-microservice/internal/problem"
+	"github.com/stretchr/testify/assert"
+
+	serviceMock "github.com/jha-captech/user-microservice/internal/handlers/mock"
+)
+
+func TestHandleListWebhooks(t *testing.T) {
+	mockService := new(serviceMock.MockWebhookLister)
+	logger := slog.Default()
+	handler := HandleListWebhooks(logger, mockService)
+
+	createdAt := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	subscriptions := []models.WebhookSubscription{
+		{ID: 1, URL: "https://example.com/hook", Secret: "0123456789abcdef", Events: []models.EventType{}, CreatedAt: createdAt, UpdatedAt: createdAt},
+		{
+			ID: 2, URL: "https://partner.example.com/users", Secret: "fedcba9876543210",
+			Events: []models.EventType{models.EventUserDeleted}, CreatedAt: createdAt, UpdatedAt: createdAt,
+		},
+	}
+
+	tests := map[string]struct {
+		mockOutput   []any
+		expectedCode int
+		expectedBody string
+	}{
+		"subscriptions found": {
+			mockOutput:   []any{subscriptions, nil},
+			expectedCode: http.StatusOK,
+			expectedBody: toJSONString(responseWebhooks{Webhooks: []outputWebhookSubscription{
+				{ID: 1, URL: "https://example.com/hook", Events: []string{}, CreatedAt: createdAt, UpdatedAt: createdAt},
+				{ID: 2, URL: "https://partner.example.com/users", Events: []string{"user.deleted"}, CreatedAt: createdAt, UpdatedAt: createdAt},
+			}}),
+		},
+		"no subscriptions": {
+			mockOutput:   []any{[]models.WebhookSubscription{}, nil},
+			expectedCode: http.StatusOK,
+			expectedBody: `{"webhooks": []}`,
+		},
+		"internal server error": {
+			mockOutput:   []any{nil, errors.New("")},
+			expectedCode: http.StatusInternalServerError,
+			expectedBody: toJSONString(problem.New(http.StatusInternalServerError, "Error retrieving data").WithInstance("/api/webhook")),
+		},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			req, err := http.NewRequest(http.MethodGet, "/api/webhook", nil)
+			assert.NoError(t, err)
+
+			// Add chi URLParam
+			rctx := chi.NewRouteContext()
+			ctx := context.WithValue(req.Context(), chi.RouteCtxKey, rctx)
+			req = req.WithContext(ctx)
+
+			mockService.
+				On("ListSubscriptions", ctx).
+				Return(tc.mockOutput...).
+				Once()
+
+			rr := httptest.NewRecorder()
+			handler.ServeHTTP(rr, req)
+
+			assert.Equal(t, tc.expectedCode, rr.Code, "Wrong code received")
+			assert.JSONEq(t, tc.expectedBody, rr.Body.String(), "Wrong response body")
+			assert.NotContains(t, rr.Body.String(), "secret", "Secret returned")
+
+			mockService.AssertExpectations(t)
+		})
+	}
+}
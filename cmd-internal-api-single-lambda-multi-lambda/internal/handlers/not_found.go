@@ -0,0 +1,28 @@
+package handlers
+
+import (
+	"net/http"
+
+	"github.com/jha-captech/user-microservice/internal/problem"
+)
+
+// HandleNotFound is a Handler that responds with a not found problem for unknown routes.
+func HandleNotFound(logger sLogger) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		logger := requestLogger(r, logger)
+
+		logger.Info("Route not found called", "path", r.URL.Path)
+		encodeProblem(w, r, logger, problem.New(http.StatusNotFound, "Page not found"))
+	}
+}
+
+// HandleMethodNotAllowed is a Handler that responds with a method not allowed problem for known
+// routes called with an unsupported method.
+func HandleMethodNotAllowed(logger sLogger) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		logger := requestLogger(r, logger)
+
+		logger.Info("Route called with method not allowed", "path", r.URL.Path, "method", r.Method)
+		encodeProblem(w, r, logger, problem.New(http.StatusMethodNotAllowed, "Method not allowed"))
+	}
+}
@@ -0,0 +1,247 @@
+package handlers
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/jha-captech/user-microservice/internal/models"
+	"github.com/jha-captech/user-microservice/internal/patch"
+	"github.com/jha-captech/user-microservice/internal/problem"
+	svc "github.com/jha-captech/user-microservice/internal/service"
+)
+
+type userPatcher interface {
+	FetchUser(ctx context.Context, ID int, includeDeleted bool) (models.User, error)
+	PatchUser(ctx context.Context, ID int, changes models.UserChanges, matchVersions []uint) (models.User, error)
+}
+
+// acceptPatch is the value of the Accept-Patch header, listing the supported patch formats.
+var acceptPatch = strings.Join([]string{patch.MergePatchContentType, patch.JSONPatchContentType}, ", ")
+
+// HandlePatchUser is a Handler that partially updates a user by ID. The request body is either a
+// JSON Merge Patch (RFC 7396) or a JSON Patch (RFC 6902), selected by the Content-Type header. The
+// patch is applied to the stored user, the result is validated as a whole and only the changed
+// fields are saved. If the request has an If-Match header, the user is only patched if its ETag
+// matches. Changes to fields that are read-only for the principal of the request are refused.
+//
+// @Summary		Patch a user by ID
+// @Description	Patch a user by ID with a JSON Merge Patch or a JSON Patch
+// @Tags		user
+// @Accept		application/merge-patch+json,application/json-patch+json
+// @Produce		json
+// @Param		id			path		int		true	"User ID"
+// @Param		patch		body		object	true	"JSON Merge Patch object or JSON Patch array"
+// @Param		If-Match	header		string	false	"ETag the user must currently have"
+// @Success		200			{object}	handlers.responseUser
+// @Header		200			{string}	ETag	"Version of the patched user"
+// @Failure		400			{object}	problem.Problem
+// @Failure		403			{object}	problem.Problem
+// @Failure		404			{object}	problem.Problem
+// @Failure		409			{object}	problem.Problem
+// @Failure		412			{object}	problem.Problem
+// @Failure		415			{object}	problem.Problem
+// @Failure		500			{object}	problem.Problem
+// @Router		/user/{ID}	[PATCH]
+func HandlePatchUser(logger sLogger, service userPatcher) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		// setup
+		logger := requestLogger(r, logger)
+		ctx := r.Context()
+
+		// get and validate ID
+		idString := chi.URLParam(r, "ID")
+		ID, err := strconv.Atoi(idString)
+		if err != nil {
+			logger.Error("error getting ID", "error", err)
+			encodeProblem(w, r, logger, problem.New(http.StatusBadRequest, "Not a valid ID"))
+			return
+		}
+
+		// get patch format
+		applyPatch, ok := patchFunc(r.Header.Get("Content-Type"))
+		if !ok {
+			logger.Error("Unsupported patch format", "Content-Type", r.Header.Get("Content-Type"))
+			w.Header().Set("Accept-Patch", acceptPatch)
+			encodeProblem(w, r, logger, problem.New(
+				http.StatusUnsupportedMediaType,
+				"Content-Type must be one of "+acceptPatch,
+			))
+			return
+		}
+
+		// get patch
+		body, err := io.ReadAll(r.Body)
+		if err != nil {
+			logger.Error("error reading body", "error", err)
+			encodeProblem(w, r, logger, problem.New(http.StatusBadRequest, "missing values or malformed body"))
+			return
+		}
+
+		// get precondition
+		matchVersions, ok := ifMatchVersions(r)
+		if !ok {
+			logger.Error("If-Match can not be met", "ID", ID, "If-Match", r.Header.Get("If-Match"))
+			encodeProblem(w, r, logger, problem.New(http.StatusPreconditionFailed, "User has been modified"))
+			return
+		}
+
+		// get object to patch from database
+		current, err := service.FetchUser(ctx, ID, false)
+		if err != nil {
+			switch {
+			case errors.Is(err, svc.ErrUserNotFound):
+				logger.Error("Object does not exist", "ID", ID, "error", err)
+				encodeProblem(w, r, logger, problem.New(http.StatusNotFound, "Object does not exist"))
+			default:
+				logger.Error("error getting object from database", "error", err)
+				encodeProblem(w, r, logger, problem.New(http.StatusInternalServerError, "Error updating object"))
+			}
+			return
+		}
+		if matchVersions != nil && !slices.Contains(matchVersions, current.Version) {
+			logger.Error("Object has been modified", "ID", ID, "version", current.Version)
+			encodeProblem(w, r, logger, problem.New(http.StatusPreconditionFailed, "User has been modified"))
+			return
+		}
+
+		// apply patch and validate the result
+		userIn, problems, err := patchValidateUser(current, body, applyPatch)
+		if err != nil {
+			switch {
+			case len(problems) > 0:
+				logger.Error("Problems validating patched object", "error", err, "problems", problems)
+				encodeProblem(w, r, logger, problem.
+					New(http.StatusBadRequest, "One or more fields in the patched user are invalid").
+					WithFieldErrors(problems),
+				)
+			case errors.Is(err, patch.ErrConflict):
+				logger.Error("Patch can not be applied", "ID", ID, "error", err)
+				encodeProblem(w, r, logger, problem.New(http.StatusConflict, "Patch can not be applied to the user"))
+			default:
+				logger.Error("Invalid patch", "ID", ID, "error", err)
+				encodeProblem(w, r, logger, problem.New(http.StatusBadRequest, "Malformed patch"))
+			}
+			return
+		}
+
+		// update changed fields in database, unless the user was changed since it was fetched
+		user := current
+		if changes := userChanges(current, userIn); !changes.IsEmpty() {
+			if err := authorizeChanges(ctx, changes); err != nil {
+				logger.Error("Request forbidden by policy", "ID", ID, "error", err)
+				encodeProblem(w, r, logger, forbiddenProblem(err))
+				return
+			}
+
+			user, err = service.PatchUser(ctx, ID, changes, []uint{current.Version})
+			if err != nil {
+				switch {
+				case errors.Is(err, svc.ErrUserNotFound):
+					logger.Error("Object does not exist", "ID", ID, "error", err)
+					encodeProblem(w, r, logger, problem.New(http.StatusNotFound, "Object does not exist"))
+				case errors.Is(err, svc.ErrVersionMismatch):
+					logger.Error("Object has been modified", "ID", ID, "error", err)
+					encodeProblem(w, r, logger, problem.New(http.StatusPreconditionFailed, "User has been modified"))
+				case errors.Is(err, svc.ErrUserIDConflict):
+					logger.Error("user_id already in use", "ID", ID, "error", err)
+					encodeProblem(w, r, logger, problem.New(http.StatusConflict, "user_id already in use"))
+				default:
+					logger.Error("error updating object in database", "error", err)
+					encodeProblem(w, r, logger, problem.New(http.StatusInternalServerError, "Error updating object"))
+				}
+				return
+			}
+		}
+
+		// return response
+		w.Header().Set("ETag", etag(user.Version))
+		userOut := mapOutput(user)
+		encodeResponse(w, logger, http.StatusOK, responseUser{
+			User: userOut,
+		})
+	}
+}
+
+// patchFunc returns the function that applies a patch of the media type contentType.
+func patchFunc(contentType string) (func(doc, patch []byte) ([]byte, error), bool) {
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return nil, false
+	}
+
+	switch mediaType {
+	case patch.MergePatchContentType:
+		return patch.Merge, true
+	case patch.JSONPatchContentType:
+		return patch.Apply, true
+	default:
+		return nil, false
+	}
+}
+
+// patchValidateUser applies body to user with applyPatch, then decodes and validates the result.
+// Members other than the fields of inputUser may not be added by the patch.
+func patchValidateUser(
+	user models.User,
+	body []byte,
+	applyPatch func(doc, patch []byte) ([]byte, error),
+) (models.User, map[string]string, error) {
+	doc, err := json.Marshal(newInputUser(user))
+	if err != nil {
+		return models.User{}, nil, fmt.Errorf("[in patchValidateUser] encode user: %w", err)
+	}
+
+	patched, err := applyPatch(doc, body)
+	if err != nil {
+		return models.User{}, nil, fmt.Errorf("[in patchValidateUser]: %w", err)
+	}
+
+	decoder := json.NewDecoder(bytes.NewReader(patched))
+	decoder.DisallowUnknownFields()
+
+	var userIn inputUser
+	if err = decoder.Decode(&userIn); err != nil {
+		return models.User{}, nil, fmt.Errorf("[in patchValidateUser] decode patched user: %w: %w", patch.ErrInvalid, err)
+	}
+
+	if problems := userIn.Valid(); len(problems) > 0 {
+		return models.User{}, problems, fmt.Errorf(
+			"[in patchValidateUser] invalid %T: %d problems", userIn, len(problems),
+		)
+	}
+
+	data, err := userIn.MapTo()
+	if err != nil {
+		return models.User{}, nil, fmt.Errorf("[in patchValidateUser] error mapping input: %w", err)
+	}
+
+	return data, nil, nil
+}
+
+// userChanges returns the fields of patched that differ from current.
+func userChanges(current, patched models.User) models.UserChanges {
+	var changes models.UserChanges
+	if patched.FirstName != current.FirstName {
+		changes.FirstName = &patched.FirstName
+	}
+	if patched.LastName != current.LastName {
+		changes.LastName = &patched.LastName
+	}
+	if patched.Role != current.Role {
+		changes.Role = &patched.Role
+	}
+	if patched.UserID != current.UserID {
+		changes.UserID = &patched.UserID
+	}
+	return changes
+}
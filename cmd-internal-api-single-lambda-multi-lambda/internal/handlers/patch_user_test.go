@@ -0,0 +1,290 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/stretchr/testify/assert"
+
+	serviceMock "github.com/jha-captech/user-microservice/internal/handlers/mock"
+	"github.com/jha-captech/user-microservice/internal/models"
+	"github.com/jha-captech/user-microservice/internal/patch"
+	"github.com/jha-captech/user-microservice/internal/policy"
+	"github.com/jha-captech/user-microservice/internal/problem"
+	"github.com/jha-captech/user-microservice/internal/service"
+)
+
+func TestHandlePatchUser(t *testing.T) {
+	mockService := new(serviceMock.MockUserPatcher)
+	logger := slog.Default()
+	handler := HandlePatchUser(logger, mockService)
+
+	storedUser := models.User{ID: 1, FirstName: "John", LastName: "Doe", Role: "Customer", UserID: 1001, Version: 3}
+	patchedUser := models.User{ID: 1, FirstName: "John", LastName: "Smith", Role: "Customer", UserID: 1001, Version: 4}
+	lastNameChange := models.UserChanges{LastName: ptr("Smith")}
+	customer := policy.Default().Principal(1001, "Customer")
+
+	tests := map[string]struct {
+		principal      *policy.Principal
+		fetchCalled    bool
+		fetchOutput    []any
+		patchCalled    bool
+		patchInput     []any
+		patchOutput    []any
+		requestIDParam string
+		contentType    string
+		requestBody    string
+		ifMatch        string
+		expectedCode   int
+		expectedETag   string
+		expectedBody   string
+	}{
+		"merge patch, user patched": {
+			fetchCalled:    true,
+			fetchOutput:    []any{storedUser, nil},
+			patchCalled:    true,
+			patchInput:     []any{1, lastNameChange, []uint{3}},
+			patchOutput:    []any{patchedUser, nil},
+			requestIDParam: "1",
+			contentType:    patch.MergePatchContentType,
+			requestBody:    `{"last_name":"Smith"}`,
+			expectedCode:   http.StatusOK,
+			expectedETag:   `"4"`,
+			expectedBody:   toJSONString(responseUser{User: mapOutput(patchedUser)}),
+		},
+		"customer patches own last_name": {
+			principal:      &customer,
+			fetchCalled:    true,
+			fetchOutput:    []any{storedUser, nil},
+			patchCalled:    true,
+			patchInput:     []any{1, lastNameChange, []uint{3}},
+			patchOutput:    []any{patchedUser, nil},
+			requestIDParam: "1",
+			contentType:    patch.MergePatchContentType,
+			requestBody:    `{"last_name":"Smith"}`,
+			expectedCode:   http.StatusOK,
+			expectedETag:   `"4"`,
+			expectedBody:   toJSONString(responseUser{User: mapOutput(patchedUser)}),
+		},
+		"customer patches own role": {
+			principal:      &customer,
+			fetchCalled:    true,
+			fetchOutput:    []any{storedUser, nil},
+			requestIDParam: "1",
+			contentType:    patch.MergePatchContentType,
+			requestBody:    `{"role":"Employee"}`,
+			expectedCode:   http.StatusForbidden,
+			expectedBody:   toJSONString(problem.New(http.StatusForbidden, "You are not allowed to change role").WithInstance("/api/user/1")),
+		},
+		"customer patches own role to its current value": {
+			principal:      &customer,
+			fetchCalled:    true,
+			fetchOutput:    []any{storedUser, nil},
+			requestIDParam: "1",
+			contentType:    patch.MergePatchContentType,
+			requestBody:    `{"role":"Customer"}`,
+			expectedCode:   http.StatusOK,
+			expectedETag:   `"3"`,
+			expectedBody:   toJSONString(responseUser{User: mapOutput(storedUser)}),
+		},
+		"JSON patch, user patched": {
+			fetchCalled:    true,
+			fetchOutput:    []any{storedUser, nil},
+			patchCalled:    true,
+			patchInput:     []any{1, lastNameChange, []uint{3}},
+			patchOutput:    []any{patchedUser, nil},
+			requestIDParam: "1",
+			contentType:    patch.JSONPatchContentType + "; charset=utf-8",
+			requestBody:    `[{"op":"test","path":"/last_name","value":"Doe"},{"op":"replace","path":"/last_name","value":"Smith"}]`,
+			ifMatch:        `"3"`,
+			expectedCode:   http.StatusOK,
+			expectedETag:   `"4"`,
+			expectedBody:   toJSONString(responseUser{User: mapOutput(patchedUser)}),
+		},
+		"patch changes nothing": {
+			fetchCalled:    true,
+			fetchOutput:    []any{storedUser, nil},
+			requestIDParam: "1",
+			contentType:    patch.MergePatchContentType,
+			requestBody:    `{"last_name":"Doe"}`,
+			expectedCode:   http.StatusOK,
+			expectedETag:   `"3"`,
+			expectedBody:   toJSONString(responseUser{User: mapOutput(storedUser)}),
+		},
+		"unsupported content type": {
+			requestIDParam: "1",
+			contentType:    "application/json",
+			requestBody:    `{"last_name":"Smith"}`,
+			expectedCode:   http.StatusUnsupportedMediaType,
+			expectedBody: toJSONString(problem.
+				New(http.StatusUnsupportedMediaType, "Content-Type must be one of "+acceptPatch).
+				WithInstance("/api/user/1"),
+			),
+		},
+		"invalid ID": {
+			requestIDParam: "abc",
+			contentType:    patch.MergePatchContentType,
+			requestBody:    `{"last_name":"Smith"}`,
+			expectedCode:   http.StatusBadRequest,
+			expectedBody:   toJSONString(problem.New(http.StatusBadRequest, "Not a valid ID").WithInstance("/api/user/abc")),
+		},
+		"malformed patch": {
+			fetchCalled:    true,
+			fetchOutput:    []any{storedUser, nil},
+			requestIDParam: "1",
+			contentType:    patch.JSONPatchContentType,
+			requestBody:    `{"op":"replace"}`,
+			expectedCode:   http.StatusBadRequest,
+			expectedBody:   toJSONString(problem.New(http.StatusBadRequest, "Malformed patch").WithInstance("/api/user/1")),
+		},
+		"patch adds unknown field": {
+			fetchCalled:    true,
+			fetchOutput:    []any{storedUser, nil},
+			requestIDParam: "1",
+			contentType:    patch.MergePatchContentType,
+			requestBody:    `{"version":7}`,
+			expectedCode:   http.StatusBadRequest,
+			expectedBody:   toJSONString(problem.New(http.StatusBadRequest, "Malformed patch").WithInstance("/api/user/1")),
+		},
+		"patch test fails": {
+			fetchCalled:    true,
+			fetchOutput:    []any{storedUser, nil},
+			requestIDParam: "1",
+			contentType:    patch.JSONPatchContentType,
+			requestBody:    `[{"op":"test","path":"/role","value":"Employee"}]`,
+			expectedCode:   http.StatusConflict,
+			expectedBody:   toJSONString(problem.New(http.StatusConflict, "Patch can not be applied to the user").WithInstance("/api/user/1")),
+		},
+		"patched user is invalid": {
+			fetchCalled:    true,
+			fetchOutput:    []any{storedUser, nil},
+			requestIDParam: "1",
+			contentType:    patch.MergePatchContentType,
+			requestBody:    `{"first_name":null,"role":"Admin"}`,
+			expectedCode:   http.StatusBadRequest,
+			expectedBody: toJSONString(problem.
+				New(http.StatusBadRequest, "One or more fields in the patched user are invalid").
+				WithInstance("/api/user/1").
+				WithFieldErrors(map[string]string{
+					"first_name": "must not be blank",
+					"role":       "must be 'Customer' or 'Employee'",
+				}),
+			),
+		},
+		"If-Match does not match": {
+			fetchCalled:    true,
+			fetchOutput:    []any{storedUser, nil},
+			requestIDParam: "1",
+			contentType:    patch.MergePatchContentType,
+			requestBody:    `{"last_name":"Smith"}`,
+			ifMatch:        `"2"`,
+			expectedCode:   http.StatusPreconditionFailed,
+			expectedBody:   toJSONString(problem.New(http.StatusPreconditionFailed, "User has been modified").WithInstance("/api/user/1")),
+		},
+		"user modified while patching": {
+			fetchCalled:    true,
+			fetchOutput:    []any{storedUser, nil},
+			patchCalled:    true,
+			patchInput:     []any{1, lastNameChange, []uint{3}},
+			patchOutput:    []any{models.User{}, fmt.Errorf("[in PatchUser]: %w", service.ErrVersionMismatch)},
+			requestIDParam: "1",
+			contentType:    patch.MergePatchContentType,
+			requestBody:    `{"last_name":"Smith"}`,
+			expectedCode:   http.StatusPreconditionFailed,
+			expectedBody:   toJSONString(problem.New(http.StatusPreconditionFailed, "User has been modified").WithInstance("/api/user/1")),
+		},
+		"user does not exist": {
+			fetchCalled:    true,
+			fetchOutput:    []any{models.User{}, fmt.Errorf("[in FetchUser]: %w", service.ErrUserNotFound)},
+			requestIDParam: "1",
+			contentType:    patch.MergePatchContentType,
+			requestBody:    `{"last_name":"Smith"}`,
+			expectedCode:   http.StatusNotFound,
+			expectedBody:   toJSONString(problem.New(http.StatusNotFound, "Object does not exist").WithInstance("/api/user/1")),
+		},
+		"user_id already in use": {
+			fetchCalled:    true,
+			fetchOutput:    []any{storedUser, nil},
+			patchCalled:    true,
+			patchInput:     []any{1, models.UserChanges{UserID: ptr[uint](1002)}, []uint{3}},
+			patchOutput:    []any{models.User{}, fmt.Errorf("[in PatchUser]: %w", service.ErrUserIDConflict)},
+			requestIDParam: "1",
+			contentType:    patch.MergePatchContentType,
+			requestBody:    `{"user_id":1002}`,
+			expectedCode:   http.StatusConflict,
+			expectedBody:   toJSONString(problem.New(http.StatusConflict, "user_id already in use").WithInstance("/api/user/1")),
+		},
+		"error patching user": {
+			fetchCalled:    true,
+			fetchOutput:    []any{storedUser, nil},
+			patchCalled:    true,
+			patchInput:     []any{1, lastNameChange, []uint{3}},
+			patchOutput:    []any{models.User{}, errors.New("patch error")},
+			requestIDParam: "1",
+			contentType:    patch.MergePatchContentType,
+			requestBody:    `{"last_name":"Smith"}`,
+			expectedCode:   http.StatusInternalServerError,
+			expectedBody:   toJSONString(problem.New(http.StatusInternalServerError, "Error updating object").WithInstance("/api/user/1")),
+		},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			req, err := http.NewRequest(http.MethodPatch, "/api/user/"+tc.requestIDParam, strings.NewReader(tc.requestBody))
+			assert.NoError(t, err)
+			req.Header.Set("Content-Type", tc.contentType)
+			if tc.ifMatch != "" {
+				req.Header.Set("If-Match", tc.ifMatch)
+			}
+
+			// Add chi URLParam
+			rctx := chi.NewRouteContext()
+			rctx.URLParams.Add("ID", tc.requestIDParam)
+			ctx := context.WithValue(req.Context(), chi.RouteCtxKey, rctx)
+			if tc.principal != nil {
+				ctx = policy.WithPrincipal(ctx, *tc.principal)
+			}
+			req = req.WithContext(ctx)
+
+			if tc.fetchCalled {
+				mockService.
+					On("FetchUser", ctx, 1, false).
+					Return(tc.fetchOutput...).
+					Once()
+			}
+			if tc.patchCalled {
+				mockService.
+					On("PatchUser", append([]any{ctx}, tc.patchInput...)...).
+					Return(tc.patchOutput...).
+					Once()
+			}
+
+			rr := httptest.NewRecorder()
+			handler.ServeHTTP(rr, req)
+
+			assert.Equal(t, tc.expectedCode, rr.Code, "Wrong code received")
+			assert.Equal(t, tc.expectedETag, rr.Header().Get("ETag"), "Wrong ETag")
+			assert.JSONEq(t, tc.expectedBody, rr.Body.String(), "Wrong response body")
+
+			mockService.AssertExpectations(t)
+			if !tc.fetchCalled {
+				mockService.AssertNotCalled(t, "FetchUser")
+			}
+			if !tc.patchCalled {
+				mockService.AssertNotCalled(t, "PatchUser")
+			}
+		})
+	}
+}
+
+// ptr returns a pointer to v.
+func ptr[T any](v T) *T {
+	return &v
+}
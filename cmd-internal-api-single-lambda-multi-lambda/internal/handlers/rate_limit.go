@@ -0,0 +1,85 @@
+package handlers
+
+import (
+	"math"
+	"net"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+
+	"github.com/jha-captech/user-microservice/internal/auth"
+	"github.com/jha-captech/user-microservice/internal/models"
+	"github.com/jha-captech/user-microservice/internal/problem"
+	"github.com/jha-captech/user-microservice/internal/ratelimit"
+)
+
+// RateLimit returns a middleware that limits the requests of each client, as told apart by
+// rateLimitKey, with the token buckets of limits kept in store. Every response carries the
+// RateLimit-Limit, RateLimit-Remaining and RateLimit-Reset headers of the bucket it took from, and
+// requests finding it empty are refused with 429 Too Many Requests and a Retry-After header. If
+// store fails, requests are let through, so the limiter can not take the service down.
+//
+// The route pattern is only known once chi has routed a request, so the middleware has to be used
+// on a group or route rather than on the router.
+func RateLimit(logger sLogger, store ratelimit.Store, limits ratelimit.Limits) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			ctx := r.Context()
+			logger := requestLogger(r, logger)
+
+			limit, bucket := limits.Default, rateLimitKey(r)
+			if rctx := chi.RouteContext(ctx); rctx != nil {
+				route := r.Method + " " + rctx.RoutePattern()
+				if routeLimit, ok := limits.Routes[route]; ok {
+					limit, bucket = routeLimit, bucket+" "+route
+				}
+			}
+			if limit.Requests == 0 {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			result, err := store.Take(ctx, bucket, limit)
+			if err != nil {
+				logger.Error("Error taking rate limit token, request let through", "bucket", bucket, "error", err)
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			w.Header().Set("RateLimit-Limit", strconv.Itoa(result.Limit))
+			w.Header().Set("RateLimit-Remaining", strconv.Itoa(result.Remaining))
+			w.Header().Set("RateLimit-Reset", ceilSeconds(result.Reset))
+			if !result.Allowed {
+				logger.Warn("Rate limit exceeded", "bucket", bucket, "path", r.URL.Path)
+				w.Header().Set("Retry-After", ceilSeconds(result.RetryAfter))
+				encodeProblem(w, r, logger, problem.New(http.StatusTooManyRequests, "Too many requests, retry later"))
+				return
+			}
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// rateLimitKey returns the client a request is made by, for rate limiting: the API key or the
+// subject of the token that authenticated it or, for requests without either, the client IP.
+func rateLimitKey(r *http.Request) string {
+	if key, ok := r.Context().Value(apiKeyContextKey{}).(models.APIKey); ok {
+		return "api-key:" + key.Prefix
+	}
+	if claims, ok := auth.FromContext(r.Context()); ok && claims.Subject != "" {
+		return "subject:" + claims.Subject
+	}
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		host = r.RemoteAddr
+	}
+	return "ip:" + host
+}
+
+// ceilSeconds returns d in whole seconds, rounded up, as a header value.
+func ceilSeconds(d time.Duration) string {
+	return strconv.Itoa(int(math.Ceil(d.Seconds())))
+}
@@ -0,0 +1,196 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/jha-captech/user-microservice/internal/auth"
+	"github.com/jha-captech/user-microservice/internal/models"
+	"github.com/jha-captech/user-microservice/internal/problem"
+	"github.com/jha-captech/user-microservice/internal/ratelimit"
+)
+
+// failingStore is a Store that is unavailable.
+type failingStore struct{}
+
+func (failingStore) Take(context.Context, string, ratelimit.Limit) (ratelimit.Result, error) {
+	return ratelimit.Result{}, errors.New("store unavailable")
+}
+
+func TestRateLimit(t *testing.T) {
+	limits := ratelimit.Limits{
+		Default: ratelimit.Limit{Requests: 2, Period: time.Minute},
+		Routes: map[string]ratelimit.Limit{
+			"POST /api/user:batch": {Requests: 1, Period: time.Minute},
+		},
+	}
+
+	type request struct {
+		method string
+		path   string
+		client string
+	}
+	type response struct {
+		code       int
+		limit      string
+		remaining  string
+		retryAfter string
+	}
+
+	tests := map[string]struct {
+		store             ratelimit.Store
+		limits            ratelimit.Limits
+		requests          []request
+		expectedResponses []response
+	}{
+		"default limit shared by routes": {
+			store:  ratelimit.NewMemoryStore(),
+			limits: limits,
+			requests: []request{
+				{http.MethodGet, "/api/user", "192.0.2.1"},
+				{http.MethodGet, "/api/user/1", "192.0.2.1"},
+				{http.MethodGet, "/api/user", "192.0.2.1"},
+			},
+			expectedResponses: []response{
+				{code: http.StatusNoContent, limit: "2", remaining: "1"},
+				{code: http.StatusNoContent, limit: "2", remaining: "0"},
+				{code: http.StatusTooManyRequests, limit: "2", remaining: "0", retryAfter: "30"},
+			},
+		},
+		"clients limited apart": {
+			store:  ratelimit.NewMemoryStore(),
+			limits: limits,
+			requests: []request{
+				{http.MethodGet, "/api/user", "192.0.2.1"},
+				{http.MethodGet, "/api/user", "192.0.2.1"},
+				{http.MethodGet, "/api/user", "192.0.2.2"},
+			},
+			expectedResponses: []response{
+				{code: http.StatusNoContent, limit: "2", remaining: "1"},
+				{code: http.StatusNoContent, limit: "2", remaining: "0"},
+				{code: http.StatusNoContent, limit: "2", remaining: "1"},
+			},
+		},
+		"route limit with bucket of its own": {
+			store:  ratelimit.NewMemoryStore(),
+			limits: limits,
+			requests: []request{
+				{http.MethodPost, "/api/user:batch", "192.0.2.1"},
+				{http.MethodPost, "/api/user:batch", "192.0.2.1"},
+				{http.MethodGet, "/api/user", "192.0.2.1"},
+			},
+			expectedResponses: []response{
+				{code: http.StatusNoContent, limit: "1", remaining: "0"},
+				{code: http.StatusTooManyRequests, limit: "1", remaining: "0", retryAfter: "60"},
+				{code: http.StatusNoContent, limit: "2", remaining: "1"},
+			},
+		},
+		"no default limit": {
+			store:  ratelimit.NewMemoryStore(),
+			limits: ratelimit.Limits{},
+			requests: []request{
+				{http.MethodGet, "/api/user", "192.0.2.1"},
+			},
+			expectedResponses: []response{
+				{code: http.StatusNoContent},
+			},
+		},
+		"store unavailable": {
+			store:  failingStore{},
+			limits: limits,
+			requests: []request{
+				{http.MethodGet, "/api/user", "192.0.2.1"},
+			},
+			expectedResponses: []response{
+				{code: http.StatusNoContent},
+			},
+		},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			next := func(w http.ResponseWriter, _ *http.Request) { w.WriteHeader(http.StatusNoContent) }
+
+			router := chi.NewRouter()
+			router.Group(func(r chi.Router) {
+				r.Use(RateLimit(slog.Default(), tc.store, tc.limits))
+				r.Get("/api/user", next)
+				r.Get("/api/user/{ID}", next)
+				r.Post("/api/user:batch", next)
+			})
+
+			for i, req := range tc.requests {
+				httpReq, err := http.NewRequest(req.method, req.path, nil)
+				assert.NoError(t, err)
+				httpReq.RemoteAddr = req.client + ":1234"
+
+				rr := httptest.NewRecorder()
+				router.ServeHTTP(rr, httpReq)
+
+				expected := tc.expectedResponses[i]
+				assert.Equal(t, expected.code, rr.Code, "Wrong code received for request %d", i)
+				assert.Equal(t, expected.limit, rr.Header().Get("RateLimit-Limit"), "Wrong RateLimit-Limit for request %d", i)
+				assert.Equal(t, expected.remaining, rr.Header().Get("RateLimit-Remaining"), "Wrong RateLimit-Remaining for request %d", i)
+				assert.Equal(t, expected.retryAfter, rr.Header().Get("Retry-After"), "Wrong Retry-After for request %d", i)
+				if expected.code == http.StatusTooManyRequests {
+					expectedBody, _ := json.Marshal(problem.
+						New(http.StatusTooManyRequests, "Too many requests, retry later").
+						WithInstance(req.path),
+					)
+					assert.JSONEq(t, string(expectedBody), rr.Body.String(), "Wrong response body")
+				}
+			}
+		})
+	}
+}
+
+func TestRateLimitKey(t *testing.T) {
+	tests := map[string]struct {
+		ctx         context.Context
+		remoteAddr  string
+		expectedKey string
+	}{
+		"api key": {
+			ctx: context.WithValue(
+				auth.WithClaims(context.Background(), auth.Claims{Subject: "jdoe"}),
+				apiKeyContextKey{}, models.APIKey{Prefix: "umk_abcdefgh"},
+			),
+			remoteAddr:  "192.0.2.1:1234",
+			expectedKey: "api-key:umk_abcdefgh",
+		},
+		"token subject": {
+			ctx:         auth.WithClaims(context.Background(), auth.Claims{Subject: "jdoe"}),
+			remoteAddr:  "192.0.2.1:1234",
+			expectedKey: "subject:jdoe",
+		},
+		"client IP": {
+			ctx:         context.Background(),
+			remoteAddr:  "192.0.2.1:1234",
+			expectedKey: "ip:192.0.2.1",
+		},
+		"client IP without port": {
+			ctx:         context.Background(),
+			remoteAddr:  "192.0.2.1",
+			expectedKey: "ip:192.0.2.1",
+		},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			req, err := http.NewRequestWithContext(tc.ctx, http.MethodGet, "/api/user", nil)
+			assert.NoError(t, err)
+			req.RemoteAddr = tc.remoteAddr
+
+			assert.Equal(t, tc.expectedKey, rateLimitKey(req))
+		})
+	}
+}
@@ -0,0 +1,79 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/jha-captech/user-microservice/internal/models"
+	"github.com/jha-captech/user-microservice/internal/problem"
+	svc "github.com/jha-captech/user-microservice/internal/service"
+)
+
+type webhookRedeliverer interface {
+	RedeliverDelivery(ctx context.Context, ID int, deliveryID int) (models.WebhookDelivery, error)
+}
+
+// HandleRedeliverWebhookDelivery is a Handler that queues a delivery of a webhook subscription to be
+// delivered again, whatever its status. The redelivery is logged as a new delivery, which is
+// returned.
+//
+// @Summary		Redeliver a webhook delivery
+// @Description	Queue the payload of a delivery to be delivered to the subscription again
+// @Tags		webhook
+// @Accept		json
+// @Produce		json
+// @Param		id				path		int		true	"Subscription ID"
+// @Param		deliveryID		path		int		true	"Delivery ID"
+// @Param		X-Admin-Token	header		string	true	"Admin token"
+// @Success		202				{object}	handlers.responseWebhookDelivery
+// @Failure		400				{object}	problem.Problem
+// @Failure		403				{object}	problem.Problem
+// @Failure		404				{object}	problem.Problem
+// @Failure		500				{object}	problem.Problem
+// @Router		/webhook/{ID}/deliveries/{deliveryID}/redeliver	[POST]
+func HandleRedeliverWebhookDelivery(logger sLogger, service webhookRedeliverer) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		// setup
+		logger := requestLogger(r, logger)
+		ctx := r.Context()
+
+		// get and validate ID
+		idString := chi.URLParam(r, "ID")
+		ID, err := strconv.Atoi(idString)
+		if err != nil {
+			logger.Error("error getting ID", "error", err)
+			encodeProblem(w, r, logger, problem.New(http.StatusBadRequest, "Not a valid ID"))
+			return
+		}
+
+		// get and validate delivery ID
+		deliveryID, err := strconv.Atoi(chi.URLParam(r, "deliveryID"))
+		if err != nil {
+			logger.Error("error getting delivery ID", "error", err)
+			encodeProblem(w, r, logger, problem.New(http.StatusBadRequest, "Not a valid delivery ID"))
+			return
+		}
+
+		// queue redelivery
+		delivery, err := service.RedeliverDelivery(ctx, ID, deliveryID)
+		if err != nil {
+			switch {
+			case errors.Is(err, svc.ErrDeliveryNotFound):
+				logger.Error("Object does not exist", "ID", ID, "deliveryID", deliveryID, "error", err)
+				encodeProblem(w, r, logger, problem.New(http.StatusNotFound, "Object does not exist"))
+			default:
+				logger.Error("error redelivering webhook delivery", "ID", ID, "deliveryID", deliveryID, "error", err)
+				encodeProblem(w, r, logger, problem.New(http.StatusInternalServerError, "Error creating object"))
+			}
+			return
+		}
+
+		// return response
+		encodeResponse(w, logger, http.StatusAccepted, responseWebhookDelivery{
+			Delivery: mapDeliveryOutput(delivery),
+		})
+	}
+}
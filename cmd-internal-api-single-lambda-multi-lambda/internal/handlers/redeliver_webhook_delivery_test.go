@@ -0,0 +1,130 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/jha-captech/user-microservice/internal/models"
+	"github.com/jha-captech/user-microservice/internal/problem"
+	"github.com/jha-captech/user-microservice/internal/service"
+	"github.com/stretchr/testify/assert"
+
+	serviceMock "github.com/jha-captech/user-microservice/internal/handlers/mock"
+)
+
+func TestHandleRedeliverWebhookDelivery(t *testing.T) {
+	mockService := new(serviceMock.MockWebhookRedeliverer)
+	logger := slog.Default()
+	handler := HandleRedeliverWebhookDelivery(logger, mockService)
+
+	createdAt := time.Date(2024, 5, 2, 12, 0, 0, 0, time.UTC)
+	original := uint(4)
+	redelivery := models.WebhookDelivery{
+		ID: 9, SubscriptionID: 1, EventID: "7", EventType: models.EventUserCreated, RedeliveryOf: &original,
+		Status: "pending", CreatedAt: createdAt, NextAttemptAt: createdAt,
+	}
+	originalOut := 4
+
+	tests := map[string]struct {
+		mockCalled      bool
+		mockInput       []any
+		mockOutput      []any
+		requestIDParam  string
+		requestDelivery string
+		expectedCode    int
+		expectedBody    string
+	}{
+		"redelivery queued": {
+			mockCalled:      true,
+			mockInput:       []any{1, 4},
+			mockOutput:      []any{redelivery, nil},
+			requestIDParam:  "1",
+			requestDelivery: "4",
+			expectedCode:    http.StatusAccepted,
+			expectedBody: toJSONString(responseWebhookDelivery{Delivery: outputWebhookDelivery{
+				ID: 9, SubscriptionID: 1, EventID: "7", EventType: "user.created", RedeliveryOf: &originalOut,
+				Status: "pending", CreatedAt: createdAt, NextAttemptAt: createdAt,
+			}}),
+		},
+		"invalid ID": {
+			mockCalled:      false,
+			requestIDParam:  "abc",
+			requestDelivery: "4",
+			expectedCode:    http.StatusBadRequest,
+			expectedBody: toJSONString(problem.New(http.StatusBadRequest, "Not a valid ID").
+				WithInstance("/api/webhook/abc/deliveries/4/redeliver")),
+		},
+		"invalid delivery ID": {
+			mockCalled:      false,
+			requestIDParam:  "1",
+			requestDelivery: "abc",
+			expectedCode:    http.StatusBadRequest,
+			expectedBody: toJSONString(problem.New(http.StatusBadRequest, "Not a valid delivery ID").
+				WithInstance("/api/webhook/1/deliveries/abc/redeliver")),
+		},
+		"delivery not found": {
+			mockCalled:      true,
+			mockInput:       []any{1, 5},
+			mockOutput:      []any{models.WebhookDelivery{}, fmt.Errorf("[in RedeliverDelivery]: %w", service.ErrDeliveryNotFound)},
+			requestIDParam:  "1",
+			requestDelivery: "5",
+			expectedCode:    http.StatusNotFound,
+			expectedBody: toJSONString(problem.New(http.StatusNotFound, "Object does not exist").
+				WithInstance("/api/webhook/1/deliveries/5/redeliver")),
+		},
+		"internal server error": {
+			mockCalled:      true,
+			mockInput:       []any{1, 4},
+			mockOutput:      []any{models.WebhookDelivery{}, errors.New("")},
+			requestIDParam:  "1",
+			requestDelivery: "4",
+			expectedCode:    http.StatusInternalServerError,
+			expectedBody: toJSONString(problem.New(http.StatusInternalServerError, "Error creating object").
+				WithInstance("/api/webhook/1/deliveries/4/redeliver")),
+		},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			req, err := http.NewRequest(
+				http.MethodPost,
+				"/api/webhook/"+tc.requestIDParam+"/deliveries/"+tc.requestDelivery+"/redeliver",
+				nil,
+			)
+			assert.NoError(t, err)
+
+			// Add chi URLParams
+			rctx := chi.NewRouteContext()
+			rctx.URLParams.Add("ID", tc.requestIDParam)
+			rctx.URLParams.Add("deliveryID", tc.requestDelivery)
+			ctx := context.WithValue(req.Context(), chi.RouteCtxKey, rctx)
+			req = req.WithContext(ctx)
+
+			if tc.mockCalled {
+				mockService.
+					On("RedeliverDelivery", append([]any{ctx}, tc.mockInput...)...).
+					Return(tc.mockOutput...).
+					Once()
+			}
+
+			rr := httptest.NewRecorder()
+			handler.ServeHTTP(rr, req)
+
+			assert.Equal(t, tc.expectedCode, rr.Code, "Wrong code received")
+			assert.JSONEq(t, tc.expectedBody, rr.Body.String(), "Wrong response body")
+
+			if tc.mockCalled {
+				mockService.AssertExpectations(t)
+			} else {
+				mockService.AssertNotCalled(t, "RedeliverDelivery")
+			}
+		})
+	}
+}
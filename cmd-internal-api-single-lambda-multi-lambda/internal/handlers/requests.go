@@ -4,8 +4,14 @@ import (
 	"encoding/json"
 	"fmt"
 	"net/http"
+	"net/url"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
 
 	"github.com/jha-captech/user-microservice/internal/models"
+	"github.com/jha-captech/user-microservice/internal/stream"
 )
 
 type Validator interface {
@@ -22,10 +28,20 @@ type ValidatorMapper[T any] interface {
 }
 
 type inputUser struct {
-	FirstName string `json:"first_name,omitempty"`
-	LastName  string `json:"last_name,omitempty"`
-	Role      string `json:"role,omitempty"`
-	UserID    int    `json:"user_id,omitempty"`
+	FirstName string `json:"first_name" pii:"true"`
+	LastName  string `json:"last_name" pii:"true"`
+	Role      string `json:"role"`
+	UserID    int    `json:"user_id"`
+}
+
+// newInputUser returns user as an inputUser, which is the document a patch is applied to.
+func newInputUser(user models.User) inputUser {
+	return inputUser{
+		FirstName: user.FirstName,
+		LastName:  user.LastName,
+		Role:      user.Role,
+		UserID:    int(user.UserID),
+	}
 }
 
 func (user inputUser) MapTo() (models.User, error) {
@@ -41,14 +57,19 @@ func (user inputUser) MapTo() (models.User, error) {
 func (user inputUser) Valid() map[string]string {
 	problems := make(map[string]string)
 
-	// validate UserID greater than 0
-	if user.UserID < 1 {
-		problems["UserID"] = "UserID must be more than 0"
+	// validate first_name is set
+	if strings.TrimSpace(user.FirstName) == "" {
+		problems["first_name"] = "must not be blank"
 	}
 
 	// validate role is `Customer` or `Employee`
 	if user.Role != "Customer" && user.Role != "Employee" {
-		problems["Role"] = "Role must be 'Customer' or 'Employee'"
+		problems["role"] = "must be 'Customer' or 'Employee'"
+	}
+
+	// validate user_id greater than 0
+	if user.UserID < 1 {
+		problems["user_id"] = "must be more than 0"
 	}
 
 	return problems
@@ -82,3 +103,556 @@ func decodeValidateBody[I ValidatorMapper[O], O any](r *http.Request) (O, map[st
 
 	return data, nil, nil
 }
+
+// maxBatchOperations is the most operations accepted in a single batch.
+const maxBatchOperations = 5000
+
+const (
+	batchModeAtomic     = "atomic"
+	batchModeBestEffort = "best_effort"
+)
+
+type inputBatch struct {
+	Mode       string                `json:"mode" enums:"atomic,best_effort" default:"atomic"`
+	Operations []inputBatchOperation `json:"operations"`
+}
+
+// batchOperations is a validated batch. Operations holds the valid operations and Indexes their
+// positions in the request, while Problems holds the problems of each invalid operation by its
+// position in the request.
+type batchOperations struct {
+	Atomic     bool
+	Size       int
+	Operations []models.BatchOperation
+	Indexes    []int
+	Problems   map[int]map[string]string
+}
+
+func (batch inputBatch) MapTo() (batchOperations, error) {
+	mapped := batchOperations{
+		Atomic:   batch.Mode != batchModeBestEffort,
+		Size:     len(batch.Operations),
+		Problems: make(map[int]map[string]string),
+	}
+
+	for i, operationIn := range batch.Operations {
+		operation, problems, err := validateInput[inputBatchOperation, models.BatchOperation](operationIn)
+		if err != nil {
+			if len(problems) == 0 {
+				return batchOperations{}, fmt.Errorf("operation %d: %w", i, err)
+			}
+			mapped.Problems[i] = problems
+			continue
+		}
+		mapped.Operations = append(mapped.Operations, operation)
+		mapped.Indexes = append(mapped.Indexes, i)
+	}
+
+	return mapped, nil
+}
+
+// Valid validates the batch as a whole. The operations in it are validated one by one by MapTo, so
+// that an invalid operation only fails itself.
+func (batch inputBatch) Valid() map[string]string {
+	problems := make(map[string]string)
+
+	// validate mode is `atomic` or `best_effort`
+	if batch.Mode != "" && batch.Mode != batchModeAtomic && batch.Mode != batchModeBestEffort {
+		problems["mode"] = fmt.Sprintf("must be '%s' or '%s'", batchModeAtomic, batchModeBestEffort)
+	}
+
+	// validate there are between 1 and maxBatchOperations operations
+	if len(batch.Operations) < 1 || len(batch.Operations) > maxBatchOperations {
+		problems["operations"] = fmt.Sprintf("must have between 1 and %d operations", maxBatchOperations)
+	}
+
+	return problems
+}
+
+type inputBatchOperation struct {
+	Op   string     `json:"op" enums:"create,update,delete"`
+	ID   int        `json:"id,omitempty"`
+	User *inputUser `json:"user,omitempty"`
+}
+
+func (operation inputBatchOperation) MapTo() (models.BatchOperation, error) {
+	mapped := models.BatchOperation{
+		Op: models.BatchOp(operation.Op),
+	}
+	if mapped.Op != models.BatchCreate {
+		mapped.ID = operation.ID
+	}
+	if mapped.Op != models.BatchDelete {
+		user, err := operation.User.MapTo()
+		if err != nil {
+			return models.BatchOperation{}, fmt.Errorf("map user: %w", err)
+		}
+		mapped.User = user
+	}
+
+	return mapped, nil
+}
+
+func (operation inputBatchOperation) Valid() map[string]string {
+	problems := make(map[string]string)
+	op := models.BatchOp(operation.Op)
+
+	// validate op is `create`, `update` or `delete`
+	if !slices.Contains(models.BatchOps, op) {
+		problems["op"] = fmt.Sprintf("must be one of %v", models.BatchOps)
+		return problems
+	}
+
+	// validate id is set for updates and deletes
+	if op != models.BatchCreate && operation.ID < 1 {
+		problems["id"] = "must be more than 0"
+	}
+
+	// validate user is set and valid for creates and updates
+	if op != models.BatchDelete {
+		if operation.User == nil {
+			problems["user"] = "must be set"
+			return problems
+		}
+		for field, detail := range operation.User.Valid() {
+			problems["user."+field] = detail
+		}
+	}
+
+	return problems
+}
+
+// maxImportRows is the most rows accepted in a single import file.
+const maxImportRows = 100_000
+
+// importTimeout is how long an import may take to be uploaded and processed, which replaces the
+// server's read and write timeouts for the request.
+const importTimeout = 2 * time.Minute
+
+type inputImportUsers struct {
+	OnConflict string
+	DryRun     string
+}
+
+// importParams holds the validated query parameters of an import.
+type importParams struct {
+	OnConflict models.ImportConflict
+	DryRun     bool
+}
+
+func newInputImportUsers(query url.Values) inputImportUsers {
+	return inputImportUsers{
+		OnConflict: query.Get("on_conflict"),
+		DryRun:     query.Get("dry_run"),
+	}
+}
+
+func (query inputImportUsers) MapTo() (importParams, error) {
+	params := importParams{
+		OnConflict: models.ImportConflictFail,
+	}
+
+	if query.OnConflict != "" {
+		params.OnConflict = models.ImportConflict(query.OnConflict)
+	}
+	dryRun, err := parseBoolQuery(query.DryRun)
+	if err != nil {
+		return importParams{}, fmt.Errorf("parse dry_run: %w", err)
+	}
+	params.DryRun = dryRun
+
+	return params, nil
+}
+
+func (query inputImportUsers) Valid() map[string]string {
+	problems := make(map[string]string)
+
+	// validate on_conflict is `fail`, `skip` or `update`
+	if query.OnConflict != "" && !slices.Contains(models.ImportConflicts, models.ImportConflict(query.OnConflict)) {
+		problems["on_conflict"] = fmt.Sprintf("must be one of %v", models.ImportConflicts)
+	}
+
+	// validate dry_run is a boolean
+	if _, err := parseBoolQuery(query.DryRun); err != nil {
+		problems["dry_run"] = "must be true or false"
+	}
+
+	return problems
+}
+
+const (
+	defaultListLimit = 20
+	maxListLimit     = 100
+)
+
+type inputListUsers struct {
+	Limit          string
+	Offset         string
+	Cursor         string
+	Role           string
+	LastName       string `pii:"true"`
+	UserIDMin      string
+	UserIDMax      string
+	Sort           string
+	IncludeDeleted string
+	UpdatedSince   string
+}
+
+func newInputListUsers(query url.Values) inputListUsers {
+	return inputListUsers{
+		Limit:          query.Get("limit"),
+		Offset:         query.Get("offset"),
+		Cursor:         query.Get("cursor"),
+		Role:           query.Get("role"),
+		LastName:       query.Get("last_name"),
+		UserIDMin:      query.Get("user_id_min"),
+		UserIDMax:      query.Get("user_id_max"),
+		Sort:           query.Get("sort"),
+		IncludeDeleted: query.Get("include_deleted"),
+		UpdatedSince:   query.Get("updated_since"),
+	}
+}
+
+func (query inputListUsers) MapTo() (models.ListUsersParams, error) {
+	params := models.ListUsersParams{
+		Limit:          defaultListLimit,
+		Cursor:         query.Cursor,
+		Role:           query.Role,
+		LastNamePrefix: query.LastName,
+		SortBy:         models.UserSortID,
+	}
+
+	var err error
+	if query.Limit != "" {
+		if params.Limit, err = strconv.Atoi(query.Limit); err != nil {
+			return models.ListUsersParams{}, fmt.Errorf("parse limit: %w", err)
+		}
+	}
+	if query.Offset != "" {
+		if params.Offset, err = strconv.Atoi(query.Offset); err != nil {
+			return models.ListUsersParams{}, fmt.Errorf("parse offset: %w", err)
+		}
+	}
+	if query.UserIDMin != "" {
+		userIDMin, err := strconv.ParseUint(query.UserIDMin, 10, 0)
+		if err != nil {
+			return models.ListUsersParams{}, fmt.Errorf("parse user_id_min: %w", err)
+		}
+		params.UserIDMin = uint(userIDMin)
+	}
+	if query.UserIDMax != "" {
+		userIDMax, err := strconv.ParseUint(query.UserIDMax, 10, 0)
+		if err != nil {
+			return models.ListUsersParams{}, fmt.Errorf("parse user_id_max: %w", err)
+		}
+		params.UserIDMax = uint(userIDMax)
+	}
+	if query.Sort != "" {
+		params.SortDesc = strings.HasPrefix(query.Sort, "-")
+		params.SortBy = models.UserSortField(strings.TrimPrefix(query.Sort, "-"))
+	}
+	if params.IncludeDeleted, err = parseBoolQuery(query.IncludeDeleted); err != nil {
+		return models.ListUsersParams{}, fmt.Errorf("parse include_deleted: %w", err)
+	}
+	if query.UpdatedSince != "" {
+		updatedSince, err := time.Parse(time.RFC3339, query.UpdatedSince)
+		if err != nil {
+			return models.ListUsersParams{}, fmt.Errorf("parse updated_since: %w", err)
+		}
+		params.UpdatedSince = updatedSince.UTC()
+	}
+
+	return params, nil
+}
+
+func (query inputListUsers) Valid() map[string]string {
+	problems := make(map[string]string)
+
+	// validate limit is between 1 and maxListLimit
+	if query.Limit != "" {
+		limit, err := strconv.Atoi(query.Limit)
+		if err != nil || limit < 1 || limit > maxListLimit {
+			problems["limit"] = fmt.Sprintf("must be a number between 1 and %d", maxListLimit)
+		}
+	}
+
+	// validate offset is not negative
+	if query.Offset != "" {
+		offset, err := strconv.Atoi(query.Offset)
+		if err != nil || offset < 0 {
+			problems["offset"] = "must be a number of 0 or more"
+		}
+	}
+
+	// validate offset and cursor paging are not mixed
+	if query.Offset != "" && query.Cursor != "" {
+		problems["cursor"] = "can not be combined with offset"
+	}
+
+	// validate role is `Customer` or `Employee`
+	if query.Role != "" && query.Role != "Customer" && query.Role != "Employee" {
+		problems["role"] = "must be 'Customer' or 'Employee'"
+	}
+
+	// validate user_id range bounds are positive and in order
+	userIDMin, errMin := strconv.ParseUint(query.UserIDMin, 10, 0)
+	if query.UserIDMin != "" && (errMin != nil || userIDMin < 1) {
+		problems["user_id_min"] = "must be more than 0"
+	}
+	userIDMax, errMax := strconv.ParseUint(query.UserIDMax, 10, 0)
+	if query.UserIDMax != "" && (errMax != nil || userIDMax < 1) {
+		problems["user_id_max"] = "must be more than 0"
+	}
+	if errMin == nil && errMax == nil && userIDMin > userIDMax {
+		problems["user_id_max"] = "must not be less than user_id_min"
+	}
+
+	// validate sort is an allowed field with an optional `-` prefix for descending order
+	if query.Sort != "" {
+		sortBy := models.UserSortField(strings.TrimPrefix(query.Sort, "-"))
+		if !slices.Contains(models.UserSortFields, sortBy) {
+			problems["sort"] = fmt.Sprintf(
+				"must be one of %v, optionally prefixed with '-'", models.UserSortFields,
+			)
+		}
+	}
+
+	// validate include_deleted is a boolean
+	if _, err := parseBoolQuery(query.IncludeDeleted); err != nil {
+		problems["include_deleted"] = "must be true or false"
+	}
+
+	// validate updated_since is a timestamp
+	if query.UpdatedSince != "" {
+		if _, err := time.Parse(time.RFC3339, query.UpdatedSince); err != nil {
+			problems["updated_since"] = "must be an RFC 3339 timestamp, such as 2024-05-01T12:00:00Z"
+		}
+	}
+
+	return problems
+}
+
+type inputListHistory struct {
+	Limit  string
+	Offset string
+}
+
+func newInputListHistory(query url.Values) inputListHistory {
+	return inputListHistory{
+		Limit:  query.Get("limit"),
+		Offset: query.Get("offset"),
+	}
+}
+
+func (query inputListHistory) MapTo() (models.ListHistoryParams, error) {
+	params := models.ListHistoryParams{
+		Limit: defaultListLimit,
+	}
+
+	var err error
+	if query.Limit != "" {
+		if params.Limit, err = strconv.Atoi(query.Limit); err != nil {
+			return models.ListHistoryParams{}, fmt.Errorf("parse limit: %w", err)
+		}
+	}
+	if query.Offset != "" {
+		if params.Offset, err = strconv.Atoi(query.Offset); err != nil {
+			return models.ListHistoryParams{}, fmt.Errorf("parse offset: %w", err)
+		}
+	}
+
+	return params, nil
+}
+
+func (query inputListHistory) Valid() map[string]string {
+	problems := make(map[string]string)
+
+	// validate limit is between 1 and maxListLimit
+	if query.Limit != "" {
+		limit, err := strconv.Atoi(query.Limit)
+		if err != nil || limit < 1 || limit > maxListLimit {
+			problems["limit"] = fmt.Sprintf("must be a number between 1 and %d", maxListLimit)
+		}
+	}
+
+	// validate offset is not negative
+	if query.Offset != "" {
+		offset, err := strconv.Atoi(query.Offset)
+		if err != nil || offset < 0 {
+			problems["offset"] = "must be a number of 0 or more"
+		}
+	}
+
+	return problems
+}
+
+type inputUserEvents struct {
+	Role string
+	ID   string
+}
+
+func newInputUserEvents(r *http.Request) inputUserEvents {
+	query := r.URL.Query()
+	return inputUserEvents{
+		Role: query.Get("role"),
+		ID:   query.Get("id"),
+	}
+}
+
+func (query inputUserEvents) MapTo() (stream.Filter, error) {
+	filter := stream.Filter{
+		Role: query.Role,
+	}
+
+	if query.ID != "" {
+		ID, err := strconv.Atoi(query.ID)
+		if err != nil {
+			return stream.Filter{}, fmt.Errorf("parse id: %w", err)
+		}
+		filter.UserID = ID
+	}
+
+	return filter, nil
+}
+
+func (query inputUserEvents) Valid() map[string]string {
+	problems := make(map[string]string)
+
+	// validate role is `Customer` or `Employee` if set
+	if query.Role != "" && query.Role != "Customer" && query.Role != "Employee" {
+		problems["role"] = "must be 'Customer' or 'Employee'"
+	}
+
+	// validate id greater than 0 if set
+	if query.ID != "" {
+		ID, err := strconv.Atoi(query.ID)
+		if err != nil || ID < 1 {
+			problems["id"] = "must be a number more than 0"
+		}
+	}
+
+	return problems
+}
+
+// minWebhookSecretLength is the shortest secret accepted for a webhook subscription.
+const minWebhookSecretLength = 16
+
+type inputWebhookSubscription struct {
+	URL    string   `json:"url"`
+	Events []string `json:"events"`
+	Secret string   `json:"secret"`
+}
+
+func (subscription inputWebhookSubscription) MapTo() (models.WebhookSubscription, error) {
+	events := make([]models.EventType, len(subscription.Events))
+	for i, event := range subscription.Events {
+		events[i] = models.EventType(event)
+	}
+
+	return models.WebhookSubscription{
+		URL:    subscription.URL,
+		Events: events,
+		Secret: subscription.Secret,
+	}, nil
+}
+
+func (subscription inputWebhookSubscription) Valid() map[string]string {
+	problems := make(map[string]string)
+
+	// validate url is an absolute http or https URL
+	target, err := url.Parse(subscription.URL)
+	if err != nil || (target.Scheme != "http" && target.Scheme != "https") || target.Host == "" {
+		problems["url"] = "must be an absolute http or https URL"
+	}
+
+	// validate events only holds known event types, once each
+	for i, event := range subscription.Events {
+		if !slices.Contains(models.EventTypes, models.EventType(event)) || slices.Index(subscription.Events, event) != i {
+			problems["events"] = "must only contain each of 'user.created', 'user.updated' and 'user.deleted' at most once"
+			break
+		}
+	}
+
+	// validate secret is long enough
+	if len(subscription.Secret) < minWebhookSecretLength {
+		problems["secret"] = fmt.Sprintf("must be at least %d characters", minWebhookSecretLength)
+	}
+
+	return problems
+}
+
+type inputAPIKey struct {
+	Name      string     `json:"name"`
+	Scopes    []string   `json:"scopes"`
+	ExpiresAt *time.Time `json:"expires_at"`
+}
+
+func (key inputAPIKey) MapTo() (models.APIKey, error) {
+	scopes := make([]models.APIKeyScope, len(key.Scopes))
+	for i, scope := range key.Scopes {
+		scopes[i] = models.APIKeyScope(scope)
+	}
+
+	return models.APIKey{
+		Name:      key.Name,
+		Scopes:    scopes,
+		ExpiresAt: key.ExpiresAt,
+	}, nil
+}
+
+func (key inputAPIKey) Valid() map[string]string {
+	problems := make(map[string]string)
+
+	// validate name is set
+	if strings.TrimSpace(key.Name) == "" {
+		problems["name"] = "must not be blank"
+	}
+
+	// validate scopes holds at least one known scope, once each
+	if len(key.Scopes) == 0 {
+		problems["scopes"] = "must contain at least one scope"
+	}
+	for i, scope := range key.Scopes {
+		if !slices.Contains(models.APIKeyScopes, models.APIKeyScope(scope)) || slices.Index(key.Scopes, scope) != i {
+			problems["scopes"] = "must only contain each of 'users:read', 'users:write' and 'users:admin' at most once"
+			break
+		}
+	}
+
+	// validate expires_at is in the future
+	if key.ExpiresAt != nil && !key.ExpiresAt.After(time.Now()) {
+		problems["expires_at"] = "must be in the future"
+	}
+
+	return problems
+}
+
+// parseBoolQuery parses the value of a boolean query parameter, which is false when not set.
+func parseBoolQuery(value string) (bool, error) {
+	if value == "" {
+		return false, nil
+	}
+	return strconv.ParseBool(value)
+}
+
+func validateInput[I ValidatorMapper[O], O any](inputModel I) (O, map[string]string, error) {
+	// validate
+	if problems := inputModel.Valid(); len(problems) > 0 {
+		return *new(O), problems, fmt.Errorf(
+			"[in validateInput] invalid %T: %d problems", inputModel, len(problems),
+		)
+	}
+
+	// map to return type
+	data, err := inputModel.MapTo()
+	if err != nil {
+		return *new(O), nil, fmt.Errorf(
+			"[in validateInput] error mapping input %T to %T: %w",
+			*new(I),
+			*new(O),
+			err,
+		)
+	}
+
+	return data, nil, nil
+}
@@ -3,16 +3,24 @@ package handlers
 import (
 	"encoding/json"
 	"net/http"
+	"time"
 
 	"github.com/jha-captech/user-microservice/internal/models"
+	"github.com/jha-captech/user-microservice/internal/problem"
 )
 
 type outputUser struct {
-	ID        int    `json:"id"`
-	FirstName string `json:"first_name"`
-	LastName  string `json:"last_name"`
-	Role      string `json:"role"`
-	UserID    int    `json:"user_id"`
+	ID        int        `json:"id"`
+	FirstName string     `json:"first_name" pii:"true"`
+	LastName  string     `json:"last_name" pii:"true"`
+	Role      string     `json:"role"`
+	UserID    int        `json:"user_id"`
+	Version   int        `json:"version"`
+	DeletedAt *time.Time `json:"deleted_at,omitempty"`
+	CreatedAt time.Time  `json:"created_at"`
+	UpdatedAt time.Time  `json:"updated_at"`
+	CreatedBy string     `json:"created_by"`
+	UpdatedBy string     `json:"updated_by"`
 }
 
 func mapOutput(user models.User) outputUser {
@@ -22,6 +30,12 @@ func mapOutput(user models.User) outputUser {
 		LastName:  user.LastName,
 		Role:      user.Role,
 		UserID:    int(user.UserID),
+		Version:   int(user.Version),
+		DeletedAt: user.DeletedAt,
+		CreatedAt: user.CreatedAt,
+		UpdatedAt: user.UpdatedAt,
+		CreatedBy: user.CreatedBy,
+		UpdatedBy: user.UpdatedBy,
 	}
 }
 
@@ -40,7 +54,187 @@ type responseUser struct {
 }
 
 type responseUsers struct {
-	Users []outputUser `json:"users"`
+	Users      []outputUser `json:"users"`
+	Total      int          `json:"total"`
+	Limit      int          `json:"limit"`
+	Offset     int          `json:"offset"`
+	Next       string       `json:"next,omitempty"`
+	NextCursor string       `json:"next_cursor,omitempty"`
+	PrevCursor string       `json:"prev_cursor,omitempty"`
+}
+
+type outputHistoryEntry struct {
+	ID        int         `json:"id"`
+	Version   int         `json:"version"`
+	Operation string      `json:"operation"`
+	Actor     string      `json:"actor"`
+	RequestID string      `json:"request_id,omitempty"`
+	ChangedAt time.Time   `json:"changed_at"`
+	Before    *outputUser `json:"before"`
+	After     *outputUser `json:"after"`
+}
+
+func mapHistoryOutput(entry models.UserHistoryEntry) outputHistoryEntry {
+	entryOut := outputHistoryEntry{
+		ID:        int(entry.ID),
+		Version:   int(entry.Version),
+		Operation: string(entry.Operation),
+		Actor:     entry.Actor,
+		RequestID: entry.RequestID,
+		ChangedAt: entry.ChangedAt,
+	}
+	if entry.Before != nil {
+		before := mapOutput(*entry.Before)
+		entryOut.Before = &before
+	}
+	if entry.After != nil {
+		after := mapOutput(*entry.After)
+		entryOut.After = &after
+	}
+
+	return entryOut
+}
+
+func mapMultipleHistoryOutput(entries []models.UserHistoryEntry) []outputHistoryEntry {
+	entriesOut := make([]outputHistoryEntry, len(entries))
+	for i := 0; i < len(entries); i++ {
+		entriesOut[i] = mapHistoryOutput(entries[i])
+	}
+
+	return entriesOut
+}
+
+type responseHistoryEntry struct {
+	Entry outputHistoryEntry `json:"entry"`
+}
+
+type responseUserHistory struct {
+	History []outputHistoryEntry `json:"history"`
+	Total   int                  `json:"total"`
+	Limit   int                  `json:"limit"`
+	Offset  int                  `json:"offset"`
+	Next    string               `json:"next,omitempty"`
+}
+
+type outputWebhookSubscription struct {
+	ID        int       `json:"id"`
+	URL       string    `json:"url"`
+	Events    []string  `json:"events"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+func mapWebhookOutput(subscription models.WebhookSubscription) outputWebhookSubscription {
+	events := make([]string, len(subscription.Events))
+	for i, event := range subscription.Events {
+		events[i] = string(event)
+	}
+
+	return outputWebhookSubscription{
+		ID:        int(subscription.ID),
+		URL:       subscription.URL,
+		Events:    events,
+		CreatedAt: subscription.CreatedAt,
+		UpdatedAt: subscription.UpdatedAt,
+	}
+}
+
+func mapMultipleWebhookOutput(subscriptions []models.WebhookSubscription) []outputWebhookSubscription {
+	subscriptionsOut := make([]outputWebhookSubscription, len(subscriptions))
+	for i := 0; i < len(subscriptions); i++ {
+		subscriptionsOut[i] = mapWebhookOutput(subscriptions[i])
+	}
+
+	return subscriptionsOut
+}
+
+type responseWebhook struct {
+	Webhook outputWebhookSubscription `json:"webhook"`
+}
+
+type responseWebhooks struct {
+	Webhooks []outputWebhookSubscription `json:"webhooks"`
+}
+
+type outputWebhookDelivery struct {
+	ID             int        `json:"id"`
+	SubscriptionID int        `json:"subscription_id"`
+	EventID        string     `json:"event_id"`
+	EventType      string     `json:"event_type"`
+	RedeliveryOf   *int       `json:"redelivery_of,omitempty"`
+	Status         string     `json:"status"`
+	Attempts       int        `json:"attempts"`
+	ResponseStatus *int       `json:"response_status,omitempty"`
+	LastError      string     `json:"last_error,omitempty"`
+	CreatedAt      time.Time  `json:"created_at"`
+	NextAttemptAt  time.Time  `json:"next_attempt_at"`
+	DeliveredAt    *time.Time `json:"delivered_at,omitempty"`
+}
+
+func mapDeliveryOutput(delivery models.WebhookDelivery) outputWebhookDelivery {
+	deliveryOut := outputWebhookDelivery{
+		ID:             int(delivery.ID),
+		SubscriptionID: int(delivery.SubscriptionID),
+		EventID:        delivery.EventID,
+		EventType:      string(delivery.EventType),
+		Status:         delivery.Status,
+		Attempts:       delivery.Attempts,
+		ResponseStatus: delivery.ResponseStatus,
+		LastError:      delivery.LastError,
+		CreatedAt:      delivery.CreatedAt,
+		NextAttemptAt:  delivery.NextAttemptAt,
+		DeliveredAt:    delivery.DeliveredAt,
+	}
+	if delivery.RedeliveryOf != nil {
+		redeliveryOf := int(*delivery.RedeliveryOf)
+		deliveryOut.RedeliveryOf = &redeliveryOf
+	}
+
+	return deliveryOut
+}
+
+func mapMultipleDeliveryOutput(deliveries []models.WebhookDelivery) []outputWebhookDelivery {
+	deliveriesOut := make([]outputWebhookDelivery, len(deliveries))
+	for i := 0; i < len(deliveries); i++ {
+		deliveriesOut[i] = mapDeliveryOutput(deliveries[i])
+	}
+
+	return deliveriesOut
+}
+
+type responseWebhookDelivery struct {
+	Delivery outputWebhookDelivery `json:"delivery"`
+}
+
+type responseWebhookDeliveries struct {
+	Deliveries []outputWebhookDelivery `json:"deliveries"`
+	Total      int                     `json:"total"`
+	Limit      int                     `json:"limit"`
+	Offset     int                     `json:"offset"`
+	Next       string                  `json:"next,omitempty"`
+}
+
+type outputBatchResult struct {
+	Index   int                  `json:"index"`
+	Status  int                  `json:"status"`
+	ID      int                  `json:"id,omitempty"`
+	Version int                  `json:"version,omitempty"`
+	Detail  string               `json:"detail,omitempty"`
+	Errors  []problem.FieldError `json:"errors,omitempty"`
+}
+
+type responseBatch struct {
+	Results []outputBatchResult `json:"results"`
+}
+
+type responseImport struct {
+	DryRun     bool   `json:"dry_run"`
+	OnConflict string `json:"on_conflict"`
+	Rows       int    `json:"rows"`
+	Created    int    `json:"created"`
+	Updated    int    `json:"updated"`
+	Skipped    int    `json:"skipped"`
+	Conflicts  []int  `json:"conflicts,omitempty"`
 }
 
 type responseMsg struct {
@@ -51,17 +245,78 @@ type responseID struct {
 	ObjectID int `json:"object_id"`
 }
 
-type responseErr struct {
-	Error            string            `json:"error,omitempty"`
-	ValidationErrors map[string]string `json:"validation_errors,omitempty"`
-}
-
-// encodeResponse encodes data as a JSON response.
+// encodeResponse encodes data as a JSON response. If data can not be encoded, an internal server
+// error problem is written instead.
 func encodeResponse(w http.ResponseWriter, logger sLogger, status int, data any) {
+	body, err := json.Marshal(data)
+	if err != nil {
+		logger.Error("Error while marshaling data", "err", err, "data", data)
+		encodeProblem(w, nil, logger, problem.New(http.StatusInternalServerError, "Internal server error"))
+		return
+	}
+
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(status)
-	if err := json.NewEncoder(w).Encode(data); err != nil {
-		logger.Error("Error while marshaling data", "err", err, "data", data)
-		http.Error(w, `{"Error": "Internal server error"}`, http.StatusInternalServerError)
+	if _, err = w.Write(append(body, '\n')); err != nil {
+		logger.Error("Error while writing response", "err", err)
 	}
 }
+
+// encodeProblem encodes p as an application/problem+json response for r.
+func encodeProblem(w http.ResponseWriter, r *http.Request, logger sLogger, p problem.Problem) {
+	if err := problem.Write(w, r, p); err != nil {
+		logger.Error("Error while writing problem", "err", err, "problem", p)
+	}
+}
+
+type outputAPIKey struct {
+	ID         int        `json:"id"`
+	Name       string     `json:"name"`
+	Prefix     string     `json:"prefix"`
+	Scopes     []string   `json:"scopes"`
+	ExpiresAt  *time.Time `json:"expires_at,omitempty"`
+	LastUsedAt *time.Time `json:"last_used_at,omitempty"`
+	RevokedAt  *time.Time `json:"revoked_at,omitempty"`
+	CreatedAt  time.Time  `json:"created_at"`
+}
+
+func mapAPIKeyOutput(key models.APIKey) outputAPIKey {
+	scopes := make([]string, len(key.Scopes))
+	for i, scope := range key.Scopes {
+		scopes[i] = string(scope)
+	}
+
+	return outputAPIKey{
+		ID:         int(key.ID),
+		Name:       key.Name,
+		Prefix:     key.Prefix,
+		Scopes:     scopes,
+		ExpiresAt:  key.ExpiresAt,
+		LastUsedAt: key.LastUsedAt,
+		RevokedAt:  key.RevokedAt,
+		CreatedAt:  key.CreatedAt,
+	}
+}
+
+func mapMultipleAPIKeyOutput(keys []models.APIKey) []outputAPIKey {
+	keysOut := make([]outputAPIKey, len(keys))
+	for i := 0; i < len(keys); i++ {
+		keysOut[i] = mapAPIKeyOutput(keys[i])
+	}
+
+	return keysOut
+}
+
+type responseAPIKey struct {
+	APIKey outputAPIKey `json:"api_key"`
+}
+
+type responseAPIKeys struct {
+	APIKeys []outputAPIKey `json:"api_keys"`
+}
+
+// responseCreatedAPIKey holds a newly minted API key, which is the only time Key is returned.
+type responseCreatedAPIKey struct {
+	APIKey outputAPIKey `json:"api_key"`
+	Key    string       `json:"key"`
+}
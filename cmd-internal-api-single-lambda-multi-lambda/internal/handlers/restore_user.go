@@ -0,0 +1,90 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/jha-captech/user-microservice/internal/models"
+	"github.com/jha-captech/user-microservice/internal/problem"
+	svc "github.com/jha-captech/user-microservice/internal/service"
+)
+
+type userRestorer interface {
+	RestoreUser(ctx context.Context, ID int, matchVersions []uint) (models.User, error)
+}
+
+// HandleRestoreUser is a Handler that restores a soft deleted user based on an ID. If the request
+// has an If-Match header, the user is only restored if its ETag matches.
+//
+// @Summary		Restore a deleted user by ID
+// @Description	Restore a soft deleted user by ID
+// @Tags		user
+// @Accept		json
+// @Produce		json
+// @Param		id						path		int		true	"User ID"
+// @Param		If-Match				header		string	false	"ETag the user must currently have"
+// @Success		200						{object}	handlers.responseUser
+// @Header		200						{string}	ETag	"Version of the restored user"
+// @Failure		400						{object}	problem.Problem
+// @Failure		404						{object}	problem.Problem
+// @Failure		409						{object}	problem.Problem
+// @Failure		412						{object}	problem.Problem
+// @Failure		500						{object}	problem.Problem
+// @Router		/user/{ID}/restore		[POST]
+func HandleRestoreUser(logger sLogger, service userRestorer) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		// setup
+		logger := requestLogger(r, logger)
+		ctx := r.Context()
+
+		// get and validate ID
+		idString := chi.URLParam(r, "ID")
+		ID, err := strconv.Atoi(idString)
+		if err != nil {
+			logger.Error("error getting ID", "error", err)
+			encodeProblem(w, r, logger, problem.New(http.StatusBadRequest, "Not a valid ID"))
+			return
+		}
+
+		// get precondition
+		matchVersions, ok := ifMatchVersions(r)
+		if !ok {
+			logger.Error("If-Match can not be met", "ID", ID, "If-Match", r.Header.Get("If-Match"))
+			encodeProblem(w, r, logger, problem.New(http.StatusPreconditionFailed, "User has been modified"))
+			return
+		}
+
+		// restore user
+		user, err := service.RestoreUser(ctx, ID, matchVersions)
+		if err != nil {
+			switch {
+			case errors.Is(err, svc.ErrUserNotFound):
+				logger.Error("Object does not exist", "ID", ID, "error", err)
+				encodeProblem(w, r, logger, problem.New(http.StatusNotFound, "Object does not exist"))
+			case errors.Is(err, svc.ErrUserNotDeleted):
+				logger.Error("Object is not deleted", "ID", ID, "error", err)
+				encodeProblem(w, r, logger, problem.New(http.StatusConflict, "User is not deleted"))
+			case errors.Is(err, svc.ErrVersionMismatch):
+				logger.Error("Object has been modified", "ID", ID, "error", err)
+				encodeProblem(w, r, logger, problem.New(http.StatusPreconditionFailed, "User has been modified"))
+			case errors.Is(err, svc.ErrUserIDConflict):
+				logger.Error("user_id already in use", "ID", ID, "error", err)
+				encodeProblem(w, r, logger, problem.New(http.StatusConflict, "user_id already in use"))
+			default:
+				logger.Error("error restoring object by ID", "ID", ID, "error", err)
+				encodeProblem(w, r, logger, problem.New(http.StatusInternalServerError, "Error restoring object"))
+			}
+			return
+		}
+
+		// return response
+		w.Header().Set("ETag", etag(user.Version))
+		userOut := mapOutput(user)
+		encodeResponse(w, logger, http.StatusOK, responseUser{
+			User: userOut,
+		})
+	}
+}
@@ -0,0 +1,142 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/stretchr/testify/assert"
+
+	serviceMock "github.com/jha-captech/user-microservice/internal/handlers/mock"
+	"github.com/jha-captech/user-microservice/internal/models"
+	"github.com/jha-captech/user-microservice/internal/problem"
+	"github.com/jha-captech/user-microservice/internal/service"
+)
+
+func TestHandleRestoreUser(t *testing.T) {
+	mockService := new(serviceMock.MockUserRestorer)
+	logger := slog.Default()
+	handler := HandleRestoreUser(logger, mockService)
+
+	restoredUser := models.User{ID: 1, FirstName: "John", LastName: "Doe", Role: "Customer", UserID: 1001, Version: 3}
+	userOut := mapOutput(restoredUser)
+
+	tests := map[string]struct {
+		mockCalled     bool
+		mockInput      []any
+		mockOutput     []any
+		requestIDParam string
+		ifMatch        string
+		expectedCode   int
+		expectedETag   string
+		expectedBody   string
+	}{
+		"valid request, user restored": {
+			mockCalled:     true,
+			mockInput:      []any{1, []uint(nil)},
+			mockOutput:     []any{restoredUser, nil},
+			requestIDParam: "1",
+			expectedCode:   http.StatusOK,
+			expectedETag:   `"3"`,
+			expectedBody:   toJSONString(responseUser{User: userOut}),
+		},
+		"If-Match matches, user restored": {
+			mockCalled:     true,
+			mockInput:      []any{1, []uint{2}},
+			mockOutput:     []any{restoredUser, nil},
+			requestIDParam: "1",
+			ifMatch:        `"2"`,
+			expectedCode:   http.StatusOK,
+			expectedETag:   `"3"`,
+			expectedBody:   toJSONString(responseUser{User: userOut}),
+		},
+		"If-Match does not match": {
+			mockCalled:     true,
+			mockInput:      []any{1, []uint{1}},
+			mockOutput:     []any{models.User{}, fmt.Errorf("[in RestoreUser]: %w", service.ErrVersionMismatch)},
+			requestIDParam: "1",
+			ifMatch:        `"1"`,
+			expectedCode:   http.StatusPreconditionFailed,
+			expectedBody:   toJSONString(problem.New(http.StatusPreconditionFailed, "User has been modified").WithInstance("/api/user/1/restore")),
+		},
+		"invalid ID": {
+			mockCalled:     false,
+			requestIDParam: "abc",
+			expectedCode:   http.StatusBadRequest,
+			expectedBody:   toJSONString(problem.New(http.StatusBadRequest, "Not a valid ID").WithInstance("/api/user/abc/restore")),
+		},
+		"user does not exist": {
+			mockCalled:     true,
+			mockInput:      []any{2, []uint(nil)},
+			mockOutput:     []any{models.User{}, fmt.Errorf("[in RestoreUser]: %w", service.ErrUserNotFound)},
+			requestIDParam: "2",
+			expectedCode:   http.StatusNotFound,
+			expectedBody:   toJSONString(problem.New(http.StatusNotFound, "Object does not exist").WithInstance("/api/user/2/restore")),
+		},
+		"user is not deleted": {
+			mockCalled:     true,
+			mockInput:      []any{1, []uint(nil)},
+			mockOutput:     []any{models.User{}, fmt.Errorf("[in RestoreUser]: %w", service.ErrUserNotDeleted)},
+			requestIDParam: "1",
+			expectedCode:   http.StatusConflict,
+			expectedBody:   toJSONString(problem.New(http.StatusConflict, "User is not deleted").WithInstance("/api/user/1/restore")),
+		},
+		"user_id already in use": {
+			mockCalled:     true,
+			mockInput:      []any{1, []uint(nil)},
+			mockOutput:     []any{models.User{}, fmt.Errorf("[in RestoreUser]: %w", service.ErrUserIDConflict)},
+			requestIDParam: "1",
+			expectedCode:   http.StatusConflict,
+			expectedBody:   toJSONString(problem.New(http.StatusConflict, "user_id already in use").WithInstance("/api/user/1/restore")),
+		},
+		"error restoring user": {
+			mockCalled:     true,
+			mockInput:      []any{1, []uint(nil)},
+			mockOutput:     []any{models.User{}, errors.New("restore error")},
+			requestIDParam: "1",
+			expectedCode:   http.StatusInternalServerError,
+			expectedBody:   toJSONString(problem.New(http.StatusInternalServerError, "Error restoring object").WithInstance("/api/user/1/restore")),
+		},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			req, err := http.NewRequest(http.MethodPost, "/api/user/"+tc.requestIDParam+"/restore", nil)
+			assert.NoError(t, err)
+			if tc.ifMatch != "" {
+				req.Header.Set("If-Match", tc.ifMatch)
+			}
+
+			// Add chi URLParam
+			rctx := chi.NewRouteContext()
+			rctx.URLParams.Add("ID", tc.requestIDParam)
+			ctx := context.WithValue(req.Context(), chi.RouteCtxKey, rctx)
+			req = req.WithContext(ctx)
+
+			if tc.mockCalled {
+				mockService.
+					On("RestoreUser", append([]any{ctx}, tc.mockInput...)...).
+					Return(tc.mockOutput...).
+					Once()
+			}
+
+			rr := httptest.NewRecorder()
+			handler.ServeHTTP(rr, req)
+
+			assert.Equal(t, tc.expectedCode, rr.Code, "Wrong code received")
+			assert.Equal(t, tc.expectedETag, rr.Header().Get("ETag"), "Wrong ETag")
+			assert.JSONEq(t, tc.expectedBody, rr.Body.String(), "Wrong response body")
+
+			if tc.mockCalled {
+				mockService.AssertExpectations(t)
+			} else {
+				mockService.AssertNotCalled(t, "RestoreUser")
+			}
+		})
+	}
+}
@@ -0,0 +1,69 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/jha-captech/user-microservice/internal/models"
+	"github.com/jha-captech/user-microservice/internal/problem"
+	svc "github.com/jha-captech/user-microservice/internal/service"
+)
+
+type apiKeyRevoker interface {
+	RevokeKey(ctx context.Context, ID int) (models.APIKey, error)
+}
+
+// HandleRevokeAPIKey is a Handler that revokes an API key based on an ID. The key is kept so its
+// last use can still be looked up, but it is refused from then on.
+//
+// @Summary		Revoke an API key by ID
+// @Description	Revoke an API key by ID
+// @Tags		api-key
+// @Accept		json
+// @Produce		json
+// @Param		id				path		int		true	"API Key ID"
+// @Param		X-Admin-Token	header		string	true	"Admin token"
+// @Success		200				{object}	handlers.responseAPIKey
+// @Failure		400				{object}	problem.Problem
+// @Failure		403				{object}	problem.Problem
+// @Failure		404				{object}	problem.Problem
+// @Failure		500				{object}	problem.Problem
+// @Router		/api-key/{ID}	[DELETE]
+func HandleRevokeAPIKey(logger sLogger, service apiKeyRevoker) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		// setup
+		logger := requestLogger(r, logger)
+		ctx := r.Context()
+
+		// get and validate ID
+		idString := chi.URLParam(r, "ID")
+		ID, err := strconv.Atoi(idString)
+		if err != nil {
+			logger.Error("error getting ID", "error", err)
+			encodeProblem(w, r, logger, problem.New(http.StatusBadRequest, "Not a valid ID"))
+			return
+		}
+
+		// revoke key
+		key, err := service.RevokeKey(ctx, ID)
+		if err != nil {
+			switch {
+			case errors.Is(err, svc.ErrAPIKeyNotFound):
+				logger.Error("Object does not exist", "ID", ID, "error", err)
+				encodeProblem(w, r, logger, problem.New(http.StatusNotFound, "Object does not exist"))
+			default:
+				logger.Error("error revoking api key", "ID", ID, "error", err)
+				encodeProblem(w, r, logger, problem.New(http.StatusInternalServerError, "Error revoking object"))
+			}
+			return
+		}
+
+		// return response
+		encodeResponse(w, logger, http.StatusOK, responseAPIKey{
+			APIKey: mapAPIKeyOutput(key),
+		})
+	}
+}
@@ -0,0 +1,105 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/jha-captech/user-microservice/internal/models"
+	"github.com/jha-captech/user-microservice/internal/problem"
+	"github.com/jha-captech/user-microservice/internal/service"
+	"github.com/stretchr/testify/assert"
+
+	serviceMock "github.com/jha-captech/user-microservice/internal/handlers/mock"
+)
+
+func TestHandleRevokeAPIKey(t *testing.T) {
+	mockService := new(serviceMock.MockApiKeyRevoker)
+	logger := slog.Default()
+	handler := HandleRevokeAPIKey(logger, mockService)
+
+	createdAt := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	revokedAt := time.Date(2024, 5, 2, 12, 0, 0, 0, time.UTC)
+	revoked := models.APIKey{
+		ID: 1, Name: "nightly export", Prefix: "umk_abcdefgh", Scopes: []models.APIKeyScope{models.ScopeUsersRead},
+		RevokedAt: &revokedAt, CreatedAt: createdAt,
+	}
+
+	tests := map[string]struct {
+		mockCalled     bool
+		mockInput      []any
+		mockOutput     []any
+		requestIDParam string
+		expectedCode   int
+		expectedBody   string
+	}{
+		"key revoked": {
+			mockCalled:     true,
+			mockInput:      []any{1},
+			mockOutput:     []any{revoked, nil},
+			requestIDParam: "1",
+			expectedCode:   http.StatusOK,
+			expectedBody:   toJSONString(responseAPIKey{APIKey: mapAPIKeyOutput(revoked)}),
+		},
+		"invalid ID": {
+			mockCalled:     false,
+			requestIDParam: "abc",
+			expectedCode:   http.StatusBadRequest,
+			expectedBody:   toJSONString(problem.New(http.StatusBadRequest, "Not a valid ID").WithInstance("/api/api-key/abc")),
+		},
+		"key not found": {
+			mockCalled:     true,
+			mockInput:      []any{2},
+			mockOutput:     []any{models.APIKey{}, fmt.Errorf("[in RevokeKey]: %w", service.ErrAPIKeyNotFound)},
+			requestIDParam: "2",
+			expectedCode:   http.StatusNotFound,
+			expectedBody:   toJSONString(problem.New(http.StatusNotFound, "Object does not exist").WithInstance("/api/api-key/2")),
+		},
+		"error revoking key": {
+			mockCalled:     true,
+			mockInput:      []any{1},
+			mockOutput:     []any{models.APIKey{}, errors.New("revoke error")},
+			requestIDParam: "1",
+			expectedCode:   http.StatusInternalServerError,
+			expectedBody:   toJSONString(problem.New(http.StatusInternalServerError, "Error revoking object").WithInstance("/api/api-key/1")),
+		},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			req, err := http.NewRequest(http.MethodDelete, "/api/api-key/"+tc.requestIDParam, nil)
+			assert.NoError(t, err)
+
+			// Add chi URLParam
+			rctx := chi.NewRouteContext()
+			rctx.URLParams.Add("ID", tc.requestIDParam)
+			ctx := context.WithValue(req.Context(), chi.RouteCtxKey, rctx)
+			req = req.WithContext(ctx)
+
+			if tc.mockCalled {
+				mockService.
+					On("RevokeKey", append([]any{ctx}, tc.mockInput...)...).
+					Return(tc.mockOutput...).
+					Once()
+			}
+
+			rr := httptest.NewRecorder()
+			handler.ServeHTTP(rr, req)
+
+			assert.Equal(t, tc.expectedCode, rr.Code, "Wrong code received")
+			assert.JSONEq(t, tc.expectedBody, rr.Body.String(), "Wrong response body")
+
+			if tc.mockCalled {
+				mockService.AssertExpectations(t)
+			} else {
+				mockService.AssertNotCalled(t, "RevokeKey")
+			}
+		})
+	}
+}
@@ -2,18 +2,26 @@ package handlers
 
 import (
 	"context"
+	"errors"
 	"net/http"
 	"strconv"
 
 	"github.com/go-chi/chi/v5"
 	"github.com/jha-captech/user-microservice/internal/models"
+	"github.com/jha-captech/user-microservice/internal/policy"
+	"github.com/jha-captech/user-microservice/internal/problem"
+	svc "github.com/jha-captech/user-microservice/internal/service"
 )
 
 type userUpdater interface {
-	UpdateUser(ctx context.Context, ID int, user models.User) (models.User, error)
+	FetchUser(ctx context.Context, ID int, includeDeleted bool) (models.User, error)
+	UpdateUser(ctx context.Context, ID int, user models.User, matchVersions []uint) (models.User, error)
 }
 
 // HandleUpdateUser is a Handler that updates a user based on a user object from the request body.
+// If the request has an If-Match header, the user is only updated if its ETag matches. Changes to
+// fields that are read-only for the principal of the request are refused, and the user is then only
+// updated if it is still at the version the changes were checked against.
 //
 // @Summary		Update a user by ID
 // @Description	Update a user by ID
@@ -22,13 +30,20 @@ type userUpdater interface {
 // @Produce		json
 // @Param		id			path		int	true						"User ID"
 // @Param		user		body		handlers.inputUser		true	"User Object"
+// @Param		If-Match	header		string	false	"ETag the user must currently have"
 // @Success		200			{object}	handlers.responseUser
-// @Failure		500			{object}	handlers.responseErr
-// @Failure		422			{object}	handlers.responseErr
+// @Header		200			{string}	ETag	"Version of the updated user"
+// @Failure		400			{object}	problem.Problem
+// @Failure		403			{object}	problem.Problem
+// @Failure		404			{object}	problem.Problem
+// @Failure		409			{object}	problem.Problem
+// @Failure		412			{object}	problem.Problem
+// @Failure		500			{object}	problem.Problem
 // @Router		/user/{ID}	[PUT]
 func HandleUpdateUser(logger sLogger, service userUpdater) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		// setup
+		logger := requestLogger(r, logger)
 		ctx := r.Context()
 
 		// get and validate ID
@@ -36,9 +51,7 @@ func HandleUpdateUser(logger sLogger, service userUpdater) http.HandlerFunc {
 		ID, err := strconv.Atoi(idString)
 		if err != nil {
 			logger.Error("error getting ID", "error", err)
-			encodeResponse(w, logger, http.StatusBadRequest, responseErr{
-				Error: "Not a valid ID",
-			})
+			encodeProblem(w, r, logger, problem.New(http.StatusBadRequest, "Not a valid ID"))
 			return
 		}
 
@@ -48,29 +61,73 @@ func HandleUpdateUser(logger sLogger, service userUpdater) http.HandlerFunc {
 			switch {
 			case len(problems) > 0:
 				logger.Error("Problems validating input", "error", err, "problems", problems)
-				encodeResponse(w, logger, http.StatusBadRequest, responseErr{
-					ValidationErrors: problems,
-				})
+				encodeProblem(w, r, logger, problem.
+					New(http.StatusBadRequest, "One or more fields in the request body are invalid").
+					WithFieldErrors(problems),
+				)
 			default:
 				logger.Error("BodyParser error", "error", err)
-				encodeResponse(w, logger, http.StatusBadRequest, responseErr{
-					Error: "missing values or malformed body",
-				})
+				encodeProblem(w, r, logger, problem.New(http.StatusBadRequest, "missing values or malformed body"))
 			}
 			return
 		}
 
+		// get precondition
+		matchVersions, ok := ifMatchVersions(r)
+		if !ok {
+			logger.Error("If-Match can not be met", "ID", ID, "If-Match", r.Header.Get("If-Match"))
+			encodeProblem(w, r, logger, problem.New(http.StatusPreconditionFailed, "User has been modified"))
+			return
+		}
+
+		// refuse changes to read-only fields
+		if principal, ok := policy.FromContext(ctx); ok && principal.HasReadOnlyFields() {
+			current, err := service.FetchUser(ctx, ID, false)
+			if err != nil {
+				switch {
+				case errors.Is(err, svc.ErrUserNotFound):
+					logger.Error("Object does not exist", "ID", ID, "error", err)
+					encodeProblem(w, r, logger, problem.New(http.StatusNotFound, "Object does not exist"))
+				default:
+					logger.Error("error getting object from database", "error", err)
+					encodeProblem(w, r, logger, problem.New(http.StatusInternalServerError, "Error updating object"))
+				}
+				return
+			}
+			if err := authorizeChanges(ctx, userChanges(current, userIn)); err != nil {
+				logger.Error("Request forbidden by policy", "ID", ID, "user_id", principal.UserID, "error", err)
+				encodeProblem(w, r, logger, forbiddenProblem(err))
+				return
+			}
+
+			// the changes were checked against current, so it must not change before the update
+			if len(matchVersions) == 0 {
+				matchVersions = []uint{current.Version}
+			}
+		}
+
 		// update object in database
-		user, err := service.UpdateUser(ctx, ID, userIn)
+		user, err := service.UpdateUser(ctx, ID, userIn, matchVersions)
 		if err != nil {
-			logger.Error("error updating object in database", "error", err)
-			encodeResponse(w, logger, http.StatusInternalServerError, responseErr{
-				Error: "Error updating object",
-			})
+			switch {
+			case errors.Is(err, svc.ErrUserNotFound):
+				logger.Error("Object does not exist", "ID", ID, "error", err)
+				encodeProblem(w, r, logger, problem.New(http.StatusNotFound, "Object does not exist"))
+			case errors.Is(err, svc.ErrVersionMismatch):
+				logger.Error("Object has been modified", "ID", ID, "error", err)
+				encodeProblem(w, r, logger, problem.New(http.StatusPreconditionFailed, "User has been modified"))
+			case errors.Is(err, svc.ErrUserIDConflict):
+				logger.Error("user_id already in use", "ID", ID, "error", err)
+				encodeProblem(w, r, logger, problem.New(http.StatusConflict, "user_id already in use"))
+			default:
+				logger.Error("error updating object in database", "error", err)
+				encodeProblem(w, r, logger, problem.New(http.StatusInternalServerError, "Error updating object"))
+			}
 			return
 		}
 
 		// return response
+		w.Header().Set("ETag", etag(user.Version))
 		userOut := mapOutput(user)
 		encodeResponse(w, logger, http.StatusOK, responseUser{
 			User: userOut,
@@ -3,6 +3,7 @@ package handlers
 import (
 	"context"
 	"errors"
+	"fmt"
 	"log/slog"
 	"net/http"
 	"net/http/httptest"
@@ -14,6 +15,9 @@ import (
 
 	serviceMock "github.com/jha-captech/user-microservice/internal/handlers/mock"
 	"github.com/jha-captech/user-microservice/internal/models"
+	"github.com/jha-captech/user-microservice/internal/policy"
+	"github.com/jha-captech/user-microservice/internal/problem"
+	"github.com/jha-captech/user-microservice/internal/service"
 )
 
 func TestHandleUpdateUser(t *testing.T) {
@@ -22,8 +26,9 @@ func TestHandleUpdateUser(t *testing.T) {
 	handler := HandleUpdateUser(logger, mockService)
 
 	user := models.User{FirstName: "John", LastName: "Doe", Role: "Customer", UserID: 1001}
+	updatedUser := models.User{ID: 1, FirstName: "John", LastName: "Doe", Role: "Customer", UserID: 1001, Version: 4}
 	userIn := inputUser{FirstName: "John", LastName: "Doe", Role: "Customer", UserID: 1001}
-	userOut := mapOutput(user)
+	userOut := mapOutput(updatedUser)
 
 	tests := map[string]struct {
 		mockCalled     bool
@@ -31,18 +36,61 @@ func TestHandleUpdateUser(t *testing.T) {
 		mockOutput     []any
 		requestIDParam string
 		requestBody    string
+		ifMatch        string
 		expectedCode   int
+		expectedETag   string
 		expectedBody   string
 	}{
 		"valid request, user updated": {
 			mockCalled:     true,
-			mockInput:      []any{1, user},
-			mockOutput:     []any{user, nil},
+			mockInput:      []any{1, user, []uint(nil)},
+			mockOutput:     []any{updatedUser, nil},
 			requestIDParam: "1",
 			requestBody:    toJSONString(userIn),
 			expectedCode:   http.StatusOK,
+			expectedETag:   `"4"`,
 			expectedBody:   toJSONString(responseUser{User: userOut}),
 		},
+		"If-Match matches, user updated": {
+			mockCalled:     true,
+			mockInput:      []any{1, user, []uint{2, 3}},
+			mockOutput:     []any{updatedUser, nil},
+			requestIDParam: "1",
+			requestBody:    toJSONString(userIn),
+			ifMatch:        `"2", W/"1", "3"`,
+			expectedCode:   http.StatusOK,
+			expectedETag:   `"4"`,
+			expectedBody:   toJSONString(responseUser{User: userOut}),
+		},
+		"If-Match is any, user updated": {
+			mockCalled:     true,
+			mockInput:      []any{1, user, []uint(nil)},
+			mockOutput:     []any{updatedUser, nil},
+			requestIDParam: "1",
+			requestBody:    toJSONString(userIn),
+			ifMatch:        "*",
+			expectedCode:   http.StatusOK,
+			expectedETag:   `"4"`,
+			expectedBody:   toJSONString(responseUser{User: userOut}),
+		},
+		"If-Match does not match": {
+			mockCalled:     true,
+			mockInput:      []any{1, user, []uint{2}},
+			mockOutput:     []any{models.User{}, fmt.Errorf("[in UpdateUser]: %w", service.ErrVersionMismatch)},
+			requestIDParam: "1",
+			requestBody:    toJSONString(userIn),
+			ifMatch:        `"2"`,
+			expectedCode:   http.StatusPreconditionFailed,
+			expectedBody:   toJSONString(problem.New(http.StatusPreconditionFailed, "User has been modified").WithInstance("/api/user/1")),
+		},
+		"If-Match has only weak entity tags": {
+			mockCalled:     false,
+			requestIDParam: "1",
+			requestBody:    toJSONString(userIn),
+			ifMatch:        `W/"2"`,
+			expectedCode:   http.StatusPreconditionFailed,
+			expectedBody:   toJSONString(problem.New(http.StatusPreconditionFailed, "User has been modified").WithInstance("/api/user/1")),
+		},
 		"invalid request body": {
 			mockCalled:     false,
 			mockInput:      nil,
@@ -50,22 +98,42 @@ func TestHandleUpdateUser(t *testing.T) {
 			requestIDParam: "1",
 			requestBody:    `{"FirstName":"John","LastName":"Doe","Role":"Admin"}`,
 			expectedCode:   http.StatusBadRequest,
-			expectedBody: toJSONString(responseErr{
-				ValidationErrors: map[string]string{
+			expectedBody: toJSONString(problem.
+				New(http.StatusBadRequest, "One or more fields in the request body are invalid").
+				WithInstance("/api/user/1").
+				WithFieldErrors(map[string]string{
 					"first_name": "must not be blank",
 					"role":       "must be 'Customer' or 'Employee'",
 					"user_id":    "must be more than 0",
-				},
-			}),
+				}),
+			),
 		},
 		"error creating user": {
 			mockCalled:     true,
-			mockInput:      []any{1, user},
+			mockInput:      []any{1, user, []uint(nil)},
 			mockOutput:     []any{models.User{}, errors.New("creation error")},
 			requestIDParam: "1",
 			requestBody:    toJSONString(userIn),
 			expectedCode:   http.StatusInternalServerError,
-			expectedBody:   toJSONString(responseErr{Error: "Error updating object"}),
+			expectedBody:   toJSONString(problem.New(http.StatusInternalServerError, "Error updating object").WithInstance("/api/user/1")),
+		},
+		"user does not exist": {
+			mockCalled:     true,
+			mockInput:      []any{2, user, []uint(nil)},
+			mockOutput:     []any{models.User{}, fmt.Errorf("[in UpdateUser]: %w", service.ErrUserNotFound)},
+			requestIDParam: "2",
+			requestBody:    toJSONString(userIn),
+			expectedCode:   http.StatusNotFound,
+			expectedBody:   toJSONString(problem.New(http.StatusNotFound, "Object does not exist").WithInstance("/api/user/2")),
+		},
+		"user_id already in use": {
+			mockCalled:     true,
+			mockInput:      []any{1, user, []uint(nil)},
+			mockOutput:     []any{models.User{}, fmt.Errorf("[in UpdateUser]: %w", service.ErrUserIDConflict)},
+			requestIDParam: "1",
+			requestBody:    toJSONString(userIn),
+			expectedCode:   http.StatusConflict,
+			expectedBody:   toJSONString(problem.New(http.StatusConflict, "user_id already in use").WithInstance("/api/user/1")),
 		},
 	}
 
@@ -73,6 +141,9 @@ func TestHandleUpdateUser(t *testing.T) {
 		t.Run(name, func(t *testing.T) {
 			req, err := http.NewRequest(http.MethodPut, "/api/user/"+tc.requestIDParam, strings.NewReader(tc.requestBody))
 			assert.NoError(t, err)
+			if tc.ifMatch != "" {
+				req.Header.Set("If-Match", tc.ifMatch)
+			}
 
 			// Add chi URLParam
 			rctx := chi.NewRouteContext()
@@ -91,6 +162,7 @@ func TestHandleUpdateUser(t *testing.T) {
 			handler.ServeHTTP(rr, req)
 
 			assert.Equal(t, tc.expectedCode, rr.Code, "Wrong code received")
+			assert.Equal(t, tc.expectedETag, rr.Header().Get("ETag"), "Wrong ETag")
 			assert.JSONEq(t, tc.expectedBody, rr.Body.String(), "Wrong response body")
 
 			if tc.mockCalled {
@@ -101,3 +173,103 @@ func TestHandleUpdateUser(t *testing.T) {
 		})
 	}
 }
+
+func TestHandleUpdateUserReadOnlyFields(t *testing.T) {
+	current := models.User{ID: 1, FirstName: "John", LastName: "Doe", Role: "Customer", UserID: 1001, Version: 3}
+	updatedUser := models.User{ID: 1, FirstName: "Johnny", LastName: "Doe", Role: "Customer", UserID: 1001, Version: 4}
+	customer := policy.Default().Principal(1001, "Customer")
+
+	tests := map[string]struct {
+		principal        policy.Principal
+		requestBody      inputUser
+		updateCalled     bool
+		updateErr        error
+		expectedCode     int
+		expectedBody     string
+		expectedFetch    bool
+		expectedUpdate   models.User
+		expectedVersions []uint
+	}{
+		"customer changes own name": {
+			principal:        customer,
+			requestBody:      inputUser{FirstName: "Johnny", LastName: "Doe", Role: "Customer", UserID: 1001},
+			updateCalled:     true,
+			expectedCode:     http.StatusOK,
+			expectedBody:     toJSONString(responseUser{User: mapOutput(updatedUser)}),
+			expectedFetch:    true,
+			expectedUpdate:   models.User{FirstName: "Johnny", LastName: "Doe", Role: "Customer", UserID: 1001},
+			expectedVersions: []uint{3},
+		},
+		"customer changes own name after a concurrent change": {
+			principal:        customer,
+			requestBody:      inputUser{FirstName: "Johnny", LastName: "Doe", Role: "Customer", UserID: 1001},
+			updateCalled:     true,
+			updateErr:        fmt.Errorf("[in UpdateUser]: %w", service.ErrVersionMismatch),
+			expectedCode:     http.StatusPreconditionFailed,
+			expectedBody:     toJSONString(problem.New(http.StatusPreconditionFailed, "User has been modified").WithInstance("/api/user/1")),
+			expectedFetch:    true,
+			expectedUpdate:   models.User{FirstName: "Johnny", LastName: "Doe", Role: "Customer", UserID: 1001},
+			expectedVersions: []uint{3},
+		},
+		"customer changes own role": {
+			principal:     customer,
+			requestBody:   inputUser{FirstName: "John", LastName: "Doe", Role: "Employee", UserID: 1001},
+			expectedCode:  http.StatusForbidden,
+			expectedBody:  toJSONString(problem.New(http.StatusForbidden, "You are not allowed to change role").WithInstance("/api/user/1")),
+			expectedFetch: true,
+		},
+		"customer changes own user_id": {
+			principal:     customer,
+			requestBody:   inputUser{FirstName: "John", LastName: "Doe", Role: "Customer", UserID: 1002},
+			expectedCode:  http.StatusForbidden,
+			expectedBody:  toJSONString(problem.New(http.StatusForbidden, "You are not allowed to change user_id").WithInstance("/api/user/1")),
+			expectedFetch: true,
+		},
+		"employee changes role": {
+			principal:      policy.Default().Principal(2001, "Employee"),
+			requestBody:    inputUser{FirstName: "Johnny", LastName: "Doe", Role: "Customer", UserID: 1001},
+			updateCalled:   true,
+			expectedCode:   http.StatusOK,
+			expectedBody:   toJSONString(responseUser{User: mapOutput(updatedUser)}),
+			expectedUpdate: models.User{FirstName: "Johnny", LastName: "Doe", Role: "Customer", UserID: 1001},
+		},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			mockService := new(serviceMock.MockUserUpdater)
+
+			req, err := http.NewRequest(http.MethodPut, "/api/user/1", strings.NewReader(toJSONString(tc.requestBody)))
+			assert.NoError(t, err)
+
+			rctx := chi.NewRouteContext()
+			rctx.URLParams.Add("ID", "1")
+			ctx := policy.WithPrincipal(context.WithValue(req.Context(), chi.RouteCtxKey, rctx), tc.principal)
+			req = req.WithContext(ctx)
+
+			if tc.expectedFetch {
+				mockService.On("FetchUser", ctx, 1, false).Return(current, nil).Once()
+			}
+			if tc.updateCalled {
+				output := updatedUser
+				if tc.updateErr != nil {
+					output = models.User{}
+				}
+				mockService.On("UpdateUser", ctx, 1, tc.expectedUpdate, tc.expectedVersions).Return(output, tc.updateErr).Once()
+			}
+
+			rr := httptest.NewRecorder()
+			HandleUpdateUser(slog.Default(), mockService).ServeHTTP(rr, req)
+
+			assert.Equal(t, tc.expectedCode, rr.Code, "Wrong code received")
+			assert.JSONEq(t, tc.expectedBody, rr.Body.String(), "Wrong response body")
+			mockService.AssertExpectations(t)
+			if !tc.expectedFetch {
+				mockService.AssertNotCalled(t, "FetchUser")
+			}
+			if !tc.updateCalled {
+				mockService.AssertNotCalled(t, "UpdateUser")
+			}
+		})
+	}
+}
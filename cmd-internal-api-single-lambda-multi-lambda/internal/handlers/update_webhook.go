@@ -0,0 +1,92 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/jha-captech/user-microservice/internal/models"
+	"github.com/jha-captech/user-microservice/internal/problem"
+	svc "github.com/jha-captech/user-microservice/internal/service"
+)
+
+type webhookUpdater interface {
+	UpdateSubscription(
+		ctx context.Context,
+		ID int,
+		subscription models.WebhookSubscription,
+	) (models.WebhookSubscription, error)
+}
+
+// HandleUpdateWebhook is a Handler that replaces the URL, event filter and secret of a webhook
+// subscription based on a subscription object from the request body. Pending deliveries are made to
+// the new URL and signed with the new secret.
+//
+// @Summary		Update a webhook subscription by ID
+// @Description	Update a webhook subscription by ID
+// @Tags		webhook
+// @Accept		json
+// @Produce		json
+// @Param		id				path		int									true	"Subscription ID"
+// @Param		webhook			body		handlers.inputWebhookSubscription	true	"Subscription Object"
+// @Param		X-Admin-Token	header		string								true	"Admin token"
+// @Success		200				{object}	handlers.responseWebhook
+// @Failure		400				{object}	problem.Problem
+// @Failure		403				{object}	problem.Problem
+// @Failure		404				{object}	problem.Problem
+// @Failure		500				{object}	problem.Problem
+// @Router		/webhook/{ID}	[PUT]
+func HandleUpdateWebhook(logger sLogger, service webhookUpdater) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		// setup
+		logger := requestLogger(r, logger)
+		ctx := r.Context()
+
+		// get and validate ID
+		idString := chi.URLParam(r, "ID")
+		ID, err := strconv.Atoi(idString)
+		if err != nil {
+			logger.Error("error getting ID", "error", err)
+			encodeProblem(w, r, logger, problem.New(http.StatusBadRequest, "Not a valid ID"))
+			return
+		}
+
+		// get and validate body as object
+		subscriptionIn, problems, err := decodeValidateBody[inputWebhookSubscription, models.WebhookSubscription](r)
+		if err != nil {
+			switch {
+			case len(problems) > 0:
+				logger.Error("Problems validating input", "error", err, "problems", problems)
+				encodeProblem(w, r, logger, problem.
+					New(http.StatusBadRequest, "One or more fields in the request body are invalid").
+					WithFieldErrors(problems),
+				)
+			default:
+				logger.Error("BodyParser error", "error", err)
+				encodeProblem(w, r, logger, problem.New(http.StatusBadRequest, "missing values or malformed body"))
+			}
+			return
+		}
+
+		// update object in database
+		subscription, err := service.UpdateSubscription(ctx, ID, subscriptionIn)
+		if err != nil {
+			switch {
+			case errors.Is(err, svc.ErrSubscriptionNotFound):
+				logger.Error("Object does not exist", "ID", ID, "error", err)
+				encodeProblem(w, r, logger, problem.New(http.StatusNotFound, "Object does not exist"))
+			default:
+				logger.Error("error updating webhook subscription", "ID", ID, "error", err)
+				encodeProblem(w, r, logger, problem.New(http.StatusInternalServerError, "Error updating object"))
+			}
+			return
+		}
+
+		// return response
+		encodeResponse(w, logger, http.StatusOK, responseWebhook{
+			Webhook: mapWebhookOutput(subscription),
+		})
+	}
+}
@@ -0,0 +1,134 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/jha-captech/user-microservice/internal/models"
+	"github.com/jha-captech/user-microservice/internal/problem"
+	"github.com/jha-captech/user-microservice/internal/service"
+	"github.com/stretchr/testify/assert"
+
+	serviceMock "github.com/jha-captech/user-microservice/internal/handlers/mock"
+)
+
+func TestHandleUpdateWebhook(t *testing.T) {
+	mockService := new(serviceMock.MockWebhookUpdater)
+	logger := slog.Default()
+	handler := HandleUpdateWebhook(logger, mockService)
+
+	createdAt := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	updatedAt := time.Date(2024, 5, 2, 12, 0, 0, 0, time.UTC)
+	subscriptionIn := inputWebhookSubscription{
+		URL:    "https://example.com/v2/hook",
+		Events: []string{"user.updated"},
+		Secret: "fedcba9876543210",
+	}
+	subscription := models.WebhookSubscription{
+		URL:    "https://example.com/v2/hook",
+		Events: []models.EventType{models.EventUserUpdated},
+		Secret: "fedcba9876543210",
+	}
+	updated := subscription
+	updated.ID = 1
+	updated.CreatedAt = createdAt
+	updated.UpdatedAt = updatedAt
+
+	tests := map[string]struct {
+		mockCalled     bool
+		mockInput      []any
+		mockOutput     []any
+		requestIDParam string
+		requestBody    string
+		expectedCode   int
+		expectedBody   string
+	}{
+		"valid request, subscription updated": {
+			mockCalled:     true,
+			mockInput:      []any{1, subscription},
+			mockOutput:     []any{updated, nil},
+			requestIDParam: "1",
+			requestBody:    toJSONString(subscriptionIn),
+			expectedCode:   http.StatusOK,
+			expectedBody: toJSONString(responseWebhook{Webhook: outputWebhookSubscription{
+				ID: 1, URL: "https://example.com/v2/hook", Events: []string{"user.updated"}, CreatedAt: createdAt, UpdatedAt: updatedAt,
+			}}),
+		},
+		"invalid ID": {
+			mockCalled:     false,
+			requestIDParam: "abc",
+			requestBody:    toJSONString(subscriptionIn),
+			expectedCode:   http.StatusBadRequest,
+			expectedBody:   toJSONString(problem.New(http.StatusBadRequest, "Not a valid ID").WithInstance("/api/webhook/abc")),
+		},
+		"invalid request body": {
+			mockCalled:     false,
+			requestIDParam: "1",
+			requestBody:    `{"url": "/hook", "secret": "0123456789abcdef"}`,
+			expectedCode:   http.StatusBadRequest,
+			expectedBody: toJSONString(problem.
+				New(http.StatusBadRequest, "One or more fields in the request body are invalid").
+				WithInstance("/api/webhook/1").
+				WithFieldErrors(map[string]string{"url": "must be an absolute http or https URL"}),
+			),
+		},
+		"subscription not found": {
+			mockCalled:     true,
+			mockInput:      []any{2, subscription},
+			mockOutput:     []any{models.WebhookSubscription{}, fmt.Errorf("[in UpdateSubscription]: %w", service.ErrSubscriptionNotFound)},
+			requestIDParam: "2",
+			requestBody:    toJSONString(subscriptionIn),
+			expectedCode:   http.StatusNotFound,
+			expectedBody:   toJSONString(problem.New(http.StatusNotFound, "Object does not exist").WithInstance("/api/webhook/2")),
+		},
+		"error updating subscription": {
+			mockCalled:     true,
+			mockInput:      []any{1, subscription},
+			mockOutput:     []any{models.WebhookSubscription{}, errors.New("update error")},
+			requestIDParam: "1",
+			requestBody:    toJSONString(subscriptionIn),
+			expectedCode:   http.StatusInternalServerError,
+			expectedBody:   toJSONString(problem.New(http.StatusInternalServerError, "Error updating object").WithInstance("/api/webhook/1")),
+		},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			req, err := http.NewRequest(http.MethodPut, "/api/webhook/"+tc.requestIDParam, strings.NewReader(tc.requestBody))
+			assert.NoError(t, err)
+
+			// Add chi URLParam
+			rctx := chi.NewRouteContext()
+			rctx.URLParams.Add("ID", tc.requestIDParam)
+			ctx := context.WithValue(req.Context(), chi.RouteCtxKey, rctx)
+			req = req.WithContext(ctx)
+
+			if tc.mockCalled {
+				mockService.
+					On("UpdateSubscription", append([]any{ctx}, tc.mockInput...)...).
+					Return(tc.mockOutput...).
+					Once()
+			}
+
+			rr := httptest.NewRecorder()
+			handler.ServeHTTP(rr, req)
+
+			assert.Equal(t, tc.expectedCode, rr.Code, "Wrong code received")
+			assert.JSONEq(t, tc.expectedBody, rr.Body.String(), "Wrong response body")
+
+			if tc.mockCalled {
+				mockService.AssertExpectations(t)
+			} else {
+				mockService.AssertNotCalled(t, "UpdateSubscription")
+			}
+		})
+	}
+}
@@ -0,0 +1,161 @@
+package handlers
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/jha-captech/user-microservice/internal/problem"
+	"github.com/jha-captech/user-microservice/internal/stream"
+)
+
+// eventRetry is the reconnection delay in milliseconds sent to SSE clients.
+const eventRetry = 3000
+
+type userEventSubscriber interface {
+	Subscribe(filter stream.Filter, after int64) (*stream.Subscription, func())
+}
+
+// HandleUserEvents is a Handler that streams changes to users as Server-Sent Events until the client
+// disconnects. Every event has the ID of the change, the event type as its name and the user as its
+// data. A client that reconnects with a Last-Event-ID header, or the last_event_id query parameter,
+// is first sent the changes it missed from the replay buffer. If changes it missed are no longer
+// buffered, it is sent a reset event instead, after which it should reload the users. A comment is
+// sent every heartbeat to keep the connection open.
+//
+// @Summary		Stream changes to users
+// @Description	Stream user change events as Server-Sent Events
+// @Tags		user
+// @Produce		text/event-stream
+// @Param		role			query		string	false	"Only stream changes to users with this role before or after the change"	Enums(Customer, Employee)
+// @Param		id				query		int		false	"Only stream changes to the user with this ID"
+// @Param		last_event_id	query		int		false	"ID of the last event received, if the Last-Event-ID header can not be set"
+// @Param		Last-Event-ID	header		int		false	"ID of the last event received"
+// @Success		200				{string}	string	"Stream of user.created, user.updated, user.deleted and reset events"
+// @Failure		400				{object}	problem.Problem
+// @Router		/user/events	[GET]
+func HandleUserEvents(logger sLogger, broker userEventSubscriber, heartbeat time.Duration) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		// setup
+		logger := requestLogger(r, logger)
+		ctx := r.Context()
+
+		// get and validate query params
+		filter, problems, err := validateInput[inputUserEvents, stream.Filter](newInputUserEvents(r))
+		if err != nil {
+			switch {
+			case len(problems) > 0:
+				logger.Error("Problems validating query", "error", err, "problems", problems)
+				encodeProblem(w, r, logger, problem.
+					New(http.StatusBadRequest, "One or more query parameters are invalid").
+					WithFieldErrors(problems),
+				)
+			default:
+				logger.Error("Query parse error", "error", err)
+				encodeProblem(w, r, logger, problem.New(http.StatusBadRequest, "malformed query parameters"))
+			}
+			return
+		}
+		after, err := lastEventID(r)
+		if err != nil {
+			logger.Error("error getting last event ID", "error", err)
+			encodeProblem(w, r, logger, problem.New(http.StatusBadRequest, "Not a valid Last-Event-ID"))
+			return
+		}
+
+		extendDeadlines(w, logger, time.Time{})
+		rc := http.NewResponseController(w)
+
+		subscription, cancel := broker.Subscribe(filter, after)
+		defer cancel()
+
+		w.Header().Set("Content-Type", "text/event-stream")
+		w.Header().Set("Cache-Control", "no-cache")
+		w.Header().Set("Connection", "keep-alive")
+		w.Header().Set("X-Accel-Buffering", "no")
+		w.WriteHeader(http.StatusOK)
+
+		// send missed events
+		if _, err = fmt.Fprintf(w, "retry: %d\n\n", eventRetry); err != nil {
+			return
+		}
+		if subscription.Gap {
+			if err = writeEvent(w, 0, "reset", []byte(`{"reason":"events since Last-Event-ID are no longer buffered"}`)); err != nil {
+				return
+			}
+		}
+		for _, event := range subscription.Replay {
+			if err = writeEvent(w, event.ID, event.Type, event.Data); err != nil {
+				return
+			}
+		}
+		if err = rc.Flush(); err != nil {
+			logger.Error("Error flushing event stream", "error", err)
+			return
+		}
+
+		// send events as they happen
+		ticker := time.NewTicker(heartbeat)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case event, ok := <-subscription.Events:
+				if !ok {
+					return
+				}
+				err = writeEvent(w, event.ID, event.Type, event.Data)
+			case <-ticker.C:
+				_, err = io.WriteString(w, ": heartbeat\n\n")
+			}
+			if err == nil {
+				err = rc.Flush()
+			}
+			if err != nil {
+				logger.Debug("Event stream closed", "error", err)
+				return
+			}
+		}
+	}
+}
+
+// lastEventID returns the ID of the last event a reconnecting client received, which is 0 if the
+// client is not reconnecting.
+func lastEventID(r *http.Request) (int64, error) {
+	value := r.Header.Get("Last-Event-ID")
+	if value == "" {
+		value = r.URL.Query().Get("last_event_id")
+	}
+	if value == "" {
+		return 0, nil
+	}
+
+	ID, err := strconv.ParseInt(value, 10, 64)
+	if err != nil || ID < 0 {
+		return 0, fmt.Errorf("[in lastEventID]: invalid ID %q", value)
+	}
+	return ID, nil
+}
+
+// writeEvent writes a single Server-Sent Event with name and the JSON data, which is compacted to a
+// single line. ID is left out if it is 0.
+func writeEvent(w io.Writer, ID int64, name string, data []byte) error {
+	var line bytes.Buffer
+	if err := json.Compact(&line, data); err != nil {
+		return fmt.Errorf("[in writeEvent] compact data: %w", err)
+	}
+
+	if ID != 0 {
+		if _, err := fmt.Fprintf(w, "id: %d\n", ID); err != nil {
+			return err
+		}
+	}
+	_, err := fmt.Fprintf(w, "event: %s\ndata: %s\n\n", name, line.Bytes())
+	return err
+}
@@ -0,0 +1,160 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/jha-captech/user-microservice/internal/problem"
+	"github.com/jha-captech/user-microservice/internal/stream"
+	"github.com/stretchr/testify/assert"
+
+	serviceMock "github.com/jha-captech/user-microservice/internal/handlers/mock"
+)
+
+func TestHandleUserEvents(t *testing.T) {
+	created := stream.Event{
+		ID: 41, Type: "user.created", UserID: 7, Role: "Customer",
+		Data: json.RawMessage(`{"id": 7, "first_name": "John", "role": "Customer"}`),
+	}
+	updated := stream.Event{
+		ID: 42, Type: "user.updated", UserID: 7, Role: "Employee", OldRole: "Customer",
+		Data: json.RawMessage(`{"id": 7, "first_name": "John", "role": "Employee"}`),
+	}
+
+	tests := map[string]struct {
+		mockCalled     bool
+		mockInput      []any
+		subscription   *stream.Subscription
+		live           []stream.Event
+		requestQuery   string
+		lastEventID    string
+		expectedCode   int
+		expectedHeader string
+		expectedBody   string
+	}{
+		"live events streamed": {
+			mockCalled:     true,
+			mockInput:      []any{stream.Filter{}, int64(0)},
+			subscription:   &stream.Subscription{Replay: []stream.Event{}},
+			live:           []stream.Event{created, updated},
+			expectedCode:   http.StatusOK,
+			expectedHeader: "text/event-stream",
+			expectedBody: "retry: 3000\n\n" +
+				"id: 41\nevent: user.created\ndata: {\"id\":7,\"first_name\":\"John\",\"role\":\"Customer\"}\n\n" +
+				"id: 42\nevent: user.updated\ndata: {\"id\":7,\"first_name\":\"John\",\"role\":\"Employee\"}\n\n",
+		},
+		"missed events replayed with filters": {
+			mockCalled:     true,
+			mockInput:      []any{stream.Filter{Role: "Employee", UserID: 7}, int64(41)},
+			subscription:   &stream.Subscription{Replay: []stream.Event{updated}},
+			requestQuery:   "?role=Employee&id=7",
+			lastEventID:    "41",
+			expectedCode:   http.StatusOK,
+			expectedHeader: "text/event-stream",
+			expectedBody: "retry: 3000\n\n" +
+				"id: 42\nevent: user.updated\ndata: {\"id\":7,\"first_name\":\"John\",\"role\":\"Employee\"}\n\n",
+		},
+		"reset sent when missed events are not buffered": {
+			mockCalled:     true,
+			mockInput:      []any{stream.Filter{}, int64(3)},
+			subscription:   &stream.Subscription{Replay: []stream.Event{updated}, Gap: true},
+			requestQuery:   "?last_event_id=3",
+			expectedCode:   http.StatusOK,
+			expectedHeader: "text/event-stream",
+			expectedBody: "retry: 3000\n\n" +
+				"event: reset\ndata: {\"reason\":\"events since Last-Event-ID are no longer buffered\"}\n\n" +
+				"id: 42\nevent: user.updated\ndata: {\"id\":7,\"first_name\":\"John\",\"role\":\"Employee\"}\n\n",
+		},
+		"invalid query parameters": {
+			mockCalled:     false,
+			requestQuery:   "?role=Admin&id=0",
+			expectedCode:   http.StatusBadRequest,
+			expectedHeader: problem.ContentType,
+			expectedBody: toJSONString(problem.
+				New(http.StatusBadRequest, "One or more query parameters are invalid").
+				WithInstance("/api/user/events").
+				WithFieldErrors(map[string]string{
+					"role": "must be 'Customer' or 'Employee'",
+					"id":   "must be a number more than 0",
+				}),
+			),
+		},
+		"invalid last event ID": {
+			mockCalled:     false,
+			lastEventID:    "abc",
+			expectedCode:   http.StatusBadRequest,
+			expectedHeader: problem.ContentType,
+			expectedBody:   toJSONString(problem.New(http.StatusBadRequest, "Not a valid Last-Event-ID").WithInstance("/api/user/events")),
+		},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			mockService := new(serviceMock.MockUserEventSubscriber)
+			handler := HandleUserEvents(slog.Default(), mockService, time.Hour)
+
+			req, err := http.NewRequest(http.MethodGet, "/api/user/events"+tc.requestQuery, nil)
+			assert.NoError(t, err)
+			if tc.lastEventID != "" {
+				req.Header.Set("Last-Event-ID", tc.lastEventID)
+			}
+
+			cancelled := false
+			if tc.mockCalled {
+				// the stream ends once the live events have been sent
+				events := make(chan stream.Event, len(tc.live))
+				for _, event := range tc.live {
+					events <- event
+				}
+				close(events)
+				tc.subscription.Events = events
+
+				mockService.
+					On("Subscribe", tc.mockInput...).
+					Return(tc.subscription, func() { cancelled = true }).
+					Once()
+			}
+
+			rr := httptest.NewRecorder()
+			handler.ServeHTTP(rr, req)
+
+			assert.Equal(t, tc.expectedCode, rr.Code, "Wrong code received")
+			assert.Equal(t, tc.expectedHeader, rr.Header().Get("Content-Type"), "Wrong content type")
+			if tc.mockCalled {
+				assert.Equal(t, tc.expectedBody, rr.Body.String(), "Wrong response body")
+				assert.True(t, cancelled, "Subscription not cancelled")
+				mockService.AssertExpectations(t)
+			} else {
+				assert.JSONEq(t, tc.expectedBody, rr.Body.String(), "Wrong response body")
+				mockService.AssertNotCalled(t, "Subscribe")
+			}
+		})
+	}
+}
+
+func TestHandleUserEventsHeartbeat(t *testing.T) {
+	mockService := new(serviceMock.MockUserEventSubscriber)
+	handler := HandleUserEvents(slog.Default(), mockService, time.Millisecond)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, "/api/user/events", nil)
+	assert.NoError(t, err)
+
+	mockService.
+		On("Subscribe", stream.Filter{}, int64(0)).
+		Return(&stream.Subscription{Replay: []stream.Event{}, Events: make(chan stream.Event)}, func() {}).
+		Once()
+
+	rr := httptest.NewRecorder()
+	handler.ServeHTTP(rr, req)
+
+	assert.Equal(t, http.StatusOK, rr.Code, "Wrong code received")
+	assert.Contains(t, rr.Body.String(), ": heartbeat\n\n", "No heartbeat sent")
+	mockService.AssertExpectations(t)
+}
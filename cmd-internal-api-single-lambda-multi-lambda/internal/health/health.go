@@ -0,0 +1,185 @@
+package health
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"sync/atomic"
+	"time"
+)
+
+// Status is the health of the service or of one of its components.
+type Status string
+
+const (
+	StatusOK   Status = "ok"
+	StatusFail Status = "fail"
+)
+
+// Component is the health of a single dependency of the service. Error says why it failed and
+// Details holds what was measured.
+type Component struct {
+	Status  Status         `json:"status"`
+	Error   string         `json:"error,omitempty"`
+	Details map[string]any `json:"details,omitempty"`
+}
+
+// Report is the health of the service, which is StatusFail if any of its components failed or the
+// service is draining. The components of a draining service are not checked.
+type Report struct {
+	Status     Status               `json:"status"`
+	Draining   bool                 `json:"draining,omitempty"`
+	Components map[string]Component `json:"components,omitempty"`
+}
+
+type schemaVersioner interface {
+	Version(ctx context.Context) (applied int, latest int, err error)
+}
+
+type Option func(*checkerOptions)
+
+type checkerOptions struct {
+	timeout       time.Duration
+	maxSaturation float64
+}
+
+// WithTimeout sets how long each component may take to respond. If this function is not called,
+// the default is 2 seconds.
+func WithTimeout(timeout time.Duration) Option {
+	return func(options *checkerOptions) {
+		options.timeout = timeout
+	}
+}
+
+// WithMaxPoolSaturation sets the share of the connection pool that may be in use before the pool
+// fails, between 0 and 1. If this function is not called, the default is 1, so the pool only fails
+// once every connection is in use. The pool never fails if it has no maximum number of connections.
+func WithMaxPoolSaturation(saturation float64) Option {
+	return func(options *checkerOptions) {
+		options.maxSaturation = saturation
+	}
+}
+
+// Checker checks whether the service is ready to serve requests: the database responds, its
+// connection pool is not saturated and its schema is at the latest migration. A Checker can be
+// marked as draining on shutdown, after which it reports the service as not ready, so load
+// balancers stop sending it requests before the server stops accepting them.
+type Checker struct {
+	db            *sql.DB
+	schema        schemaVersioner
+	timeout       time.Duration
+	maxSaturation float64
+	draining      atomic.Bool
+}
+
+// NewChecker returns a new Checker struct for db, whose schema version is read from schema.
+func NewChecker(db *sql.DB, schema schemaVersioner, opts ...Option) *Checker {
+	options := checkerOptions{
+		timeout:       2 * time.Second,
+		maxSaturation: 1,
+	}
+	for _, opt := range opts {
+		opt(&options)
+	}
+
+	return &Checker{
+		db:            db,
+		schema:        schema,
+		timeout:       options.timeout,
+		maxSaturation: options.maxSaturation,
+	}
+}
+
+// Drain marks the service as draining, which it stays until it exits.
+func (c *Checker) Drain() {
+	c.draining.Store(true)
+}
+
+// Ready checks every component and reports whether the service is ready.
+func (c *Checker) Ready(ctx context.Context) Report {
+	if c.draining.Load() {
+		return Report{Status: StatusFail, Draining: true}
+	}
+
+	report := Report{
+		Status: StatusOK,
+		Components: map[string]Component{
+			"database":   c.checkDatabase(ctx),
+			"pool":       c.checkPool(),
+			"migrations": c.checkMigrations(ctx),
+		},
+	}
+	for _, component := range report.Components {
+		if component.Status != StatusOK {
+			report.Status = StatusFail
+		}
+	}
+
+	return report
+}
+
+// checkDatabase pings the database.
+func (c *Checker) checkDatabase(ctx context.Context) Component {
+	ctx, cancel := context.WithTimeout(ctx, c.timeout)
+	defer cancel()
+
+	start := time.Now()
+	if err := c.db.PingContext(ctx); err != nil {
+		return Component{Status: StatusFail, Error: err.Error()}
+	}
+
+	return Component{
+		Status:  StatusOK,
+		Details: map[string]any{"latency_ms": time.Since(start).Milliseconds()},
+	}
+}
+
+// checkPool reports how saturated the connection pool is.
+func (c *Checker) checkPool() Component {
+	stats := c.db.Stats()
+	component := Component{
+		Status: StatusOK,
+		Details: map[string]any{
+			"open":             stats.OpenConnections,
+			"in_use":           stats.InUse,
+			"idle":             stats.Idle,
+			"max_open":         stats.MaxOpenConnections,
+			"wait_count":       stats.WaitCount,
+			"wait_duration_ms": stats.WaitDuration.Milliseconds(),
+		},
+	}
+	if stats.MaxOpenConnections == 0 {
+		return component
+	}
+
+	saturation := float64(stats.InUse) / float64(stats.MaxOpenConnections)
+	component.Details["saturation"] = saturation
+	if saturation >= c.maxSaturation {
+		component.Status = StatusFail
+		component.Error = fmt.Sprintf("%d of %d connections in use", stats.InUse, stats.MaxOpenConnections)
+	}
+
+	return component
+}
+
+// checkMigrations checks that the latest migration has been applied.
+func (c *Checker) checkMigrations(ctx context.Context) Component {
+	ctx, cancel := context.WithTimeout(ctx, c.timeout)
+	defer cancel()
+
+	applied, latest, err := c.schema.Version(ctx)
+	if err != nil {
+		return Component{Status: StatusFail, Error: err.Error()}
+	}
+
+	component := Component{
+		Status:  StatusOK,
+		Details: map[string]any{"applied": applied, "expected": latest},
+	}
+	if applied != latest {
+		component.Status = StatusFail
+		component.Error = fmt.Sprintf("schema is at version %d, expected %d", applied, latest)
+	}
+
+	return component
+}
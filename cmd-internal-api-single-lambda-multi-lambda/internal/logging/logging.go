@@ -0,0 +1,119 @@
+package logging
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"log/slog"
+	"net/http"
+
+	"github.com/awslabs/aws-lambda-go-api-proxy/core"
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/chi/v5/middleware"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// RequestIDHeader is the header the ID of a request is read from, and echoed in on its response.
+const RequestIDHeader = "X-Request-ID"
+
+// maxRequestIDLength is the maximum length of a request ID taken from a request header, longer IDs
+// are replaced.
+const maxRequestIDLength = 128
+
+type loggerKey struct{}
+
+// NewContext returns a copy of ctx carrying logger as the logger of its request.
+func NewContext(ctx context.Context, logger *slog.Logger) context.Context {
+	return context.WithValue(ctx, loggerKey{}, logger)
+}
+
+// FromContext returns the logger of the request of ctx, and whether ctx carries one.
+func FromContext(ctx context.Context) (*slog.Logger, bool) {
+	logger, ok := ctx.Value(loggerKey{}).(*slog.Logger)
+	return logger, ok
+}
+
+// With returns a copy of ctx whose request logger logs args with every line, such as the user of
+// the request once it is known. ctx is returned as is if it carries no logger.
+func With(ctx context.Context, args ...any) context.Context {
+	logger, ok := FromContext(ctx)
+	if !ok {
+		return ctx
+	}
+	return NewContext(ctx, logger.With(args...))
+}
+
+// Middleware returns a middleware that identifies every request and puts a logger into its context
+// that logs the request ID, the trace ID and the route of the request with every line. The ID is
+// taken from the X-Request-ID header of the request, or the request ID of API Gateway in a Lambda,
+// or else generated, and is echoed in the X-Request-ID header of the response. It is also where
+// middleware.GetReqID finds it.
+//
+// The route is the pattern of router that the request matches, such as `/api/user/{ID}`, and is
+// left out for requests that match none.
+func Middleware(logger *slog.Logger, router chi.Routes) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			ctx := r.Context()
+
+			id := requestID(r)
+			w.Header().Set(RequestIDHeader, id)
+			ctx = context.WithValue(ctx, middleware.RequestIDKey, id)
+
+			args := []any{"request_id", id}
+			if spanContext := trace.SpanContextFromContext(ctx); spanContext.HasTraceID() {
+				args = append(args, "trace_id", spanContext.TraceID().String())
+			}
+			if route := matchRoute(router, r); route != "" {
+				args = append(args, "route", route)
+			}
+
+			next.ServeHTTP(w, r.WithContext(NewContext(ctx, logger.With(args...))))
+		})
+	}
+}
+
+// requestID returns the ID of r: its X-Request-ID header if that is a valid ID, or the request ID
+// of API Gateway if r is a Lambda invocation, or else a new random ID.
+func requestID(r *http.Request) string {
+	if id := r.Header.Get(RequestIDHeader); validRequestID(id) {
+		return id
+	}
+	if gateway, ok := core.GetAPIGatewayContextFromContext(r.Context()); ok && gateway.RequestID != "" {
+		return gateway.RequestID
+	}
+
+	// crypto/rand.Read never fails
+	b := make([]byte, 16)
+	_, _ = rand.Read(b)
+	return hex.EncodeToString(b)
+}
+
+// validRequestID reports whether id can be used as a request ID: it is not empty, not too long and
+// only has printable ASCII characters other than spaces, so it can not forge log lines.
+func validRequestID(id string) bool {
+	if id == "" || len(id) > maxRequestIDLength {
+		return false
+	}
+	for i := 0; i < len(id); i++ {
+		if id[i] <= ' ' || id[i] > '~' {
+			return false
+		}
+	}
+	return true
+}
+
+// matchRoute returns the pattern of router that r matches, or an empty string if it matches none.
+// Unlike tracing.ChiRoute, it finds the pattern before r is routed.
+func matchRoute(router chi.Routes, r *http.Request) string {
+	path := r.URL.RawPath
+	if path == "" {
+		path = r.URL.Path
+	}
+
+	rctx := chi.NewRouteContext()
+	if !router.Match(rctx, r.Method, path) {
+		return ""
+	}
+	return rctx.RoutePattern()
+}
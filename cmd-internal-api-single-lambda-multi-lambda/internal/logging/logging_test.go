@@ -0,0 +1,160 @@
+package logging
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/awslabs/aws-lambda-go-api-proxy/core"
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/chi/v5/middleware"
+	"github.com/stretchr/testify/assert"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// newRequest returns a request for path, made through API Gateway with gatewayID as its request ID
+// unless gatewayID is empty.
+func newRequest(t *testing.T, path string, gatewayID string) *http.Request {
+	if gatewayID == "" {
+		return httptest.NewRequest(http.MethodGet, path, nil)
+	}
+
+	r, err := (&core.RequestAccessor{}).EventToRequestWithContext(context.Background(), events.APIGatewayProxyRequest{
+		HTTPMethod:     http.MethodGet,
+		Path:           path,
+		RequestContext: events.APIGatewayProxyRequestContext{RequestID: gatewayID},
+	})
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when converting an API Gateway request", err)
+	}
+	return r
+}
+
+func TestMiddleware(t *testing.T) {
+	traceID, _ := trace.TraceIDFromHex("4bf92f3577b34da6a3ce929d0e0e4736")
+	spanID, _ := trace.SpanIDFromHex("00f067aa0ba902b7")
+	spanContext := trace.NewSpanContext(trace.SpanContextConfig{TraceID: traceID, SpanID: spanID})
+
+	tests := map[string]struct {
+		path          string
+		header        string
+		gatewayID     string
+		traced        bool
+		expectedID    string
+		expectedRoute any
+		expectedTrace any
+	}{
+		"request id header": {
+			path:          "/api/user/1",
+			header:        "abc-123",
+			expectedID:    "abc-123",
+			expectedRoute: "/api/user/{ID}",
+		},
+		"request id header over api gateway": {
+			path:          "/api/user/1",
+			header:        "abc-123",
+			gatewayID:     "gateway-456",
+			expectedID:    "abc-123",
+			expectedRoute: "/api/user/{ID}",
+		},
+		"api gateway request id": {
+			path:          "/api/user/1",
+			gatewayID:     "gateway-456",
+			expectedID:    "gateway-456",
+			expectedRoute: "/api/user/{ID}",
+		},
+		"invalid request id header": {
+			path:          "/api/user/1",
+			header:        "abc 123\n",
+			expectedRoute: "/api/user/{ID}",
+		},
+		"generated request id": {
+			path:          "/api/user/1",
+			expectedRoute: "/api/user/{ID}",
+		},
+		"trace id": {
+			path:          "/api/user/1",
+			header:        "abc-123",
+			traced:        true,
+			expectedID:    "abc-123",
+			expectedRoute: "/api/user/{ID}",
+			expectedTrace: "4bf92f3577b34da6a3ce929d0e0e4736",
+		},
+		"no route": {
+			path:       "/api/unknown",
+			header:     "abc-123",
+			expectedID: "abc-123",
+		},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			var out bytes.Buffer
+			logger := slog.New(slog.NewJSONHandler(&out, nil))
+
+			var contextID string
+			r := chi.NewRouter()
+			r.Use(Middleware(logger, r))
+			handler := func(w http.ResponseWriter, r *http.Request) {
+				contextID = middleware.GetReqID(r.Context())
+				requestLogger, ok := FromContext(r.Context())
+				if assert.True(t, ok, "request should carry a logger") {
+					requestLogger.Info("handled")
+				}
+			}
+			r.Get("/api/user/{ID}", handler)
+			r.NotFound(handler)
+
+			req := newRequest(t, tc.path, tc.gatewayID)
+			if tc.header != "" {
+				req.Header.Set(RequestIDHeader, tc.header)
+			}
+			if tc.traced {
+				req = req.WithContext(trace.ContextWithSpanContext(req.Context(), spanContext))
+			}
+
+			w := httptest.NewRecorder()
+			r.ServeHTTP(w, req)
+
+			var line map[string]any
+			if !assert.NoError(t, json.Unmarshal(out.Bytes(), &line)) {
+				return
+			}
+
+			id := w.Header().Get(RequestIDHeader)
+			if tc.expectedID != "" {
+				assert.Equal(t, tc.expectedID, id)
+			} else {
+				assert.Len(t, id, 32, "request id should be generated")
+			}
+			assert.Equal(t, id, contextID)
+			assert.Equal(t, id, line["request_id"])
+			assert.Equal(t, tc.expectedRoute, line["route"])
+			assert.Equal(t, tc.expectedTrace, line["trace_id"])
+		})
+	}
+}
+
+func TestWith(t *testing.T) {
+	var out bytes.Buffer
+	ctx := NewContext(context.Background(), slog.New(slog.NewJSONHandler(&out, nil)))
+
+	ctx = With(ctx, "user_id", 1)
+	logger, ok := FromContext(ctx)
+	if !assert.True(t, ok) {
+		return
+	}
+	logger.Info("handled")
+
+	var line map[string]any
+	if assert.NoError(t, json.Unmarshal(out.Bytes(), &line)) {
+		assert.Equal(t, 1.0, line["user_id"])
+	}
+
+	assert.Equal(t, context.Background(), With(context.Background(), "user_id", 1), "context without logger should be returned as is")
+}
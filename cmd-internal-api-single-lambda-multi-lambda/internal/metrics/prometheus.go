@@ -0,0 +1,110 @@
+package metrics
+
+import (
+	"database/sql"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+// Prometheus is a Recorder that keeps its metrics in a Prometheus registry, together with the
+// connection pool stats of the database and the Go runtime and process metrics. The metrics are
+// exposed in the Prometheus text format by Handler.
+type Prometheus struct {
+	registry *prometheus.Registry
+	requests *prometheus.CounterVec
+	errors   *prometheus.CounterVec
+	duration *prometheus.HistogramVec
+	inFlight prometheus.Gauge
+	created  prometheus.Counter
+	updated  prometheus.Counter
+	deleted  prometheus.Counter
+}
+
+// NewPrometheus returns a new Prometheus struct whose registry includes the sql.DBStats of db, as
+// the go_sql_* metrics with a db_name label of dbName.
+func NewPrometheus(db *sql.DB, dbName string) *Prometheus {
+	p := &Prometheus{
+		registry: prometheus.NewRegistry(),
+		requests: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "http_requests_total",
+			Help: "Number of requests served, by method, route and status.",
+		}, []string{"method", "route", "status"}),
+		errors: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "http_request_errors_total",
+			Help: "Number of requests that failed with a 5xx status, by method, route and status.",
+		}, []string{"method", "route", "status"}),
+		duration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Name:    "http_request_duration_seconds",
+			Help:    "Time taken to serve requests, by method and route.",
+			Buckets: prometheus.DefBuckets,
+		}, []string{"method", "route"}),
+		inFlight: prometheus.NewGauge(prometheus.GaugeOpts{
+			Name: "http_requests_in_flight",
+			Help: "Number of requests being served.",
+		}),
+		created: prometheus.NewCounter(prometheus.CounterOpts{
+			Name: "users_created_total",
+			Help: "Number of users created.",
+		}),
+		updated: prometheus.NewCounter(prometheus.CounterOpts{
+			Name: "users_updated_total",
+			Help: "Number of users updated, including restores.",
+		}),
+		deleted: prometheus.NewCounter(prometheus.CounterOpts{
+			Name: "users_deleted_total",
+			Help: "Number of users soft deleted or purged.",
+		}),
+	}
+
+	p.registry.MustRegister(
+		p.requests,
+		p.errors,
+		p.duration,
+		p.inFlight,
+		p.created,
+		p.updated,
+		p.deleted,
+		collectors.NewDBStatsCollector(db, dbName),
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+	)
+
+	return p
+}
+
+// Handler returns a handler that serves the metrics of p in the Prometheus text format.
+func (p *Prometheus) Handler() http.Handler {
+	return promhttp.HandlerFor(p.registry, promhttp.HandlerOpts{Registry: p.registry})
+}
+
+func (p *Prometheus) RequestStarted() {
+	p.inFlight.Inc()
+}
+
+func (p *Prometheus) RequestFinished(method string, route string, status int, duration time.Duration) {
+	statusLabel := strconv.Itoa(status)
+
+	p.inFlight.Dec()
+	p.requests.WithLabelValues(method, route, statusLabel).Inc()
+	if isError(status) {
+		p.errors.WithLabelValues(method, route, statusLabel).Inc()
+	}
+	p.duration.WithLabelValues(method, route).Observe(duration.Seconds())
+}
+
+func (p *Prometheus) UsersCreated(count int) {
+	p.created.Add(float64(count))
+}
+
+func (p *Prometheus) UsersUpdated(count int) {
+	p.updated.Add(float64(count))
+}
+
+func (p *Prometheus) UsersDeleted(count int) {
+	p.deleted.Add(float64(count))
+}
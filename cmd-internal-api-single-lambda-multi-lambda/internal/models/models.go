@@ -1,9 +1,282 @@
 package models
 
+import (
+	"slices"
+	"time"
+)
+
+// User is a user of the service. DeletedAt is set when the user has been soft deleted. CreatedAt,
+// UpdatedAt, CreatedBy and UpdatedBy record when and by which actor the user was created and last
+// changed, and are maintained by the service rather than set by callers. Fields tagged `pii:"true"`
+// hold personal data, which logging.RedactHandler keeps out of logs.
 type User struct {
 	ID        uint
-	FirstName string
-	LastName  string
+	FirstName string `pii:"true"`
+	LastName  string `pii:"true"`
 	Role      string
 	UserID    uint
+	Version   uint
+	DeletedAt *time.Time
+	CreatedAt time.Time
+	UpdatedAt time.Time
+	CreatedBy string
+	UpdatedBy string
+}
+
+// UserChanges holds the fields of a user to change. Nil fields are left unchanged.
+type UserChanges struct {
+	FirstName *string `pii:"true"`
+	LastName  *string `pii:"true"`
+	Role      *string
+	UserID    *uint
+}
+
+// IsEmpty reports whether changes does not change any field.
+func (changes UserChanges) IsEmpty() bool {
+	return changes.FirstName == nil && changes.LastName == nil && changes.Role == nil && changes.UserID == nil
+}
+
+// UserSortField is a column that a list of users can be ordered by.
+type UserSortField string
+
+const (
+	UserSortID        UserSortField = "id"
+	UserSortFirstName UserSortField = "first_name"
+	UserSortLastName  UserSortField = "last_name"
+	UserSortRole      UserSortField = "role"
+	UserSortUserID    UserSortField = "user_id"
+)
+
+// UserSortFields is the allow-list of fields a list of users can be ordered by.
+var UserSortFields = []UserSortField{
+	UserSortID,
+	UserSortFirstName,
+	UserSortLastName,
+	UserSortRole,
+	UserSortUserID,
+}
+
+// ListUsersParams holds the paging, filtering and sorting options used when listing users. Zero
+// values for the filter fields mean that the filter is not applied. When Cursor is set, keyset
+// pagination is used and Offset is ignored. Soft deleted users are only listed if IncludeDeleted
+// is set. UpdatedSince limits the list to users changed at or after that time.
+type ListUsersParams struct {
+	Limit          int
+	Offset         int
+	Cursor         string
+	Role           string
+	LastNamePrefix string `pii:"true"`
+	UserIDMin      uint
+	UserIDMax      uint
+	SortBy         UserSortField
+	SortDesc       bool
+	IncludeDeleted bool
+	UpdatedSince   time.Time
+}
+
+// UserPage is a single page of users returned when listing users. NextCursor and PrevCursor are
+// opaque tokens pointing to the adjacent pages and are empty when there is no such page.
+type UserPage struct {
+	Users      []User
+	Total      int
+	NextCursor string
+	PrevCursor string
+}
+
+// HistoryOperation is the kind of change recorded by a UserHistoryEntry.
+type HistoryOperation string
+
+const (
+	HistoryCreate  HistoryOperation = "create"
+	HistoryUpdate  HistoryOperation = "update"
+	HistoryPatch   HistoryOperation = "patch"
+	HistoryDelete  HistoryOperation = "delete"
+	HistoryRestore HistoryOperation = "restore"
+	HistoryPurge   HistoryOperation = "purge"
+	HistoryImport  HistoryOperation = "import"
+)
+
+// UserHistoryEntry records a single change to the user with ID, which brought it to Version.
+// Before and After are snapshots of the user as it was before and after the change, Before being
+// nil for creates and After for purges. A purge is recorded at the version after the last version
+// of the user.
+type UserHistoryEntry struct {
+	ID        uint
+	Version   uint
+	Operation HistoryOperation
+	Actor     string
+	RequestID string
+	ChangedAt time.Time
+	Before    *User
+	After     *User
+}
+
+// ListHistoryParams holds the paging options used when listing the history of a user.
+type ListHistoryParams struct {
+	Limit  int
+	Offset int
+}
+
+// UserHistoryPage is a single page of the history of a user, newest change first. Total is the
+// number of entries in the whole history.
+type UserHistoryPage struct {
+	Entries []UserHistoryEntry
+	Total   int
+}
+
+// EventType is the type of a user domain event published through the outbox.
+type EventType string
+
+const (
+	EventUserCreated EventType = "user.created"
+	EventUserUpdated EventType = "user.updated"
+	EventUserDeleted EventType = "user.deleted"
+)
+
+// EventTypes is the allow-list of event types a webhook subscription can filter on.
+var EventTypes = []EventType{EventUserCreated, EventUserUpdated, EventUserDeleted}
+
+// WebhookSubscription is an endpoint that user events are delivered to. Events filters the event
+// types delivered, and every event type is delivered when it is empty. Secret is the key the
+// payloads are signed with and is never returned to clients.
+type WebhookSubscription struct {
+	ID        uint
+	URL       string
+	Events    []EventType
+	Secret    string
+	CreatedAt time.Time
+	UpdatedAt time.Time
+}
+
+// WebhookDelivery is a single event delivered, or to be delivered, to the subscription with
+// SubscriptionID. RedeliveryOf is set for deliveries made by hand, and holds the delivery they
+// repeat. ResponseStatus is the HTTP status of the last response of the subscriber, which is nil if
+// it has not responded.
+type WebhookDelivery struct {
+	ID             uint
+	SubscriptionID uint
+	EventID        string
+	EventType      EventType
+	RedeliveryOf   *uint
+	Status         string
+	Attempts       int
+	ResponseStatus *int
+	LastError      string
+	CreatedAt      time.Time
+	NextAttemptAt  time.Time
+	DeliveredAt    *time.Time
+}
+
+// ListDeliveriesParams holds the paging options used when listing the deliveries of a subscription.
+type ListDeliveriesParams struct {
+	Limit  int
+	Offset int
+}
+
+// WebhookDeliveryPage is a single page of the deliveries of a subscription, newest first. Total is
+// the number of deliveries the subscription has.
+type WebhookDeliveryPage struct {
+	Deliveries []WebhookDelivery
+	Total      int
+}
+
+// APIKeyScope is a permission granted to an API key. Each scope includes the ones before it, so a
+// key with ScopeUsersAdmin can also read and write users.
+type APIKeyScope string
+
+const (
+	ScopeUsersRead  APIKeyScope = "users:read"
+	ScopeUsersWrite APIKeyScope = "users:write"
+	ScopeUsersAdmin APIKeyScope = "users:admin"
+)
+
+// APIKeyScopes is the allow-list of scopes an API key can be granted, from least to most powerful.
+var APIKeyScopes = []APIKeyScope{ScopeUsersRead, ScopeUsersWrite, ScopeUsersAdmin}
+
+// APIKey is a key that services and batch jobs authenticate with in place of a bearer token. Only a
+// hash of the key is stored, and Prefix holds its first characters so it can be recognized. The
+// key is refused once ExpiresAt has passed or it has been revoked.
+type APIKey struct {
+	ID         uint
+	Name       string
+	Prefix     string
+	Scopes     []APIKeyScope
+	ExpiresAt  *time.Time
+	LastUsedAt *time.Time
+	RevokedAt  *time.Time
+	CreatedAt  time.Time
+}
+
+// HasScope reports whether the key grants scope, either directly or through a scope including it.
+func (key APIKey) HasScope(scope APIKeyScope) bool {
+	required := slices.Index(APIKeyScopes, scope)
+	if required < 0 {
+		return false
+	}
+	for _, granted := range key.Scopes {
+		if slices.Index(APIKeyScopes, granted) >= required {
+			return true
+		}
+	}
+	return false
+}
+
+// BatchOp is the kind of change made by a BatchOperation.
+type BatchOp string
+
+const (
+	BatchCreate BatchOp = "create"
+	BatchUpdate BatchOp = "update"
+	BatchDelete BatchOp = "delete"
+)
+
+// BatchOps is the allow-list of operations in a batch.
+var BatchOps = []BatchOp{BatchCreate, BatchUpdate, BatchDelete}
+
+// BatchOperation is a single create, update or delete in a batch of changes to users. ID is the
+// user to update or delete and User holds the fields to create or update.
+type BatchOperation struct {
+	Op   BatchOp
+	ID   int
+	User User
+}
+
+// BatchResult is the outcome of a single BatchOperation. ID and Version are those of the created,
+// updated or deleted user and Err is set when the operation was not applied.
+type BatchResult struct {
+	ID      int
+	Version uint
+	Err     error
+}
+
+// ImportConflict is how an import resolves a row whose user_id is already used by a user.
+type ImportConflict string
+
+const (
+	// ImportConflictFail imports nothing if any row conflicts.
+	ImportConflictFail ImportConflict = "fail"
+	// ImportConflictSkip leaves the existing user unchanged and does not import the row.
+	ImportConflictSkip ImportConflict = "skip"
+	// ImportConflictUpdate updates the existing user with the fields of the row.
+	ImportConflictUpdate ImportConflict = "update"
+)
+
+// ImportConflicts is the allow-list of ways to resolve import conflicts.
+var ImportConflicts = []ImportConflict{ImportConflictFail, ImportConflictSkip, ImportConflictUpdate}
+
+// ImportRow is a single user read from an import file. Row is its position in the file, counting
+// from 1, and is used to report problems with it.
+type ImportRow struct {
+	Row  int
+	User User
+}
+
+// ImportResult is the outcome of an import. ConflictRows are the rows whose user_id is already used
+// by a user, which were skipped, updated or caused the import to fail depending on the
+// ImportConflict.
+type ImportResult struct {
+	Created      int
+	Updated      int
+	Skipped      int
+	ConflictRows []int
 }
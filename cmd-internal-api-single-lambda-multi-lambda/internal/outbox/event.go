@@ -0,0 +1,27 @@
+package outbox
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// SpecVersion is the version of the CloudEvents specification that Event follows.
+const SpecVersion = "1.0"
+
+// ContentType is the media type of an Event encoded as JSON in the structured content mode of the
+// CloudEvents HTTP binding.
+const ContentType = "application/cloudevents+json"
+
+// Event is a user domain event in the CloudEvents JSON envelope. ID is the ID of the event in the
+// outbox, which is unique for Source, and Subject is the ID of the user the event is about. Data is
+// the user after the change, or before it if the event is for a purge.
+type Event struct {
+	SpecVersion     string          `json:"specversion"`
+	ID              string          `json:"id"`
+	Source          string          `json:"source"`
+	Type            string          `json:"type"`
+	Subject         string          `json:"subject"`
+	Time            time.Time       `json:"time"`
+	DataContentType string          `json:"datacontenttype"`
+	Data            json.RawMessage `json:"data"`
+}
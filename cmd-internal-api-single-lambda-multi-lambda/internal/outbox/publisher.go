@@ -0,0 +1,126 @@
+package outbox
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// Publisher publishes events taken from the outbox by a Relay. An event is retried by the relay
+// until Publish returns nil, so an event can be published more than once.
+type Publisher interface {
+	Publish(ctx context.Context, event Event) error
+}
+
+// MemoryPublisher is a Publisher that keeps the events it is given in memory, for tests and local
+// development.
+type MemoryPublisher struct {
+	mu     sync.Mutex
+	events []Event
+}
+
+// NewMemoryPublisher returns a new MemoryPublisher without any events.
+func NewMemoryPublisher() *MemoryPublisher {
+	return &MemoryPublisher{}
+}
+
+// Publish appends event to the events of p.
+func (p *MemoryPublisher) Publish(_ context.Context, event Event) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.events = append(p.events, event)
+	return nil
+}
+
+// Events returns a copy of the events published to p, in the order they were published.
+func (p *MemoryPublisher) Events() []Event {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	events := make([]Event, len(p.events))
+	copy(events, p.events)
+	return events
+}
+
+// MultiPublisher is a Publisher that publishes every event to each of a list of publishers. An
+// event fails if any of them fails, in which case the relay retries it with all of them, so the
+// publishers that succeeded receive it again.
+type MultiPublisher struct {
+	publishers []Publisher
+}
+
+// NewMultiPublisher returns a new MultiPublisher that publishes events to publishers.
+func NewMultiPublisher(publishers ...Publisher) *MultiPublisher {
+	return &MultiPublisher{
+		publishers: publishers,
+	}
+}
+
+// Publish publishes event to every publisher of p, even if some of them fail.
+func (p *MultiPublisher) Publish(ctx context.Context, event Event) error {
+	var errs []error
+	for _, publisher := range p.publishers {
+		if err := publisher.Publish(ctx, event); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	if len(errs) > 0 {
+		return fmt.Errorf("[in MultiPublisher.Publish]: %w", errors.Join(errs...))
+	}
+
+	return nil
+}
+
+// WebhookPublisher is a Publisher that posts every event as JSON to a URL in the structured content
+// mode of the CloudEvents HTTP binding. Any response other than a 2xx status fails the event.
+type WebhookPublisher struct {
+	url    string
+	client *http.Client
+}
+
+// NewWebhookPublisher returns a new WebhookPublisher that posts events to url with client. If client
+// is nil, a client with a 10 second timeout is used.
+func NewWebhookPublisher(url string, client *http.Client) *WebhookPublisher {
+	if client == nil {
+		client = &http.Client{Timeout: 10 * time.Second}
+	}
+
+	return &WebhookPublisher{
+		url:    url,
+		client: client,
+	}
+}
+
+// Publish posts event to the URL of p.
+func (p *WebhookPublisher) Publish(ctx context.Context, event Event) error {
+	body, err := json.Marshal(event)
+	if err != nil {
+		return fmt.Errorf("[in WebhookPublisher.Publish] encode event: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, p.url, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("[in WebhookPublisher.Publish]: %w", err)
+	}
+	req.Header.Set("Content-Type", ContentType)
+
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("[in WebhookPublisher.Publish]: %w", err)
+	}
+	defer resp.Body.Close()
+	_, _ = io.Copy(io.Discard, resp.Body)
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("[in WebhookPublisher.Publish]: webhook responded with %s", resp.Status)
+	}
+
+	return nil
+}
@@ -0,0 +1,100 @@
+package outbox
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWebhookPublisher(t *testing.T) {
+	event := Event{
+		SpecVersion:     SpecVersion,
+		ID:              "42",
+		Source:          "/user-microservice",
+		Type:            "user.created",
+		Subject:         "7",
+		Time:            time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC),
+		DataContentType: "application/json",
+		Data:            json.RawMessage(`{"id":7}`),
+	}
+
+	tests := map[string]struct {
+		status      int
+		expectedErr string
+	}{
+		"event accepted": {
+			status: http.StatusAccepted,
+		},
+		"event rejected": {
+			status:      http.StatusServiceUnavailable,
+			expectedErr: "[in WebhookPublisher.Publish]: webhook responded with 503 Service Unavailable",
+		},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			var (
+				contentType string
+				body        []byte
+			)
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				contentType = r.Header.Get("Content-Type")
+				body, _ = io.ReadAll(r.Body)
+				w.WriteHeader(tc.status)
+			}))
+			defer server.Close()
+
+			err := NewWebhookPublisher(server.URL, server.Client()).Publish(context.Background(), event)
+
+			if tc.expectedErr == "" {
+				assert.NoError(t, err)
+			} else {
+				assert.EqualError(t, err, tc.expectedErr)
+			}
+			assert.Equal(t, ContentType, contentType, "Wrong content type")
+			assert.JSONEq(t, `{
+				"specversion": "1.0",
+				"id": "42",
+				"source": "/user-microservice",
+				"type": "user.created",
+				"subject": "7",
+				"time": "2024-05-01T12:00:00Z",
+				"datacontenttype": "application/json",
+				"data": {"id": 7}
+			}`, string(body), "Wrong body")
+		})
+	}
+}
+
+func TestMemoryPublisher(t *testing.T) {
+	publisher := NewMemoryPublisher()
+	first := Event{ID: "1", Type: "user.created"}
+	second := Event{ID: "2", Type: "user.deleted"}
+
+	assert.NoError(t, publisher.Publish(context.Background(), first))
+	assert.NoError(t, publisher.Publish(context.Background(), second))
+
+	assert.Equal(t, []Event{first, second}, publisher.Events())
+}
+
+func TestMultiPublisher(t *testing.T) {
+	first := NewMemoryPublisher()
+	second := NewMemoryPublisher()
+	failing := publisherFunc(func(context.Context, Event) error {
+		return errors.New("connection refused")
+	})
+	event := Event{ID: "1", Type: "user.created"}
+
+	err := NewMultiPublisher(first, failing, second).Publish(context.Background(), event)
+
+	assert.EqualError(t, err, "[in MultiPublisher.Publish]: connection refused")
+	assert.Equal(t, []Event{event}, first.Events(), "Event not published to first publisher")
+	assert.Equal(t, []Event{event}, second.Events(), "Event not published to publisher after failing one")
+}
@@ -0,0 +1,334 @@
+package outbox
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+type sLogger interface {
+	Debug(msg string, args ...any)
+	Info(msg string, args ...any)
+	Warn(msg string, args ...any)
+	Error(msg string, args ...any)
+}
+
+// Statuses of the events in the outbox. An event is pending until it is published, or until
+// publishing it has failed the maximum number of times, after which it is dead and no longer
+// retried.
+const (
+	StatusPending   = "pending"
+	StatusDelivered = "delivered"
+	StatusDead      = "dead"
+)
+
+type Option func(*relayOptions)
+
+type relayOptions struct {
+	source       string
+	batchSize    int
+	pollInterval time.Duration
+	maxAttempts  int
+	minBackoff   time.Duration
+	maxBackoff   time.Duration
+	lease        time.Duration
+}
+
+// WithSource sets the CloudEvents source of the published events. If this function is not called,
+// the default is `/user-microservice`.
+func WithSource(source string) Option {
+	return func(options *relayOptions) {
+		options.source = source
+	}
+}
+
+// WithBatchSize sets the maximum number of events taken from the outbox at a time. If this function
+// is not called, the default is 100.
+func WithBatchSize(size int) Option {
+	return func(options *relayOptions) {
+		options.batchSize = size
+	}
+}
+
+// WithPollInterval sets how often the outbox is checked for pending events. If this function is not
+// called, the default is 1 second.
+func WithPollInterval(interval time.Duration) Option {
+	return func(options *relayOptions) {
+		options.pollInterval = interval
+	}
+}
+
+// WithMaxAttempts sets how many times publishing an event is attempted before it is marked dead. If
+// this function is not called, the default is 10.
+func WithMaxAttempts(attempts int) Option {
+	return func(options *relayOptions) {
+		options.maxAttempts = attempts
+	}
+}
+
+// WithBackoff sets the delay before the first retry of an event, which doubles with every further
+// attempt up to maxDelay. If this function is not called, the defaults are 1 second and 5 minutes.
+func WithBackoff(delay, maxDelay time.Duration) Option {
+	return func(options *relayOptions) {
+		options.minBackoff = delay
+		options.maxBackoff = maxDelay
+	}
+}
+
+// WithLease sets how long the events of a batch are claimed for. An event that is not marked
+// delivered or failed within its lease is taken again, so the lease should be longer than it takes
+// to publish a batch. If this function is not called, the default is 5 minutes.
+func WithLease(lease time.Duration) Option {
+	return func(options *relayOptions) {
+		options.lease = lease
+	}
+}
+
+// Relay publishes the events written to the user_outbox table by the service layer. Events are
+// delivered at least once: an event is only marked delivered once it has been published, so an
+// event is published again if the relay stops before marking it. Events are taken in the order they
+// were written, but a retried event can be published after events written after it.
+//
+// A batch of events is claimed for a lease before it is published, so any number of relays can share
+// an outbox, and events claimed by a relay that stops are taken again once their lease expires.
+type Relay struct {
+	db        *sql.DB
+	publisher Publisher
+	logger    sLogger
+	options   relayOptions
+}
+
+// NewRelay returns a new Relay publishing the events in the outbox of db to publisher.
+func NewRelay(db *sql.DB, publisher Publisher, logger sLogger, opts ...Option) *Relay {
+	options := relayOptions{
+		source:       "/user-microservice",
+		batchSize:    100,
+		pollInterval: time.Second,
+		maxAttempts:  10,
+		minBackoff:   time.Second,
+		maxBackoff:   5 * time.Minute,
+		lease:        5 * time.Minute,
+	}
+	for _, opt := range opts {
+		opt(&options)
+	}
+
+	return &Relay{
+		db:        db,
+		publisher: publisher,
+		logger:    logger,
+		options:   options,
+	}
+}
+
+// Run publishes pending events until ctx is done. The outbox is polled every poll interval, and
+// straight away again as long as a full batch of events was found.
+func (r *Relay) Run(ctx context.Context) {
+	ticker := time.NewTicker(r.options.pollInterval)
+	defer ticker.Stop()
+
+	for {
+		for {
+			count, err := r.Poll(ctx)
+			if err != nil {
+				if ctx.Err() == nil {
+					r.logger.Error("Error relaying outbox events", "err", err)
+				}
+				break
+			}
+			if count < r.options.batchSize {
+				break
+			}
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// pendingEvent is an event taken from the outbox along with the number of times it has been
+// attempted.
+type pendingEvent struct {
+	id       int64
+	attempts int
+	event    Event
+}
+
+// Poll publishes a single batch of the pending events that are due and returns the number of events
+// it attempted. The batch is claimed first and published outside of any transaction, and the
+// outcome of every event is recorded on its own. An event that fails is retried after a backoff,
+// or marked dead if it has been attempted the maximum number of times.
+func (r *Relay) Poll(ctx context.Context) (int, error) {
+	pending, err := r.claim(ctx)
+	if err != nil {
+		return 0, fmt.Errorf("[in Relay.Poll]: %w", err)
+	}
+
+	for _, p := range pending {
+		if publishErr := r.publisher.Publish(ctx, p.event); publishErr != nil {
+			if ctx.Err() != nil {
+				return 0, fmt.Errorf("[in Relay.Poll]: %w", ctx.Err())
+			}
+			if err = r.markFailed(ctx, p, publishErr); err != nil {
+				r.logger.Error("Error recording failed outbox event", "id", p.id, "err", err)
+			}
+			continue
+		}
+
+		_, err = r.db.ExecContext(
+			ctx,
+			`UPDATE "user_outbox" SET "status" = $2, "attempts" = "attempts" + 1, "delivered_at" = NOW() WHERE "id" = $1`,
+			p.id,
+			StatusDelivered,
+		)
+		if err != nil {
+			r.logger.Error("Error marking outbox event delivered", "id", p.id, "err", err)
+		}
+	}
+
+	return len(pending), nil
+}
+
+// claim takes the pending events that are due and leases them to this relay by moving their next
+// attempt to the end of the lease, so other relays skip them until it expires.
+func (r *Relay) claim(ctx context.Context) ([]pendingEvent, error) {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		_ = tx.Rollback()
+	}()
+
+	pending, err := r.takePending(ctx, tx)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(pending) > 0 {
+		ids := make([]int64, len(pending))
+		for i, p := range pending {
+			ids[i] = p.id
+		}
+		_, err = tx.ExecContext(
+			ctx,
+			`UPDATE "user_outbox" SET "next_attempt_at" = NOW() + $2 * INTERVAL '1 millisecond' WHERE "id" = ANY($1)`,
+			pq.Array(ids),
+			r.options.lease.Milliseconds(),
+		)
+		if err != nil {
+			return nil, fmt.Errorf("lease events: %w", err)
+		}
+	}
+
+	if err = tx.Commit(); err != nil {
+		return nil, err
+	}
+
+	return pending, nil
+}
+
+// takePending locks and returns the pending events that are due, skipping events locked by other
+// relays.
+func (r *Relay) takePending(ctx context.Context, tx *sql.Tx) ([]pendingEvent, error) {
+	rows, err := tx.QueryContext(
+		ctx,
+		`
+		SELECT
+			"id", "type", "subject", "data", "created_at", "attempts"
+		FROM
+			"user_outbox"
+		WHERE
+			"status" = $1 AND "next_attempt_at" <= NOW()
+		ORDER BY
+			"id"
+		LIMIT $2
+		FOR UPDATE SKIP LOCKED
+		`,
+		StatusPending,
+		r.options.batchSize,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var pending []pendingEvent
+	for rows.Next() {
+		var (
+			p    pendingEvent
+			data []byte
+		)
+		if err = rows.Scan(&p.id, &p.event.Type, &p.event.Subject, &data, &p.event.Time, &p.attempts); err != nil {
+			return nil, err
+		}
+		p.event.SpecVersion = SpecVersion
+		p.event.ID = strconv.FormatInt(p.id, 10)
+		p.event.Source = r.options.source
+		p.event.Time = p.event.Time.UTC()
+		p.event.DataContentType = "application/json"
+		p.event.Data = json.RawMessage(data)
+		pending = append(pending, p)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return pending, nil
+}
+
+// markFailed records that publishing p failed with publishErr, and schedules its next attempt or
+// marks it dead.
+func (r *Relay) markFailed(ctx context.Context, p pendingEvent, publishErr error) error {
+	attempts := p.attempts + 1
+	status := StatusPending
+	if attempts >= r.options.maxAttempts {
+		status = StatusDead
+		r.logger.Warn("Outbox event is dead after too many attempts", "id", p.id, "attempts", attempts, "err", publishErr)
+	} else {
+		r.logger.Info("Error publishing outbox event, will retry", "id", p.id, "attempts", attempts, "err", publishErr)
+	}
+
+	_, err := r.db.ExecContext(
+		ctx,
+		`
+		UPDATE
+			"user_outbox"
+		SET
+			"status" = $2,
+			"attempts" = $3,
+			"last_error" = $4,
+			"next_attempt_at" = NOW() + $5 * INTERVAL '1 millisecond'
+		WHERE
+			"id" = $1
+		`,
+		p.id,
+		status,
+		attempts,
+		publishErr.Error(),
+		r.backoff(attempts).Milliseconds(),
+	)
+	if err != nil {
+		return fmt.Errorf("mark event %d failed: %w", p.id, err)
+	}
+
+	return nil
+}
+
+// backoff returns the delay before the next attempt of an event that has been attempted attempts
+// times.
+func (r *Relay) backoff(attempts int) time.Duration {
+	delay := r.options.minBackoff
+	for i := 1; i < attempts && delay < r.options.maxBackoff; i++ {
+		delay *= 2
+	}
+	return min(delay, r.options.maxBackoff)
+}
@@ -0,0 +1,217 @@
+package outbox
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/lib/pq"
+	"github.com/stretchr/testify/assert"
+)
+
+// publisherFunc is a Publisher that calls itself.
+type publisherFunc func(ctx context.Context, event Event) error
+
+func (f publisherFunc) Publish(ctx context.Context, event Event) error {
+	return f(ctx, event)
+}
+
+func TestRelayPoll(t *testing.T) {
+	const (
+		selectPending = `SELECT "id", "type", "subject", "data", "created_at", "attempts" FROM "user_outbox" ` +
+			`WHERE "status" = $1 AND "next_attempt_at" <= NOW() ORDER BY "id" LIMIT $2 FOR UPDATE SKIP LOCKED`
+		leaseEvents   = `UPDATE "user_outbox" SET "next_attempt_at" = NOW() + $2 * INTERVAL '1 millisecond' WHERE "id" = ANY($1)`
+		markDelivered = `UPDATE "user_outbox" SET "status" = $2, "attempts" = "attempts" + 1, "delivered_at" = NOW() WHERE "id" = $1`
+		markFailed    = `UPDATE "user_outbox" SET "status" = $2, "attempts" = $3, "last_error" = $4, ` +
+			`"next_attempt_at" = NOW() + $5 * INTERVAL '1 millisecond' WHERE "id" = $1`
+	)
+
+	createdAt := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	columns := []string{"id", "type", "subject", "data", "created_at", "attempts"}
+	created := Event{
+		SpecVersion:     SpecVersion,
+		ID:              "1",
+		Source:          "/users",
+		Type:            "user.created",
+		Subject:         "7",
+		Time:            createdAt,
+		DataContentType: "application/json",
+		Data:            json.RawMessage(`{"id": 7}`),
+	}
+	deleted := Event{
+		SpecVersion:     SpecVersion,
+		ID:              "2",
+		Source:          "/users",
+		Type:            "user.deleted",
+		Subject:         "7",
+		Time:            createdAt,
+		DataContentType: "application/json",
+		Data:            json.RawMessage(`{"id": 7}`),
+	}
+	publishErr := errors.New("connection refused")
+
+	tests := map[string]struct {
+		setup             func(mock sqlmock.Sqlmock)
+		publishErr        error
+		expectedPublished []Event
+		expectedCount     int
+		expectedErr       error
+	}{
+		"events delivered": {
+			setup: func(mock sqlmock.Sqlmock) {
+				mock.ExpectBegin()
+				mock.ExpectQuery(regexp.QuoteMeta(selectPending)).
+					WithArgs(StatusPending, 10).
+					WillReturnRows(sqlmock.NewRows(columns).
+						AddRow(1, "user.created", "7", []byte(`{"id": 7}`), createdAt, 0).
+						AddRow(2, "user.deleted", "7", []byte(`{"id": 7}`), createdAt, 0))
+				mock.ExpectExec(regexp.QuoteMeta(leaseEvents)).
+					WithArgs(pq.Array([]int64{1, 2}), int64(60000)).
+					WillReturnResult(sqlmock.NewResult(0, 2))
+				mock.ExpectCommit()
+				mock.ExpectExec(regexp.QuoteMeta(markDelivered)).
+					WithArgs(1, StatusDelivered).
+					WillReturnResult(sqlmock.NewResult(0, 1))
+				mock.ExpectExec(regexp.QuoteMeta(markDelivered)).
+					WithArgs(2, StatusDelivered).
+					WillReturnResult(sqlmock.NewResult(0, 1))
+			},
+			expectedPublished: []Event{created, deleted},
+			expectedCount:     2,
+		},
+		"error marking one event delivered": {
+			setup: func(mock sqlmock.Sqlmock) {
+				mock.ExpectBegin()
+				mock.ExpectQuery(regexp.QuoteMeta(selectPending)).
+					WithArgs(StatusPending, 10).
+					WillReturnRows(sqlmock.NewRows(columns).
+						AddRow(1, "user.created", "7", []byte(`{"id": 7}`), createdAt, 0).
+						AddRow(2, "user.deleted", "7", []byte(`{"id": 7}`), createdAt, 0))
+				mock.ExpectExec(regexp.QuoteMeta(leaseEvents)).
+					WithArgs(pq.Array([]int64{1, 2}), int64(60000)).
+					WillReturnResult(sqlmock.NewResult(0, 2))
+				mock.ExpectCommit()
+				mock.ExpectExec(regexp.QuoteMeta(markDelivered)).
+					WithArgs(1, StatusDelivered).
+					WillReturnError(errors.New("test"))
+				mock.ExpectExec(regexp.QuoteMeta(markDelivered)).
+					WithArgs(2, StatusDelivered).
+					WillReturnResult(sqlmock.NewResult(0, 1))
+			},
+			expectedPublished: []Event{created, deleted},
+			expectedCount:     2,
+		},
+		"failed event retried after backoff": {
+			setup: func(mock sqlmock.Sqlmock) {
+				mock.ExpectBegin()
+				mock.ExpectQuery(regexp.QuoteMeta(selectPending)).
+					WithArgs(StatusPending, 10).
+					WillReturnRows(sqlmock.NewRows(columns).
+						AddRow(1, "user.created", "7", []byte(`{"id": 7}`), createdAt, 2))
+				mock.ExpectExec(regexp.QuoteMeta(leaseEvents)).
+					WithArgs(pq.Array([]int64{1}), int64(60000)).
+					WillReturnResult(sqlmock.NewResult(0, 1))
+				mock.ExpectCommit()
+				mock.ExpectExec(regexp.QuoteMeta(markFailed)).
+					WithArgs(1, StatusPending, 3, publishErr.Error(), int64(4000)).
+					WillReturnResult(sqlmock.NewResult(0, 1))
+			},
+			publishErr:    publishErr,
+			expectedCount: 1,
+		},
+		"failed event dead after max attempts": {
+			setup: func(mock sqlmock.Sqlmock) {
+				mock.ExpectBegin()
+				mock.ExpectQuery(regexp.QuoteMeta(selectPending)).
+					WithArgs(StatusPending, 10).
+					WillReturnRows(sqlmock.NewRows(columns).
+						AddRow(1, "user.created", "7", []byte(`{"id": 7}`), createdAt, 4))
+				mock.ExpectExec(regexp.QuoteMeta(leaseEvents)).
+					WithArgs(pq.Array([]int64{1}), int64(60000)).
+					WillReturnResult(sqlmock.NewResult(0, 1))
+				mock.ExpectCommit()
+				mock.ExpectExec(regexp.QuoteMeta(markFailed)).
+					WithArgs(1, StatusDead, 5, publishErr.Error(), int64(10000)).
+					WillReturnResult(sqlmock.NewResult(0, 1))
+			},
+			publishErr:    publishErr,
+			expectedCount: 1,
+		},
+		"no pending events": {
+			setup: func(mock sqlmock.Sqlmock) {
+				mock.ExpectBegin()
+				mock.ExpectQuery(regexp.QuoteMeta(selectPending)).
+					WithArgs(StatusPending, 10).
+					WillReturnRows(sqlmock.NewRows(columns))
+				mock.ExpectCommit()
+			},
+		},
+		"error taking pending events": {
+			setup: func(mock sqlmock.Sqlmock) {
+				mock.ExpectBegin()
+				mock.ExpectQuery(regexp.QuoteMeta(selectPending)).
+					WithArgs(StatusPending, 10).
+					WillReturnError(errors.New("test"))
+				mock.ExpectRollback()
+			},
+			expectedErr: fmt.Errorf("[in Relay.Poll]: %w", errors.New("test")),
+		},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			db, mock, err := sqlmock.New()
+			assert.NoError(t, err)
+			defer db.Close()
+			tc.setup(mock)
+
+			var published []Event
+			publisher := publisherFunc(func(_ context.Context, event Event) error {
+				if tc.publishErr != nil {
+					return tc.publishErr
+				}
+				published = append(published, event)
+				return nil
+			})
+			relay := NewRelay(
+				db,
+				publisher,
+				slog.Default(),
+				WithSource("/users"),
+				WithBatchSize(10),
+				WithMaxAttempts(5),
+				WithBackoff(time.Second, 10*time.Second),
+				WithLease(time.Minute),
+			)
+
+			count, err := relay.Poll(context.Background())
+
+			assert.Equal(t, tc.expectedErr, err, "errors did not match")
+			assert.Equal(t, tc.expectedCount, count, "Wrong number of events")
+			assert.Equal(t, tc.expectedPublished, published, "Wrong events published")
+			assert.NoError(t, mock.ExpectationsWereMet())
+		})
+	}
+}
+
+func TestRelayBackoff(t *testing.T) {
+	relay := NewRelay(nil, nil, slog.Default(), WithBackoff(time.Second, time.Minute))
+
+	tests := map[int]time.Duration{
+		1:  time.Second,
+		2:  2 * time.Second,
+		4:  8 * time.Second,
+		7:  time.Minute,
+		50: time.Minute,
+	}
+
+	for attempts, expected := range tests {
+		assert.Equal(t, expected, relay.backoff(attempts), "Wrong backoff after %d attempts", attempts)
+	}
+}
@@ -0,0 +1,370 @@
+package patch
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+const (
+	// MergePatchContentType is the media type of a JSON Merge Patch as defined by RFC 7396.
+	MergePatchContentType = "application/merge-patch+json"
+
+	// JSONPatchContentType is the media type of a JSON Patch as defined by RFC 6902.
+	JSONPatchContentType = "application/json-patch+json"
+)
+
+var (
+	// ErrInvalid is returned when a patch or the document it is applied to is not valid JSON, or
+	// when a JSON Patch operation is malformed.
+	ErrInvalid = errors.New("invalid patch")
+
+	// ErrConflict is returned when a JSON Patch operation can not be applied to the document, either
+	// because a path does not exist or because a test operation failed.
+	ErrConflict = errors.New("patch conflicts with document")
+)
+
+// Merge applies the JSON Merge Patch patch to the JSON document doc and returns the result. Members
+// of patch replace the members of doc, objects are merged recursively and members set to null are
+// removed.
+func Merge(doc, patch []byte) ([]byte, error) {
+	var target, mergePatch any
+	if err := json.Unmarshal(doc, &target); err != nil {
+		return nil, fmt.Errorf("[in patch.Merge] decode document: %w: %w", ErrInvalid, err)
+	}
+	if err := json.Unmarshal(patch, &mergePatch); err != nil {
+		return nil, fmt.Errorf("[in patch.Merge] decode patch: %w: %w", ErrInvalid, err)
+	}
+
+	result, err := json.Marshal(mergeValue(target, mergePatch))
+	if err != nil {
+		return nil, fmt.Errorf("[in patch.Merge] encode result: %w", err)
+	}
+
+	return result, nil
+}
+
+// mergeValue implements the MergePatch function of RFC 7396.
+func mergeValue(target, patch any) any {
+	patchObject, ok := patch.(map[string]any)
+	if !ok {
+		return patch
+	}
+
+	targetObject, ok := target.(map[string]any)
+	if !ok {
+		targetObject = make(map[string]any)
+	}
+	for name, value := range patchObject {
+		if value == nil {
+			delete(targetObject, name)
+			continue
+		}
+		targetObject[name] = mergeValue(targetObject[name], value)
+	}
+
+	return targetObject
+}
+
+// Operation is a single operation of a JSON Patch.
+type Operation struct {
+	Op    string           `json:"op"`
+	Path  string           `json:"path"`
+	From  string           `json:"from,omitempty"`
+	Value *json.RawMessage `json:"value,omitempty"`
+}
+
+// Apply applies the JSON Patch patch to the JSON document doc and returns the result. The operations
+// are applied in order and the patch is applied atomically: if any operation fails, an error is
+// returned and doc is left unchanged.
+func Apply(doc, patch []byte) ([]byte, error) {
+	var target any
+	if err := json.Unmarshal(doc, &target); err != nil {
+		return nil, fmt.Errorf("[in patch.Apply] decode document: %w: %w", ErrInvalid, err)
+	}
+
+	var operations []Operation
+	if err := json.Unmarshal(patch, &operations); err != nil {
+		return nil, fmt.Errorf("[in patch.Apply] decode patch: %w: %w", ErrInvalid, err)
+	}
+
+	for i, operation := range operations {
+		var err error
+		if target, err = applyOperation(target, operation); err != nil {
+			return nil, fmt.Errorf("[in patch.Apply] operation %d (%s %q): %w", i, operation.Op, operation.Path, err)
+		}
+	}
+
+	result, err := json.Marshal(target)
+	if err != nil {
+		return nil, fmt.Errorf("[in patch.Apply] encode result: %w", err)
+	}
+
+	return result, nil
+}
+
+// applyOperation applies operation to doc and returns the resulting document.
+func applyOperation(doc any, operation Operation) (any, error) {
+	path, err := parsePointer(operation.Path)
+	if err != nil {
+		return nil, err
+	}
+
+	switch operation.Op {
+	case "add":
+		value, err := operation.value()
+		if err != nil {
+			return nil, err
+		}
+		return add(doc, path, value)
+
+	case "remove":
+		doc, _, err = remove(doc, path)
+		return doc, err
+
+	case "replace":
+		value, err := operation.value()
+		if err != nil {
+			return nil, err
+		}
+		if doc, _, err = remove(doc, path); err != nil {
+			return nil, err
+		}
+		return add(doc, path, value)
+
+	case "move", "copy":
+		from, err := parsePointer(operation.From)
+		if err != nil {
+			return nil, err
+		}
+
+		var value any
+		if operation.Op == "move" {
+			if isPrefix(from, path) && len(from) < len(path) {
+				return nil, fmt.Errorf("%w: can not move %q into one of its children", ErrInvalid, operation.From)
+			}
+			doc, value, err = remove(doc, from)
+		} else {
+			value, err = get(doc, from)
+			if err == nil {
+				value, err = clone(value)
+			}
+		}
+		if err != nil {
+			return nil, err
+		}
+		return add(doc, path, value)
+
+	case "test":
+		value, err := operation.value()
+		if err != nil {
+			return nil, err
+		}
+		current, err := get(doc, path)
+		if err != nil {
+			return nil, err
+		}
+		if !reflect.DeepEqual(current, value) {
+			return nil, fmt.Errorf("%w: test failed", ErrConflict)
+		}
+		return doc, nil
+
+	default:
+		return nil, fmt.Errorf("%w: unknown op %q", ErrInvalid, operation.Op)
+	}
+}
+
+// value returns the decoded value of operation. It is an error for the value to be missing.
+func (operation Operation) value() (any, error) {
+	if operation.Value == nil {
+		return nil, fmt.Errorf("%w: missing value", ErrInvalid)
+	}
+
+	var value any
+	if err := json.Unmarshal(*operation.Value, &value); err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrInvalid, err)
+	}
+	return value, nil
+}
+
+// parsePointer splits the JSON Pointer (RFC 6901) pointer into its unescaped reference tokens.
+func parsePointer(pointer string) ([]string, error) {
+	if pointer == "" {
+		return nil, nil
+	}
+	if !strings.HasPrefix(pointer, "/") {
+		return nil, fmt.Errorf("%w: path %q must start with '/'", ErrInvalid, pointer)
+	}
+
+	unescape := strings.NewReplacer("~1", "/", "~0", "~")
+	tokens := strings.Split(pointer[1:], "/")
+	for i, token := range tokens {
+		tokens[i] = unescape.Replace(token)
+	}
+
+	return tokens, nil
+}
+
+// isPrefix reports whether the path prefix is equal to, or an ancestor of, path.
+func isPrefix(prefix, path []string) bool {
+	if len(prefix) > len(path) {
+		return false
+	}
+	for i := range prefix {
+		if prefix[i] != path[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// get returns the value at path in doc.
+func get(doc any, path []string) (any, error) {
+	for _, token := range path {
+		switch node := doc.(type) {
+		case map[string]any:
+			value, ok := node[token]
+			if !ok {
+				return nil, fmt.Errorf("%w: member %q does not exist", ErrConflict, token)
+			}
+			doc = value
+		case []any:
+			index, err := arrayIndex(token, len(node)-1)
+			if err != nil {
+				return nil, err
+			}
+			doc = node[index]
+		default:
+			return nil, fmt.Errorf("%w: %q is not in an object or array", ErrConflict, token)
+		}
+	}
+
+	return doc, nil
+}
+
+// add adds value at path in doc and returns the resulting document. A value in an object is
+// replaced and a value in an array is inserted before the value at the index, or appended for "-".
+func add(doc any, path []string, value any) (any, error) {
+	if len(path) == 0 {
+		return value, nil
+	}
+
+	parent, err := get(doc, path[:len(path)-1])
+	if err != nil {
+		return nil, err
+	}
+	token := path[len(path)-1]
+
+	switch node := parent.(type) {
+	case map[string]any:
+		node[token] = value
+		return doc, nil
+	case []any:
+		index := len(node)
+		if token != "-" {
+			if index, err = arrayIndex(token, len(node)); err != nil {
+				return nil, err
+			}
+		}
+		node = append(node[:index], append([]any{value}, node[index:]...)...)
+		return setParent(doc, path[:len(path)-1], node)
+	default:
+		return nil, fmt.Errorf("%w: %q is not in an object or array", ErrConflict, token)
+	}
+}
+
+// remove removes the value at path in doc and returns the resulting document and the removed value.
+func remove(doc any, path []string) (any, any, error) {
+	if len(path) == 0 {
+		return nil, doc, nil
+	}
+
+	parent, err := get(doc, path[:len(path)-1])
+	if err != nil {
+		return nil, nil, err
+	}
+	token := path[len(path)-1]
+
+	switch node := parent.(type) {
+	case map[string]any:
+		value, ok := node[token]
+		if !ok {
+			return nil, nil, fmt.Errorf("%w: member %q does not exist", ErrConflict, token)
+		}
+		delete(node, token)
+		return doc, value, nil
+	case []any:
+		index, err := arrayIndex(token, len(node)-1)
+		if err != nil {
+			return nil, nil, err
+		}
+		value := node[index]
+		node = append(node[:index:index], node[index+1:]...)
+		doc, err = setParent(doc, path[:len(path)-1], node)
+		return doc, value, err
+	default:
+		return nil, nil, fmt.Errorf("%w: %q is not in an object or array", ErrConflict, token)
+	}
+}
+
+// setParent replaces the array at path in doc with node, as arrays can not be grown or shrunk in
+// place.
+func setParent(doc any, path []string, node []any) (any, error) {
+	if len(path) == 0 {
+		return node, nil
+	}
+
+	parent, err := get(doc, path[:len(path)-1])
+	if err != nil {
+		return nil, err
+	}
+	token := path[len(path)-1]
+
+	switch container := parent.(type) {
+	case map[string]any:
+		container[token] = node
+	case []any:
+		index, err := arrayIndex(token, len(container)-1)
+		if err != nil {
+			return nil, err
+		}
+		container[index] = node
+	}
+
+	return doc, nil
+}
+
+// arrayIndex parses token as an index into an array, which must not be more than limit.
+func arrayIndex(token string, limit int) (int, error) {
+	if token == "" || (len(token) > 1 && token[0] == '0') {
+		return 0, fmt.Errorf("%w: %q is not an array index", ErrInvalid, token)
+	}
+
+	index, err := strconv.Atoi(token)
+	if err != nil || index < 0 {
+		return 0, fmt.Errorf("%w: %q is not an array index", ErrInvalid, token)
+	}
+	if index > limit {
+		return 0, fmt.Errorf("%w: array index %d is out of bounds", ErrConflict, index)
+	}
+
+	return index, nil
+}
+
+// clone returns a deep copy of value, so that a copied value does not share maps or slices with its
+// source.
+func clone(value any) (any, error) {
+	data, err := json.Marshal(value)
+	if err != nil {
+		return nil, err
+	}
+
+	var copied any
+	if err = json.Unmarshal(data, &copied); err != nil {
+		return nil, err
+	}
+	return copied, nil
+}
@@ -0,0 +1,169 @@
+package patch
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMerge(t *testing.T) {
+	testCases := map[string]struct {
+		doc            string
+		patch          string
+		expectedResult string
+		expectedError  error
+	}{
+		"replace member": {
+			doc:            `{"first_name":"John","last_name":"Doe"}`,
+			patch:          `{"last_name":"Smith"}`,
+			expectedResult: `{"first_name":"John","last_name":"Smith"}`,
+		},
+		"add member": {
+			doc:            `{"first_name":"John"}`,
+			patch:          `{"last_name":"Doe"}`,
+			expectedResult: `{"first_name":"John","last_name":"Doe"}`,
+		},
+		"null removes member": {
+			doc:            `{"first_name":"John","last_name":"Doe"}`,
+			patch:          `{"last_name":null}`,
+			expectedResult: `{"first_name":"John"}`,
+		},
+		"nested objects are merged": {
+			doc:            `{"a":{"b":1,"c":2}}`,
+			patch:          `{"a":{"b":null,"d":3}}`,
+			expectedResult: `{"a":{"c":2,"d":3}}`,
+		},
+		"arrays are replaced": {
+			doc:            `{"a":[1,2]}`,
+			patch:          `{"a":[3]}`,
+			expectedResult: `{"a":[3]}`,
+		},
+		"non-object patch replaces document": {
+			doc:            `{"a":1}`,
+			patch:          `"b"`,
+			expectedResult: `"b"`,
+		},
+		"invalid patch": {
+			doc:           `{"a":1}`,
+			patch:         `{"a":`,
+			expectedError: ErrInvalid,
+		},
+	}
+
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			result, err := Merge([]byte(tc.doc), []byte(tc.patch))
+
+			assert.ErrorIs(t, err, tc.expectedError, "Wrong error")
+			if tc.expectedError == nil {
+				assert.JSONEq(t, tc.expectedResult, string(result), "Wrong result")
+			}
+		})
+	}
+}
+
+func TestApply(t *testing.T) {
+	testCases := map[string]struct {
+		doc            string
+		patch          string
+		expectedResult string
+		expectedError  error
+	}{
+		"add member": {
+			doc:            `{"first_name":"John"}`,
+			patch:          `[{"op":"add","path":"/last_name","value":"Doe"}]`,
+			expectedResult: `{"first_name":"John","last_name":"Doe"}`,
+		},
+		"add to array": {
+			doc:            `{"a":[1,3]}`,
+			patch:          `[{"op":"add","path":"/a/1","value":2},{"op":"add","path":"/a/-","value":4}]`,
+			expectedResult: `{"a":[1,2,3,4]}`,
+		},
+		"remove member": {
+			doc:            `{"first_name":"John","last_name":"Doe"}`,
+			patch:          `[{"op":"remove","path":"/last_name"}]`,
+			expectedResult: `{"first_name":"John"}`,
+		},
+		"remove from array": {
+			doc:            `{"a":[1,2,3]}`,
+			patch:          `[{"op":"remove","path":"/a/1"}]`,
+			expectedResult: `{"a":[1,3]}`,
+		},
+		"replace member": {
+			doc:            `{"last_name":"Doe","user_id":1}`,
+			patch:          `[{"op":"replace","path":"/last_name","value":"Smith"},{"op":"replace","path":"/user_id","value":2}]`,
+			expectedResult: `{"last_name":"Smith","user_id":2}`,
+		},
+		"move member": {
+			doc:            `{"a":{"b":1},"c":{}}`,
+			patch:          `[{"op":"move","from":"/a/b","path":"/c/d"}]`,
+			expectedResult: `{"a":{},"c":{"d":1}}`,
+		},
+		"copy member": {
+			doc:            `{"a":{"b":[1]}}`,
+			patch:          `[{"op":"copy","from":"/a/b","path":"/c"},{"op":"add","path":"/c/-","value":2}]`,
+			expectedResult: `{"a":{"b":[1]},"c":[1,2]}`,
+		},
+		"escaped path": {
+			doc:            `{"a/b":1,"c~d":2}`,
+			patch:          `[{"op":"replace","path":"/a~1b","value":3},{"op":"remove","path":"/c~0d"}]`,
+			expectedResult: `{"a/b":3}`,
+		},
+		"test passes": {
+			doc:            `{"role":"Customer","user_id":1}`,
+			patch:          `[{"op":"test","path":"/role","value":"Customer"},{"op":"replace","path":"/role","value":"Employee"}]`,
+			expectedResult: `{"role":"Employee","user_id":1}`,
+		},
+		"test fails": {
+			doc:           `{"role":"Customer"}`,
+			patch:         `[{"op":"test","path":"/role","value":"Employee"}]`,
+			expectedError: ErrConflict,
+		},
+		"replace missing member": {
+			doc:           `{"first_name":"John"}`,
+			patch:         `[{"op":"replace","path":"/last_name","value":"Doe"}]`,
+			expectedError: ErrConflict,
+		},
+		"array index out of bounds": {
+			doc:           `{"a":[1]}`,
+			patch:         `[{"op":"add","path":"/a/2","value":2}]`,
+			expectedError: ErrConflict,
+		},
+		"move into own child": {
+			doc:           `{"a":{"b":{}}}`,
+			patch:         `[{"op":"move","from":"/a","path":"/a/b/c"}]`,
+			expectedError: ErrInvalid,
+		},
+		"missing value": {
+			doc:           `{"a":1}`,
+			patch:         `[{"op":"add","path":"/b"}]`,
+			expectedError: ErrInvalid,
+		},
+		"unknown op": {
+			doc:           `{"a":1}`,
+			patch:         `[{"op":"increment","path":"/a"}]`,
+			expectedError: ErrInvalid,
+		},
+		"path without leading slash": {
+			doc:           `{"a":1}`,
+			patch:         `[{"op":"remove","path":"a"}]`,
+			expectedError: ErrInvalid,
+		},
+		"patch is not an array": {
+			doc:           `{"a":1}`,
+			patch:         `{"op":"remove","path":"/a"}`,
+			expectedError: ErrInvalid,
+		},
+	}
+
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			result, err := Apply([]byte(tc.doc), []byte(tc.patch))
+
+			assert.ErrorIs(t, err, tc.expectedError, "Wrong error")
+			if tc.expectedError == nil {
+				assert.JSONEq(t, tc.expectedResult, string(result), "Wrong result")
+			}
+		})
+	}
+}
@@ -0,0 +1,221 @@
+package policy
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"slices"
+)
+
+// Action is something a principal can do with users.
+type Action string
+
+const (
+	ActionList    Action = "list"
+	ActionRead    Action = "read"
+	ActionCreate  Action = "create"
+	ActionUpdate  Action = "update"
+	ActionDelete  Action = "delete"
+	ActionRestore Action = "restore"
+)
+
+// Actions are all the actions a rule can grant.
+var Actions = []Action{ActionList, ActionRead, ActionCreate, ActionUpdate, ActionDelete, ActionRestore}
+
+// collectionActions are the actions that are not taken on a single user, which can therefore not
+// be limited to the own record of a principal.
+var collectionActions = []Action{ActionList, ActionCreate}
+
+// Scope is the set of users an action is granted on.
+type Scope string
+
+const (
+	// ScopeAll grants an action on every user.
+	ScopeAll Scope = "all"
+	// ScopeOwn grants an action only on the user whose user_id is the one of the principal.
+	ScopeOwn Scope = "own"
+)
+
+// Rule is what a role may do. Actions maps every action the role may take to the users it may take
+// it on, and ReadOnlyFields lists the fields of a user, by their JSON name, the role may not change
+// when updating one.
+type Rule struct {
+	Actions        map[Action]Scope `json:"actions"`
+	ReadOnlyFields []string         `json:"read_only_fields,omitempty"`
+}
+
+// Policy maps every role to its rule. Roles that are not in the policy may not do anything.
+type Policy map[string]Rule
+
+// Default returns the policy used unless another is configured: Employees may list and manage all
+// users, while Customers may only read and update their own record, without changing its role or
+// user_id.
+func Default() Policy {
+	return Policy{
+		"Employee": {
+			Actions: map[Action]Scope{
+				ActionList:    ScopeAll,
+				ActionRead:    ScopeAll,
+				ActionCreate:  ScopeAll,
+				ActionUpdate:  ScopeAll,
+				ActionDelete:  ScopeAll,
+				ActionRestore: ScopeAll,
+			},
+		},
+		"Customer": {
+			Actions: map[Action]Scope{
+				ActionRead:   ScopeOwn,
+				ActionUpdate: ScopeOwn,
+			},
+			ReadOnlyFields: []string{"role", "user_id"},
+		},
+	}
+}
+
+// Parse decodes a policy from JSON, in the form
+//
+//	{"Customer": {"actions": {"read": "own", "update": "own"}, "read_only_fields": ["role"]}}
+//
+// An error is returned for unknown actions and scopes, and for actions on a collection of users
+// that are limited to the own record.
+func Parse(data []byte) (Policy, error) {
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.DisallowUnknownFields()
+
+	var policy Policy
+	if err := decoder.Decode(&policy); err != nil {
+		return nil, fmt.Errorf("[in policy.Parse]: %w", err)
+	}
+
+	for role, rule := range policy {
+		for action, scope := range rule.Actions {
+			switch {
+			case !slices.Contains(Actions, action):
+				return nil, fmt.Errorf("[in policy.Parse] role %q: unknown action %q", role, action)
+			case scope != ScopeAll && scope != ScopeOwn:
+				return nil, fmt.Errorf("[in policy.Parse] role %q: unknown scope %q for %s", role, scope, action)
+			case scope == ScopeOwn && slices.Contains(collectionActions, action):
+				return nil, fmt.Errorf("[in policy.Parse] role %q: %s can not be limited to the own record", role, action)
+			}
+		}
+	}
+
+	return policy, nil
+}
+
+// LoadFile reads a policy from the JSON file at path, see Parse.
+func LoadFile(path string) (Policy, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("[in policy.LoadFile]: %w", err)
+	}
+
+	policy, err := Parse(data)
+	if err != nil {
+		return nil, fmt.Errorf("[in policy.LoadFile] %s: %w", path, err)
+	}
+
+	return policy, nil
+}
+
+// New returns the policy of the JSON file at path, see Parse, or Default if path is empty.
+func New(path string) (Policy, error) {
+	if path == "" {
+		return Default(), nil
+	}
+	return LoadFile(path)
+}
+
+// Principal returns the principal for the user with userID and role, who is granted the rule of
+// role. A role that is not in p is granted nothing.
+func (p Policy) Principal(userID uint, role string) Principal {
+	return Principal{UserID: userID, Role: role, rule: p[role]}
+}
+
+// ErrForbidden is wrapped by every *ForbiddenError.
+var ErrForbidden = errors.New("forbidden")
+
+// ForbiddenError is returned when a principal is refused an action. Field is set when the action
+// is refused because it changes a read-only field, and Owner when it is refused because the user
+// acted on is not the own record of the principal.
+type ForbiddenError struct {
+	Role   string
+	Action Action
+	Field  string
+	Owner  bool
+}
+
+func (e *ForbiddenError) Error() string {
+	switch {
+	case e.Field != "":
+		return fmt.Sprintf("role %q may not change %s", e.Role, e.Field)
+	case e.Owner:
+		return fmt.Sprintf("role %q may only %s its own record", e.Role, e.Action)
+	default:
+		return fmt.Sprintf("role %q may not %s users", e.Role, e.Action)
+	}
+}
+
+func (e *ForbiddenError) Unwrap() error {
+	return ErrForbidden
+}
+
+// Principal is the user making a request, with the rule of their role.
+type Principal struct {
+	UserID uint
+	Role   string
+	rule   Rule
+}
+
+// Scope returns the users p may take action on, or a *ForbiddenError if p may not take it at all.
+func (p Principal) Scope(action Action) (Scope, error) {
+	scope, ok := p.rule.Actions[action]
+	if !ok {
+		return "", &ForbiddenError{Role: p.Role, Action: action}
+	}
+	return scope, nil
+}
+
+// Authorize returns a *ForbiddenError if p may not take action on the user whose user_id is owner.
+func (p Principal) Authorize(action Action, owner uint) error {
+	scope, err := p.Scope(action)
+	if err != nil {
+		return err
+	}
+	if scope == ScopeOwn && owner != p.UserID {
+		return &ForbiddenError{Role: p.Role, Action: action, Owner: true}
+	}
+	return nil
+}
+
+// AuthorizeChanges returns a *ForbiddenError if any of fields, the JSON names of the fields an
+// update changes, is read-only for p.
+func (p Principal) AuthorizeChanges(fields []string) error {
+	for _, field := range fields {
+		if slices.Contains(p.rule.ReadOnlyFields, field) {
+			return &ForbiddenError{Role: p.Role, Action: ActionUpdate, Field: field}
+		}
+	}
+	return nil
+}
+
+// HasReadOnlyFields reports whether p may not change some fields of the users it may update.
+func (p Principal) HasReadOnlyFields() bool {
+	return len(p.rule.ReadOnlyFields) > 0
+}
+
+type principalKey struct{}
+
+// WithPrincipal returns a copy of ctx carrying principal as the user making the request.
+func WithPrincipal(ctx context.Context, principal Principal) context.Context {
+	return context.WithValue(ctx, principalKey{}, principal)
+}
+
+// FromContext returns the principal set on ctx with WithPrincipal, and whether there is one.
+func FromContext(ctx context.Context) (Principal, bool) {
+	principal, ok := ctx.Value(principalKey{}).(Principal)
+	return principal, ok
+}
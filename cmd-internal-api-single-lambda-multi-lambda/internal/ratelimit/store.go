@@ -0,0 +1,160 @@
+package ratelimit
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ErrInvalidLimit is returned when a limit is not of the form <requests>/<period>.
+var ErrInvalidLimit = errors.New("invalid rate limit")
+
+// Limit is a token bucket that holds Requests tokens and is refilled with Requests tokens every
+// Period. Every request takes a token, so a client can make Requests requests at once and then keeps
+// being allowed Requests requests per Period. The zero Limit does not limit anything.
+type Limit struct {
+	Requests int
+	Period   time.Duration
+}
+
+// Limits are the limits of the routes of a service. Routes holds the limits of single routes, keyed
+// by method and route pattern such as `GET /api/user/{ID}`, each with a bucket of its own. Every
+// other route takes from a bucket shared by all of them, which holds Default.
+type Limits struct {
+	Default Limit
+	Routes  map[string]Limit
+}
+
+// ParseLimit parses a limit of the form <requests>/<period>, such as 100/1m, where period is a
+// time.Duration.
+func ParseLimit(value string) (Limit, error) {
+	requests, period, ok := strings.Cut(strings.TrimSpace(value), "/")
+	if !ok {
+		return Limit{}, fmt.Errorf("[in ParseLimit] %q: %w", value, ErrInvalidLimit)
+	}
+
+	var (
+		limit Limit
+		err   error
+	)
+	limit.Requests, err = strconv.Atoi(requests)
+	if err != nil || limit.Requests < 1 {
+		return Limit{}, fmt.Errorf("[in ParseLimit] %q: requests must be more than 0: %w", value, ErrInvalidLimit)
+	}
+	limit.Period, err = time.ParseDuration(period)
+	if err != nil || limit.Period <= 0 {
+		return Limit{}, fmt.Errorf("[in ParseLimit] %q: period must be a positive duration: %w", value, ErrInvalidLimit)
+	}
+
+	return limit, nil
+}
+
+// ParseRouteLimits parses the limits of routes, keyed by method and route pattern such as
+// `GET /api/user/{ID}`, with ParseLimit.
+func ParseRouteLimits(routes map[string]string) (map[string]Limit, error) {
+	limits := make(map[string]Limit, len(routes))
+	for route, value := range routes {
+		limit, err := ParseLimit(value)
+		if err != nil {
+			return nil, fmt.Errorf("[in ParseRouteLimits] %s: %w", route, err)
+		}
+		limits[strings.TrimSpace(route)] = limit
+	}
+	return limits, nil
+}
+
+// Result is the state of a bucket after a request took, or failed to take, a token from it.
+// Remaining is the number of tokens left, Reset the time until the bucket is full again and
+// RetryAfter, for refused requests, the time until the next token is added.
+type Result struct {
+	Allowed    bool
+	Limit      int
+	Remaining  int
+	Reset      time.Duration
+	RetryAfter time.Duration
+}
+
+// Store keeps the token buckets of clients. MemoryStore keeps them in the memory of a single
+// instance. A Store keeping them in a shared cache lets the limits apply across instances.
+type Store interface {
+	// Take takes a token from the bucket with key, which holds limit, and reports whether there was
+	// one.
+	Take(ctx context.Context, key string, limit Limit) (Result, error)
+}
+
+// sweepInterval is how often MemoryStore drops buckets that have refilled completely, which are
+// the same as no bucket at all.
+const sweepInterval = time.Minute
+
+type bucket struct {
+	tokens  float64
+	updated time.Time
+	full    time.Time
+}
+
+// MemoryStore is a Store keeping the buckets in memory, so every instance has limits of its own.
+type MemoryStore struct {
+	mu        sync.Mutex
+	buckets   map[string]*bucket
+	now       func() time.Time
+	lastSweep time.Time
+}
+
+// NewMemoryStore returns a new MemoryStore struct.
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{
+		buckets: make(map[string]*bucket),
+		now:     time.Now,
+	}
+}
+
+// Take takes a token from the bucket with key, after refilling it for the time since it was last
+// used. It never returns an error.
+func (s *MemoryStore) Take(_ context.Context, key string, limit Limit) (Result, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := s.now()
+	capacity := float64(limit.Requests)
+	perSecond := capacity / limit.Period.Seconds()
+
+	b, ok := s.buckets[key]
+	if !ok {
+		b = &bucket{tokens: capacity, updated: now}
+		s.buckets[key] = b
+	}
+	b.tokens = math.Min(capacity, b.tokens+now.Sub(b.updated).Seconds()*perSecond)
+	b.updated = now
+
+	result := Result{Limit: limit.Requests}
+	if b.tokens >= 1 {
+		b.tokens--
+		result.Allowed = true
+	} else {
+		result.RetryAfter = seconds((1 - b.tokens) / perSecond)
+	}
+	result.Remaining = int(b.tokens)
+	result.Reset = seconds((capacity - b.tokens) / perSecond)
+	b.full = now.Add(result.Reset)
+
+	if now.Sub(s.lastSweep) >= sweepInterval {
+		for key, b := range s.buckets {
+			if !b.full.After(now) {
+				delete(s.buckets, key)
+			}
+		}
+		s.lastSweep = now
+	}
+
+	return result, nil
+}
+
+// seconds returns value seconds as a time.Duration.
+func seconds(value float64) time.Duration {
+	return time.Duration(value * float64(time.Second))
+}
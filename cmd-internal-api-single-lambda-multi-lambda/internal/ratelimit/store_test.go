@@ -0,0 +1,128 @@
+package ratelimit
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseLimit(t *testing.T) {
+	tests := map[string]struct {
+		value         string
+		expectedLimit Limit
+		expectedErr   error
+	}{
+		"requests per minute": {
+			value:         "100/1m",
+			expectedLimit: Limit{Requests: 100, Period: time.Minute},
+		},
+		"surrounding spaces": {
+			value:         " 5/1s ",
+			expectedLimit: Limit{Requests: 5, Period: time.Second},
+		},
+		"no period": {
+			value:       "100",
+			expectedErr: fmt.Errorf("[in ParseLimit] %q: %w", "100", ErrInvalidLimit),
+		},
+		"no requests": {
+			value:       "0/1m",
+			expectedErr: fmt.Errorf("[in ParseLimit] %q: requests must be more than 0: %w", "0/1m", ErrInvalidLimit),
+		},
+		"invalid period": {
+			value:       "100/minute",
+			expectedErr: fmt.Errorf("[in ParseLimit] %q: period must be a positive duration: %w", "100/minute", ErrInvalidLimit),
+		},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			limit, err := ParseLimit(tc.value)
+
+			assert.Equal(t, tc.expectedErr, err, "errors did not match")
+			assert.Equal(t, tc.expectedLimit, limit, "limits did not match")
+		})
+	}
+}
+
+func TestParseRouteLimits(t *testing.T) {
+	limits, err := ParseRouteLimits(map[string]string{" POST /api/user:batch": "10/1m", "GET /api/user/{ID}": "5/1s"})
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]Limit{
+		"POST /api/user:batch": {Requests: 10, Period: time.Minute},
+		"GET /api/user/{ID}":   {Requests: 5, Period: time.Second},
+	}, limits)
+
+	_, err = ParseRouteLimits(map[string]string{"GET /api/user": "fast"})
+	assert.ErrorIs(t, err, ErrInvalidLimit)
+}
+
+func TestMemoryStoreTake(t *testing.T) {
+	limit := Limit{Requests: 2, Period: 10 * time.Second}
+	start := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+
+	// each step takes a token at its offset from start
+	tests := map[string]struct {
+		offsets         []time.Duration
+		expectedResults []Result
+	}{
+		"burst allowed then refused": {
+			offsets: []time.Duration{0, 0, 0},
+			expectedResults: []Result{
+				{Allowed: true, Limit: 2, Remaining: 1, Reset: 5 * time.Second},
+				{Allowed: true, Limit: 2, Remaining: 0, Reset: 10 * time.Second},
+				{Allowed: false, Limit: 2, Remaining: 0, Reset: 10 * time.Second, RetryAfter: 5 * time.Second},
+			},
+		},
+		"refilled over time": {
+			offsets: []time.Duration{0, 0, 5 * time.Second, 5 * time.Second},
+			expectedResults: []Result{
+				{Allowed: true, Limit: 2, Remaining: 1, Reset: 5 * time.Second},
+				{Allowed: true, Limit: 2, Remaining: 0, Reset: 10 * time.Second},
+				{Allowed: true, Limit: 2, Remaining: 0, Reset: 10 * time.Second},
+				{Allowed: false, Limit: 2, Remaining: 0, Reset: 10 * time.Second, RetryAfter: 5 * time.Second},
+			},
+		},
+		"refilled up to limit": {
+			offsets: []time.Duration{0, time.Hour},
+			expectedResults: []Result{
+				{Allowed: true, Limit: 2, Remaining: 1, Reset: 5 * time.Second},
+				{Allowed: true, Limit: 2, Remaining: 1, Reset: 5 * time.Second},
+			},
+		},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			store := NewMemoryStore()
+
+			for i, offset := range tc.offsets {
+				store.now = func() time.Time { return start.Add(offset) }
+
+				result, err := store.Take(context.Background(), "client", limit)
+
+				assert.NoError(t, err)
+				assert.Equal(t, tc.expectedResults[i], result, "result %d did not match", i)
+			}
+		})
+	}
+}
+
+func TestMemoryStoreBuckets(t *testing.T) {
+	limit := Limit{Requests: 1, Period: time.Minute}
+	start := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	store := NewMemoryStore()
+	store.now = func() time.Time { return start }
+
+	first, _ := store.Take(context.Background(), "first", limit)
+	second, _ := store.Take(context.Background(), "second", limit)
+	assert.True(t, first.Allowed, "first client refused")
+	assert.True(t, second.Allowed, "second client refused by the bucket of the first")
+
+	// once refilled, the buckets are dropped by the next sweep
+	store.now = func() time.Time { return start.Add(2 * time.Minute) }
+	_, _ = store.Take(context.Background(), "third", limit)
+	assert.Len(t, store.buckets, 1, "refilled buckets not dropped")
+}
@@ -1,9 +1,17 @@
 package routes
 
 import (
+	"net/http"
+	"time"
+
 	"github.com/go-chi/chi/v5"
 	"github.com/jha-captech/user-microservice/internal/handlers"
+	"github.com/jha-captech/user-microservice/internal/health"
+	"github.com/jha-captech/user-microservice/internal/models"
+	"github.com/jha-captech/user-microservice/internal/policy"
+	"github.com/jha-captech/user-microservice/internal/ratelimit"
 	"github.com/jha-captech/user-microservice/internal/service"
+	"github.com/jha-captech/user-microservice/internal/stream"
 )
 
 type sLogger interface {
@@ -15,32 +23,179 @@ type sLogger interface {
 type Option func(*routerOptions)
 
 type routerOptions struct {
-	registerHealthRoute bool
+	healthChecker  *health.Checker
+	adminToken     string
+	authenticate   func(http.Handler) http.Handler
+	policy         policy.Policy
+	webhooks       *service.Webhook
+	apiKeys        *service.APIKey
+	rateLimitStore ratelimit.Store
+	rateLimits     ratelimit.Limits
+	userEvents     *stream.Broker
+	heartbeat      time.Duration
 }
 
-// WithRegisterHealthRoute controls whether a healthcheck route will be registered. If `false` is
-// passed in or this function is not called, the default is `false`.
-func WithRegisterHealthRoute(registerHealthRoute bool) Option {
+// WithHealthChecks registers the liveness probe and the readiness probe, which reports the
+// components checked by checker. If this function is not called, the probes are not registered.
+func WithHealthChecks(checker *health.Checker) Option {
 	return func(options *routerOptions) {
-		options.registerHealthRoute = registerHealthRoute
+		options.healthChecker = checker
 	}
 }
 
-func RegisterRoutes(r *chi.Mux, logger sLogger, svs *service.User, opts ...Option) {
-	options := routerOptions{
-		registerHealthRoute: false,
+// WithAdminToken sets the token that administrators pass in the X-Admin-Token header to use
+// admin-only routes. If this function is not called or token is empty, admin-only routes are
+// refused for everyone.
+func WithAdminToken(token string) Option {
+	return func(options *routerOptions) {
+		options.adminToken = token
+	}
+}
+
+// WithAuthentication sets the middleware that authenticates requests to the user and webhook routes,
+// which is handlers.Authenticate for the API and handlers.AuthenticateAuthorizer for Lambda
+// functions. If this function is not called, requests are not authenticated, which is only meant for
+// local development.
+func WithAuthentication(authenticate func(http.Handler) http.Handler) Option {
+	return func(options *routerOptions) {
+		options.authenticate = authenticate
+	}
+}
+
+// WithAuthorization sets the policy that decides what the user making a request may do with users,
+// by the role of the user named in the user_id claim of their token. It only applies to requests
+// authenticated with the middleware of WithAuthentication. If this function is not called, every
+// authenticated request may do everything.
+func WithAuthorization(rules policy.Policy) Option {
+	return func(options *routerOptions) {
+		options.policy = rules
+	}
+}
+
+// WithWebhooks registers the admin-only routes managing the webhook subscriptions of webhooks. If
+// this function is not called, the webhook routes are not registered, which is meant for deployments
+// that do not run the webhook worker.
+func WithWebhooks(webhooks *service.Webhook) Option {
+	return func(options *routerOptions) {
+		options.webhooks = webhooks
+	}
+}
+
+// WithAPIKeys lets services and batch jobs authenticate with the API keys of apiKeys, alongside the
+// middleware of WithAuthentication, and registers the admin-only routes minting and revoking them.
+// Requests made with an API key may only use the user routes its scopes allow. If this function is
+// not called, API keys are not accepted.
+func WithAPIKeys(apiKeys *service.APIKey) Option {
+	return func(options *routerOptions) {
+		options.apiKeys = apiKeys
 	}
+}
+
+// WithRateLimit limits the requests each client makes to the user, webhook and API key routes to
+// limits, with the token buckets kept in store. Clients are told apart by their API key, the subject
+// of their token or their IP. If this function is not called, requests are not rate limited.
+func WithRateLimit(store ratelimit.Store, limits ratelimit.Limits) Option {
+	return func(options *routerOptions) {
+		options.rateLimitStore = store
+		options.rateLimits = limits
+	}
+}
+
+// WithUserEvents registers the Server-Sent Events stream of the user changes published to broker,
+// which sends a heartbeat every heartbeat. If this function is not called, the stream is not
+// registered, which is meant for deployments that can not hold long-lived connections.
+func WithUserEvents(broker *stream.Broker, heartbeat time.Duration) Option {
+	return func(options *routerOptions) {
+		options.userEvents = broker
+		options.heartbeat = heartbeat
+	}
+}
+
+func RegisterRoutes(r *chi.Mux, logger sLogger, svs *service.User, opts ...Option) {
+	options := routerOptions{}
 	for _, opt := range opts {
 		opt(&options)
 	}
 
-	if options.registerHealthRoute {
-		r.Get("/api/health-check", handlers.HandleHealth(logger))
+	r.Use(handlers.IdentifyActor)
+
+	r.NotFound(handlers.HandleNotFound(logger))
+	r.MethodNotAllowed(handlers.HandleMethodNotAllowed(logger))
+
+	if options.healthChecker != nil {
+		r.Get("/api/health/live", handlers.HandleLiveness(logger))
+		r.Get("/api/health/ready", handlers.HandleReadiness(logger, options.healthChecker))
 	}
 
-	r.Get("/api/user", handlers.HandleListUsers(logger, svs))
-	r.Get("/api/user/{ID}", handlers.HandleFetchUser(logger, svs))
-	r.Put("/api/user/{ID}", handlers.HandleUpdateUser(logger, svs))
-	r.Post("/api/user", handlers.HandleCreateUser(logger, svs))
-	r.Delete("/api/user/{ID}", handlers.HandleDeleteUser(logger, svs))
+	r.Group(func(r chi.Router) {
+		authenticate := options.authenticate
+		if options.apiKeys != nil {
+			authenticate = handlers.AuthenticateAPIKey(logger, options.apiKeys, authenticate)
+		}
+		if authenticate != nil {
+			r.Use(authenticate)
+		}
+		if options.rateLimitStore != nil {
+			r.Use(handlers.RateLimit(logger, options.rateLimitStore, options.rateLimits))
+		}
+		if options.policy != nil {
+			r.Use(handlers.Authorize(logger, svs, options.policy))
+		}
+
+		permit := func(scope models.APIKeyScope, actions ...policy.Action) func(http.Handler) http.Handler {
+			return chi.Chain(handlers.RequireScope(logger, scope), handlers.Permit(logger, svs, actions...)).Handler
+		}
+
+		r.With(permit(models.ScopeUsersRead, policy.ActionList)).Get("/api/user", handlers.HandleListUsers(logger, svs))
+		if options.userEvents != nil {
+			r.With(permit(models.ScopeUsersRead, policy.ActionList)).
+				Get("/api/user/events", handlers.HandleUserEvents(logger, options.userEvents, options.heartbeat))
+		}
+		r.With(permit(models.ScopeUsersRead, policy.ActionRead)).Get("/api/user/{ID}", handlers.HandleFetchUser(logger, svs))
+		r.With(permit(models.ScopeUsersRead, policy.ActionRead)).
+			Get("/api/user/{ID}/history", handlers.HandleListUserHistory(logger, svs))
+		r.With(permit(models.ScopeUsersRead, policy.ActionRead)).
+			Get("/api/user/{ID}/history/{version}", handlers.HandleFetchUserHistory(logger, svs))
+		r.With(permit(models.ScopeUsersWrite, policy.ActionUpdate)).
+			Put("/api/user/{ID}", handlers.HandleUpdateUser(logger, svs))
+		r.With(permit(models.ScopeUsersWrite, policy.ActionUpdate)).
+			Patch("/api/user/{ID}", handlers.HandlePatchUser(logger, svs))
+		r.With(permit(models.ScopeUsersWrite, policy.ActionCreate)).Post("/api/user", handlers.HandleCreateUser(logger, svs))
+		// batches may delete users, which takes the admin scope
+		r.With(permit(models.ScopeUsersAdmin, policy.ActionCreate, policy.ActionUpdate, policy.ActionDelete)).
+			Post("/api/user:batch", handlers.HandleBatchUsers(logger, svs))
+		r.With(permit(models.ScopeUsersWrite, policy.ActionCreate, policy.ActionUpdate)).
+			Post("/api/user/import", handlers.HandleImportUsers(logger, svs))
+		r.With(permit(models.ScopeUsersAdmin, policy.ActionRestore)).
+			Post("/api/user/{ID}/restore", handlers.HandleRestoreUser(logger, svs))
+		r.With(permit(models.ScopeUsersAdmin, policy.ActionDelete), handlers.AuthenticateAdmin(options.adminToken)).
+			Delete("/api/user/{ID}", handlers.HandleDeleteUser(logger, svs))
+
+		if options.webhooks != nil {
+			r.Route("/api/webhook", func(r chi.Router) {
+				r.Use(handlers.AuthenticateAdmin(options.adminToken), handlers.RequireAdmin(logger))
+
+				r.Get("/", handlers.HandleListWebhooks(logger, options.webhooks))
+				r.Post("/", handlers.HandleCreateWebhook(logger, options.webhooks))
+				r.Get("/{ID}", handlers.HandleFetchWebhook(logger, options.webhooks))
+				r.Put("/{ID}", handlers.HandleUpdateWebhook(logger, options.webhooks))
+				r.Delete("/{ID}", handlers.HandleDeleteWebhook(logger, options.webhooks))
+				r.Get("/{ID}/deliveries", handlers.HandleListWebhookDeliveries(logger, options.webhooks))
+				r.Post(
+					"/{ID}/deliveries/{deliveryID}/redeliver",
+					handlers.HandleRedeliverWebhookDelivery(logger, options.webhooks),
+				)
+			})
+		}
+
+		if options.apiKeys != nil {
+			r.Route("/api/api-key", func(r chi.Router) {
+				r.Use(handlers.AuthenticateAdmin(options.adminToken), handlers.RequireAdmin(logger))
+
+				r.Get("/", handlers.HandleListAPIKeys(logger, options.apiKeys))
+				r.Post("/", handlers.HandleCreateAPIKey(logger, options.apiKeys))
+				r.Delete("/{ID}", handlers.HandleRevokeAPIKey(logger, options.apiKeys))
+			})
+		}
+	})
 }
@@ -0,0 +1,193 @@
+package service
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+
+	"github.com/jha-captech/user-microservice/internal/models"
+	"github.com/lib/pq"
+)
+
+var (
+	// ErrAPIKeyNotFound is returned when no API key exists with the requested ID.
+	ErrAPIKeyNotFound = errors.New("api key not found")
+
+	// ErrInvalidAPIKey is returned when an API key is unknown, expired or revoked.
+	ErrInvalidAPIKey = errors.New("invalid api key")
+)
+
+const (
+	// apiKeyPrefix starts every API key, so that leaked keys are easy to recognize.
+	apiKeyPrefix = "umk_"
+
+	// apiKeyBytes is the number of random bytes in an API key.
+	apiKeyBytes = 32
+
+	// apiKeyPrefixLength is the number of leading characters of an API key stored in the clear.
+	apiKeyPrefixLength = len(apiKeyPrefix) + 8
+)
+
+// apiKeyColumns are the columns of the api_keys table read into a models.APIKey, in the order
+// scanned by scanAPIKey.
+const apiKeyColumns = `"id", "name", "prefix", "scopes", "expires_at", "last_used_at", "revoked_at", "created_at"`
+
+// scanAPIKey reads the apiKeyColumns of row into a models.APIKey.
+func scanAPIKey(row rowScanner) (models.APIKey, error) {
+	var (
+		key    models.APIKey
+		scopes pq.StringArray
+	)
+	err := row.Scan(
+		&key.ID, &key.Name, &key.Prefix, &scopes, &key.ExpiresAt, &key.LastUsedAt, &key.RevokedAt, &key.CreatedAt,
+	)
+	if err != nil {
+		return models.APIKey{}, err
+	}
+
+	key.Scopes = make([]models.APIKeyScope, len(scopes))
+	for i, scope := range scopes {
+		key.Scopes[i] = models.APIKeyScope(scope)
+	}
+
+	return key, nil
+}
+
+// scopeNames returns scopes as a Postgres text array.
+func scopeNames(scopes []models.APIKeyScope) pq.StringArray {
+	names := make(pq.StringArray, len(scopes))
+	for i, scope := range scopes {
+		names[i] = string(scope)
+	}
+	return names
+}
+
+// hashAPIKey returns the hex encoded SHA-256 hash of key, which is what is stored of it. Keys are
+// long and random, so a fast hash is enough and lets keys be looked up by their hash.
+func hashAPIKey(key string) string {
+	sum := sha256.Sum256([]byte(key))
+	return hex.EncodeToString(sum[:])
+}
+
+// APIKey manages the API keys that services and batch jobs authenticate with.
+type APIKey struct {
+	database *sql.DB
+}
+
+// NewAPIKey returns a new APIKey struct.
+func NewAPIKey(db *sql.DB) *APIKey {
+	return &APIKey{
+		database: db,
+	}
+}
+
+// ListKeys returns all API keys, including expired and revoked ones, oldest first.
+func (s APIKey) ListKeys(ctx context.Context) ([]models.APIKey, error) {
+	rows, err := s.database.QueryContext(ctx, `SELECT `+apiKeyColumns+` FROM "api_keys" ORDER BY "id"`)
+	if err != nil {
+		return nil, fmt.Errorf("[in ListKeys]: %w", err)
+	}
+	defer rows.Close()
+
+	keys := []models.APIKey{}
+	for rows.Next() {
+		key, err := scanAPIKey(rows)
+		if err != nil {
+			return nil, fmt.Errorf("[in ListKeys]: %w", err)
+		}
+		keys = append(keys, key)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("[in ListKeys]: %w", err)
+	}
+
+	return keys, nil
+}
+
+// CreateKey mints a new API key with the name, scopes and expiry of key. It returns the stored key
+// along with the key itself, which can not be retrieved again as only its hash is stored.
+func (s APIKey) CreateKey(ctx context.Context, key models.APIKey) (models.APIKey, string, error) {
+	random := make([]byte, apiKeyBytes)
+	if _, err := rand.Read(random); err != nil {
+		return models.APIKey{}, "", fmt.Errorf("[in CreateKey] generate key: %w", err)
+	}
+	secret := apiKeyPrefix + base64.RawURLEncoding.EncodeToString(random)
+
+	created, err := scanAPIKey(s.database.QueryRowContext(
+		ctx,
+		`
+		INSERT INTO "api_keys" ("name", "prefix", "key_hash", "scopes", "expires_at")
+		VALUES ($1, $2, $3, $4, $5)
+		RETURNING
+			`+apiKeyColumns,
+		key.Name,
+		secret[:apiKeyPrefixLength],
+		hashAPIKey(secret),
+		scopeNames(key.Scopes),
+		key.ExpiresAt,
+	))
+	if err != nil {
+		return models.APIKey{}, "", fmt.Errorf("[in CreateKey]: %w", err)
+	}
+
+	return created, secret, nil
+}
+
+// RevokeKey revokes the API key with ID, which is refused from then on. Revoking a key that is
+// already revoked keeps the time it was first revoked.
+func (s APIKey) RevokeKey(ctx context.Context, ID int) (models.APIKey, error) {
+	key, err := scanAPIKey(s.database.QueryRowContext(
+		ctx,
+		`
+		UPDATE
+			"api_keys"
+		SET
+			"revoked_at" = COALESCE("revoked_at", NOW())
+		WHERE
+			"id" = $1
+		RETURNING
+			`+apiKeyColumns,
+		ID,
+	))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			err = ErrAPIKeyNotFound
+		}
+		return models.APIKey{}, fmt.Errorf("[in RevokeKey]: %w", err)
+	}
+
+	return key, nil
+}
+
+// AuthenticateKey returns the API key secret, which must be neither expired nor revoked, and
+// records that it was used.
+func (s APIKey) AuthenticateKey(ctx context.Context, secret string) (models.APIKey, error) {
+	key, err := scanAPIKey(s.database.QueryRowContext(
+		ctx,
+		`
+		UPDATE
+			"api_keys"
+		SET
+			"last_used_at" = NOW()
+		WHERE
+			"key_hash" = $1
+			AND "revoked_at" IS NULL
+			AND ("expires_at" IS NULL OR "expires_at" > NOW())
+		RETURNING
+			`+apiKeyColumns,
+		hashAPIKey(secret),
+	))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			err = ErrInvalidAPIKey
+		}
+		return models.APIKey{}, fmt.Errorf("[in AuthenticateKey]: %w", err)
+	}
+
+	return key, nil
+}
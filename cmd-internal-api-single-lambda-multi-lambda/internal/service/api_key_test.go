@@ -0,0 +1,265 @@
+package service
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/jha-captech/user-microservice/internal/models"
+	"github.com/lib/pq"
+	"github.com/stretchr/testify/assert"
+)
+
+var apiKeyColumnNames = []string{
+	"id", "name", "prefix", "scopes", "expires_at", "last_used_at", "revoked_at", "created_at",
+}
+
+// argRecorder is a sqlmock.Argument that matches any value and records it.
+type argRecorder struct {
+	value driver.Value
+}
+
+func (a *argRecorder) Match(value driver.Value) bool {
+	a.value = value
+	return true
+}
+
+func (s *testSuit) TestListKeys() {
+	t := s.T()
+
+	const selectKeys = `SELECT "id", "name", "prefix", "scopes", "expires_at", "last_used_at", "revoked_at", "created_at" ` +
+		`FROM "api_keys" ORDER BY "id"`
+
+	createdAt := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	expiresAt := time.Date(2025, 5, 1, 12, 0, 0, 0, time.UTC)
+
+	testCases := map[string]struct {
+		mockReturn     *sqlmock.Rows
+		mockReturnErr  error
+		expectedReturn []models.APIKey
+		expectedError  error
+	}{
+		"Return keys": {
+			mockReturn: sqlmock.NewRows(apiKeyColumnNames).
+				AddRow(1, "nightly export", "umk_abcdefgh", []byte("{users:read}"), expiresAt, nil, nil, createdAt).
+				AddRow(2, "billing", "umk_hgfedcba", []byte("{users:read,users:write}"), nil, createdAt, createdAt, createdAt),
+			expectedReturn: []models.APIKey{
+				{
+					ID: 1, Name: "nightly export", Prefix: "umk_abcdefgh", Scopes: []models.APIKeyScope{models.ScopeUsersRead},
+					ExpiresAt: &expiresAt, CreatedAt: createdAt,
+				},
+				{
+					ID: 2, Name: "billing", Prefix: "umk_hgfedcba",
+					Scopes:     []models.APIKeyScope{models.ScopeUsersRead, models.ScopeUsersWrite},
+					LastUsedAt: &createdAt, RevokedAt: &createdAt, CreatedAt: createdAt,
+				},
+			},
+		},
+		"No keys": {
+			mockReturn:     sqlmock.NewRows(apiKeyColumnNames),
+			expectedReturn: []models.APIKey{},
+		},
+		"Error getting keys": {
+			mockReturnErr: errors.New("test"),
+			expectedError: fmt.Errorf("[in ListKeys]: %w", errors.New("test")),
+		},
+	}
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			mockReturn := tc.mockReturn
+			if mockReturn == nil {
+				mockReturn = &sqlmock.Rows{}
+			}
+			s.dbMock.
+				ExpectQuery(regexp.QuoteMeta(selectKeys)).
+				WillReturnRows(mockReturn).
+				WillReturnError(tc.mockReturnErr)
+
+			actualReturn, err := s.apiKeys.ListKeys(context.Background())
+
+			assert.Equal(t, tc.expectedError, err, "errors did not match")
+			assert.Equal(t, tc.expectedReturn, actualReturn, "returned data does not match")
+
+			err = s.dbMock.ExpectationsWereMet()
+			assert.NoError(t, err)
+		})
+	}
+}
+
+func (s *testSuit) TestCreateKey() {
+	t := s.T()
+
+	const insertKey = `INSERT INTO "api_keys" ("name", "prefix", "key_hash", "scopes", "expires_at") VALUES ($1, $2, $3, $4, $5) ` +
+		`RETURNING "id", "name", "prefix", "scopes", "expires_at", "last_used_at", "revoked_at", "created_at"`
+
+	createdAt := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	input := models.APIKey{Name: "nightly export", Scopes: []models.APIKeyScope{models.ScopeUsersRead}}
+
+	testCases := map[string]struct {
+		mockReturn     *sqlmock.Rows
+		mockReturnErr  error
+		expectedReturn models.APIKey
+		expectedError  error
+	}{
+		"Create key": {
+			mockReturn: sqlmock.NewRows(apiKeyColumnNames).
+				AddRow(1, "nightly export", "umk_abcdefgh", []byte("{users:read}"), nil, nil, nil, createdAt),
+			expectedReturn: models.APIKey{
+				ID: 1, Name: "nightly export", Prefix: "umk_abcdefgh", Scopes: []models.APIKeyScope{models.ScopeUsersRead},
+				CreatedAt: createdAt,
+			},
+		},
+		"Error creating key": {
+			mockReturnErr: errors.New("test"),
+			expectedError: fmt.Errorf("[in CreateKey]: %w", errors.New("test")),
+		},
+	}
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			mockReturn := tc.mockReturn
+			if mockReturn == nil {
+				mockReturn = &sqlmock.Rows{}
+			}
+			var prefix, hash argRecorder
+			s.dbMock.
+				ExpectQuery(regexp.QuoteMeta(insertKey)).
+				WithArgs(input.Name, &prefix, &hash, pq.StringArray{"users:read"}, nil).
+				WillReturnRows(mockReturn).
+				WillReturnError(tc.mockReturnErr)
+
+			actualReturn, secret, err := s.apiKeys.CreateKey(context.Background(), input)
+
+			assert.Equal(t, tc.expectedError, err, "errors did not match")
+			assert.Equal(t, tc.expectedReturn, actualReturn, "returned data does not match")
+			if tc.expectedError == nil {
+				assert.True(t, strings.HasPrefix(secret, "umk_"), "key does not start with umk_")
+				assert.Equal(t, secret[:12], prefix.value, "stored prefix does not match key")
+				assert.Equal(t, hashAPIKey(secret), hash.value, "stored hash does not match key")
+			} else {
+				assert.Empty(t, secret, "key returned on error")
+			}
+
+			err = s.dbMock.ExpectationsWereMet()
+			assert.NoError(t, err)
+		})
+	}
+}
+
+func (s *testSuit) TestRevokeKey() {
+	t := s.T()
+
+	const revokeKey = `UPDATE "api_keys" SET "revoked_at" = COALESCE("revoked_at", NOW()) WHERE "id" = $1 ` +
+		`RETURNING "id", "name", "prefix", "scopes", "expires_at", "last_used_at", "revoked_at", "created_at"`
+
+	createdAt := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	revokedAt := time.Date(2024, 5, 2, 12, 0, 0, 0, time.UTC)
+
+	testCases := map[string]struct {
+		inputID        int
+		mockReturn     *sqlmock.Rows
+		mockReturnErr  error
+		expectedReturn models.APIKey
+		expectedError  error
+	}{
+		"Revoke key": {
+			inputID: 1,
+			mockReturn: sqlmock.NewRows(apiKeyColumnNames).
+				AddRow(1, "nightly export", "umk_abcdefgh", []byte("{users:read}"), nil, nil, revokedAt, createdAt),
+			expectedReturn: models.APIKey{
+				ID: 1, Name: "nightly export", Prefix: "umk_abcdefgh", Scopes: []models.APIKeyScope{models.ScopeUsersRead},
+				RevokedAt: &revokedAt, CreatedAt: createdAt,
+			},
+		},
+		"Key with given ID does not exist": {
+			inputID:       2,
+			mockReturnErr: sql.ErrNoRows,
+			expectedError: fmt.Errorf("[in RevokeKey]: %w", ErrAPIKeyNotFound),
+		},
+	}
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			mockReturn := tc.mockReturn
+			if mockReturn == nil {
+				mockReturn = &sqlmock.Rows{}
+			}
+			s.dbMock.
+				ExpectQuery(regexp.QuoteMeta(revokeKey)).
+				WithArgs(tc.inputID).
+				WillReturnRows(mockReturn).
+				WillReturnError(tc.mockReturnErr)
+
+			actualReturn, err := s.apiKeys.RevokeKey(context.Background(), tc.inputID)
+
+			assert.Equal(t, tc.expectedError, err, "errors did not match")
+			assert.Equal(t, tc.expectedReturn, actualReturn, "returned data does not match")
+
+			err = s.dbMock.ExpectationsWereMet()
+			assert.NoError(t, err)
+		})
+	}
+}
+
+func (s *testSuit) TestAuthenticateKey() {
+	t := s.T()
+
+	const useKey = `UPDATE "api_keys" SET "last_used_at" = NOW() WHERE "key_hash" = $1 AND "revoked_at" IS NULL ` +
+		`AND ("expires_at" IS NULL OR "expires_at" > NOW()) ` +
+		`RETURNING "id", "name", "prefix", "scopes", "expires_at", "last_used_at", "revoked_at", "created_at"`
+
+	const secret = "umk_abcdefghijklmnopqrstuvwxyz0123456789ABCDEFG"
+
+	createdAt := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	usedAt := time.Date(2024, 5, 2, 12, 0, 0, 0, time.UTC)
+
+	testCases := map[string]struct {
+		mockReturn     *sqlmock.Rows
+		mockReturnErr  error
+		expectedReturn models.APIKey
+		expectedError  error
+	}{
+		"Valid key": {
+			mockReturn: sqlmock.NewRows(apiKeyColumnNames).
+				AddRow(1, "nightly export", "umk_abcdefgh", []byte("{users:read}"), nil, usedAt, nil, createdAt),
+			expectedReturn: models.APIKey{
+				ID: 1, Name: "nightly export", Prefix: "umk_abcdefgh", Scopes: []models.APIKeyScope{models.ScopeUsersRead},
+				LastUsedAt: &usedAt, CreatedAt: createdAt,
+			},
+		},
+		"Unknown, expired or revoked key": {
+			mockReturnErr: sql.ErrNoRows,
+			expectedError: fmt.Errorf("[in AuthenticateKey]: %w", ErrInvalidAPIKey),
+		},
+		"Error authenticating key": {
+			mockReturnErr: errors.New("test"),
+			expectedError: fmt.Errorf("[in AuthenticateKey]: %w", errors.New("test")),
+		},
+	}
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			mockReturn := tc.mockReturn
+			if mockReturn == nil {
+				mockReturn = &sqlmock.Rows{}
+			}
+			s.dbMock.
+				ExpectQuery(regexp.QuoteMeta(useKey)).
+				WithArgs(hashAPIKey(secret)).
+				WillReturnRows(mockReturn).
+				WillReturnError(tc.mockReturnErr)
+
+			actualReturn, err := s.apiKeys.AuthenticateKey(context.Background(), secret)
+
+			assert.Equal(t, tc.expectedError, err, "errors did not match")
+			assert.Equal(t, tc.expectedReturn, actualReturn, "returned data does not match")
+
+			err = s.dbMock.ExpectationsWereMet()
+			assert.NoError(t, err)
+		})
+	}
+}
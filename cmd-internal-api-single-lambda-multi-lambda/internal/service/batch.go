@@ -0,0 +1,429 @@
+package service
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/jha-captech/user-microservice/internal/audit"
+	"github.com/jha-captech/user-microservice/internal/models"
+	"github.com/lib/pq"
+)
+
+var (
+	// ErrBatchAborted is returned for an operation in an atomic batch that was not applied because
+	// another operation in the batch failed.
+	ErrBatchAborted = errors.New("batch aborted")
+
+	// ErrBatchDuplicateID is returned for an operation in a batch that updates or deletes a user that
+	// an earlier operation in the same batch already updates or deletes.
+	ErrBatchDuplicateID = errors.New("user changed more than once in batch")
+)
+
+// queryer is the part of *sql.DB and *sql.Tx used to run the statements of a batch, so that a
+// batch can run in a transaction or directly against the database.
+type queryer interface {
+	QueryContext(ctx context.Context, query string, args ...any) (*sql.Rows, error)
+}
+
+// BatchUsers applies operations and returns a result for each of them, in the same order.
+//
+// All deletes, all updates and all creates are each made with a single statement, in that order, so
+// a batch takes at most three round trips however many operations it has. Before anything is written,
+// operations that change the same user twice or that would reuse a user_id held by another user are
+// failed.
+//
+// When atomic is set, the batch runs in a transaction and is only committed if every operation
+// succeeds. Otherwise the failing operations are rolled back and all others fail with
+// ErrBatchAborted, and a statement that fails fails all of its operations. When atomic is not set,
+// the operations that pass the checks are applied on a best effort basis: if a statement fails, its
+// operations are made again one statement each, so only the operations that fail on their own fail.
+//
+// An operation that fails with a unique violation fails with ErrUserIDConflict. The returned error is only set when the batch could not be run at all, such as when a
+// transaction can not be started or committed, in which case nothing has been written.
+func (s User) BatchUsers(ctx context.Context, operations []models.BatchOperation, atomic bool) ([]models.BatchResult, error) {
+	results := make([]models.BatchResult, len(operations))
+
+	if err := s.checkBatch(ctx, operations, results); err != nil {
+		return nil, fmt.Errorf("[in BatchUsers]: %w", err)
+	}
+	if atomic && abortBatch(results) {
+		return results, nil
+	}
+
+	var db queryer = s.database
+	var tx *sql.Tx
+	if atomic {
+		var err error
+		if tx, err = s.database.BeginTx(ctx, nil); err != nil {
+			return nil, fmt.Errorf("[in BatchUsers] begin transaction: %w", err)
+		}
+		defer tx.Rollback()
+		db = tx
+	}
+
+	steps := []struct {
+		op  models.BatchOp
+		run batchStep
+	}{
+		{models.BatchDelete, batchDelete},
+		{models.BatchUpdate, batchUpdate},
+		{models.BatchCreate, batchCreate},
+	}
+	for _, step := range steps {
+		indexes := pendingOperations(operations, results, step.op)
+		if len(indexes) == 0 {
+			continue
+		}
+
+		if err := step.run(ctx, db, indexes, operations, results); err != nil {
+			if atomic || len(indexes) == 1 {
+				failBatchStatement(indexes, results, err)
+			} else {
+				// the failed statement wrote nothing, so its operations are made one at a time to
+				// only fail the ones that fail on their own
+				for _, index := range indexes {
+					if err = step.run(ctx, db, []int{index}, operations, results); err != nil {
+						failBatchStatement([]int{index}, results, err)
+					}
+				}
+			}
+		}
+		if atomic && abortBatch(results) {
+			return results, nil
+		}
+	}
+
+	if atomic {
+		if err := tx.Commit(); err != nil {
+			return nil, fmt.Errorf("[in BatchUsers] commit transaction: %w", err)
+		}
+	}
+
+	s.countBatch(operations, results)
+	return results, nil
+}
+
+// countBatch counts the users changed by the operations of a batch that succeeded.
+func (s User) countBatch(operations []models.BatchOperation, results []models.BatchResult) {
+	counts := make(map[models.BatchOp]int, len(models.BatchOps))
+	for i, operation := range operations {
+		if results[i].Err == nil {
+			counts[operation.Op]++
+		}
+	}
+
+	s.changes.UsersCreated(counts[models.BatchCreate])
+	s.changes.UsersUpdated(counts[models.BatchUpdate])
+	s.changes.UsersDeleted(counts[models.BatchDelete])
+}
+
+// checkBatch sets the result of each operation in operations that can not succeed to an error,
+// without changing anything. An operation fails with ErrBatchDuplicateID if an earlier operation
+// already changes the same user, and with ErrUserIDConflict if its user_id is used by an earlier
+// operation or by a user that is not changed by the batch.
+func (s User) checkBatch(ctx context.Context, operations []models.BatchOperation, results []models.BatchResult) error {
+	var (
+		changedIDs = make(map[int]bool)
+		batchUIDs  = make(map[uint]bool)
+		userIDs    []int64
+	)
+
+	for i, operation := range operations {
+		if operation.Op != models.BatchCreate {
+			if changedIDs[operation.ID] {
+				results[i].Err = ErrBatchDuplicateID
+				continue
+			}
+			changedIDs[operation.ID] = true
+		}
+		if operation.Op != models.BatchDelete {
+			if batchUIDs[operation.User.UserID] {
+				results[i].Err = ErrUserIDConflict
+				continue
+			}
+			batchUIDs[operation.User.UserID] = true
+			userIDs = append(userIDs, int64(operation.User.UserID))
+		}
+	}
+	if len(userIDs) == 0 {
+		return nil
+	}
+
+	// find the users already holding a user_id used in the batch
+	rows, err := s.database.QueryContext(
+		ctx,
+		`SELECT "id", "user_id" FROM "users" WHERE "user_id" = ANY($1) AND `+activeUsers,
+		pq.Array(userIDs),
+	)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	holders := make(map[uint]int)
+	for rows.Next() {
+		var (
+			ID     int
+			userID uint
+		)
+		if err = rows.Scan(&ID, &userID); err != nil {
+			return err
+		}
+		holders[userID] = ID
+	}
+	if err = rows.Err(); err != nil {
+		return err
+	}
+
+	for i, operation := range operations {
+		if results[i].Err != nil || operation.Op == models.BatchDelete {
+			continue
+		}
+		// a user_id is free if it is held by the user being updated, or by a user that is deleted
+		// or given another user_id by the batch
+		holder, held := holders[operation.User.UserID]
+		if held && holder != operation.ID && !changedIDs[holder] {
+			results[i].Err = ErrUserIDConflict
+		}
+	}
+
+	return nil
+}
+
+// batchStep writes the operations at indexes, which are all of the same kind, with a single
+// statement and sets their results. It returns an error if the statement failed, in which case
+// nothing was written and no results were set.
+type batchStep func(ctx context.Context, db queryer, indexes []int, operations []models.BatchOperation, results []models.BatchResult) error
+
+// batchDelete soft deletes the users of the delete operations at indexes, in the same way as
+// DeleteUser, recording the actor of ctx as the one who updated them. Operations whose user does not
+// exist fail with ErrUserNotFound.
+func batchDelete(ctx context.Context, db queryer, indexes []int, operations []models.BatchOperation, results []models.BatchResult) error {
+	IDs := make([]int64, len(indexes))
+	for i, index := range indexes {
+		IDs[i] = int64(operations[index].ID)
+	}
+
+	rows, err := db.QueryContext(
+		ctx,
+		changeUsers(
+			models.HistoryDelete,
+			`"deleted_at" = NOW(), "version" = "version" + 1, "updated_at" = NOW(), "updated_by" = $2`,
+			[]string{`"id" = ANY($1)`, activeUsers},
+			2,
+			3,
+			`"id", "user_id", "version"`,
+		),
+		pq.Array(IDs),
+		audit.Actor(ctx),
+		audit.RequestID(ctx),
+	)
+	if err != nil {
+		return err
+	}
+
+	changed, err := scanChangedUsers(rows)
+	if err != nil {
+		return err
+	}
+
+	byID := make(map[int]changedUser, len(changed))
+	for _, user := range changed {
+		byID[user.ID] = user
+	}
+	for _, index := range indexes {
+		user, ok := byID[operations[index].ID]
+		if !ok {
+			results[index].Err = ErrUserNotFound
+			continue
+		}
+		results[index].ID = user.ID
+		results[index].Version = user.version
+	}
+
+	return nil
+}
+
+// batchUpdate updates the users of the update operations at indexes, increments their versions and
+// records the actor of ctx as the one who updated them. Operations whose user does not exist fail
+// with ErrUserNotFound.
+func batchUpdate(ctx context.Context, db queryer, indexes []int, operations []models.BatchOperation, results []models.BatchResult) error {
+	values := make([]string, len(indexes))
+	args := make([]any, 0, len(indexes)*5+2)
+	args = append(args, audit.Actor(ctx), audit.RequestID(ctx))
+	for i, index := range indexes {
+		operation := operations[index]
+		args = append(
+			args,
+			operation.ID,
+			operation.User.FirstName,
+			operation.User.LastName,
+			operation.User.Role,
+			operation.User.UserID,
+		)
+		n := len(args)
+		values[i] = fmt.Sprintf(
+			"($%d::integer, $%d::text, $%d::text, $%d::text, $%d::integer)", n-4, n-3, n-2, n-1, n,
+		)
+	}
+
+	rows, err := db.QueryContext(
+		ctx,
+		`WITH "v" ("id", "first_name", "last_name", "role", "user_id") AS (VALUES `+strings.Join(values, ", ")+`), `+
+			`"before" AS (SELECT `+userColumns+` FROM "users" WHERE "id" IN (SELECT "id" FROM "v") AND `+activeUsers+` FOR UPDATE), `+
+			`"after" AS (UPDATE "users" SET `+
+			`"first_name" = "v"."first_name", "last_name" = "v"."last_name", "role" = "v"."role", `+
+			`"user_id" = "v"."user_id", "version" = "users"."version" + 1, "updated_at" = NOW(), "updated_by" = $1 `+
+			`FROM "v" WHERE "users"."id" = "v"."id" AND "users"."id" IN (SELECT "id" FROM "before") `+
+			`RETURNING `+qualifiedUserColumns+`), `+
+			recordChange(models.HistoryUpdate, "before", "after", 1, 2)+` `+
+			`SELECT "id", "user_id", "version" FROM "after"`,
+		args...,
+	)
+	if err != nil {
+		return err
+	}
+
+	changed, err := scanChangedUsers(rows)
+	if err != nil {
+		return err
+	}
+
+	// the user_ids of a batch are unique, so they identify the operation each user was updated by
+	byUserID := make(map[uint]changedUser, len(changed))
+	for _, user := range changed {
+		byUserID[user.userID] = user
+	}
+	for _, index := range indexes {
+		operation := operations[index]
+		user, ok := byUserID[operation.User.UserID]
+		if !ok || user.ID != operation.ID {
+			results[index].Err = ErrUserNotFound
+			continue
+		}
+		results[index].ID = user.ID
+		results[index].Version = user.version
+	}
+
+	return nil
+}
+
+// batchCreate creates the users of the create operations at indexes with a single multi-row insert,
+// recording the actor of ctx as the one who created them.
+func batchCreate(ctx context.Context, db queryer, indexes []int, operations []models.BatchOperation, results []models.BatchResult) error {
+	values := make([]string, len(indexes))
+	args := make([]any, 0, len(indexes)*4+2)
+	args = append(args, audit.Actor(ctx), audit.RequestID(ctx))
+	for i, index := range indexes {
+		user := operations[index].User
+		args = append(args, user.FirstName, user.LastName, user.Role, user.UserID)
+		n := len(args)
+		values[i] = fmt.Sprintf("($%d, $%d, $%d, $%d, $1, $1)", n-3, n-2, n-1, n)
+	}
+
+	rows, err := db.QueryContext(
+		ctx,
+		`WITH "after" AS (`+
+			`INSERT INTO "users" ("first_name", "last_name", "role", "user_id", "created_by", "updated_by") VALUES `+
+			strings.Join(values, ", ")+` RETURNING `+userColumns+`), `+
+			recordChange(models.HistoryCreate, "", "after", 1, 2)+` `+
+			`SELECT "id", "user_id", "version" FROM "after"`,
+		args...,
+	)
+	if err != nil {
+		return err
+	}
+
+	changed, err := scanChangedUsers(rows)
+	if err != nil {
+		return err
+	}
+
+	// the user_ids of a batch are unique, so they identify the operation each user was created by
+	byUserID := make(map[uint]changedUser, len(changed))
+	for _, user := range changed {
+		byUserID[user.userID] = user
+	}
+	for _, index := range indexes {
+		user, ok := byUserID[operations[index].User.UserID]
+		if !ok {
+			results[index].Err = errors.New("no row returned for created user")
+			continue
+		}
+		results[index].ID = user.ID
+		results[index].Version = user.version
+	}
+
+	return nil
+}
+
+// changedUser is a user written by a batch statement.
+type changedUser struct {
+	ID      int
+	userID  uint
+	version uint
+}
+
+// scanChangedUsers reads the "id", "user_id" and "version" of the users written by a statement.
+func scanChangedUsers(rows *sql.Rows) ([]changedUser, error) {
+	defer rows.Close()
+
+	var changed []changedUser
+	for rows.Next() {
+		var user changedUser
+		if err := rows.Scan(&user.ID, &user.userID, &user.version); err != nil {
+			return nil, err
+		}
+		changed = append(changed, user)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return changed, nil
+}
+
+// failBatchStatement fails the operations at indexes, which were all part of one statement, with
+// err. A unique violation is converted into ErrUserIDConflict.
+func failBatchStatement(indexes []int, results []models.BatchResult, err error) {
+	err = mapUniqueViolation(err)
+	for _, index := range indexes {
+		results[index] = models.BatchResult{Err: err}
+	}
+}
+
+// pendingOperations returns the indexes of the operations of kind op that have not failed.
+func pendingOperations(operations []models.BatchOperation, results []models.BatchResult, op models.BatchOp) []int {
+	var indexes []int
+	for i, operation := range operations {
+		if operation.Op == op && results[i].Err == nil {
+			indexes = append(indexes, i)
+		}
+	}
+	return indexes
+}
+
+// abortBatch reports whether any operation in results failed and, if so, fails every other
+// operation with ErrBatchAborted and clears their IDs and versions.
+func abortBatch(results []models.BatchResult) bool {
+	failed := false
+	for _, result := range results {
+		if result.Err != nil {
+			failed = true
+			break
+		}
+	}
+	if !failed {
+		return false
+	}
+
+	for i := range results {
+		if results[i].Err == nil {
+			results[i] = models.BatchResult{Err: ErrBatchAborted}
+		}
+	}
+	return true
+}
@@ -0,0 +1,257 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"regexp"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/jha-captech/user-microservice/internal/audit"
+	"github.com/jha-captech/user-microservice/internal/models"
+	"github.com/lib/pq"
+	"github.com/stretchr/testify/assert"
+)
+
+func (s *testSuit) TestBatchUsers() {
+	t := s.T()
+
+	const (
+		checkUserIDs = `SELECT "id", "user_id" FROM "users" WHERE "user_id" = ANY($1) AND "deleted_at" IS NULL`
+		updateUsers  = `WITH "v" ("id", "first_name", "last_name", "role", "user_id") AS ` +
+			`(VALUES ($3::integer, $4::text, $5::text, $6::text, $7::integer)), ` +
+			`"before" AS (SELECT ` + userColumnsSQL + ` FROM "users" WHERE "id" IN (SELECT "id" FROM "v") AND "deleted_at" IS NULL FOR UPDATE), ` +
+			`"after" AS (UPDATE "users" SET "first_name" = "v"."first_name", "last_name" = "v"."last_name", ` +
+			`"role" = "v"."role", "user_id" = "v"."user_id", "version" = "users"."version" + 1, "updated_at" = NOW(), "updated_by" = $1 ` +
+			`FROM "v" WHERE "users"."id" = "v"."id" AND "users"."id" IN (SELECT "id" FROM "before") RETURNING "users"."id", ` +
+			`"users"."first_name", "users"."last_name", "users"."role", "users"."user_id", "users"."version", "users"."deleted_at", ` +
+			`"users"."created_at", "users"."updated_at", "users"."created_by", "users"."updated_by"), `
+		insertUser = `WITH "after" AS (INSERT INTO "users" ("first_name", "last_name", "role", "user_id", "created_by", "updated_by") ` +
+			`VALUES ($3, $4, $5, $6, $1, $1) RETURNING ` + userColumnsSQL + `), `
+		insertUsers = `WITH "after" AS (INSERT INTO "users" ("first_name", "last_name", "role", "user_id", "created_by", "updated_by") ` +
+			`VALUES ($3, $4, $5, $6, $1, $1), ($7, $8, $9, $10, $1, $1) RETURNING ` + userColumnsSQL + `), `
+	)
+
+	john := models.User{FirstName: "John", LastName: "Doe", Role: "Customer", UserID: 1001}
+	jane := models.User{FirstName: "Jane", LastName: "Smith", Role: "Employee", UserID: 1002}
+	jim := models.User{FirstName: "Jim", LastName: "Brown", Role: "Customer", UserID: 1003}
+	uniqueViolation := &pq.Error{Code: pgUniqueViolation, Message: "duplicate key value"}
+	ctx := audit.WithActor(context.Background(), "jdoe")
+	deleteUsers := changeUserSQL(
+		`"id" = ANY($1) AND "deleted_at" IS NULL`,
+		`"deleted_at" = NOW(), "version" = "version" + 1, "updated_at" = NOW(), "updated_by" = $2`,
+		recordSQL(historySQL("delete", 2, 3, changedEntriesSQL), eventsSQL("user.deleted", "after")),
+		`"id", "user_id", "version"`,
+	)
+	updateHistory := recordSQL(historySQL("update", 1, 2, changedEntriesSQL), eventsSQL("user.updated", "after")) +
+		` SELECT "id", "user_id", "version" FROM "after"`
+	createHistory := recordSQL(historySQL("create", 1, 2, createdEntriesSQL), eventsSQL("user.created", "after")) +
+		` SELECT "id", "user_id", "version" FROM "after"`
+
+	changedColumns := []string{"id", "user_id", "version"}
+
+	operations := []models.BatchOperation{
+		{Op: models.BatchCreate, User: john},
+		{Op: models.BatchUpdate, ID: 5, User: jim},
+		{Op: models.BatchDelete, ID: 7},
+		{Op: models.BatchCreate, User: jane},
+	}
+	expectWrites := func(mock sqlmock.Sqlmock) {
+		mock.ExpectQuery(regexp.QuoteMeta(deleteUsers)).
+			WithArgs("{7}", "jdoe", "").
+			WillReturnRows(sqlmock.NewRows(changedColumns).AddRow(7, 1004, 2))
+		mock.ExpectQuery(regexp.QuoteMeta(updateUsers+updateHistory)).
+			WithArgs("jdoe", "", 5, jim.FirstName, jim.LastName, jim.Role, jim.UserID).
+			WillReturnRows(sqlmock.NewRows(changedColumns).AddRow(5, 1003, 4))
+		mock.ExpectQuery(regexp.QuoteMeta(insertUsers+createHistory)).
+			WithArgs("jdoe", "", john.FirstName, john.LastName, john.Role, john.UserID, jane.FirstName, jane.LastName, jane.Role, jane.UserID).
+			WillReturnRows(sqlmock.NewRows(changedColumns).AddRow(11, 1002, 1).AddRow(10, 1001, 1))
+	}
+
+	testCases := map[string]struct {
+		inputOperations []models.BatchOperation
+		inputAtomic     bool
+		setup           func(mock sqlmock.Sqlmock)
+		expectedReturn  []models.BatchResult
+		expectedChanges changeCounts
+		expectedError   error
+	}{
+		"atomic batch committed": {
+			inputOperations: operations,
+			inputAtomic:     true,
+			setup: func(mock sqlmock.Sqlmock) {
+				mock.ExpectQuery(regexp.QuoteMeta(checkUserIDs)).
+					WithArgs("{1001,1003,1002}").
+					WillReturnRows(sqlmock.NewRows([]string{"id", "user_id"}).AddRow(5, 1003))
+				mock.ExpectBegin()
+				expectWrites(mock)
+				mock.ExpectCommit()
+			},
+			expectedReturn: []models.BatchResult{
+				{ID: 10, Version: 1},
+				{ID: 5, Version: 4},
+				{ID: 7, Version: 2},
+				{ID: 11, Version: 1},
+			},
+			expectedChanges: changeCounts{created: 2, updated: 1, deleted: 1},
+		},
+		"best effort batch applied": {
+			inputOperations: operations,
+			inputAtomic:     false,
+			setup: func(mock sqlmock.Sqlmock) {
+				mock.ExpectQuery(regexp.QuoteMeta(checkUserIDs)).
+					WithArgs("{1001,1003,1002}").
+					WillReturnRows(sqlmock.NewRows([]string{"id", "user_id"}))
+				expectWrites(mock)
+			},
+			expectedReturn: []models.BatchResult{
+				{ID: 10, Version: 1},
+				{ID: 5, Version: 4},
+				{ID: 7, Version: 2},
+				{ID: 11, Version: 1},
+			},
+			expectedChanges: changeCounts{created: 2, updated: 1, deleted: 1},
+		},
+		"atomic batch with user_id held by another user is not started": {
+			inputOperations: []models.BatchOperation{
+				{Op: models.BatchCreate, User: john},
+				{Op: models.BatchDelete, ID: 7},
+			},
+			inputAtomic: true,
+			setup: func(mock sqlmock.Sqlmock) {
+				mock.ExpectQuery(regexp.QuoteMeta(checkUserIDs)).
+					WithArgs("{1001}").
+					WillReturnRows(sqlmock.NewRows([]string{"id", "user_id"}).AddRow(3, 1001))
+			},
+			expectedReturn: []models.BatchResult{
+				{Err: ErrUserIDConflict},
+				{Err: ErrBatchAborted},
+			},
+		},
+		"user_id freed by a delete in the batch": {
+			inputOperations: []models.BatchOperation{
+				{Op: models.BatchDelete, ID: 3},
+				{Op: models.BatchCreate, User: john},
+			},
+			inputAtomic: false,
+			setup: func(mock sqlmock.Sqlmock) {
+				mock.ExpectQuery(regexp.QuoteMeta(checkUserIDs)).
+					WithArgs("{1001}").
+					WillReturnRows(sqlmock.NewRows([]string{"id", "user_id"}).AddRow(3, 1001))
+				mock.ExpectQuery(regexp.QuoteMeta(deleteUsers)).
+					WithArgs("{3}", "jdoe", "").
+					WillReturnRows(sqlmock.NewRows(changedColumns).AddRow(3, 1001, 1))
+				mock.ExpectQuery(regexp.QuoteMeta(insertUser+createHistory)).
+					WithArgs("jdoe", "", john.FirstName, john.LastName, john.Role, john.UserID).
+					WillReturnRows(sqlmock.NewRows(changedColumns).AddRow(12, 1001, 1))
+			},
+			expectedReturn: []models.BatchResult{
+				{ID: 3, Version: 1},
+				{ID: 12, Version: 1},
+			},
+			expectedChanges: changeCounts{created: 1, deleted: 1},
+		},
+		"atomic batch rolled back when a user does not exist": {
+			inputOperations: []models.BatchOperation{
+				{Op: models.BatchDelete, ID: 7},
+				{Op: models.BatchDelete, ID: 8},
+				{Op: models.BatchCreate, User: john},
+			},
+			inputAtomic: true,
+			setup: func(mock sqlmock.Sqlmock) {
+				mock.ExpectQuery(regexp.QuoteMeta(checkUserIDs)).
+					WithArgs("{1001}").
+					WillReturnRows(sqlmock.NewRows([]string{"id", "user_id"}))
+				mock.ExpectBegin()
+				mock.ExpectQuery(regexp.QuoteMeta(deleteUsers)).
+					WithArgs("{7,8}", "jdoe", "").
+					WillReturnRows(sqlmock.NewRows(changedColumns).AddRow(7, 1004, 2))
+				mock.ExpectRollback()
+			},
+			expectedReturn: []models.BatchResult{
+				{Err: ErrBatchAborted},
+				{Err: ErrUserNotFound},
+				{Err: ErrBatchAborted},
+			},
+		},
+		"best effort batch with duplicate ID and failed statement": {
+			inputOperations: []models.BatchOperation{
+				{Op: models.BatchUpdate, ID: 5, User: jim},
+				{Op: models.BatchDelete, ID: 5},
+				{Op: models.BatchCreate, User: john},
+				{Op: models.BatchCreate, User: john},
+			},
+			inputAtomic: false,
+			setup: func(mock sqlmock.Sqlmock) {
+				mock.ExpectQuery(regexp.QuoteMeta(checkUserIDs)).
+					WithArgs("{1003,1001}").
+					WillReturnRows(sqlmock.NewRows([]string{"id", "user_id"}))
+				mock.ExpectQuery(regexp.QuoteMeta(updateUsers+updateHistory)).
+					WithArgs("jdoe", "", 5, jim.FirstName, jim.LastName, jim.Role, jim.UserID).
+					WillReturnError(uniqueViolation)
+				mock.ExpectQuery(regexp.QuoteMeta(insertUser+createHistory)).
+					WithArgs("jdoe", "", john.FirstName, john.LastName, john.Role, john.UserID).
+					WillReturnRows(sqlmock.NewRows(changedColumns).AddRow(12, 1001, 1))
+			},
+			expectedReturn: []models.BatchResult{
+				{Err: fmt.Errorf("%w: %w", ErrUserIDConflict, uniqueViolation)},
+				{Err: ErrBatchDuplicateID},
+				{ID: 12, Version: 1},
+				{Err: ErrUserIDConflict},
+			},
+			expectedChanges: changeCounts{created: 1},
+		},
+		"best effort batch retries a failed statement one operation at a time": {
+			inputOperations: []models.BatchOperation{
+				{Op: models.BatchCreate, User: john},
+				{Op: models.BatchCreate, User: jane},
+			},
+			inputAtomic: false,
+			setup: func(mock sqlmock.Sqlmock) {
+				mock.ExpectQuery(regexp.QuoteMeta(checkUserIDs)).
+					WithArgs("{1001,1002}").
+					WillReturnRows(sqlmock.NewRows([]string{"id", "user_id"}))
+				mock.ExpectQuery(regexp.QuoteMeta(insertUsers+createHistory)).
+					WithArgs("jdoe", "", john.FirstName, john.LastName, john.Role, john.UserID, jane.FirstName, jane.LastName, jane.Role, jane.UserID).
+					WillReturnError(uniqueViolation)
+				mock.ExpectQuery(regexp.QuoteMeta(insertUser+createHistory)).
+					WithArgs("jdoe", "", john.FirstName, john.LastName, john.Role, john.UserID).
+					WillReturnRows(sqlmock.NewRows(changedColumns).AddRow(12, 1001, 1))
+				mock.ExpectQuery(regexp.QuoteMeta(insertUser+createHistory)).
+					WithArgs("jdoe", "", jane.FirstName, jane.LastName, jane.Role, jane.UserID).
+					WillReturnError(uniqueViolation)
+			},
+			expectedReturn: []models.BatchResult{
+				{ID: 12, Version: 1},
+				{Err: fmt.Errorf("%w: %w", ErrUserIDConflict, uniqueViolation)},
+			},
+			expectedChanges: changeCounts{created: 1},
+		},
+		"error checking user_ids": {
+			inputOperations: []models.BatchOperation{{Op: models.BatchCreate, User: john}},
+			inputAtomic:     true,
+			setup: func(mock sqlmock.Sqlmock) {
+				mock.ExpectQuery(regexp.QuoteMeta(checkUserIDs)).
+					WithArgs("{1001}").
+					WillReturnError(errors.New("test"))
+			},
+			expectedError: fmt.Errorf("[in BatchUsers]: %w", errors.New("test")),
+		},
+	}
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			tc.setup(s.dbMock)
+			*s.changes = changeCounts{}
+
+			actualReturn, err := s.service.BatchUsers(ctx, tc.inputOperations, tc.inputAtomic)
+
+			assert.Equal(t, tc.expectedError, err, "errors did not match")
+			assert.Equal(t, tc.expectedReturn, actualReturn, "returned data does not match")
+			assert.Equal(t, tc.expectedChanges, *s.changes, "changes were not counted")
+
+			err = s.dbMock.ExpectationsWereMet()
+			assert.NoError(t, err)
+		})
+	}
+}
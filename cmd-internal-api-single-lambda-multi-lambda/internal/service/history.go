@@ -0,0 +1,232 @@
+package service
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/jha-captech/user-microservice/internal/models"
+)
+
+// ErrHistoryNotFound is returned when the history of a user has no entry for the requested version.
+var ErrHistoryNotFound = errors.New("history entry not found")
+
+// historyColumns are the columns of the user_history table read into a models.UserHistoryEntry, in
+// the order scanned by scanHistoryEntry.
+const historyColumns = `"id", "version", "operation", "actor", "request_id", "changed_at", "before", "after"`
+
+// qualifiedUserColumns are the userColumns qualified with the users table, for statements joining
+// other tables with columns of the same names.
+var qualifiedUserColumns = `"users".` + strings.ReplaceAll(userColumns, `, "`, `, "users"."`)
+
+// userSnapshot is a user as recorded in the before and after columns of user_history, which hold the
+// userColumns of the user as a JSON object. It has the same fields as models.User.
+type userSnapshot struct {
+	ID        uint       `json:"id"`
+	FirstName string     `json:"first_name" pii:"true"`
+	LastName  string     `json:"last_name" pii:"true"`
+	Role      string     `json:"role"`
+	UserID    uint       `json:"user_id"`
+	Version   uint       `json:"version"`
+	DeletedAt *time.Time `json:"deleted_at"`
+	CreatedAt time.Time  `json:"created_at"`
+	UpdatedAt time.Time  `json:"updated_at"`
+	CreatedBy string     `json:"created_by"`
+	UpdatedBy string     `json:"updated_by"`
+}
+
+// ListUserHistory returns a page of the history of the user with ID, newest change first. The
+// history of a purged user is kept, so ErrUserNotFound is only returned if the user has neither
+// history nor a row in the users table.
+func (s User) ListUserHistory(ctx context.Context, ID int, params models.ListHistoryParams) (models.UserHistoryPage, error) {
+	var total int
+	err := s.database.
+		QueryRowContext(ctx, `SELECT COUNT(*) FROM "user_history" WHERE "id" = $1`, ID).
+		Scan(&total)
+	if err != nil {
+		return models.UserHistoryPage{}, fmt.Errorf("[in ListUserHistory]: %w", err)
+	}
+	if total == 0 {
+		var exists bool
+		err = s.database.
+			QueryRowContext(ctx, `SELECT EXISTS (SELECT 1 FROM "users" WHERE "id" = $1)`, ID).
+			Scan(&exists)
+		if err != nil {
+			return models.UserHistoryPage{}, fmt.Errorf("[in ListUserHistory]: %w", err)
+		}
+		if !exists {
+			return models.UserHistoryPage{}, fmt.Errorf("[in ListUserHistory]: %w", ErrUserNotFound)
+		}
+		return models.UserHistoryPage{Entries: []models.UserHistoryEntry{}}, nil
+	}
+
+	rows, err := s.database.QueryContext(
+		ctx,
+		`
+		SELECT
+			`+historyColumns+`
+		FROM
+			"user_history"
+		WHERE
+			"id" = $1
+		ORDER BY
+			"version" DESC
+		LIMIT $2 OFFSET $3
+		`,
+		ID,
+		params.Limit,
+		params.Offset,
+	)
+	if err != nil {
+		return models.UserHistoryPage{}, fmt.Errorf("[in ListUserHistory]: %w", err)
+	}
+	defer rows.Close()
+
+	entries := []models.UserHistoryEntry{}
+	for rows.Next() {
+		entry, err := scanHistoryEntry(rows)
+		if err != nil {
+			return models.UserHistoryPage{}, fmt.Errorf("[in ListUserHistory]: %w", err)
+		}
+		entries = append(entries, entry)
+	}
+	if err = rows.Err(); err != nil {
+		return models.UserHistoryPage{}, fmt.Errorf("[in ListUserHistory]: %w", err)
+	}
+
+	return models.UserHistoryPage{Entries: entries, Total: total}, nil
+}
+
+// FetchUserHistory returns the entry of the history of the user with ID that brought it to version.
+func (s User) FetchUserHistory(ctx context.Context, ID int, version uint) (models.UserHistoryEntry, error) {
+	entry, err := scanHistoryEntry(s.database.QueryRowContext(
+		ctx,
+		`SELECT `+historyColumns+` FROM "user_history" WHERE "id" = $1 AND "version" = $2`,
+		ID,
+		version,
+	))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			err = ErrHistoryNotFound
+		}
+		return models.UserHistoryEntry{}, fmt.Errorf("[in FetchUserHistory]: %w", err)
+	}
+
+	return entry, nil
+}
+
+// scanHistoryEntry reads the historyColumns of row into a models.UserHistoryEntry.
+func scanHistoryEntry(row rowScanner) (models.UserHistoryEntry, error) {
+	var (
+		entry         models.UserHistoryEntry
+		before, after []byte
+	)
+	err := row.Scan(
+		&entry.ID,
+		&entry.Version,
+		&entry.Operation,
+		&entry.Actor,
+		&entry.RequestID,
+		&entry.ChangedAt,
+		&before,
+		&after,
+	)
+	if err != nil {
+		return models.UserHistoryEntry{}, err
+	}
+
+	if entry.Before, err = decodeSnapshot(before); err != nil {
+		return models.UserHistoryEntry{}, fmt.Errorf("decode before: %w", err)
+	}
+	if entry.After, err = decodeSnapshot(after); err != nil {
+		return models.UserHistoryEntry{}, fmt.Errorf("decode after: %w", err)
+	}
+
+	return entry, nil
+}
+
+// decodeSnapshot decodes a user snapshot of user_history, which is nil when data is NULL.
+func decodeSnapshot(data []byte) (*models.User, error) {
+	if data == nil {
+		return nil, nil
+	}
+
+	var snapshot userSnapshot
+	if err := json.Unmarshal(data, &snapshot); err != nil {
+		return nil, err
+	}
+	// to_jsonb renders timestamps in the time zone of the session
+	snapshot.CreatedAt = snapshot.CreatedAt.UTC()
+	snapshot.UpdatedAt = snapshot.UpdatedAt.UTC()
+	if snapshot.DeletedAt != nil {
+		deletedAt := snapshot.DeletedAt.UTC()
+		snapshot.DeletedAt = &deletedAt
+	}
+
+	user := models.User(snapshot)
+	return &user, nil
+}
+
+// changeUsers returns a statement that locks the users matching conditions, changes them with the
+// assignments in set and records the change with recordChange as operation, with the actor and
+// request ID in the placeholders actor and requestID. The statement returns the columns in returning
+// of the changed users, so it returns no rows if no user matches conditions.
+func changeUsers(
+	operation models.HistoryOperation,
+	set string,
+	conditions []string,
+	actor, requestID int,
+	returning string,
+) string {
+	return `
+		WITH "before" AS (
+			SELECT ` + userColumns + ` FROM "users"` + whereClause(conditions) + ` FOR UPDATE
+		), "after" AS (
+			UPDATE "users" SET ` + set + ` WHERE "id" IN (SELECT "id" FROM "before")
+			RETURNING ` + userColumns + `
+		), ` + recordChange(operation, "before", "after", actor, requestID) + `
+		SELECT ` + returning + ` FROM "after"`
+}
+
+// recordChange returns the common table expressions that record a change to users in their history
+// with insertHistory and queue an event for it in the outbox with insertEvents, to be used in the
+// statement that changes the users so that both are written in the same transaction as the change.
+func recordChange(operation models.HistoryOperation, before, after string, actor, requestID int) string {
+	return `"history" AS (` + insertHistory(operation, before, after, actor, requestID) + `), ` +
+		`"outbox" AS (` + insertEvents(operation, before, after) + `)`
+}
+
+// insertHistory returns an insert into user_history, to be used as a common table expression of a
+// statement that changes users, which records operation for every user changed by the statement.
+// before and after name the common table expressions that hold the userColumns of the users before
+// and after the change. before is empty for statements that create users and after for statements
+// that purge them, which are recorded at the version after the last version of the user. actor and
+// requestID are the placeholders of the actor and request ID.
+func insertHistory(operation models.HistoryOperation, before, after string, actor, requestID int) string {
+	var entries string
+	switch {
+	case before == "":
+		entries = fmt.Sprintf(`"a"."id", "a"."version", NULL::jsonb, to_jsonb("a") FROM "%s" AS "a"`, after)
+	case after == "":
+		entries = fmt.Sprintf(`"b"."id", "b"."version" + 1, to_jsonb("b"), NULL::jsonb FROM "%s" AS "b"`, before)
+	default:
+		entries = fmt.Sprintf(
+			`"a"."id", "a"."version", to_jsonb("b"), to_jsonb("a") FROM "%s" AS "a" JOIN "%s" AS "b" ON "b"."id" = "a"."id"`,
+			after,
+			before,
+		)
+	}
+
+	return fmt.Sprintf(
+		`INSERT INTO "user_history" ("operation", "actor", "request_id", "id", "version", "before", "after") `+
+			`SELECT '%s', $%d::text, $%d::text, %s`,
+		operation,
+		actor,
+		requestID,
+		entries,
+	)
+}
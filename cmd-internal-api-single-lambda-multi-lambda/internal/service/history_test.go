@@ -0,0 +1,211 @@
+package service
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/jha-captech/user-microservice/internal/models"
+	"github.com/stretchr/testify/assert"
+)
+
+var historyColumnNames = []string{"id", "version", "operation", "actor", "request_id", "changed_at", "before", "after"}
+
+func (s *testSuit) TestListUserHistory() {
+	t := s.T()
+
+	const (
+		countHistory  = `SELECT COUNT(*) FROM "user_history" WHERE "id" = $1`
+		userExists    = `SELECT EXISTS (SELECT 1 FROM "users" WHERE "id" = $1)`
+		selectHistory = `SELECT "id", "version", "operation", "actor", "request_id", "changed_at", "before", "after" ` +
+			`FROM "user_history" WHERE "id" = $1 ORDER BY "version" DESC LIMIT $2 OFFSET $3`
+		createdSnapshot = `{"id": 1, "first_name": "John", "last_name": "Doe", "role": "Admin", "user_id": 1001, "version": 1, ` +
+			`"deleted_at": null, "created_at": "2024-05-01T12:00:00+00:00", "updated_at": "2024-05-01T12:00:00+00:00", ` +
+			`"created_by": "system", "updated_by": "system"}`
+		updatedSnapshot = `{"id": 1, "first_name": "John", "last_name": "Smith", "role": "Admin", "user_id": 1001, "version": 2, ` +
+			`"deleted_at": null, "created_at": "2024-05-01T12:00:00+00:00", "updated_at": "2024-05-02T12:00:00+00:00", ` +
+			`"created_by": "system", "updated_by": "jdoe"}`
+	)
+
+	createdAt := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	updatedAt := time.Date(2024, 5, 2, 12, 0, 0, 0, time.UTC)
+	created := models.User{
+		ID: 1, FirstName: "John", LastName: "Doe", Role: "Admin", UserID: 1001, Version: 1,
+		CreatedAt: createdAt, UpdatedAt: createdAt, CreatedBy: "system", UpdatedBy: "system",
+	}
+	updated := models.User{
+		ID: 1, FirstName: "John", LastName: "Smith", Role: "Admin", UserID: 1001, Version: 2,
+		CreatedAt: createdAt, UpdatedAt: updatedAt, CreatedBy: "system", UpdatedBy: "jdoe",
+	}
+
+	testCases := map[string]struct {
+		setup          func(mock sqlmock.Sqlmock)
+		inputID        int
+		inputParams    models.ListHistoryParams
+		expectedReturn models.UserHistoryPage
+		expectedError  error
+	}{
+		"Return page of history": {
+			setup: func(mock sqlmock.Sqlmock) {
+				mock.ExpectQuery(regexp.QuoteMeta(countHistory)).
+					WithArgs(1).
+					WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(2))
+				mock.ExpectQuery(regexp.QuoteMeta(selectHistory)).
+					WithArgs(1, 10, 0).
+					WillReturnRows(sqlmock.NewRows(historyColumnNames).
+						AddRow(1, 2, "update", "jdoe", "req-1", updatedAt, []byte(createdSnapshot), []byte(updatedSnapshot)).
+						AddRow(1, 1, "create", "system", "", createdAt, nil, []byte(createdSnapshot)))
+			},
+			inputID:     1,
+			inputParams: models.ListHistoryParams{Limit: 10},
+			expectedReturn: models.UserHistoryPage{
+				Entries: []models.UserHistoryEntry{
+					{
+						ID: 1, Version: 2, Operation: models.HistoryUpdate, Actor: "jdoe", RequestID: "req-1",
+						ChangedAt: updatedAt, Before: &created, After: &updated,
+					},
+					{
+						ID: 1, Version: 1, Operation: models.HistoryCreate, Actor: "system",
+						ChangedAt: createdAt, After: &created,
+					},
+				},
+				Total: 2,
+			},
+		},
+		"User without history": {
+			setup: func(mock sqlmock.Sqlmock) {
+				mock.ExpectQuery(regexp.QuoteMeta(countHistory)).
+					WithArgs(1).
+					WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(0))
+				mock.ExpectQuery(regexp.QuoteMeta(userExists)).
+					WithArgs(1).
+					WillReturnRows(sqlmock.NewRows([]string{"exists"}).AddRow(true))
+			},
+			inputID:        1,
+			inputParams:    models.ListHistoryParams{Limit: 10},
+			expectedReturn: models.UserHistoryPage{Entries: []models.UserHistoryEntry{}},
+		},
+		"User with given ID does not exist": {
+			setup: func(mock sqlmock.Sqlmock) {
+				mock.ExpectQuery(regexp.QuoteMeta(countHistory)).
+					WithArgs(2).
+					WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(0))
+				mock.ExpectQuery(regexp.QuoteMeta(userExists)).
+					WithArgs(2).
+					WillReturnRows(sqlmock.NewRows([]string{"exists"}).AddRow(false))
+			},
+			inputID:       2,
+			inputParams:   models.ListHistoryParams{Limit: 10},
+			expectedError: fmt.Errorf("[in ListUserHistory]: %w", ErrUserNotFound),
+		},
+		"Error getting history": {
+			setup: func(mock sqlmock.Sqlmock) {
+				mock.ExpectQuery(regexp.QuoteMeta(countHistory)).
+					WithArgs(1).
+					WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(2))
+				mock.ExpectQuery(regexp.QuoteMeta(selectHistory)).
+					WithArgs(1, 10, 20).
+					WillReturnError(errors.New("test"))
+			},
+			inputID:       1,
+			inputParams:   models.ListHistoryParams{Limit: 10, Offset: 20},
+			expectedError: fmt.Errorf("[in ListUserHistory]: %w", errors.New("test")),
+		},
+	}
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			tc.setup(s.dbMock)
+
+			actualReturn, err := s.service.ListUserHistory(context.Background(), tc.inputID, tc.inputParams)
+
+			assert.Equal(t, tc.expectedError, err, "errors did not match")
+			assert.Equal(t, tc.expectedReturn, actualReturn, "returned data does not match")
+
+			err = s.dbMock.ExpectationsWereMet()
+			assert.NoError(t, err)
+		})
+	}
+}
+
+func (s *testSuit) TestFetchUserHistory() {
+	t := s.T()
+
+	const (
+		selectEntry = `SELECT "id", "version", "operation", "actor", "request_id", "changed_at", "before", "after" ` +
+			`FROM "user_history" WHERE "id" = $1 AND "version" = $2`
+		purgedSnapshot = `{"id": 1, "first_name": "John", "last_name": "Doe", "role": "Admin", "user_id": 1001, "version": 3, ` +
+			`"deleted_at": "2024-05-02T12:00:00+00:00", "created_at": "2024-05-01T12:00:00+00:00", ` +
+			`"updated_at": "2024-05-02T12:00:00+00:00", "created_by": "system", "updated_by": "jdoe"}`
+	)
+
+	createdAt := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	deletedAt := time.Date(2024, 5, 2, 12, 0, 0, 0, time.UTC)
+	purgedAt := time.Date(2024, 5, 3, 12, 0, 0, 0, time.UTC)
+	purged := models.User{
+		ID: 1, FirstName: "John", LastName: "Doe", Role: "Admin", UserID: 1001, Version: 3, DeletedAt: &deletedAt,
+		CreatedAt: createdAt, UpdatedAt: deletedAt, CreatedBy: "system", UpdatedBy: "jdoe",
+	}
+
+	testCases := map[string]struct {
+		mockReturn     *sqlmock.Rows
+		mockReturnErr  error
+		inputID        int
+		inputVersion   uint
+		expectedReturn models.UserHistoryEntry
+		expectedError  error
+	}{
+		"Return purge of user": {
+			mockReturn: sqlmock.NewRows(historyColumnNames).
+				AddRow(1, 4, "purge", "admin", "req-2", purgedAt, []byte(purgedSnapshot), nil),
+			inputID:      1,
+			inputVersion: 4,
+			expectedReturn: models.UserHistoryEntry{
+				ID: 1, Version: 4, Operation: models.HistoryPurge, Actor: "admin", RequestID: "req-2",
+				ChangedAt: purgedAt, Before: &purged,
+			},
+		},
+		"Version does not exist": {
+			mockReturnErr: sql.ErrNoRows,
+			inputID:       1,
+			inputVersion:  9,
+			expectedError: fmt.Errorf("[in FetchUserHistory]: %w", ErrHistoryNotFound),
+		},
+		"Invalid snapshot": {
+			mockReturn: sqlmock.NewRows(historyColumnNames).
+				AddRow(1, 4, "purge", "admin", "", purgedAt, []byte(`{`), nil),
+			inputID:       1,
+			inputVersion:  4,
+			expectedError: errors.New("[in FetchUserHistory]: decode before: unexpected end of JSON input"),
+		},
+	}
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			mockReturn := tc.mockReturn
+			if mockReturn == nil {
+				mockReturn = &sqlmock.Rows{}
+			}
+			s.dbMock.
+				ExpectQuery(regexp.QuoteMeta(selectEntry)).
+				WithArgs(tc.inputID, tc.inputVersion).
+				WillReturnRows(mockReturn).
+				WillReturnError(tc.mockReturnErr)
+
+			actualReturn, err := s.service.FetchUserHistory(context.Background(), tc.inputID, tc.inputVersion)
+
+			if tc.expectedError != nil {
+				assert.EqualError(t, err, tc.expectedError.Error(), "errors did not match")
+			} else {
+				assert.NoError(t, err)
+			}
+			assert.Equal(t, tc.expectedReturn, actualReturn, "returned data does not match")
+
+			err = s.dbMock.ExpectationsWereMet()
+			assert.NoError(t, err)
+		})
+	}
+}
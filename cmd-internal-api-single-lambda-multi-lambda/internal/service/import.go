@@ -0,0 +1,210 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"slices"
+
+	"github.com/jha-captech/user-microservice/internal/audit"
+	"github.com/jha-captech/user-microservice/internal/models"
+	"github.com/lib/pq"
+)
+
+// ImportUsers loads rows into the users table. The rows are copied into a temporary staging table
+// with COPY, which is then merged into the users table according to onConflict with two statements,
+// however many rows there are. Soft deleted users are ignored, so a row may reuse the user_id of a
+// deleted user. The actor of ctx is recorded as the one who created or updated the users, and every
+// created or updated user is recorded in its history as imported.
+//
+// Everything runs in one transaction. If dryRun is set, the staged rows are only counted against the
+// users table, so that the result reports what the import would do without anything being written. With
+// models.ImportConflictFail, nothing is written if any row conflicts and the result only lists the
+// conflicting rows.
+func (s User) ImportUsers(
+	ctx context.Context,
+	rows []models.ImportRow,
+	onConflict models.ImportConflict,
+	dryRun bool,
+) (models.ImportResult, error) {
+	if !slices.Contains(models.ImportConflicts, onConflict) {
+		return models.ImportResult{}, fmt.Errorf("[in ImportUsers]: invalid conflict resolution %q", onConflict)
+	}
+
+	tx, err := s.database.BeginTx(ctx, nil)
+	if err != nil {
+		return models.ImportResult{}, fmt.Errorf("[in ImportUsers] begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	// stage rows
+	_, err = tx.ExecContext(
+		ctx,
+		`
+		CREATE TEMPORARY TABLE "users_import" (
+			"row"        INTEGER NOT NULL,
+			"first_name" TEXT    NOT NULL,
+			"last_name"  TEXT    NOT NULL,
+			"role"       TEXT    NOT NULL,
+			"user_id"    INTEGER NOT NULL
+		) ON COMMIT DROP
+		`,
+	)
+	if err != nil {
+		return models.ImportResult{}, fmt.Errorf("[in ImportUsers] create staging table: %w", err)
+	}
+
+	stmt, err := tx.PrepareContext(ctx, pq.CopyIn("users_import", "row", "first_name", "last_name", "role", "user_id"))
+	if err != nil {
+		return models.ImportResult{}, fmt.Errorf("[in ImportUsers] start copy: %w", err)
+	}
+	defer stmt.Close()
+
+	for _, row := range rows {
+		user := row.User
+		if _, err = stmt.ExecContext(ctx, row.Row, user.FirstName, user.LastName, user.Role, user.UserID); err != nil {
+			return models.ImportResult{}, fmt.Errorf("[in ImportUsers] copy row %d: %w", row.Row, err)
+		}
+	}
+	if _, err = stmt.ExecContext(ctx); err != nil {
+		return models.ImportResult{}, fmt.Errorf("[in ImportUsers] finish copy: %w", err)
+	}
+
+	// find rows with a user_id that is already in use
+	conflicts, err := tx.QueryContext(
+		ctx,
+		`
+		SELECT
+			"s"."row"
+		FROM
+			"users_import" AS "s"
+			JOIN "users" AS "u" ON "u"."user_id" = "s"."user_id" AND "u"."deleted_at" IS NULL
+		ORDER BY
+			"s"."row"
+		`,
+	)
+	if err != nil {
+		return models.ImportResult{}, fmt.Errorf("[in ImportUsers] find conflicts: %w", err)
+	}
+	defer conflicts.Close()
+
+	var result models.ImportResult
+	for conflicts.Next() {
+		var row int
+		if err = conflicts.Scan(&row); err != nil {
+			return models.ImportResult{}, fmt.Errorf("[in ImportUsers] find conflicts: %w", err)
+		}
+		result.ConflictRows = append(result.ConflictRows, row)
+	}
+	if err = conflicts.Err(); err != nil {
+		return models.ImportResult{}, fmt.Errorf("[in ImportUsers] find conflicts: %w", err)
+	}
+
+	// resolve conflicts
+	switch onConflict {
+	case models.ImportConflictFail:
+		if len(result.ConflictRows) > 0 {
+			return result, nil
+		}
+	case models.ImportConflictSkip:
+		result.Skipped = len(result.ConflictRows)
+	}
+
+	// count what the import would do instead of doing it
+	if dryRun {
+		var updated int
+		err = tx.QueryRowContext(
+			ctx,
+			`
+			SELECT
+				COUNT(*) FILTER (WHERE "u"."id" IS NULL),
+				COUNT(DISTINCT "u"."id")
+			FROM
+				"users_import" AS "s"
+				LEFT JOIN "users" AS "u" ON "u"."user_id" = "s"."user_id" AND "u"."deleted_at" IS NULL
+			`,
+		).Scan(&result.Created, &updated)
+		if err != nil {
+			return models.ImportResult{}, fmt.Errorf("[in ImportUsers] count rows: %w", err)
+		}
+		if onConflict == models.ImportConflictUpdate {
+			result.Updated = updated
+		}
+		return result, nil
+	}
+
+	// update users for conflicting rows
+	if onConflict == models.ImportConflictUpdate {
+		err = tx.QueryRowContext(
+			ctx,
+			`
+			WITH "before" AS (
+				SELECT
+					`+userColumns+`
+				FROM
+					"users"
+				WHERE
+					"user_id" IN (SELECT "user_id" FROM "users_import") AND "deleted_at" IS NULL
+				FOR UPDATE
+			), "after" AS (
+				UPDATE
+					"users"
+				SET
+					"first_name" = "s"."first_name",
+					"last_name" = "s"."last_name",
+					"role" = "s"."role",
+					"version" = "users"."version" + 1,
+					"updated_at" = NOW(),
+					"updated_by" = $1
+				FROM
+					"users_import" AS "s"
+				WHERE
+					"users"."user_id" = "s"."user_id" AND "users"."id" IN (SELECT "id" FROM "before")
+				RETURNING
+					`+qualifiedUserColumns+`
+			), `+recordChange(models.HistoryImport, "before", "after", 1, 2)+`
+			SELECT COUNT(*) FROM "after"
+			`,
+			audit.Actor(ctx),
+			audit.RequestID(ctx),
+		).Scan(&result.Updated)
+		if err != nil {
+			return models.ImportResult{}, fmt.Errorf("[in ImportUsers] update users: %w", err)
+		}
+	}
+
+	// create users for all other rows
+	err = tx.QueryRowContext(
+		ctx,
+		`
+		WITH "after" AS (
+			INSERT INTO "users" ("first_name", "last_name", "role", "user_id", "created_by", "updated_by")
+			SELECT
+				"s"."first_name", "s"."last_name", "s"."role", "s"."user_id", $1, $1
+			FROM
+				"users_import" AS "s"
+			WHERE
+				NOT EXISTS (
+					SELECT 1 FROM "users" AS "u" WHERE "u"."user_id" = "s"."user_id" AND "u"."deleted_at" IS NULL
+				)
+			ORDER BY
+				"s"."row"
+			RETURNING
+				`+userColumns+`
+		), `+recordChange(models.HistoryImport, "", "after", 1, 2)+`
+		SELECT COUNT(*) FROM "after"
+		`,
+		audit.Actor(ctx),
+		audit.RequestID(ctx),
+	).Scan(&result.Created)
+	if err != nil {
+		return models.ImportResult{}, fmt.Errorf("[in ImportUsers] create users: %w", mapUniqueViolation(err))
+	}
+
+	if err = tx.Commit(); err != nil {
+		return models.ImportResult{}, fmt.Errorf("[in ImportUsers] commit transaction: %w", err)
+	}
+
+	s.changes.UsersCreated(result.Created)
+	s.changes.UsersUpdated(result.Updated)
+	return result, nil
+}
@@ -0,0 +1,159 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"regexp"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/jha-captech/user-microservice/internal/audit"
+	"github.com/jha-captech/user-microservice/internal/models"
+	"github.com/stretchr/testify/assert"
+)
+
+func (s *testSuit) TestImportUsers() {
+	t := s.T()
+
+	const (
+		createStaging = `CREATE TEMPORARY TABLE "users_import"`
+		copyIn        = `COPY "users_import" ("row", "first_name", "last_name", "role", "user_id") FROM STDIN`
+		findConflicts = `SELECT "s"."row" FROM "users_import" AS "s" JOIN "users" AS "u" ON "u"."user_id" = "s"."user_id" AND "u"."deleted_at" IS NULL`
+		updateUsers   = `UPDATE "users" SET "first_name" = "s"."first_name", "last_name" = "s"."last_name", "role" = "s"."role", "version" = "users"."version" + 1, "updated_at" = NOW(), "updated_by" = $1 FROM "users_import" AS "s"`
+		insertUsers   = `INSERT INTO "users" ("first_name", "last_name", "role", "user_id", "created_by", "updated_by") SELECT`
+		countRows     = `SELECT COUNT(*) FILTER (WHERE "u"."id" IS NULL), COUNT(DISTINCT "u"."id") FROM "users_import" AS "s" LEFT JOIN "users" AS "u"`
+	)
+
+	ctx := audit.WithActor(context.Background(), "jdoe")
+	updateUsersSQL := regexp.QuoteMeta(updateUsers) + ".*" + regexp.QuoteMeta(recordSQL(historySQL("import", 1, 2, changedEntriesSQL), eventsSQL("user.updated", "after")))
+	insertUsersSQL := regexp.QuoteMeta(insertUsers) + ".*" + regexp.QuoteMeta(recordSQL(historySQL("import", 1, 2, createdEntriesSQL), eventsSQL("user.created", "after")))
+	count := func(n int) *sqlmock.Rows {
+		return sqlmock.NewRows([]string{"count"}).AddRow(n)
+	}
+	rows := []models.ImportRow{
+		{Row: 1, User: models.User{FirstName: "John", LastName: "Doe", Role: "Customer", UserID: 1001}},
+		{Row: 3, User: models.User{FirstName: "Jane", LastName: "Smith", Role: "Employee", UserID: 1002}},
+	}
+	expectStaging := func(mock sqlmock.Sqlmock) {
+		mock.ExpectBegin()
+		mock.ExpectExec(regexp.QuoteMeta(createStaging)).WillReturnResult(sqlmock.NewResult(0, 0))
+		copyStmt := mock.ExpectPrepare(regexp.QuoteMeta(copyIn))
+		for _, row := range rows {
+			copyStmt.ExpectExec().
+				WithArgs(row.Row, row.User.FirstName, row.User.LastName, row.User.Role, row.User.UserID).
+				WillReturnResult(sqlmock.NewResult(0, 0))
+		}
+		copyStmt.ExpectExec().WithoutArgs().WillReturnResult(sqlmock.NewResult(0, 0))
+	}
+
+	testCases := map[string]struct {
+		inputConflict  models.ImportConflict
+		inputDryRun    bool
+		setup          func(mock sqlmock.Sqlmock)
+		expectedReturn models.ImportResult
+		expectedError  error
+	}{
+		"conflicting rows skipped": {
+			inputConflict: models.ImportConflictSkip,
+			setup: func(mock sqlmock.Sqlmock) {
+				expectStaging(mock)
+				mock.ExpectQuery(regexp.QuoteMeta(findConflicts)).
+					WillReturnRows(sqlmock.NewRows([]string{"row"}).AddRow(3))
+				mock.ExpectQuery(insertUsersSQL).WithArgs("jdoe", "").WillReturnRows(count(1))
+				mock.ExpectCommit()
+			},
+			expectedReturn: models.ImportResult{Created: 1, Skipped: 1, ConflictRows: []int{3}},
+		},
+		"conflicting rows updated": {
+			inputConflict: models.ImportConflictUpdate,
+			setup: func(mock sqlmock.Sqlmock) {
+				expectStaging(mock)
+				mock.ExpectQuery(regexp.QuoteMeta(findConflicts)).
+					WillReturnRows(sqlmock.NewRows([]string{"row"}).AddRow(1))
+				mock.ExpectQuery(updateUsersSQL).WithArgs("jdoe", "").WillReturnRows(count(1))
+				mock.ExpectQuery(insertUsersSQL).WithArgs("jdoe", "").WillReturnRows(count(1))
+				mock.ExpectCommit()
+			},
+			expectedReturn: models.ImportResult{Created: 1, Updated: 1, ConflictRows: []int{1}},
+		},
+		"conflicting rows updated in dry run": {
+			inputConflict: models.ImportConflictUpdate,
+			inputDryRun:   true,
+			setup: func(mock sqlmock.Sqlmock) {
+				expectStaging(mock)
+				mock.ExpectQuery(regexp.QuoteMeta(findConflicts)).
+					WillReturnRows(sqlmock.NewRows([]string{"row"}).AddRow(1))
+				mock.ExpectQuery(regexp.QuoteMeta(countRows)).
+					WillReturnRows(sqlmock.NewRows([]string{"created", "updated"}).AddRow(1, 1))
+				mock.ExpectRollback()
+			},
+			expectedReturn: models.ImportResult{Created: 1, Updated: 1, ConflictRows: []int{1}},
+		},
+		"conflicting rows skipped in dry run": {
+			inputConflict: models.ImportConflictSkip,
+			inputDryRun:   true,
+			setup: func(mock sqlmock.Sqlmock) {
+				expectStaging(mock)
+				mock.ExpectQuery(regexp.QuoteMeta(findConflicts)).
+					WillReturnRows(sqlmock.NewRows([]string{"row"}).AddRow(3))
+				mock.ExpectQuery(regexp.QuoteMeta(countRows)).
+					WillReturnRows(sqlmock.NewRows([]string{"created", "updated"}).AddRow(1, 1))
+				mock.ExpectRollback()
+			},
+			expectedReturn: models.ImportResult{Created: 1, Skipped: 1, ConflictRows: []int{3}},
+		},
+		"conflicting rows fail import": {
+			inputConflict: models.ImportConflictFail,
+			setup: func(mock sqlmock.Sqlmock) {
+				expectStaging(mock)
+				mock.ExpectQuery(regexp.QuoteMeta(findConflicts)).
+					WillReturnRows(sqlmock.NewRows([]string{"row"}).AddRow(1).AddRow(3))
+				mock.ExpectRollback()
+			},
+			expectedReturn: models.ImportResult{ConflictRows: []int{1, 3}},
+		},
+		"all rows created": {
+			inputConflict: models.ImportConflictFail,
+			setup: func(mock sqlmock.Sqlmock) {
+				expectStaging(mock)
+				mock.ExpectQuery(regexp.QuoteMeta(findConflicts)).
+					WillReturnRows(sqlmock.NewRows([]string{"row"}))
+				mock.ExpectQuery(insertUsersSQL).WithArgs("jdoe", "").WillReturnRows(count(2))
+				mock.ExpectCommit()
+			},
+			expectedReturn: models.ImportResult{Created: 2},
+		},
+		"error copying rows": {
+			inputConflict: models.ImportConflictSkip,
+			setup: func(mock sqlmock.Sqlmock) {
+				mock.ExpectBegin()
+				mock.ExpectExec(regexp.QuoteMeta(createStaging)).WillReturnResult(sqlmock.NewResult(0, 0))
+				mock.ExpectPrepare(regexp.QuoteMeta(copyIn)).
+					ExpectExec().
+					WithArgs(rows[0].Row, rows[0].User.FirstName, rows[0].User.LastName, rows[0].User.Role, rows[0].User.UserID).
+					WillReturnError(errors.New("test"))
+				mock.ExpectRollback()
+			},
+			expectedError: fmt.Errorf("[in ImportUsers] copy row 1: %w", errors.New("test")),
+		},
+		"invalid conflict resolution": {
+			inputConflict: "merge",
+			setup:         func(mock sqlmock.Sqlmock) {},
+			expectedError: fmt.Errorf("[in ImportUsers]: invalid conflict resolution %q", "merge"),
+		},
+	}
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			tc.setup(s.dbMock)
+
+			actualReturn, err := s.service.ImportUsers(ctx, rows, tc.inputConflict, tc.inputDryRun)
+
+			assert.Equal(t, tc.expectedError, err, "errors did not match")
+			assert.Equal(t, tc.expectedReturn, actualReturn, "returned data does not match")
+
+			err = s.dbMock.ExpectationsWereMet()
+			assert.NoError(t, err)
+		})
+	}
+}
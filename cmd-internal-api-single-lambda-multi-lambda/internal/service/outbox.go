@@ -0,0 +1,38 @@
+package service
+
+import (
+	"fmt"
+
+	"github.com/jha-captech/user-microservice/internal/models"
+)
+
+// changeEvent returns the type of the event published for a change recorded in the history of a
+// user as operation, where before and after are as for insertHistory. Restores and imports are
+// published as updates, and purges as deletes.
+func changeEvent(operation models.HistoryOperation, before, after string) models.EventType {
+	switch {
+	case before == "":
+		return models.EventUserCreated
+	case after == "", operation == models.HistoryDelete:
+		return models.EventUserDeleted
+	default:
+		return models.EventUserUpdated
+	}
+}
+
+// insertEvents returns an insert into user_outbox, to be used as a common table expression of a
+// statement that changes users, which queues an event of the type given by changeEvent for every
+// user changed by the statement. The data of the event is the user after the change, or before it
+// if it was purged. before and after are as for insertHistory.
+func insertEvents(operation models.HistoryOperation, before, after string) string {
+	users := after
+	if after == "" {
+		users = before
+	}
+
+	return fmt.Sprintf(
+		`INSERT INTO "user_outbox" ("type", "subject", "data") SELECT '%s', "u"."id"::text, to_jsonb("u") FROM "%s" AS "u"`,
+		changeEvent(operation, before, after),
+		users,
+	)
+}
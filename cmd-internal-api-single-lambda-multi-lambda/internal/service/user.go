@@ -3,141 +3,738 @@ package service
 import (
 	"context"
 	"database/sql"
+	"errors"
 	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
 
+	"github.com/jha-captech/user-microservice/internal/audit"
+	"github.com/jha-captech/user-microservice/internal/cursor"
 	"github.com/jha-captech/user-microservice/internal/models"
+	"github.com/lib/pq"
 )
 
+// pgUniqueViolation is the Postgres error code raised when a unique constraint is violated.
+const pgUniqueViolation = "23505"
+
+var (
+	// ErrInvalidCursor is returned when a pagination cursor is malformed, has been tampered with,
+	// has expired or does not match the requested sort order.
+	ErrInvalidCursor = errors.New("invalid or expired cursor")
+
+	// ErrUserNotFound is returned when no user exists with the requested ID.
+	ErrUserNotFound = errors.New("user not found")
+
+	// ErrUserIDConflict is returned when a user is created or updated with a user_id that already
+	// belongs to another user.
+	ErrUserIDConflict = errors.New("user_id already in use")
+
+	// ErrVersionMismatch is returned when a user is updated or deleted on the condition that it is
+	// at one of a set of versions and it is at another version.
+	ErrVersionMismatch = errors.New("user version does not match")
+
+	// ErrUserNotDeleted is returned when a user that has not been soft deleted is restored.
+	ErrUserNotDeleted = errors.New("user is not deleted")
+)
+
+// Conditions limiting a statement to users that have or have not been soft deleted.
+const (
+	activeUsers  = `"deleted_at" IS NULL`
+	deletedUsers = `"deleted_at" IS NOT NULL`
+)
+
+// userColumns are the columns of the users table read into a models.User, in the order scanned by
+// scanUser.
+const userColumns = `"id", "first_name", "last_name", "role", "user_id", "version", "deleted_at", ` +
+	`"created_at", "updated_at", "created_by", "updated_by"`
+
+// rowScanner is implemented by *sql.Row and *sql.Rows.
+type rowScanner interface {
+	Scan(dest ...any) error
+}
+
+// scanUser reads the userColumns of row into a models.User.
+func scanUser(row rowScanner) (models.User, error) {
+	var user models.User
+	err := row.Scan(
+		&user.ID, &user.FirstName, &user.LastName, &user.Role, &user.UserID, &user.Version, &user.DeletedAt,
+		&user.CreatedAt, &user.UpdatedAt, &user.CreatedBy, &user.UpdatedBy,
+	)
+	return user, err
+}
+
 type User struct {
 	database *sql.DB
+	cursors  cursor.Codec
+	changes  changeCounter
+}
+
+// changeCounter counts the users that User changes, such as metrics.Recorder.
+type changeCounter interface {
+	UsersCreated(count int)
+	UsersUpdated(count int)
+	UsersDeleted(count int)
+}
+
+// uncounted is a changeCounter that counts nothing.
+type uncounted struct{}
+
+func (uncounted) UsersCreated(int) {}
+func (uncounted) UsersUpdated(int) {}
+func (uncounted) UsersDeleted(int) {}
+
+type Option func(*userOptions)
+
+type userOptions struct {
+	cursorSecret []byte
+	cursorTTL    time.Duration
+	changes      changeCounter
+}
+
+// WithCursorSecret sets the secret used to sign pagination cursors. If this function is not called,
+// a random secret is generated and cursors are only valid for this instance of User.
+func WithCursorSecret(secret string) Option {
+	return func(options *userOptions) {
+		options.cursorSecret = []byte(secret)
+	}
+}
+
+// WithCursorTTL sets how long pagination cursors are valid for. If this function is not called, the
+// default is 24 hours.
+func WithCursorTTL(ttl time.Duration) Option {
+	return func(options *userOptions) {
+		options.cursorTTL = ttl
+	}
+}
+
+// WithChangeCounter sets what counts the users that are created, updated and deleted. Restores are
+// counted as updates, and both soft deletes and purges as deletes. If this function is not called,
+// changes are not counted.
+func WithChangeCounter(changes changeCounter) Option {
+	return func(options *userOptions) {
+		options.changes = changes
+	}
 }
 
 // NewUser returns a new User struct.
-func NewUser(db *sql.DB) *User {
+func NewUser(db *sql.DB, opts ...Option) *User {
+	options := userOptions{
+		cursorTTL: 24 * time.Hour,
+		changes:   uncounted{},
+	}
+	for _, opt := range opts {
+		opt(&options)
+	}
+
 	return &User{
 		database: db,
+		cursors:  cursor.NewCodec(options.cursorSecret, options.cursorTTL),
+		changes:  options.changes,
 	}
 }
 
-// ListUsers returns a list of all User objects from the database.
-func (s User) ListUsers(ctx context.Context) ([]models.User, error) {
+// ListUsers returns a page of User objects from the database matching the given params, along
+// with the total number of users matching the filters and cursors for the adjacent pages.
+//
+// When params.Cursor is empty the page starts at params.Offset, otherwise the page starts directly
+// after (or before) the row the cursor points to. Keyset paging is stable while rows are inserted
+// and deleted, as it never relies on the position of a row.
+func (s User) ListUsers(ctx context.Context, params models.ListUsersParams) (models.UserPage, error) {
+	orderBy, err := userListOrderBy(params)
+	if err != nil {
+		return models.UserPage{}, fmt.Errorf("[in ListUsers]: %w", err)
+	}
+	conditions, args := userListFilter(params)
+
+	var key *cursor.Key
+	if params.Cursor != "" {
+		decoded, err := s.cursors.Decode(params.Cursor)
+		if err != nil {
+			return models.UserPage{}, fmt.Errorf("[in ListUsers]: %w: %w", ErrInvalidCursor, err)
+		}
+		if decoded.SortBy != string(sortByOrDefault(params)) || decoded.Desc != params.SortDesc {
+			return models.UserPage{}, fmt.Errorf(
+				"[in ListUsers]: %w: cursor sort %q does not match request", ErrInvalidCursor, decoded.SortBy,
+			)
+		}
+		key = &decoded
+	}
+
+	var total int
+	err = s.database.
+		QueryRowContext(
+			ctx,
+			`SELECT COUNT(*) FROM "users"`+whereClause(conditions),
+			args...,
+		).
+		Scan(&total)
+	if err != nil {
+		return models.UserPage{}, fmt.Errorf("[in ListUsers]: %w", err)
+	}
+
+	// fetch one extra row to find out if there are more rows past this page
+	var pageClause string
+	if key != nil {
+		var condition string
+		condition, args = userListKeyset(params, *key, args)
+		conditions = append(conditions, condition)
+		if key.Before {
+			orderBy, _ = userListOrderBy(reverseSort(params))
+		}
+		args = append(args, params.Limit+1)
+		pageClause = fmt.Sprintf(" LIMIT $%d", len(args))
+	} else {
+		args = append(args, params.Limit+1, params.Offset)
+		pageClause = fmt.Sprintf(" LIMIT $%d OFFSET $%d", len(args)-1, len(args))
+	}
+
 	rows, err := s.database.QueryContext(
 		ctx,
-		`SELECT * FROM "users"`,
+		`SELECT `+userColumns+` FROM "users"`+whereClause(conditions)+orderBy+pageClause,
+		args...,
 	)
 	if err != nil {
-		return []models.User{}, fmt.Errorf("[in ListUsers]: %w", err)
+		return models.UserPage{}, fmt.Errorf("[in ListUsers]: %w", err)
 	}
 	defer rows.Close()
 
-	var users []models.User
+	users := []models.User{}
 	for rows.Next() {
-		var user models.User
-		err = rows.Scan(&user.ID, &user.FirstName, &user.LastName, &user.Role, &user.UserID)
+		user, err := scanUser(rows)
 		if err != nil {
-			return []models.User{}, fmt.Errorf("[in ListUsers]: %w", err)
+			return models.UserPage{}, fmt.Errorf("[in ListUsers]: %w", err)
 		}
 		users = append(users, user)
 	}
 
 	if err = rows.Err(); err != nil {
-		return []models.User{}, fmt.Errorf("[in ListUsers]: %w", err)
+		return models.UserPage{}, fmt.Errorf("[in ListUsers]: %w", err)
+	}
+
+	hasMore := len(users) > params.Limit
+	if hasMore {
+		users = users[:params.Limit]
+	}
+	if key != nil && key.Before {
+		slices.Reverse(users)
+	}
+
+	// work out which adjacent pages exist
+	var hasNext, hasPrev bool
+	switch {
+	case key == nil:
+		hasNext, hasPrev = hasMore, params.Offset > 0
+	case key.Before:
+		hasNext, hasPrev = true, hasMore
+	default:
+		hasNext, hasPrev = hasMore, true
+	}
+
+	page := models.UserPage{
+		Users: users,
+		Total: total,
+	}
+	if len(users) > 0 {
+		if hasNext {
+			page.NextCursor = s.cursors.Encode(userCursorKey(params, users[len(users)-1], false))
+		}
+		if hasPrev {
+			page.PrevCursor = s.cursors.Encode(userCursorKey(params, users[0], true))
+		}
+	}
+
+	return page, nil
+}
+
+// ExportUsers calls fn with every User from the database matching the filters in params, in the
+// order given by params. The paging fields of params are ignored. Users are passed to fn as they are
+// read from the database, so memory use does not grow with the number of users. If fn returns an
+// error, the export is stopped and the error is returned.
+func (s User) ExportUsers(ctx context.Context, params models.ListUsersParams, fn func(models.User) error) error {
+	orderBy, err := userListOrderBy(params)
+	if err != nil {
+		return fmt.Errorf("[in ExportUsers]: %w", err)
+	}
+	conditions, args := userListFilter(params)
+
+	rows, err := s.database.QueryContext(
+		ctx,
+		`SELECT `+userColumns+` FROM "users"`+whereClause(conditions)+orderBy,
+		args...,
+	)
+	if err != nil {
+		return fmt.Errorf("[in ExportUsers]: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		user, err := scanUser(rows)
+		if err != nil {
+			return fmt.Errorf("[in ExportUsers]: %w", err)
+		}
+		if err = fn(user); err != nil {
+			return fmt.Errorf("[in ExportUsers]: %w", err)
+		}
+	}
+
+	if err = rows.Err(); err != nil {
+		return fmt.Errorf("[in ExportUsers]: %w", err)
 	}
 
-	return users, nil
+	return nil
 }
 
-// FetchUser returns am User objects from the database by ID.
-func (s User) FetchUser(ctx context.Context, ID int) (models.User, error) {
-	var user models.User
-	err := s.database.
-		QueryRowContext(
-			ctx,
-			`
-			SELECT
-				*
-			FROM
-				"users"
-			WHERE
-				id = $1
-			ORDER BY
-				"users"."id"
-			LIMIT 1
-			`,
-			ID,
-		).
-		Scan(&user.ID, &user.FirstName, &user.LastName, &user.Role, &user.UserID)
+// FetchUser returns am User objects from the database by ID. A soft deleted user is only returned
+// if includeDeleted is set.
+func (s User) FetchUser(ctx context.Context, ID int, includeDeleted bool) (models.User, error) {
+	conditions := []string{`"id" = $1`}
+	if !includeDeleted {
+		conditions = append(conditions, activeUsers)
+	}
+
+	user, err := scanUser(s.database.QueryRowContext(
+		ctx,
+		`SELECT `+userColumns+` FROM "users"`+whereClause(conditions)+` LIMIT 1`,
+		ID,
+	))
 	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			err = ErrUserNotFound
+		}
 		return models.User{}, fmt.Errorf("[in FetchUser]: %w", err)
 	}
 
 	return user, nil
 }
 
-// UpdateUser updates am User objects from the database by ID.
-func (s User) UpdateUser(ctx context.Context, ID int, user models.User) (models.User, error) {
-	_, err := s.database.ExecContext(
+// FetchUserByUserID returns the User whose user_id is userID, which identifies the user across
+// systems, from the database. Soft deleted users are not returned.
+func (s User) FetchUserByUserID(ctx context.Context, userID uint) (models.User, error) {
+	user, err := scanUser(s.database.QueryRowContext(
 		ctx,
-		`
-		UPDATE
-			"users"
-		SET
+		`SELECT `+userColumns+` FROM "users"`+whereClause([]string{`"user_id" = $1`, activeUsers})+` LIMIT 1`,
+		userID,
+	))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			err = ErrUserNotFound
+		}
+		return models.User{}, fmt.Errorf("[in FetchUserByUserID]: %w", err)
+	}
+
+	return user, nil
+}
+
+// UpdateUser updates am User objects from the database by ID, increments its version and records
+// the actor of ctx as the one who updated it. If matchVersions is not empty, the user is only
+// updated if it is at one of those versions and ErrVersionMismatch is returned otherwise. Soft
+// deleted users can not be updated. The change is recorded in the history of the user.
+func (s User) UpdateUser(ctx context.Context, ID int, user models.User, matchVersions []uint) (models.User, error) {
+	conditions, args := userVersionFilter(
+		ID,
+		activeUsers,
+		matchVersions,
+		[]any{user.FirstName, user.LastName, user.Role, user.UserID, audit.Actor(ctx), audit.RequestID(ctx)},
+	)
+
+	updated, err := scanUser(s.database.QueryRowContext(
+		ctx,
+		changeUsers(
+			models.HistoryUpdate,
+			`
 			"first_name" = $1,
 			"last_name" = $2,
 			"role" = $3,
-			"user_id" = $4
-		WHERE
-			"id" = $5
-		`,
-		user.FirstName,
-		user.LastName,
-		user.Role,
-		user.UserID,
-		ID,
+			"user_id" = $4,
+			"version" = "version" + 1,
+			"updated_at" = NOW(),
+			"updated_by" = $5
+			`,
+			conditions,
+			5,
+			6,
+			userColumns,
+		),
+		args...,
+	))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			err = s.unchangedUserError(ctx, ID, activeUsers, matchVersions)
+		}
+		return models.User{}, fmt.Errorf("[in UpdateUser]: %w", mapUniqueViolation(err))
+	}
+
+	s.changes.UsersUpdated(1)
+	return updated, nil
+}
+
+// PatchUser updates only the fields of the User with ID that are set in changes, increments its
+// version and records the actor of ctx as the one who updated it. If matchVersions is not empty,
+// the user is only updated if it is at one of those versions and ErrVersionMismatch is returned
+// otherwise. Soft deleted users can not be updated. The change is recorded in the history of the
+// user.
+func (s User) PatchUser(ctx context.Context, ID int, changes models.UserChanges, matchVersions []uint) (models.User, error) {
+	var (
+		assignments []string
+		args        []any
 	)
+	set := func(column string, value any) {
+		args = append(args, value)
+		assignments = append(assignments, fmt.Sprintf(`"%s" = $%d`, column, len(args)))
+	}
+
+	if changes.FirstName != nil {
+		set("first_name", *changes.FirstName)
+	}
+	if changes.LastName != nil {
+		set("last_name", *changes.LastName)
+	}
+	if changes.Role != nil {
+		set("role", *changes.Role)
+	}
+	if changes.UserID != nil {
+		set("user_id", *changes.UserID)
+	}
+	set("updated_by", audit.Actor(ctx))
+	assignments = append(assignments, `"version" = "version" + 1`, `"updated_at" = NOW()`)
+	actor := len(args)
+	args = append(args, audit.RequestID(ctx))
+
+	conditions, args := userVersionFilter(ID, activeUsers, matchVersions, args)
+
+	user, err := scanUser(s.database.QueryRowContext(
+		ctx,
+		changeUsers(models.HistoryPatch, strings.Join(assignments, ", "), conditions, actor, actor+1, userColumns),
+		args...,
+	))
 	if err != nil {
-		return models.User{}, fmt.Errorf("[in UpdateUser]: %w", err)
+		if errors.Is(err, sql.ErrNoRows) {
+			err = s.unchangedUserError(ctx, ID, activeUsers, matchVersions)
+		}
+		return models.User{}, fmt.Errorf("[in PatchUser]: %w", mapUniqueViolation(err))
 	}
 
-	user.ID = uint(ID)
+	s.changes.UsersUpdated(1)
 	return user, nil
 }
 
-// CreateUser creates am User objects in the database.
+// CreateUser creates am User objects in the database, recording the actor of ctx as the one who
+// created it. The creation is recorded in the history of the user.
 func (s User) CreateUser(ctx context.Context, user models.User) (int, error) {
 	var ID int
 	err := s.database.QueryRowContext(
 		ctx,
 		`
-		INSERT INTO "users" ("first_name", "last_name", "role", "user_id")
-			VALUES ($1, $2, $3, $4)
-		RETURNING "id"
+		WITH "after" AS (
+			INSERT INTO "users" ("first_name", "last_name", "role", "user_id", "created_by", "updated_by")
+				VALUES ($1, $2, $3, $4, $5, $5)
+			RETURNING `+userColumns+`
+		), `+recordChange(models.HistoryCreate, "", "after", 5, 6)+`
+		SELECT "id" FROM "after"
 		`,
 		user.FirstName,
 		user.LastName,
 		user.Role,
 		user.UserID,
+		audit.Actor(ctx),
+		audit.RequestID(ctx),
 	).Scan(&ID)
 	if err != nil {
-		return 0, fmt.Errorf("[in CreateUser]: %w", err)
+		return 0, fmt.Errorf("[in CreateUser]: %w", mapUniqueViolation(err))
 	}
 
+	s.changes.UsersCreated(1)
 	return ID, nil
 }
 
-// DeleteUser deletes am User objects from the database by ID.
-func (s User) DeleteUser(ctx context.Context, ID int) error {
-	_, err := s.database.ExecContext(
-		ctx,
-		`
-		DELETE FROM "users"
-		WHERE "users"."id" = $1
-		`,
+// DeleteUser soft deletes am User objects from the database by ID, by setting its deleted_at and
+// incrementing its version. The user is kept, so that it can be restored with RestoreUser, but is
+// treated as not existing by every other method. The actor of ctx is recorded as the one who
+// updated it. If matchVersions is not empty, the user is only deleted if it is at one of those
+// versions and ErrVersionMismatch is returned otherwise. The deletion is recorded in the history of
+// the user.
+func (s User) DeleteUser(ctx context.Context, ID int, matchVersions []uint) error {
+	conditions, args := userVersionFilter(
 		ID,
+		activeUsers,
+		matchVersions,
+		[]any{audit.Actor(ctx), audit.RequestID(ctx)},
 	)
+
+	var deletedID int
+	err := s.database.QueryRowContext(
+		ctx,
+		changeUsers(
+			models.HistoryDelete,
+			`"deleted_at" = NOW(), "version" = "version" + 1, "updated_at" = NOW(), "updated_by" = $1`,
+			conditions,
+			1,
+			2,
+			`"id"`,
+		),
+		args...,
+	).Scan(&deletedID)
 	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			err = s.unchangedUserError(ctx, ID, activeUsers, matchVersions)
+		}
 		return fmt.Errorf("[in DeleteUser]: %w", err)
 	}
 
+	s.changes.UsersDeleted(1)
 	return nil
 }
+
+// RestoreUser undoes the soft delete of the User with ID, increments its version and records the
+// actor of ctx as the one who updated it. ErrUserNotDeleted is returned if the user has not been
+// deleted and ErrUserIDConflict if its user_id has since been given to another user. If
+// matchVersions is not empty, the user is only restored if it is at one of those versions and
+// ErrVersionMismatch is returned otherwise. The restore is recorded in the history of the user.
+func (s User) RestoreUser(ctx context.Context, ID int, matchVersions []uint) (models.User, error) {
+	conditions, args := userVersionFilter(
+		ID,
+		deletedUsers,
+		matchVersions,
+		[]any{audit.Actor(ctx), audit.RequestID(ctx)},
+	)
+
+	user, err := scanUser(s.database.QueryRowContext(
+		ctx,
+		changeUsers(
+			models.HistoryRestore,
+			`"deleted_at" = NULL, "version" = "version" + 1, "updated_at" = NOW(), "updated_by" = $1`,
+			conditions,
+			1,
+			2,
+			userColumns,
+		),
+		args...,
+	))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			err = s.unchangedUserError(ctx, ID, deletedUsers, matchVersions)
+		}
+		return models.User{}, fmt.Errorf("[in RestoreUser]: %w", mapUniqueViolation(err))
+	}
+
+	s.changes.UsersUpdated(1)
+	return user, nil
+}
+
+// PurgeUser permanently deletes the User with ID from the database, whether or not it has been soft
+// deleted. If matchVersions is not empty, the user is only deleted if it is at one of those versions
+// and ErrVersionMismatch is returned otherwise. The history of the user is kept, and the purge is
+// recorded in it with the actor of ctx.
+func (s User) PurgeUser(ctx context.Context, ID int, matchVersions []uint) error {
+	conditions, args := userVersionFilter(ID, "", matchVersions, []any{audit.Actor(ctx), audit.RequestID(ctx)})
+
+	var purgedID int
+	err := s.database.QueryRowContext(
+		ctx,
+		`
+		WITH "before" AS (
+			DELETE FROM "users"`+whereClause(conditions)+`
+			RETURNING `+userColumns+`
+		), `+recordChange(models.HistoryPurge, "before", "", 1, 2)+`
+		SELECT "id" FROM "before"
+		`,
+		args...,
+	).Scan(&purgedID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			err = s.unchangedUserError(ctx, ID, "", matchVersions)
+		}
+		return fmt.Errorf("[in PurgeUser]: %w", err)
+	}
+
+	s.changes.UsersDeleted(1)
+	return nil
+}
+
+// unchangedUserError returns why a statement on the user with ID, limited to users matching scope
+// and conditional on matchVersions, changed no rows: either the user does not exist, it does not
+// match scope or it is at another version. A user that is not deleted does not match deletedUsers
+// with ErrUserNotDeleted, while every other mismatch is reported as ErrUserNotFound.
+func (s User) unchangedUserError(ctx context.Context, ID int, scope string, matchVersions []uint) error {
+	if len(matchVersions) == 0 && scope != deletedUsers {
+		return ErrUserNotFound
+	}
+
+	var deleted bool
+	err := s.database.
+		QueryRowContext(ctx, `SELECT "deleted_at" IS NOT NULL FROM "users" WHERE "id" = $1`, ID).
+		Scan(&deleted)
+	switch {
+	case errors.Is(err, sql.ErrNoRows):
+		return ErrUserNotFound
+	case err != nil:
+		return err
+	case scope == activeUsers && deleted:
+		return ErrUserNotFound
+	case scope == deletedUsers && !deleted:
+		return ErrUserNotDeleted
+	}
+
+	return ErrVersionMismatch
+}
+
+// mapUniqueViolation converts a Postgres unique violation into ErrUserIDConflict. Other errors are
+// returned unchanged.
+func mapUniqueViolation(err error) error {
+	var pqErr *pq.Error
+	if errors.As(err, &pqErr) && pqErr.Code == pgUniqueViolation {
+		return fmt.Errorf("%w: %w", ErrUserIDConflict, err)
+	}
+
+	return err
+}
+
+// userListFilter builds the WHERE conditions and their positional arguments for the filters set in
+// params.
+func userListFilter(params models.ListUsersParams) ([]string, []any) {
+	var (
+		conditions []string
+		args       []any
+	)
+
+	if params.Role != "" {
+		args = append(args, params.Role)
+		conditions = append(conditions, fmt.Sprintf(`"role" = $%d`, len(args)))
+	}
+	if params.LastNamePrefix != "" {
+		args = append(args, likePrefix(params.LastNamePrefix))
+		conditions = append(conditions, fmt.Sprintf(`"last_name" LIKE $%d`, len(args)))
+	}
+	if params.UserIDMin > 0 {
+		args = append(args, params.UserIDMin)
+		conditions = append(conditions, fmt.Sprintf(`"user_id" >= $%d`, len(args)))
+	}
+	if params.UserIDMax > 0 {
+		args = append(args, params.UserIDMax)
+		conditions = append(conditions, fmt.Sprintf(`"user_id" <= $%d`, len(args)))
+	}
+	if !params.UpdatedSince.IsZero() {
+		args = append(args, params.UpdatedSince)
+		conditions = append(conditions, fmt.Sprintf(`"updated_at" >= $%d`, len(args)))
+	}
+	if !params.IncludeDeleted {
+		conditions = append(conditions, activeUsers)
+	}
+
+	return conditions, args
+}
+
+// userVersionFilter builds the WHERE conditions that select the user with ID, restricted to users
+// matching scope when it is not empty and to matchVersions when it is not empty. Their positional
+// arguments are appended to args.
+func userVersionFilter(ID int, scope string, matchVersions []uint, args []any) ([]string, []any) {
+	args = append(args, ID)
+	conditions := []string{fmt.Sprintf(`"id" = $%d`, len(args))}
+	if scope != "" {
+		conditions = append(conditions, scope)
+	}
+
+	if len(matchVersions) > 0 {
+		placeholders := make([]string, len(matchVersions))
+		for i, version := range matchVersions {
+			args = append(args, version)
+			placeholders[i] = fmt.Sprintf("$%d", len(args))
+		}
+		conditions = append(conditions, fmt.Sprintf(`"version" IN (%s)`, strings.Join(placeholders, ", ")))
+	}
+
+	return conditions, args
+}
+
+// userListKeyset builds the WHERE condition that starts a page directly after (or before) the row
+// key points to, appending its positional arguments to args.
+func userListKeyset(params models.ListUsersParams, key cursor.Key, args []any) (string, []any) {
+	operator := ">"
+	if params.SortDesc != key.Before {
+		operator = "<"
+	}
+
+	sortBy := sortByOrDefault(params)
+	if sortBy == models.UserSortID {
+		args = append(args, key.ID)
+		return fmt.Sprintf(`"id" %s $%d`, operator, len(args)), args
+	}
+
+	args = append(args, key.Value, key.ID)
+	return fmt.Sprintf(`("%s", "id") %s ($%d, $%d)`, sortBy, operator, len(args)-1, len(args)), args
+}
+
+// userListOrderBy builds the ORDER BY clause for params. Only fields in models.UserSortFields are
+// permitted, and "id" is always used as a tie-breaker so that paging is deterministic.
+func userListOrderBy(params models.ListUsersParams) (string, error) {
+	sortBy := sortByOrDefault(params)
+	if !slices.Contains(models.UserSortFields, sortBy) {
+		return "", fmt.Errorf("invalid sort field %q", sortBy)
+	}
+
+	direction := "ASC"
+	if params.SortDesc {
+		direction = "DESC"
+	}
+
+	if sortBy == models.UserSortID {
+		return fmt.Sprintf(` ORDER BY "id" %s`, direction), nil
+	}
+	return fmt.Sprintf(` ORDER BY "%s" %s, "id" %s`, sortBy, direction, direction), nil
+}
+
+// userCursorKey returns the cursor.Key for user, based on the sort order in params.
+func userCursorKey(params models.ListUsersParams, user models.User, before bool) cursor.Key {
+	sortBy := sortByOrDefault(params)
+
+	var value string
+	switch sortBy {
+	case models.UserSortFirstName:
+		value = user.FirstName
+	case models.UserSortLastName:
+		value = user.LastName
+	case models.UserSortRole:
+		value = user.Role
+	case models.UserSortUserID:
+		value = strconv.FormatUint(uint64(user.UserID), 10)
+	}
+
+	return cursor.Key{
+		SortBy: string(sortBy),
+		Desc:   params.SortDesc,
+		Value:  value,
+		ID:     user.ID,
+		Before: before,
+	}
+}
+
+// sortByOrDefault returns the sort field in params, defaulting to "id".
+func sortByOrDefault(params models.ListUsersParams) models.UserSortField {
+	if params.SortBy == "" {
+		return models.UserSortID
+	}
+	return params.SortBy
+}
+
+// reverseSort returns a copy of params with the sort direction flipped.
+func reverseSort(params models.ListUsersParams) models.ListUsersParams {
+	params.SortDesc = !params.SortDesc
+	return params
+}
+
+// whereClause joins conditions into a WHERE clause. An empty string is returned when there are no
+// conditions.
+func whereClause(conditions []string) string {
+	if len(conditions) == 0 {
+		return ""
+	}
+	return " WHERE " + strings.Join(conditions, " AND ")
+}
+
+// likePrefix escapes the LIKE wildcards in prefix and appends a trailing wildcard.
+func likePrefix(prefix string) string {
+	replacer := strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`)
+	return replacer.Replace(prefix) + "%"
+}
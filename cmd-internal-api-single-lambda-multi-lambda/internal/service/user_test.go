@@ -8,19 +8,28 @@ import (
 	"fmt"
 	"reflect"
 	"regexp"
+	"slices"
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/go-chi/chi/v5/middleware"
+	"github.com/jha-captech/user-microservice/internal/audit"
+	"github.com/jha-captech/user-microservice/internal/cursor"
 	"github.com/jha-captech/user-microservice/internal/models"
+	"github.com/lib/pq"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/suite"
 )
 
 type testSuit struct {
 	suite.Suite
-	service *User
-	dbMock  sqlmock.Sqlmock
+	service  *User
+	webhooks *Webhook
+	apiKeys  *APIKey
+	changes  *changeCounts
+	dbMock   sqlmock.Sqlmock
 }
 
 func TestTestSuit(t *testing.T) {
@@ -32,7 +41,10 @@ func (s *testSuit) SetupSuite() {
 	assert.NoError(s.T(), err)
 
 	s.dbMock = mock
-	s.service = NewUser(db)
+	s.changes = &changeCounts{}
+	s.service = NewUser(db, WithChangeCounter(s.changes))
+	s.webhooks = NewWebhook(db)
+	s.apiKeys = NewAPIKey(db)
 }
 
 func (s *testSuit) TearDownSuite() {
@@ -45,39 +57,263 @@ func (s *testSuit) TestListUsers() {
 	users := []models.User{
 		{ID: 1, FirstName: "John", LastName: "Doe", Role: "Admin", UserID: 1001},
 		{ID: 2, FirstName: "Jane", LastName: "Smith", Role: "User", UserID: 1002},
+		{ID: 3, FirstName: "Jim", LastName: "Smith", Role: "User", UserID: 1003},
 	}
+	deletedAt := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	deletedUser := models.User{ID: 4, FirstName: "Joe", LastName: "Bloggs", Role: "User", UserID: 1004, DeletedAt: &deletedAt}
+
+	const selectUsers = `SELECT "id", "first_name", "last_name", "role", "user_id", "version", "deleted_at", "created_at", "updated_at", "created_by", "updated_by" FROM "users"`
+
+	afterCursor := s.service.cursors.Encode(cursor.Key{SortBy: "last_name", Value: "Doe", ID: 1})
+	beforeCursor := s.service.cursors.Encode(cursor.Key{SortBy: "id", Desc: true, ID: 1, Before: true})
 
 	testCases := map[string]struct {
+		inputParams        models.ListUsersParams
+		expectedCountSQL   string
+		expectedSelectSQL  string
+		expectedArgs       []driver.Value
+		expectedSelectArgs []driver.Value
+		mockCount          *sqlmock.Rows
+		mockCountErr       error
+		mockReturn         *sqlmock.Rows
+		mockReturnErr      error
+		expectedReturn     []models.User
+		expectedTotal      int
+		expectedNext       *cursor.Key
+		expectedPrev       *cursor.Key
+		expectedError      error
+	}{
+		"Return page of users": {
+			inputParams:        models.ListUsersParams{Limit: 20},
+			expectedCountSQL:   `SELECT COUNT(*) FROM "users" WHERE "deleted_at" IS NULL`,
+			expectedSelectSQL:  selectUsers + ` WHERE "deleted_at" IS NULL ORDER BY "id" ASC LIMIT $1 OFFSET $2`,
+			expectedArgs:       []driver.Value{},
+			expectedSelectArgs: []driver.Value{21, 0},
+			mockCount:          sqlmock.NewRows([]string{"count"}).AddRow(3),
+			mockReturn:         mustStructsToRows(users),
+			expectedReturn:     users,
+			expectedTotal:      3,
+		},
+		"Return filtered and sorted page of users": {
+			inputParams: models.ListUsersParams{
+				Limit:          1,
+				Offset:         10,
+				Role:           "Employee",
+				LastNamePrefix: "Sm_th%",
+				UserIDMin:      1000,
+				UserIDMax:      2000,
+				SortBy:         models.UserSortLastName,
+				SortDesc:       true,
+			},
+			expectedCountSQL: `SELECT COUNT(*) FROM "users" WHERE "role" = $1 AND "last_name" LIKE $2 AND "user_id" >= $3 AND "user_id" <= $4 AND "deleted_at" IS NULL`,
+			expectedSelectSQL: selectUsers +
+				` WHERE "role" = $1 AND "last_name" LIKE $2 AND "user_id" >= $3 AND "user_id" <= $4 AND "deleted_at" IS NULL` +
+				` ORDER BY "last_name" DESC, "id" DESC LIMIT $5 OFFSET $6`,
+			expectedArgs:       []driver.Value{"Employee", `Sm\_th\%%`, 1000, 2000},
+			expectedSelectArgs: []driver.Value{"Employee", `Sm\_th\%%`, 1000, 2000, 2, 10},
+			mockCount:          sqlmock.NewRows([]string{"count"}).AddRow(12),
+			mockReturn:         mustStructsToRows(users[1:]),
+			expectedReturn:     users[1:2],
+			expectedTotal:      12,
+			expectedNext:       &cursor.Key{SortBy: "last_name", Desc: true, Value: "Smith", ID: 2},
+			expectedPrev:       &cursor.Key{SortBy: "last_name", Desc: true, Value: "Smith", ID: 2, Before: true},
+		},
+		"Return page of users after cursor": {
+			inputParams:        models.ListUsersParams{Limit: 2, Cursor: afterCursor, SortBy: models.UserSortLastName},
+			expectedCountSQL:   `SELECT COUNT(*) FROM "users" WHERE "deleted_at" IS NULL`,
+			expectedSelectSQL:  selectUsers + ` WHERE "deleted_at" IS NULL AND ("last_name", "id") > ($1, $2) ORDER BY "last_name" ASC, "id" ASC LIMIT $3`,
+			expectedArgs:       []driver.Value{},
+			expectedSelectArgs: []driver.Value{"Doe", 1, 3},
+			mockCount:          sqlmock.NewRows([]string{"count"}).AddRow(3),
+			mockReturn:         mustStructsToRows(users[1:]),
+			expectedReturn:     users[1:],
+			expectedTotal:      3,
+			expectedPrev:       &cursor.Key{SortBy: "last_name", Value: "Smith", ID: 2, Before: true},
+		},
+		"Return page of users before cursor": {
+			inputParams:        models.ListUsersParams{Limit: 1, Cursor: beforeCursor, SortBy: models.UserSortID, SortDesc: true, Role: "User"},
+			expectedCountSQL:   `SELECT COUNT(*) FROM "users" WHERE "role" = $1 AND "deleted_at" IS NULL`,
+			expectedSelectSQL:  selectUsers + ` WHERE "role" = $1 AND "deleted_at" IS NULL AND "id" > $2 ORDER BY "id" ASC LIMIT $3`,
+			expectedArgs:       []driver.Value{"User"},
+			expectedSelectArgs: []driver.Value{"User", 1, 2},
+			mockCount:          sqlmock.NewRows([]string{"count"}).AddRow(3),
+			mockReturn:         mustStructsToRows(users[1:]),
+			expectedReturn:     users[1:2],
+			expectedTotal:      3,
+			expectedNext:       &cursor.Key{SortBy: "id", Desc: true, ID: 2},
+			expectedPrev:       &cursor.Key{SortBy: "id", Desc: true, ID: 2, Before: true},
+		},
+		"Return page of users updated since": {
+			inputParams:        models.ListUsersParams{Limit: 20, Role: "User", UpdatedSince: deletedAt},
+			expectedCountSQL:   `SELECT COUNT(*) FROM "users" WHERE "role" = $1 AND "updated_at" >= $2 AND "deleted_at" IS NULL`,
+			expectedSelectSQL:  selectUsers + ` WHERE "role" = $1 AND "updated_at" >= $2 AND "deleted_at" IS NULL ORDER BY "id" ASC LIMIT $3 OFFSET $4`,
+			expectedArgs:       []driver.Value{"User", deletedAt},
+			expectedSelectArgs: []driver.Value{"User", deletedAt, 21, 0},
+			mockCount:          sqlmock.NewRows([]string{"count"}).AddRow(2),
+			mockReturn:         mustStructsToRows(users[1:]),
+			expectedReturn:     users[1:],
+			expectedTotal:      2,
+		},
+		"Return page of users including deleted users": {
+			inputParams:        models.ListUsersParams{Limit: 20, IncludeDeleted: true},
+			expectedCountSQL:   `SELECT COUNT(*) FROM "users"`,
+			expectedSelectSQL:  selectUsers + ` ORDER BY "id" ASC LIMIT $1 OFFSET $2`,
+			expectedArgs:       []driver.Value{},
+			expectedSelectArgs: []driver.Value{21, 0},
+			mockCount:          sqlmock.NewRows([]string{"count"}).AddRow(4),
+			mockReturn:         mustStructsToRows(append(slices.Clone(users), deletedUser)),
+			expectedReturn:     append(slices.Clone(users), deletedUser),
+			expectedTotal:      4,
+		},
+		"No users found": {
+			inputParams:        models.ListUsersParams{Limit: 20},
+			expectedCountSQL:   `SELECT COUNT(*) FROM "users" WHERE "deleted_at" IS NULL`,
+			expectedSelectSQL:  selectUsers + ` WHERE "deleted_at" IS NULL ORDER BY "id" ASC LIMIT $1 OFFSET $2`,
+			expectedArgs:       []driver.Value{},
+			expectedSelectArgs: []driver.Value{21, 0},
+			mockCount:          sqlmock.NewRows([]string{"count"}).AddRow(0),
+			mockReturn:         mustStructToEmptyRow(models.User{}),
+			expectedReturn:     []models.User{},
+			expectedTotal:      0,
+		},
+		"Invalid sort field": {
+			inputParams:   models.ListUsersParams{Limit: 20, SortBy: "password"},
+			expectedError: fmt.Errorf("[in ListUsers]: %w", errors.New(`invalid sort field "password"`)),
+		},
+		"Tampered cursor": {
+			inputParams:   models.ListUsersParams{Limit: 20, Cursor: "x" + afterCursor, SortBy: models.UserSortLastName},
+			expectedError: ErrInvalidCursor,
+		},
+		"Cursor for a different sort": {
+			inputParams:   models.ListUsersParams{Limit: 20, Cursor: afterCursor, SortBy: models.UserSortFirstName},
+			expectedError: ErrInvalidCursor,
+		},
+		"Error counting users": {
+			inputParams:      models.ListUsersParams{Limit: 20},
+			expectedCountSQL: `SELECT COUNT(*) FROM "users" WHERE "deleted_at" IS NULL`,
+			expectedArgs:     []driver.Value{},
+			mockCount:        &sqlmock.Rows{},
+			mockCountErr:     errors.New("test"),
+			expectedError:    fmt.Errorf("[in ListUsers]: %w", errors.New("test")),
+		},
+		"Error getting users": {
+			inputParams:        models.ListUsersParams{Limit: 20},
+			expectedCountSQL:   `SELECT COUNT(*) FROM "users" WHERE "deleted_at" IS NULL`,
+			expectedSelectSQL:  selectUsers + ` WHERE "deleted_at" IS NULL ORDER BY "id" ASC LIMIT $1 OFFSET $2`,
+			expectedArgs:       []driver.Value{},
+			expectedSelectArgs: []driver.Value{21, 0},
+			mockCount:          sqlmock.NewRows([]string{"count"}).AddRow(2),
+			mockReturn:         &sqlmock.Rows{},
+			mockReturnErr:      errors.New("test"),
+			expectedError:      fmt.Errorf("[in ListUsers]: %w", errors.New("test")),
+		},
+	}
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			if tc.expectedCountSQL != "" {
+				s.dbMock.
+					ExpectQuery(regexp.QuoteMeta(tc.expectedCountSQL)).
+					WithArgs(tc.expectedArgs...).
+					WillReturnRows(tc.mockCount).
+					WillReturnError(tc.mockCountErr)
+			}
+			if tc.expectedSelectSQL != "" {
+				s.dbMock.
+					ExpectQuery(regexp.QuoteMeta(tc.expectedSelectSQL)).
+					WithArgs(tc.expectedSelectArgs...).
+					WillReturnRows(tc.mockReturn).
+					WillReturnError(tc.mockReturnErr)
+			}
+
+			actualReturn, err := s.service.ListUsers(context.Background(), tc.inputParams)
+
+			if errors.Is(tc.expectedError, ErrInvalidCursor) {
+				assert.ErrorIs(t, err, ErrInvalidCursor, "errors did not match")
+			} else {
+				assert.Equal(t, tc.expectedError, err, "errors did not match")
+			}
+			assert.Equal(t, tc.expectedReturn, actualReturn.Users, "returned data does not match")
+			assert.Equal(t, tc.expectedTotal, actualReturn.Total, "returned total does not match")
+			s.assertCursor(t, tc.expectedNext, actualReturn.NextCursor, "next cursor does not match")
+			s.assertCursor(t, tc.expectedPrev, actualReturn.PrevCursor, "prev cursor does not match")
+
+			err = s.dbMock.ExpectationsWereMet()
+			assert.NoError(t, err)
+		})
+	}
+}
+
+func (s *testSuit) TestExportUsers() {
+	t := s.T()
+
+	users := []models.User{
+		{ID: 2, FirstName: "Jane", LastName: "Smith", Role: "Employee", UserID: 1002, Version: 1},
+		{ID: 3, FirstName: "Jim", LastName: "Smith", Role: "Employee", UserID: 1003, Version: 2},
+	}
+	stopErr := errors.New("stop")
+
+	const selectUsers = `SELECT "id", "first_name", "last_name", "role", "user_id", "version", "deleted_at", "created_at", "updated_at", "created_by", "updated_by" FROM "users"`
+
+	testCases := map[string]struct {
+		inputParams    models.ListUsersParams
+		fnErr          error
+		expectedSQL    string
+		expectedArgs   []driver.Value
 		mockReturn     *sqlmock.Rows
 		mockReturnErr  error
 		expectedReturn []models.User
 		expectedError  error
 	}{
-		"Return slice of users": {
+		"all users exported in order": {
+			inputParams:    models.ListUsersParams{Limit: 1, Offset: 5},
+			expectedSQL:    selectUsers + ` WHERE "deleted_at" IS NULL ORDER BY "id" ASC`,
 			mockReturn:     mustStructsToRows(users),
-			mockReturnErr:  nil,
 			expectedReturn: users,
-			expectedError:  nil,
 		},
-		"Error getting users": {
-			mockReturn:     &sqlmock.Rows{},
-			mockReturnErr:  errors.New("test"),
-			expectedReturn: []models.User{},
-			expectedError:  fmt.Errorf("[in ListUsers]: %w", errors.New("test")),
+		"filtered and sorted users exported": {
+			inputParams: models.ListUsersParams{
+				Role:           "Employee",
+				LastNamePrefix: "Sm",
+				SortBy:         models.UserSortFirstName,
+				SortDesc:       true,
+			},
+			expectedSQL:    selectUsers + ` WHERE "role" = $1 AND "last_name" LIKE $2 AND "deleted_at" IS NULL ORDER BY "first_name" DESC, "id" DESC`,
+			expectedArgs:   []driver.Value{"Employee", "Sm%"},
+			mockReturn:     mustStructsToRows(users),
+			expectedReturn: users,
+		},
+		"export stopped by fn": {
+			inputParams:    models.ListUsersParams{IncludeDeleted: true},
+			fnErr:          stopErr,
+			expectedSQL:    selectUsers + ` ORDER BY "id" ASC`,
+			mockReturn:     mustStructsToRows(users),
+			expectedReturn: users[:1],
+			expectedError:  fmt.Errorf("[in ExportUsers]: %w", stopErr),
+		},
+		"error querying users": {
+			inputParams:   models.ListUsersParams{},
+			expectedSQL:   selectUsers + ` WHERE "deleted_at" IS NULL ORDER BY "id" ASC`,
+			mockReturn:    &sqlmock.Rows{},
+			mockReturnErr: errors.New("test"),
+			expectedError: fmt.Errorf("[in ExportUsers]: %w", errors.New("test")),
 		},
 	}
 	for name, tc := range testCases {
 		t.Run(name, func(t *testing.T) {
-			exp := `SELECT * FROM "users"`
 			s.dbMock.
-				ExpectQuery(regexp.QuoteMeta(exp)).
+				ExpectQuery(regexp.QuoteMeta(tc.expectedSQL)).
+				WithArgs(tc.expectedArgs...).
 				WillReturnRows(tc.mockReturn).
 				WillReturnError(tc.mockReturnErr)
 
-			actualReturn, err := s.service.ListUsers(context.Background())
+			var actualReturn []models.User
+			err := s.service.ExportUsers(context.Background(), tc.inputParams, func(user models.User) error {
+				actualReturn = append(actualReturn, user)
+				return tc.fnErr
+			})
 
 			assert.Equal(t, tc.expectedError, err, "errors did not match")
-			assert.Equal(t, tc.expectedReturn, actualReturn, "returned data does not match")
+			assert.Equal(t, tc.expectedReturn, actualReturn, "exported data does not match")
 
 			err = s.dbMock.ExpectationsWereMet()
 			assert.NoError(t, err)
@@ -88,40 +324,54 @@ func (s *testSuit) TestListUsers() {
 func (s *testSuit) TestFetchUser() {
 	t := s.T()
 
-	user := models.User{ID: 1, FirstName: "John", LastName: "Doe", Role: "Admin", UserID: 1001}
+	user := models.User{ID: 1, FirstName: "John", LastName: "Doe", Role: "Admin", UserID: 1001, Version: 1}
+	deletedAt := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	deletedUser := models.User{ID: 2, FirstName: "Jane", LastName: "Doe", Role: "Admin", UserID: 1002, Version: 2, DeletedAt: &deletedAt}
+
+	const selectUser = `SELECT "id", "first_name", "last_name", "role", "user_id", "version", "deleted_at", "created_at", "updated_at", "created_by", "updated_by" FROM "users"`
 
 	testCases := map[string]struct {
-		mockReturn     *sqlmock.Rows
-		mockReturnErr  error
-		inputID        int
-		expectedReturn models.User
-		expectedError  error
+		expectedSQL         string
+		mockReturn          *sqlmock.Rows
+		mockReturnErr       error
+		inputID             int
+		inputIncludeDeleted bool
+		expectedReturn      models.User
+		expectedError       error
 	}{
 		"Return usr by ID": {
+			expectedSQL:    selectUser + ` WHERE "id" = $1 AND "deleted_at" IS NULL LIMIT 1`,
 			mockReturn:     mustStructsToRows([]models.User{user}),
 			mockReturnErr:  nil,
 			inputID:        int(user.ID),
 			expectedReturn: user,
 			expectedError:  nil,
 		},
+		"Return deleted user by ID": {
+			expectedSQL:         selectUser + ` WHERE "id" = $1 LIMIT 1`,
+			mockReturn:          mustStructsToRows([]models.User{deletedUser}),
+			inputID:             int(deletedUser.ID),
+			inputIncludeDeleted: true,
+			expectedReturn:      deletedUser,
+		},
 		"User with given ID does not exist": {
+			expectedSQL:    selectUser + ` WHERE "id" = $1 AND "deleted_at" IS NULL LIMIT 1`,
 			mockReturn:     &sqlmock.Rows{},
 			mockReturnErr:  sql.ErrNoRows,
 			inputID:        0,
 			expectedReturn: models.User{},
-			expectedError:  fmt.Errorf("[in FetchUser]: %w", sql.ErrNoRows),
+			expectedError:  fmt.Errorf("[in FetchUser]: %w", ErrUserNotFound),
 		},
 	}
 	for name, tc := range testCases {
 		t.Run(name, func(t *testing.T) {
-			exp := `SELECT * FROM "users" WHERE id = $1 ORDER BY "users"."id" LIMIT 1`
 			s.dbMock.
-				ExpectQuery(regexp.QuoteMeta(exp)).
+				ExpectQuery(regexp.QuoteMeta(tc.expectedSQL)).
 				WithArgs(tc.inputID).
 				WillReturnRows(tc.mockReturn).
 				WillReturnError(tc.mockReturnErr)
 
-			actualReturn, err := s.service.FetchUser(context.Background(), tc.inputID)
+			actualReturn, err := s.service.FetchUser(context.Background(), tc.inputID, tc.inputIncludeDeleted)
 
 			assert.Equal(t, tc.expectedError, err, "errors did not match")
 			assert.Equal(t, tc.expectedReturn, actualReturn, "returned data does not match")
@@ -132,60 +382,301 @@ func (s *testSuit) TestFetchUser() {
 	}
 }
 
-func (s *testSuit) TestUpdateUser() {
+func (s *testSuit) TestFetchUserByUserID() {
 	t := s.T()
 
-	userIn := models.User{ID: 0, FirstName: "John", LastName: "Doe", Role: "Admin", UserID: 1001}
-	userOut := models.User{ID: 1, FirstName: "John", LastName: "Doe", Role: "Admin", UserID: 1001}
+	user := models.User{ID: 1, FirstName: "John", LastName: "Doe", Role: "Customer", UserID: 1001, Version: 1}
+
+	const selectUser = `SELECT "id", "first_name", "last_name", "role", "user_id", "version", "deleted_at", "created_at", "updated_at", "created_by", "updated_by" FROM "users"`
 
 	testCases := map[string]struct {
-		mockInputArgs  []driver.Value
-		mockReturn     driver.Result
+		mockReturn     *sqlmock.Rows
 		mockReturnErr  error
-		inputID        int
-		inputUser      models.User
+		inputUserID    uint
 		expectedReturn models.User
 		expectedError  error
+	}{
+		"Return user by user_id": {
+			mockReturn:     mustStructsToRows([]models.User{user}),
+			inputUserID:    user.UserID,
+			expectedReturn: user,
+		},
+		"User with given user_id does not exist": {
+			mockReturn:     &sqlmock.Rows{},
+			mockReturnErr:  sql.ErrNoRows,
+			inputUserID:    9999,
+			expectedReturn: models.User{},
+			expectedError:  fmt.Errorf("[in FetchUserByUserID]: %w", ErrUserNotFound),
+		},
+	}
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			s.dbMock.
+				ExpectQuery(regexp.QuoteMeta(selectUser + ` WHERE "user_id" = $1 AND "deleted_at" IS NULL LIMIT 1`)).
+				WithArgs(tc.inputUserID).
+				WillReturnRows(tc.mockReturn).
+				WillReturnError(tc.mockReturnErr)
+
+			actualReturn, err := s.service.FetchUserByUserID(context.Background(), tc.inputUserID)
+
+			assert.Equal(t, tc.expectedError, err, "errors did not match")
+			assert.Equal(t, tc.expectedReturn, actualReturn, "returned data does not match")
+
+			err = s.dbMock.ExpectationsWereMet()
+			assert.NoError(t, err)
+		})
+	}
+}
+
+func (s *testSuit) TestUpdateUser() {
+	t := s.T()
+
+	userIn := models.User{ID: 0, FirstName: "John", LastName: "Doe", Role: "Admin", UserID: 1001}
+	createdAt := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	updatedAt := time.Date(2024, 5, 2, 12, 0, 0, 0, time.UTC)
+	userOut := models.User{
+		ID: 1, FirstName: "John", LastName: "Doe", Role: "Admin", UserID: 1001, Version: 3,
+		CreatedAt: createdAt, UpdatedAt: updatedAt, CreatedBy: "system", UpdatedBy: "jdoe",
+	}
+	ctx := context.WithValue(audit.WithActor(context.Background(), "jdoe"), middleware.RequestIDKey, "req-1")
+	uniqueViolation := &pq.Error{Code: pgUniqueViolation, Message: "duplicate key value"}
+
+	const updateUser = `
+		"first_name" = $1,
+		"last_name" = $2,
+		"role" = $3,
+		"user_id" = $4,
+		"version" = "version" + 1,
+		"updated_at" = NOW(),
+		"updated_by" = $5
+	`
+	updateHistory := recordSQL(historySQL("update", 5, 6, changedEntriesSQL), eventsSQL("user.updated", "after"))
+
+	testCases := map[string]struct {
+		expectedSQL        string
+		mockInputArgs      []driver.Value
+		mockReturn         *sqlmock.Rows
+		mockReturnErr      error
+		mockExists         *bool
+		inputID            int
+		inputUser          models.User
+		inputMatchVersions []uint
+		expectedReturn     models.User
+		expectedError      error
 	}{
 		"user updated by ID": {
-			mockInputArgs:  []driver.Value{userIn.FirstName, userIn.LastName, userIn.Role, userIn.UserID, int(userOut.ID)},
-			mockReturn:     sqlmock.NewResult(1, 1),
-			mockReturnErr:  nil,
+			expectedSQL:    changeUserSQL(`"id" = $7 AND "deleted_at" IS NULL`, updateUser, updateHistory, userColumnsSQL),
+			mockInputArgs:  []driver.Value{userIn.FirstName, userIn.LastName, userIn.Role, userIn.UserID, "jdoe", "req-1", int(userOut.ID)},
+			mockReturn:     mustStructsToRows([]models.User{userOut}),
 			inputID:        int(userOut.ID),
 			inputUser:      userIn,
 			expectedReturn: userOut,
-			expectedError:  nil,
+		},
+		"user updated by ID and version": {
+			expectedSQL:        changeUserSQL(`"id" = $7 AND "deleted_at" IS NULL AND "version" IN ($8, $9)`, updateUser, updateHistory, userColumnsSQL),
+			mockInputArgs:      []driver.Value{userIn.FirstName, userIn.LastName, userIn.Role, userIn.UserID, "jdoe", "req-1", int(userOut.ID), 1, 2},
+			mockReturn:         mustStructsToRows([]models.User{userOut}),
+			inputID:            int(userOut.ID),
+			inputUser:          userIn,
+			inputMatchVersions: []uint{1, 2},
+			expectedReturn:     userOut,
 		},
 		"Error updating user": {
-			mockInputArgs:  []driver.Value{userIn.FirstName, userIn.LastName, userIn.Role, userIn.UserID, 0},
-			mockReturn:     nil,
+			expectedSQL:    changeUserSQL(`"id" = $7 AND "deleted_at" IS NULL`, updateUser, updateHistory, userColumnsSQL),
+			mockInputArgs:  []driver.Value{userIn.FirstName, userIn.LastName, userIn.Role, userIn.UserID, "jdoe", "req-1", 0},
+			mockReturn:     &sqlmock.Rows{},
 			mockReturnErr:  errors.New("test"),
 			inputID:        0,
 			inputUser:      userIn,
 			expectedReturn: models.User{},
 			expectedError:  fmt.Errorf("[in UpdateUser]: %w", errors.New("test")),
 		},
+		"User with given ID does not exist": {
+			expectedSQL:    changeUserSQL(`"id" = $7 AND "deleted_at" IS NULL`, updateUser, updateHistory, userColumnsSQL),
+			mockInputArgs:  []driver.Value{userIn.FirstName, userIn.LastName, userIn.Role, userIn.UserID, "jdoe", "req-1", 2},
+			mockReturn:     &sqlmock.Rows{},
+			mockReturnErr:  sql.ErrNoRows,
+			inputID:        2,
+			inputUser:      userIn,
+			expectedReturn: models.User{},
+			expectedError:  fmt.Errorf("[in UpdateUser]: %w", ErrUserNotFound),
+		},
+		"User is at another version": {
+			expectedSQL:        changeUserSQL(`"id" = $7 AND "deleted_at" IS NULL AND "version" IN ($8)`, updateUser, updateHistory, userColumnsSQL),
+			mockInputArgs:      []driver.Value{userIn.FirstName, userIn.LastName, userIn.Role, userIn.UserID, "jdoe", "req-1", 1, 1},
+			mockReturn:         &sqlmock.Rows{},
+			mockReturnErr:      sql.ErrNoRows,
+			mockExists:         ptr(true),
+			inputID:            1,
+			inputUser:          userIn,
+			inputMatchVersions: []uint{1},
+			expectedReturn:     models.User{},
+			expectedError:      fmt.Errorf("[in UpdateUser]: %w", ErrVersionMismatch),
+		},
+		"User with given ID and version does not exist": {
+			expectedSQL:        changeUserSQL(`"id" = $7 AND "deleted_at" IS NULL AND "version" IN ($8)`, updateUser, updateHistory, userColumnsSQL),
+			mockInputArgs:      []driver.Value{userIn.FirstName, userIn.LastName, userIn.Role, userIn.UserID, "jdoe", "req-1", 2, 1},
+			mockReturn:         &sqlmock.Rows{},
+			mockReturnErr:      sql.ErrNoRows,
+			mockExists:         ptr(false),
+			inputID:            2,
+			inputUser:          userIn,
+			inputMatchVersions: []uint{1},
+			expectedReturn:     models.User{},
+			expectedError:      fmt.Errorf("[in UpdateUser]: %w", ErrUserNotFound),
+		},
+		"user_id already in use": {
+			expectedSQL:    changeUserSQL(`"id" = $7 AND "deleted_at" IS NULL`, updateUser, updateHistory, userColumnsSQL),
+			mockInputArgs:  []driver.Value{userIn.FirstName, userIn.LastName, userIn.Role, userIn.UserID, "jdoe", "req-1", 1},
+			mockReturn:     &sqlmock.Rows{},
+			mockReturnErr:  uniqueViolation,
+			inputID:        1,
+			inputUser:      userIn,
+			expectedReturn: models.User{},
+			expectedError:  fmt.Errorf("[in UpdateUser]: %w", fmt.Errorf("%w: %w", ErrUserIDConflict, uniqueViolation)),
+		},
+	}
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			s.dbMock.
+				ExpectQuery(regexp.QuoteMeta(tc.expectedSQL)).
+				WithArgs(tc.mockInputArgs...).
+				WillReturnRows(tc.mockReturn).
+				WillReturnError(tc.mockReturnErr)
+			if tc.mockExists != nil {
+				expectUserExists(s.dbMock, tc.inputID, *tc.mockExists)
+			}
+
+			actualReturn, err := s.service.UpdateUser(ctx, tc.inputID, tc.inputUser, tc.inputMatchVersions)
+
+			assert.Equal(t, tc.expectedError, err, "errors did not match")
+			assert.Equal(t, tc.expectedReturn, actualReturn, "returned data does not match")
+
+			err = s.dbMock.ExpectationsWereMet()
+			assert.NoError(t, err)
+		})
+	}
+}
+
+func (s *testSuit) TestPatchUser() {
+	t := s.T()
+
+	createdAt := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	updatedAt := time.Date(2024, 5, 2, 12, 0, 0, 0, time.UTC)
+	userOut := models.User{
+		ID: 1, FirstName: "John", LastName: "Smith", Role: "Employee", UserID: 1001, Version: 3,
+		CreatedAt: createdAt, UpdatedAt: updatedAt, CreatedBy: "system", UpdatedBy: audit.Anonymous,
+	}
+	uniqueViolation := &pq.Error{Code: pgUniqueViolation, Message: "duplicate key value"}
+	returnColumns := []string{
+		"id", "first_name", "last_name", "role", "user_id", "version", "deleted_at",
+		"created_at", "updated_at", "created_by", "updated_by",
+	}
+
+	testCases := map[string]struct {
+		expectedSQL        string
+		mockInputArgs      []driver.Value
+		mockReturn         *sqlmock.Rows
+		mockReturnErr      error
+		mockExists         *bool
+		inputID            int
+		inputChanges       models.UserChanges
+		inputMatchVersions []uint
+		expectedReturn     models.User
+		expectedError      error
+	}{
+		"one field patched": {
+			expectedSQL: changeUserSQL(
+				`"id" = $4 AND "deleted_at" IS NULL`,
+				`"last_name" = $1, "updated_by" = $2, "version" = "version" + 1, "updated_at" = NOW()`,
+				recordSQL(historySQL("patch", 2, 3, changedEntriesSQL), eventsSQL("user.updated", "after")),
+				userColumnsSQL,
+			),
+			mockInputArgs:  []driver.Value{"Smith", audit.Anonymous, "", 1},
+			mockReturn:     sqlmock.NewRows(returnColumns).AddRow(1, "John", "Smith", "Employee", 1001, 3, nil, createdAt, updatedAt, "system", audit.Anonymous),
+			inputID:        1,
+			inputChanges:   models.UserChanges{LastName: ptr("Smith")},
+			expectedReturn: userOut,
+		},
+		"all fields patched by version": {
+			expectedSQL: changeUserSQL(
+				`"id" = $7 AND "deleted_at" IS NULL AND "version" IN ($8)`,
+				`"first_name" = $1, "last_name" = $2, "role" = $3, "user_id" = $4, "updated_by" = $5, "version" = "version" + 1, "updated_at" = NOW()`,
+				recordSQL(historySQL("patch", 5, 6, changedEntriesSQL), eventsSQL("user.updated", "after")),
+				userColumnsSQL,
+			),
+			mockInputArgs: []driver.Value{"John", "Smith", "Employee", 1001, audit.Anonymous, "", 1, 2},
+			mockReturn:    sqlmock.NewRows(returnColumns).AddRow(1, "John", "Smith", "Employee", 1001, 3, nil, createdAt, updatedAt, "system", audit.Anonymous),
+			inputID:       1,
+			inputChanges: models.UserChanges{
+				FirstName: ptr("John"),
+				LastName:  ptr("Smith"),
+				Role:      ptr("Employee"),
+				UserID:    ptr[uint](1001),
+			},
+			inputMatchVersions: []uint{2},
+			expectedReturn:     userOut,
+		},
+		"User is at another version": {
+			expectedSQL: changeUserSQL(
+				`"id" = $4 AND "deleted_at" IS NULL AND "version" IN ($5)`,
+				`"role" = $1, "updated_by" = $2, "version" = "version" + 1, "updated_at" = NOW()`,
+				recordSQL(historySQL("patch", 2, 3, changedEntriesSQL), eventsSQL("user.updated", "after")),
+				userColumnsSQL,
+			),
+			mockInputArgs:      []driver.Value{"Employee", audit.Anonymous, "", 1, 2},
+			mockReturn:         &sqlmock.Rows{},
+			mockReturnErr:      sql.ErrNoRows,
+			mockExists:         ptr(true),
+			inputID:            1,
+			inputChanges:       models.UserChanges{Role: ptr("Employee")},
+			inputMatchVersions: []uint{2},
+			expectedReturn:     models.User{},
+			expectedError:      fmt.Errorf("[in PatchUser]: %w", ErrVersionMismatch),
+		},
+		"User with given ID does not exist": {
+			expectedSQL: changeUserSQL(
+				`"id" = $4 AND "deleted_at" IS NULL`,
+				`"role" = $1, "updated_by" = $2, "version" = "version" + 1, "updated_at" = NOW()`,
+				recordSQL(historySQL("patch", 2, 3, changedEntriesSQL), eventsSQL("user.updated", "after")),
+				userColumnsSQL,
+			),
+			mockInputArgs:  []driver.Value{"Employee", audit.Anonymous, "", 2},
+			mockReturn:     &sqlmock.Rows{},
+			mockReturnErr:  sql.ErrNoRows,
+			inputID:        2,
+			inputChanges:   models.UserChanges{Role: ptr("Employee")},
+			expectedReturn: models.User{},
+			expectedError:  fmt.Errorf("[in PatchUser]: %w", ErrUserNotFound),
+		},
+		"user_id already in use": {
+			expectedSQL: changeUserSQL(
+				`"id" = $4 AND "deleted_at" IS NULL`,
+				`"user_id" = $1, "updated_by" = $2, "version" = "version" + 1, "updated_at" = NOW()`,
+				recordSQL(historySQL("patch", 2, 3, changedEntriesSQL), eventsSQL("user.updated", "after")),
+				userColumnsSQL,
+			),
+			mockInputArgs:  []driver.Value{1002, audit.Anonymous, "", 1},
+			mockReturn:     &sqlmock.Rows{},
+			mockReturnErr:  uniqueViolation,
+			inputID:        1,
+			inputChanges:   models.UserChanges{UserID: ptr[uint](1002)},
+			expectedReturn: models.User{},
+			expectedError:  fmt.Errorf("[in PatchUser]: %w", fmt.Errorf("%w: %w", ErrUserIDConflict, uniqueViolation)),
+		},
 	}
 	for name, tc := range testCases {
 		t.Run(name, func(t *testing.T) {
-			exp := `
-				UPDATE
-					"users"
-				SET
-					"first_name" = $1,
-					"last_name" = $2,
-					"role" = $3,
-					"user_id" = $4
-				WHERE
-					"id" = $5
-			`
 			s.dbMock.
-				ExpectExec(regexp.QuoteMeta(exp)).
+				ExpectQuery(regexp.QuoteMeta(tc.expectedSQL)).
 				WithArgs(tc.mockInputArgs...).
-				WillReturnResult(tc.mockReturn).
+				WillReturnRows(tc.mockReturn).
 				WillReturnError(tc.mockReturnErr)
+			if tc.mockExists != nil {
+				expectUserExists(s.dbMock, tc.inputID, *tc.mockExists)
+			}
 
-			actualReturn, err := s.service.UpdateUser(context.Background(), tc.inputID, tc.inputUser)
+			actualReturn, err := s.service.PatchUser(context.Background(), tc.inputID, tc.inputChanges, tc.inputMatchVersions)
 
 			assert.Equal(t, tc.expectedError, err, "errors did not match")
 			assert.Equal(t, tc.expectedReturn, actualReturn, "returned data does not match")
@@ -200,30 +691,46 @@ func (s *testSuit) TestCreateUser() {
 	t := s.T()
 
 	userIn := models.User{ID: 0, FirstName: "John", LastName: "Doe", Role: "Admin", UserID: 1001}
+	uniqueViolation := &pq.Error{Code: pgUniqueViolation, Message: "duplicate key value"}
+	ctx := audit.WithActor(context.Background(), "jdoe")
 
 	testCases := map[string]struct {
-		mockInputArgs  []driver.Value
-		mockReturn     *sqlmock.Rows
-		mockReturnErr  error
-		inputUser      models.User
-		expectedReturn int
-		expectedError  error
+		mockInputArgs   []driver.Value
+		mockReturn      *sqlmock.Rows
+		mockReturnErr   error
+		inputUser       models.User
+		expectedReturn  int
+		expectedChanges changeCounts
+		expectedError   error
 	}{
 		"create": {
-			mockInputArgs:  []driver.Value{userIn.FirstName, userIn.LastName, userIn.Role, userIn.UserID},
-			mockReturn:     mustStructsToRows([]struct{ ID int }{{ID: 1}}),
-			mockReturnErr:  nil,
-			inputUser:      userIn,
-			expectedReturn: 1,
-			expectedError:  nil,
+			mockInputArgs:   []driver.Value{userIn.FirstName, userIn.LastName, userIn.Role, userIn.UserID, "jdoe", ""},
+			mockReturn:      mustStructsToRows([]struct{ ID int }{{ID: 1}}),
+			mockReturnErr:   nil,
+			inputUser:       userIn,
+			expectedReturn:  1,
+			expectedChanges: changeCounts{created: 1},
+			expectedError:   nil,
+		},
+		"user_id already in use": {
+			mockInputArgs:   []driver.Value{userIn.FirstName, userIn.LastName, userIn.Role, userIn.UserID, "jdoe", ""},
+			mockReturn:      &sqlmock.Rows{},
+			mockReturnErr:   uniqueViolation,
+			inputUser:       userIn,
+			expectedReturn:  0,
+			expectedChanges: changeCounts{},
+			expectedError:   fmt.Errorf("[in CreateUser]: %w", fmt.Errorf("%w: %w", ErrUserIDConflict, uniqueViolation)),
 		},
 	}
 	for name, tc := range testCases {
 		t.Run(name, func(t *testing.T) {
 			exp := `
-				INSERT INTO "users" ("first_name", "last_name", "role", "user_id")
-					VALUES ($1, $2, $3, $4)
-				RETURNING "id"
+				WITH "after" AS (
+					INSERT INTO "users" ("first_name", "last_name", "role", "user_id", "created_by", "updated_by")
+						VALUES ($1, $2, $3, $4, $5, $5)
+					RETURNING ` + userColumnsSQL + `
+				), ` + recordSQL(historySQL("create", 5, 6, createdEntriesSQL), eventsSQL("user.created", "after")) + `
+				SELECT "id" FROM "after"
 			`
 			s.dbMock.
 				ExpectQuery(regexp.QuoteMeta(exp)).
@@ -231,10 +738,13 @@ func (s *testSuit) TestCreateUser() {
 				WillReturnRows(tc.mockReturn).
 				WillReturnError(tc.mockReturnErr)
 
-			actualReturn, err := s.service.CreateUser(context.Background(), tc.inputUser)
+			*s.changes = changeCounts{}
+
+			actualReturn, err := s.service.CreateUser(ctx, tc.inputUser)
 
 			assert.Equal(t, tc.expectedError, err, "errors did not match")
 			assert.Equal(t, tc.expectedReturn, actualReturn, "returned data does not match")
+			assert.Equal(t, tc.expectedChanges, *s.changes, "changes were not counted")
 
 			err = s.dbMock.ExpectationsWereMet()
 			assert.NoError(t, err)
@@ -245,34 +755,252 @@ func (s *testSuit) TestCreateUser() {
 func (s *testSuit) TestDeleteUser() {
 	t := s.T()
 
+	const deleteUser = `"deleted_at" = NOW(), "version" = "version" + 1, "updated_at" = NOW(), "updated_by" = $1`
+	deleteHistory := recordSQL(historySQL("delete", 1, 2, changedEntriesSQL), eventsSQL("user.deleted", "after"))
+
 	testCases := map[string]struct {
-		mockInputArgs []driver.Value
-		mockReturn    driver.Result
-		mockReturnErr error
-		inputID       int
-		expectedError error
+		expectedSQL        string
+		mockInputArgs      []driver.Value
+		mockReturn         *sqlmock.Rows
+		mockReturnErr      error
+		mockExists         *bool
+		mockDeleted        bool
+		inputID            int
+		inputMatchVersions []uint
+		expectedError      error
 	}{
 		"create": {
-			mockInputArgs: []driver.Value{1},
-			mockReturn:    sqlmock.NewResult(1, 1),
-			mockReturnErr: nil,
+			expectedSQL:   changeUserSQL(`"id" = $3 AND "deleted_at" IS NULL`, deleteUser, deleteHistory, `"id"`),
+			mockInputArgs: []driver.Value{audit.Anonymous, "", 1},
+			mockReturn:    sqlmock.NewRows([]string{"id"}).AddRow(1),
 			inputID:       1,
-			expectedError: nil,
+		},
+		"User with given ID does not exist": {
+			expectedSQL:   changeUserSQL(`"id" = $3 AND "deleted_at" IS NULL`, deleteUser, deleteHistory, `"id"`),
+			mockInputArgs: []driver.Value{audit.Anonymous, "", 2},
+			mockReturnErr: sql.ErrNoRows,
+			inputID:       2,
+			expectedError: fmt.Errorf("[in DeleteUser]: %w", ErrUserNotFound),
+		},
+		"User deleted by ID and version": {
+			expectedSQL:        changeUserSQL(`"id" = $3 AND "deleted_at" IS NULL AND "version" IN ($4)`, deleteUser, deleteHistory, `"id"`),
+			mockInputArgs:      []driver.Value{audit.Anonymous, "", 1, 4},
+			mockReturn:         sqlmock.NewRows([]string{"id"}).AddRow(1),
+			inputID:            1,
+			inputMatchVersions: []uint{4},
+		},
+		"User is at another version": {
+			expectedSQL:        changeUserSQL(`"id" = $3 AND "deleted_at" IS NULL AND "version" IN ($4)`, deleteUser, deleteHistory, `"id"`),
+			mockInputArgs:      []driver.Value{audit.Anonymous, "", 1, 4},
+			mockReturnErr:      sql.ErrNoRows,
+			mockExists:         ptr(true),
+			inputID:            1,
+			inputMatchVersions: []uint{4},
+			expectedError:      fmt.Errorf("[in DeleteUser]: %w", ErrVersionMismatch),
+		},
+		"User is already deleted": {
+			expectedSQL:        changeUserSQL(`"id" = $3 AND "deleted_at" IS NULL AND "version" IN ($4)`, deleteUser, deleteHistory, `"id"`),
+			mockInputArgs:      []driver.Value{audit.Anonymous, "", 1, 4},
+			mockReturnErr:      sql.ErrNoRows,
+			mockExists:         ptr(true),
+			mockDeleted:        true,
+			inputID:            1,
+			inputMatchVersions: []uint{4},
+			expectedError:      fmt.Errorf("[in DeleteUser]: %w", ErrUserNotFound),
 		},
 	}
 	for name, tc := range testCases {
 		t.Run(name, func(t *testing.T) {
-			exp := `
-				DELETE FROM "users"
-				WHERE "users"."id" = $1
-			`
+			mockReturn := tc.mockReturn
+			if mockReturn == nil {
+				mockReturn = &sqlmock.Rows{}
+			}
 			s.dbMock.
-				ExpectExec(regexp.QuoteMeta(exp)).
+				ExpectQuery(regexp.QuoteMeta(tc.expectedSQL)).
 				WithArgs(tc.mockInputArgs...).
-				WillReturnResult(tc.mockReturn).
+				WillReturnRows(mockReturn).
 				WillReturnError(tc.mockReturnErr)
+			if tc.mockExists != nil {
+				expectUserState(s.dbMock, tc.inputID, *tc.mockExists, tc.mockDeleted)
+			}
 
-			err := s.service.DeleteUser(context.Background(), tc.inputID)
+			err := s.service.DeleteUser(context.Background(), tc.inputID, tc.inputMatchVersions)
+
+			assert.Equal(t, tc.expectedError, err, "errors did not match")
+
+			err = s.dbMock.ExpectationsWereMet()
+			assert.NoError(t, err)
+		})
+	}
+}
+
+func (s *testSuit) TestRestoreUser() {
+	t := s.T()
+
+	user := models.User{
+		ID: 1, FirstName: "John", LastName: "Doe", Role: "Admin", UserID: 1001, Version: 3,
+		CreatedAt: time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC),
+		UpdatedAt: time.Date(2024, 5, 3, 12, 0, 0, 0, time.UTC),
+		CreatedBy: "system",
+		UpdatedBy: "jdoe",
+	}
+	ctx := audit.WithActor(context.Background(), "jdoe")
+	uniqueViolation := &pq.Error{Code: pgUniqueViolation, Message: "duplicate key value"}
+
+	const restoreUser = `"deleted_at" = NULL, "version" = "version" + 1, "updated_at" = NOW(), "updated_by" = $1`
+	restoreHistory := recordSQL(historySQL("restore", 1, 2, changedEntriesSQL), eventsSQL("user.updated", "after"))
+
+	testCases := map[string]struct {
+		expectedSQL        string
+		mockInputArgs      []driver.Value
+		mockReturn         *sqlmock.Rows
+		mockReturnErr      error
+		mockExists         *bool
+		mockDeleted        bool
+		inputID            int
+		inputMatchVersions []uint
+		expectedReturn     models.User
+		expectedError      error
+	}{
+		"User restored": {
+			expectedSQL:    changeUserSQL(`"id" = $3 AND "deleted_at" IS NOT NULL`, restoreUser, restoreHistory, userColumnsSQL),
+			mockInputArgs:  []driver.Value{"jdoe", "", 1},
+			mockReturn:     mustStructsToRows([]models.User{user}),
+			inputID:        1,
+			expectedReturn: user,
+		},
+		"User restored by ID and version": {
+			expectedSQL:        changeUserSQL(`"id" = $3 AND "deleted_at" IS NOT NULL AND "version" IN ($4)`, restoreUser, restoreHistory, userColumnsSQL),
+			mockInputArgs:      []driver.Value{"jdoe", "", 1, 2},
+			mockReturn:         mustStructsToRows([]models.User{user}),
+			inputID:            1,
+			inputMatchVersions: []uint{2},
+			expectedReturn:     user,
+		},
+		"User with given ID does not exist": {
+			expectedSQL:   changeUserSQL(`"id" = $3 AND "deleted_at" IS NOT NULL`, restoreUser, restoreHistory, userColumnsSQL),
+			mockInputArgs: []driver.Value{"jdoe", "", 2},
+			mockReturnErr: sql.ErrNoRows,
+			mockExists:    ptr(false),
+			inputID:       2,
+			expectedError: fmt.Errorf("[in RestoreUser]: %w", ErrUserNotFound),
+		},
+		"User is not deleted": {
+			expectedSQL:   changeUserSQL(`"id" = $3 AND "deleted_at" IS NOT NULL`, restoreUser, restoreHistory, userColumnsSQL),
+			mockInputArgs: []driver.Value{"jdoe", "", 1},
+			mockReturnErr: sql.ErrNoRows,
+			mockExists:    ptr(true),
+			inputID:       1,
+			expectedError: fmt.Errorf("[in RestoreUser]: %w", ErrUserNotDeleted),
+		},
+		"User is at another version": {
+			expectedSQL:        changeUserSQL(`"id" = $3 AND "deleted_at" IS NOT NULL AND "version" IN ($4)`, restoreUser, restoreHistory, userColumnsSQL),
+			mockInputArgs:      []driver.Value{"jdoe", "", 1, 2},
+			mockReturnErr:      sql.ErrNoRows,
+			mockExists:         ptr(true),
+			mockDeleted:        true,
+			inputID:            1,
+			inputMatchVersions: []uint{2},
+			expectedError:      fmt.Errorf("[in RestoreUser]: %w", ErrVersionMismatch),
+		},
+		"user_id has been reused": {
+			expectedSQL:   changeUserSQL(`"id" = $3 AND "deleted_at" IS NOT NULL`, restoreUser, restoreHistory, userColumnsSQL),
+			mockInputArgs: []driver.Value{"jdoe", "", 1},
+			mockReturnErr: uniqueViolation,
+			inputID:       1,
+			expectedError: fmt.Errorf(
+				"[in RestoreUser]: %w",
+				fmt.Errorf("%w: %w", ErrUserIDConflict, uniqueViolation),
+			),
+		},
+	}
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			mockReturn := tc.mockReturn
+			if mockReturn == nil {
+				mockReturn = &sqlmock.Rows{}
+			}
+			s.dbMock.
+				ExpectQuery(regexp.QuoteMeta(tc.expectedSQL)).
+				WithArgs(tc.mockInputArgs...).
+				WillReturnRows(mockReturn).
+				WillReturnError(tc.mockReturnErr)
+			if tc.mockExists != nil {
+				expectUserState(s.dbMock, tc.inputID, *tc.mockExists, tc.mockDeleted)
+			}
+
+			actualReturn, err := s.service.RestoreUser(ctx, tc.inputID, tc.inputMatchVersions)
+
+			assert.Equal(t, tc.expectedError, err, "errors did not match")
+			assert.Equal(t, tc.expectedReturn, actualReturn, "returned data does not match")
+
+			err = s.dbMock.ExpectationsWereMet()
+			assert.NoError(t, err)
+		})
+	}
+}
+
+func (s *testSuit) TestPurgeUser() {
+	t := s.T()
+
+	const purgeUser = `
+		WITH "before" AS (
+			DELETE FROM "users" WHERE %s
+			RETURNING ` + userColumnsSQL + `
+		), %s
+		SELECT "id" FROM "before"
+	`
+	purgeHistory := recordSQL(historySQL("purge", 1, 2, purgedEntriesSQL), eventsSQL("user.deleted", "before"))
+
+	testCases := map[string]struct {
+		expectedSQL        string
+		mockInputArgs      []driver.Value
+		mockReturn         *sqlmock.Rows
+		mockReturnErr      error
+		mockExists         *bool
+		inputID            int
+		inputMatchVersions []uint
+		expectedError      error
+	}{
+		"User purged": {
+			expectedSQL:   fmt.Sprintf(purgeUser, `"id" = $3`, purgeHistory),
+			mockInputArgs: []driver.Value{audit.Anonymous, "", 1},
+			mockReturn:    sqlmock.NewRows([]string{"id"}).AddRow(1),
+			inputID:       1,
+		},
+		"User with given ID does not exist": {
+			expectedSQL:   fmt.Sprintf(purgeUser, `"id" = $3`, purgeHistory),
+			mockInputArgs: []driver.Value{audit.Anonymous, "", 2},
+			mockReturnErr: sql.ErrNoRows,
+			inputID:       2,
+			expectedError: fmt.Errorf("[in PurgeUser]: %w", ErrUserNotFound),
+		},
+		"User is at another version": {
+			expectedSQL:        fmt.Sprintf(purgeUser, `"id" = $3 AND "version" IN ($4)`, purgeHistory),
+			mockInputArgs:      []driver.Value{audit.Anonymous, "", 1, 4},
+			mockReturnErr:      sql.ErrNoRows,
+			mockExists:         ptr(true),
+			inputID:            1,
+			inputMatchVersions: []uint{4},
+			expectedError:      fmt.Errorf("[in PurgeUser]: %w", ErrVersionMismatch),
+		},
+	}
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			mockReturn := tc.mockReturn
+			if mockReturn == nil {
+				mockReturn = &sqlmock.Rows{}
+			}
+			s.dbMock.
+				ExpectQuery(regexp.QuoteMeta(tc.expectedSQL)).
+				WithArgs(tc.mockInputArgs...).
+				WillReturnRows(mockReturn).
+				WillReturnError(tc.mockReturnErr)
+			if tc.mockExists != nil {
+				expectUserState(s.dbMock, tc.inputID, *tc.mockExists, true)
+			}
+
+			err := s.service.PurgeUser(context.Background(), tc.inputID, tc.inputMatchVersions)
 
 			assert.Equal(t, tc.expectedError, err, "errors did not match")
 
@@ -284,6 +1012,73 @@ func (s *testSuit) TestDeleteUser() {
 
 // ━━ HELPERS ━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━
 
+// userColumnsSQL are the columns of the users table selected and returned by the statements of the
+// service.
+const userColumnsSQL = `"id", "first_name", "last_name", "role", "user_id", "version", "deleted_at", ` +
+	`"created_at", "updated_at", "created_by", "updated_by"`
+
+// The entries recorded in user_history for created, changed and purged users.
+const (
+	createdEntriesSQL = `"a"."id", "a"."version", NULL::jsonb, to_jsonb("a") FROM "after" AS "a"`
+	changedEntriesSQL = `"a"."id", "a"."version", to_jsonb("b"), to_jsonb("a") FROM "after" AS "a" JOIN "before" AS "b" ON "b"."id" = "a"."id"`
+	purgedEntriesSQL  = `"b"."id", "b"."version" + 1, to_jsonb("b"), NULL::jsonb FROM "before" AS "b"`
+)
+
+// historySQL returns the insert expected to record entries in user_history as operation, with the
+// actor and request ID in the placeholders actor and requestID.
+func historySQL(operation string, actor, requestID int, entries string) string {
+	return fmt.Sprintf(
+		`INSERT INTO "user_history" ("operation", "actor", "request_id", "id", "version", "before", "after") `+
+			`SELECT '%s', $%d::text, $%d::text, %s`,
+		operation,
+		actor,
+		requestID,
+		entries,
+	)
+}
+
+// eventsSQL returns the insert expected to queue an event of eventType in user_outbox for every user
+// in users.
+func eventsSQL(eventType, users string) string {
+	return fmt.Sprintf(
+		`INSERT INTO "user_outbox" ("type", "subject", "data") SELECT '%s', "u"."id"::text, to_jsonb("u") FROM "%s" AS "u"`,
+		eventType,
+		users,
+	)
+}
+
+// recordSQL returns the common table expressions expected to record a change with history and
+// events.
+func recordSQL(history, events string) string {
+	return `"history" AS (` + history + `), "outbox" AS (` + events + `)`
+}
+
+// changeUserSQL returns the statement expected to lock the users matching where, change them with
+// set and record the change with record, returning the columns in returning.
+func changeUserSQL(where, set, record, returning string) string {
+	return `
+		WITH "before" AS (
+			SELECT ` + userColumnsSQL + ` FROM "users" WHERE ` + where + ` FOR UPDATE
+		), "after" AS (
+			UPDATE "users" SET ` + set + ` WHERE "id" IN (SELECT "id" FROM "before")
+			RETURNING ` + userColumnsSQL + `
+		), ` + record + `
+		SELECT ` + returning + ` FROM "after"
+	`
+}
+
+// assertCursor asserts that token decodes to expected, or that token is empty if expected is nil.
+func (s *testSuit) assertCursor(t *testing.T, expected *cursor.Key, token string, msg string) {
+	if expected == nil {
+		assert.Empty(t, token, msg)
+		return
+	}
+
+	actual, err := s.service.cursors.Decode(token)
+	assert.NoError(t, err, msg)
+	assert.Equal(t, *expected, actual, msg)
+}
+
 // structSliceToSQLMockRows converts a slice of structs to sqlmock.Rows using reflect.
 // It can also be used when only a single struct is needed by wrapping in a slice.
 func mustStructsToRows[T any](slice []T) *sqlmock.Rows {
@@ -367,3 +1162,38 @@ func toSnake(camel string) (snake string) {
 	}
 	return b.String()
 }
+
+// expectUserExists adds the expectation for checking whether the user with ID exists and has not
+// been deleted.
+func expectUserExists(mock sqlmock.Sqlmock, ID int, exists bool) {
+	expectUserState(mock, ID, exists, false)
+}
+
+// expectUserState adds the expectation for checking whether the user with ID exists and whether it
+// has been deleted.
+func expectUserState(mock sqlmock.Sqlmock, ID int, exists bool, deleted bool) {
+	rows := sqlmock.NewRows([]string{"deleted"})
+	if exists {
+		rows.AddRow(deleted)
+	}
+	mock.
+		ExpectQuery(regexp.QuoteMeta(`SELECT "deleted_at" IS NOT NULL FROM "users" WHERE "id" = $1`)).
+		WithArgs(ID).
+		WillReturnRows(rows)
+}
+
+// ptr returns a pointer to v.
+// changeCounts is a changeCounter that keeps the number of users changed.
+type changeCounts struct {
+	created int
+	updated int
+	deleted int
+}
+
+func (c *changeCounts) UsersCreated(count int) { c.created += count }
+func (c *changeCounts) UsersUpdated(count int) { c.updated += count }
+func (c *changeCounts) UsersDeleted(count int) { c.deleted += count }
+
+func ptr[T any](v T) *T {
+	return &v
+}
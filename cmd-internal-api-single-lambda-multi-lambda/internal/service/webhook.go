@@ -0,0 +1,294 @@
+package service
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+
+	"github.com/jha-captech/user-microservice/internal/models"
+	"github.com/lib/pq"
+)
+
+var (
+	// ErrSubscriptionNotFound is returned when no webhook subscription exists with the requested ID.
+	ErrSubscriptionNotFound = errors.New("webhook subscription not found")
+
+	// ErrDeliveryNotFound is returned when a webhook subscription has no delivery with the requested
+	// ID.
+	ErrDeliveryNotFound = errors.New("webhook delivery not found")
+)
+
+// subscriptionColumns are the columns of the webhook_subscriptions table read into a
+// models.WebhookSubscription, in the order scanned by scanSubscription.
+const subscriptionColumns = `"id", "url", "events", "secret", "created_at", "updated_at"`
+
+// deliveryColumns are the columns of the webhook_deliveries table read into a
+// models.WebhookDelivery, in the order scanned by scanDelivery.
+const deliveryColumns = `"id", "subscription_id", "event_id", "event_type", "redelivery_of", "status", ` +
+	`"attempts", "response_status", "last_error", "created_at", "next_attempt_at", "delivered_at"`
+
+// scanSubscription reads the subscriptionColumns of row into a models.WebhookSubscription.
+func scanSubscription(row rowScanner) (models.WebhookSubscription, error) {
+	var (
+		subscription models.WebhookSubscription
+		events       pq.StringArray
+	)
+	err := row.Scan(
+		&subscription.ID, &subscription.URL, &events, &subscription.Secret,
+		&subscription.CreatedAt, &subscription.UpdatedAt,
+	)
+	if err != nil {
+		return models.WebhookSubscription{}, err
+	}
+
+	subscription.Events = make([]models.EventType, len(events))
+	for i, event := range events {
+		subscription.Events[i] = models.EventType(event)
+	}
+
+	return subscription, nil
+}
+
+// scanDelivery reads the deliveryColumns of row into a models.WebhookDelivery.
+func scanDelivery(row rowScanner) (models.WebhookDelivery, error) {
+	var delivery models.WebhookDelivery
+	err := row.Scan(
+		&delivery.ID, &delivery.SubscriptionID, &delivery.EventID, &delivery.EventType, &delivery.RedeliveryOf,
+		&delivery.Status, &delivery.Attempts, &delivery.ResponseStatus, &delivery.LastError,
+		&delivery.CreatedAt, &delivery.NextAttemptAt, &delivery.DeliveredAt,
+	)
+	return delivery, err
+}
+
+// eventNames returns events as a Postgres text array. The array is never NULL, so that a
+// subscription without events is stored with an empty filter.
+func eventNames(events []models.EventType) pq.StringArray {
+	names := make(pq.StringArray, len(events))
+	for i, event := range events {
+		names[i] = string(event)
+	}
+	return names
+}
+
+// Webhook manages the webhook subscriptions that user events are delivered to, and the log of the
+// deliveries made to them. The deliveries themselves are made by a webhook.Worker.
+type Webhook struct {
+	database *sql.DB
+}
+
+// NewWebhook returns a new Webhook struct.
+func NewWebhook(db *sql.DB) *Webhook {
+	return &Webhook{
+		database: db,
+	}
+}
+
+// ListSubscriptions returns all webhook subscriptions, oldest first.
+func (s Webhook) ListSubscriptions(ctx context.Context) ([]models.WebhookSubscription, error) {
+	rows, err := s.database.QueryContext(
+		ctx,
+		`SELECT `+subscriptionColumns+` FROM "webhook_subscriptions" ORDER BY "id"`,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("[in ListSubscriptions]: %w", err)
+	}
+	defer rows.Close()
+
+	subscriptions := []models.WebhookSubscription{}
+	for rows.Next() {
+		subscription, err := scanSubscription(rows)
+		if err != nil {
+			return nil, fmt.Errorf("[in ListSubscriptions]: %w", err)
+		}
+		subscriptions = append(subscriptions, subscription)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("[in ListSubscriptions]: %w", err)
+	}
+
+	return subscriptions, nil
+}
+
+// FetchSubscription returns the webhook subscription with ID.
+func (s Webhook) FetchSubscription(ctx context.Context, ID int) (models.WebhookSubscription, error) {
+	subscription, err := scanSubscription(s.database.QueryRowContext(
+		ctx,
+		`SELECT `+subscriptionColumns+` FROM "webhook_subscriptions" WHERE "id" = $1`,
+		ID,
+	))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			err = ErrSubscriptionNotFound
+		}
+		return models.WebhookSubscription{}, fmt.Errorf("[in FetchSubscription]: %w", err)
+	}
+
+	return subscription, nil
+}
+
+// CreateSubscription creates a webhook subscription and returns its ID. Only events written to the
+// outbox after the subscription is created are delivered to it.
+func (s Webhook) CreateSubscription(ctx context.Context, subscription models.WebhookSubscription) (int, error) {
+	var ID int
+	err := s.database.QueryRowContext(
+		ctx,
+		`INSERT INTO "webhook_subscriptions" ("url", "events", "secret") VALUES ($1, $2, $3) RETURNING "id"`,
+		subscription.URL,
+		eventNames(subscription.Events),
+		subscription.Secret,
+	).Scan(&ID)
+	if err != nil {
+		return 0, fmt.Errorf("[in CreateSubscription]: %w", err)
+	}
+
+	return ID, nil
+}
+
+// UpdateSubscription replaces the URL, event filter and secret of the webhook subscription with ID.
+// Pending deliveries are made to the new URL and signed with the new secret.
+func (s Webhook) UpdateSubscription(
+	ctx context.Context,
+	ID int,
+	subscription models.WebhookSubscription,
+) (models.WebhookSubscription, error) {
+	updated, err := scanSubscription(s.database.QueryRowContext(
+		ctx,
+		`
+		UPDATE
+			"webhook_subscriptions"
+		SET
+			"url" = $2,
+			"events" = $3,
+			"secret" = $4,
+			"updated_at" = NOW()
+		WHERE
+			"id" = $1
+		RETURNING
+			`+subscriptionColumns,
+		ID,
+		subscription.URL,
+		eventNames(subscription.Events),
+		subscription.Secret,
+	))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			err = ErrSubscriptionNotFound
+		}
+		return models.WebhookSubscription{}, fmt.Errorf("[in UpdateSubscription]: %w", err)
+	}
+
+	return updated, nil
+}
+
+// DeleteSubscription deletes the webhook subscription with ID along with its deliveries, including
+// those still pending.
+func (s Webhook) DeleteSubscription(ctx context.Context, ID int) error {
+	result, err := s.database.ExecContext(ctx, `DELETE FROM "webhook_subscriptions" WHERE "id" = $1`, ID)
+	if err != nil {
+		return fmt.Errorf("[in DeleteSubscription]: %w", err)
+	}
+
+	count, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("[in DeleteSubscription]: %w", err)
+	}
+	if count == 0 {
+		return fmt.Errorf("[in DeleteSubscription]: %w", ErrSubscriptionNotFound)
+	}
+
+	return nil
+}
+
+// ListDeliveries returns a page of the deliveries of the webhook subscription with ID, newest first.
+func (s Webhook) ListDeliveries(
+	ctx context.Context,
+	ID int,
+	params models.ListDeliveriesParams,
+) (models.WebhookDeliveryPage, error) {
+	var total int
+	err := s.database.
+		QueryRowContext(ctx, `SELECT COUNT(*) FROM "webhook_deliveries" WHERE "subscription_id" = $1`, ID).
+		Scan(&total)
+	if err != nil {
+		return models.WebhookDeliveryPage{}, fmt.Errorf("[in ListDeliveries]: %w", err)
+	}
+	if total == 0 {
+		var exists bool
+		err = s.database.
+			QueryRowContext(ctx, `SELECT EXISTS (SELECT 1 FROM "webhook_subscriptions" WHERE "id" = $1)`, ID).
+			Scan(&exists)
+		if err != nil {
+			return models.WebhookDeliveryPage{}, fmt.Errorf("[in ListDeliveries]: %w", err)
+		}
+		if !exists {
+			return models.WebhookDeliveryPage{}, fmt.Errorf("[in ListDeliveries]: %w", ErrSubscriptionNotFound)
+		}
+		return models.WebhookDeliveryPage{Deliveries: []models.WebhookDelivery{}}, nil
+	}
+
+	rows, err := s.database.QueryContext(
+		ctx,
+		`
+		SELECT
+			`+deliveryColumns+`
+		FROM
+			"webhook_deliveries"
+		WHERE
+			"subscription_id" = $1
+		ORDER BY
+			"id" DESC
+		LIMIT $2 OFFSET $3
+		`,
+		ID,
+		params.Limit,
+		params.Offset,
+	)
+	if err != nil {
+		return models.WebhookDeliveryPage{}, fmt.Errorf("[in ListDeliveries]: %w", err)
+	}
+	defer rows.Close()
+
+	deliveries := []models.WebhookDelivery{}
+	for rows.Next() {
+		delivery, err := scanDelivery(rows)
+		if err != nil {
+			return models.WebhookDeliveryPage{}, fmt.Errorf("[in ListDeliveries]: %w", err)
+		}
+		deliveries = append(deliveries, delivery)
+	}
+	if err = rows.Err(); err != nil {
+		return models.WebhookDeliveryPage{}, fmt.Errorf("[in ListDeliveries]: %w", err)
+	}
+
+	return models.WebhookDeliveryPage{Deliveries: deliveries, Total: total}, nil
+}
+
+// RedeliverDelivery queues the payload of the delivery with deliveryID of the webhook subscription
+// with ID to be delivered again, whatever the status of the delivery. The redelivery is a new
+// delivery, so the log of the original delivery is kept.
+func (s Webhook) RedeliverDelivery(ctx context.Context, ID int, deliveryID int) (models.WebhookDelivery, error) {
+	delivery, err := scanDelivery(s.database.QueryRowContext(
+		ctx,
+		`
+		INSERT INTO "webhook_deliveries" ("subscription_id", "event_id", "event_type", "payload", "redelivery_of")
+		SELECT
+			"subscription_id", "event_id", "event_type", "payload", "id"
+		FROM
+			"webhook_deliveries"
+		WHERE
+			"id" = $2 AND "subscription_id" = $1
+		RETURNING
+			`+deliveryColumns,
+		ID,
+		deliveryID,
+	))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			err = ErrDeliveryNotFound
+		}
+		return models.WebhookDelivery{}, fmt.Errorf("[in RedeliverDelivery]: %w", err)
+	}
+
+	return delivery, nil
+}
@@ -0,0 +1,390 @@
+package service
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/jha-captech/user-microservice/internal/models"
+	"github.com/lib/pq"
+	"github.com/stretchr/testify/assert"
+)
+
+var (
+	subscriptionColumnNames = []string{"id", "url", "events", "secret", "created_at", "updated_at"}
+	deliveryColumnNames     = []string{
+		"id", "subscription_id", "event_id", "event_type", "redelivery_of", "status", "attempts", "response_status",
+		"last_error", "created_at", "next_attempt_at", "delivered_at",
+	}
+)
+
+func (s *testSuit) TestListSubscriptions() {
+	t := s.T()
+
+	const selectSubscriptions = `SELECT "id", "url", "events", "secret", "created_at", "updated_at" FROM "webhook_subscriptions" ORDER BY "id"`
+
+	createdAt := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+
+	testCases := map[string]struct {
+		mockReturn     *sqlmock.Rows
+		mockReturnErr  error
+		expectedReturn []models.WebhookSubscription
+		expectedError  error
+	}{
+		"Return subscriptions": {
+			mockReturn: sqlmock.NewRows(subscriptionColumnNames).
+				AddRow(1, "https://example.com/hook", []byte("{}"), "0123456789abcdef", createdAt, createdAt).
+				AddRow(2, "https://partner.example.com/users", []byte("{user.created,user.deleted}"), "fedcba9876543210", createdAt, createdAt),
+			expectedReturn: []models.WebhookSubscription{
+				{
+					ID: 1, URL: "https://example.com/hook", Events: []models.EventType{}, Secret: "0123456789abcdef",
+					CreatedAt: createdAt, UpdatedAt: createdAt,
+				},
+				{
+					ID: 2, URL: "https://partner.example.com/users", Secret: "fedcba9876543210",
+					Events:    []models.EventType{models.EventUserCreated, models.EventUserDeleted},
+					CreatedAt: createdAt, UpdatedAt: createdAt,
+				},
+			},
+		},
+		"No subscriptions": {
+			mockReturn:     sqlmock.NewRows(subscriptionColumnNames),
+			expectedReturn: []models.WebhookSubscription{},
+		},
+		"Error getting subscriptions": {
+			mockReturnErr: errors.New("test"),
+			expectedError: fmt.Errorf("[in ListSubscriptions]: %w", errors.New("test")),
+		},
+	}
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			mockReturn := tc.mockReturn
+			if mockReturn == nil {
+				mockReturn = &sqlmock.Rows{}
+			}
+			s.dbMock.
+				ExpectQuery(regexp.QuoteMeta(selectSubscriptions)).
+				WillReturnRows(mockReturn).
+				WillReturnError(tc.mockReturnErr)
+
+			actualReturn, err := s.webhooks.ListSubscriptions(context.Background())
+
+			assert.Equal(t, tc.expectedError, err, "errors did not match")
+			assert.Equal(t, tc.expectedReturn, actualReturn, "returned data does not match")
+
+			err = s.dbMock.ExpectationsWereMet()
+			assert.NoError(t, err)
+		})
+	}
+}
+
+func (s *testSuit) TestCreateSubscription() {
+	t := s.T()
+
+	const insertSubscription = `INSERT INTO "webhook_subscriptions" ("url", "events", "secret") VALUES ($1, $2, $3) RETURNING "id"`
+
+	testCases := map[string]struct {
+		input          models.WebhookSubscription
+		expectedEvents pq.StringArray
+		mockReturn     *sqlmock.Rows
+		mockReturnErr  error
+		expectedReturn int
+		expectedError  error
+	}{
+		"Create subscription with event filter": {
+			input: models.WebhookSubscription{
+				URL: "https://example.com/hook", Events: []models.EventType{models.EventUserDeleted}, Secret: "0123456789abcdef",
+			},
+			expectedEvents: pq.StringArray{"user.deleted"},
+			mockReturn:     sqlmock.NewRows([]string{"id"}).AddRow(1),
+			expectedReturn: 1,
+		},
+		"Create subscription without event filter": {
+			input:          models.WebhookSubscription{URL: "https://example.com/hook", Secret: "0123456789abcdef"},
+			expectedEvents: pq.StringArray{},
+			mockReturn:     sqlmock.NewRows([]string{"id"}).AddRow(2),
+			expectedReturn: 2,
+		},
+		"Error creating subscription": {
+			input:          models.WebhookSubscription{URL: "https://example.com/hook", Secret: "0123456789abcdef"},
+			expectedEvents: pq.StringArray{},
+			mockReturnErr:  errors.New("test"),
+			expectedError:  fmt.Errorf("[in CreateSubscription]: %w", errors.New("test")),
+		},
+	}
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			mockReturn := tc.mockReturn
+			if mockReturn == nil {
+				mockReturn = &sqlmock.Rows{}
+			}
+			s.dbMock.
+				ExpectQuery(regexp.QuoteMeta(insertSubscription)).
+				WithArgs(tc.input.URL, tc.expectedEvents, tc.input.Secret).
+				WillReturnRows(mockReturn).
+				WillReturnError(tc.mockReturnErr)
+
+			actualReturn, err := s.webhooks.CreateSubscription(context.Background(), tc.input)
+
+			assert.Equal(t, tc.expectedError, err, "errors did not match")
+			assert.Equal(t, tc.expectedReturn, actualReturn, "returned data does not match")
+
+			err = s.dbMock.ExpectationsWereMet()
+			assert.NoError(t, err)
+		})
+	}
+}
+
+func (s *testSuit) TestUpdateSubscription() {
+	t := s.T()
+
+	const updateSubscription = `UPDATE "webhook_subscriptions" SET "url" = $2, "events" = $3, "secret" = $4, "updated_at" = NOW() ` +
+		`WHERE "id" = $1 RETURNING "id", "url", "events", "secret", "created_at", "updated_at"`
+
+	createdAt := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	updatedAt := time.Date(2024, 5, 2, 12, 0, 0, 0, time.UTC)
+	input := models.WebhookSubscription{
+		URL: "https://example.com/v2/hook", Events: []models.EventType{models.EventUserUpdated}, Secret: "fedcba9876543210",
+	}
+
+	testCases := map[string]struct {
+		inputID        int
+		mockReturn     *sqlmock.Rows
+		mockReturnErr  error
+		expectedReturn models.WebhookSubscription
+		expectedError  error
+	}{
+		"Update subscription": {
+			inputID: 1,
+			mockReturn: sqlmock.NewRows(subscriptionColumnNames).
+				AddRow(1, "https://example.com/v2/hook", []byte("{user.updated}"), "fedcba9876543210", createdAt, updatedAt),
+			expectedReturn: models.WebhookSubscription{
+				ID: 1, URL: "https://example.com/v2/hook", Events: []models.EventType{models.EventUserUpdated},
+				Secret: "fedcba9876543210", CreatedAt: createdAt, UpdatedAt: updatedAt,
+			},
+		},
+		"Subscription with given ID does not exist": {
+			inputID:       2,
+			mockReturnErr: sql.ErrNoRows,
+			expectedError: fmt.Errorf("[in UpdateSubscription]: %w", ErrSubscriptionNotFound),
+		},
+	}
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			mockReturn := tc.mockReturn
+			if mockReturn == nil {
+				mockReturn = &sqlmock.Rows{}
+			}
+			s.dbMock.
+				ExpectQuery(regexp.QuoteMeta(updateSubscription)).
+				WithArgs(tc.inputID, input.URL, pq.StringArray{"user.updated"}, input.Secret).
+				WillReturnRows(mockReturn).
+				WillReturnError(tc.mockReturnErr)
+
+			actualReturn, err := s.webhooks.UpdateSubscription(context.Background(), tc.inputID, input)
+
+			assert.Equal(t, tc.expectedError, err, "errors did not match")
+			assert.Equal(t, tc.expectedReturn, actualReturn, "returned data does not match")
+
+			err = s.dbMock.ExpectationsWereMet()
+			assert.NoError(t, err)
+		})
+	}
+}
+
+func (s *testSuit) TestDeleteSubscription() {
+	t := s.T()
+
+	const deleteSubscription = `DELETE FROM "webhook_subscriptions" WHERE "id" = $1`
+
+	testCases := map[string]struct {
+		inputID       int
+		mockResult    sql.Result
+		mockErr       error
+		expectedError error
+	}{
+		"Delete subscription": {
+			inputID:    1,
+			mockResult: sqlmock.NewResult(0, 1),
+		},
+		"Subscription with given ID does not exist": {
+			inputID:       2,
+			mockResult:    sqlmock.NewResult(0, 0),
+			expectedError: fmt.Errorf("[in DeleteSubscription]: %w", ErrSubscriptionNotFound),
+		},
+		"Error deleting subscription": {
+			inputID:       1,
+			mockErr:       errors.New("test"),
+			expectedError: fmt.Errorf("[in DeleteSubscription]: %w", errors.New("test")),
+		},
+	}
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			s.dbMock.
+				ExpectExec(regexp.QuoteMeta(deleteSubscription)).
+				WithArgs(tc.inputID).
+				WillReturnResult(tc.mockResult).
+				WillReturnError(tc.mockErr)
+
+			err := s.webhooks.DeleteSubscription(context.Background(), tc.inputID)
+
+			assert.Equal(t, tc.expectedError, err, "errors did not match")
+
+			err = s.dbMock.ExpectationsWereMet()
+			assert.NoError(t, err)
+		})
+	}
+}
+
+func (s *testSuit) TestListDeliveries() {
+	t := s.T()
+
+	const (
+		countDeliveries    = `SELECT COUNT(*) FROM "webhook_deliveries" WHERE "subscription_id" = $1`
+		subscriptionExists = `SELECT EXISTS (SELECT 1 FROM "webhook_subscriptions" WHERE "id" = $1)`
+		selectDeliveries   = `SELECT "id", "subscription_id", "event_id", "event_type", "redelivery_of", "status", "attempts", ` +
+			`"response_status", "last_error", "created_at", "next_attempt_at", "delivered_at" FROM "webhook_deliveries" ` +
+			`WHERE "subscription_id" = $1 ORDER BY "id" DESC LIMIT $2 OFFSET $3`
+	)
+
+	createdAt := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	deliveredAt := time.Date(2024, 5, 1, 12, 0, 1, 0, time.UTC)
+	ok := 200
+	original := uint(1)
+
+	testCases := map[string]struct {
+		setup          func(mock sqlmock.Sqlmock)
+		inputID        int
+		inputParams    models.ListDeliveriesParams
+		expectedReturn models.WebhookDeliveryPage
+		expectedError  error
+	}{
+		"Return page of deliveries": {
+			setup: func(mock sqlmock.Sqlmock) {
+				mock.ExpectQuery(regexp.QuoteMeta(countDeliveries)).
+					WithArgs(1).
+					WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(2))
+				mock.ExpectQuery(regexp.QuoteMeta(selectDeliveries)).
+					WithArgs(1, 10, 0).
+					WillReturnRows(sqlmock.NewRows(deliveryColumnNames).
+						AddRow(2, 1, "7", "user.created", 1, "pending", 0, nil, "", createdAt, createdAt, nil).
+						AddRow(1, 1, "7", "user.created", nil, "delivered", 1, 200, "", createdAt, createdAt, deliveredAt))
+			},
+			inputID:     1,
+			inputParams: models.ListDeliveriesParams{Limit: 10},
+			expectedReturn: models.WebhookDeliveryPage{
+				Deliveries: []models.WebhookDelivery{
+					{
+						ID: 2, SubscriptionID: 1, EventID: "7", EventType: models.EventUserCreated, RedeliveryOf: &original,
+						Status: "pending", CreatedAt: createdAt, NextAttemptAt: createdAt,
+					},
+					{
+						ID: 1, SubscriptionID: 1, EventID: "7", EventType: models.EventUserCreated, Status: "delivered",
+						Attempts: 1, ResponseStatus: &ok, CreatedAt: createdAt, NextAttemptAt: createdAt, DeliveredAt: &deliveredAt,
+					},
+				},
+				Total: 2,
+			},
+		},
+		"Subscription without deliveries": {
+			setup: func(mock sqlmock.Sqlmock) {
+				mock.ExpectQuery(regexp.QuoteMeta(countDeliveries)).
+					WithArgs(1).
+					WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(0))
+				mock.ExpectQuery(regexp.QuoteMeta(subscriptionExists)).
+					WithArgs(1).
+					WillReturnRows(sqlmock.NewRows([]string{"exists"}).AddRow(true))
+			},
+			inputID:        1,
+			inputParams:    models.ListDeliveriesParams{Limit: 10},
+			expectedReturn: models.WebhookDeliveryPage{Deliveries: []models.WebhookDelivery{}},
+		},
+		"Subscription with given ID does not exist": {
+			setup: func(mock sqlmock.Sqlmock) {
+				mock.ExpectQuery(regexp.QuoteMeta(countDeliveries)).
+					WithArgs(2).
+					WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(0))
+				mock.ExpectQuery(regexp.QuoteMeta(subscriptionExists)).
+					WithArgs(2).
+					WillReturnRows(sqlmock.NewRows([]string{"exists"}).AddRow(false))
+			},
+			inputID:       2,
+			inputParams:   models.ListDeliveriesParams{Limit: 10},
+			expectedError: fmt.Errorf("[in ListDeliveries]: %w", ErrSubscriptionNotFound),
+		},
+	}
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			tc.setup(s.dbMock)
+
+			actualReturn, err := s.webhooks.ListDeliveries(context.Background(), tc.inputID, tc.inputParams)
+
+			assert.Equal(t, tc.expectedError, err, "errors did not match")
+			assert.Equal(t, tc.expectedReturn, actualReturn, "returned data does not match")
+
+			err = s.dbMock.ExpectationsWereMet()
+			assert.NoError(t, err)
+		})
+	}
+}
+
+func (s *testSuit) TestRedeliverDelivery() {
+	t := s.T()
+
+	const insertRedelivery = `INSERT INTO "webhook_deliveries" ("subscription_id", "event_id", "event_type", "payload", "redelivery_of") ` +
+		`SELECT "subscription_id", "event_id", "event_type", "payload", "id" FROM "webhook_deliveries" ` +
+		`WHERE "id" = $2 AND "subscription_id" = $1 RETURNING "id", "subscription_id", "event_id", "event_type", ` +
+		`"redelivery_of", "status", "attempts", "response_status", "last_error", "created_at", "next_attempt_at", "delivered_at"`
+
+	createdAt := time.Date(2024, 5, 2, 12, 0, 0, 0, time.UTC)
+	original := uint(4)
+
+	testCases := map[string]struct {
+		inputDeliveryID int
+		mockReturn      *sqlmock.Rows
+		mockReturnErr   error
+		expectedReturn  models.WebhookDelivery
+		expectedError   error
+	}{
+		"Queue redelivery": {
+			inputDeliveryID: 4,
+			mockReturn: sqlmock.NewRows(deliveryColumnNames).
+				AddRow(9, 1, "7", "user.created", 4, "pending", 0, nil, "", createdAt, createdAt, nil),
+			expectedReturn: models.WebhookDelivery{
+				ID: 9, SubscriptionID: 1, EventID: "7", EventType: models.EventUserCreated, RedeliveryOf: &original,
+				Status: "pending", CreatedAt: createdAt, NextAttemptAt: createdAt,
+			},
+		},
+		"Delivery does not exist": {
+			inputDeliveryID: 5,
+			mockReturnErr:   sql.ErrNoRows,
+			expectedError:   fmt.Errorf("[in RedeliverDelivery]: %w", ErrDeliveryNotFound),
+		},
+	}
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			mockReturn := tc.mockReturn
+			if mockReturn == nil {
+				mockReturn = &sqlmock.Rows{}
+			}
+			s.dbMock.
+				ExpectQuery(regexp.QuoteMeta(insertRedelivery)).
+				WithArgs(1, tc.inputDeliveryID).
+				WillReturnRows(mockReturn).
+				WillReturnError(tc.mockReturnErr)
+
+			actualReturn, err := s.webhooks.RedeliverDelivery(context.Background(), 1, tc.inputDeliveryID)
+
+			assert.Equal(t, tc.expectedError, err, "errors did not match")
+			assert.Equal(t, tc.expectedReturn, actualReturn, "returned data does not match")
+
+			err = s.dbMock.ExpectationsWereMet()
+			assert.NoError(t, err)
+		})
+	}
+}
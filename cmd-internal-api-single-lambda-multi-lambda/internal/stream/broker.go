@@ -0,0 +1,172 @@
+package stream
+
+import (
+	"encoding/json"
+	"sync"
+)
+
+// subscriberBuffer is the number of events queued for a subscriber before it is dropped as too slow.
+const subscriberBuffer = 64
+
+// Event is a change to a user, as notified by the trigger on the users table. ID is taken from a
+// database sequence, so it increases with every change, is the same on every instance of the service
+// and is used as the SSE event ID. OldRole is the role of the user before an update, so that clients
+// watching a role also see users leave it. Data is the user after the change, or before it if the
+// user was purged.
+type Event struct {
+	ID      int64           `json:"seq"`
+	Type    string          `json:"type"`
+	UserID  int             `json:"id"`
+	Role    string          `json:"role"`
+	OldRole string          `json:"old_role"`
+	Data    json.RawMessage `json:"user"`
+}
+
+// Filter limits the events sent to a subscriber. Zero values for its fields mean that the filter is
+// not applied.
+type Filter struct {
+	Role   string
+	UserID int
+}
+
+// Match reports whether event passes filter. An event matches a role if the user has that role
+// either before or after the change.
+func (filter Filter) Match(event Event) bool {
+	if filter.UserID != 0 && event.UserID != filter.UserID {
+		return false
+	}
+	if filter.Role != "" && event.Role != filter.Role && event.OldRole != filter.Role {
+		return false
+	}
+	return true
+}
+
+// Subscription is a subscription to the events of a Broker. Replay holds the buffered events after
+// the event the subscriber resumed from, and Events receives the events published after it
+// subscribed. Events is closed when the subscriber falls too far behind or the broker is closed, after
+// which the subscriber can resume from the last event it received. Gap is set if the subscriber
+// resumed from an event that is no longer buffered, so that events may have been missed.
+type Subscription struct {
+	Replay []Event
+	Events <-chan Event
+	Gap    bool
+}
+
+type subscriber struct {
+	filter Filter
+	events chan Event
+}
+
+// Broker fans the events of a Listener out to subscribers, and keeps the latest events in a bounded
+// replay buffer, so that subscribers can resume after reconnecting.
+type Broker struct {
+	mu          sync.Mutex
+	size        int
+	buffer      []Event
+	floor       int64
+	subscribers map[*subscriber]struct{}
+	closed      bool
+}
+
+// NewBroker returns a new Broker that buffers the latest size events for replay.
+func NewBroker(size int) *Broker {
+	return &Broker{
+		size:        size,
+		subscribers: make(map[*subscriber]struct{}),
+	}
+}
+
+// Publish buffers event and sends it to every subscriber whose filter it matches. A subscriber that
+// can not keep up is dropped rather than blocking the other subscribers.
+func (b *Broker) Publish(event Event) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.closed {
+		return
+	}
+
+	b.buffer = append(b.buffer, event)
+	if len(b.buffer) > b.size {
+		evicted := len(b.buffer) - b.size
+		b.floor = max(b.floor, b.buffer[evicted-1].ID)
+		b.buffer = append([]Event(nil), b.buffer[evicted:]...)
+	}
+
+	for s := range b.subscribers {
+		if !s.filter.Match(event) {
+			continue
+		}
+		select {
+		case s.events <- event:
+		default:
+			delete(b.subscribers, s)
+			close(s.events)
+		}
+	}
+}
+
+// Reset empties the replay buffer after events may have been missed, such as when the listener
+// reconnected. floor is the ID of the latest event that may have been missed, so that subscribers
+// resuming from an earlier event are told about the gap.
+func (b *Broker) Reset(floor int64) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.buffer = nil
+	b.floor = max(b.floor, floor)
+}
+
+// Subscribe subscribes to the events matching filter. If after is not 0, the subscriber resumes
+// after the event with that ID, and the buffered events after it are replayed. The returned function
+// ends the subscription and must be called once the subscriber is done.
+func (b *Broker) Subscribe(filter Filter, after int64) (*Subscription, func()) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	s := &subscriber{
+		filter: filter,
+		events: make(chan Event, subscriberBuffer),
+	}
+	subscription := &Subscription{
+		Replay: []Event{},
+		Events: s.events,
+	}
+	if after != 0 {
+		subscription.Gap = after < b.floor
+		for _, event := range b.buffer {
+			if event.ID > after && filter.Match(event) {
+				subscription.Replay = append(subscription.Replay, event)
+			}
+		}
+	}
+
+	if b.closed {
+		close(s.events)
+		return subscription, func() {}
+	}
+	b.subscribers[s] = struct{}{}
+
+	return subscription, func() {
+		b.mu.Lock()
+		defer b.mu.Unlock()
+
+		if _, ok := b.subscribers[s]; ok {
+			delete(b.subscribers, s)
+			close(s.events)
+		}
+	}
+}
+
+// Close ends every subscription and refuses new ones, so that long-lived streams end when the server
+// shuts down.
+func (b *Broker) Close() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.closed = true
+	for s := range b.subscribers {
+		delete(b.subscribers, s)
+		close(s.events)
+	}
+}
@@ -0,0 +1,164 @@
+package stream
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// receive returns the events received on events until it is closed or no event is waiting.
+func receive(events <-chan Event) ([]Event, bool) {
+	var received []Event
+	for {
+		select {
+		case event, ok := <-events:
+			if !ok {
+				return received, false
+			}
+			received = append(received, event)
+		default:
+			return received, true
+		}
+	}
+}
+
+func TestBrokerSubscribe(t *testing.T) {
+	customer := func(id int64, userID int) Event {
+		return Event{ID: id, Type: "user.updated", UserID: userID, Role: "Customer", OldRole: "Customer", Data: json.RawMessage(`{}`)}
+	}
+	promoted := Event{ID: 4, Type: "user.updated", UserID: 2, Role: "Employee", OldRole: "Customer", Data: json.RawMessage(`{}`)}
+	employee := Event{ID: 5, Type: "user.created", UserID: 3, Role: "Employee", Data: json.RawMessage(`{}`)}
+
+	tests := map[string]struct {
+		floor          int64
+		published      []Event
+		filter         Filter
+		after          int64
+		expectedReplay []Event
+		expectedGap    bool
+	}{
+		"no replay without last event ID": {
+			published:      []Event{customer(1, 1), customer(2, 1)},
+			expectedReplay: []Event{},
+		},
+		"replay after last event ID": {
+			published:      []Event{customer(1, 1), customer(2, 1), customer(3, 2)},
+			after:          1,
+			expectedReplay: []Event{customer(2, 1), customer(3, 2)},
+		},
+		"replay filtered by role, including users leaving it": {
+			published:      []Event{customer(1, 1), promoted, employee},
+			filter:         Filter{Role: "Customer"},
+			after:          1,
+			expectedReplay: []Event{promoted},
+		},
+		"replay filtered by ID": {
+			published:      []Event{customer(1, 1), customer(2, 1), customer(3, 2)},
+			filter:         Filter{UserID: 1},
+			after:          1,
+			expectedReplay: []Event{customer(2, 1)},
+		},
+		"gap when last event ID is evicted": {
+			published:      []Event{customer(1, 1), customer(2, 1), customer(3, 2), promoted, employee},
+			after:          1,
+			expectedReplay: []Event{customer(3, 2), promoted, employee},
+			expectedGap:    true,
+		},
+		"gap when last event ID is before reset": {
+			floor:          10,
+			after:          7,
+			expectedReplay: []Event{},
+			expectedGap:    true,
+		},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			broker := NewBroker(3)
+			broker.Reset(tc.floor)
+			for _, event := range tc.published {
+				broker.Publish(event)
+			}
+
+			subscription, cancel := broker.Subscribe(tc.filter, tc.after)
+			defer cancel()
+
+			assert.Equal(t, tc.expectedReplay, subscription.Replay, "Wrong events replayed")
+			assert.Equal(t, tc.expectedGap, subscription.Gap, "Wrong gap")
+		})
+	}
+}
+
+func TestBrokerPublish(t *testing.T) {
+	broker := NewBroker(10)
+	all, cancelAll := broker.Subscribe(Filter{}, 0)
+	defer cancelAll()
+	employees, cancelEmployees := broker.Subscribe(Filter{Role: "Employee"}, 0)
+	defer cancelEmployees()
+
+	created := Event{ID: 1, Type: "user.created", UserID: 1, Role: "Customer"}
+	promoted := Event{ID: 2, Type: "user.updated", UserID: 1, Role: "Employee", OldRole: "Customer"}
+	broker.Publish(created)
+	broker.Publish(promoted)
+
+	received, open := receive(all.Events)
+	assert.Equal(t, []Event{created, promoted}, received, "Wrong events received without filter")
+	assert.True(t, open, "Subscription closed")
+
+	received, open = receive(employees.Events)
+	assert.Equal(t, []Event{promoted}, received, "Wrong events received with role filter")
+	assert.True(t, open, "Subscription closed")
+
+	cancelEmployees()
+	_, open = receive(employees.Events)
+	assert.False(t, open, "Subscription not closed when cancelled")
+}
+
+func TestBrokerDropsSlowSubscriber(t *testing.T) {
+	broker := NewBroker(10)
+	slow, cancel := broker.Subscribe(Filter{}, 0)
+	defer cancel()
+
+	for i := range subscriberBuffer + 1 {
+		broker.Publish(Event{ID: int64(i + 1)})
+	}
+
+	received, open := receive(slow.Events)
+	assert.Len(t, received, subscriberBuffer, "Wrong number of events received")
+	assert.False(t, open, "Slow subscriber not dropped")
+}
+
+func TestBrokerClose(t *testing.T) {
+	broker := NewBroker(10)
+	before, cancel := broker.Subscribe(Filter{}, 0)
+	defer cancel()
+
+	broker.Close()
+	broker.Publish(Event{ID: 1})
+	after, cancelAfter := broker.Subscribe(Filter{}, 0)
+	defer cancelAfter()
+
+	_, open := receive(before.Events)
+	assert.False(t, open, "Subscription not closed with broker")
+	received, open := receive(after.Events)
+	assert.Empty(t, received, "Event published after close")
+	assert.False(t, open, "Subscription after close not closed")
+}
+
+func TestDecodeEvent(t *testing.T) {
+	payload := `{"seq": 42, "type": "user.updated", "id": 7, "role": "Employee", "old_role": "Customer", ` +
+		`"user": {"id": 7, "first_name": "John", "role": "Employee"}}`
+
+	event, err := DecodeEvent([]byte(payload))
+
+	assert.NoError(t, err)
+	assert.Equal(t, Event{
+		ID:      42,
+		Type:    "user.updated",
+		UserID:  7,
+		Role:    "Employee",
+		OldRole: "Customer",
+		Data:    json.RawMessage(`{"id": 7, "first_name": "John", "role": "Employee"}`),
+	}, event)
+}
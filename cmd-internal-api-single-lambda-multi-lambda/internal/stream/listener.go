@@ -0,0 +1,110 @@
+package stream
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+// Channel is the Postgres notification channel the trigger on the users table notifies changes on.
+const Channel = "user_changes"
+
+// pingInterval is how often the listener connection is checked while no notifications arrive.
+const pingInterval = 90 * time.Second
+
+type sLogger interface {
+	Debug(msg string, args ...any)
+	Info(msg string, args ...any)
+	Warn(msg string, args ...any)
+	Error(msg string, args ...any)
+}
+
+// Listener publishes the user changes notified by Postgres to a Broker. It holds a connection of its
+// own, as notifications are received on the connection that listens for them. If the connection is
+// lost, it reconnects and resets the replay buffer of the broker, as changes may have been missed.
+type Listener struct {
+	db     *sql.DB
+	dsn    string
+	broker *Broker
+	logger sLogger
+}
+
+// NewListener returns a new Listener that listens with a connection to dsn, and reads the sequence of
+// event IDs from db.
+func NewListener(db *sql.DB, dsn string, broker *Broker, logger sLogger) *Listener {
+	return &Listener{
+		db:     db,
+		dsn:    dsn,
+		broker: broker,
+		logger: logger,
+	}
+}
+
+// Run publishes notifications to the broker until ctx is done.
+func (l *Listener) Run(ctx context.Context) error {
+	listener := pq.NewListener(l.dsn, time.Second, time.Minute, nil)
+	defer listener.Close()
+
+	if err := listener.Listen(Channel); err != nil {
+		return fmt.Errorf("[in Listener.Run] listen: %w", err)
+	}
+	if err := l.reset(ctx); err != nil {
+		return fmt.Errorf("[in Listener.Run]: %w", err)
+	}
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case notification := <-listener.Notify:
+			// a nil notification is sent after the connection has been re-established
+			if notification == nil {
+				l.logger.Warn("User change listener reconnected, replay buffer reset")
+				if err := l.reset(ctx); err != nil && ctx.Err() == nil {
+					l.logger.Error("Error resetting replay buffer", "err", err)
+				}
+				continue
+			}
+
+			event, err := DecodeEvent([]byte(notification.Extra))
+			if err != nil {
+				l.logger.Error("Error decoding user change notification", "err", err, "payload", notification.Extra)
+				continue
+			}
+			l.broker.Publish(event)
+		case <-time.After(pingInterval):
+			go func() {
+				if err := listener.Ping(); err != nil {
+					l.logger.Warn("User change listener ping failed", "err", err)
+				}
+			}()
+		}
+	}
+}
+
+// reset resets the replay buffer of the broker to the latest event ID handed out by the database.
+func (l *Listener) reset(ctx context.Context) error {
+	var floor int64
+	err := l.db.
+		QueryRowContext(ctx, `SELECT CASE WHEN "is_called" THEN "last_value" ELSE 0 END FROM "user_events_seq"`).
+		Scan(&floor)
+	if err != nil {
+		return fmt.Errorf("read event sequence: %w", err)
+	}
+
+	l.broker.Reset(floor)
+	return nil
+}
+
+// DecodeEvent decodes the payload of a notification on Channel.
+func DecodeEvent(payload []byte) (Event, error) {
+	var event Event
+	if err := json.Unmarshal(payload, &event); err != nil {
+		return Event{}, err
+	}
+	return event, nil
+}
@@ -0,0 +1,115 @@
+package tracing
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/stretchr/testify/assert"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// newTestProvider returns a provider that keeps its spans in the returned exporter.
+func newTestProvider() (*sdktrace.TracerProvider, *tracetest.InMemoryExporter) {
+	exporter := tracetest.NewInMemoryExporter()
+	return sdktrace.NewTracerProvider(sdktrace.WithSyncer(exporter)), exporter
+}
+
+// attributes returns the attributes of span by key.
+func attributes(span tracetest.SpanStub) map[attribute.Key]attribute.Value {
+	values := make(map[attribute.Key]attribute.Value, len(span.Attributes))
+	for _, kv := range span.Attributes {
+		values[kv.Key] = kv.Value
+	}
+	return values
+}
+
+func TestMiddleware(t *testing.T) {
+	const traceparent = "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01"
+
+	tests := map[string]struct {
+		path           string
+		traceparent    string
+		expectedName   string
+		expectedRoute  string
+		expectedStatus int
+		expectedCode   codes.Code
+		expectedParent bool
+	}{
+		"routed request": {
+			path:           "/api/user/1",
+			expectedName:   "GET /api/user/{ID}",
+			expectedRoute:  "/api/user/{ID}",
+			expectedStatus: http.StatusOK,
+			expectedCode:   codes.Unset,
+		},
+		"continues traceparent": {
+			path:           "/api/user/1",
+			traceparent:    traceparent,
+			expectedName:   "GET /api/user/{ID}",
+			expectedRoute:  "/api/user/{ID}",
+			expectedStatus: http.StatusOK,
+			expectedCode:   codes.Unset,
+			expectedParent: true,
+		},
+		"server error": {
+			path:           "/api/fail",
+			expectedName:   "GET /api/fail",
+			expectedRoute:  "/api/fail",
+			expectedStatus: http.StatusInternalServerError,
+			expectedCode:   codes.Error,
+		},
+		"no route": {
+			path:           "/api/unknown",
+			expectedName:   "GET",
+			expectedStatus: http.StatusNotFound,
+			expectedCode:   codes.Unset,
+		},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			provider, exporter := newTestProvider()
+
+			r := chi.NewRouter()
+			r.Use(Middleware(provider, ChiRoute))
+			r.Get("/api/user/{ID}", func(w http.ResponseWriter, r *http.Request) {
+				assert.True(t, trace.SpanContextFromContext(r.Context()).IsValid(), "Span not in request context")
+				_, _ = w.Write([]byte("ok"))
+			})
+			r.Get("/api/fail", func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(http.StatusInternalServerError)
+			})
+
+			req, err := http.NewRequest(http.MethodGet, tc.path, nil)
+			assert.NoError(t, err)
+			if tc.traceparent != "" {
+				req.Header.Set("traceparent", tc.traceparent)
+			}
+
+			r.ServeHTTP(httptest.NewRecorder(), req)
+
+			spans := exporter.GetSpans()
+			if !assert.Len(t, spans, 1, "Wrong number of spans") {
+				return
+			}
+			span := spans[0]
+			attrs := attributes(span)
+
+			assert.Equal(t, tc.expectedName, span.Name, "Wrong span name")
+			assert.Equal(t, tc.expectedCode, span.Status.Code, "Wrong span status")
+			assert.Equal(t, trace.SpanKindServer, span.SpanKind, "Wrong span kind")
+			assert.Equal(t, int64(tc.expectedStatus), attrs["http.response.status_code"].AsInt64(), "Wrong status attribute")
+			assert.Equal(t, tc.expectedRoute, attrs["http.route"].AsString(), "Wrong route attribute")
+			assert.Equal(t, tc.expectedParent, span.Parent.IsRemote(), "Wrong parent")
+			if tc.expectedParent {
+				assert.Equal(t, "4bf92f3577b34da6a3ce929d0e0e4736", span.SpanContext.TraceID().String(), "Wrong trace ID")
+			}
+		})
+	}
+}
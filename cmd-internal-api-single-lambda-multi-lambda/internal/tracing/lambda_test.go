@@ -0,0 +1,72 @@
+package tracing
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/aws/aws-lambda-go/lambdacontext"
+	"github.com/stretchr/testify/assert"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+)
+
+func TestWrapAPIGateway(t *testing.T) {
+	req := events.APIGatewayProxyRequest{
+		HTTPMethod: http.MethodGet,
+		Resource:   "/api/user/{ID}",
+		Path:       "/api/user/1",
+		Headers: map[string]string{
+			"Traceparent": "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01",
+		},
+	}
+
+	tests := map[string]struct {
+		statusCode   int
+		err          error
+		expectedCode codes.Code
+	}{
+		"success": {
+			statusCode:   http.StatusOK,
+			expectedCode: codes.Unset,
+		},
+		"server error": {
+			statusCode:   http.StatusInternalServerError,
+			expectedCode: codes.Error,
+		},
+		"handler error": {
+			err:          errors.New("test"),
+			expectedCode: codes.Error,
+		},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			provider, exporter := newTestProvider()
+
+			handler := WrapAPIGateway(provider, func(ctx context.Context, _ events.APIGatewayProxyRequest) (events.APIGatewayProxyResponse, error) {
+				assert.True(t, trace.SpanContextFromContext(ctx).IsValid(), "Span not in invocation context")
+				return events.APIGatewayProxyResponse{StatusCode: tc.statusCode}, tc.err
+			})
+
+			ctx := lambdacontext.NewContext(context.Background(), &lambdacontext.LambdaContext{AwsRequestID: "request-1"})
+			_, err := handler(ctx, req)
+			assert.Equal(t, tc.err, err, "Wrong error")
+
+			spans := exporter.GetSpans()
+			if !assert.Len(t, spans, 1, "Wrong number of spans") {
+				return
+			}
+			span := spans[0]
+			attrs := attributes(span)
+
+			assert.Equal(t, "GET /api/user/{ID}", span.Name, "Wrong span name")
+			assert.Equal(t, tc.expectedCode, span.Status.Code, "Wrong span status")
+			assert.Equal(t, "4bf92f3577b34da6a3ce929d0e0e4736", span.SpanContext.TraceID().String(), "Wrong trace ID")
+			assert.Equal(t, "request-1", attrs["faas.invocation_id"].AsString(), "Wrong invocation ID")
+			assert.Equal(t, "/api/user/{ID}", attrs["http.route"].AsString(), "Wrong route attribute")
+		})
+	}
+}
@@ -0,0 +1,42 @@
+package tracing
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewExporter(t *testing.T) {
+	tests := map[string]struct {
+		name        string
+		expectedNil bool
+		expectedErr error
+	}{
+		"stdout": {
+			name: "stdout",
+		},
+		"none": {
+			name:        "none",
+			expectedNil: true,
+		},
+		"empty": {
+			name:        "",
+			expectedNil: true,
+		},
+		"unknown": {
+			name:        "zipkin",
+			expectedNil: true,
+			expectedErr: ErrUnknownExporter,
+		},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			exporter, err := NewExporter(context.Background(), tc.name)
+
+			assert.ErrorIs(t, err, tc.expectedErr, "Wrong error")
+			assert.Equal(t, tc.expectedNil, exporter == nil, "Wrong exporter")
+		})
+	}
+}
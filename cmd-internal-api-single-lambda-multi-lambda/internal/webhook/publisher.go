@@ -0,0 +1,54 @@
+package webhook
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+
+	"github.com/jha-captech/user-microservice/internal/outbox"
+)
+
+// Publisher is an outbox.Publisher that queues every event for delivery to each webhook subscription
+// whose event filter matches it. The deliveries are then made by a Worker. An event is only queued
+// once per subscription, however many times it is published.
+type Publisher struct {
+	db *sql.DB
+}
+
+// NewPublisher returns a new Publisher queueing deliveries to the subscriptions in db.
+func NewPublisher(db *sql.DB) *Publisher {
+	return &Publisher{
+		db: db,
+	}
+}
+
+// Publish queues event for delivery to the matching subscriptions.
+func (p *Publisher) Publish(ctx context.Context, event outbox.Event) error {
+	payload, err := json.Marshal(event)
+	if err != nil {
+		return fmt.Errorf("[in webhook.Publisher.Publish] encode event: %w", err)
+	}
+
+	_, err = p.db.ExecContext(
+		ctx,
+		`
+		INSERT INTO "webhook_deliveries" ("subscription_id", "event_id", "event_type", "payload")
+		SELECT
+			"id", $1, $2, $3
+		FROM
+			"webhook_subscriptions"
+		WHERE
+			cardinality("events") = 0 OR $2 = ANY("events")
+		ON CONFLICT ("subscription_id", "event_id") WHERE "redelivery_of" IS NULL DO NOTHING
+		`,
+		event.ID,
+		event.Type,
+		string(payload),
+	)
+	if err != nil {
+		return fmt.Errorf("[in webhook.Publisher.Publish]: %w", err)
+	}
+
+	return nil
+}
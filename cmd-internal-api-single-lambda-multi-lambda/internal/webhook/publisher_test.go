@@ -0,0 +1,62 @@
+package webhook
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/jha-captech/user-microservice/internal/outbox"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPublisherPublish(t *testing.T) {
+	const insertDeliveries = `INSERT INTO "webhook_deliveries" ("subscription_id", "event_id", "event_type", "payload") ` +
+		`SELECT "id", $1, $2, $3 FROM "webhook_subscriptions" WHERE cardinality("events") = 0 OR $2 = ANY("events") ` +
+		`ON CONFLICT ("subscription_id", "event_id") WHERE "redelivery_of" IS NULL DO NOTHING`
+
+	event := outbox.Event{
+		SpecVersion:     outbox.SpecVersion,
+		ID:              "42",
+		Source:          "/user-microservice",
+		Type:            "user.created",
+		Subject:         "7",
+		Time:            time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC),
+		DataContentType: "application/json",
+		Data:            json.RawMessage(`{"id":7}`),
+	}
+	payload := `{"specversion":"1.0","id":"42","source":"/user-microservice","type":"user.created","subject":"7",` +
+		`"time":"2024-05-01T12:00:00Z","datacontenttype":"application/json","data":{"id":7}}`
+
+	tests := map[string]struct {
+		mockErr     error
+		expectedErr error
+	}{
+		"event queued": {},
+		"error queueing event": {
+			mockErr:     errors.New("test"),
+			expectedErr: fmt.Errorf("[in webhook.Publisher.Publish]: %w", errors.New("test")),
+		},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			db, mock, err := sqlmock.New()
+			assert.NoError(t, err)
+			defer db.Close()
+			mock.ExpectExec(regexp.QuoteMeta(insertDeliveries)).
+				WithArgs("42", "user.created", payload).
+				WillReturnResult(sqlmock.NewResult(0, 2)).
+				WillReturnError(tc.mockErr)
+
+			err = NewPublisher(db).Publish(context.Background(), event)
+
+			assert.Equal(t, tc.expectedErr, err, "errors did not match")
+			assert.NoError(t, mock.ExpectationsWereMet())
+		})
+	}
+}
@@ -0,0 +1,34 @@
+package webhook
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+)
+
+// Headers of the requests that deliver events to webhook subscriptions.
+const (
+	// SignatureHeader carries the signature of the payload made by Sign.
+	SignatureHeader = "X-Signature"
+	// DeliveryHeader carries the ID of the delivery, which differs between redeliveries of an event.
+	DeliveryHeader = "X-Webhook-Delivery"
+	// EventHeader carries the type of the delivered event.
+	EventHeader = "X-Webhook-Event"
+)
+
+// signaturePrefix names the algorithm of a signature.
+const signaturePrefix = "sha256="
+
+// Sign returns the signature of payload for a subscription with secret, which is the hex encoded
+// HMAC-SHA256 of payload keyed with secret, prefixed with `sha256=`.
+func Sign(secret string, payload []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(payload)
+	return signaturePrefix + hex.EncodeToString(mac.Sum(nil))
+}
+
+// Verify reports whether signature is the signature of payload for a subscription with secret. The
+// signatures are compared in constant time, so that receivers can use Verify to check deliveries.
+func Verify(secret string, payload []byte, signature string) bool {
+	return hmac.Equal([]byte(Sign(secret, payload)), []byte(signature))
+}
@@ -0,0 +1,19 @@
+package webhook
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSign(t *testing.T) {
+	payload := []byte(`{"id":"1","type":"user.created"}`)
+
+	signature := Sign("It's a Secret to Everybody", []byte("Hello, World!"))
+
+	assert.Equal(t, "sha256=757107ea0eb2509fc211221cce984b8a37570b6d7586c22c46f4379c8b043e17", signature)
+	assert.True(t, Verify("secret", payload, Sign("secret", payload)), "Valid signature not verified")
+	assert.False(t, Verify("other", payload, Sign("secret", payload)), "Signature with other secret verified")
+	assert.False(t, Verify("secret", []byte(`{}`), Sign("secret", payload)), "Signature of other payload verified")
+	assert.False(t, Verify("secret", payload, ""), "Missing signature verified")
+}
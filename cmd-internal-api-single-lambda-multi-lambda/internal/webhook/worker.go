@@ -0,0 +1,372 @@
+package webhook
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/lib/pq"
+
+	"github.com/jha-captech/user-microservice/internal/outbox"
+)
+
+type sLogger interface {
+	Debug(msg string, args ...any)
+	Info(msg string, args ...any)
+	Warn(msg string, args ...any)
+	Error(msg string, args ...any)
+}
+
+type Option func(*workerOptions)
+
+type workerOptions struct {
+	client       *http.Client
+	batchSize    int
+	pollInterval time.Duration
+	maxAttempts  int
+	minBackoff   time.Duration
+	maxBackoff   time.Duration
+	lease        time.Duration
+}
+
+// WithHTTPClient sets the client deliveries are made with. If this function is not called, a client
+// with a 10 second timeout is used.
+func WithHTTPClient(client *http.Client) Option {
+	return func(options *workerOptions) {
+		options.client = client
+	}
+}
+
+// WithBatchSize sets the maximum number of deliveries taken at a time. If this function is not
+// called, the default is 100.
+func WithBatchSize(size int) Option {
+	return func(options *workerOptions) {
+		options.batchSize = size
+	}
+}
+
+// WithPollInterval sets how often pending deliveries are checked for. If this function is not
+// called, the default is 1 second.
+func WithPollInterval(interval time.Duration) Option {
+	return func(options *workerOptions) {
+		options.pollInterval = interval
+	}
+}
+
+// WithMaxAttempts sets how many times a delivery is attempted before it is marked dead. If this
+// function is not called, the default is 10.
+func WithMaxAttempts(attempts int) Option {
+	return func(options *workerOptions) {
+		options.maxAttempts = attempts
+	}
+}
+
+// WithBackoff sets the delay before the first retry of a delivery, which doubles with every further
+// attempt up to maxDelay. If this function is not called, the defaults are 1 second and 5 minutes.
+func WithBackoff(delay, maxDelay time.Duration) Option {
+	return func(options *workerOptions) {
+		options.minBackoff = delay
+		options.maxBackoff = maxDelay
+	}
+}
+
+// WithLease sets how long the deliveries of a batch are claimed for. A delivery that is not marked
+// delivered or failed within its lease is taken again, so the lease should be longer than it takes
+// to make a batch. If this function is not called, the default is 5 minutes.
+func WithLease(lease time.Duration) Option {
+	return func(options *workerOptions) {
+		options.lease = lease
+	}
+}
+
+// Worker delivers the deliveries queued by a Publisher to the URLs of their subscriptions. Every
+// delivery is a POST of the CloudEvents JSON envelope of the event, signed with the secret of the
+// subscription in the X-Signature header. Any response other than a 2xx status fails the delivery,
+// which is retried with exponential backoff until it has been attempted the maximum number of
+// times. The status, number of attempts and last response of every delivery are kept as the
+// delivery log of the subscription.
+//
+// A batch of deliveries is claimed for a lease before it is made, so any number of workers can share
+// the deliveries, and deliveries claimed by a worker that stops are taken again once their lease
+// expires.
+type Worker struct {
+	db      *sql.DB
+	logger  sLogger
+	options workerOptions
+}
+
+// NewWorker returns a new Worker making the deliveries queued in db.
+func NewWorker(db *sql.DB, logger sLogger, opts ...Option) *Worker {
+	options := workerOptions{
+		batchSize:    100,
+		pollInterval: time.Second,
+		maxAttempts:  10,
+		minBackoff:   time.Second,
+		maxBackoff:   5 * time.Minute,
+		lease:        5 * time.Minute,
+	}
+	for _, opt := range opts {
+		opt(&options)
+	}
+	if options.client == nil {
+		options.client = &http.Client{Timeout: 10 * time.Second}
+	}
+
+	return &Worker{
+		db:      db,
+		logger:  logger,
+		options: options,
+	}
+}
+
+// Run makes pending deliveries until ctx is done. Deliveries are polled for every poll interval, and
+// straight away again as long as a full batch of deliveries was found.
+func (w *Worker) Run(ctx context.Context) {
+	ticker := time.NewTicker(w.options.pollInterval)
+	defer ticker.Stop()
+
+	for {
+		for {
+			count, err := w.Poll(ctx)
+			if err != nil {
+				if ctx.Err() == nil {
+					w.logger.Error("Error delivering webhooks", "err", err)
+				}
+				break
+			}
+			if count < w.options.batchSize {
+				break
+			}
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// pendingDelivery is a delivery taken from the queue along with the subscription it is made to.
+type pendingDelivery struct {
+	id        int64
+	eventType string
+	payload   []byte
+	attempts  int
+	url       string
+	secret    string
+}
+
+// Poll makes a single batch of the pending deliveries that are due and returns the number of
+// deliveries it attempted. The batch is claimed first and delivered outside of any transaction,
+// and the outcome of every delivery is recorded on its own. A delivery that fails is retried after
+// a backoff, or marked dead if it has been attempted the maximum number of times.
+func (w *Worker) Poll(ctx context.Context) (int, error) {
+	pending, err := w.claim(ctx)
+	if err != nil {
+		return 0, fmt.Errorf("[in Worker.Poll]: %w", err)
+	}
+
+	for _, p := range pending {
+		status, deliverErr := w.deliver(ctx, p)
+		if deliverErr != nil {
+			if ctx.Err() != nil {
+				return 0, fmt.Errorf("[in Worker.Poll]: %w", ctx.Err())
+			}
+			if err = w.markFailed(ctx, p, status, deliverErr); err != nil {
+				w.logger.Error("Error recording failed webhook delivery", "id", p.id, "err", err)
+			}
+			continue
+		}
+
+		_, err = w.db.ExecContext(
+			ctx,
+			`
+			UPDATE
+				"webhook_deliveries"
+			SET
+				"status" = $2,
+				"attempts" = "attempts" + 1,
+				"response_status" = $3,
+				"last_error" = '',
+				"delivered_at" = NOW()
+			WHERE
+				"id" = $1
+			`,
+			p.id,
+			outbox.StatusDelivered,
+			status,
+		)
+		if err != nil {
+			w.logger.Error("Error marking webhook delivery delivered", "id", p.id, "err", err)
+		}
+	}
+
+	return len(pending), nil
+}
+
+// claim takes the pending deliveries that are due and leases them to this worker by moving their
+// next attempt to the end of the lease, so other workers skip them until it expires.
+func (w *Worker) claim(ctx context.Context) ([]pendingDelivery, error) {
+	tx, err := w.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		_ = tx.Rollback()
+	}()
+
+	pending, err := w.takePending(ctx, tx)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(pending) > 0 {
+		ids := make([]int64, len(pending))
+		for i, p := range pending {
+			ids[i] = p.id
+		}
+		_, err = tx.ExecContext(
+			ctx,
+			`UPDATE "webhook_deliveries" SET "next_attempt_at" = NOW() + $2 * INTERVAL '1 millisecond' WHERE "id" = ANY($1)`,
+			pq.Array(ids),
+			w.options.lease.Milliseconds(),
+		)
+		if err != nil {
+			return nil, fmt.Errorf("lease deliveries: %w", err)
+		}
+	}
+
+	if err = tx.Commit(); err != nil {
+		return nil, err
+	}
+
+	return pending, nil
+}
+
+// takePending locks and returns the pending deliveries that are due, skipping deliveries locked by
+// other workers.
+func (w *Worker) takePending(ctx context.Context, tx *sql.Tx) ([]pendingDelivery, error) {
+	rows, err := tx.QueryContext(
+		ctx,
+		`
+		SELECT
+			"d"."id", "d"."event_type", "d"."payload", "d"."attempts", "s"."url", "s"."secret"
+		FROM
+			"webhook_deliveries" AS "d"
+			JOIN "webhook_subscriptions" AS "s" ON "s"."id" = "d"."subscription_id"
+		WHERE
+			"d"."status" = $1 AND "d"."next_attempt_at" <= NOW()
+		ORDER BY
+			"d"."id"
+		LIMIT $2
+		FOR UPDATE OF "d" SKIP LOCKED
+		`,
+		outbox.StatusPending,
+		w.options.batchSize,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var pending []pendingDelivery
+	for rows.Next() {
+		var p pendingDelivery
+		if err = rows.Scan(&p.id, &p.eventType, &p.payload, &p.attempts, &p.url, &p.secret); err != nil {
+			return nil, err
+		}
+		pending = append(pending, p)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return pending, nil
+}
+
+// deliver posts the payload of p to the URL of its subscription and returns the status of the
+// response, which is 0 if there was no response.
+func (w *Worker) deliver(ctx context.Context, p pendingDelivery) (int, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, p.url, bytes.NewReader(p.payload))
+	if err != nil {
+		return 0, err
+	}
+	req.Header.Set("Content-Type", outbox.ContentType)
+	req.Header.Set(SignatureHeader, Sign(p.secret, p.payload))
+	req.Header.Set(DeliveryHeader, strconv.FormatInt(p.id, 10))
+	req.Header.Set(EventHeader, p.eventType)
+
+	resp, err := w.options.client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+	_, _ = io.Copy(io.Discard, resp.Body)
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return resp.StatusCode, fmt.Errorf("subscriber responded with %s", resp.Status)
+	}
+
+	return resp.StatusCode, nil
+}
+
+// markFailed records that delivering p failed with deliverErr and a response with status, and
+// schedules its next attempt or marks it dead.
+func (w *Worker) markFailed(ctx context.Context, p pendingDelivery, status int, deliverErr error) error {
+	attempts := p.attempts + 1
+	deliveryStatus := outbox.StatusPending
+	if attempts >= w.options.maxAttempts {
+		deliveryStatus = outbox.StatusDead
+		w.logger.Warn("Webhook delivery is dead after too many attempts", "id", p.id, "attempts", attempts, "err", deliverErr)
+	} else {
+		w.logger.Info("Error delivering webhook, will retry", "id", p.id, "attempts", attempts, "err", deliverErr)
+	}
+
+	var responseStatus any
+	if status != 0 {
+		responseStatus = status
+	}
+
+	_, err := w.db.ExecContext(
+		ctx,
+		`
+		UPDATE
+			"webhook_deliveries"
+		SET
+			"status" = $2,
+			"attempts" = $3,
+			"response_status" = $4,
+			"last_error" = $5,
+			"next_attempt_at" = NOW() + $6 * INTERVAL '1 millisecond'
+		WHERE
+			"id" = $1
+		`,
+		p.id,
+		deliveryStatus,
+		attempts,
+		responseStatus,
+		deliverErr.Error(),
+		w.backoff(attempts).Milliseconds(),
+	)
+	if err != nil {
+		return fmt.Errorf("mark delivery %d failed: %w", p.id, err)
+	}
+
+	return nil
+}
+
+// backoff returns the delay before the next attempt of a delivery that has been attempted attempts
+// times.
+func (w *Worker) backoff(attempts int) time.Duration {
+	delay := w.options.minBackoff
+	for i := 1; i < attempts && delay < w.options.maxBackoff; i++ {
+		delay *= 2
+	}
+	return min(delay, w.options.maxBackoff)
+}
@@ -0,0 +1,222 @@
+package webhook
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/jha-captech/user-microservice/internal/outbox"
+	"github.com/lib/pq"
+	"github.com/stretchr/testify/assert"
+)
+
+// receivedDelivery is a request received by the test subscriber.
+type receivedDelivery struct {
+	contentType string
+	signature   string
+	deliveryID  string
+	eventType   string
+	body        string
+}
+
+func TestWorkerPoll(t *testing.T) {
+	const (
+		selectPending = `SELECT "d"."id", "d"."event_type", "d"."payload", "d"."attempts", "s"."url", "s"."secret" ` +
+			`FROM "webhook_deliveries" AS "d" JOIN "webhook_subscriptions" AS "s" ON "s"."id" = "d"."subscription_id" ` +
+			`WHERE "d"."status" = $1 AND "d"."next_attempt_at" <= NOW() ORDER BY "d"."id" LIMIT $2 FOR UPDATE OF "d" SKIP LOCKED`
+		leaseDeliveries = `UPDATE "webhook_deliveries" SET "next_attempt_at" = NOW() + $2 * INTERVAL '1 millisecond' WHERE "id" = ANY($1)`
+		markDelivered   = `UPDATE "webhook_deliveries" SET "status" = $2, "attempts" = "attempts" + 1, "response_status" = $3, ` +
+			`"last_error" = '', "delivered_at" = NOW() WHERE "id" = $1`
+		markFailed = `UPDATE "webhook_deliveries" SET "status" = $2, "attempts" = $3, "response_status" = $4, "last_error" = $5, ` +
+			`"next_attempt_at" = NOW() + $6 * INTERVAL '1 millisecond' WHERE "id" = $1`
+		payload = `{"id": "1", "type": "user.created", "data": {"id": 7}}`
+		secret  = "0123456789abcdef"
+	)
+
+	columns := []string{"id", "event_type", "payload", "attempts", "url", "secret"}
+	delivered := receivedDelivery{
+		contentType: outbox.ContentType,
+		signature:   Sign(secret, []byte(payload)),
+		deliveryID:  "3",
+		eventType:   "user.created",
+		body:        payload,
+	}
+
+	tests := map[string]struct {
+		status           int
+		setup            func(mock sqlmock.Sqlmock, url string)
+		expectedReceived []receivedDelivery
+		expectedCount    int
+		expectedErr      error
+	}{
+		"delivery signed and delivered": {
+			status: http.StatusNoContent,
+			setup: func(mock sqlmock.Sqlmock, url string) {
+				mock.ExpectBegin()
+				mock.ExpectQuery(regexp.QuoteMeta(selectPending)).
+					WithArgs(outbox.StatusPending, 10).
+					WillReturnRows(sqlmock.NewRows(columns).
+						AddRow(3, "user.created", []byte(payload), 0, url, secret))
+				mock.ExpectExec(regexp.QuoteMeta(leaseDeliveries)).
+					WithArgs(pq.Array([]int64{3}), int64(60000)).
+					WillReturnResult(sqlmock.NewResult(0, 1))
+				mock.ExpectCommit()
+				mock.ExpectExec(regexp.QuoteMeta(markDelivered)).
+					WithArgs(3, outbox.StatusDelivered, http.StatusNoContent).
+					WillReturnResult(sqlmock.NewResult(0, 1))
+			},
+			expectedReceived: []receivedDelivery{delivered},
+			expectedCount:    1,
+		},
+		"failed delivery retried after backoff": {
+			status: http.StatusInternalServerError,
+			setup: func(mock sqlmock.Sqlmock, url string) {
+				mock.ExpectBegin()
+				mock.ExpectQuery(regexp.QuoteMeta(selectPending)).
+					WithArgs(outbox.StatusPending, 10).
+					WillReturnRows(sqlmock.NewRows(columns).
+						AddRow(3, "user.created", []byte(payload), 2, url, secret))
+				mock.ExpectExec(regexp.QuoteMeta(leaseDeliveries)).
+					WithArgs(pq.Array([]int64{3}), int64(60000)).
+					WillReturnResult(sqlmock.NewResult(0, 1))
+				mock.ExpectCommit()
+				mock.ExpectExec(regexp.QuoteMeta(markFailed)).
+					WithArgs(
+						3,
+						outbox.StatusPending,
+						3,
+						http.StatusInternalServerError,
+						"subscriber responded with 500 Internal Server Error",
+						int64(4000),
+					).
+					WillReturnResult(sqlmock.NewResult(0, 1))
+			},
+			expectedReceived: []receivedDelivery{delivered},
+			expectedCount:    1,
+		},
+		"failed delivery dead after max attempts": {
+			status: http.StatusGone,
+			setup: func(mock sqlmock.Sqlmock, url string) {
+				mock.ExpectBegin()
+				mock.ExpectQuery(regexp.QuoteMeta(selectPending)).
+					WithArgs(outbox.StatusPending, 10).
+					WillReturnRows(sqlmock.NewRows(columns).
+						AddRow(3, "user.created", []byte(payload), 4, url, secret))
+				mock.ExpectExec(regexp.QuoteMeta(leaseDeliveries)).
+					WithArgs(pq.Array([]int64{3}), int64(60000)).
+					WillReturnResult(sqlmock.NewResult(0, 1))
+				mock.ExpectCommit()
+				mock.ExpectExec(regexp.QuoteMeta(markFailed)).
+					WithArgs(3, outbox.StatusDead, 5, http.StatusGone, "subscriber responded with 410 Gone", int64(10000)).
+					WillReturnResult(sqlmock.NewResult(0, 1))
+			},
+			expectedReceived: []receivedDelivery{delivered},
+			expectedCount:    1,
+		},
+		"error marking delivery delivered": {
+			status: http.StatusNoContent,
+			setup: func(mock sqlmock.Sqlmock, url string) {
+				mock.ExpectBegin()
+				mock.ExpectQuery(regexp.QuoteMeta(selectPending)).
+					WithArgs(outbox.StatusPending, 10).
+					WillReturnRows(sqlmock.NewRows(columns).
+						AddRow(3, "user.created", []byte(payload), 0, url, secret))
+				mock.ExpectExec(regexp.QuoteMeta(leaseDeliveries)).
+					WithArgs(pq.Array([]int64{3}), int64(60000)).
+					WillReturnResult(sqlmock.NewResult(0, 1))
+				mock.ExpectCommit()
+				mock.ExpectExec(regexp.QuoteMeta(markDelivered)).
+					WithArgs(3, outbox.StatusDelivered, http.StatusNoContent).
+					WillReturnError(errors.New("test"))
+			},
+			expectedReceived: []receivedDelivery{delivered},
+			expectedCount:    1,
+		},
+		"no pending deliveries": {
+			setup: func(mock sqlmock.Sqlmock, _ string) {
+				mock.ExpectBegin()
+				mock.ExpectQuery(regexp.QuoteMeta(selectPending)).
+					WithArgs(outbox.StatusPending, 10).
+					WillReturnRows(sqlmock.NewRows(columns))
+				mock.ExpectCommit()
+			},
+		},
+		"error taking pending deliveries": {
+			setup: func(mock sqlmock.Sqlmock, _ string) {
+				mock.ExpectBegin()
+				mock.ExpectQuery(regexp.QuoteMeta(selectPending)).
+					WithArgs(outbox.StatusPending, 10).
+					WillReturnError(errors.New("test"))
+				mock.ExpectRollback()
+			},
+			expectedErr: fmt.Errorf("[in Worker.Poll]: %w", errors.New("test")),
+		},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			var received []receivedDelivery
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				body, _ := io.ReadAll(r.Body)
+				received = append(received, receivedDelivery{
+					contentType: r.Header.Get("Content-Type"),
+					signature:   r.Header.Get(SignatureHeader),
+					deliveryID:  r.Header.Get(DeliveryHeader),
+					eventType:   r.Header.Get(EventHeader),
+					body:        string(body),
+				})
+				w.WriteHeader(tc.status)
+			}))
+			defer server.Close()
+
+			db, mock, err := sqlmock.New()
+			assert.NoError(t, err)
+			defer db.Close()
+			tc.setup(mock, server.URL)
+
+			worker := NewWorker(
+				db,
+				slog.Default(),
+				WithHTTPClient(server.Client()),
+				WithBatchSize(10),
+				WithMaxAttempts(5),
+				WithBackoff(time.Second, 10*time.Second),
+				WithLease(time.Minute),
+			)
+
+			count, err := worker.Poll(context.Background())
+
+			assert.Equal(t, tc.expectedErr, err, "errors did not match")
+			assert.Equal(t, tc.expectedCount, count, "Wrong number of deliveries")
+			assert.Equal(t, tc.expectedReceived, received, "Wrong requests received")
+			for _, r := range received {
+				assert.True(t, Verify(secret, []byte(r.body), r.signature), "Signature not verified")
+			}
+			assert.NoError(t, mock.ExpectationsWereMet())
+		})
+	}
+}
+
+func TestWorkerBackoff(t *testing.T) {
+	worker := NewWorker(nil, slog.Default(), WithBackoff(time.Second, time.Minute))
+
+	tests := map[int]time.Duration{
+		1:  time.Second,
+		2:  2 * time.Second,
+		4:  8 * time.Second,
+		7:  time.Minute,
+		50: time.Minute,
+	}
+
+	for attempts, expected := range tests {
+		assert.Equal(t, expected, worker.backoff(attempts), "Wrong backoff after %d attempts", attempts)
+	}
+}
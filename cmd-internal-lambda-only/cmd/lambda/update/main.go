@@ -1,15 +1,25 @@
 package main
 
 import (
+	"context"
 	"fmt"
 	"log"
 	"log/slog"
+	"os"
+	"strings"
+	"time"
 
 	"github.com/aws/aws-lambda-go/lambda"
+	"github.com/aws/aws-lambda-go/lambdacontext"
 
 	"github.com/jha-captech/user-microservice/internal/config"
+	"github.com/jha-captech/user-microservice/internal/cursor"
 	"github.com/jha-captech/user-microservice/internal/database"
 	"github.com/jha-captech/user-microservice/internal/handler"
+	"github.com/jha-captech/user-microservice/internal/logging"
+	"github.com/jha-captech/user-microservice/internal/metrics"
+	"github.com/jha-captech/user-microservice/internal/policy"
+	"github.com/jha-captech/user-microservice/internal/tracing"
 	"github.com/jha-captech/user-microservice/internal/user"
 )
 
@@ -26,6 +36,39 @@ func run() error {
 	}
 
 	logger := slog.Default()
+	if cfg.Log.RedactPII {
+		mode := cfg.Log.RedactMode
+		if mode == "" {
+			mode = logging.RedactMask
+		}
+		keys := []string{"first_name", "last_name"}
+		if cfg.Log.RedactKeys != "" {
+			keys = strings.Split(cfg.Log.RedactKeys, ",")
+		}
+		redact, err := logging.NewRedactHandler(logger.Handler(), mode, []byte(cfg.Log.RedactSecret), keys)
+		if err != nil {
+			return fmt.Errorf("[in run]: %w", err)
+		}
+		logger = slog.New(redact)
+	}
+
+	serviceName := cfg.Tracing.ServiceName
+	if serviceName == "" {
+		serviceName = "user-microservice"
+	}
+	var tracingOpts []tracing.Option
+	if cfg.Tracing.SampleRatio > 0 {
+		tracingOpts = append(tracingOpts, tracing.WithSampleRatio(cfg.Tracing.SampleRatio))
+	}
+	provider, err := tracing.New(context.Background(), cfg.Tracing.Exporter, serviceName, tracingOpts...)
+	if err != nil {
+		return fmt.Errorf("[in run]: %w", err)
+	}
+
+	migrateOption := database.WithSchemaCheck()
+	if cfg.Database.MigrateOnStartup {
+		migrateOption = database.WithMigrations()
+	}
 
 	db, err := database.NewDatabase(
 		fmt.Sprintf(
@@ -38,21 +81,57 @@ func run() error {
 		),
 		logger,
 		cfg.Database.ConnectionRetry,
+		database.WithTracing(provider),
+		migrateOption,
 	)
 	if err != nil {
 		return fmt.Errorf("[in run]: %w", err)
 	}
 
-	us := user.NewService(db)
+	namespace := cfg.Metrics.Namespace
+	if namespace == "" {
+		namespace = "UserMicroservice"
+	}
+	recorder := metrics.NewEMF(os.Stdout, namespace, lambdacontext.FunctionName, db.Session)
+
+	if cfg.Cursor.Secret == "" {
+		logger.Warn("CURSOR_SECRET is not set, cursors are only accepted by the instance that issued them")
+	}
+	cursorTTL := cfg.Cursor.TTL
+	if cursorTTL == 0 {
+		cursorTTL = 24 * time.Hour
+	}
+	us := user.NewService(
+		db,
+		cursor.NewCodec(
+			[]byte(cfg.Cursor.Secret),
+			cursorTTL,
+		),
+		user.WithChangeCounter(recorder),
+	)
 	h := handler.NewHandler(logger, us)
 
+	handle := h.UpdateUsersHandler()
+	if cfg.Auth.Disabled {
+		logger.Warn("AUTH_DISABLED is set, requests are not authenticated")
+	} else {
+		rules, err := policy.New(cfg.Auth.PolicyFile)
+		if err != nil {
+			return fmt.Errorf("[in run]: %w", err)
+		}
+		handle = h.Authenticate(h.Authorize(rules, policy.ActionUpdate, handle))
+	}
+
 	lambda.StartWithOptions(
-		h.UpdateUsersHandler(),
+		tracing.WrapAPIGateway(provider, metrics.WrapAPIGateway(recorder, logging.WrapAPIGateway(logger, handle))),
 		lambda.WithEnableSIGTERM(func() {
 			logger.Info("function container shutting down")
 			if err = db.Session.Close(); err != nil {
 				logger.Error("error closing database session", "err", err)
 			}
+			if err = provider.Shutdown(context.Background()); err != nil {
+				logger.Error("error shutting down tracer provider", "err", err)
+			}
 		}),
 	)
 
@@ -0,0 +1,122 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"log/slog"
+	"os"
+	"strconv"
+	"text/tabwriter"
+	"time"
+
+	"github.com/jha-captech/user-microservice/internal/config"
+	"github.com/jha-captech/user-microservice/internal/database"
+)
+
+const usage = `usage: migrate <command> [arg]
+
+commands:
+  up               apply all pending migrations
+  down [steps]     revert the last steps applied migrations (default 1)
+  status           list all migrations and whether they are applied
+  force <version>  mark migrations up to version as applied without running them`
+
+func main() {
+	if err := run(os.Args[1:]); err != nil {
+		log.Fatalf("Migrate failed. err: %v", err)
+	}
+}
+
+func run(args []string) error {
+	if len(args) == 0 {
+		return errors.New(usage)
+	}
+
+	cfg, err := config.NewConfiguration()
+	if err != nil {
+		return fmt.Errorf("[in run]: %w", err)
+	}
+
+	logger := slog.Default()
+
+	db, err := database.NewDatabase(
+		fmt.Sprintf(
+			"host=%s user=%s password=%s dbname=%s port=%s sslmode=disable",
+			cfg.Database.Host,
+			cfg.Database.User,
+			cfg.Database.Password,
+			cfg.Database.Name,
+			cfg.Database.Port,
+		),
+		logger,
+		cfg.Database.ConnectionRetry,
+	)
+	if err != nil {
+		return fmt.Errorf("[in run]: %w", err)
+	}
+
+	defer func() {
+		if err = db.Session.Close(); err != nil {
+			logger.Error("Error closing db connection", "err", err)
+		}
+	}()
+
+	migrator, err := database.NewMigrator(db.Session, logger)
+	if err != nil {
+		return fmt.Errorf("[in run]: %w", err)
+	}
+
+	ctx := context.Background()
+
+	switch args[0] {
+	case "up":
+		return migrator.Up(ctx)
+
+	case "down":
+		steps := 1
+		if len(args) > 1 {
+			if steps, err = strconv.Atoi(args[1]); err != nil || steps < 1 {
+				return fmt.Errorf("[in run]: steps must be a positive number, got %q", args[1])
+			}
+		}
+		return migrator.Down(ctx, steps)
+
+	case "status":
+		statuses, err := migrator.Status(ctx)
+		if err != nil {
+			return err
+		}
+		printStatus(statuses)
+		return nil
+
+	case "force":
+		if len(args) < 2 {
+			return errors.New(usage)
+		}
+		version, err := strconv.Atoi(args[1])
+		if err != nil {
+			return fmt.Errorf("[in run]: version must be a number, got %q", args[1])
+		}
+		return migrator.Force(ctx, version)
+
+	default:
+		return errors.New(usage)
+	}
+}
+
+// printStatus writes statuses to stdout as a table.
+func printStatus(statuses []database.MigrationStatus) {
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	defer w.Flush()
+
+	fmt.Fprintln(w, "VERSION\tNAME\tAPPLIED AT")
+	for _, status := range statuses {
+		appliedAt := "pending"
+		if status.Applied {
+			appliedAt = status.AppliedAt.Format(time.RFC3339)
+		}
+		fmt.Fprintf(w, "%d\t%s\t%s\n", status.Version, status.Name, appliedAt)
+	}
+}
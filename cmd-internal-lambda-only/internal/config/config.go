@@ -8,6 +8,7 @@ import (
 	"reflect"
 	"strconv"
 	"strings"
+	"time"
 
 	"github.com/joho/godotenv"
 )
@@ -16,12 +17,35 @@ type Configuration struct {
 	Env      string `env:"ENV"`
 	LogLevel string `env:"LOG_LEVEL"`
 	Database struct {
-		Name            string `env:"DATABASE_NAME,required"`
-		User            string `env:"DATABASE_USER,required"`
-		Password        string `env:"DATABASE_PASSWORD,required"`
-		Host            string `env:"DATABASE_HOST,required"`
-		Port            string `env:"DATABASE_PORT,required"`
-		ConnectionRetry int    `env:"DATABASE_CONNECTION_RETRY,required"`
+		Name             string `env:"DATABASE_NAME,required"`
+		User             string `env:"DATABASE_USER,required"`
+		Password         string `env:"DATABASE_PASSWORD,required"`
+		Host             string `env:"DATABASE_HOST,required"`
+		Port             string `env:"DATABASE_PORT,required"`
+		ConnectionRetry  int    `env:"DATABASE_CONNECTION_RETRY,required"`
+		MigrateOnStartup bool   `env:"DATABASE_MIGRATE_ON_STARTUP"`
+	}
+	Cursor struct {
+		Secret string        `env:"CURSOR_SECRET"`
+		TTL    time.Duration `env:"CURSOR_TTL"`
+	}
+	Auth struct {
+		Disabled   bool   `env:"AUTH_DISABLED"`
+		PolicyFile string `env:"AUTH_POLICY_FILE"`
+	}
+	Tracing struct {
+		Exporter    string  `env:"TRACING_EXPORTER"`
+		ServiceName string  `env:"TRACING_SERVICE_NAME"`
+		SampleRatio float64 `env:"TRACING_SAMPLE_RATIO"`
+	}
+	Metrics struct {
+		Namespace string `env:"METRICS_NAMESPACE"`
+	}
+	Log struct {
+		RedactPII    bool   `env:"LOG_REDACT_PII"`
+		RedactMode   string `env:"LOG_REDACT_MODE"`
+		RedactSecret string `env:"LOG_REDACT_SECRET"`
+		RedactKeys   string `env:"LOG_REDACT_KEYS"`
 	}
 }
 
@@ -110,6 +134,23 @@ func ParseStructFromEnv(obj any) error {
 				}
 				field.SetBool(value)
 
+			case reflect.Int64:
+				if field.Type() != reflect.TypeFor[time.Duration]() {
+					continue
+				}
+				value, err := time.ParseDuration(envValue)
+				if err != nil && required {
+					return newEnvVarParsingErr(key, err)
+				}
+				field.SetInt(int64(value))
+
+			case reflect.Float64:
+				value, err := strconv.ParseFloat(envValue, 64)
+				if err != nil && required {
+					return newEnvVarParsingErr(key, err)
+				}
+				field.SetFloat(value)
+
 			default:
 				continue
 			}
@@ -1,25 +1,64 @@
 package database
 
 import (
+	"context"
 	"database/sql"
 	"errors"
 	"fmt"
 	"log/slog"
 	"time"
 
-	_ "github.com/lib/pq"
+	"github.com/lib/pq"
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/jha-captech/user-microservice/internal/tracing"
 )
 
 type Database struct {
 	Session *sql.DB
 }
 
-// NewDatabase Establish Session connection and migrate tables before
-// returning Database.Database struct.
-func NewDatabase(connectionString string, logger *slog.Logger, retryCount int) (Database, error) {
+type Option func(*databaseOptions)
+
+type databaseOptions struct {
+	migrate        bool
+	schemaCheck    bool
+	tracerProvider trace.TracerProvider
+}
+
+// WithMigrations applies any pending migrations once the database connection is established.
+func WithMigrations() Option {
+	return func(options *databaseOptions) {
+		options.migrate = true
+	}
+}
+
+// WithSchemaCheck makes NewDatabase fail with ErrSchemaBehind if the database has pending migrations. It
+// is ignored if WithMigrations is also given.
+func WithSchemaCheck() Option {
+	return func(options *databaseOptions) {
+		options.schemaCheck = true
+	}
+}
+
+// WithTracing runs every statement that is made in a traced invocation in a span of provider.
+func WithTracing(provider trace.TracerProvider) Option {
+	return func(options *databaseOptions) {
+		options.tracerProvider = provider
+	}
+}
+
+// NewDatabase Establish Session connection and, depending on opts, migrate or check the schema
+// before returning Database.Database struct.
+func NewDatabase(connectionString string, logger *slog.Logger, retryCount int, opts ...Option) (Database, error) {
+	var options databaseOptions
+	for _, opt := range opts {
+		opt(&options)
+	}
+
 	logger.Info("Attempting to connect to Database")
 	db, err := retryWithReturn(retryCount, 100*time.Millisecond, func() (*sql.DB, error) {
-		return sql.Open("postgres", connectionString)
+		return open(connectionString, options.tracerProvider)
 	})
 	if err != nil {
 		return Database{}, fmt.Errorf(
@@ -44,9 +83,44 @@ func NewDatabase(connectionString string, logger *slog.Logger, retryCount int) (
 
 	logger.Info("Database connection established")
 
+	if options.migrate || options.schemaCheck {
+		if err = prepareSchema(db, logger, options); err != nil {
+			db.Close()
+			return Database{}, fmt.Errorf("[in NewDatabase]: %w", err)
+		}
+	}
+
 	return Database{Session: db}, nil
 }
 
+// open returns a *sql.DB for connectionString, whose statements are traced if provider is not nil.
+func open(connectionString string, provider trace.TracerProvider) (*sql.DB, error) {
+	if provider == nil {
+		return sql.Open("postgres", connectionString)
+	}
+
+	connector, err := pq.NewConnector(connectionString)
+	if err != nil {
+		return nil, err
+	}
+	return sql.OpenDB(tracing.WrapConnector(connector, provider)), nil
+}
+
+// prepareSchema applies pending migrations or checks that there are none, depending on options.
+func prepareSchema(db *sql.DB, logger *slog.Logger, options databaseOptions) error {
+	migrator, err := NewMigrator(db, logger)
+	if err != nil {
+		return err
+	}
+
+	if options.migrate {
+		logger.Info("Applying database migrations")
+		return migrator.Up(context.Background())
+	}
+
+	return migrator.Check(context.Background())
+}
+
 // retry will retry a given function n times with a wait of a given duration between each retry attempt.
 func retry(retryCount int, waitTime time.Duration, fn func() error) error {
 	_, err := retryWithReturn(retryCount, waitTime, func() (any, error) {
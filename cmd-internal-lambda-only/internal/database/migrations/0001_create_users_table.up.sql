@@ -0,0 +1,8 @@
+CREATE TABLE users
+(
+    id         SERIAL PRIMARY KEY,
+    first_name VARCHAR(50)                                          NOT NULL,
+    last_name  VARCHAR(50)                                          NOT NULL,
+    role       VARCHAR(10) CHECK (role IN ('Customer', 'Employee')) NOT NULL,
+    user_id    INTEGER UNIQUE                                       NOT NULL
+);
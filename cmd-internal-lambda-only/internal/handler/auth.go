@@ -0,0 +1,30 @@
+package handler
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/jha-captech/user-microservice/internal/auth"
+	"github.com/jha-captech/user-microservice/internal/problem"
+)
+
+// Authenticate wraps next for functions behind an API Gateway authorizer, which verifies the token
+// of a request before it reaches the function. The claims of the authorizer context are put into the
+// context passed to next, where they can be read with auth.FromContext. Requests without them are
+// refused, which only happens if the route has no authorizer.
+func (h *Handler) Authenticate(next APIGatewayHandler) APIGatewayHandler {
+	return func(ctx context.Context, request events.APIGatewayProxyRequest) (events.APIGatewayProxyResponse, error) {
+		claims, err := auth.FromAuthorizer(request.RequestContext.Authorizer)
+		if err != nil {
+			h.requestLogger(ctx).Error("Request without authorizer claims", "path", request.Path, "err", err)
+			response, err := h.returnProblem(ctx, request, problem.New(http.StatusUnauthorized, "A bearer token is required"))
+			if response.Headers != nil {
+				response.Headers["WWW-Authenticate"] = "Bearer"
+			}
+			return response, err
+		}
+
+		return next(auth.WithClaims(ctx, claims), request)
+	}
+}
@@ -0,0 +1,137 @@
+package handler
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/jha-captech/user-microservice/internal/auth"
+	"github.com/jha-captech/user-microservice/internal/logging"
+	"github.com/jha-captech/user-microservice/internal/models"
+	"github.com/jha-captech/user-microservice/internal/policy"
+	"github.com/jha-captech/user-microservice/internal/problem"
+	"github.com/jha-captech/user-microservice/internal/user"
+)
+
+// Authorize wraps next so that it is only called for requests whose caller may take action. The
+// caller is the user named by the user_id claim of the authorizer context, see Authenticate, and
+// what they may do is the rule rules has for their role. On functions of a single user, an action
+// the caller may only take on their own record is refused if the user belongs to someone else. The
+// caller is put into the context passed to next as a policy.Principal. Requests without claims,
+// which are only served when authentication is disabled, are passed on without a principal.
+func (h *Handler) Authorize(rules policy.Policy, action policy.Action, next APIGatewayHandler) APIGatewayHandler {
+	return func(ctx context.Context, request events.APIGatewayProxyRequest) (events.APIGatewayProxyResponse, error) {
+		claims, ok := auth.FromContext(ctx)
+		if !ok {
+			return next(ctx, request)
+		}
+
+		// get the caller
+		userID, ok := claims.UserID()
+		if !ok {
+			h.requestLogger(ctx).Error("Token without user_id claim", "subject", claims.Subject)
+			return h.returnProblem(ctx, request, problem.New(http.StatusForbidden, "The bearer token does not identify a user"))
+		}
+		caller, err := h.UserService.FetchUserByUserID(ctx, userID)
+		if err != nil {
+			switch {
+			case errors.Is(err, user.ErrUserNotFound):
+				h.requestLogger(ctx).Error("Token for unknown user", "subject", claims.Subject, "user_id", userID)
+				return h.returnProblem(ctx, request, problem.New(http.StatusForbidden, "The bearer token does not identify a user"))
+			default:
+				h.requestLogger(ctx).Error("Encountered error while getting user of token from the database", "err", err)
+				return h.returnProblem(ctx, request, problem.New(http.StatusInternalServerError, "Internal server error"))
+			}
+		}
+		ctx = logging.With(ctx, "user_id", caller.UserID)
+		principal := rules.Principal(caller.UserID, caller.Role)
+
+		// check the action, and the owner of the user if the action is limited to the own record
+		scope, err := principal.Scope(action)
+		if err == nil && scope == policy.ScopeOwn {
+			owner, ok, ownerErr := h.userOwner(ctx, request.PathParameters["ID"])
+			if ownerErr != nil {
+				h.requestLogger(ctx).Error("Encountered error while getting object from the database", "err", ownerErr)
+				return h.returnProblem(ctx, request, problem.New(http.StatusInternalServerError, "Internal server error"))
+			}
+			if !ok {
+				// next reports the ID as invalid or the user as not existing
+				return next(policy.WithPrincipal(ctx, principal), request)
+			}
+			err = principal.Authorize(action, owner)
+		}
+		if err != nil {
+			h.requestLogger(ctx).Error("Request forbidden by policy", "path", request.Path, "user_id", principal.UserID, "err", err)
+			return h.returnProblem(ctx, request, forbiddenProblem(err))
+		}
+
+		return next(policy.WithPrincipal(ctx, principal), request)
+	}
+}
+
+// userOwner returns the user_id of the user with the ID idString, and false if idString is not a
+// valid ID or no such user exists. Functions of a collection of users have no ID, and their owner
+// is 0, which is never the user_id of a principal.
+func (h *Handler) userOwner(ctx context.Context, idString string) (uint, bool, error) {
+	if idString == "" {
+		return 0, true, nil
+	}
+
+	ID, err := strconv.Atoi(idString)
+	if err != nil {
+		return 0, false, nil
+	}
+
+	found, err := h.UserService.FetchUser(ctx, ID)
+	if err != nil {
+		if errors.Is(err, user.ErrUserNotFound) {
+			return 0, false, nil
+		}
+		return 0, false, err
+	}
+
+	return found.UserID, true, nil
+}
+
+// authorizeChanges returns a *policy.ForbiddenError if updated changes a field of current that is
+// read-only for the principal of ctx. Without a principal, every change is allowed.
+func authorizeChanges(ctx context.Context, current, updated models.User) error {
+	principal, ok := policy.FromContext(ctx)
+	if !ok {
+		return nil
+	}
+
+	var fields []string
+	if updated.FirstName != current.FirstName {
+		fields = append(fields, "first_name")
+	}
+	if updated.LastName != current.LastName {
+		fields = append(fields, "last_name")
+	}
+	if updated.Role != current.Role {
+		fields = append(fields, "role")
+	}
+	if updated.UserID != current.UserID {
+		fields = append(fields, "user_id")
+	}
+	return principal.AuthorizeChanges(fields)
+}
+
+// forbiddenProblem returns the problem reported for err, a *policy.ForbiddenError.
+func forbiddenProblem(err error) problem.Problem {
+	var forbidden *policy.ForbiddenError
+	if !errors.As(err, &forbidden) {
+		return problem.New(http.StatusForbidden, "Forbidden")
+	}
+
+	switch {
+	case forbidden.Field != "":
+		return problem.New(http.StatusForbidden, "You are not allowed to change "+forbidden.Field)
+	case forbidden.Owner:
+		return problem.New(http.StatusForbidden, "You are only allowed to "+string(forbidden.Action)+" your own user")
+	default:
+		return problem.New(http.StatusForbidden, "You are not allowed to "+string(forbidden.Action)+" users")
+	}
+}
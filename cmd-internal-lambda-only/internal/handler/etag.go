@@ -0,0 +1,85 @@
+package handler
+
+import (
+	"strconv"
+	"strings"
+
+	"github.com/aws/aws-lambda-go/events"
+)
+
+// etag returns the strong entity tag of a user at version.
+func etag(version uint) string {
+	return `"` + strconv.FormatUint(uint64(version), 10) + `"`
+}
+
+// ifMatchVersions parses the If-Match header of request. It returns nil when the header is absent
+// or is "*", as any version of the user meets the precondition. Otherwise it returns the versions of
+// the strong entity tags in the header, and ok is false if there are none, in which case the
+// precondition can not be met.
+func ifMatchVersions(request events.APIGatewayProxyRequest) (versions []uint, ok bool) {
+	header := requestHeader(request, "If-Match")
+	if header == "" {
+		return nil, true
+	}
+
+	for _, tag := range entityTags(header) {
+		if tag == "*" {
+			return nil, true
+		}
+		if version, valid := parseETag(tag); valid {
+			versions = append(versions, version)
+		}
+	}
+
+	return versions, len(versions) > 0
+}
+
+// ifNoneMatch reports whether the If-None-Match header of request matches a user at version.
+// Entity tags are compared weakly, as required for If-None-Match.
+func ifNoneMatch(request events.APIGatewayProxyRequest, version uint) bool {
+	header := requestHeader(request, "If-None-Match")
+	if header == "" {
+		return false
+	}
+
+	for _, tag := range entityTags(header) {
+		if tag == "*" || strings.TrimPrefix(tag, "W/") == etag(version) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// requestHeader returns the value of the header name of request. API Gateway passes headers with
+// the case the client sent them in, so name is matched case-insensitively.
+func requestHeader(request events.APIGatewayProxyRequest, name string) string {
+	for key, value := range request.Headers {
+		if strings.EqualFold(key, name) {
+			return value
+		}
+	}
+	return ""
+}
+
+// entityTags splits the value of an If-Match or If-None-Match header into its entity tags.
+func entityTags(header string) []string {
+	tags := strings.Split(header, ",")
+	for i := range tags {
+		tags[i] = strings.TrimSpace(tags[i])
+	}
+	return tags
+}
+
+// parseETag returns the version in a strong entity tag returned by etag.
+func parseETag(tag string) (uint, bool) {
+	if len(tag) < 2 || tag[0] != '"' || tag[len(tag)-1] != '"' {
+		return 0, false
+	}
+
+	version, err := strconv.ParseUint(tag[1:len(tag)-1], 10, 0)
+	if err != nil {
+		return 0, false
+	}
+	return uint(version), true
+}
@@ -5,6 +5,7 @@ import (
 	"log/slog"
 
 	"github.com/aws/aws-lambda-go/events"
+	"github.com/jha-captech/user-microservice/internal/logging"
 	"github.com/jha-captech/user-microservice/internal/user"
 )
 
@@ -21,3 +22,12 @@ func NewHandler(logger *slog.Logger, us user.Service) Handler {
 		UserService: us,
 	}
 }
+
+// requestLogger returns the logger that logging.WrapAPIGateway put into ctx, which logs the request
+// ID, trace ID, route and user of the request with every line, or the logger of h if ctx has none.
+func (h *Handler) requestLogger(ctx context.Context) *slog.Logger {
+	if logger, ok := logging.FromContext(ctx); ok {
+		return logger
+	}
+	return h.logger
+}
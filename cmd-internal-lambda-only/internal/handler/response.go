@@ -1,11 +1,14 @@
 package handler
 
 import (
+	"context"
 	"encoding/json"
 	"net/http"
 
 	"github.com/aws/aws-lambda-go/events"
+	"github.com/jha-captech/user-microservice/internal/logging"
 	"github.com/jha-captech/user-microservice/internal/models"
+	"github.com/jha-captech/user-microservice/internal/problem"
 )
 
 type ResponseMessage struct {
@@ -20,19 +23,39 @@ type ResponseOneUser struct {
 	User models.User `json:"user"`
 }
 
-type ResponseAllUsers struct {
-	Users []models.User `json:"users"`
-}
-type ResponseError struct {
-	Error string `json:"error"`
-}
-
-func (h *Handler) returnJSON(statusCode int, data any) (events.APIGatewayProxyResponse, error) {
+func (h *Handler) returnJSON(ctx context.Context, statusCode int, data any) (events.APIGatewayProxyResponse, error) {
 	JSONData, err := json.Marshal(data)
 	if err != nil {
-		h.logger.Error("Error marshaling return", "err", err, "data", data)
+		h.requestLogger(ctx).Error("Error marshaling return", "err", err, "data", data)
 		return events.APIGatewayProxyResponse{StatusCode: http.StatusInternalServerError}, nil
 	}
 
 	return events.APIGatewayProxyResponse{StatusCode: statusCode, Body: string(JSONData)}, nil
 }
+
+// returnProblem returns p as an application/problem+json response. If not already set, Instance is
+// set to the path of request and RequestID to the ID that logging.WrapAPIGateway gave the request
+// of ctx, or else the API Gateway request ID.
+func (h *Handler) returnProblem(ctx context.Context, request events.APIGatewayProxyRequest, p problem.Problem) (events.APIGatewayProxyResponse, error) {
+	if p.Instance == "" {
+		p.Instance = request.Path
+	}
+	if p.RequestID == "" {
+		p.RequestID = logging.RequestID(ctx)
+	}
+	if p.RequestID == "" {
+		p.RequestID = request.RequestContext.RequestID
+	}
+
+	JSONData, err := json.Marshal(p)
+	if err != nil {
+		h.requestLogger(ctx).Error("Error marshaling problem", "err", err, "problem", p)
+		return events.APIGatewayProxyResponse{StatusCode: http.StatusInternalServerError}, nil
+	}
+
+	return events.APIGatewayProxyResponse{
+		StatusCode: p.Status,
+		Headers:    map[string]string{"Content-Type": problem.ContentType},
+		Body:       string(JSONData),
+	}, nil
+}
@@ -2,107 +2,184 @@ package handler
 
 import (
 	"context"
-	"database/sql"
 	"encoding/json"
 	"errors"
+	"fmt"
 	"net/http"
 	"strconv"
 
 	"github.com/aws/aws-lambda-go/events"
 	"github.com/jha-captech/user-microservice/internal/models"
+	"github.com/jha-captech/user-microservice/internal/policy"
+	"github.com/jha-captech/user-microservice/internal/problem"
+	"github.com/jha-captech/user-microservice/internal/user"
 )
 
-// ListUsersHandler returns a list of all users from the database.
+const (
+	defaultListLimit = 20
+	maxListLimit     = 100
+)
+
+// ListUsersHandler returns a page of users from the database. The `limit` query parameter sets the
+// page size and the `cursor` query parameter takes a `next_cursor` or `prev_cursor` from a previous
+// page.
 func (h *Handler) ListUsersHandler() APIGatewayHandler {
 	return func(ctx context.Context, request events.APIGatewayProxyRequest) (events.APIGatewayProxyResponse, error) {
+		// get and validate query params
+		params := models.ListUsersParams{
+			Limit:  defaultListLimit,
+			Cursor: request.QueryStringParameters["cursor"],
+		}
+		if limitString := request.QueryStringParameters["limit"]; limitString != "" {
+			limit, err := strconv.Atoi(limitString)
+			if err != nil || limit < 1 || limit > maxListLimit {
+				h.requestLogger(ctx).Error("Failed to parse limit from query paramaters", "limit", limitString, "err", err)
+				return h.returnProblem(ctx, request, problem.
+					New(http.StatusBadRequest, "One or more query parameters are invalid").
+					WithFieldErrors(map[string]string{
+						"limit": fmt.Sprintf("must be a number between 1 and %d", maxListLimit),
+					}),
+				)
+			}
+			params.Limit = limit
+		}
+
 		// get values from db
-		users, err := h.UserService.ListUsers()
+		page, err := h.UserService.ListUsers(ctx, params)
 		if err != nil {
-			h.logger.Error("Encountered error while getting objects from the database", "err", err)
-			return h.returnJSON(http.StatusInternalServerError, ResponseError{
-				Error: "Internal server error",
-			})
+			switch {
+			case errors.Is(err, user.ErrInvalidCursor):
+				h.requestLogger(ctx).Error("Invalid cursor", "err", err)
+				return h.returnProblem(ctx, request, problem.New(http.StatusBadRequest, "Invalid or expired cursor"))
+			default:
+				h.requestLogger(ctx).Error("Encountered error while getting objects from the database", "err", err)
+				return h.returnProblem(ctx, request, problem.New(http.StatusInternalServerError, "Internal server error"))
+			}
 		}
 
 		// return response
-		return h.returnJSON(http.StatusOK, ResponseAllUsers{
-			Users: users,
-		})
+		return h.returnJSON(ctx, http.StatusOK, page)
 	}
 }
 
 // FetchUsersHandler returns a single user based on an id passed as a path parameter on the request.
+// The response has the ETag of the user and is 304 Not Modified if it matches If-None-Match.
 func (h *Handler) FetchUsersHandler() APIGatewayHandler {
 	return func(ctx context.Context, request events.APIGatewayProxyRequest) (events.APIGatewayProxyResponse, error) {
 		// get and validate ID
 		idString := request.PathParameters["ID"]
 		ID, err := strconv.Atoi(idString)
 		if err != nil {
-			h.logger.Error("Failed to parse ID from path paramaters", "err", err)
-			return h.returnJSON(http.StatusBadRequest, ResponseError{
-				Error: "Not a valid ID",
-			})
+			h.requestLogger(ctx).Error("Failed to parse ID from path paramaters", "err", err)
+			return h.returnProblem(ctx, request, problem.New(http.StatusBadRequest, "Not a valid ID"))
 		}
 
 		// get value from db
-		user, err := h.UserService.FetchUser(ID)
+		foundUser, err := h.UserService.FetchUser(ctx, ID)
 		if err != nil {
 			switch {
-			case errors.Is(err, sql.ErrNoRows):
-				return h.returnJSON(http.StatusOK, ResponseOneUser{
-					User: models.User{},
-				})
+			case errors.Is(err, user.ErrUserNotFound):
+				h.requestLogger(ctx).Error("Object with given ID does not exist", "ID", ID, "err", err)
+				return h.returnProblem(ctx, request, problem.New(http.StatusNotFound, "Object does not exist"))
 			default:
-				h.logger.Error("Encountered error while getting object from the database", "err", err)
-				return h.returnJSON(http.StatusInternalServerError, ResponseError{
-					Error: "Internal server error",
-				})
+				h.requestLogger(ctx).Error("Encountered error while getting object from the database", "err", err)
+				return h.returnProblem(ctx, request, problem.New(http.StatusInternalServerError, "Internal server error"))
 			}
 		}
 
-		// return response
-		return h.returnJSON(http.StatusOK, ResponseOneUser{
-			User: user,
+		// return response, or nothing if the client has the current version
+		if ifNoneMatch(request, foundUser.Version) {
+			return events.APIGatewayProxyResponse{
+				StatusCode: http.StatusNotModified,
+				Headers:    map[string]string{"ETag": etag(foundUser.Version)},
+			}, nil
+		}
+
+		response, err := h.returnJSON(ctx, http.StatusOK, ResponseOneUser{
+			User: foundUser,
 		})
+		response.Headers = map[string]string{"ETag": etag(foundUser.Version)}
+		return response, err
 	}
 }
 
-// UpdateUsersHandler updates a user by ID.
+// UpdateUsersHandler updates a user by ID. If the request has an If-Match header, the user is only
+// updated if its ETag matches. Changes to fields that are read-only for the caller are refused, and
+// the user is then only updated if it is still at the version the changes were checked against.
 func (h *Handler) UpdateUsersHandler() APIGatewayHandler {
 	return func(ctx context.Context, request events.APIGatewayProxyRequest) (events.APIGatewayProxyResponse, error) {
 		// get and validate ID
 		idString := request.PathParameters["ID"]
 		ID, err := strconv.Atoi(idString)
 		if err != nil {
-			h.logger.Error("Failed to parse ID from path paramaters", "err", err)
-			return h.returnJSON(http.StatusBadRequest, ResponseError{
-				Error: "Not a valid ID",
-			})
+			h.requestLogger(ctx).Error("Failed to parse ID from path paramaters", "err", err)
+			return h.returnProblem(ctx, request, problem.New(http.StatusBadRequest, "Not a valid ID"))
 		}
 
 		// get and validate body as object
 		var inputUser models.User
 		err = json.Unmarshal([]byte(request.Body), &inputUser)
 		if err != nil {
-			h.logger.Error("Failed to unmarshal request body", "err", err)
-			return h.returnJSON(http.StatusBadRequest, ResponseError{
-				Error: "Missing values or malformed body",
-			})
+			h.requestLogger(ctx).Error("Failed to unmarshal request body", "err", err)
+			return h.returnProblem(ctx, request, problem.New(http.StatusBadRequest, "Missing values or malformed body"))
+		}
+
+		// get precondition
+		matchVersions, ok := ifMatchVersions(request)
+		if !ok {
+			h.requestLogger(ctx).Error("If-Match can not be met", "ID", ID, "If-Match", requestHeader(request, "If-Match"))
+			return h.returnProblem(ctx, request, problem.New(http.StatusPreconditionFailed, "User has been modified"))
+		}
+
+		// refuse changes to fields that are read-only for the caller
+		if principal, ok := policy.FromContext(ctx); ok && principal.HasReadOnlyFields() {
+			current, err := h.UserService.FetchUser(ctx, ID)
+			if err != nil {
+				switch {
+				case errors.Is(err, user.ErrUserNotFound):
+					h.requestLogger(ctx).Error("Object with given ID does not exist", "ID", ID, "err", err)
+					return h.returnProblem(ctx, request, problem.New(http.StatusNotFound, "Object does not exist"))
+				default:
+					h.requestLogger(ctx).Error("Encountered error while getting object from the database", "err", err)
+					return h.returnProblem(ctx, request, problem.New(http.StatusInternalServerError, "Internal server error"))
+				}
+			}
+			if err = authorizeChanges(ctx, current, inputUser); err != nil {
+				h.requestLogger(ctx).Error("Request forbidden by policy", "ID", ID, "err", err)
+				return h.returnProblem(ctx, request, forbiddenProblem(err))
+			}
+
+			// the changes were checked against current, so it must not change before the update
+			if len(matchVersions) == 0 {
+				matchVersions = []uint{current.Version}
+			}
 		}
 
 		// update object in db
-		user, err := h.UserService.UpdateUser(ID, inputUser)
+		updatedUser, err := h.UserService.UpdateUser(ctx, ID, inputUser, matchVersions)
 		if err != nil {
-			h.logger.Error("Encountered error while updating object in the database", "err", err)
-			return h.returnJSON(http.StatusInternalServerError, ResponseError{
-				Error: "Internal server error",
-			})
+			switch {
+			case errors.Is(err, user.ErrUserNotFound):
+				h.requestLogger(ctx).Error("Object with given ID does not exist", "ID", ID, "err", err)
+				return h.returnProblem(ctx, request, problem.New(http.StatusNotFound, "Object does not exist"))
+			case errors.Is(err, user.ErrVersionMismatch):
+				h.requestLogger(ctx).Error("Object has been modified", "ID", ID, "err", err)
+				return h.returnProblem(ctx, request, problem.New(http.StatusPreconditionFailed, "User has been modified"))
+			case errors.Is(err, user.ErrUserIDConflict):
+				h.requestLogger(ctx).Error("user_id already in use", "ID", ID, "err", err)
+				return h.returnProblem(ctx, request, problem.New(http.StatusConflict, "user_id already in use"))
+			default:
+				h.requestLogger(ctx).Error("Encountered error while updating object in the database", "err", err)
+				return h.returnProblem(ctx, request, problem.New(http.StatusInternalServerError, "Internal server error"))
+			}
 		}
 
 		// return response
-		return h.returnJSON(http.StatusOK, ResponseOneUser{
-			User: user,
+		response, err := h.returnJSON(ctx, http.StatusOK, ResponseOneUser{
+			User: updatedUser,
 		})
+		response.Headers = map[string]string{"ETag": etag(updatedUser.Version)}
+		return response, err
 	}
 }
 
@@ -113,68 +190,66 @@ func (h *Handler) CreateUsersHandler() APIGatewayHandler {
 		var inputUser models.User
 		err := json.Unmarshal([]byte(request.Body), &inputUser)
 		if err != nil {
-			h.logger.Error("Failed to unmarshal request body", "err", err)
-			return h.returnJSON(http.StatusBadRequest, ResponseError{
-				Error: "Missing values or malformed body",
-			})
+			h.requestLogger(ctx).Error("Failed to unmarshal request body", "err", err)
+			return h.returnProblem(ctx, request, problem.New(http.StatusBadRequest, "Missing values or malformed body"))
 		}
 
 		// create object in db
-		ID, err := h.UserService.CreateUser(inputUser)
+		ID, err := h.UserService.CreateUser(ctx, inputUser)
 		if err != nil {
-			h.logger.Error("Encountered error while creating object in the database", "err", err)
-			return h.returnJSON(http.StatusInternalServerError, ResponseError{
-				Error: "Internal server error",
-			})
+			switch {
+			case errors.Is(err, user.ErrUserIDConflict):
+				h.requestLogger(ctx).Error("user_id already in use", "err", err)
+				return h.returnProblem(ctx, request, problem.New(http.StatusConflict, "user_id already in use"))
+			default:
+				h.requestLogger(ctx).Error("Encountered error while creating object in the database", "err", err)
+				return h.returnProblem(ctx, request, problem.New(http.StatusInternalServerError, "Internal server error"))
+			}
 		}
 
 		// return response
-		return h.returnJSON(http.StatusOK, ResponseID{
+		return h.returnJSON(ctx, http.StatusOK, ResponseID{
 			ObjectID: ID,
 		})
 	}
 }
 
-// DeleteUsersHandler deletes a user by ID.
+// DeleteUsersHandler deletes a user by ID. If the request has an If-Match header, the user is only
+// deleted if its ETag matches.
 func (h *Handler) DeleteUsersHandler() APIGatewayHandler {
 	return func(ctx context.Context, request events.APIGatewayProxyRequest) (events.APIGatewayProxyResponse, error) {
 		// get and validate ID
 		idString := request.PathParameters["ID"]
 		ID, err := strconv.Atoi(idString)
 		if err != nil {
-			h.logger.Error("Failed to parse ID from path paramaters", "err", err)
-			return h.returnJSON(http.StatusBadRequest, ResponseError{
-				Error: "Not a valid ID",
-			})
+			h.requestLogger(ctx).Error("Failed to parse ID from path paramaters", "err", err)
+			return h.returnProblem(ctx, request, problem.New(http.StatusBadRequest, "Not a valid ID"))
 		}
 
-		// check that object exists
-		_, err = h.UserService.FetchUser(ID)
-		if err != nil {
-			switch {
-			case errors.Is(err, sql.ErrNoRows):
-				h.logger.Error("Object with given ID does not exist", "ID", ID, "err", err)
-				return h.returnJSON(http.StatusBadRequest, ResponseError{
-					Error: "Internal server error",
-				})
-			default:
-				h.logger.Error("Encountered error while validating object in the database", "err", err)
-				return h.returnJSON(http.StatusInternalServerError, ResponseError{
-					Error: "Internal server error",
-				})
-			}
+		// get precondition
+		matchVersions, ok := ifMatchVersions(request)
+		if !ok {
+			h.requestLogger(ctx).Error("If-Match can not be met", "ID", ID, "If-Match", requestHeader(request, "If-Match"))
+			return h.returnProblem(ctx, request, problem.New(http.StatusPreconditionFailed, "User has been modified"))
 		}
 
 		// delete returnedUser from db
-		if err = h.UserService.DeleteUser(ID); err != nil {
-			h.logger.Error("Encountered error while deleting object from the database", "err", err)
-			return h.returnJSON(http.StatusInternalServerError, ResponseError{
-				Error: "Internal server error",
-			})
+		if err = h.UserService.DeleteUser(ctx, ID, matchVersions); err != nil {
+			switch {
+			case errors.Is(err, user.ErrUserNotFound):
+				h.requestLogger(ctx).Error("Object with given ID does not exist", "ID", ID, "err", err)
+				return h.returnProblem(ctx, request, problem.New(http.StatusNotFound, "Object does not exist"))
+			case errors.Is(err, user.ErrVersionMismatch):
+				h.requestLogger(ctx).Error("Object has been modified", "ID", ID, "err", err)
+				return h.returnProblem(ctx, request, problem.New(http.StatusPreconditionFailed, "User has been modified"))
+			default:
+				h.requestLogger(ctx).Error("Encountered error while deleting object from the database", "err", err)
+				return h.returnProblem(ctx, request, problem.New(http.StatusInternalServerError, "Internal server error"))
+			}
 		}
 
 		// return response
-		return h.returnJSON(http.StatusOK, ResponseMessage{
+		return h.returnJSON(ctx, http.StatusOK, ResponseMessage{
 			Message: "object successful deleted",
 		})
 	}
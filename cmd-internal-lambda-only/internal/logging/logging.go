@@ -0,0 +1,115 @@
+package logging
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"log/slog"
+	"strings"
+
+	"github.com/aws/aws-lambda-go/events"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// RequestIDHeader is the header the ID of a request is read from, and echoed in on its response.
+const RequestIDHeader = "X-Request-ID"
+
+// maxRequestIDLength is the maximum length of a request ID taken from a request header, longer IDs
+// are replaced.
+const maxRequestIDLength = 128
+
+type (
+	loggerKey    struct{}
+	requestIDKey struct{}
+)
+
+// NewContext returns a copy of ctx carrying logger as the logger of its request.
+func NewContext(ctx context.Context, logger *slog.Logger) context.Context {
+	return context.WithValue(ctx, loggerKey{}, logger)
+}
+
+// FromContext returns the logger of the request of ctx, and whether ctx carries one.
+func FromContext(ctx context.Context) (*slog.Logger, bool) {
+	logger, ok := ctx.Value(loggerKey{}).(*slog.Logger)
+	return logger, ok
+}
+
+// With returns a copy of ctx whose request logger logs args with every line, such as the user of
+// the request once it is known. ctx is returned as is if it carries no logger.
+func With(ctx context.Context, args ...any) context.Context {
+	logger, ok := FromContext(ctx)
+	if !ok {
+		return ctx
+	}
+	return NewContext(ctx, logger.With(args...))
+}
+
+// RequestID returns the ID that WrapAPIGateway gave the request of ctx, or an empty string if it
+// has none.
+func RequestID(ctx context.Context) string {
+	id, _ := ctx.Value(requestIDKey{}).(string)
+	return id
+}
+
+// WrapAPIGateway returns a handler that identifies every invocation of handler and puts a logger
+// into its context that logs the request ID, the trace ID and the resource of the event, such as
+// `/api/user/{ID}`, with every line. The ID is taken from the X-Request-ID header of the event, or
+// else the request ID of API Gateway, and is echoed in the X-Request-ID header of the response. It
+// has to be wrapped by tracing.WrapAPIGateway for the trace ID to be known.
+func WrapAPIGateway(
+	logger *slog.Logger,
+	handler func(ctx context.Context, req events.APIGatewayProxyRequest) (events.APIGatewayProxyResponse, error),
+) func(ctx context.Context, req events.APIGatewayProxyRequest) (events.APIGatewayProxyResponse, error) {
+	return func(ctx context.Context, req events.APIGatewayProxyRequest) (events.APIGatewayProxyResponse, error) {
+		id := requestID(req)
+		ctx = context.WithValue(ctx, requestIDKey{}, id)
+
+		args := []any{"request_id", id}
+		if spanContext := trace.SpanContextFromContext(ctx); spanContext.HasTraceID() {
+			args = append(args, "trace_id", spanContext.TraceID().String())
+		}
+		if req.Resource != "" {
+			args = append(args, "route", req.Resource)
+		}
+
+		resp, err := handler(NewContext(ctx, logger.With(args...)), req)
+		if resp.Headers == nil {
+			resp.Headers = map[string]string{}
+		}
+		resp.Headers[RequestIDHeader] = id
+
+		return resp, err
+	}
+}
+
+// requestID returns the ID of req: its X-Request-ID header if that is a valid ID, or else the
+// request ID of API Gateway, or a new random ID if the event has none, such as in tests.
+func requestID(req events.APIGatewayProxyRequest) string {
+	for key, value := range req.Headers {
+		if strings.EqualFold(key, RequestIDHeader) && validRequestID(value) {
+			return value
+		}
+	}
+	if req.RequestContext.RequestID != "" {
+		return req.RequestContext.RequestID
+	}
+
+	// crypto/rand.Read never fails
+	b := make([]byte, 16)
+	_, _ = rand.Read(b)
+	return hex.EncodeToString(b)
+}
+
+// validRequestID reports whether id can be used as a request ID: it is not empty, not too long and
+// only has printable ASCII characters other than spaces, so it can not forge log lines.
+func validRequestID(id string) bool {
+	if id == "" || len(id) > maxRequestIDLength {
+		return false
+	}
+	for i := 0; i < len(id); i++ {
+		if id[i] <= ' ' || id[i] > '~' {
+			return false
+		}
+	}
+	return true
+}
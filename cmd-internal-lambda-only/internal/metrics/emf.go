@@ -0,0 +1,177 @@
+package metrics
+
+import (
+	"database/sql"
+	"encoding/json"
+	"io"
+	"sync"
+	"time"
+)
+
+// The units of EMF metrics.
+const (
+	unitCount        = "Count"
+	unitMilliseconds = "Milliseconds"
+)
+
+// EMF is a Recorder that writes its metrics to w in the CloudWatch Embedded Metric Format, which
+// CloudWatch Logs turns into metrics when w is the stdout of a Lambda function. Every request is
+// written as one document, with its metrics by route and method and the connection pool stats of
+// the database once it was served. The wait count and wait duration of the pool are written as the
+// increase since the previous request, so that their sum over a period is that of the period.
+type EMF struct {
+	w         io.Writer
+	namespace string
+	function  string
+	db        *sql.DB
+	now       func() time.Time
+
+	mu        sync.Mutex
+	inFlight  int
+	lastStats sql.DBStats
+}
+
+// NewEMF returns a new EMF struct that writes the metrics of the function named function to w,
+// in the CloudWatch namespace namespace. Every metric has the dimension Function, and the metrics
+// of requests also have the dimensions Route and Method.
+func NewEMF(w io.Writer, namespace string, function string, db *sql.DB) *EMF {
+	return &EMF{
+		w:         w,
+		namespace: namespace,
+		function:  function,
+		db:        db,
+		now:       time.Now,
+	}
+}
+
+// emfMetric is a metric of an emfDirective.
+type emfMetric struct {
+	Name string `json:"Name"`
+	Unit string `json:"Unit"`
+}
+
+// emfDirective tells CloudWatch which values of a document are metrics, and by which dimensions.
+type emfDirective struct {
+	Namespace  string      `json:"Namespace"`
+	Dimensions [][]string  `json:"Dimensions"`
+	Metrics    []emfMetric `json:"Metrics"`
+}
+
+// emfMetadata is the `_aws` member of a document.
+type emfMetadata struct {
+	Timestamp         int64          `json:"Timestamp"`
+	CloudWatchMetrics []emfDirective `json:"CloudWatchMetrics"`
+}
+
+func (e *EMF) RequestStarted() {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	e.inFlight++
+}
+
+func (e *EMF) RequestFinished(method string, route string, status int, duration time.Duration) {
+	stats := e.db.Stats()
+	errorCount := 0
+	if isError(status) {
+		errorCount = 1
+	}
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	inFlight := e.inFlight
+	e.inFlight--
+	waitCount := stats.WaitCount - e.lastStats.WaitCount
+	waitDuration := stats.WaitDuration - e.lastStats.WaitDuration
+	e.lastStats = stats
+
+	e.write(
+		[]emfDirective{
+			e.directive(
+				[]string{"Function", "Route", "Method"},
+				emfMetric{Name: "Requests", Unit: unitCount},
+				emfMetric{Name: "Errors", Unit: unitCount},
+				emfMetric{Name: "Latency", Unit: unitMilliseconds},
+			),
+			e.directive(
+				[]string{"Function"},
+				emfMetric{Name: "InFlightRequests", Unit: unitCount},
+				emfMetric{Name: "DBOpenConnections", Unit: unitCount},
+				emfMetric{Name: "DBInUseConnections", Unit: unitCount},
+				emfMetric{Name: "DBIdleConnections", Unit: unitCount},
+				emfMetric{Name: "DBWaitCount", Unit: unitCount},
+				emfMetric{Name: "DBWaitDuration", Unit: unitMilliseconds},
+			),
+		},
+		map[string]any{
+			"Route":              route,
+			"Method":             method,
+			"StatusCode":         status,
+			"Requests":           1,
+			"Errors":             errorCount,
+			"Latency":            milliseconds(duration),
+			"InFlightRequests":   inFlight,
+			"DBOpenConnections":  stats.OpenConnections,
+			"DBInUseConnections": stats.InUse,
+			"DBIdleConnections":  stats.Idle,
+			"DBWaitCount":        waitCount,
+			"DBWaitDuration":     milliseconds(waitDuration),
+		},
+	)
+}
+
+func (e *EMF) UsersCreated(count int) {
+	e.writeCount("UsersCreated", count)
+}
+
+func (e *EMF) UsersUpdated(count int) {
+	e.writeCount("UsersUpdated", count)
+}
+
+func (e *EMF) UsersDeleted(count int) {
+	e.writeCount("UsersDeleted", count)
+}
+
+// writeCount writes a document with count as the metric name. Nothing is written for a count of 0.
+func (e *EMF) writeCount(name string, count int) {
+	if count == 0 {
+		return
+	}
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	e.write(
+		[]emfDirective{e.directive([]string{"Function"}, emfMetric{Name: name, Unit: unitCount})},
+		map[string]any{name: count},
+	)
+}
+
+// directive returns a directive in the namespace of e for metrics by dimensions.
+func (e *EMF) directive(dimensions []string, metrics ...emfMetric) emfDirective {
+	return emfDirective{
+		Namespace:  e.namespace,
+		Dimensions: [][]string{dimensions},
+		Metrics:    metrics,
+	}
+}
+
+// write writes a document of values, the Function dimension and the metadata of directives as a
+// single line. e.mu must be held, so that documents are not interleaved.
+func (e *EMF) write(directives []emfDirective, values map[string]any) {
+	values["_aws"] = emfMetadata{
+		Timestamp:         e.now().UnixMilli(),
+		CloudWatchMetrics: directives,
+	}
+	values["Function"] = e.function
+
+	// a document of strings and numbers always encodes
+	document, _ := json.Marshal(values)
+	_, _ = e.w.Write(append(document, '\n'))
+}
+
+// milliseconds returns d in milliseconds.
+func milliseconds(d time.Duration) float64 {
+	return float64(d) / float64(time.Millisecond)
+}
@@ -0,0 +1,64 @@
+package problem
+
+import (
+	"net/http"
+	"sort"
+)
+
+// ContentType is the media type of a problem details response as defined by RFC 7807.
+const ContentType = "application/problem+json"
+
+// FieldError is a single violation of a field in a request body or query.
+type FieldError struct {
+	Field  string `json:"field"`
+	Detail string `json:"detail"`
+}
+
+// Problem is an RFC 7807 problem details object.
+type Problem struct {
+	Type      string       `json:"type"`
+	Title     string       `json:"title"`
+	Status    int          `json:"status"`
+	Detail    string       `json:"detail,omitempty"`
+	Instance  string       `json:"instance,omitempty"`
+	Errors    []FieldError `json:"errors,omitempty"`
+	RequestID string       `json:"request_id,omitempty"`
+}
+
+// New returns a Problem for status with a human-readable detail. The type is "about:blank" and the
+// title is the standard text of status, as recommended by RFC 7807 for problems that have no more
+// specific type.
+func New(status int, detail string) Problem {
+	return Problem{
+		Type:   "about:blank",
+		Title:  http.StatusText(status),
+		Status: status,
+		Detail: detail,
+	}
+}
+
+// WithFieldErrors returns a copy of p with the given field violations, keyed by field name, added
+// to Errors. Errors are sorted by field so that responses are stable.
+func (p Problem) WithFieldErrors(problems map[string]string) Problem {
+	errs := make([]FieldError, 0, len(p.Errors)+len(problems))
+	errs = append(errs, p.Errors...)
+	for field, detail := range problems {
+		errs = append(errs, FieldError{Field: field, Detail: detail})
+	}
+	sort.SliceStable(errs, func(i, j int) bool { return errs[i].Field < errs[j].Field })
+
+	p.Errors = errs
+	return p
+}
+
+// WithInstance returns a copy of p with Instance set to instance.
+func (p Problem) WithInstance(instance string) Problem {
+	p.Instance = instance
+	return p
+}
+
+// WithRequestID returns a copy of p with RequestID set to requestID.
+func (p Problem) WithRequestID(requestID string) Problem {
+	p.RequestID = requestID
+	return p
+}
@@ -0,0 +1,316 @@
+package tracing
+
+import (
+	"context"
+	"database/sql/driver"
+	"errors"
+	"io"
+	"reflect"
+	"strings"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// returnedRowsKey is the number of rows that a statement returned or affected.
+const returnedRowsKey = attribute.Key("db.response.returned_rows")
+
+// WrapConnector returns a connector whose connections run every statement in a span of provider,
+// with the statement and the number of rows it returned or affected. Spans are only started for
+// statements whose context already has a span, so background work such as migrations does not
+// start traces of its own.
+func WrapConnector(connector driver.Connector, provider trace.TracerProvider) driver.Connector {
+	return &tracedConnector{
+		Connector: connector,
+		tracer:    provider.Tracer(instrumentationName),
+	}
+}
+
+type tracedConnector struct {
+	driver.Connector
+	tracer trace.Tracer
+}
+
+func (c *tracedConnector) Connect(ctx context.Context) (driver.Conn, error) {
+	conn, err := c.Connector.Connect(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return &tracedConn{Conn: conn, tracer: c.tracer}, nil
+}
+
+// startSpan starts the span of query, or returns a nil span if ctx has no span to be a child of.
+func startSpan(ctx context.Context, tracer trace.Tracer, query string) (context.Context, trace.Span) {
+	if !trace.SpanContextFromContext(ctx).IsValid() {
+		return ctx, nil
+	}
+
+	operation := "query"
+	if fields := strings.Fields(query); len(fields) > 0 {
+		operation = strings.ToUpper(fields[0])
+	}
+
+	return tracer.Start(
+		ctx,
+		operation,
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(
+			semconv.DBSystemPostgreSQL,
+			semconv.DBQueryText(query),
+			semconv.DBOperationName(operation),
+		),
+	)
+}
+
+// endSpan ends span with the outcome of its statement. A nil span is ignored.
+func endSpan(span trace.Span, rows int64, err error) {
+	if span == nil {
+		return
+	}
+	if err != nil && !errors.Is(err, driver.ErrSkip) {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	} else {
+		span.SetAttributes(returnedRowsKey.Int64(rows))
+	}
+	span.End()
+}
+
+// affectedRows returns the number of rows that result affected, or 0 if the driver does not know.
+func affectedRows(result driver.Result) int64 {
+	if result == nil {
+		return 0
+	}
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return 0
+	}
+	return rows
+}
+
+// tracedConn is a driver.Conn that traces its statements. It implements the optional interfaces
+// of database/sql by falling back to the wrapped connection, or returning driver.ErrSkip where
+// database/sql falls back itself.
+type tracedConn struct {
+	driver.Conn
+	tracer trace.Tracer
+}
+
+func (c *tracedConn) Prepare(query string) (driver.Stmt, error) {
+	return c.PrepareContext(context.Background(), query)
+}
+
+func (c *tracedConn) PrepareContext(ctx context.Context, query string) (driver.Stmt, error) {
+	var (
+		stmt driver.Stmt
+		err  error
+	)
+	if preparer, ok := c.Conn.(driver.ConnPrepareContext); ok {
+		stmt, err = preparer.PrepareContext(ctx, query)
+	} else {
+		stmt, err = c.Conn.Prepare(query)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &tracedStmt{Stmt: stmt, tracer: c.tracer, query: query}, nil
+}
+
+func (c *tracedConn) BeginTx(ctx context.Context, opts driver.TxOptions) (driver.Tx, error) {
+	if beginner, ok := c.Conn.(driver.ConnBeginTx); ok {
+		return beginner.BeginTx(ctx, opts)
+	}
+	// Begin is the fallback for drivers without BeginTx.
+	return c.Conn.Begin()
+}
+
+func (c *tracedConn) QueryContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Rows, error) {
+	queryer, ok := c.Conn.(driver.QueryerContext)
+	if !ok {
+		return nil, driver.ErrSkip
+	}
+
+	ctx, span := startSpan(ctx, c.tracer, query)
+	rows, err := queryer.QueryContext(ctx, query, args)
+	if err != nil {
+		endSpan(span, 0, err)
+		return nil, err
+	}
+	if span == nil {
+		return rows, nil
+	}
+	return &tracedRows{Rows: rows, span: span}, nil
+}
+
+func (c *tracedConn) ExecContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Result, error) {
+	execer, ok := c.Conn.(driver.ExecerContext)
+	if !ok {
+		return nil, driver.ErrSkip
+	}
+
+	ctx, span := startSpan(ctx, c.tracer, query)
+	result, err := execer.ExecContext(ctx, query, args)
+	endSpan(span, affectedRows(result), err)
+	return result, err
+}
+
+func (c *tracedConn) Ping(ctx context.Context) error {
+	if pinger, ok := c.Conn.(driver.Pinger); ok {
+		return pinger.Ping(ctx)
+	}
+	return nil
+}
+
+func (c *tracedConn) ResetSession(ctx context.Context) error {
+	if resetter, ok := c.Conn.(driver.SessionResetter); ok {
+		return resetter.ResetSession(ctx)
+	}
+	return nil
+}
+
+func (c *tracedConn) IsValid() bool {
+	if validator, ok := c.Conn.(driver.Validator); ok {
+		return validator.IsValid()
+	}
+	return true
+}
+
+func (c *tracedConn) CheckNamedValue(value *driver.NamedValue) error {
+	if checker, ok := c.Conn.(driver.NamedValueChecker); ok {
+		return checker.CheckNamedValue(value)
+	}
+	return driver.ErrSkip
+}
+
+// tracedStmt is a driver.Stmt that traces its executions.
+type tracedStmt struct {
+	driver.Stmt
+	tracer trace.Tracer
+	query  string
+}
+
+func (s *tracedStmt) ExecContext(ctx context.Context, args []driver.NamedValue) (driver.Result, error) {
+	ctx, span := startSpan(ctx, s.tracer, s.query)
+
+	var (
+		result driver.Result
+		err    error
+	)
+	if execer, ok := s.Stmt.(driver.StmtExecContext); ok {
+		result, err = execer.ExecContext(ctx, args)
+	} else {
+		// Exec is the fallback for drivers without ExecContext.
+		result, err = s.Stmt.Exec(values(args))
+	}
+
+	endSpan(span, affectedRows(result), err)
+	return result, err
+}
+
+func (s *tracedStmt) QueryContext(ctx context.Context, args []driver.NamedValue) (driver.Rows, error) {
+	ctx, span := startSpan(ctx, s.tracer, s.query)
+
+	var (
+		rows driver.Rows
+		err  error
+	)
+	if queryer, ok := s.Stmt.(driver.StmtQueryContext); ok {
+		rows, err = queryer.QueryContext(ctx, args)
+	} else {
+		// Query is the fallback for drivers without QueryContext.
+		rows, err = s.Stmt.Query(values(args))
+	}
+
+	if err != nil {
+		endSpan(span, 0, err)
+		return nil, err
+	}
+	if span == nil {
+		return rows, nil
+	}
+	return &tracedRows{Rows: rows, span: span}, nil
+}
+
+func (s *tracedStmt) CheckNamedValue(value *driver.NamedValue) error {
+	if checker, ok := s.Stmt.(driver.NamedValueChecker); ok {
+		return checker.CheckNamedValue(value)
+	}
+	return driver.ErrSkip
+}
+
+// values returns the values of args, for drivers that predate named values.
+func values(args []driver.NamedValue) []driver.Value {
+	vals := make([]driver.Value, len(args))
+	for i, arg := range args {
+		vals[i] = arg.Value
+	}
+	return vals
+}
+
+// tracedRows is a driver.Rows that counts its rows and ends the span of its query once closed.
+type tracedRows struct {
+	driver.Rows
+	span  trace.Span
+	count int64
+	err   error
+}
+
+func (r *tracedRows) Next(dest []driver.Value) error {
+	err := r.Rows.Next(dest)
+	switch {
+	case err == nil:
+		r.count++
+	case !errors.Is(err, io.EOF):
+		r.err = err
+	}
+	return err
+}
+
+func (r *tracedRows) Close() error {
+	err := r.Rows.Close()
+	if r.span != nil {
+		endSpan(r.span, r.count, r.err)
+		r.span = nil
+	}
+	return err
+}
+
+// The column types of the wrapped rows, with the defaults of database/sql for drivers that do not
+// report them.
+
+func (r *tracedRows) ColumnTypeScanType(index int) reflect.Type {
+	if rows, ok := r.Rows.(driver.RowsColumnTypeScanType); ok {
+		return rows.ColumnTypeScanType(index)
+	}
+	return reflect.TypeOf(new(any)).Elem()
+}
+
+func (r *tracedRows) ColumnTypeDatabaseTypeName(index int) string {
+	if rows, ok := r.Rows.(driver.RowsColumnTypeDatabaseTypeName); ok {
+		return rows.ColumnTypeDatabaseTypeName(index)
+	}
+	return ""
+}
+
+func (r *tracedRows) ColumnTypeLength(index int) (int64, bool) {
+	if rows, ok := r.Rows.(driver.RowsColumnTypeLength); ok {
+		return rows.ColumnTypeLength(index)
+	}
+	return 0, false
+}
+
+func (r *tracedRows) ColumnTypeNullable(index int) (bool, bool) {
+	if rows, ok := r.Rows.(driver.RowsColumnTypeNullable); ok {
+		return rows.ColumnTypeNullable(index)
+	}
+	return false, false
+}
+
+func (r *tracedRows) ColumnTypePrecisionScale(index int) (int64, int64, bool) {
+	if rows, ok := r.Rows.(driver.RowsColumnTypePrecisionScale); ok {
+		return rows.ColumnTypePrecisionScale(index)
+	}
+	return 0, 0, false
+}
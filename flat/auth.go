@@ -0,0 +1,659 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"crypto"
+	"crypto/ecdh"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"math/big"
+	"net/http"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ── Claims ───────────────────────────────────────────────────────────────────────────────────────
+
+// Claims are the claims of a verified token. Registered claims have fields of their own, and every
+// claim, registered or not, is kept in Raw for claims specific to an issuer.
+type Claims struct {
+	Subject   string
+	Issuer    string
+	Audience  []string
+	ExpiresAt time.Time
+	NotBefore time.Time
+	IssuedAt  time.Time
+	Raw       map[string]any
+}
+
+type claimsKey struct{}
+
+// withClaims returns a copy of ctx carrying claims as the claims of the caller making the request.
+func withClaims(ctx context.Context, claims Claims) context.Context {
+	return context.WithValue(ctx, claimsKey{}, claims)
+}
+
+// ClaimsFromContext returns the claims AuthMiddleware put into ctx, and whether there are any.
+func ClaimsFromContext(ctx context.Context) (Claims, bool) {
+	claims, ok := ctx.Value(claimsKey{}).(Claims)
+	return claims, ok
+}
+
+// UserID returns the user_id claim, which names the user_id of the user the token was issued to,
+// and whether it is set to a positive integer. Numeric strings are accepted as well as numbers.
+func (c Claims) UserID() (uint, bool) {
+	var text string
+	switch value := c.Raw["user_id"].(type) {
+	case json.Number:
+		text = value.String()
+	case float64:
+		text = strconv.FormatFloat(value, 'f', -1, 64)
+	case string:
+		text = value
+	default:
+		return 0, false
+	}
+
+	userID, err := strconv.ParseUint(text, 10, 0)
+	if err != nil || userID == 0 {
+		return 0, false
+	}
+	return uint(userID), true
+}
+
+// newClaims returns the Claims of the claim set raw.
+func newClaims(raw map[string]any) (Claims, error) {
+	claims := Claims{Raw: raw}
+	claims.Subject, _ = raw["sub"].(string)
+	claims.Issuer, _ = raw["iss"].(string)
+
+	// aud is either a single audience or a list of them
+	switch aud := raw["aud"].(type) {
+	case string:
+		claims.Audience = []string{aud}
+	case []any:
+		for _, item := range aud {
+			if item, ok := item.(string); ok {
+				claims.Audience = append(claims.Audience, item)
+			}
+		}
+	}
+
+	times := map[string]*time.Time{
+		"exp": &claims.ExpiresAt,
+		"nbf": &claims.NotBefore,
+		"iat": &claims.IssuedAt,
+	}
+	for name, field := range times {
+		value, ok := raw[name]
+		if !ok {
+			continue
+		}
+		number, ok := value.(json.Number)
+		if !ok {
+			return Claims{}, fmt.Errorf("claim %q is not a NumericDate", name)
+		}
+		seconds, err := number.Float64()
+		if err != nil {
+			return Claims{}, fmt.Errorf("claim %q is not a NumericDate", name)
+		}
+		*field = time.Unix(int64(seconds), 0)
+	}
+
+	return claims, nil
+}
+
+// ── Keys ─────────────────────────────────────────────────────────────────────────────────────────
+
+// ErrKeyNotFound is returned by a KeySource that has no key with the ID a token was signed with.
+var ErrKeyNotFound = errors.New("signing key not found")
+
+// KeySource provides the public keys that tokens are verified with.
+type KeySource interface {
+	// Key returns the public key with ID kid, which is an *rsa.PublicKey or an *ecdsa.PublicKey.
+	Key(ctx context.Context, kid string) (crypto.PublicKey, error)
+}
+
+// StaticKeys is a KeySource for a fixed set of keys by key ID, such as the keys of a key file.
+type StaticKeys map[string]crypto.PublicKey
+
+// Key returns the key with ID kid.
+func (keys StaticKeys) Key(_ context.Context, kid string) (crypto.PublicKey, error) {
+	key, ok := lookupKey(keys, kid)
+	if !ok {
+		return nil, fmt.Errorf("in StaticKeys.Key: kid %q: %w", kid, ErrKeyNotFound)
+	}
+	return key, nil
+}
+
+// LoadKeyFile returns the keys of the file at path, which is either a JWK Set or PEM encoded public
+// keys or certificates. Keys in PEM files have no key ID, so a PEM file should hold a single key.
+func LoadKeyFile(path string) (StaticKeys, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("in LoadKeyFile: %w", err)
+	}
+
+	var keys StaticKeys
+	if block, _ := pem.Decode(data); block != nil {
+		keys, err = parsePEM(data)
+	} else {
+		keys, err = parseJWKS(data)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("in LoadKeyFile: %s: %w", path, err)
+	}
+	if len(keys) == 0 {
+		return nil, fmt.Errorf("in LoadKeyFile: %s: no RS256 or ES256 keys", path)
+	}
+
+	return keys, nil
+}
+
+// JWKS is a KeySource for the JWK Set published by an issuer. The set is cached and fetched again
+// once it is older than the refresh interval, or when a token is signed with a key that is not in
+// it, so that keys the issuer rotates in are picked up. Fetching for unknown keys is rate limited,
+// so that tokens with made up key IDs can not be used to flood the issuer. Concurrent requests
+// share a single fetch, which runs without holding the cache lock.
+type JWKS struct {
+	url        string
+	client     *http.Client
+	refresh    time.Duration
+	minRefresh time.Duration
+	now        func() time.Time
+
+	mu        sync.RWMutex
+	keys      StaticKeys
+	fetched   time.Time
+	attempted time.Time
+	inFlight  *jwksFetch
+}
+
+// jwksFetch is a fetch of the JWK Set that is in progress. done is closed once err is set.
+type jwksFetch struct {
+	done chan struct{}
+	err  error
+}
+
+// jwksFetchTimeout is how long a fetch of the JWK Set may take. A fetch is detached from the
+// request that started it, as other requests may be waiting for it too.
+const jwksFetchTimeout = 10 * time.Second
+
+// JWKSOption is a function that configures a JWKS.
+type JWKSOption func(*JWKS)
+
+// WithRefreshInterval sets how long a fetched JWK Set is used before it is fetched again. The
+// default is 1 hour.
+func WithRefreshInterval(refresh time.Duration) JWKSOption {
+	return func(j *JWKS) {
+		j.refresh = refresh
+	}
+}
+
+// NewJWKS returns a new JWKS that fetches the JWK Set at url when it is first used.
+func NewJWKS(url string, opts ...JWKSOption) *JWKS {
+	jwks := &JWKS{
+		url:        url,
+		client:     &http.Client{Timeout: jwksFetchTimeout},
+		refresh:    time.Hour,
+		minRefresh: time.Minute,
+		now:        time.Now,
+	}
+	for _, opt := range opts {
+		opt(jwks)
+	}
+	return jwks
+}
+
+// Key returns the key with ID kid, fetching the JWK Set if it is stale or does not have the key. If
+// fetching a stale set fails, the cached keys are used until the issuer can be reached again, which
+// is tried again no sooner than the minimum refresh interval.
+func (j *JWKS) Key(ctx context.Context, kid string) (crypto.PublicKey, error) {
+	now := j.now()
+	keys, fetched, attempted := j.cached()
+
+	stale := now.Sub(fetched) >= j.refresh && now.Sub(attempted) >= j.minRefresh
+	if keys == nil || stale {
+		err := j.fetch(ctx)
+		keys, _, attempted = j.cached()
+		if err != nil && keys == nil {
+			return nil, fmt.Errorf("in JWKS.Key: %w", err)
+		}
+	}
+
+	key, ok := lookupKey(keys, kid)
+	if !ok && now.Sub(attempted) >= j.minRefresh {
+		if err := j.fetch(ctx); err != nil {
+			return nil, fmt.Errorf("in JWKS.Key: %w", err)
+		}
+		keys, _, _ = j.cached()
+		key, ok = lookupKey(keys, kid)
+	}
+	if !ok {
+		return nil, fmt.Errorf("in JWKS.Key: kid %q: %w", kid, ErrKeyNotFound)
+	}
+
+	return key, nil
+}
+
+// cached returns the cached keys of j and when they were last fetched and attempted to be fetched.
+func (j *JWKS) cached() (StaticKeys, time.Time, time.Time) {
+	j.mu.RLock()
+	defer j.mu.RUnlock()
+	return j.keys, j.fetched, j.attempted
+}
+
+// fetch replaces the cached keys with the JWK Set at the URL of j. If a fetch is already in
+// progress, it waits for that fetch instead of starting another one. It stops waiting when ctx is
+// done, but the fetch itself carries on so that its result is cached for later requests.
+func (j *JWKS) fetch(ctx context.Context) error {
+	j.mu.Lock()
+	call := j.inFlight
+	if call == nil {
+		call = &jwksFetch{done: make(chan struct{})}
+		j.inFlight = call
+		go j.doFetch(context.WithoutCancel(ctx), call)
+	}
+	j.mu.Unlock()
+
+	select {
+	case <-call.done:
+		return call.err
+	case <-ctx.Done():
+		return fmt.Errorf("fetch JWK Set: %w", ctx.Err())
+	}
+}
+
+// doFetch runs call, caching the keys it gets.
+func (j *JWKS) doFetch(ctx context.Context, call *jwksFetch) {
+	now := j.now()
+	keys, err := j.get(ctx)
+
+	j.mu.Lock()
+	j.attempted = now
+	if err == nil {
+		j.keys = keys
+		j.fetched = now
+	}
+	j.inFlight = nil
+	j.mu.Unlock()
+
+	call.err = err
+	close(call.done)
+}
+
+// get returns the keys of the JWK Set at the URL of j.
+func (j *JWKS) get(ctx context.Context) (StaticKeys, error) {
+	ctx, cancel := context.WithTimeout(ctx, jwksFetchTimeout)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, j.url, nil)
+	if err != nil {
+		return nil, fmt.Errorf("fetch JWK Set: %w", err)
+	}
+	req.Header.Set("Accept", "application/json")
+
+	resp, err := j.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("fetch JWK Set: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("fetch JWK Set: unexpected status %d", resp.StatusCode)
+	}
+
+	var set jwkSet
+	if err = json.NewDecoder(resp.Body).Decode(&set); err != nil {
+		return nil, fmt.Errorf("decode JWK Set: %w", err)
+	}
+	keys, err := set.keys()
+	if err != nil {
+		return nil, fmt.Errorf("decode JWK Set: %w", err)
+	}
+	return keys, nil
+}
+
+// lookupKey returns the key with ID kid. A set with a single key without an ID is used for every kid,
+// as that is what a PEM key file gives.
+func lookupKey(keys StaticKeys, kid string) (crypto.PublicKey, bool) {
+	if key, ok := keys[kid]; ok {
+		return key, true
+	}
+	if key, ok := keys[""]; ok && len(keys) == 1 {
+		return key, true
+	}
+	return nil, false
+}
+
+// jwk is a JSON Web Key, as defined in RFC 7517, with the parameters of RSA and EC keys.
+type jwk struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	Use string `json:"use"`
+	Alg string `json:"alg"`
+	N   string `json:"n"`
+	E   string `json:"e"`
+	Crv string `json:"crv"`
+	X   string `json:"x"`
+	Y   string `json:"y"`
+}
+
+type jwkSet struct {
+	Keys []jwk `json:"keys"`
+}
+
+// keys returns the signing keys of set that tokens can be verified with. Keys for encryption or
+// other algorithms are skipped, as issuers commonly publish them in the same set.
+func (set jwkSet) keys() (StaticKeys, error) {
+	keys := make(StaticKeys, len(set.Keys))
+	for _, key := range set.Keys {
+		if key.Use != "" && key.Use != "sig" {
+			continue
+		}
+
+		var (
+			public crypto.PublicKey
+			err    error
+		)
+		switch {
+		case key.Kty == "RSA" && (key.Alg == "" || key.Alg == algRS256):
+			public, err = key.rsa()
+		case key.Kty == "EC" && key.Crv == "P-256" && (key.Alg == "" || key.Alg == algES256):
+			public, err = key.ecdsa()
+		default:
+			continue
+		}
+		if err != nil {
+			return nil, fmt.Errorf("key %q: %w", key.Kid, err)
+		}
+		keys[key.Kid] = public
+	}
+	return keys, nil
+}
+
+func (key jwk) rsa() (*rsa.PublicKey, error) {
+	n, err := decodeInt(key.N)
+	if err != nil {
+		return nil, fmt.Errorf("modulus: %w", err)
+	}
+	e, err := decodeInt(key.E)
+	if err != nil {
+		return nil, fmt.Errorf("exponent: %w", err)
+	}
+	if !e.IsInt64() || e.Int64() < 3 || e.Int64() > 1<<31-1 {
+		return nil, errors.New("exponent out of range")
+	}
+	return &rsa.PublicKey{N: n, E: int(e.Int64())}, nil
+}
+
+func (key jwk) ecdsa() (*ecdsa.PublicKey, error) {
+	x, err := decodeInt(key.X)
+	if err != nil {
+		return nil, fmt.Errorf("x coordinate: %w", err)
+	}
+	y, err := decodeInt(key.Y)
+	if err != nil {
+		return nil, fmt.Errorf("y coordinate: %w", err)
+	}
+
+	if x.BitLen() > 256 || y.BitLen() > 256 {
+		return nil, errors.New("coordinates out of range")
+	}
+
+	// the point is checked by parsing its uncompressed encoding, which fails if it is not on the curve
+	point := make([]byte, 65)
+	point[0] = 4
+	x.FillBytes(point[1:33])
+	y.FillBytes(point[33:])
+	if _, err = ecdh.P256().NewPublicKey(point); err != nil {
+		return nil, err
+	}
+	return &ecdsa.PublicKey{Curve: elliptic.P256(), X: x, Y: y}, nil
+}
+
+func decodeInt(value string) (*big.Int, error) {
+	data, err := base64.RawURLEncoding.DecodeString(value)
+	if err != nil {
+		return nil, err
+	}
+	if len(data) == 0 {
+		return nil, errors.New("missing")
+	}
+	return new(big.Int).SetBytes(data), nil
+}
+
+// parseJWKS returns the signing keys of the JWK Set data.
+func parseJWKS(data []byte) (StaticKeys, error) {
+	var set jwkSet
+	if err := json.Unmarshal(data, &set); err != nil {
+		return nil, err
+	}
+	return set.keys()
+}
+
+// parsePEM returns the RSA and P-256 keys of the PEM encoded public keys and certificates in data.
+func parsePEM(data []byte) (StaticKeys, error) {
+	keys := StaticKeys{}
+	for {
+		var block *pem.Block
+		block, data = pem.Decode(data)
+		if block == nil {
+			break
+		}
+
+		var (
+			public any
+			err    error
+		)
+		switch block.Type {
+		case "PUBLIC KEY":
+			public, err = x509.ParsePKIXPublicKey(block.Bytes)
+		case "RSA PUBLIC KEY":
+			public, err = x509.ParsePKCS1PublicKey(block.Bytes)
+		case "CERTIFICATE":
+			var cert *x509.Certificate
+			if cert, err = x509.ParseCertificate(block.Bytes); err == nil {
+				public = cert.PublicKey
+			}
+		default:
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		switch public := public.(type) {
+		case *rsa.PublicKey:
+		case *ecdsa.PublicKey:
+			if public.Curve != elliptic.P256() {
+				continue
+			}
+		default:
+			continue
+		}
+		if _, ok := keys[""]; ok {
+			return nil, errors.New("more than one key, use a JWK Set to give them key IDs")
+		}
+		keys[""] = public
+	}
+	return keys, nil
+}
+
+// ── Verifier ─────────────────────────────────────────────────────────────────────────────────────
+
+// ErrInvalidToken is returned by Verifier.Verify for a token that is malformed, is not signed by a
+// trusted key or has claims that are not accepted. Other errors mean that the token could not be
+// verified, such as when the JWK Set can not be fetched.
+var ErrInvalidToken = errors.New("invalid token")
+
+const (
+	algRS256 = "RS256"
+	algES256 = "ES256"
+)
+
+type jwtHeader struct {
+	Alg string `json:"alg"`
+	Kid string `json:"kid"`
+}
+
+// Verifier verifies JSON Web Tokens signed with RS256 or ES256, and checks that they were issued by
+// the expected issuer for the expected audience and are currently valid.
+type Verifier struct {
+	keys     KeySource
+	issuer   string
+	audience string
+	leeway   time.Duration
+	now      func() time.Time
+}
+
+// VerifierOption is a function that configures a Verifier.
+type VerifierOption func(*Verifier)
+
+// WithLeeway sets how much clock skew between the issuer and the service is tolerated when checking
+// the exp and nbf claims. The default is 1 minute.
+func WithLeeway(leeway time.Duration) VerifierOption {
+	return func(v *Verifier) {
+		v.leeway = leeway
+	}
+}
+
+// NewVerifier returns a new Verifier that verifies tokens with the keys of keys, and accepts them
+// if their iss claim is issuer and their aud claim includes audience.
+func NewVerifier(keys KeySource, issuer string, audience string, opts ...VerifierOption) *Verifier {
+	verifier := &Verifier{
+		keys:     keys,
+		issuer:   issuer,
+		audience: audience,
+		leeway:   time.Minute,
+		now:      time.Now,
+	}
+	for _, opt := range opts {
+		opt(verifier)
+	}
+	return verifier
+}
+
+// Verify verifies the compact serialized token and returns its claims. The exp claim is required,
+// while nbf is only checked if it is present.
+func (v *Verifier) Verify(ctx context.Context, token string) (Claims, error) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return Claims{}, fmt.Errorf("in Verifier.Verify: not a JWS compact serialization: %w", ErrInvalidToken)
+	}
+
+	var head jwtHeader
+	if err := decodeSegment(parts[0], &head); err != nil {
+		return Claims{}, fmt.Errorf("in Verifier.Verify: header: %w: %w", ErrInvalidToken, err)
+	}
+	if head.Alg != algRS256 && head.Alg != algES256 {
+		return Claims{}, fmt.Errorf("in Verifier.Verify: algorithm %q not allowed: %w", head.Alg, ErrInvalidToken)
+	}
+
+	signature, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil {
+		return Claims{}, fmt.Errorf("in Verifier.Verify: signature: %w: %w", ErrInvalidToken, err)
+	}
+	key, err := v.keys.Key(ctx, head.Kid)
+	if err != nil {
+		if errors.Is(err, ErrKeyNotFound) {
+			return Claims{}, fmt.Errorf("in Verifier.Verify: %w: %w", ErrInvalidToken, err)
+		}
+		return Claims{}, fmt.Errorf("in Verifier.Verify: %w", err)
+	}
+	if err = verifySignature(head.Alg, key, parts[0]+"."+parts[1], signature); err != nil {
+		return Claims{}, fmt.Errorf("in Verifier.Verify: %w: %w", ErrInvalidToken, err)
+	}
+
+	var raw map[string]any
+	if err = decodeSegment(parts[1], &raw); err != nil {
+		return Claims{}, fmt.Errorf("in Verifier.Verify: claims: %w: %w", ErrInvalidToken, err)
+	}
+	claims, err := newClaims(raw)
+	if err != nil {
+		return Claims{}, fmt.Errorf("in Verifier.Verify: %w: %w", ErrInvalidToken, err)
+	}
+	if err = v.check(claims); err != nil {
+		return Claims{}, fmt.Errorf("in Verifier.Verify: %w: %w", ErrInvalidToken, err)
+	}
+
+	return claims, nil
+}
+
+// check checks the registered claims of a token with a valid signature.
+func (v *Verifier) check(claims Claims) error {
+	now := v.now()
+
+	if claims.Issuer != v.issuer {
+		return fmt.Errorf("issuer %q not accepted", claims.Issuer)
+	}
+	if !slices.Contains(claims.Audience, v.audience) {
+		return fmt.Errorf("audience %q not accepted", claims.Audience)
+	}
+	if claims.ExpiresAt.IsZero() {
+		return errors.New("no expiration time")
+	}
+	if !now.Before(claims.ExpiresAt.Add(v.leeway)) {
+		return fmt.Errorf("expired at %s", claims.ExpiresAt.Format(time.RFC3339))
+	}
+	if !claims.NotBefore.IsZero() && now.Add(v.leeway).Before(claims.NotBefore) {
+		return fmt.Errorf("not valid before %s", claims.NotBefore.Format(time.RFC3339))
+	}
+	return nil
+}
+
+// verifySignature verifies the signature of signed, the header and claims of a token, with key.
+func verifySignature(alg string, key crypto.PublicKey, signed string, signature []byte) error {
+	digest := sha256.Sum256([]byte(signed))
+
+	switch alg {
+	case algRS256:
+		public, ok := key.(*rsa.PublicKey)
+		if !ok {
+			return errors.New("RS256 token signed with a key that is not an RSA key")
+		}
+		if err := rsa.VerifyPKCS1v15(public, crypto.SHA256, digest[:], signature); err != nil {
+			return fmt.Errorf("signature: %w", err)
+		}
+	case algES256:
+		public, ok := key.(*ecdsa.PublicKey)
+		if !ok || public.Curve != elliptic.P256() {
+			return errors.New("ES256 token signed with a key that is not a P-256 key")
+		}
+		// JWS signatures are the fixed size R and S values, rather than the ASN.1 encoding
+		if len(signature) != 64 {
+			return errors.New("signature: wrong length")
+		}
+		r := new(big.Int).SetBytes(signature[:32])
+		s := new(big.Int).SetBytes(signature[32:])
+		if !ecdsa.Verify(public, digest[:], r, s) {
+			return errors.New("signature: verification error")
+		}
+	}
+	return nil
+}
+
+// decodeSegment decodes the base64url encoded JSON segment of a token into v. Numbers are decoded
+// as json.Number, so that NumericDate claims keep their precision.
+func decodeSegment(segment string, v any) error {
+	data, err := base64.RawURLEncoding.DecodeString(segment)
+	if err != nil {
+		return err
+	}
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+	return decoder.Decode(v)
+}
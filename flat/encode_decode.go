@@ -6,13 +6,24 @@ import (
 	"net/http"
 )
 
-// encodeResponse encodes a struct of type T as a JSON response.
+// encodeResponse encodes a struct of type T as a JSON response. If data can not be encoded, an
+// internal server error problem is written instead.
 func encodeResponse[T any](w http.ResponseWriter, status int, data T) {
+	body, err := json.Marshal(data)
+	if err != nil {
+		_ = writeProblem(w, nil, newProblem(http.StatusInternalServerError, "Internal error"))
+		return
+	}
+
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(status)
-	if err := json.NewEncoder(w).Encode(data); err != nil {
-		http.Error(w, "Internal error", http.StatusInternalServerError)
-		return
+	_, _ = w.Write(append(body, '\n'))
+}
+
+// encodeProblem encodes p as an application/problem+json response for r.
+func (h *handler) encodeProblem(w http.ResponseWriter, r *http.Request, p Problem) {
+	if err := writeProblem(w, r, p); err != nil {
+		h.requestLogger(r).Error("error writing problem", "error", err, "problem", p)
 	}
 }
 
@@ -1,6 +1,8 @@
 package main
 
 import (
+	"context"
+	"errors"
 	"log/slog"
 	"net/http"
 	"strconv"
@@ -9,23 +11,48 @@ import (
 // ── Handler Struct And Constructor ───────────────────────────────────────────────────────────────
 
 type handler struct {
-	logger  *slog.Logger
-	service UserService
+	logger        *slog.Logger
+	service       UserService
+	healthChecker *HealthChecker
 }
 
-func newHandler(logger *slog.Logger, us UserService) handler {
+func newHandler(logger *slog.Logger, us UserService, checker *HealthChecker) handler {
 	return handler{
-		logger:  logger,
-		service: us,
+		logger:        logger,
+		service:       us,
+		healthChecker: checker,
 	}
 }
 
-// ── Healthcheck Handler ──────────────────────────────────────────────────────────────────────────
+// requestLogger returns the logger that RequestIDMiddleware put into the context of r, which logs
+// the request ID, trace ID, route and user of r with every line, or the logger of h if r has none.
+func (h *handler) requestLogger(r *http.Request) *slog.Logger {
+	return requestLogger(r, h.logger)
+}
+
+// ── Healthcheck Handlers ─────────────────────────────────────────────────────────────────────────
 
-func (h *handler) handleHealthCheck() http.HandlerFunc {
+// handleLiveness reports the service as alive whenever it can serve a request at all. It does not
+// check any dependency, so a database outage does not get the service restarted.
+func (h *handler) handleLiveness() http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
-		h.logger.Info("Health check called")
-		encodeResponse(w, http.StatusOK, map[string]string{"message": "hello world"})
+		encodeResponse(w, http.StatusOK, HealthReport{Status: HealthOK})
+	}
+}
+
+// handleReadiness reports whether the service is ready to serve requests, with the health of each
+// of its components. A service that is not ready, including one that is draining on shutdown,
+// responds with 503 Service Unavailable.
+func (h *handler) handleReadiness() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		report := h.healthChecker.Ready(r.Context())
+		if report.Status != HealthOK {
+			h.requestLogger(r).Warn("Service not ready", "report", report)
+			encodeResponse(w, http.StatusServiceUnavailable, report)
+			return
+		}
+
+		encodeResponse(w, http.StatusOK, report)
 	}
 }
 
@@ -47,22 +74,14 @@ type responseID struct {
 	ObjectID int `json:"object_id"`
 }
 
-type responseError struct {
-	Error string `json:"error"`
-}
-
 // handleListUsers is a handler that returns a list of all users.
 func (h *handler) handleListUsers() http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		// get values from DB
-		users, err := h.service.ListUsers()
+		users, err := h.service.ListUsers(r.Context())
 		if err != nil {
-			h.logger.Error("error getting all locations", "error", err)
-			encodeResponse(
-				w,
-				http.StatusInternalServerError,
-				responseError{Error: "Error retrieving data"},
-			)
+			h.requestLogger(r).Error("error getting all locations", "error", err)
+			h.encodeProblem(w, r, newProblem(http.StatusInternalServerError, "Error retrieving data"))
 			return
 		}
 
@@ -78,20 +97,22 @@ func (h *handler) handleFetchUser() http.HandlerFunc {
 		idString := r.PathValue("id")
 		ID, err := strconv.Atoi(idString)
 		if err != nil {
-			h.logger.Error("error getting ID", "error", err)
-			encodeResponse(w, http.StatusBadRequest, responseError{Error: "Not a valid ID"})
+			h.requestLogger(r).Error("error getting ID", "error", err)
+			h.encodeProblem(w, r, newProblem(http.StatusBadRequest, "Not a valid ID"))
 			return
 		}
 
 		// get values from DB
-		user, err := h.service.FetchUser(ID)
+		user, err := h.service.FetchUser(r.Context(), ID)
 		if err != nil {
-			h.logger.Error("error getting locations buy ID", "ID", ID, "error", err)
-			encodeResponse(
-				w,
-				http.StatusInternalServerError,
-				responseError{Error: "Error retrieving data"},
-			)
+			switch {
+			case errors.Is(err, ErrUserNotFound):
+				h.requestLogger(r).Error("object does not exist", "ID", ID, "error", err)
+				h.encodeProblem(w, r, newProblem(http.StatusNotFound, "Object does not exist"))
+			default:
+				h.requestLogger(r).Error("error getting locations buy ID", "ID", ID, "error", err)
+				h.encodeProblem(w, r, newProblem(http.StatusInternalServerError, "Error retrieving data"))
+			}
 			return
 		}
 
@@ -101,38 +122,61 @@ func (h *handler) handleFetchUser() http.HandlerFunc {
 }
 
 // handleUpdateUser is a handler that updates a user based on a user object from the request body.
+// Changes to fields that are read-only for the principal of the request are refused.
 func (h *handler) handleUpdateUser() http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		// get and validate ID
 		idString := r.PathValue("id")
 		ID, err := strconv.Atoi(idString)
 		if err != nil {
-			h.logger.Error("error getting ID", "error", err)
-			encodeResponse(w, http.StatusBadRequest, responseError{Error: "Not a valid ID"})
+			h.requestLogger(r).Error("error getting ID", "error", err)
+			h.encodeProblem(w, r, newProblem(http.StatusBadRequest, "Not a valid ID"))
 			return
 		}
 
 		// get and validate body as object
 		inputUser, err := decodeToStruct[User](r)
 		if err != nil {
-			h.logger.Error("BodyParser error", "error", err)
-			encodeResponse(
-				w,
-				http.StatusBadRequest,
-				responseError{Error: "missing values or malformed body"},
-			)
+			h.requestLogger(r).Error("BodyParser error", "error", err)
+			h.encodeProblem(w, r, newProblem(http.StatusBadRequest, "missing values or malformed body"))
 			return
 		}
 
+		// refuse changes to fields that are read-only for the principal of the request
+		if principal, ok := PrincipalFromContext(r.Context()); ok && principal.HasReadOnlyFields() {
+			current, err := h.service.FetchUser(r.Context(), ID)
+			if err != nil {
+				switch {
+				case errors.Is(err, ErrUserNotFound):
+					h.requestLogger(r).Error("object does not exist", "ID", ID, "error", err)
+					h.encodeProblem(w, r, newProblem(http.StatusNotFound, "Object does not exist"))
+				default:
+					h.requestLogger(r).Error("error getting object from DB", "ID", ID, "error", err)
+					h.encodeProblem(w, r, newProblem(http.StatusInternalServerError, "Error updating data"))
+				}
+				return
+			}
+			if err = authorizeChanges(r.Context(), current, inputUser); err != nil {
+				h.requestLogger(r).Error("request forbidden by policy", "ID", ID, "error", err)
+				h.encodeProblem(w, r, forbiddenProblem(err))
+				return
+			}
+		}
+
 		// update object in Database
-		user, err := h.service.UpdateUser(ID, inputUser)
+		user, err := h.service.UpdateUser(r.Context(), ID, inputUser)
 		if err != nil {
-			h.logger.Error("error updating object in DB", "error", err)
-			encodeResponse(
-				w,
-				http.StatusInternalServerError,
-				responseError{Error: "Error updating data"},
-			)
+			switch {
+			case errors.Is(err, ErrUserNotFound):
+				h.requestLogger(r).Error("object does not exist", "ID", ID, "error", err)
+				h.encodeProblem(w, r, newProblem(http.StatusNotFound, "Object does not exist"))
+			case errors.Is(err, ErrUserIDConflict):
+				h.requestLogger(r).Error("user_id already in use", "ID", ID, "error", err)
+				h.encodeProblem(w, r, newProblem(http.StatusConflict, "user_id already in use"))
+			default:
+				h.requestLogger(r).Error("error updating object in DB", "error", err)
+				h.encodeProblem(w, r, newProblem(http.StatusInternalServerError, "Error updating data"))
+			}
 			return
 		}
 
@@ -147,24 +191,22 @@ func (h *handler) handleCreateUser() http.HandlerFunc {
 		// get and validate body as object
 		inputUser, err := decodeToStruct[User](r)
 		if err != nil {
-			h.logger.Error("BodyParser error", "error", err)
-			encodeResponse(
-				w,
-				http.StatusBadRequest,
-				responseError{Error: "missing values or malformed body"},
-			)
+			h.requestLogger(r).Error("BodyParser error", "error", err)
+			h.encodeProblem(w, r, newProblem(http.StatusBadRequest, "missing values or malformed body"))
 			return
 		}
 
 		// create object in Database
-		ID, err := h.service.CreateUser(inputUser)
+		ID, err := h.service.CreateUser(r.Context(), inputUser)
 		if err != nil {
-			h.logger.Error("error creating object to DB", "error", err)
-			encodeResponse(
-				w,
-				http.StatusInternalServerError,
-				responseError{Error: "Error creating object"},
-			)
+			switch {
+			case errors.Is(err, ErrUserIDConflict):
+				h.requestLogger(r).Error("user_id already in use", "error", err)
+				h.encodeProblem(w, r, newProblem(http.StatusConflict, "user_id already in use"))
+			default:
+				h.requestLogger(r).Error("error creating object to DB", "error", err)
+				h.encodeProblem(w, r, newProblem(http.StatusInternalServerError, "Error creating object"))
+			}
 			return
 		}
 
@@ -180,39 +222,129 @@ func (h *handler) handleDeleteUser() http.HandlerFunc {
 		idString := r.PathValue("id")
 		ID, err := strconv.Atoi(idString)
 		if err != nil {
-			h.logger.Error("error getting ID", "error", err)
-			encodeResponse(w, http.StatusBadRequest, responseError{Error: "Not a valid ID"})
+			h.requestLogger(r).Error("error getting ID", "error", err)
+			h.encodeProblem(w, r, newProblem(http.StatusBadRequest, "Not a valid ID"))
 			return
 		}
 
-		// check that object exists
-		user, err := h.service.FetchUser(ID)
-		if err != nil {
-			h.logger.Error("error getting object by ID", "error", err)
-			encodeResponse(
-				w,
-				http.StatusInternalServerError,
-				responseError{Error: "Error validating object"},
-			)
+		// delete user
+		if err = h.service.DeleteUser(r.Context(), ID); err != nil {
+			switch {
+			case errors.Is(err, ErrUserNotFound):
+				h.requestLogger(r).Error("object does not exist", "ID", ID, "error", err)
+				h.encodeProblem(w, r, newProblem(http.StatusNotFound, "Object does not exist"))
+			default:
+				h.requestLogger(r).Error("error deleting object by ID", "ID", ID, "error", err)
+				h.encodeProblem(w, r, newProblem(http.StatusInternalServerError, "Error deleting object."))
+			}
 			return
 		}
-		if user.ID == 0 {
-			encodeResponse(w, http.StatusBadRequest, responseError{Error: "Object does not exist"})
+
+		// return message
+		encodeResponse(w, http.StatusOK, responseMessage{Message: "object successful deleted"})
+	}
+}
+
+// ── Authorization ────────────────────────────────────────────────────────────────────────────────
+
+// permit wraps next so that requests whose principal may not take action are refused. On routes of
+// a single user, an action the principal may only take on their own record is refused if the user
+// belongs to someone else. Requests without a principal, which are only served when authentication
+// is disabled, are let through.
+func (h *handler) permit(action Action, next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		principal, ok := PrincipalFromContext(r.Context())
+		if !ok {
+			next(w, r)
 			return
 		}
 
-		// delete user
-		if err = h.service.DeleteUser(ID); err != nil {
-			h.logger.Error("error deleting object by ID", "ID", ID, "error", err)
-			encodeResponse(
-				w,
-				http.StatusInternalServerError,
-				responseError{Error: "Error deleting object."},
-			)
+		scope, err := principal.Scope(action)
+		if err == nil && scope == ScopeOwn {
+			owner, ok, ownerErr := h.userOwner(r.Context(), r.PathValue("id"))
+			if ownerErr != nil {
+				h.requestLogger(r).Error("error getting object by ID", "error", ownerErr)
+				h.encodeProblem(w, r, newProblem(http.StatusInternalServerError, "Internal server error"))
+				return
+			}
+			if !ok {
+				// the handler reports the ID as invalid or the user as not existing
+				next(w, r)
+				return
+			}
+			err = principal.Authorize(action, owner)
+		}
+		if err != nil {
+			h.requestLogger(r).Error("request forbidden by policy", "path", r.URL.Path, "user_id", principal.UserID, "error", err)
+			h.encodeProblem(w, r, forbiddenProblem(err))
 			return
 		}
 
-		// return message
-		encodeResponse(w, http.StatusOK, responseMessage{Message: "object successful deleted"})
+		next(w, r)
+	}
+}
+
+// userOwner returns the user_id of the user with the ID idString, and false if idString is not a
+// valid ID or no such user exists. Routes of a collection of users have no ID, and their owner is
+// 0, which is never the user_id of a principal.
+func (h *handler) userOwner(ctx context.Context, idString string) (uint, bool, error) {
+	if idString == "" {
+		return 0, true, nil
+	}
+
+	ID, err := strconv.Atoi(idString)
+	if err != nil {
+		return 0, false, nil
+	}
+
+	found, err := h.service.FetchUser(ctx, ID)
+	if err != nil {
+		if errors.Is(err, ErrUserNotFound) {
+			return 0, false, nil
+		}
+		return 0, false, err
+	}
+
+	return found.UserID, true, nil
+}
+
+// authorizeChanges returns a *ForbiddenError if updated changes a field of current that is
+// read-only for the principal of ctx. Without a principal, every change is allowed.
+func authorizeChanges(ctx context.Context, current, updated User) error {
+	principal, ok := PrincipalFromContext(ctx)
+	if !ok {
+		return nil
+	}
+
+	var fields []string
+	if updated.FirstName != current.FirstName {
+		fields = append(fields, "first_name")
+	}
+	if updated.LastName != current.LastName {
+		fields = append(fields, "last_name")
+	}
+	if updated.Role != current.Role {
+		fields = append(fields, "role")
+	}
+	if updated.UserID != current.UserID {
+		fields = append(fields, "user_id")
+	}
+	return principal.AuthorizeChanges(fields)
+}
+
+// forbiddenProblem returns the problem reported for err, a *ForbiddenError.
+func forbiddenProblem(err error) Problem {
+	var forbidden *ForbiddenError
+	if !errors.As(err, &forbidden) {
+		return newProblem(http.StatusForbidden, "Forbidden")
+	}
+
+	switch {
+	case forbidden.Field != "":
+		return newProblem(http.StatusForbidden, "You are not allowed to change "+forbidden.Field)
+	case forbidden.Owner:
+		return newProblem(http.StatusForbidden, "You are only allowed to "+string(forbidden.Action)+" your own user")
+	default:
+		return newProblem(http.StatusForbidden, "You are not allowed to "+string(forbidden.Action)+" users")
 	}
 }
@@ -0,0 +1,177 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"sync/atomic"
+	"time"
+)
+
+// HealthStatus is the health of the service or of one of its components.
+type HealthStatus string
+
+const (
+	HealthOK   HealthStatus = "ok"
+	HealthFail HealthStatus = "fail"
+)
+
+// HealthComponent is the health of a single dependency of the service. Error says why it failed and
+// Details holds what was measured.
+type HealthComponent struct {
+	Status  HealthStatus   `json:"status"`
+	Error   string         `json:"error,omitempty"`
+	Details map[string]any `json:"details,omitempty"`
+}
+
+// HealthReport is the health of the service, which is HealthFail if any of its components failed or
+// the service is draining. The components of a draining service are not checked.
+type HealthReport struct {
+	Status     HealthStatus               `json:"status"`
+	Draining   bool                       `json:"draining,omitempty"`
+	Components map[string]HealthComponent `json:"components,omitempty"`
+}
+
+type HealthOption func(*HealthChecker)
+
+type schemaVersioner interface {
+	Version(ctx context.Context) (applied int, latest int, err error)
+}
+
+// WithHealthTimeout sets how long each component may take to respond. If this function is not
+// called, the default is 2 seconds.
+func WithHealthTimeout(timeout time.Duration) HealthOption {
+	return func(c *HealthChecker) {
+		c.timeout = timeout
+	}
+}
+
+// WithMaxPoolSaturation sets the share of the connection pool that may be in use before the pool
+// fails, between 0 and 1. If this function is not called, the default is 1, so the pool only fails
+// once every connection is in use. The pool never fails if it has no maximum number of connections.
+func WithMaxPoolSaturation(saturation float64) HealthOption {
+	return func(c *HealthChecker) {
+		c.maxSaturation = saturation
+	}
+}
+
+// HealthChecker checks whether the service is ready to serve requests: the database responds, its
+// connection pool is not saturated and its schema is at the latest migration. A HealthChecker can
+// be marked as draining on shutdown, after which it reports the service as not ready, so load
+// balancers stop sending it requests before the server stops accepting them.
+type HealthChecker struct {
+	db            *sql.DB
+	schema        schemaVersioner
+	timeout       time.Duration
+	maxSaturation float64
+	draining      atomic.Bool
+}
+
+// NewHealthChecker returns a new HealthChecker struct for db, whose schema version is read from
+// schema.
+func NewHealthChecker(db *sql.DB, schema schemaVersioner, opts ...HealthOption) *HealthChecker {
+	c := &HealthChecker{
+		db:            db,
+		schema:        schema,
+		timeout:       2 * time.Second,
+		maxSaturation: 1,
+	}
+	for _, opt := range opts {
+		opt(c)
+	}
+	return c
+}
+
+// Drain marks the service as draining, which it stays until it exits.
+func (c *HealthChecker) Drain() {
+	c.draining.Store(true)
+}
+
+// Ready checks every component and reports whether the service is ready.
+func (c *HealthChecker) Ready(ctx context.Context) HealthReport {
+	if c.draining.Load() {
+		return HealthReport{Status: HealthFail, Draining: true}
+	}
+
+	report := HealthReport{
+		Status: HealthOK,
+		Components: map[string]HealthComponent{
+			"database":   c.checkDatabase(ctx),
+			"pool":       c.checkPool(),
+			"migrations": c.checkMigrations(ctx),
+		},
+	}
+	for _, component := range report.Components {
+		if component.Status != HealthOK {
+			report.Status = HealthFail
+		}
+	}
+
+	return report
+}
+
+// checkDatabase pings the database.
+func (c *HealthChecker) checkDatabase(ctx context.Context) HealthComponent {
+	ctx, cancel := context.WithTimeout(ctx, c.timeout)
+	defer cancel()
+
+	start := time.Now()
+	if err := c.db.PingContext(ctx); err != nil {
+		return HealthComponent{Status: HealthFail, Error: err.Error()}
+	}
+
+	return HealthComponent{
+		Status:  HealthOK,
+		Details: map[string]any{"latency_ms": time.Since(start).Milliseconds()},
+	}
+}
+
+// checkPool reports how saturated the connection pool is.
+func (c *HealthChecker) checkPool() HealthComponent {
+	stats := c.db.Stats()
+	component := HealthComponent{
+		Status: HealthOK,
+		Details: map[string]any{
+			"open":             stats.OpenConnections,
+			"in_use":           stats.InUse,
+			"idle":             stats.Idle,
+			"max_open":         stats.MaxOpenConnections,
+			"wait_count":       stats.WaitCount,
+			"wait_duration_ms": stats.WaitDuration.Milliseconds(),
+		},
+	}
+	if stats.MaxOpenConnections == 0 {
+		return component
+	}
+
+	saturation := float64(stats.InUse) / float64(stats.MaxOpenConnections)
+	component.Details["saturation"] = saturation
+	if saturation >= c.maxSaturation {
+		component.Status = HealthFail
+		component.Error = fmt.Sprintf("%d of %d connections in use", stats.InUse, stats.MaxOpenConnections)
+	}
+
+	return component
+}
+
+// checkMigrations checks that the latest migration has been applied.
+func (c *HealthChecker) checkMigrations(ctx context.Context) HealthComponent {
+	ctx, cancel := context.WithTimeout(ctx, c.timeout)
+	defer cancel()
+
+	applied, latest, err := c.schema.Version(ctx)
+	if err != nil {
+		return HealthComponent{Status: HealthFail, Error: err.Error()}
+	}
+
+	component := HealthComponent{
+		Status:  HealthOK,
+		Details: map[string]any{"applied": applied, "expected": latest},
+	}
+	if applied != latest {
+		component.Status = HealthFail
+		component.Error = fmt.Sprintf("schema is at version %d, expected %d", applied, latest)
+	}
+
+	return component
+}
@@ -9,11 +9,20 @@ import (
 	"net/http"
 	"os"
 	"os/signal"
+	"strings"
 	"syscall"
 	"time"
+
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
 )
 
 func main() {
+	if len(os.Args) > 1 && os.Args[1] == "migrate" {
+		if err := runMigrate(os.Args[2:]); err != nil {
+			log.Fatalf("Migrate failed. err: %v", err)
+		}
+		return
+	}
 	run()
 }
 
@@ -21,7 +30,22 @@ func run() {
 	// Setup
 	config := MustNewConfiguration()
 
-	logger := slog.Default()
+	logger := mustNewLogger(config)
+
+	provider := mustNewTracerProvider(config)
+	defer func() {
+		// spans that are still buffered are sent before exiting
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		if err := provider.Shutdown(shutdownCtx); err != nil {
+			logger.Error("Error shutting down tracer provider", "err", err)
+		}
+	}()
+
+	migrateOption := WithSchemaCheck()
+	if config.Database.MigrateOnStartup {
+		migrateOption = WithMigrations()
+	}
 
 	db := MustNewDatabase(
 		fmt.Sprintf(
@@ -34,23 +58,46 @@ func run() {
 		),
 		logger,
 		config.Database.ConnectionRetry,
+		WithTracing(provider),
+		migrateOption,
 	)
 	defer db.Session.Close()
+	if config.Database.MaxOpenConnections > 0 {
+		db.Session.SetMaxOpenConns(config.Database.MaxOpenConnections)
+	}
 
-	us := NewUserService(db)
+	metrics := NewMetrics(db.Session, config.Database.Name)
 
-	h := newHandler(logger, us)
+	us := NewUserService(db, WithChangeCounter(metrics))
+	checker := mustNewHealthChecker(config, db, logger)
+
+	h := newHandler(logger, us, checker)
 
 	mux := http.NewServeMux()
 
-	stack := CreateStack(
+	middlewares := []Middleware{
+		TracingMiddleware(provider, mux),
+		MetricsMiddleware(metrics, mux),
+		RequestIDMiddleware(logger, mux),
 		CORSMiddleware(CORSOptions{
-			allowedOrigins: []string{"*"},
+			allowedOrigins: splitList(config.HTTP.CORSAllowedOrigins),
 			allowedMethods: []string{"GET", "POST", "PUT", "DELETE"},
 		}),
 		LoggerMiddleware(logger),
 		RecoveryMiddleware(logger),
-	)
+	}
+	if config.Auth.Disabled {
+		logger.Warn("AUTH_DISABLED is set, requests are not authenticated")
+	} else {
+		middlewares = append(middlewares,
+			AuthMiddleware(logger, AuthOptions{
+				verifier:    mustNewVerifier(config),
+				publicPaths: []string{"/api/health/live", "/api/health/ready"},
+			}),
+			AuthorizeMiddleware(logger, us, mustNewPolicy(config.Auth.PolicyFile)),
+		)
+	}
+	stack := CreateStack(middlewares...)
 
 	RegisterRoutes(mux, h)
 
@@ -59,6 +106,21 @@ func run() {
 		Handler: stack(mux),
 	}
 
+	// metrics are served on their own port, so they are not exposed with the API
+	metricsMux := http.NewServeMux()
+	metricsMux.Handle("GET /metrics", metrics.Handler())
+	metricsServer := &http.Server{
+		Addr:    config.HTTP.Domain + metricsPort(config),
+		Handler: metricsMux,
+	}
+	go func() {
+		logger.Info(fmt.Sprintf("Metrics are served on %s", metricsServer.Addr))
+		err := metricsServer.ListenAndServe()
+		if err != nil && !errors.Is(err, http.ErrServerClosed) {
+			logger.Error("Metrics server stopped", "err", err)
+		}
+	}()
+
 	// Graceful shutdown
 	serverCtx, serverStopCtx := context.WithCancel(context.Background())
 
@@ -68,11 +130,17 @@ func run() {
 		<-sig
 
 		fmt.Println()
-		logger.Info("Shutdown signal received")
+		logger.Info("Shutdown signal received, draining", "delay seconds", config.HTTP.DrainDelaySeconds)
 
-		shutdownCtx, _ := context.WithTimeout(
+		// readiness fails from here on, so load balancers stop sending requests before the server
+		// stops accepting them
+		checker.Drain()
+		time.Sleep(time.Duration(config.HTTP.DrainDelaySeconds) * time.Second)
+
+		shutdownCtx, cancel := context.WithTimeout(
 			serverCtx, time.Duration(config.HTTP.ShutdownGracePeriod)*time.Second,
 		)
+		defer cancel()
 
 		go func() {
 			<-shutdownCtx.Done()
@@ -85,6 +153,9 @@ func run() {
 		if err != nil {
 			log.Fatal(err)
 		}
+		if err := metricsServer.Shutdown(shutdownCtx); err != nil {
+			logger.Error("Error shutting down metrics server", "err", err)
+		}
 		serverStopCtx()
 	}()
 
@@ -98,3 +169,131 @@ func run() {
 	<-serverCtx.Done()
 	logger.Info("Shutdown complete")
 }
+
+// mustNewLogger returns the default logger, which redacts personal data in LOG_REDACT_MODE,
+// `mask` if it is not set, when LOG_REDACT_PII is set. The attributes in LOG_REDACT_KEYS are
+// redacted, or `first_name` and `last_name` if it is not set. The hash mode is keyed by
+// LOG_REDACT_SECRET. It panics if the mode is unknown or the hash mode has no secret.
+func mustNewLogger(config Configuration) *slog.Logger {
+	logger := slog.Default()
+	if !config.Log.RedactPII {
+		return logger
+	}
+
+	mode := config.Log.RedactMode
+	if mode == "" {
+		mode = RedactMask
+	}
+	keys := splitList(config.Log.RedactKeys)
+	if len(keys) == 0 {
+		keys = []string{"first_name", "last_name"}
+	}
+
+	handler, err := NewRedactHandler(logger.Handler(), mode, []byte(config.Log.RedactSecret), keys)
+	if err != nil {
+		panic(fmt.Sprintf("Error creating log redaction: %v", err))
+	}
+	return slog.New(handler)
+}
+
+// mustNewVerifier returns the verifier of bearer tokens configured by config, which takes its keys
+// from the JWK Set at AUTH_JWKS_URL or, if that is not set, from the key file at AUTH_KEY_FILE. It
+// panics if authentication is not configured.
+func mustNewVerifier(config Configuration) *Verifier {
+	if config.Auth.Issuer == "" || config.Auth.Audience == "" {
+		panic("AUTH_ISSUER and AUTH_AUDIENCE must be set unless AUTH_DISABLED is true")
+	}
+
+	var keys KeySource
+	switch {
+	case config.Auth.JWKSURL != "":
+		var opts []JWKSOption
+		if config.Auth.JWKSRefreshSeconds > 0 {
+			opts = append(opts, WithRefreshInterval(time.Duration(config.Auth.JWKSRefreshSeconds)*time.Second))
+		}
+		keys = NewJWKS(config.Auth.JWKSURL, opts...)
+	case config.Auth.KeyFile != "":
+		keyFile, err := LoadKeyFile(config.Auth.KeyFile)
+		if err != nil {
+			panic(fmt.Sprintf("Error loading AUTH_KEY_FILE: %v", err))
+		}
+		keys = keyFile
+	default:
+		panic("AUTH_JWKS_URL or AUTH_KEY_FILE must be set unless AUTH_DISABLED is true")
+	}
+
+	var opts []VerifierOption
+	if config.Auth.LeewaySeconds > 0 {
+		opts = append(opts, WithLeeway(time.Duration(config.Auth.LeewaySeconds)*time.Second))
+	}
+	return NewVerifier(keys, config.Auth.Issuer, config.Auth.Audience, opts...)
+}
+
+// mustNewPolicy returns the policy of the file at path, or the default policy if path is empty. It
+// panics if the file can not be loaded.
+func mustNewPolicy(path string) Policy {
+	rules, err := NewPolicy(path)
+	if err != nil {
+		panic(fmt.Sprintf("Error loading AUTH_POLICY_FILE: %v", err))
+	}
+	return rules
+}
+
+// mustNewHealthChecker returns the readiness checker of db, with the timeout and pool saturation of
+// config if they are set. It panics if the migrations the schema is checked against can not be
+// loaded.
+func mustNewHealthChecker(config Configuration, db Database, logger *slog.Logger) *HealthChecker {
+	var opts []HealthOption
+	if config.Health.TimeoutSeconds > 0 {
+		opts = append(opts, WithHealthTimeout(time.Duration(config.Health.TimeoutSeconds)*time.Second))
+	}
+	if config.Health.MaxPoolSaturation > 0 {
+		opts = append(opts, WithMaxPoolSaturation(config.Health.MaxPoolSaturation))
+	}
+
+	migrator, err := NewMigrator(db.Session, logger)
+	if err != nil {
+		panic(fmt.Sprintf("Error loading migrations: %v", err))
+	}
+	return NewHealthChecker(db.Session, migrator, opts...)
+}
+
+// mustNewTracerProvider returns the tracer provider configured by config, which sends spans to the
+// TRACING_EXPORTER. It panics if the exporter is unknown or can not be created.
+func mustNewTracerProvider(config Configuration) *sdktrace.TracerProvider {
+	serviceName := config.Tracing.ServiceName
+	if serviceName == "" {
+		serviceName = "user-microservice"
+	}
+
+	var opts []TracerOption
+	if config.Tracing.SampleRatio > 0 {
+		opts = append(opts, WithSampleRatio(config.Tracing.SampleRatio))
+	}
+
+	provider, err := NewTracerProvider(context.Background(), config.Tracing.Exporter, serviceName, opts...)
+	if err != nil {
+		panic(fmt.Sprintf("Error creating tracer provider: %v", err))
+	}
+	return provider
+}
+
+// metricsPort returns the METRICS_PORT of config, or `:9090` if it is not set.
+func metricsPort(config Configuration) string {
+	if config.Metrics.Port == "" {
+		return ":9090"
+	}
+	return config.Metrics.Port
+}
+
+// splitList returns the items of a comma separated list, or nil if it is empty.
+func splitList(list string) []string {
+	if strings.TrimSpace(list) == "" {
+		return nil
+	}
+	items := strings.Split(list, ",")
+	for i := range items {
+		items[i] = strings.TrimSpace(items[i])
+	}
+	return items
+}
@@ -0,0 +1,119 @@
+package main
+
+import (
+	"database/sql"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+// unmatchedRoute is the route of requests that matched no route, so that the paths of unknown
+// routes do not each get their own series.
+const unmatchedRoute = "unmatched"
+
+// Metrics keeps the metrics of the service in a Prometheus registry: the rate, errors and duration
+// of requests by route, the requests in flight, the users that were created, updated and deleted,
+// and the connection pool stats of the database together with the Go runtime and process metrics.
+// The metrics are exposed in the Prometheus text format by Handler.
+type Metrics struct {
+	registry *prometheus.Registry
+	requests *prometheus.CounterVec
+	errors   *prometheus.CounterVec
+	duration *prometheus.HistogramVec
+	inFlight prometheus.Gauge
+	created  prometheus.Counter
+	updated  prometheus.Counter
+	deleted  prometheus.Counter
+}
+
+// NewMetrics returns a new Metrics struct whose registry includes the sql.DBStats of db, as the
+// go_sql_* metrics with a db_name label of dbName.
+func NewMetrics(db *sql.DB, dbName string) *Metrics {
+	m := &Metrics{
+		registry: prometheus.NewRegistry(),
+		requests: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "http_requests_total",
+			Help: "Number of requests served, by method, route and status.",
+		}, []string{"method", "route", "status"}),
+		errors: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "http_request_errors_total",
+			Help: "Number of requests that failed with a 5xx status, by method, route and status.",
+		}, []string{"method", "route", "status"}),
+		duration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Name:    "http_request_duration_seconds",
+			Help:    "Time taken to serve requests, by method and route.",
+			Buckets: prometheus.DefBuckets,
+		}, []string{"method", "route"}),
+		inFlight: prometheus.NewGauge(prometheus.GaugeOpts{
+			Name: "http_requests_in_flight",
+			Help: "Number of requests being served.",
+		}),
+		created: prometheus.NewCounter(prometheus.CounterOpts{
+			Name: "users_created_total",
+			Help: "Number of users created.",
+		}),
+		updated: prometheus.NewCounter(prometheus.CounterOpts{
+			Name: "users_updated_total",
+			Help: "Number of users updated.",
+		}),
+		deleted: prometheus.NewCounter(prometheus.CounterOpts{
+			Name: "users_deleted_total",
+			Help: "Number of users deleted.",
+		}),
+	}
+
+	m.registry.MustRegister(
+		m.requests,
+		m.errors,
+		m.duration,
+		m.inFlight,
+		m.created,
+		m.updated,
+		m.deleted,
+		collectors.NewDBStatsCollector(db, dbName),
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+	)
+
+	return m
+}
+
+// Handler returns a handler that serves the metrics of m in the Prometheus text format.
+func (m *Metrics) Handler() http.Handler {
+	return promhttp.HandlerFor(m.registry, promhttp.HandlerOpts{Registry: m.registry})
+}
+
+// RequestStarted records that a request is being served.
+func (m *Metrics) RequestStarted() {
+	m.inFlight.Inc()
+}
+
+// RequestFinished records that a request started with RequestStarted was served with status after
+// duration. route is the pattern the request was routed to, such as `/api/user/{id}`, never its
+// path. Requests with a 5xx status are counted as errors too.
+func (m *Metrics) RequestFinished(method string, route string, status int, duration time.Duration) {
+	statusLabel := strconv.Itoa(status)
+
+	m.inFlight.Dec()
+	m.requests.WithLabelValues(method, route, statusLabel).Inc()
+	if status >= http.StatusInternalServerError {
+		m.errors.WithLabelValues(method, route, statusLabel).Inc()
+	}
+	m.duration.WithLabelValues(method, route).Observe(duration.Seconds())
+}
+
+func (m *Metrics) UsersCreated(count int) {
+	m.created.Add(float64(count))
+}
+
+func (m *Metrics) UsersUpdated(count int) {
+	m.updated.Add(float64(count))
+}
+
+func (m *Metrics) UsersDeleted(count int) {
+	m.deleted.Add(float64(count))
+}
@@ -1,11 +1,20 @@
 package main
 
 import (
-	"encoding/json"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
 	"log/slog"
 	"net/http"
+	"slices"
 	"strings"
 	"time"
+
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+	"go.opentelemetry.io/otel/trace"
 )
 
 // ── Middleware Setup ─────────────────────────────────────────────────────────────────────────────
@@ -22,6 +31,98 @@ func CreateStack(fn ...Middleware) Middleware {
 	}
 }
 
+// ── Request ID ───────────────────────────────────────────────────────────────────────────────────
+
+// maxRequestIDLength is the maximum length of a request ID taken from a request header, longer IDs
+// are replaced.
+const maxRequestIDLength = 128
+
+type (
+	loggerContextKey    struct{}
+	requestIDContextKey struct{}
+)
+
+// RequestIDMiddleware identifies every request and puts a logger into its context that logs the
+// request ID, the trace ID and the pattern of mux that the request matches with every line. The ID
+// is taken from the X-Request-ID header of the request, or else generated, and is echoed in the
+// X-Request-ID header of the response. It has to come after TracingMiddleware for the trace ID to be
+// known.
+func RequestIDMiddleware(logger *slog.Logger, mux *http.ServeMux) Middleware {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			ctx := r.Context()
+
+			id := r.Header.Get(requestIDHeader)
+			if !validRequestID(id) {
+				id = newRequestID()
+			}
+			w.Header().Set(requestIDHeader, id)
+			ctx = context.WithValue(ctx, requestIDContextKey{}, id)
+
+			args := []any{"request_id", id}
+			if spanContext := trace.SpanContextFromContext(ctx); spanContext.HasTraceID() {
+				args = append(args, "trace_id", spanContext.TraceID().String())
+			}
+			if route := muxRoute(mux, r); route != "" {
+				args = append(args, "route", route)
+			}
+
+			ctx = context.WithValue(ctx, loggerContextKey{}, logger.With(args...))
+			next.ServeHTTP(w, r.WithContext(ctx))
+		})
+	}
+}
+
+// requestLogger returns the logger that RequestIDMiddleware put into the context of r, which logs
+// the request ID, trace ID, route and user of r with every line, or logger if r has none.
+func requestLogger(r *http.Request, logger *slog.Logger) *slog.Logger {
+	if requestLogger, ok := r.Context().Value(loggerContextKey{}).(*slog.Logger); ok {
+		return requestLogger
+	}
+	return logger
+}
+
+// withLogArgs returns a copy of ctx whose request logger logs args with every line, such as the
+// user of the request once it is known. ctx is returned as is if it carries no logger.
+func withLogArgs(ctx context.Context, args ...any) context.Context {
+	logger, ok := ctx.Value(loggerContextKey{}).(*slog.Logger)
+	if !ok {
+		return ctx
+	}
+	return context.WithValue(ctx, loggerContextKey{}, logger.With(args...))
+}
+
+// requestID returns the ID that RequestIDMiddleware gave r or, if there is none, the value of its
+// X-Request-ID header.
+func requestID(r *http.Request) string {
+	if id, ok := r.Context().Value(requestIDContextKey{}).(string); ok {
+		return id
+	}
+	return r.Header.Get(requestIDHeader)
+}
+
+// newRequestID returns a new random request ID.
+func newRequestID() string {
+	// crypto/rand.Read never fails
+	b := make([]byte, 16)
+	_, _ = rand.Read(b)
+	return hex.EncodeToString(b)
+}
+
+// validRequestID reports whether id can be used as a request ID: it is not empty, not too long and
+// only has printable ASCII characters other than spaces, so it can not forge log lines.
+func validRequestID(id string) bool {
+	if id == "" || len(id) > maxRequestIDLength {
+		return false
+	}
+	for i := 0; i < len(id); i++ {
+		if id[i] <= ' ' || id[i] > '~' {
+			return false
+		}
+	}
+	return true
+}
+
 // ── Request Logger ───────────────────────────────────────────────────────────────────────────────
 
 type wrappedWriter struct {
@@ -46,7 +147,7 @@ func LoggerMiddleware(logger *slog.Logger) Middleware {
 
 			next.ServeHTTP(wrapped, r)
 
-			logger.Info(
+			requestLogger(r, logger).Info(
 				"Incoming request",
 				"status code",
 				wrapped.statusCode,
@@ -71,17 +172,12 @@ func RecoveryMiddleware(logger *slog.Logger) Middleware {
 			defer func() {
 				err := recover()
 				if err != nil {
-					logger.Error("panic recovered", "panic", err)
-
-					jsonBody, _ := json.Marshal(
-						map[string]string{
-							"error": "There was an internal server error",
-						},
-					)
+					requestLogger(r, logger).Error("panic recovered", "panic", err)
 
-					w.Header().Set("Content-Type", "application/json")
-					w.WriteHeader(http.StatusInternalServerError)
-					w.Write(jsonBody)
+					_ = writeProblem(w, r, newProblem(
+						http.StatusInternalServerError,
+						"There was an internal server error",
+					))
 				}
 			}()
 
@@ -112,7 +208,15 @@ func CORSMiddleware(opts CORSOptions) Middleware {
 
 	return func(next http.Handler) http.Handler {
 		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-			w.Header().Set("Access-Control-Allow-Origin", strings.Join(opts.allowedOrigins, ","))
+			// a response can only allow a single origin, so the origin of the request is echoed if it is
+			// allowed
+			switch origin := r.Header.Get("Origin"); {
+			case slices.Contains(opts.allowedOrigins, "*"):
+				w.Header().Set("Access-Control-Allow-Origin", "*")
+			case slices.Contains(opts.allowedOrigins, origin):
+				w.Header().Set("Access-Control-Allow-Origin", origin)
+				w.Header().Add("Vary", "Origin")
+			}
 			w.Header().Set("Access-Control-Allow-Methods", strings.Join(opts.allowedMethods, ","))
 			w.Header().Set("Access-Control-Allow-Headers", strings.Join(opts.allowedHeaders, ","))
 
@@ -126,3 +230,187 @@ func CORSMiddleware(opts CORSOptions) Middleware {
 		})
 	}
 }
+
+// ── Auth ─────────────────────────────────────────────────────────────────────────────────────────
+
+type AuthOptions struct {
+	verifier *Verifier
+	// publicPaths are served without a token, such as the health check.
+	publicPaths []string
+}
+
+// AuthMiddleware refuses requests without a bearer token that opts.verifier accepts, and puts the
+// claims of the token into the request context, where they can be read with ClaimsFromContext.
+func AuthMiddleware(logger *slog.Logger, opts AuthOptions) Middleware {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			logger := requestLogger(r, logger)
+
+			if slices.Contains(opts.publicPaths, r.URL.Path) {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			scheme, token, ok := strings.Cut(r.Header.Get("Authorization"), " ")
+			token = strings.TrimSpace(token)
+			if !ok || !strings.EqualFold(scheme, "Bearer") || token == "" {
+				logger.Error("request without bearer token", "path", r.URL.Path)
+				w.Header().Set("WWW-Authenticate", "Bearer")
+				_ = writeProblem(w, r, newProblem(http.StatusUnauthorized, "A bearer token is required"))
+				return
+			}
+
+			claims, err := opts.verifier.Verify(r.Context(), token)
+			if err != nil {
+				if errors.Is(err, ErrInvalidToken) {
+					logger.Error("invalid bearer token", "err", err)
+					w.Header().Set("WWW-Authenticate", `Bearer error="invalid_token"`)
+					_ = writeProblem(w, r, newProblem(http.StatusUnauthorized, "The bearer token is not valid"))
+					return
+				}
+				logger.Error("error verifying bearer token", "err", err)
+				_ = writeProblem(w, r, newProblem(http.StatusServiceUnavailable, "Unable to verify the bearer token"))
+				return
+			}
+
+			next.ServeHTTP(w, r.WithContext(withClaims(r.Context(), claims)))
+		})
+	}
+}
+
+// ── Authorization ────────────────────────────────────────────────────────────────────────────────
+
+// AuthorizeMiddleware identifies the user making a request by the user_id claim of the token that
+// authenticated it, and puts them into the request context as a Principal with the rule that rules
+// has for their role. Requests whose token has no user_id claim, or names a user that does not
+// exist, are refused. Requests without claims, such as those to public paths, are passed on without
+// a principal.
+func AuthorizeMiddleware(logger *slog.Logger, us UserService, rules Policy) Middleware {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			logger := requestLogger(r, logger)
+
+			claims, ok := ClaimsFromContext(r.Context())
+			if !ok {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			userID, ok := claims.UserID()
+			if !ok {
+				logger.Error("token without user_id claim", "subject", claims.Subject)
+				_ = writeProblem(w, r, newProblem(http.StatusForbidden, "The bearer token does not identify a user"))
+				return
+			}
+
+			user, err := us.FetchUserByUserID(r.Context(), userID)
+			if err != nil {
+				if errors.Is(err, ErrUserNotFound) {
+					logger.Error("token for unknown user", "subject", claims.Subject, "user_id", userID)
+					_ = writeProblem(w, r, newProblem(http.StatusForbidden, "The bearer token does not identify a user"))
+					return
+				}
+				logger.Error("error getting user of token", "err", err)
+				_ = writeProblem(w, r, newProblem(http.StatusInternalServerError, "Internal server error"))
+				return
+			}
+
+			principal := rules.Principal(user.UserID, user.Role)
+			ctx := withLogArgs(r.Context(), "user_id", user.UserID)
+			next.ServeHTTP(w, r.WithContext(withPrincipal(ctx, principal)))
+		})
+	}
+}
+
+// ── Tracing ──────────────────────────────────────────────────────────────────────────────────────
+
+// TracingMiddleware serves every request in a span of provider. The span continues the trace of
+// the W3C traceparent header of the request, unless the request context already has a span, such as
+// that of a Lambda invocation. It is named after the method and the pattern of mux that the request
+// matches, and marked as failed if the response status is 5xx.
+func TracingMiddleware(provider trace.TracerProvider, mux *http.ServeMux) Middleware {
+	tracer := provider.Tracer(instrumentationName)
+	propagator := propagation.TraceContext{}
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			ctx := r.Context()
+			kind := trace.SpanKindInternal
+			if !trace.SpanContextFromContext(ctx).IsValid() {
+				ctx = propagator.Extract(ctx, propagation.HeaderCarrier(r.Header))
+				kind = trace.SpanKindServer
+			}
+
+			name := r.Method
+			route := muxRoute(mux, r)
+			if route != "" {
+				name += " " + route
+			}
+
+			ctx, span := tracer.Start(
+				ctx,
+				name,
+				trace.WithSpanKind(kind),
+				trace.WithAttributes(
+					semconv.HTTPRequestMethodKey.String(r.Method),
+					semconv.URLPath(r.URL.Path),
+				),
+			)
+			defer span.End()
+			if route != "" {
+				span.SetAttributes(semconv.HTTPRoute(route))
+			}
+
+			wrapped := &wrappedWriter{
+				ResponseWriter: w,
+				statusCode:     http.StatusOK,
+			}
+
+			next.ServeHTTP(wrapped, r.WithContext(ctx))
+
+			span.SetAttributes(semconv.HTTPResponseStatusCode(wrapped.statusCode))
+			if wrapped.statusCode >= http.StatusInternalServerError {
+				span.SetStatus(codes.Error, http.StatusText(wrapped.statusCode))
+			}
+		})
+	}
+}
+
+// ── Metrics ──────────────────────────────────────────────────────────────────────────────────────
+
+// MetricsMiddleware records every request with metrics, by its method and the pattern of mux that
+// it matches. Requests that match no pattern are recorded as unmatchedRoute, so that the paths of
+// unknown routes do not each get their own series.
+func MetricsMiddleware(metrics *Metrics, mux *http.ServeMux) Middleware {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			start := time.Now()
+			metrics.RequestStarted()
+
+			route := muxRoute(mux, r)
+			if route == "" {
+				route = unmatchedRoute
+			}
+
+			wrapped := &wrappedWriter{
+				ResponseWriter: w,
+				statusCode:     http.StatusOK,
+			}
+			defer func() {
+				metrics.RequestFinished(r.Method, route, wrapped.statusCode, time.Since(start))
+			}()
+
+			next.ServeHTTP(wrapped, r)
+		})
+	}
+}
+
+// muxRoute returns the path of the pattern of mux that r matches, such as `/api/user/{id}`, or an
+// empty string if it matches none.
+func muxRoute(mux *http.ServeMux, r *http.Request) string {
+	_, pattern := mux.Handler(r)
+	if i := strings.Index(pattern, "/"); i >= 0 {
+		return pattern[i:]
+	}
+	return ""
+}
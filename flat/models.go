@@ -1,9 +1,11 @@
 package main
 
+// User is a user of the service. Fields tagged `pii:"true"` hold personal data, which
+// RedactHandler keeps out of logs.
 type User struct {
 	ID        uint   `json:"id,omitempty"`
-	FirstName string `json:"first_name,omitempty"`
-	LastName  string `json:"last_name,omitempty"`
+	FirstName string `json:"first_name,omitempty" pii:"true"`
+	LastName  string `json:"last_name,omitempty" pii:"true"`
 	Role      string `json:"role,omitempty"`
 	UserID    uint   `json:"user_id,omitempty"`
 }
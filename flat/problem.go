@@ -0,0 +1,83 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sort"
+)
+
+// problemContentType is the media type of a problem details response as defined by RFC 7807.
+const problemContentType = "application/problem+json"
+
+// requestIDHeader is the header the ID of a request is read from, and echoed in on its response.
+const requestIDHeader = "X-Request-Id"
+
+// FieldError is a single violation of a field in a request body or query.
+type FieldError struct {
+	Field  string `json:"field"`
+	Detail string `json:"detail"`
+}
+
+// Problem is an RFC 7807 problem details object.
+type Problem struct {
+	Type      string       `json:"type"`
+	Title     string       `json:"title"`
+	Status    int          `json:"status"`
+	Detail    string       `json:"detail,omitempty"`
+	Instance  string       `json:"instance,omitempty"`
+	Errors    []FieldError `json:"errors,omitempty"`
+	RequestID string       `json:"request_id,omitempty"`
+}
+
+// newProblem returns a Problem for status with a human-readable detail. The type is "about:blank"
+// and the title is the standard text of status.
+func newProblem(status int, detail string) Problem {
+	return Problem{
+		Type:   "about:blank",
+		Title:  http.StatusText(status),
+		Status: status,
+		Detail: detail,
+	}
+}
+
+// withFieldErrors returns a copy of p with the given field violations, keyed by field name, added
+// to Errors. Errors are sorted by field so that responses are stable.
+func (p Problem) withFieldErrors(problems map[string]string) Problem {
+	errs := make([]FieldError, 0, len(p.Errors)+len(problems))
+	errs = append(errs, p.Errors...)
+	for field, detail := range problems {
+		errs = append(errs, FieldError{Field: field, Detail: detail})
+	}
+	sort.SliceStable(errs, func(i, j int) bool { return errs[i].Field < errs[j].Field })
+
+	p.Errors = errs
+	return p
+}
+
+// writeProblem writes p to w as an application/problem+json response. If not already set,
+// Instance is set to the path of r and RequestID to the ID of r. r may be nil when there is no
+// request to take these from.
+func writeProblem(w http.ResponseWriter, r *http.Request, p Problem) error {
+	if r != nil {
+		if p.Instance == "" {
+			p.Instance = r.URL.Path
+		}
+		if p.RequestID == "" {
+			p.RequestID = requestID(r)
+		}
+	}
+
+	body, err := json.Marshal(p)
+	if err != nil {
+		return fmt.Errorf("in writeProblem: marshal: %w", err)
+	}
+
+	w.Header().Set("Content-Type", problemContentType)
+	w.WriteHeader(p.Status)
+	if _, err = w.Write(append(body, '\n')); err != nil {
+		return fmt.Errorf("in writeProblem: write: %w", err)
+	}
+
+	return nil
+}
@@ -3,10 +3,11 @@ package main
 import "net/http"
 
 func RegisterRoutes(mux *http.ServeMux, h handler) {
-	mux.HandleFunc("GET /api/health-check", h.handleHealthCheck())
-	mux.HandleFunc("GET /api/user", h.handleListUsers())
-	mux.HandleFunc("GET /api/user/{id}", h.handleFetchUser())
-	mux.HandleFunc("PUT /api/user/{id}", h.handleUpdateUser())
-	mux.HandleFunc("POST /api/user", h.handleCreateUser())
-	mux.HandleFunc("DELETE /api/user/{id}", h.handleDeleteUser())
+	mux.HandleFunc("GET /api/health/live", h.handleLiveness())
+	mux.HandleFunc("GET /api/health/ready", h.handleReadiness())
+	mux.HandleFunc("GET /api/user", h.permit(ActionList, h.handleListUsers()))
+	mux.HandleFunc("GET /api/user/{id}", h.permit(ActionRead, h.handleFetchUser()))
+	mux.HandleFunc("PUT /api/user/{id}", h.permit(ActionUpdate, h.handleUpdateUser()))
+	mux.HandleFunc("POST /api/user", h.permit(ActionCreate, h.handleCreateUser()))
+	mux.HandleFunc("DELETE /api/user/{id}", h.permit(ActionDelete, h.handleDeleteUser()))
 }
@@ -0,0 +1,14 @@
+-- Seed the users table with sample records. The schema is created by the migrations in
+-- migrations, so run `go run . migrate up` first.
+INSERT INTO users (first_name, last_name, role, user_id)
+VALUES ('John', 'Doe', 'Customer', 1001),
+       ('Jane', 'Smith', 'Employee', 1002),
+       ('Robert', 'Johnson', 'Employee', 1003),
+       ('Emily', 'Davis', 'Customer', 1004),
+       ('Michael', 'Brown', 'Employee', 1005),
+       ('Linda', 'Wilson', 'Employee', 1006),
+       ('David', 'Martinez', 'Customer', 1007),
+       ('Elizabeth', 'Taylor', 'Employee', 1008),
+       ('Richard', 'Anderson', 'Employee', 1009),
+       ('Susan', 'Thomas', 'Customer', 1010)
+ON CONFLICT (user_id) DO NOTHING;
@@ -0,0 +1,408 @@
+package main
+
+import (
+	"context"
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"io"
+	"reflect"
+	"strings"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/exporters/stdout/stdouttrace"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// instrumentationName is the name of the tracer that the spans of the service are started with.
+const instrumentationName = "github.com/jha-captech/user-microservice"
+
+// The exporters that spans can be sent to.
+const (
+	// ExporterOTLP sends spans to an OpenTelemetry collector over OTLP/HTTP. The collector is set
+	// with the standard OTEL_EXPORTER_OTLP_ENDPOINT and OTEL_EXPORTER_OTLP_HEADERS variables.
+	ExporterOTLP = "otlp"
+	// ExporterStdout writes spans to stdout as JSON.
+	ExporterStdout = "stdout"
+	// ExporterNone drops spans. Trace context is still propagated.
+	ExporterNone = "none"
+)
+
+// ErrUnknownExporter is returned when an exporter name is not one of the known exporters.
+var ErrUnknownExporter = errors.New("unknown exporter")
+
+type TracerOption func(*tracerOptions)
+
+type tracerOptions struct {
+	sampleRatio float64
+}
+
+// WithSampleRatio sets the share of traces that are sampled, between 0 and 1. Traces that a caller
+// already sampled or dropped keep that decision. If this function is not called, the default is 1,
+// so every trace is sampled.
+func WithSampleRatio(ratio float64) TracerOption {
+	return func(options *tracerOptions) {
+		options.sampleRatio = ratio
+	}
+}
+
+// NewTracerProvider returns a TracerProvider that sends the spans of serviceName to the exporter with the given
+// name, which is one of ExporterOTLP, ExporterStdout or ExporterNone. An empty name is the same as
+// ExporterNone. The provider must be shut down on exit, so spans that are still buffered are sent.
+func NewTracerProvider(ctx context.Context, exporter string, serviceName string, opts ...TracerOption) (*sdktrace.TracerProvider, error) {
+	spanExporter, err := newSpanExporter(ctx, exporter)
+	if err != nil {
+		return nil, fmt.Errorf("in NewTracerProvider: %w", err)
+	}
+
+	return newTracerProvider(spanExporter, serviceName, opts...), nil
+}
+
+// newSpanExporter returns the span exporter with the given name, or nil for ExporterNone.
+func newSpanExporter(ctx context.Context, name string) (sdktrace.SpanExporter, error) {
+	switch strings.ToLower(strings.TrimSpace(name)) {
+	case ExporterOTLP:
+		exporter, err := otlptracehttp.New(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("in newSpanExporter: %q: %w", name, err)
+		}
+		return exporter, nil
+	case ExporterStdout:
+		exporter, err := stdouttrace.New()
+		if err != nil {
+			return nil, fmt.Errorf("in newSpanExporter: %q: %w", name, err)
+		}
+		return exporter, nil
+	case ExporterNone, "":
+		return nil, nil
+	default:
+		return nil, fmt.Errorf("in newSpanExporter: %q: %w", name, ErrUnknownExporter)
+	}
+}
+
+// newTracerProvider returns a TracerProvider that sends the spans of serviceName to exporter in batches.
+// If exporter is nil, spans are started and propagated but not sent anywhere.
+func newTracerProvider(exporter sdktrace.SpanExporter, serviceName string, opts ...TracerOption) *sdktrace.TracerProvider {
+	options := tracerOptions{
+		sampleRatio: 1,
+	}
+	for _, opt := range opts {
+		opt(&options)
+	}
+
+	providerOpts := []sdktrace.TracerProviderOption{
+		sdktrace.WithResource(resource.NewWithAttributes(semconv.SchemaURL, semconv.ServiceName(serviceName))),
+		sdktrace.WithSampler(sdktrace.ParentBased(sdktrace.TraceIDRatioBased(options.sampleRatio))),
+	}
+	if exporter != nil {
+		providerOpts = append(providerOpts, sdktrace.WithBatcher(exporter))
+	}
+
+	return sdktrace.NewTracerProvider(providerOpts...)
+}
+
+// returnedRowsKey is the number of rows that a statement returned or affected.
+const returnedRowsKey = attribute.Key("db.response.returned_rows")
+
+// wrapConnector returns a connector whose connections run every statement in a span of provider,
+// with the statement and the number of rows it returned or affected. Spans are only started for
+// statements whose context already has a span, so background work such as migrations does not
+// start traces of its own.
+func wrapConnector(connector driver.Connector, provider trace.TracerProvider) driver.Connector {
+	return &tracedConnector{
+		Connector: connector,
+		tracer:    provider.Tracer(instrumentationName),
+	}
+}
+
+type tracedConnector struct {
+	driver.Connector
+	tracer trace.Tracer
+}
+
+func (c *tracedConnector) Connect(ctx context.Context) (driver.Conn, error) {
+	conn, err := c.Connector.Connect(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return &tracedConn{Conn: conn, tracer: c.tracer}, nil
+}
+
+// startSpan starts the span of query, or returns a nil span if ctx has no span to be a child of.
+func startSpan(ctx context.Context, tracer trace.Tracer, query string) (context.Context, trace.Span) {
+	if !trace.SpanContextFromContext(ctx).IsValid() {
+		return ctx, nil
+	}
+
+	operation := "query"
+	if fields := strings.Fields(query); len(fields) > 0 {
+		operation = strings.ToUpper(fields[0])
+	}
+
+	return tracer.Start(
+		ctx,
+		operation,
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(
+			semconv.DBSystemPostgreSQL,
+			semconv.DBQueryText(query),
+			semconv.DBOperationName(operation),
+		),
+	)
+}
+
+// endSpan ends span with the outcome of its statement. A nil span is ignored.
+func endSpan(span trace.Span, rows int64, err error) {
+	if span == nil {
+		return
+	}
+	if err != nil && !errors.Is(err, driver.ErrSkip) {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	} else {
+		span.SetAttributes(returnedRowsKey.Int64(rows))
+	}
+	span.End()
+}
+
+// affectedRows returns the number of rows that result affected, or 0 if the driver does not know.
+func affectedRows(result driver.Result) int64 {
+	if result == nil {
+		return 0
+	}
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return 0
+	}
+	return rows
+}
+
+// tracedConn is a driver.Conn that traces its statements. It implements the optional interfaces
+// of database/sql by falling back to the wrapped connection, or returning driver.ErrSkip where
+// database/sql falls back itself.
+type tracedConn struct {
+	driver.Conn
+	tracer trace.Tracer
+}
+
+func (c *tracedConn) Prepare(query string) (driver.Stmt, error) {
+	return c.PrepareContext(context.Background(), query)
+}
+
+func (c *tracedConn) PrepareContext(ctx context.Context, query string) (driver.Stmt, error) {
+	var (
+		stmt driver.Stmt
+		err  error
+	)
+	if preparer, ok := c.Conn.(driver.ConnPrepareContext); ok {
+		stmt, err = preparer.PrepareContext(ctx, query)
+	} else {
+		stmt, err = c.Conn.Prepare(query)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &tracedStmt{Stmt: stmt, tracer: c.tracer, query: query}, nil
+}
+
+func (c *tracedConn) BeginTx(ctx context.Context, opts driver.TxOptions) (driver.Tx, error) {
+	if beginner, ok := c.Conn.(driver.ConnBeginTx); ok {
+		return beginner.BeginTx(ctx, opts)
+	}
+	// Begin is the fallback for drivers without BeginTx.
+	return c.Conn.Begin()
+}
+
+func (c *tracedConn) QueryContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Rows, error) {
+	queryer, ok := c.Conn.(driver.QueryerContext)
+	if !ok {
+		return nil, driver.ErrSkip
+	}
+
+	ctx, span := startSpan(ctx, c.tracer, query)
+	rows, err := queryer.QueryContext(ctx, query, args)
+	if err != nil {
+		endSpan(span, 0, err)
+		return nil, err
+	}
+	if span == nil {
+		return rows, nil
+	}
+	return &tracedRows{Rows: rows, span: span}, nil
+}
+
+func (c *tracedConn) ExecContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Result, error) {
+	execer, ok := c.Conn.(driver.ExecerContext)
+	if !ok {
+		return nil, driver.ErrSkip
+	}
+
+	ctx, span := startSpan(ctx, c.tracer, query)
+	result, err := execer.ExecContext(ctx, query, args)
+	endSpan(span, affectedRows(result), err)
+	return result, err
+}
+
+func (c *tracedConn) Ping(ctx context.Context) error {
+	if pinger, ok := c.Conn.(driver.Pinger); ok {
+		return pinger.Ping(ctx)
+	}
+	return nil
+}
+
+func (c *tracedConn) ResetSession(ctx context.Context) error {
+	if resetter, ok := c.Conn.(driver.SessionResetter); ok {
+		return resetter.ResetSession(ctx)
+	}
+	return nil
+}
+
+func (c *tracedConn) IsValid() bool {
+	if validator, ok := c.Conn.(driver.Validator); ok {
+		return validator.IsValid()
+	}
+	return true
+}
+
+func (c *tracedConn) CheckNamedValue(value *driver.NamedValue) error {
+	if checker, ok := c.Conn.(driver.NamedValueChecker); ok {
+		return checker.CheckNamedValue(value)
+	}
+	return driver.ErrSkip
+}
+
+// tracedStmt is a driver.Stmt that traces its executions.
+type tracedStmt struct {
+	driver.Stmt
+	tracer trace.Tracer
+	query  string
+}
+
+func (s *tracedStmt) ExecContext(ctx context.Context, args []driver.NamedValue) (driver.Result, error) {
+	ctx, span := startSpan(ctx, s.tracer, s.query)
+
+	var (
+		result driver.Result
+		err    error
+	)
+	if execer, ok := s.Stmt.(driver.StmtExecContext); ok {
+		result, err = execer.ExecContext(ctx, args)
+	} else {
+		// Exec is the fallback for drivers without ExecContext.
+		result, err = s.Stmt.Exec(namedValuesToValues(args))
+	}
+
+	endSpan(span, affectedRows(result), err)
+	return result, err
+}
+
+func (s *tracedStmt) QueryContext(ctx context.Context, args []driver.NamedValue) (driver.Rows, error) {
+	ctx, span := startSpan(ctx, s.tracer, s.query)
+
+	var (
+		rows driver.Rows
+		err  error
+	)
+	if queryer, ok := s.Stmt.(driver.StmtQueryContext); ok {
+		rows, err = queryer.QueryContext(ctx, args)
+	} else {
+		// Query is the fallback for drivers without QueryContext.
+		rows, err = s.Stmt.Query(namedValuesToValues(args))
+	}
+
+	if err != nil {
+		endSpan(span, 0, err)
+		return nil, err
+	}
+	if span == nil {
+		return rows, nil
+	}
+	return &tracedRows{Rows: rows, span: span}, nil
+}
+
+func (s *tracedStmt) CheckNamedValue(value *driver.NamedValue) error {
+	if checker, ok := s.Stmt.(driver.NamedValueChecker); ok {
+		return checker.CheckNamedValue(value)
+	}
+	return driver.ErrSkip
+}
+
+// namedValuesToValues returns the values of args, for drivers that predate named values.
+func namedValuesToValues(args []driver.NamedValue) []driver.Value {
+	vals := make([]driver.Value, len(args))
+	for i, arg := range args {
+		vals[i] = arg.Value
+	}
+	return vals
+}
+
+// tracedRows is a driver.Rows that counts its rows and ends the span of its query once closed.
+type tracedRows struct {
+	driver.Rows
+	span  trace.Span
+	count int64
+	err   error
+}
+
+func (r *tracedRows) Next(dest []driver.Value) error {
+	err := r.Rows.Next(dest)
+	switch {
+	case err == nil:
+		r.count++
+	case !errors.Is(err, io.EOF):
+		r.err = err
+	}
+	return err
+}
+
+func (r *tracedRows) Close() error {
+	err := r.Rows.Close()
+	if r.span != nil {
+		endSpan(r.span, r.count, r.err)
+		r.span = nil
+	}
+	return err
+}
+
+// The column types of the wrapped rows, with the defaults of database/sql for drivers that do not
+// report them.
+
+func (r *tracedRows) ColumnTypeScanType(index int) reflect.Type {
+	if rows, ok := r.Rows.(driver.RowsColumnTypeScanType); ok {
+		return rows.ColumnTypeScanType(index)
+	}
+	return reflect.TypeOf(new(any)).Elem()
+}
+
+func (r *tracedRows) ColumnTypeDatabaseTypeName(index int) string {
+	if rows, ok := r.Rows.(driver.RowsColumnTypeDatabaseTypeName); ok {
+		return rows.ColumnTypeDatabaseTypeName(index)
+	}
+	return ""
+}
+
+func (r *tracedRows) ColumnTypeLength(index int) (int64, bool) {
+	if rows, ok := r.Rows.(driver.RowsColumnTypeLength); ok {
+		return rows.ColumnTypeLength(index)
+	}
+	return 0, false
+}
+
+func (r *tracedRows) ColumnTypeNullable(index int) (bool, bool) {
+	if rows, ok := r.Rows.(driver.RowsColumnTypeNullable); ok {
+		return rows.ColumnTypeNullable(index)
+	}
+	return false, false
+}
+
+func (r *tracedRows) ColumnTypePrecisionScale(index int) (int64, int64, bool) {
+	if rows, ok := r.Rows.(driver.RowsColumnTypePrecisionScale); ok {
+		return rows.ColumnTypePrecisionScale(index)
+	}
+	return 0, 0, false
+}
@@ -1,25 +1,71 @@
 package main
 
 import (
+	"context"
 	"database/sql"
 	"errors"
 	"fmt"
+
+	"github.com/lib/pq"
+)
+
+// pgUniqueViolation is the Postgres error code raised when a unique constraint is violated.
+const pgUniqueViolation = "23505"
+
+var (
+	// ErrUserNotFound is returned when no user exists with the requested ID.
+	ErrUserNotFound = errors.New("user not found")
+
+	// ErrUserIDConflict is returned when a user is created or updated with a user_id that already
+	// belongs to another user.
+	ErrUserIDConflict = errors.New("user_id already in use")
 )
 
 type UserService struct {
-	DB Database
+	DB      Database
+	changes changeCounter
+}
+
+// changeCounter counts the users that a UserService changes, such as Metrics.
+type changeCounter interface {
+	UsersCreated(count int)
+	UsersUpdated(count int)
+	UsersDeleted(count int)
+}
+
+// uncounted is the changeCounter of a UserService that counts no changes.
+type uncounted struct{}
+
+func (uncounted) UsersCreated(int) {}
+func (uncounted) UsersUpdated(int) {}
+func (uncounted) UsersDeleted(int) {}
+
+type UserServiceOption func(*UserService)
+
+// WithChangeCounter counts every user that the UserService creates, updates or deletes with
+// changes.
+func WithChangeCounter(changes changeCounter) UserServiceOption {
+	return func(us *UserService) {
+		us.changes = changes
+	}
 }
 
 // NewUserService returns a new UserService struct.
-func NewUserService(db Database) UserService {
-	return UserService{
-		DB: db,
+func NewUserService(db Database, opts ...UserServiceOption) UserService {
+	us := UserService{
+		DB:      db,
+		changes: uncounted{},
+	}
+	for _, opt := range opts {
+		opt(&us)
 	}
+	return us
 }
 
 // ListUsers returns a list of all User objects from the Database.
-func (us UserService) ListUsers() ([]User, error) {
-	rows, err := us.DB.Session.Query(
+func (us UserService) ListUsers(ctx context.Context) ([]User, error) {
+	rows, err := us.DB.Session.QueryContext(
+		ctx,
 		`
 		SELECT 
 		    * 
@@ -50,10 +96,11 @@ func (us UserService) ListUsers() ([]User, error) {
 }
 
 // FetchUser returns am User objects from the Database by ID.
-func (us UserService) FetchUser(ID int) (User, error) {
+func (us UserService) FetchUser(ctx context.Context, ID int) (User, error) {
 	var user User
 	err := us.DB.Session.
-		QueryRow(
+		QueryRowContext(
+			ctx,
 			`
 			SELECT
 				*
@@ -70,17 +117,46 @@ func (us UserService) FetchUser(ID int) (User, error) {
 		Scan(&user.ID, &user.FirstName, &user.LastName, &user.Role, &user.UserID)
 	if err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
-			return User{}, nil
+			err = ErrUserNotFound
+		}
+		return User{}, fmt.Errorf("in FetchUser: %w", err)
+	}
+
+	return user, nil
+}
+
+// FetchUserByUserID returns the User object from the Database with the user_id userID.
+func (us UserService) FetchUserByUserID(ctx context.Context, userID uint) (User, error) {
+	var user User
+	err := us.DB.Session.
+		QueryRowContext(
+			ctx,
+			`
+			SELECT
+				"id", "first_name", "last_name", "role", "user_id"
+			FROM
+				"users"
+			WHERE
+				"user_id" = $1
+			LIMIT 1
+			`,
+			userID,
+		).
+		Scan(&user.ID, &user.FirstName, &user.LastName, &user.Role, &user.UserID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			err = ErrUserNotFound
 		}
-		return User{}, fmt.Errorf("in ListUsers:, %w", err)
+		return User{}, fmt.Errorf("in FetchUserByUserID: %w", err)
 	}
 
 	return user, nil
 }
 
 // UpdateUser updates am User objects from the Database by ID.
-func (us UserService) UpdateUser(ID int, user User) (User, error) {
-	_, err := us.DB.Session.Exec(
+func (us UserService) UpdateUser(ctx context.Context, ID int, user User) (User, error) {
+	result, err := us.DB.Session.ExecContext(
+		ctx,
 		`
 		UPDATE
 			"users"
@@ -99,17 +175,22 @@ func (us UserService) UpdateUser(ID int, user User) (User, error) {
 		ID,
 	)
 	if err != nil {
+		return User{}, fmt.Errorf("in UpdateUser: %w", mapUniqueViolation(err))
+	}
+	if err = requireRowsAffected(result); err != nil {
 		return User{}, fmt.Errorf("in UpdateUser: %w", err)
 	}
+	us.changes.UsersUpdated(1)
 
 	user.ID = uint(ID)
 	return user, nil
 }
 
 // CreateUser creates am User objects in the Database.
-func (us UserService) CreateUser(user User) (int, error) {
+func (us UserService) CreateUser(ctx context.Context, user User) (int, error) {
 	var ID int
-	err := us.DB.Session.QueryRow(
+	err := us.DB.Session.QueryRowContext(
+		ctx,
 		`
 		INSERT INTO "users" ("first_name", "last_name", "role", "user_id")
 			VALUES ($1, $2, $3, $4)
@@ -121,15 +202,17 @@ func (us UserService) CreateUser(user User) (int, error) {
 		user.UserID,
 	).Scan(&ID)
 	if err != nil {
-		return 0, fmt.Errorf("in CreateUser: %w", err)
+		return 0, fmt.Errorf("in CreateUser: %w", mapUniqueViolation(err))
 	}
+	us.changes.UsersCreated(1)
 
 	return ID, nil
 }
 
 // DeleteUser deletes am User objects from the Database by ID.
-func (us UserService) DeleteUser(ID int) error {
-	_, err := us.DB.Session.Exec(
+func (us UserService) DeleteUser(ctx context.Context, ID int) error {
+	result, err := us.DB.Session.ExecContext(
+		ctx,
 		`
 		DELETE FROM "users"
 		WHERE "users"."id" = $1
@@ -137,8 +220,36 @@ func (us UserService) DeleteUser(ID int) error {
 		ID,
 	)
 	if err != nil {
-		return fmt.Errorf("could not delete user: %v", err)
+		return fmt.Errorf("in DeleteUser: %w", err)
+	}
+	if err = requireRowsAffected(result); err != nil {
+		return fmt.Errorf("in DeleteUser: %w", err)
 	}
+	us.changes.UsersDeleted(1)
 
 	return nil
 }
+
+// requireRowsAffected returns ErrUserNotFound when result reports that no rows were changed.
+func requireRowsAffected(result sql.Result) error {
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rows == 0 {
+		return ErrUserNotFound
+	}
+
+	return nil
+}
+
+// mapUniqueViolation converts a Postgres unique violation into ErrUserIDConflict. Other errors are
+// returned unchanged.
+func mapUniqueViolation(err error) error {
+	var pqErr *pq.Error
+	if errors.As(err, &pqErr) && pqErr.Code == pgUniqueViolation {
+		return fmt.Errorf("%w: %w", ErrUserIDConflict, err)
+	}
+
+	return err
+}
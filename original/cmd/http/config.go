@@ -7,24 +7,57 @@ import (
 	"os"
 	"reflect"
 	"strconv"
+	"strings"
 
 	"github.com/joho/godotenv"
 )
 
 type configuration struct {
-	Env      string `env:"ENV"`
-	Database struct {
-		Name            string `env:"DATABASE_NAME"`
-		User            string `env:"DATABASE_USER"`
-		Password        string `env:"DATABASE_PASSWORD"`
-		Host            string `env:"DATABASE_HOST"`
-		Port            string `env:"DATABASE_PORT"`
-		ConnectionRetry int    `env:"DATABASE_CONNECTION_RETRY"`
+	Env        string `env:"ENV"`
+	AdminToken string `env:"ADMIN_TOKEN"`
+	Database   struct {
+		Name               string `env:"DATABASE_NAME"`
+		User               string `env:"DATABASE_USER"`
+		Password           string `env:"DATABASE_PASSWORD"`
+		Host               string `env:"DATABASE_HOST"`
+		Port               string `env:"DATABASE_PORT"`
+		ConnectionRetry    int    `env:"DATABASE_CONNECTION_RETRY"`
+		MigrateOnStartup   bool   `env:"DATABASE_MIGRATE_ON_STARTUP"`
+		MaxOpenConnections int    `env:"DATABASE_MAX_OPEN_CONNECTIONS,optional"`
 	}
 	HTTP struct {
 		Domain              string `env:"HTTP_DOMAIN"`
 		Port                string `env:"HTTP_PORT"`
 		ShutdownGracePeriod int    `env:"HTTP_SHUTDOWN_GRACE_PERIOD"`
+		DrainDelaySeconds   int    `env:"HTTP_DRAIN_DELAY_SECONDS,optional"`
+	}
+	Health struct {
+		TimeoutSeconds    int     `env:"HEALTH_TIMEOUT_SECONDS,optional"`
+		MaxPoolSaturation float64 `env:"HEALTH_MAX_POOL_SATURATION,optional"`
+	}
+	Auth struct {
+		Disabled           bool   `env:"AUTH_DISABLED"`
+		JWKSURL            string `env:"AUTH_JWKS_URL,optional"`
+		KeyFile            string `env:"AUTH_KEY_FILE,optional"`
+		Issuer             string `env:"AUTH_ISSUER,optional"`
+		Audience           string `env:"AUTH_AUDIENCE,optional"`
+		LeewaySeconds      int    `env:"AUTH_LEEWAY_SECONDS,optional"`
+		JWKSRefreshSeconds int    `env:"AUTH_JWKS_REFRESH_SECONDS,optional"`
+		PolicyFile         string `env:"AUTH_POLICY_FILE,optional"`
+	}
+	Tracing struct {
+		Exporter    string  `env:"TRACING_EXPORTER,optional"`
+		ServiceName string  `env:"TRACING_SERVICE_NAME,optional"`
+		SampleRatio float64 `env:"TRACING_SAMPLE_RATIO,optional"`
+	}
+	Metrics struct {
+		Port string `env:"METRICS_PORT,optional"`
+	}
+	Log struct {
+		RedactPII    bool   `env:"LOG_REDACT_PII,optional"`
+		RedactMode   string `env:"LOG_REDACT_MODE,optional"`
+		RedactSecret string `env:"LOG_REDACT_SECRET,optional"`
+		RedactKeys   string `env:"LOG_REDACT_KEYS,optional"`
 	}
 }
 
@@ -55,7 +88,8 @@ func loadEnv() {
 // with that tag will be retrieved and added to the struct.
 //
 // If the `errOnMissingValue` flag is set to `true`, any tag that is missing an environment variable
-// will result in an error being returned.
+// will result in an error being returned. Tags with the `optional` option, such as
+// `env:"AUTH_JWKS_URL,optional"`, are left at their zero value when the variable is missing.
 func ParseStructFromEnv(obj any, errOnMissingValue bool) (err error) {
 	defer func() {
 		if err != nil {
@@ -83,7 +117,10 @@ func ParseStructFromEnv(obj any, errOnMissingValue bool) (err error) {
 
 		// Get and then set env value based on tag if present
 		fieldType := val.Type().Field(i)
-		envTag := fieldType.Tag.Get("env")
+		envTag, tagOptions, _ := strings.Cut(fieldType.Tag.Get("env"), ",")
+		if tagOptions == "optional" && os.Getenv(envTag) == "" {
+			continue
+		}
 
 		if field.CanSet() && envTag != "" {
 			switch field.Kind() {
@@ -105,6 +142,12 @@ func ParseStructFromEnv(obj any, errOnMissingValue bool) (err error) {
 					return err
 				}
 				field.SetBool(value)
+			case reflect.Float64:
+				value, err := getEnvFloat64(envTag, errOnMissingValue)
+				if err != nil {
+					return err
+				}
+				field.SetFloat(value)
 			default:
 				continue
 			}
@@ -13,17 +13,24 @@ import (
 	"time"
 
 	"user-microservice/cmd/http/route"
+	"user-microservice/internal/auth"
 	"user-microservice/internal/database"
+	"user-microservice/internal/health"
+	"user-microservice/internal/logging"
+	"user-microservice/internal/metrics"
+	"user-microservice/internal/policy"
+	"user-microservice/internal/tracing"
 	"user-microservice/internal/user"
 
 	"github.com/go-chi/chi/v5"
 	"github.com/go-chi/chi/v5/middleware"
 
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
 	"gorm.io/driver/postgres"
 )
 
 func main() {
-	logger, config, router := SetupServer()
+	logger, config, router, checker, provider, recorder := SetupServer()
 	fmt.Printf("%+v", config)
 
 	server := &http.Server{
@@ -31,6 +38,21 @@ func main() {
 		Handler: router,
 	}
 
+	// Metrics are served on their own port, so they are not exposed with the API
+	metricsRouter := chi.NewRouter()
+	metricsRouter.Method(http.MethodGet, "/metrics", recorder.Handler())
+	metricsServer := &http.Server{
+		Addr:    config.HTTP.Domain + metricsPort(config),
+		Handler: metricsRouter,
+	}
+	go func() {
+		logger.Info(fmt.Sprintf("Metrics are served on %s", metricsServer.Addr))
+		err := metricsServer.ListenAndServe()
+		if err != nil && !errors.Is(err, http.ErrServerClosed) {
+			logger.Error("Metrics server stopped", "error", err)
+		}
+	}()
+
 	// Server run context
 	serverCtx, serverStopCtx := context.WithCancel(context.Background())
 
@@ -41,12 +63,18 @@ func main() {
 		<-sig
 
 		fmt.Println()
-		logger.Info("Shutdown signal received")
+		logger.Info("Shutdown signal received, draining", "delay seconds", config.HTTP.DrainDelaySeconds)
+
+		// Fail readiness, so load balancers stop sending requests before the server stops
+		// accepting them
+		checker.Drain()
+		time.Sleep(time.Duration(config.HTTP.DrainDelaySeconds) * time.Second)
 
 		// Shutdown signal with grace period of 30 seconds
-		shutdownCtx, _ := context.WithTimeout(
+		shutdownCtx, cancel := context.WithTimeout(
 			serverCtx, time.Duration(config.HTTP.ShutdownGracePeriod)*time.Second,
 		)
+		defer cancel()
 
 		go func() {
 			<-shutdownCtx.Done()
@@ -60,6 +88,9 @@ func main() {
 		if err != nil {
 			log.Fatal(err)
 		}
+		if err = metricsServer.Shutdown(shutdownCtx); err != nil {
+			logger.Error("Error shutting down metrics server", "error", err)
+		}
 		serverStopCtx()
 	}()
 
@@ -72,13 +103,23 @@ func main() {
 
 	// Wait for server context to be stopped
 	<-serverCtx.Done()
+
+	// Send the spans that are still buffered
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err = provider.Shutdown(shutdownCtx); err != nil {
+		logger.Error("Error shutting down tracer provider", "error", err)
+	}
+
 	logger.Info("Shutdown complete")
 }
 
-func SetupServer() (*slog.Logger, configuration, *chi.Mux) {
+func SetupServer() (*slog.Logger, configuration, *chi.Mux, *health.Checker, *sdktrace.TracerProvider, *metrics.Prometheus) {
 	config := mustNewConfiguration()
 
-	logger := newLogger(false)
+	logger := mustRedactPII(newLogger(false), config)
+
+	provider := mustNewTracerProvider(config)
 
 	db := database.MustNewDatabase(
 		postgres.Open(
@@ -93,19 +134,145 @@ func SetupServer() (*slog.Logger, configuration, *chi.Mux) {
 		),
 		database.WithLogger(logger),
 		database.WithRetryCount(5),
-		database.WithAutoMigrate(true),
+		database.WithMigrations(config.Database.MigrateOnStartup),
+		database.WithSchemaCheck(true),
+		database.WithTracing(provider),
 	)
 
-	us := user.NewService(db)
+	checker := mustNewChecker(db, config, logger)
+	recorder := mustNewRecorder(db, config)
+	us := user.NewService(db, user.WithChangeCounter(recorder))
 
-	h := route.NewHandler(us, logger)
+	options := []route.HandlerOptions{
+		route.WithAdminToken(config.AdminToken),
+		route.WithHealthChecker(checker),
+	}
+	if config.Auth.Disabled {
+		logger.Warn("AUTH_DISABLED is set, requests are not authenticated")
+	} else {
+		options = append(options,
+			route.WithVerifier(mustNewVerifier(config)),
+			route.WithPolicy(mustNewPolicy(config.Auth.PolicyFile)),
+		)
+	}
+
+	h := route.NewHandler(us, logger, options...)
 
 	r := chi.NewRouter()
 
+	r.Use(tracing.Middleware(provider, tracing.ChiRoute))
+	r.Use(metrics.Middleware(recorder, tracing.ChiRoute))
+	r.Use(logging.Middleware(logger, r))
 	r.Use(middleware.Logger)
 	r.Use(middleware.Recoverer)
 
 	route.SetUpRoutes(r, h)
 
-	return logger, config, r
+	return logger, config, r, checker, provider, recorder
+}
+
+// mustNewRecorder returns the Prometheus metrics of the service, including the connection pool
+// stats of db, or panics if the connection pool can not be read.
+func mustNewRecorder(db database.Database, config configuration) *metrics.Prometheus {
+	sqlDB, err := db.Session.DB()
+	if err != nil {
+		panic(fmt.Sprintf("Error getting database connection pool: %v", err))
+	}
+	return metrics.NewPrometheus(sqlDB, config.Database.Name)
+}
+
+// metricsPort returns the METRICS_PORT of config, or `:9090` if it is not set.
+func metricsPort(config configuration) string {
+	if config.Metrics.Port == "" {
+		return ":9090"
+	}
+	return config.Metrics.Port
+}
+
+// mustNewChecker returns the readiness checker of db, or panics if it can not be created. The
+// connection pool of db is limited to DATABASE_MAX_OPEN_CONNECTIONS if it is set.
+func mustNewChecker(db database.Database, config configuration, logger *slog.Logger) *health.Checker {
+	sqlDB, err := db.Session.DB()
+	if err != nil {
+		panic(fmt.Sprintf("Error getting database connection pool: %v", err))
+	}
+	if config.Database.MaxOpenConnections > 0 {
+		sqlDB.SetMaxOpenConns(config.Database.MaxOpenConnections)
+	}
+
+	migrator, err := database.NewMigrator(sqlDB, logger)
+	if err != nil {
+		panic(fmt.Sprintf("Error creating migrator: %v", err))
+	}
+
+	var options []health.Option
+	if config.Health.TimeoutSeconds > 0 {
+		options = append(options, health.WithTimeout(time.Duration(config.Health.TimeoutSeconds)*time.Second))
+	}
+	if config.Health.MaxPoolSaturation > 0 {
+		options = append(options, health.WithMaxPoolSaturation(config.Health.MaxPoolSaturation))
+	}
+	return health.NewChecker(sqlDB, migrator, options...)
+}
+
+// mustNewTracerProvider returns the tracer provider configured by config, which sends spans to the
+// TRACING_EXPORTER. It panics if the exporter is unknown or can not be created.
+func mustNewTracerProvider(config configuration) *sdktrace.TracerProvider {
+	serviceName := config.Tracing.ServiceName
+	if serviceName == "" {
+		serviceName = "user-microservice"
+	}
+
+	var options []tracing.Option
+	if config.Tracing.SampleRatio > 0 {
+		options = append(options, tracing.WithSampleRatio(config.Tracing.SampleRatio))
+	}
+
+	provider, err := tracing.New(context.Background(), config.Tracing.Exporter, serviceName, options...)
+	if err != nil {
+		panic(fmt.Sprintf("Error creating tracer provider: %v", err))
+	}
+	return provider
+}
+
+// mustNewVerifier returns a verifier for the bearer tokens of requests, using the keys of the JWKS
+// URL or key file set in config, or panics if config is incomplete.
+func mustNewVerifier(config configuration) *auth.Verifier {
+	if config.Auth.Issuer == "" || config.Auth.Audience == "" {
+		panic("AUTH_ISSUER and AUTH_AUDIENCE must be set unless AUTH_DISABLED is true")
+	}
+
+	var keys auth.KeySource
+	switch {
+	case config.Auth.JWKSURL != "":
+		var options []auth.JWKSOption
+		if config.Auth.JWKSRefreshSeconds > 0 {
+			options = append(options, auth.WithRefreshInterval(time.Duration(config.Auth.JWKSRefreshSeconds)*time.Second))
+		}
+		keys = auth.NewJWKS(config.Auth.JWKSURL, options...)
+	case config.Auth.KeyFile != "":
+		keyFile, err := auth.LoadKeyFile(config.Auth.KeyFile)
+		if err != nil {
+			panic(fmt.Sprintf("Error loading AUTH_KEY_FILE: %v", err))
+		}
+		keys = keyFile
+	default:
+		panic("AUTH_JWKS_URL or AUTH_KEY_FILE must be set unless AUTH_DISABLED is true")
+	}
+
+	var options []auth.VerifierOption
+	if config.Auth.LeewaySeconds > 0 {
+		options = append(options, auth.WithLeeway(time.Duration(config.Auth.LeewaySeconds)*time.Second))
+	}
+	return auth.NewVerifier(keys, config.Auth.Issuer, config.Auth.Audience, options...)
+}
+
+// mustNewPolicy returns the policy of the file at path, or the default policy if path is empty. It
+// panics if the file can not be loaded.
+func mustNewPolicy(path string) policy.Policy {
+	rules, err := policy.New(path)
+	if err != nil {
+		panic(fmt.Sprintf("Error loading AUTH_POLICY_FILE: %v", err))
+	}
+	return rules
 }
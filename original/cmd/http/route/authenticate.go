@@ -0,0 +1,64 @@
+package route
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strings"
+
+	"user-microservice/internal/auth"
+	"user-microservice/internal/problem"
+)
+
+type tokenVerifier interface {
+	Verify(context.Context, string) (auth.Claims, error)
+}
+
+// WithVerifier sets the verifier used to authenticate requests to the user routes. Every request
+// must then carry a bearer token that the verifier accepts, and the claims of that token are put
+// into the request context, where they can be read with auth.FromContext. If no verifier is set,
+// the user routes are served without authentication.
+func WithVerifier(verifier tokenVerifier) HandlerOptions {
+	return func(h *Handler) {
+		h.verifier = verifier
+	}
+}
+
+// authenticate is a middleware that refuses requests without a bearer token that the verifier of
+// h accepts.
+func (h Handler) authenticate(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		token, ok := bearerToken(r)
+		if !ok {
+			h.requestLogger(r).Error("request without bearer token", "path", r.URL.Path)
+			w.Header().Set("WWW-Authenticate", "Bearer")
+			h.encodeProblem(w, r, problem.New(http.StatusUnauthorized, "A bearer token is required"))
+			return
+		}
+
+		claims, err := h.verifier.Verify(r.Context(), token)
+		if err != nil {
+			if errors.Is(err, auth.ErrInvalidToken) {
+				h.requestLogger(r).Error("invalid bearer token", "error", err)
+				w.Header().Set("WWW-Authenticate", `Bearer error="invalid_token"`)
+				h.encodeProblem(w, r, problem.New(http.StatusUnauthorized, "The bearer token is not valid"))
+				return
+			}
+			h.requestLogger(r).Error("error verifying bearer token", "error", err)
+			h.encodeProblem(w, r, problem.New(http.StatusServiceUnavailable, "Unable to verify the bearer token"))
+			return
+		}
+
+		next.ServeHTTP(w, r.WithContext(auth.WithClaims(r.Context(), claims)))
+	})
+}
+
+// bearerToken returns the token of the Authorization header of r if it uses the Bearer scheme.
+func bearerToken(r *http.Request) (string, bool) {
+	scheme, token, ok := strings.Cut(r.Header.Get("Authorization"), " ")
+	token = strings.TrimSpace(token)
+	if !ok || !strings.EqualFold(scheme, "Bearer") || token == "" {
+		return "", false
+	}
+	return token, true
+}
@@ -0,0 +1,144 @@
+package route
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+
+	"user-microservice/internal/auth"
+	"user-microservice/internal/database/entity"
+	"user-microservice/internal/health"
+	"user-microservice/internal/problem"
+)
+
+// MOCKS
+
+type verifierMock struct {
+	mock.Mock
+}
+
+func (vm *verifierMock) Verify(ctx context.Context, token string) (auth.Claims, error) {
+	args := vm.Called(ctx, token)
+	return args.Get(0).(auth.Claims), args.Error(1)
+}
+
+// ━━ TESTS ━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━
+
+func TestAuthenticate(t *testing.T) {
+	claims := auth.Claims{Subject: "jdoe", Audience: []string{"user-microservice"}}
+
+	testCases := map[string]struct {
+		path                 string
+		header               string
+		mockCalled           bool
+		mockReturnArgs       []any
+		expectedStatus       int
+		expectedAuthenticate string
+		expectedBody         any
+	}{
+		"200 - valid token": {
+			path:           "/api/user/",
+			header:         "Bearer token",
+			mockCalled:     true,
+			mockReturnArgs: []any{claims, nil},
+			expectedStatus: http.StatusOK,
+			expectedBody:   responseAllUsers{Users: []entity.User{}},
+		},
+		"200 - health check needs no token": {
+			path:           "/api/health/live",
+			expectedStatus: http.StatusOK,
+			expectedBody:   health.Report{Status: health.StatusOK},
+		},
+		"401 - no token": {
+			path:                 "/api/user/",
+			expectedStatus:       http.StatusUnauthorized,
+			expectedAuthenticate: "Bearer",
+			expectedBody:         problem.New(http.StatusUnauthorized, "A bearer token is required").WithInstance("/api/user/"),
+		},
+		"401 - not a bearer token": {
+			path:                 "/api/user/",
+			header:               "Basic dXNlcjpwYXNz",
+			expectedStatus:       http.StatusUnauthorized,
+			expectedAuthenticate: "Bearer",
+			expectedBody:         problem.New(http.StatusUnauthorized, "A bearer token is required").WithInstance("/api/user/"),
+		},
+		"401 - invalid token": {
+			path:                 "/api/user/",
+			header:               "Bearer token",
+			mockCalled:           true,
+			mockReturnArgs:       []any{auth.Claims{}, fmt.Errorf("expired: %w", auth.ErrInvalidToken)},
+			expectedStatus:       http.StatusUnauthorized,
+			expectedAuthenticate: `Bearer error="invalid_token"`,
+			expectedBody:         problem.New(http.StatusUnauthorized, "The bearer token is not valid").WithInstance("/api/user/"),
+		},
+		"503 - keys unavailable": {
+			path:           "/api/user/",
+			header:         "Bearer token",
+			mockCalled:     true,
+			mockReturnArgs: []any{auth.Claims{}, fmt.Errorf("fetch JWK Set: unexpected status 503")},
+			expectedStatus: http.StatusServiceUnavailable,
+			expectedBody:   problem.New(http.StatusServiceUnavailable, "Unable to verify the bearer token").WithInstance("/api/user/"),
+		},
+	}
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			mockVerifier := new(verifierMock)
+			if tc.mockCalled {
+				mockVerifier.
+					On("Verify", mock.Anything, "token").
+					Return(tc.mockReturnArgs...).
+					Once()
+			}
+
+			userServiceMock := new(serviceMock)
+			userServiceMock.On("List", mock.Anything, false).Return([]entity.User{}, nil).Maybe()
+
+			router := chi.NewRouter()
+			SetUpRoutes(router, NewHandler(userServiceMock, slog.Default(), WithVerifier(mockVerifier)))
+
+			w := httptest.NewRecorder()
+			req, _ := http.NewRequest(http.MethodGet, tc.path, nil)
+			if tc.header != "" {
+				req.Header.Set("Authorization", tc.header)
+			}
+			router.ServeHTTP(w, req)
+
+			expectedBody, _ := json.Marshal(tc.expectedBody)
+
+			assert.Equal(t, tc.expectedStatus, w.Code, "Wrong code received")
+			assert.Equal(t, tc.expectedAuthenticate, w.Header().Get("WWW-Authenticate"), "Wrong WWW-Authenticate header")
+			assert.Equal(t, string(expectedBody), strings.TrimSpace(w.Body.String()), "Wrong response body")
+			mockVerifier.AssertExpectations(t)
+		})
+	}
+}
+
+func TestAuthenticateClaims(t *testing.T) {
+	claims := auth.Claims{Subject: "jdoe", Audience: []string{"user-microservice"}}
+
+	mockVerifier := new(verifierMock)
+	mockVerifier.On("Verify", mock.Anything, "token").Return(claims, nil).Once()
+
+	h := NewHandler(new(serviceMock), slog.Default(), WithVerifier(mockVerifier))
+
+	var actualClaims auth.Claims
+	next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		actualClaims, _ = auth.FromContext(r.Context())
+	})
+
+	req, _ := http.NewRequest(http.MethodGet, "/api/user/", nil)
+	req.Header.Set("Authorization", "Bearer token")
+	h.authenticate(next).ServeHTTP(httptest.NewRecorder(), req)
+
+	assert.Equal(t, claims, actualClaims, "Wrong claims in context")
+	mockVerifier.AssertExpectations(t)
+}
@@ -0,0 +1,166 @@
+package route
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/go-chi/chi/v5"
+
+	"user-microservice/internal/auth"
+	"user-microservice/internal/database/entity"
+	"user-microservice/internal/logging"
+	"user-microservice/internal/policy"
+	"user-microservice/internal/problem"
+	"user-microservice/internal/user"
+)
+
+// WithPolicy sets the policy that decides what authenticated callers may do with the user routes.
+// The caller is the user named by the user_id claim of their token, and is granted the rule the
+// policy has for their role. The policy is only applied together with WithVerifier.
+func WithPolicy(rules policy.Policy) HandlerOptions {
+	return func(h *Handler) {
+		h.policy = rules
+	}
+}
+
+// authorize is a middleware that puts the caller of an authenticated request into the request
+// context as a policy.Principal. Requests whose token has no user_id claim, or names a user that
+// does not exist, are refused.
+func (h Handler) authorize(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		claims, ok := auth.FromContext(r.Context())
+		if !ok {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		userID, ok := claims.UserID()
+		if !ok {
+			h.requestLogger(r).Error("token without user_id claim", "subject", claims.Subject)
+			h.encodeProblem(w, r, problem.New(http.StatusForbidden, "The bearer token does not identify a user"))
+			return
+		}
+
+		caller, err := h.userService.FetchByUserID(r.Context(), userID)
+		if err != nil {
+			switch {
+			case errors.Is(err, user.ErrUserNotFound):
+				h.requestLogger(r).Error("token for unknown user", "subject", claims.Subject, "user_id", userID)
+				h.encodeProblem(w, r, problem.New(http.StatusForbidden, "The bearer token does not identify a user"))
+			default:
+				h.requestLogger(r).Error("error getting user of token", "error", err)
+				h.encodeProblem(w, r, problem.New(http.StatusInternalServerError, "Internal server error"))
+			}
+			return
+		}
+
+		principal := h.policy.Principal(caller.UserID, caller.Role)
+		ctx := logging.With(r.Context(), "user_id", caller.UserID)
+		next.ServeHTTP(w, r.WithContext(policy.WithPrincipal(ctx, principal)))
+	})
+}
+
+// permit returns a middleware that refuses requests whose principal may not take action. On routes
+// of a single user, an action the principal may only take on their own record is refused if the
+// user belongs to someone else. Requests without a principal are let through, see authorize.
+func (h Handler) permit(action policy.Action) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			principal, ok := policy.FromContext(r.Context())
+			if !ok {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			scope, err := principal.Scope(action)
+			if err == nil && scope == policy.ScopeOwn {
+				owner, ok, ownerErr := h.userOwner(r.Context(), chi.URLParam(r, "ID"))
+				if ownerErr != nil {
+					h.requestLogger(r).Error("error getting object by ID", "error", ownerErr)
+					h.encodeProblem(w, r, problem.New(http.StatusInternalServerError, "Internal server error"))
+					return
+				}
+				if !ok {
+					// the handler reports the ID as invalid or the user as not existing
+					next.ServeHTTP(w, r)
+					return
+				}
+				err = principal.Authorize(action, owner)
+			}
+			if err != nil {
+				h.requestLogger(r).Error("request forbidden by policy", "path", r.URL.Path, "user_id", principal.UserID, "error", err)
+				h.encodeProblem(w, r, forbiddenProblem(err))
+				return
+			}
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// userOwner returns the user_id of the user with the ID idString, and false if idString is not a
+// valid ID or no such user exists. Routes of a collection of users have no ID, and their owner is
+// 0, which is never the user_id of a principal.
+func (h Handler) userOwner(ctx context.Context, idString string) (uint, bool, error) {
+	if idString == "" {
+		return 0, true, nil
+	}
+
+	ID, err := strconv.Atoi(idString)
+	if err != nil {
+		return 0, false, nil
+	}
+
+	found, err := h.userService.Fetch(ctx, ID, true)
+	if err != nil {
+		if errors.Is(err, user.ErrUserNotFound) {
+			return 0, false, nil
+		}
+		return 0, false, err
+	}
+
+	return found.UserID, true, nil
+}
+
+// authorizeChanges returns a *policy.ForbiddenError if updated changes a field of current that is
+// read-only for the principal of ctx. Without a principal, every change is allowed.
+func authorizeChanges(ctx context.Context, current, updated entity.User) error {
+	principal, ok := policy.FromContext(ctx)
+	if !ok {
+		return nil
+	}
+
+	var fields []string
+	if updated.FirstName != current.FirstName {
+		fields = append(fields, "first_name")
+	}
+	if updated.LastName != current.LastName {
+		fields = append(fields, "last_name")
+	}
+	if updated.Role != current.Role {
+		fields = append(fields, "role")
+	}
+	if updated.UserID != current.UserID {
+		fields = append(fields, "user_id")
+	}
+	return principal.AuthorizeChanges(fields)
+}
+
+// forbiddenProblem returns the problem reported for err, a *policy.ForbiddenError.
+func forbiddenProblem(err error) problem.Problem {
+	var forbidden *policy.ForbiddenError
+	if !errors.As(err, &forbidden) {
+		return problem.New(http.StatusForbidden, "Forbidden")
+	}
+
+	switch {
+	case forbidden.Field != "":
+		return problem.New(http.StatusForbidden, "You are not allowed to change "+forbidden.Field)
+	case forbidden.Owner:
+		return problem.New(http.StatusForbidden, "You are only allowed to "+string(forbidden.Action)+" your own user")
+	default:
+		return problem.New(http.StatusForbidden, "You are not allowed to "+string(forbidden.Action)+" users")
+	}
+}
@@ -0,0 +1,198 @@
+package route
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"gorm.io/gorm"
+
+	"user-microservice/internal/auth"
+	"user-microservice/internal/database/entity"
+	"user-microservice/internal/policy"
+	"user-microservice/internal/problem"
+	"user-microservice/internal/testutil"
+	userSvc "user-microservice/internal/user"
+)
+
+// ━━ TESTS ━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━
+
+func TestAuthorize(t *testing.T) {
+	employee := testutil.NewUser(testutil.WithID(1), testutil.WithRole("Employee"), testutil.WithUserID(1001))
+	customer := testutil.NewUser(testutil.WithID(2), testutil.WithRole("Customer"), testutil.WithUserID(1002))
+	other := testutil.NewUser(testutil.WithID(3), testutil.WithRole("Customer"), testutil.WithUserID(1003))
+
+	renamed := customer
+	renamed.FirstName = "Jane"
+	promoted := customer
+	promoted.Role = "Employee"
+
+	notFound := fmt.Errorf("in user.FetchByUserID: %w", fmt.Errorf("%w: %w", userSvc.ErrUserNotFound, gorm.ErrRecordNotFound))
+
+	testCases := map[string]struct {
+		method         string
+		path           string
+		body           any
+		userID         string
+		caller         entity.User
+		callerErr      error
+		setupMock      func(sm *serviceMock)
+		expectedStatus int
+		expectedBody   any
+	}{
+		"200 - employee lists users": {
+			method: http.MethodGet,
+			path:   "/api/user/",
+			userID: "1001",
+			caller: employee,
+			setupMock: func(sm *serviceMock) {
+				sm.On("List", mock.Anything, false).Return([]entity.User{employee, customer}, nil).Once()
+			},
+			expectedStatus: http.StatusOK,
+			expectedBody:   responseAllUsers{Users: []entity.User{employee, customer}},
+		},
+		"403 - customer lists users": {
+			method:         http.MethodGet,
+			path:           "/api/user/",
+			userID:         "1002",
+			caller:         customer,
+			expectedStatus: http.StatusForbidden,
+			expectedBody:   problem.New(http.StatusForbidden, "You are not allowed to list users").WithInstance("/api/user/"),
+		},
+		"200 - customer fetches own user": {
+			method: http.MethodGet,
+			path:   "/api/user/2",
+			userID: "1002",
+			caller: customer,
+			setupMock: func(sm *serviceMock) {
+				sm.On("Fetch", mock.Anything, 2, true).Return(customer, nil).Once()
+				sm.On("Fetch", mock.Anything, 2, false).Return(customer, nil).Once()
+			},
+			expectedStatus: http.StatusOK,
+			expectedBody:   responseOneUser{User: customer},
+		},
+		"403 - customer fetches other user": {
+			method: http.MethodGet,
+			path:   "/api/user/3",
+			userID: "1002",
+			caller: customer,
+			setupMock: func(sm *serviceMock) {
+				sm.On("Fetch", mock.Anything, 3, true).Return(other, nil).Once()
+			},
+			expectedStatus: http.StatusForbidden,
+			expectedBody:   problem.New(http.StatusForbidden, "You are only allowed to read your own user").WithInstance("/api/user/3"),
+		},
+		"200 - customer renames own user": {
+			method: http.MethodPut,
+			path:   "/api/user/2",
+			body:   renamed,
+			userID: "1002",
+			caller: customer,
+			setupMock: func(sm *serviceMock) {
+				sm.On("Fetch", mock.Anything, 2, true).Return(customer, nil).Once()
+				sm.On("Fetch", mock.Anything, 2, false).Return(customer, nil).Once()
+				sm.On("Update", mock.Anything, 2, renamed).Return(renamed, nil).Once()
+			},
+			expectedStatus: http.StatusOK,
+			expectedBody:   responseOneUser{User: renamed},
+		},
+		"403 - customer changes own role": {
+			method: http.MethodPut,
+			path:   "/api/user/2",
+			body:   promoted,
+			userID: "1002",
+			caller: customer,
+			setupMock: func(sm *serviceMock) {
+				sm.On("Fetch", mock.Anything, 2, true).Return(customer, nil).Once()
+				sm.On("Fetch", mock.Anything, 2, false).Return(customer, nil).Once()
+			},
+			expectedStatus: http.StatusForbidden,
+			expectedBody:   problem.New(http.StatusForbidden, "You are not allowed to change role").WithInstance("/api/user/2"),
+		},
+		"403 - customer deletes own user": {
+			method:         http.MethodDelete,
+			path:           "/api/user/2",
+			userID:         "1002",
+			caller:         customer,
+			expectedStatus: http.StatusForbidden,
+			expectedBody:   problem.New(http.StatusForbidden, "You are not allowed to delete users").WithInstance("/api/user/2"),
+		},
+		"404 - customer fetches missing user": {
+			method: http.MethodGet,
+			path:   "/api/user/4",
+			userID: "1002",
+			caller: customer,
+			setupMock: func(sm *serviceMock) {
+				sm.On("Fetch", mock.Anything, 4, true).Return(entity.User{}, userSvc.ErrUserNotFound).Once()
+				sm.On("Fetch", mock.Anything, 4, false).Return(entity.User{}, userSvc.ErrUserNotFound).Once()
+			},
+			expectedStatus: http.StatusNotFound,
+			expectedBody:   problem.New(http.StatusNotFound, "Object does not exist").WithInstance("/api/user/4"),
+		},
+		"403 - token without user_id": {
+			method:         http.MethodGet,
+			path:           "/api/user/",
+			expectedStatus: http.StatusForbidden,
+			expectedBody:   problem.New(http.StatusForbidden, "The bearer token does not identify a user").WithInstance("/api/user/"),
+		},
+		"403 - token of unknown user": {
+			method:         http.MethodGet,
+			path:           "/api/user/",
+			userID:         "1009",
+			callerErr:      notFound,
+			expectedStatus: http.StatusForbidden,
+			expectedBody:   problem.New(http.StatusForbidden, "The bearer token does not identify a user").WithInstance("/api/user/"),
+		},
+	}
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			raw := map[string]any{"sub": "jdoe"}
+			if tc.userID != "" {
+				raw["user_id"] = tc.userID
+			}
+			mockVerifier := new(verifierMock)
+			mockVerifier.On("Verify", mock.Anything, "token").Return(auth.Claims{Subject: "jdoe", Raw: raw}, nil).Once()
+
+			userServiceMock := new(serviceMock)
+			if tc.userID != "" {
+				userID, _ := strconv.ParseUint(tc.userID, 10, 0)
+				userServiceMock.On("FetchByUserID", mock.Anything, uint(userID)).Return(tc.caller, tc.callerErr).Once()
+			}
+			if tc.setupMock != nil {
+				tc.setupMock(userServiceMock)
+			}
+
+			router := chi.NewRouter()
+			SetUpRoutes(router, NewHandler(
+				userServiceMock,
+				slog.Default(),
+				WithVerifier(mockVerifier),
+				WithPolicy(policy.Default()),
+			))
+
+			var body bytes.Buffer
+			if tc.body != nil {
+				_ = json.NewEncoder(&body).Encode(tc.body)
+			}
+			w := httptest.NewRecorder()
+			req, _ := http.NewRequest(tc.method, tc.path, &body)
+			req.Header.Set("Authorization", "Bearer token")
+			router.ServeHTTP(w, req)
+
+			expectedBody, _ := json.Marshal(tc.expectedBody)
+
+			assert.Equal(t, tc.expectedStatus, w.Code, "Wrong code received")
+			assert.Equal(t, string(expectedBody), strings.TrimSpace(w.Body.String()), "Wrong response body")
+			userServiceMock.AssertExpectations(t)
+		})
+	}
+}
@@ -1,37 +1,62 @@
 package route
 
 import (
+	"context"
 	"net/http"
 
 	"github.com/go-chi/chi/v5"
+
+	"user-microservice/internal/health"
 )
 
+type readinessChecker interface {
+	Ready(ctx context.Context) health.Report
+}
+
+// WithHealthChecker sets the checker that the readiness probe reports. If no checker is set, the
+// readiness probe is not registered.
+func WithHealthChecker(checker readinessChecker) HandlerOptions {
+	return func(h *Handler) {
+		h.healthChecker = checker
+	}
+}
+
 func healthCheck(h Handler) func(r chi.Router) {
 	return func(r chi.Router) {
-		// @Summary		Health check response
-		// @Description	Health check response
+		// @Summary		Liveness probe
+		// @Description	Report that the service is alive
 		// @Tags		health-check
 		// @Accept		json
 		// @Produce		json
-		// @Success		200				{object}	routes.responseMessage
-		// @Router		/health-check 	[GET]
-		r.Get("/", func(w http.ResponseWriter, r *http.Request) {
-			h.logger.Info("Health check called")
-			encodeResponse(w, http.StatusOK, responseMessage{Message: "Hello World"})
+		// @Success		200				{object}	health.Report
+		// @Router		/health/live	[GET]
+		r.Get("/live", func(w http.ResponseWriter, r *http.Request) {
+			encodeResponse(w, http.StatusOK, health.Report{Status: health.StatusOK})
 		})
+
+		if h.healthChecker != nil {
+			r.Get("/ready", handleReadiness(h))
+		}
 	}
 }
 
-// @Summary		Health check response
-// @Description	Health check response
+// @Summary		Readiness probe
+// @Description	Report whether the service and its components are ready
 // @Tags		health-check
 // @Accept		json
 // @Produce		json
-// @Success		200					{object}	routes.responseMessage
-// @Router		/health-check/v2	[GET]
-func handleHealthCheck(h Handler) http.HandlerFunc {
+// @Success		200				{object}	health.Report
+// @Failure		503				{object}	health.Report
+// @Router		/health/ready	[GET]
+func handleReadiness(h Handler) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
-		h.logger.Info("Health check v2 called")
-		encodeResponse(w, http.StatusOK, responseMessage{Message: "Hello World"})
+		report := h.healthChecker.Ready(r.Context())
+		if report.Status != health.StatusOK {
+			h.requestLogger(r).Warn("Service not ready", "report", report)
+			encodeResponse(w, http.StatusServiceUnavailable, report)
+			return
+		}
+
+		encodeResponse(w, http.StatusOK, report)
 	}
 }
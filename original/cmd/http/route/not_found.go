@@ -4,11 +4,18 @@ import (
 	"net/http"
 
 	"github.com/go-chi/chi/v5"
+
+	"user-microservice/internal/problem"
 )
 
 func notFound(r chi.Router, h Handler) {
 	r.NotFound(func(w http.ResponseWriter, r *http.Request) {
-		h.logger.Info("Route not found called")
-		encodeResponse(w, http.StatusNotFound, responseMessage{Message: "Page not found"})
+		h.requestLogger(r).Info("Route not found called")
+		h.encodeProblem(w, r, problem.New(http.StatusNotFound, "Page not found"))
+	})
+
+	r.MethodNotAllowed(func(w http.ResponseWriter, r *http.Request) {
+		h.requestLogger(r).Info("Route called with method not allowed", "path", r.URL.Path, "method", r.Method)
+		h.encodeProblem(w, r, problem.New(http.StatusMethodNotAllowed, "Method not allowed"))
 	})
 }
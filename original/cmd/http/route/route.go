@@ -1,11 +1,15 @@
 package route
 
 import (
+	"context"
 	"log/slog"
+	"net/http"
 
 	"github.com/go-chi/chi/v5"
 
 	"user-microservice/internal/database/entity"
+	"user-microservice/internal/logging"
+	"user-microservice/internal/policy"
 )
 
 type responseMessage struct {
@@ -16,45 +20,78 @@ type responseID struct {
 	ObjectID int `json:"object_id"`
 }
 
-type responseError struct {
-	Error string `json:"error"`
-}
-
 type userService interface {
-	List() ([]entity.User, error)
-	Fetch(int) (entity.User, error)
-	Update(int, entity.User) (entity.User, error)
-	Create(entity.User) (int, error)
-	Delete(int) error
+	List(context.Context, bool) ([]entity.User, error)
+	Fetch(context.Context, int, bool) (entity.User, error)
+	FetchByUserID(context.Context, uint) (entity.User, error)
+	Update(context.Context, int, entity.User) (entity.User, error)
+	Create(context.Context, entity.User) (int, error)
+	Delete(context.Context, int) error
+	Restore(context.Context, int) (entity.User, error)
+	Purge(context.Context, int) error
 }
 
 type Handler struct {
-	userService userService
-	logger      *slog.Logger
+	userService   userService
+	logger        *slog.Logger
+	adminToken    string
+	verifier      tokenVerifier
+	policy        policy.Policy
+	healthChecker readinessChecker
+}
+
+type HandlerOptions func(*Handler)
+
+// WithAdminToken sets the token that requests must pass in the X-Admin-Token header for admin-only
+// operations such as purging a user. If no token is set, admin-only operations are always
+// forbidden.
+func WithAdminToken(token string) HandlerOptions {
+	return func(h *Handler) {
+		h.adminToken = token
+	}
 }
 
 // NewHandler creates and returns a new Handler struct.
-func NewHandler(userService userService, logger *slog.Logger) Handler {
-	return Handler{
+func NewHandler(userService userService, logger *slog.Logger, options ...HandlerOptions) Handler {
+	h := Handler{
 		userService: userService,
 		logger:      logger,
 	}
+
+	for _, option := range options {
+		option(&h)
+	}
+
+	return h
+}
+
+// requestLogger returns the logger that logging.Middleware put into the context of r, which logs the
+// request ID, trace ID, route and user of r with every line, or the logger of h if r has none.
+func (h Handler) requestLogger(r *http.Request) *slog.Logger {
+	if logger, ok := logging.FromContext(r.Context()); ok {
+		return logger
+	}
+	return h.logger
 }
 
 // SetUpRoutes sets up routes using a *chi.Mux.
 func SetUpRoutes(r *chi.Mux, h Handler) {
 	r.Route("/api", func(r chi.Router) {
-		// For demonstration purposes, there are two versions of health check that show the two ways of
-		// using http handlers with chi and the standard library.
-		// "/health-check" uses an anonymous handler func inside a route function
-		// "/health-check/v2" uses a names function as a closure for a http.HandlerFunc
-		r.Route("/health-check", healthCheck(h))
-
-		r.Route("/health-check/v2", func(r chi.Router) {
-			r.Get("/", handleHealthCheck(h))
-		})
+		// For demonstration purposes, the two health checks show the two ways of using http handlers
+		// with chi and the standard library.
+		// "/health/live" uses an anonymous handler func inside a route function
+		// "/health/ready" uses a named function as a closure for a http.HandlerFunc
+		r.Route("/health", healthCheck(h))
 
-		r.Route("/user", userRoutes(h))
+		r.Route("/user", func(r chi.Router) {
+			if h.verifier != nil {
+				r.Use(h.authenticate)
+				if h.policy != nil {
+					r.Use(h.authorize)
+				}
+			}
+			userRoutes(h)(r)
+		})
 	})
 
 	notFound(r, h)
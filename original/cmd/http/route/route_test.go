@@ -2,6 +2,7 @@ package route
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
 	"fmt"
 	"log/slog"
@@ -9,6 +10,7 @@ import (
 	"net/http/httptest"
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/go-chi/chi/v5"
 	"github.com/go-chi/chi/v5/middleware"
@@ -16,9 +18,13 @@ import (
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
 	"github.com/stretchr/testify/suite"
+	"gorm.io/gorm"
 
 	"user-microservice/internal/database/entity"
+	"user-microservice/internal/health"
+	"user-microservice/internal/problem"
 	"user-microservice/internal/testutil"
+	userSvc "user-microservice/internal/user"
 )
 
 // MOCKS
@@ -27,37 +33,62 @@ type serviceMock struct {
 	mock.Mock
 }
 
-func (sm *serviceMock) List() ([]entity.User, error) {
-	args := sm.Called()
+func (sm *serviceMock) List(ctx context.Context, includeDeleted bool) ([]entity.User, error) {
+	args := sm.Called(ctx, includeDeleted)
 	return args.Get(0).([]entity.User), args.Error(1)
 }
 
-func (sm *serviceMock) Fetch(ID int) (entity.User, error) {
-	args := sm.Called(ID)
+func (sm *serviceMock) Fetch(ctx context.Context, ID int, includeDeleted bool) (entity.User, error) {
+	args := sm.Called(ctx, ID, includeDeleted)
 	return args.Get(0).(entity.User), args.Error(1)
 }
 
-func (sm *serviceMock) Update(ID int, user entity.User) (entity.User, error) {
-	args := sm.Called(ID, user)
+func (sm *serviceMock) FetchByUserID(ctx context.Context, userID uint) (entity.User, error) {
+	args := sm.Called(ctx, userID)
 	return args.Get(0).(entity.User), args.Error(1)
 }
 
-func (sm *serviceMock) Create(user entity.User) (int, error) {
-	args := sm.Called(user)
+func (sm *serviceMock) Update(ctx context.Context, ID int, user entity.User) (entity.User, error) {
+	args := sm.Called(ctx, ID, user)
+	return args.Get(0).(entity.User), args.Error(1)
+}
+
+func (sm *serviceMock) Create(ctx context.Context, user entity.User) (int, error) {
+	args := sm.Called(ctx, user)
 	return args.Int(0), args.Error(1)
 }
 
-func (sm *serviceMock) Delete(ID int) error {
-	args := sm.Called(ID)
+func (sm *serviceMock) Delete(ctx context.Context, ID int) error {
+	args := sm.Called(ctx, ID)
 	return args.Error(0)
 }
 
+func (sm *serviceMock) Restore(ctx context.Context, ID int) (entity.User, error) {
+	args := sm.Called(ctx, ID)
+	return args.Get(0).(entity.User), args.Error(1)
+}
+
+func (sm *serviceMock) Purge(ctx context.Context, ID int) error {
+	args := sm.Called(ctx, ID)
+	return args.Error(0)
+}
+
+type checkerMock struct {
+	mock.Mock
+}
+
+func (cm *checkerMock) Ready(ctx context.Context) health.Report {
+	args := cm.Called(ctx)
+	return args.Get(0).(health.Report)
+}
+
 // ━━ TEST SETUP ━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━
 
 type routerSuit struct {
 	suite.Suite
-	router   *chi.Mux
-	userMock *serviceMock
+	router      *chi.Mux
+	userMock    *serviceMock
+	checkerMock *checkerMock
 }
 
 func TestRouterSuit(t *testing.T) {
@@ -67,10 +98,16 @@ func TestRouterSuit(t *testing.T) {
 func (rs *routerSuit) SetupSuite() {
 	userServiceMock := new(serviceMock)
 	rs.userMock = userServiceMock
+	rs.checkerMock = new(checkerMock)
 
 	logger := slog.Default()
 
-	handler := NewHandler(userServiceMock, logger)
+	handler := NewHandler(
+		userServiceMock,
+		logger,
+		WithAdminToken("secret"),
+		WithHealthChecker(rs.checkerMock),
+	)
 
 	rs.router = chi.NewRouter()
 	rs.router.Use(middleware.Logger)
@@ -82,29 +119,77 @@ func (rs *routerSuit) SetupSuite() {
 func (rs *routerSuit) TestHealthCheck() {
 	t := rs.T()
 
+	ready := health.Report{
+		Status:     health.StatusOK,
+		Components: map[string]health.Component{"database": {Status: health.StatusOK}},
+	}
+	notReady := health.Report{
+		Status:     health.StatusFail,
+		Components: map[string]health.Component{"database": {Status: health.StatusFail, Error: "connection refused"}},
+	}
+
 	testCases := map[string]struct {
+		mockReturnArgs []any
 		method         string
 		path           string
 		expectedStatus int
+		expectedBody   any
 	}{
-		"200": {
+		"200 - live": {
+			nil,
 			http.MethodGet,
-			"/api/health-check",
+			"/api/health/live",
 			http.StatusOK,
+			health.Report{Status: health.StatusOK},
+		},
+		"200 - ready": {
+			[]any{ready},
+			http.MethodGet,
+			"/api/health/ready",
+			http.StatusOK,
+			ready,
+		},
+		"503 - not ready": {
+			[]any{notReady},
+			http.MethodGet,
+			"/api/health/ready",
+			http.StatusServiceUnavailable,
+			notReady,
+		},
+		"503 - draining": {
+			[]any{health.Report{Status: health.StatusFail, Draining: true}},
+			http.MethodGet,
+			"/api/health/ready",
+			http.StatusServiceUnavailable,
+			health.Report{Status: health.StatusFail, Draining: true},
 		},
 		"405": {
+			nil,
 			http.MethodPut,
-			"/api/health-check",
+			"/api/health/ready",
 			http.StatusMethodNotAllowed,
+			nil,
 		},
 	}
 	for name, tc := range testCases {
 		t.Run(name, func(t *testing.T) {
+			if tc.mockReturnArgs != nil {
+				rs.checkerMock.
+					On("Ready", mock.Anything).
+					Return(tc.mockReturnArgs...).
+					Once()
+			}
+
 			w := httptest.NewRecorder()
 			req, _ := http.NewRequest(tc.method, tc.path, nil)
 			rs.router.ServeHTTP(w, req)
 
 			assert.Equal(t, tc.expectedStatus, w.Code, "Wrong code received")
+			if tc.expectedBody != nil {
+				expectedBody, _ := json.Marshal(tc.expectedBody)
+				assert.JSONEq(t, string(expectedBody), w.Body.String(), "Wrong response body")
+			}
+			rs.checkerMock.AssertExpectations(t)
 		})
 	}
 }
@@ -115,6 +200,7 @@ func (rs *routerSuit) TestUserList() {
 	users := testutil.NewUsers(3, testutil.WithIDStartRange(1))
 
 	testCases := map[string]struct {
+		mockInputArgs  []any
 		mockReturnArgs []any
 		method         string
 		path           string
@@ -122,24 +208,45 @@ func (rs *routerSuit) TestUserList() {
 		expectedBody   any
 	}{
 		"200 - Good call": {
+			[]any{mock.Anything, false},
 			[]any{users, nil},
 			http.MethodGet,
 			"/api/user/",
 			http.StatusOK,
 			responseAllUsers{Users: users},
 		},
+		"200 - include deleted": {
+			[]any{mock.Anything, true},
+			[]any{users, nil},
+			http.MethodGet,
+			"/api/user/?include_deleted=true",
+			http.StatusOK,
+			responseAllUsers{Users: users},
+		},
+		"400 - invalid include_deleted": {
+			[]any{},
+			[]any{},
+			http.MethodGet,
+			"/api/user/?include_deleted=maybe",
+			http.StatusBadRequest,
+			problem.
+				New(http.StatusBadRequest, "One or more query parameters are invalid").
+				WithInstance("/api/user/").
+				WithFieldErrors(map[string]string{"include_deleted": "must be true or false"}),
+		},
 		"405 - wrong verb": {
 			[]any{},
-			http.MethodPost,
+			[]any{},
+			http.MethodPatch,
 			"/api/user/",
 			http.StatusMethodNotAllowed,
-			responseMessage{Message: "Page not found"},
+			problem.New(http.StatusMethodNotAllowed, "Method not allowed").WithInstance("/api/user/"),
 		},
 	}
 	for name, tc := range testCases {
 		t.Run(name, func(t *testing.T) {
 			rs.userMock.
-				On("List").
+				On("List", tc.mockInputArgs...).
 				Return(tc.mockReturnArgs...).
 				Once()
 
@@ -164,6 +271,8 @@ func (rs *routerSuit) TestUserFetch() {
 	t := rs.T()
 
 	user := testutil.NewUser()
+	deletedUser := testutil.NewUser()
+	deletedUser.DeletedAt = gorm.DeletedAt{Time: time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC), Valid: true}
 
 	testCases := map[string]struct {
 		mockInputArgs  []any
@@ -174,20 +283,28 @@ func (rs *routerSuit) TestUserFetch() {
 		expectedBody   any
 	}{
 		"200 - Good call": {
-			[]any{int(user.ID)},
+			[]any{mock.Anything, int(user.ID), false},
 			[]any{user, nil},
 			http.MethodGet,
 			fmt.Sprintf("/api/user/%d", int(user.ID)),
 			http.StatusOK,
 			responseOneUser{User: user},
 		},
-		"200 - Good call, no user": {
-			[]any{int(user.ID) + 1},
-			[]any{entity.User{}, nil},
+		"200 - include deleted": {
+			[]any{mock.Anything, int(deletedUser.ID), true},
+			[]any{deletedUser, nil},
 			http.MethodGet,
-			fmt.Sprintf("/api/user/%d", int(user.ID)+1),
+			fmt.Sprintf("/api/user/%d?include_deleted=true", int(deletedUser.ID)),
 			http.StatusOK,
-			responseOneUser{User: entity.User{}},
+			responseOneUser{User: deletedUser},
+		},
+		"404 - no user": {
+			[]any{mock.Anything, int(user.ID) + 1, false},
+			[]any{entity.User{}, fmt.Errorf("in user.Fetch: %w", userSvc.ErrUserNotFound)},
+			http.MethodGet,
+			fmt.Sprintf("/api/user/%d", int(user.ID)+1),
+			http.StatusNotFound,
+			problem.New(http.StatusNotFound, "Object does not exist").WithInstance(fmt.Sprintf("/api/user/%d", int(user.ID)+1)),
 		},
 		"400 - not a valid ID": {
 			[]any{},
@@ -195,7 +312,7 @@ func (rs *routerSuit) TestUserFetch() {
 			http.MethodGet,
 			"/api/user/id",
 			http.StatusBadRequest,
-			responseError{Error: "Not a valid ID"},
+			problem.New(http.StatusBadRequest, "Not a valid ID").WithInstance("/api/user/id"),
 		},
 	}
 	for name, tc := range testCases {
@@ -237,7 +354,7 @@ func (rs *routerSuit) TestUserUpdate() {
 		expectedBody   any
 	}{
 		"200 - Good call": {
-			[]any{int(user.ID), user},
+			[]any{mock.Anything, int(user.ID), user},
 			[]any{user, nil},
 			http.MethodPut,
 			fmt.Sprintf("/api/user/%d", int(user.ID)),
@@ -245,6 +362,24 @@ func (rs *routerSuit) TestUserUpdate() {
 			http.StatusOK,
 			responseOneUser{User: user},
 		},
+		"404 - no user": {
+			[]any{mock.Anything, int(user.ID) + 1, user},
+			[]any{entity.User{}, fmt.Errorf("in user.Update: %w", userSvc.ErrUserNotFound)},
+			http.MethodPut,
+			fmt.Sprintf("/api/user/%d", int(user.ID)+1),
+			user,
+			http.StatusNotFound,
+			problem.New(http.StatusNotFound, "Object does not exist").WithInstance(fmt.Sprintf("/api/user/%d", int(user.ID)+1)),
+		},
+		"409 - user_id already in use": {
+			[]any{mock.Anything, int(user.ID) + 2, user},
+			[]any{entity.User{}, fmt.Errorf("in user.Update: %w", userSvc.ErrUserIDConflict)},
+			http.MethodPut,
+			fmt.Sprintf("/api/user/%d", int(user.ID)+2),
+			user,
+			http.StatusConflict,
+			problem.New(http.StatusConflict, "user_id already in use").WithInstance(fmt.Sprintf("/api/user/%d", int(user.ID)+2)),
+		},
 	}
 	for name, tc := range testCases {
 		t.Run(name, func(t *testing.T) {
@@ -276,6 +411,7 @@ func (rs *routerSuit) TestUserCreate() {
 	t := rs.T()
 
 	user := testutil.NewUser()
+	conflictUser := testutil.NewUser()
 
 	testCases := map[string]struct {
 		mockInputArgs  []any
@@ -287,7 +423,7 @@ func (rs *routerSuit) TestUserCreate() {
 		expectedBody   any
 	}{
 		"200 - Good call": {
-			[]any{user},
+			[]any{mock.Anything, user},
 			[]any{int(user.ID), nil},
 			http.MethodPost,
 			fmt.Sprintf("/api/user"),
@@ -295,6 +431,15 @@ func (rs *routerSuit) TestUserCreate() {
 			http.StatusOK,
 			responseID{ObjectID: int(user.ID)},
 		},
+		"409 - user_id already in use": {
+			[]any{mock.Anything, conflictUser},
+			[]any{0, fmt.Errorf("in user.Create: %w", userSvc.ErrUserIDConflict)},
+			http.MethodPost,
+			"/api/user",
+			conflictUser,
+			http.StatusConflict,
+			problem.New(http.StatusConflict, "user_id already in use").WithInstance("/api/user"),
+		},
 	}
 	for name, tc := range testCases {
 		t.Run(name, func(t *testing.T) {
@@ -328,26 +473,146 @@ func (rs *routerSuit) TestDeleteCreate() {
 	user := testutil.NewUser()
 
 	testCases := map[string]struct {
+		mockMethod     string
 		mockInputArgs  []any
 		mockReturnArgs []any
 		method         string
 		path           string
+		adminToken     string
 		expectedStatus int
 		expectedBody   any
 	}{
 		"200 - Good call": {
-			[]any{int(user.ID)},
+			"Delete",
+			[]any{mock.Anything, int(user.ID)},
 			[]any{nil},
 			http.MethodDelete,
 			fmt.Sprintf("/api/user/%d", int(user.ID)),
+			"",
 			http.StatusOK,
 			responseMessage{Message: "object successful deleted"},
 		},
+		"404 - no user": {
+			"Delete",
+			[]any{mock.Anything, int(user.ID) + 1},
+			[]any{fmt.Errorf("in user.Delete: %w", userSvc.ErrUserNotFound)},
+			http.MethodDelete,
+			fmt.Sprintf("/api/user/%d", int(user.ID)+1),
+			"",
+			http.StatusNotFound,
+			problem.New(http.StatusNotFound, "Object does not exist").WithInstance(fmt.Sprintf("/api/user/%d", int(user.ID)+1)),
+		},
+		"200 - purge by admin": {
+			"Purge",
+			[]any{mock.Anything, int(user.ID)},
+			[]any{nil},
+			http.MethodDelete,
+			fmt.Sprintf("/api/user/%d?purge=true", int(user.ID)),
+			"secret",
+			http.StatusOK,
+			responseMessage{Message: "object successful purged"},
+		},
+		"403 - purge by non-admin": {
+			"Purge",
+			[]any{},
+			[]any{},
+			http.MethodDelete,
+			fmt.Sprintf("/api/user/%d?purge=true", int(user.ID)),
+			"guess",
+			http.StatusForbidden,
+			problem.New(http.StatusForbidden, "Only administrators can purge users").WithInstance(fmt.Sprintf("/api/user/%d", int(user.ID))),
+		},
+		"400 - invalid purge": {
+			"Purge",
+			[]any{},
+			[]any{},
+			http.MethodDelete,
+			fmt.Sprintf("/api/user/%d?purge=maybe", int(user.ID)),
+			"secret",
+			http.StatusBadRequest,
+			problem.
+				New(http.StatusBadRequest, "One or more query parameters are invalid").
+				WithInstance(fmt.Sprintf("/api/user/%d", int(user.ID))).
+				WithFieldErrors(map[string]string{"purge": "must be true or false"}),
+		},
+	}
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			rs.userMock.
+				On(tc.mockMethod, tc.mockInputArgs...).
+				Return(tc.mockReturnArgs...).
+				Once()
+
+			w := httptest.NewRecorder()
+			req, _ := http.NewRequest(tc.method, tc.path, nil)
+			if tc.adminToken != "" {
+				req.Header.Set("X-Admin-Token", tc.adminToken)
+			}
+			rs.router.ServeHTTP(w, req)
+
+			expectedBody, _ := json.Marshal(tc.expectedBody)
+
+			assert.Equal(t, tc.expectedStatus, w.Code, "Wrong code received")
+			assert.Equal(
+				t,
+				string(expectedBody),
+				strings.TrimSpace(w.Body.String()),
+				"Wrong response body",
+			)
+		})
+	}
+}
+
+func (rs *routerSuit) TestUserRestore() {
+	t := rs.T()
+
+	user := testutil.NewUser()
+
+	testCases := map[string]struct {
+		mockInputArgs  []any
+		mockReturnArgs []any
+		method         string
+		path           string
+		expectedStatus int
+		expectedBody   any
+	}{
+		"200 - Good call": {
+			[]any{mock.Anything, int(user.ID)},
+			[]any{user, nil},
+			http.MethodPost,
+			fmt.Sprintf("/api/user/%d/restore", int(user.ID)),
+			http.StatusOK,
+			responseOneUser{User: user},
+		},
+		"404 - no user": {
+			[]any{mock.Anything, int(user.ID) + 1},
+			[]any{entity.User{}, fmt.Errorf("in user.Restore: %w", userSvc.ErrUserNotFound)},
+			http.MethodPost,
+			fmt.Sprintf("/api/user/%d/restore", int(user.ID)+1),
+			http.StatusNotFound,
+			problem.New(http.StatusNotFound, "Object does not exist").WithInstance(fmt.Sprintf("/api/user/%d/restore", int(user.ID)+1)),
+		},
+		"409 - user not deleted": {
+			[]any{mock.Anything, int(user.ID) + 2},
+			[]any{entity.User{}, fmt.Errorf("in user.Restore: %w", userSvc.ErrUserNotDeleted)},
+			http.MethodPost,
+			fmt.Sprintf("/api/user/%d/restore", int(user.ID)+2),
+			http.StatusConflict,
+			problem.New(http.StatusConflict, "User is not deleted").WithInstance(fmt.Sprintf("/api/user/%d/restore", int(user.ID)+2)),
+		},
+		"409 - user_id already in use": {
+			[]any{mock.Anything, int(user.ID) + 3},
+			[]any{entity.User{}, fmt.Errorf("in user.Restore: %w", userSvc.ErrUserIDConflict)},
+			http.MethodPost,
+			fmt.Sprintf("/api/user/%d/restore", int(user.ID)+3),
+			http.StatusConflict,
+			problem.New(http.StatusConflict, "user_id already in use").WithInstance(fmt.Sprintf("/api/user/%d/restore", int(user.ID)+3)),
+		},
 	}
 	for name, tc := range testCases {
 		t.Run(name, func(t *testing.T) {
 			rs.userMock.
-				On("Delete", tc.mockInputArgs...).
+				On("Restore", tc.mockInputArgs...).
 				Return(tc.mockReturnArgs...).
 				Once()
 
@@ -1,12 +1,16 @@
 package route
 
 import (
+	"errors"
 	"net/http"
 	"strconv"
 
 	"github.com/go-chi/chi/v5"
 
 	"user-microservice/internal/database/entity"
+	"user-microservice/internal/policy"
+	"user-microservice/internal/problem"
+	"user-microservice/internal/user"
 )
 
 type responseOneUser struct {
@@ -24,22 +28,32 @@ func userRoutes(h Handler) func(r chi.Router) {
 		// @Tags		users
 		// @Accept		json
 		// @Produce		json
-		// @Success		200		{object}	route.responseAllUsers
-		// @Failure		500		{object}	route.responseError
-		// @Router		/user	[GET]
-		r.Get("/", func(w http.ResponseWriter, r *http.Request) {
-			// get values from db
-			users, err := h.userService.List()
+		// @Param		include_deleted	query		bool	false	"Include soft deleted users"	default(false)
+		// @Success		200				{object}	route.responseAllUsers
+		// @Failure		400				{object}	problem.Problem
+		// @Failure		403				{object}	problem.Problem
+		// @Failure		500				{object}	problem.Problem
+		// @Router		/user			[GET]
+		r.With(h.permit(policy.ActionList)).Get("/", func(w http.ResponseWriter, r *http.Request) {
+			// get and validate query params
+			includeDeleted, err := parseBoolQuery(r.URL.Query().Get("include_deleted"))
 			if err != nil {
-				h.logger.Error("error getting all locations", "error", err)
-				encodeResponse(
-					w,
-					http.StatusInternalServerError,
-					responseError{Error: "Error retrieving data"},
+				h.requestLogger(r).Error("error parsing query", "error", err)
+				h.encodeProblem(w, r, problem.
+					New(http.StatusBadRequest, "One or more query parameters are invalid").
+					WithFieldErrors(map[string]string{"include_deleted": "must be true or false"}),
 				)
 				return
 			}
 
+			// get values from db
+			users, err := h.userService.List(r.Context(), includeDeleted)
+			if err != nil {
+				h.requestLogger(r).Error("error getting all locations", "error", err)
+				h.encodeProblem(w, r, problem.New(http.StatusInternalServerError, "Error retrieving data"))
+				return
+			}
+
 			// return response
 			encodeResponse(w, http.StatusOK, responseAllUsers{Users: users})
 		})
@@ -49,35 +63,51 @@ func userRoutes(h Handler) func(r chi.Router) {
 		// @Tags		user
 		// @Accept		json
 		// @Produce		json
-		// @Param		id			path		int	true				"User ID"
-		// @Success		200			{object}	route.responseOneUser
-		// @Failure		400			{object}	route.responseError
-		// @Failure		500			{object}	route.responseError
-		// @Router		/user/{ID}	[GET]
-		r.Get("/{ID}", func(w http.ResponseWriter, r *http.Request) {
+		// @Param		id				path		int		true	"User ID"
+		// @Param		include_deleted	query		bool	false	"Return the user even if it is soft deleted"	default(false)
+		// @Success		200				{object}	route.responseOneUser
+		// @Failure		400				{object}	problem.Problem
+		// @Failure		403				{object}	problem.Problem
+		// @Failure		404				{object}	problem.Problem
+		// @Failure		500				{object}	problem.Problem
+		// @Router		/user/{ID}		[GET]
+		r.With(h.permit(policy.ActionRead)).Get("/{ID}", func(w http.ResponseWriter, r *http.Request) {
 			// get and validate ID
 			idString := chi.URLParam(r, "ID")
 			ID, err := strconv.Atoi(idString)
 			if err != nil {
-				h.logger.Error("error getting ID", "error", err)
-				encodeResponse(w, http.StatusBadRequest, responseError{Error: "Not a valid ID"})
+				h.requestLogger(r).Error("error getting ID", "error", err)
+				h.encodeProblem(w, r, problem.New(http.StatusBadRequest, "Not a valid ID"))
 				return
 			}
 
-			// get values from db
-			user, err := h.userService.Fetch(ID)
+			// get and validate query params
+			includeDeleted, err := parseBoolQuery(r.URL.Query().Get("include_deleted"))
 			if err != nil {
-				h.logger.Error("error getting locations buy ID", "ID", ID, "error", err)
-				encodeResponse(
-					w,
-					http.StatusInternalServerError,
-					responseError{Error: "Error retrieving data"},
+				h.requestLogger(r).Error("error parsing query", "error", err)
+				h.encodeProblem(w, r, problem.
+					New(http.StatusBadRequest, "One or more query parameters are invalid").
+					WithFieldErrors(map[string]string{"include_deleted": "must be true or false"}),
 				)
 				return
 			}
 
+			// get values from db
+			foundUser, err := h.userService.Fetch(r.Context(), ID, includeDeleted)
+			if err != nil {
+				switch {
+				case errors.Is(err, user.ErrUserNotFound):
+					h.requestLogger(r).Error("object does not exist", "ID", ID, "error", err)
+					h.encodeProblem(w, r, problem.New(http.StatusNotFound, "Object does not exist"))
+				default:
+					h.requestLogger(r).Error("error getting locations buy ID", "ID", ID, "error", err)
+					h.encodeProblem(w, r, problem.New(http.StatusInternalServerError, "Error retrieving data"))
+				}
+				return
+			}
+
 			// return response
-			encodeResponse(w, http.StatusOK, responseOneUser{User: user})
+			encodeResponse(w, http.StatusOK, responseOneUser{User: foundUser})
 		})
 
 		// @Summary		Update a user by ID
@@ -88,45 +118,70 @@ func userRoutes(h Handler) func(r chi.Router) {
 		// @Param		id			path		int	true				"User ID"
 		// @Param		user		body		entity.User	true		"User Object"
 		// @Success		200			{object}	route.responseOneUser
-		// @Failure		500			{object}	route.responseError
-		// @Failure		422			{object}	route.responseError
+		// @Failure		400			{object}	problem.Problem
+		// @Failure		403			{object}	problem.Problem
+		// @Failure		404			{object}	problem.Problem
+		// @Failure		409			{object}	problem.Problem
+		// @Failure		500			{object}	problem.Problem
 		// @Router		/user/{ID}	[PUT]
-		r.Put("/{ID}", func(w http.ResponseWriter, r *http.Request) {
+		r.With(h.permit(policy.ActionUpdate)).Put("/{ID}", func(w http.ResponseWriter, r *http.Request) {
 			// get and validate ID
 			idString := chi.URLParam(r, "ID")
 			ID, err := strconv.Atoi(idString)
 			if err != nil {
-				h.logger.Error("error getting ID", "error", err)
-				encodeResponse(w, http.StatusBadRequest, responseError{Error: "Not a valid ID"})
+				h.requestLogger(r).Error("error getting ID", "error", err)
+				h.encodeProblem(w, r, problem.New(http.StatusBadRequest, "Not a valid ID"))
 				return
 			}
 
 			// get and validate body as object
 			inputUser, err := decodeToStruct[entity.User](r)
 			if err != nil {
-				h.logger.Error("BodyParser error", "error", err)
-				encodeResponse(
-					w,
-					http.StatusBadRequest,
-					responseError{Error: "missing values or malformed body"},
-				)
+				h.requestLogger(r).Error("BodyParser error", "error", err)
+				h.encodeProblem(w, r, problem.New(http.StatusBadRequest, "missing values or malformed body"))
 				return
 			}
 
+			// refuse changes to fields that are read-only for the caller
+			if principal, ok := policy.FromContext(r.Context()); ok && principal.HasReadOnlyFields() {
+				current, err := h.userService.Fetch(r.Context(), ID, false)
+				if err != nil {
+					switch {
+					case errors.Is(err, user.ErrUserNotFound):
+						h.requestLogger(r).Error("object does not exist", "ID", ID, "error", err)
+						h.encodeProblem(w, r, problem.New(http.StatusNotFound, "Object does not exist"))
+					default:
+						h.requestLogger(r).Error("error getting object from db", "ID", ID, "error", err)
+						h.encodeProblem(w, r, problem.New(http.StatusInternalServerError, "Error updating data"))
+					}
+					return
+				}
+				if err = authorizeChanges(r.Context(), current, inputUser); err != nil {
+					h.requestLogger(r).Error("request forbidden by policy", "ID", ID, "error", err)
+					h.encodeProblem(w, r, forbiddenProblem(err))
+					return
+				}
+			}
+
 			// update object in database
-			user, err := h.userService.Update(ID, inputUser)
+			updatedUser, err := h.userService.Update(r.Context(), ID, inputUser)
 			if err != nil {
-				h.logger.Error("error updating object in db", "error", err)
-				encodeResponse(
-					w,
-					http.StatusInternalServerError,
-					responseError{Error: "Error updating data"},
-				)
+				switch {
+				case errors.Is(err, user.ErrUserNotFound):
+					h.requestLogger(r).Error("object does not exist", "ID", ID, "error", err)
+					h.encodeProblem(w, r, problem.New(http.StatusNotFound, "Object does not exist"))
+				case errors.Is(err, user.ErrUserIDConflict):
+					h.requestLogger(r).Error("user_id already in use", "ID", ID, "error", err)
+					h.encodeProblem(w, r, problem.New(http.StatusConflict, "user_id already in use"))
+				default:
+					h.requestLogger(r).Error("error updating object in db", "error", err)
+					h.encodeProblem(w, r, problem.New(http.StatusInternalServerError, "Error updating data"))
+				}
 				return
 			}
 
 			// return response
-			encodeResponse(w, http.StatusOK, responseOneUser{User: user})
+			encodeResponse(w, http.StatusOK, responseOneUser{User: updatedUser})
 		})
 
 		// @Summary		Create a user
@@ -136,32 +191,31 @@ func userRoutes(h Handler) func(r chi.Router) {
 		// @Produce		json
 		// @Param		user		body		entity.User	true	"User Object"
 		// @Success		201			{object}	route.responseID
-		// @Failure		422			{object}	route.responseError
-		// @Failure		500			{object}	route.responseError
-		// @Failure		409			{object}	route.responseError
+		// @Failure		400			{object}	problem.Problem
+		// @Failure		403			{object}	problem.Problem
+		// @Failure		500			{object}	problem.Problem
+		// @Failure		409			{object}	problem.Problem
 		// @Router		/user		[POST]
-		r.Post("/", func(w http.ResponseWriter, r *http.Request) {
+		r.With(h.permit(policy.ActionCreate)).Post("/", func(w http.ResponseWriter, r *http.Request) {
 			// get and validate body as object
 			inputUser, err := decodeToStruct[entity.User](r)
 			if err != nil {
-				h.logger.Error("BodyParser error", "error", err)
-				encodeResponse(
-					w,
-					http.StatusBadRequest,
-					responseError{Error: "missing values or malformed body"},
-				)
+				h.requestLogger(r).Error("BodyParser error", "error", err)
+				h.encodeProblem(w, r, problem.New(http.StatusBadRequest, "missing values or malformed body"))
 				return
 			}
 
 			// create object in database
-			id, err := h.userService.Create(inputUser)
+			id, err := h.userService.Create(r.Context(), inputUser)
 			if err != nil {
-				h.logger.Error("error creating object to db", "error", err)
-				encodeResponse(
-					w,
-					http.StatusInternalServerError,
-					responseError{Error: "Error creating object"},
-				)
+				switch {
+				case errors.Is(err, user.ErrUserIDConflict):
+					h.requestLogger(r).Error("user_id already in use", "error", err)
+					h.encodeProblem(w, r, problem.New(http.StatusConflict, "user_id already in use"))
+				default:
+					h.requestLogger(r).Error("error creating object to db", "error", err)
+					h.encodeProblem(w, r, problem.New(http.StatusInternalServerError, "Error creating object"))
+				}
 				return
 			}
 
@@ -170,54 +224,111 @@ func userRoutes(h Handler) func(r chi.Router) {
 		})
 
 		// @Summary		Delete a user by ID
-		// @Description	Delete a user by ID
+		// @Description	Soft delete a user by ID, or permanently delete it with purge
 		// @Tags		user
 		// @Accept		json
 		// @Produce		json
-		// @Param		id			path		int	true				"User ID"
-		// @Success		202			{object}	route.responseMessage
-		// @Failure		500			{object}	route.responseError
-		// @Failure		404			{object}	route.responseError
-		// @Router		/user/{ID}	[DELETE]
-		r.Delete("/{ID}", func(w http.ResponseWriter, r *http.Request) {
+		// @Param		id				path		int		true	"User ID"
+		// @Param		purge			query		bool	false	"Permanently delete the user, which requires X-Admin-Token"	default(false)
+		// @Param		X-Admin-Token	header		string	false	"Admin token, required to purge"
+		// @Success		202				{object}	route.responseMessage
+		// @Failure		400				{object}	problem.Problem
+		// @Failure		403				{object}	problem.Problem
+		// @Failure		404				{object}	problem.Problem
+		// @Failure		500				{object}	problem.Problem
+		// @Router		/user/{ID}		[DELETE]
+		r.With(h.permit(policy.ActionDelete)).Delete("/{ID}", func(w http.ResponseWriter, r *http.Request) {
 			// get and validate ID
 			idString := chi.URLParam(r, "ID")
 			ID, err := strconv.Atoi(idString)
 			if err != nil {
-				h.logger.Error("error getting ID", "error", err)
-				encodeResponse(w, http.StatusBadRequest, responseError{Error: "Not a valid ID"})
+				h.requestLogger(r).Error("error getting ID", "error", err)
+				h.encodeProblem(w, r, problem.New(http.StatusBadRequest, "Not a valid ID"))
 				return
 			}
 
-			// check that object exists
-			user, err := h.userService.Fetch(ID)
+			// get and validate query params
+			purge, err := parseBoolQuery(r.URL.Query().Get("purge"))
 			if err != nil {
-				h.logger.Error("error getting object by ID", "error", err)
-				encodeResponse(
-					w,
-					http.StatusInternalServerError,
-					responseError{Error: "Error validating object"},
+				h.requestLogger(r).Error("error parsing query", "error", err)
+				h.encodeProblem(w, r, problem.
+					New(http.StatusBadRequest, "One or more query parameters are invalid").
+					WithFieldErrors(map[string]string{"purge": "must be true or false"}),
 				)
 				return
 			}
-			if user.ID == 0 {
-				encodeResponse(w, http.StatusBadRequest, responseError{Error: "Object does not exist"})
+			if purge && !h.isAdmin(r) {
+				h.requestLogger(r).Error("purge by non-admin", "ID", ID)
+				h.encodeProblem(w, r, problem.New(http.StatusForbidden, "Only administrators can purge users"))
 				return
 			}
 
 			// delete user
-			if err = h.userService.Delete(ID); err != nil {
-				h.logger.Error("error deleting object by ID", "ID", ID, "error", err)
-				encodeResponse(
-					w,
-					http.StatusInternalServerError,
-					responseError{Error: "Error deleting object."},
-				)
+			deleteUser, message := h.userService.Delete, "object successful deleted"
+			if purge {
+				deleteUser, message = h.userService.Purge, "object successful purged"
+			}
+			if err = deleteUser(r.Context(), ID); err != nil {
+				switch {
+				case errors.Is(err, user.ErrUserNotFound):
+					h.requestLogger(r).Error("object does not exist", "ID", ID, "error", err)
+					h.encodeProblem(w, r, problem.New(http.StatusNotFound, "Object does not exist"))
+				default:
+					h.requestLogger(r).Error("error deleting object by ID", "ID", ID, "error", err)
+					h.encodeProblem(w, r, problem.New(http.StatusInternalServerError, "Error deleting object."))
+				}
 				return
 			}
 
 			// return message
-			encodeResponse(w, http.StatusOK, responseMessage{Message: "object successful deleted"})
+			encodeResponse(w, http.StatusOK, responseMessage{Message: message})
+		})
+
+		// @Summary		Restore a deleted user by ID
+		// @Description	Restore a soft deleted user by ID
+		// @Tags		user
+		// @Accept		json
+		// @Produce		json
+		// @Param		id						path		int	true	"User ID"
+		// @Success		200						{object}	route.responseOneUser
+		// @Failure		400						{object}	problem.Problem
+		// @Failure		403						{object}	problem.Problem
+		// @Failure		404						{object}	problem.Problem
+		// @Failure		409						{object}	problem.Problem
+		// @Failure		500						{object}	problem.Problem
+		// @Router		/user/{ID}/restore		[POST]
+		r.With(h.permit(policy.ActionRestore)).Post("/{ID}/restore", func(w http.ResponseWriter, r *http.Request) {
+			// get and validate ID
+			idString := chi.URLParam(r, "ID")
+			ID, err := strconv.Atoi(idString)
+			if err != nil {
+				h.requestLogger(r).Error("error getting ID", "error", err)
+				h.encodeProblem(w, r, problem.New(http.StatusBadRequest, "Not a valid ID"))
+				return
+			}
+
+			// restore user
+			restoredUser, err := h.userService.Restore(r.Context(), ID)
+			if err != nil {
+				switch {
+				case errors.Is(err, user.ErrUserNotFound):
+					h.requestLogger(r).Error("object does not exist", "ID", ID, "error", err)
+					h.encodeProblem(w, r, problem.New(http.StatusNotFound, "Object does not exist"))
+				case errors.Is(err, user.ErrUserNotDeleted):
+					h.requestLogger(r).Error("object is not deleted", "ID", ID, "error", err)
+					h.encodeProblem(w, r, problem.New(http.StatusConflict, "User is not deleted"))
+				case errors.Is(err, user.ErrUserIDConflict):
+					h.requestLogger(r).Error("user_id already in use", "ID", ID, "error", err)
+					h.encodeProblem(w, r, problem.New(http.StatusConflict, "user_id already in use"))
+				default:
+					h.requestLogger(r).Error("error restoring object by ID", "ID", ID, "error", err)
+					h.encodeProblem(w, r, problem.New(http.StatusInternalServerError, "Error restoring object"))
+				}
+				return
+			}
+
+			// return response
+			encodeResponse(w, http.StatusOK, responseOneUser{User: restoredUser})
 		})
 	}
 }
@@ -1,18 +1,33 @@
 package route
 
 import (
+	"crypto/subtle"
 	"encoding/json"
 	"fmt"
 	"net/http"
+	"strconv"
+
+	"user-microservice/internal/problem"
 )
 
-// encodeResponse encodes a struct of type T as a JSON response.
+// encodeResponse encodes a struct of type T as a JSON response. If data can not be encoded, an
+// internal server error problem is written instead.
 func encodeResponse[T any](w http.ResponseWriter, status int, data T) {
+	body, err := json.Marshal(data)
+	if err != nil {
+		_ = problem.Write(w, nil, problem.New(http.StatusInternalServerError, "Internal error"))
+		return
+	}
+
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(status)
-	if err := json.NewEncoder(w).Encode(data); err != nil {
-		http.Error(w, "Internal error", http.StatusInternalServerError)
-		return
+	_, _ = w.Write(append(body, '\n'))
+}
+
+// encodeProblem encodes p as an application/problem+json response for r.
+func (h Handler) encodeProblem(w http.ResponseWriter, r *http.Request, p problem.Problem) {
+	if err := problem.Write(w, r, p); err != nil {
+		h.requestLogger(r).Error("error writing problem", "error", err, "problem", p)
 	}
 }
 
@@ -24,3 +39,17 @@ func decodeToStruct[T any](r *http.Request) (T, error) {
 	}
 	return data, nil
 }
+
+// parseBoolQuery parses a boolean query parameter. An empty value is false.
+func parseBoolQuery(value string) (bool, error) {
+	if value == "" {
+		return false, nil
+	}
+	return strconv.ParseBool(value)
+}
+
+// isAdmin reports whether r has an X-Admin-Token header matching the admin token of h.
+func (h Handler) isAdmin(r *http.Request) bool {
+	token := r.Header.Get("X-Admin-Token")
+	return h.adminToken != "" && subtle.ConstantTimeCompare([]byte(token), []byte(h.adminToken)) == 1
+}
@@ -7,19 +7,40 @@ import (
 	"os"
 	"reflect"
 	"strconv"
+	"strings"
 
 	"github.com/joho/godotenv"
 )
 
 type configuration struct {
-	Env      string `env:"ENV"`
-	Database struct {
-		Name            string `env:"DATABASE_NAME"`
-		User            string `env:"DATABASE_USER"`
-		Password        string `env:"DATABASE_PASSWORD"`
-		Host            string `env:"DATABASE_HOST"`
-		Port            string `env:"DATABASE_PORT"`
-		ConnectionRetry int    `env:"DATABASE_CONNECTION_RETRY"`
+	Env        string `env:"ENV"`
+	AdminToken string `env:"ADMIN_TOKEN"`
+	Database   struct {
+		Name             string `env:"DATABASE_NAME"`
+		User             string `env:"DATABASE_USER"`
+		Password         string `env:"DATABASE_PASSWORD"`
+		Host             string `env:"DATABASE_HOST"`
+		Port             string `env:"DATABASE_PORT"`
+		ConnectionRetry  int    `env:"DATABASE_CONNECTION_RETRY"`
+		MigrateOnStartup bool   `env:"DATABASE_MIGRATE_ON_STARTUP"`
+	}
+	Auth struct {
+		Disabled   bool   `env:"AUTH_DISABLED"`
+		PolicyFile string `env:"AUTH_POLICY_FILE,optional"`
+	}
+	Tracing struct {
+		Exporter    string  `env:"TRACING_EXPORTER,optional"`
+		ServiceName string  `env:"TRACING_SERVICE_NAME,optional"`
+		SampleRatio float64 `env:"TRACING_SAMPLE_RATIO,optional"`
+	}
+	Metrics struct {
+		Namespace string `env:"METRICS_NAMESPACE,optional"`
+	}
+	Log struct {
+		RedactPII    bool   `env:"LOG_REDACT_PII,optional"`
+		RedactMode   string `env:"LOG_REDACT_MODE,optional"`
+		RedactSecret string `env:"LOG_REDACT_SECRET,optional"`
+		RedactKeys   string `env:"LOG_REDACT_KEYS,optional"`
 	}
 }
 
@@ -50,7 +71,8 @@ func loadEnv() {
 // with that tag will be retrieved and added to the struct.
 //
 // If the `errOnMissingValue` flag is set to `true`, any tag that is missing an environment variable
-// will result in an error being returned.
+// will result in an error being returned. Tags with the `optional` option, such as
+// `env:"AUTH_POLICY_FILE,optional"`, are left at their zero value when the variable is missing.
 func ParseStructFromEnv(obj any, errOnMissingValue bool) (err error) {
 	defer func() {
 		if err != nil {
@@ -78,7 +100,10 @@ func ParseStructFromEnv(obj any, errOnMissingValue bool) (err error) {
 
 		// Get and then set env value based on tag if present
 		fieldType := val.Type().Field(i)
-		envTag := fieldType.Tag.Get("env")
+		envTag, tagOptions, _ := strings.Cut(fieldType.Tag.Get("env"), ",")
+		if tagOptions == "optional" && os.Getenv(envTag) == "" {
+			continue
+		}
 
 		if field.CanSet() && envTag != "" {
 			switch field.Kind() {
@@ -100,6 +125,12 @@ func ParseStructFromEnv(obj any, errOnMissingValue bool) (err error) {
 					return err
 				}
 				field.SetBool(value)
+			case reflect.Float64:
+				value, err := getEnvFloat64(envTag, errOnMissingValue)
+				if err != nil {
+					return err
+				}
+				field.SetFloat(value)
 			default:
 				continue
 			}
@@ -0,0 +1,170 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/aws/aws-lambda-go/events"
+
+	"user-microservice/internal/auth"
+	"user-microservice/internal/database/entity"
+	"user-microservice/internal/logging"
+	"user-microservice/internal/policy"
+	"user-microservice/internal/problem"
+	"user-microservice/internal/user"
+)
+
+// Authorize wraps next so that it is only called for requests whose caller may take the action of
+// the request with rules. The caller is the user named by the user_id claim of the authorizer
+// context, see Authenticate, and is granted the rule rules has for their role. An action the caller
+// may only take on their own record is refused if the user belongs to someone else, and updates are
+// refused if they change a field that is read-only for the caller. Requests without claims, which
+// are only served when authentication is disabled, are passed on unchecked.
+func (h Handler) Authorize(rules policy.Policy, next APIGatewayHandler) APIGatewayHandler {
+	return func(ctx context.Context, request events.APIGatewayProxyRequest) (events.APIGatewayProxyResponse, error) {
+		claims, ok := auth.FromContext(ctx)
+		if !ok {
+			return next(ctx, request)
+		}
+		action, ok := requestAction(request)
+		if !ok {
+			// next reports the method as not allowed
+			return next(ctx, request)
+		}
+
+		// get the caller
+		userID, ok := claims.UserID()
+		if !ok {
+			h.requestLogger(ctx).Error("token without user_id claim", "subject", claims.Subject)
+			return problemResponse(ctx, request, problem.New(http.StatusForbidden, "The bearer token does not identify a user")), nil
+		}
+		caller, err := h.userService.FetchByUserID(ctx, userID)
+		if err != nil {
+			if errors.Is(err, user.ErrUserNotFound) {
+				h.requestLogger(ctx).Error("token for unknown user", "subject", claims.Subject, "user_id", userID)
+				return problemResponse(ctx, request, problem.New(http.StatusForbidden, "The bearer token does not identify a user")), nil
+			}
+			h.requestLogger(ctx).Error("error getting user of token", "error", err)
+			return problemResponse(ctx, request, problem.New(http.StatusInternalServerError, "Internal server error")), nil
+		}
+		ctx = logging.With(ctx, "user_id", caller.UserID)
+		principal := rules.Principal(caller.UserID, caller.Role)
+
+		// check the action, and the user acted on if the action is limited to the own record or the
+		// caller may not change some of its fields
+		scope, err := principal.Scope(action)
+		if err == nil && (scope == policy.ScopeOwn || action == policy.ActionUpdate && principal.HasReadOnlyFields()) {
+			current, ok, fetchErr := h.targetUser(ctx, request.PathParameters["ID"])
+			if fetchErr != nil {
+				h.requestLogger(ctx).Error("error getting object by ID", "error", fetchErr)
+				return problemResponse(ctx, request, problem.New(http.StatusInternalServerError, "Internal server error")), nil
+			}
+			if !ok {
+				// next reports the ID as invalid or the user as not existing
+				return next(policy.WithPrincipal(ctx, principal), request)
+			}
+			err = principal.Authorize(action, current.UserID)
+			if err == nil && action == policy.ActionUpdate {
+				err = authorizeChanges(principal, current, request.Body)
+			}
+		}
+		if err != nil {
+			h.requestLogger(ctx).Error("request forbidden by policy", "path", request.Path, "user_id", principal.UserID, "error", err)
+			return problemResponse(ctx, request, forbiddenProblem(err)), nil
+		}
+
+		return next(policy.WithPrincipal(ctx, principal), request)
+	}
+}
+
+// requestAction returns the action request takes, matching the dispatch of Run, and false if Run
+// does not serve its method.
+func requestAction(request events.APIGatewayProxyRequest) (policy.Action, bool) {
+	switch request.HTTPMethod {
+	case http.MethodGet:
+		if request.PathParameters != nil {
+			return policy.ActionRead, true
+		}
+		return policy.ActionList, true
+	case http.MethodPut:
+		return policy.ActionUpdate, true
+	case http.MethodPost:
+		if strings.HasSuffix(request.Resource, "/restore") {
+			return policy.ActionRestore, true
+		}
+		return policy.ActionCreate, true
+	case http.MethodDelete:
+		return policy.ActionDelete, true
+	default:
+		return "", false
+	}
+}
+
+// targetUser returns the user with the ID idString, including soft deleted users, and false if
+// idString is not a valid ID or no such user exists. Requests of a collection of users have no ID,
+// and their target is a user without a user_id, which is never the user_id of a principal.
+func (h Handler) targetUser(ctx context.Context, idString string) (entity.User, bool, error) {
+	if idString == "" {
+		return entity.User{}, true, nil
+	}
+
+	ID, err := strconv.Atoi(idString)
+	if err != nil {
+		return entity.User{}, false, nil
+	}
+
+	found, err := h.userService.Fetch(ctx, ID, true)
+	if err != nil {
+		if errors.Is(err, user.ErrUserNotFound) {
+			return entity.User{}, false, nil
+		}
+		return entity.User{}, false, err
+	}
+
+	return found, true, nil
+}
+
+// authorizeChanges returns a *policy.ForbiddenError if body, the user an update replaces current
+// with, changes a field that is read-only for principal. A malformed body is left to the handler.
+func authorizeChanges(principal policy.Principal, current entity.User, body string) error {
+	var updated entity.User
+	if err := json.Unmarshal([]byte(body), &updated); err != nil {
+		return nil
+	}
+
+	var fields []string
+	if updated.FirstName != current.FirstName {
+		fields = append(fields, "first_name")
+	}
+	if updated.LastName != current.LastName {
+		fields = append(fields, "last_name")
+	}
+	if updated.Role != current.Role {
+		fields = append(fields, "role")
+	}
+	if updated.UserID != current.UserID {
+		fields = append(fields, "user_id")
+	}
+	return principal.AuthorizeChanges(fields)
+}
+
+// forbiddenProblem returns the problem reported for err, a *policy.ForbiddenError.
+func forbiddenProblem(err error) problem.Problem {
+	var forbidden *policy.ForbiddenError
+	if !errors.As(err, &forbidden) {
+		return problem.New(http.StatusForbidden, "Forbidden")
+	}
+
+	switch {
+	case forbidden.Field != "":
+		return problem.New(http.StatusForbidden, "You are not allowed to change "+forbidden.Field)
+	case forbidden.Owner:
+		return problem.New(http.StatusForbidden, "You are only allowed to "+string(forbidden.Action)+" your own user")
+	default:
+		return problem.New(http.StatusForbidden, "You are not allowed to "+string(forbidden.Action)+" users")
+	}
+}
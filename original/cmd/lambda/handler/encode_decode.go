@@ -1,8 +1,18 @@
 package handler
 
 import (
+	"context"
+	"crypto/subtle"
 	"encoding/json"
 	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/aws/aws-lambda-go/events"
+
+	"user-microservice/internal/logging"
+	"user-microservice/internal/problem"
 )
 
 // structToJSON marshals a struct of type T to a JSON encoded string.
@@ -13,3 +23,49 @@ func structToJSON[T any](data T) (string, error) {
 	}
 	return string(JSONData), nil
 }
+
+// problemResponse returns p as an application/problem+json response. If not already set, Instance
+// is set to the path of request and RequestID to the ID that logging.WrapAPIGateway gave the request
+// of ctx, or else the API Gateway request ID.
+func problemResponse(ctx context.Context, request events.APIGatewayProxyRequest, p problem.Problem) events.APIGatewayProxyResponse {
+	if p.Instance == "" {
+		p.Instance = request.Path
+	}
+	if p.RequestID == "" {
+		p.RequestID = logging.RequestID(ctx)
+	}
+	if p.RequestID == "" {
+		p.RequestID = request.RequestContext.RequestID
+	}
+
+	respBody, err := structToJSON(p)
+	if err != nil {
+		return events.APIGatewayProxyResponse{StatusCode: http.StatusInternalServerError}
+	}
+
+	return events.APIGatewayProxyResponse{
+		StatusCode: p.Status,
+		Headers:    map[string]string{"Content-Type": problem.ContentType},
+		Body:       respBody,
+	}
+}
+
+// parseBoolQuery parses a boolean query parameter. An empty value is false.
+func parseBoolQuery(value string) (bool, error) {
+	if value == "" {
+		return false, nil
+	}
+	return strconv.ParseBool(value)
+}
+
+// isAdmin reports whether request has an X-Admin-Token header matching the admin token of h. API
+// Gateway passes headers on as sent, so the header name is matched case-insensitively.
+func (h Handler) isAdmin(request events.APIGatewayProxyRequest) bool {
+	var token string
+	for key, value := range request.Headers {
+		if strings.EqualFold(key, "X-Admin-Token") {
+			token = value
+		}
+	}
+	return h.adminToken != "" && subtle.ConstantTimeCompare([]byte(token), []byte(h.adminToken)) == 1
+}
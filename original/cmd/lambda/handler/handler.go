@@ -4,32 +4,65 @@ import (
 	"context"
 	"log/slog"
 	"net/http"
+	"strings"
 
 	"github.com/aws/aws-lambda-go/events"
 
 	"user-microservice/internal/database/entity"
+	"user-microservice/internal/logging"
+	"user-microservice/internal/problem"
 )
 
 type APIGatewayHandler func(context.Context, events.APIGatewayProxyRequest) (events.APIGatewayProxyResponse, error)
 
 type userService interface {
-	List() ([]entity.User, error)
-	Fetch(int) (entity.User, error)
-	Update(int, entity.User) (entity.User, error)
-	Create(entity.User) (int, error)
-	Delete(int) error
+	List(context.Context, bool) ([]entity.User, error)
+	Fetch(context.Context, int, bool) (entity.User, error)
+	FetchByUserID(context.Context, uint) (entity.User, error)
+	Update(context.Context, int, entity.User) (entity.User, error)
+	Create(context.Context, entity.User) (int, error)
+	Delete(context.Context, int) error
+	Restore(context.Context, int) (entity.User, error)
+	Purge(context.Context, int) error
 }
 
 type Handler struct {
 	userService userService
 	logger      *slog.Logger
+	adminToken  string
 }
 
-func New(userService userService, logger *slog.Logger) Handler {
-	return Handler{
+type Options func(*Handler)
+
+// WithAdminToken sets the token that requests must pass in the X-Admin-Token header for admin-only
+// operations such as purging a user. If no token is set, admin-only operations are always
+// forbidden.
+func WithAdminToken(token string) Options {
+	return func(h *Handler) {
+		h.adminToken = token
+	}
+}
+
+func New(userService userService, logger *slog.Logger, options ...Options) Handler {
+	h := Handler{
 		userService: userService,
 		logger:      logger,
 	}
+
+	for _, option := range options {
+		option(&h)
+	}
+
+	return h
+}
+
+// requestLogger returns the logger that logging.WrapAPIGateway put into ctx, which logs the request
+// ID, trace ID, route and user of the request with every line, or the logger of h if ctx has none.
+func (h Handler) requestLogger(ctx context.Context) *slog.Logger {
+	if logger, ok := logging.FromContext(ctx); ok {
+		return logger
+	}
+	return h.logger
 }
 
 func Run(h Handler) APIGatewayHandler {
@@ -37,21 +70,24 @@ func Run(h Handler) APIGatewayHandler {
 		switch request.HTTPMethod {
 		case http.MethodGet:
 			if request.PathParameters != nil {
-				return h.fetchUser(request)
+				return h.fetchUser(ctx, request)
 			}
-			return h.listUsers(request)
+			return h.listUsers(ctx, request)
 
 		case http.MethodPut:
-			return h.updateUser(request)
+			return h.updateUser(ctx, request)
 
 		case http.MethodPost:
-			return h.createUser(request)
+			if strings.HasSuffix(request.Resource, "/restore") {
+				return h.restoreUser(ctx, request)
+			}
+			return h.createUser(ctx, request)
 
 		case http.MethodDelete:
-			return h.deleteUser(request)
+			return h.deleteUser(ctx, request)
 
 		default:
-			return events.APIGatewayProxyResponse{StatusCode: http.StatusMethodNotAllowed}, nil
+			return problemResponse(ctx, request, problem.New(http.StatusMethodNotAllowed, "Method not allowed")), nil
 		}
 	}
 }
@@ -1,23 +1,23 @@
 package handler
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
 	"net/http"
 	"strconv"
 
 	"github.com/aws/aws-lambda-go/events"
 
 	"user-microservice/internal/database/entity"
+	"user-microservice/internal/problem"
+	"user-microservice/internal/user"
 )
 
 // ── Constants And Errors ─────────────────────────────────────────────────────────────────────────
 
 // ── Return Structs ───────────────────────────────────────────────────────────────────────────────
 
-type responseError struct {
-	Error string `json:"error"`
-}
-
 type responseMessage struct {
 	Message string `json:"message"`
 }
@@ -36,17 +36,24 @@ type responseAllUsers struct {
 
 // ── Method Handlers ──────────────────────────────────────────────────────────────────────────────
 
-// listUsers returns a list of all users from the database.
-func (h Handler) listUsers(request events.APIGatewayProxyRequest) (events.APIGatewayProxyResponse, error) {
+// listUsers returns a list of all users from the database. Soft deleted users are included if the
+// include_deleted query parameter is set.
+func (h Handler) listUsers(ctx context.Context, request events.APIGatewayProxyRequest) (events.APIGatewayProxyResponse, error) {
+	// get and validate query params
+	includeDeleted, err := parseBoolQuery(request.QueryStringParameters["include_deleted"])
+	if err != nil {
+		h.requestLogger(ctx).Error("error parsing query", "error", err)
+		return problemResponse(ctx, request, problem.
+			New(http.StatusBadRequest, "One or more query parameters are invalid").
+			WithFieldErrors(map[string]string{"include_deleted": "must be true or false"}),
+		), nil
+	}
+
 	// get values from db
-	users, err := h.userService.List()
+	users, err := h.userService.List(ctx, includeDeleted)
 	if err != nil {
-		h.logger.Error("error getting all locations", "error", err)
-		respBody, _ := structToJSON(responseError{Error: "Error retrieving data"})
-		return events.APIGatewayProxyResponse{
-			StatusCode: http.StatusInternalServerError,
-			Body:       respBody,
-		}, err
+		h.requestLogger(ctx).Error("error getting all locations", "error", err)
+		return problemResponse(ctx, request, problem.New(http.StatusInternalServerError, "Error retrieving data")), err
 	}
 
 	// return response
@@ -54,101 +61,100 @@ func (h Handler) listUsers(request events.APIGatewayProxyRequest) (events.APIGat
 	return events.APIGatewayProxyResponse{StatusCode: http.StatusOK, Body: respBody}, err
 }
 
-// fetchUser returns a single user based on an id passed as a path parameter on the request.
-func (h Handler) fetchUser(request events.APIGatewayProxyRequest) (events.APIGatewayProxyResponse, error) {
+// fetchUser returns a single user based on an id passed as a path parameter on the request. A soft
+// deleted user is only returned if the include_deleted query parameter is set.
+func (h Handler) fetchUser(ctx context.Context, request events.APIGatewayProxyRequest) (events.APIGatewayProxyResponse, error) {
 	// get and validate ID
 	idString := request.PathParameters["ID"]
 	ID, err := strconv.Atoi(idString)
 	if err != nil {
-		h.logger.Error("error getting ID", "error", err)
-		respBody, _ := structToJSON(responseError{Error: "Not a valid ID"})
-		return events.APIGatewayProxyResponse{
-			StatusCode: http.StatusInternalServerError,
-			Body:       respBody,
-		}, nil
+		h.requestLogger(ctx).Error("error getting ID", "error", err)
+		return problemResponse(ctx, request, problem.New(http.StatusBadRequest, "Not a valid ID")), nil
+	}
+
+	// get and validate query params
+	includeDeleted, err := parseBoolQuery(request.QueryStringParameters["include_deleted"])
+	if err != nil {
+		h.requestLogger(ctx).Error("error parsing query", "error", err)
+		return problemResponse(ctx, request, problem.
+			New(http.StatusBadRequest, "One or more query parameters are invalid").
+			WithFieldErrors(map[string]string{"include_deleted": "must be true or false"}),
+		), nil
 	}
 
 	// get values from db
-	user, err := h.userService.Fetch(ID)
+	foundUser, err := h.userService.Fetch(ctx, ID, includeDeleted)
 	if err != nil {
-		h.logger.Error("error getting all locations", "error", err)
-		respBody, _ := structToJSON(responseError{Error: "Error retrieving data"})
-		return events.APIGatewayProxyResponse{
-			StatusCode: http.StatusInternalServerError,
-			Body:       respBody,
-		}, nil
+		if errors.Is(err, user.ErrUserNotFound) {
+			h.requestLogger(ctx).Error("object does not exist", "ID", ID, "error", err)
+			return problemResponse(ctx, request, problem.New(http.StatusNotFound, "Object does not exist")), nil
+		}
+		h.requestLogger(ctx).Error("error getting all locations", "error", err)
+		return problemResponse(ctx, request, problem.New(http.StatusInternalServerError, "Error retrieving data")), nil
 	}
 
 	// return response
-	respBody, _ := structToJSON(responseOneUser{User: user})
+	respBody, _ := structToJSON(responseOneUser{User: foundUser})
 	return events.APIGatewayProxyResponse{StatusCode: http.StatusOK, Body: respBody}, nil
 }
 
 // updateUser updates a user by ID.
-func (h Handler) updateUser(request events.APIGatewayProxyRequest) (events.APIGatewayProxyResponse, error) {
+func (h Handler) updateUser(ctx context.Context, request events.APIGatewayProxyRequest) (events.APIGatewayProxyResponse, error) {
 	// get and validate ID
 	idString := request.PathParameters["ID"]
 	ID, err := strconv.Atoi(idString)
 	if err != nil {
-		h.logger.Error("error getting ID", "error", err)
-		respBody, _ := structToJSON(responseError{Error: "Not a valid ID"})
-		return events.APIGatewayProxyResponse{
-			StatusCode: http.StatusInternalServerError,
-			Body:       respBody,
-		}, nil
+		h.requestLogger(ctx).Error("error getting ID", "error", err)
+		return problemResponse(ctx, request, problem.New(http.StatusBadRequest, "Not a valid ID")), nil
 	}
 
 	// get and validate body as object
 	var inputUser entity.User
 	err = json.Unmarshal([]byte(request.Body), &inputUser)
 	if err != nil {
-		h.logger.Error("Error Unmarshalling request body", "error", err)
-		respBody, _ := structToJSON(responseError{Error: "missing values or malformed body"})
-		return events.APIGatewayProxyResponse{
-			StatusCode: http.StatusInternalServerError,
-			Body:       respBody,
-		}, nil
+		h.requestLogger(ctx).Error("Error Unmarshalling request body", "error", err)
+		return problemResponse(ctx, request, problem.New(http.StatusBadRequest, "missing values or malformed body")), nil
 	}
 
 	// update object in database
-	user, err := h.userService.Update(ID, inputUser)
+	updatedUser, err := h.userService.Update(ctx, ID, inputUser)
 	if err != nil {
-		h.logger.Error("error updating object in db", "error", err)
-		respBody, _ := structToJSON(responseError{Error: "Error updating data"})
-		return events.APIGatewayProxyResponse{
-			StatusCode: http.StatusInternalServerError,
-			Body:       respBody,
-		}, nil
+		switch {
+		case errors.Is(err, user.ErrUserNotFound):
+			h.requestLogger(ctx).Error("object does not exist", "ID", ID, "error", err)
+			return problemResponse(ctx, request, problem.New(http.StatusNotFound, "Object does not exist")), nil
+		case errors.Is(err, user.ErrUserIDConflict):
+			h.requestLogger(ctx).Error("user_id already in use", "ID", ID, "error", err)
+			return problemResponse(ctx, request, problem.New(http.StatusConflict, "user_id already in use")), nil
+		}
+		h.requestLogger(ctx).Error("error updating object in db", "error", err)
+		return problemResponse(ctx, request, problem.New(http.StatusInternalServerError, "Error updating data")), nil
 	}
 
 	// return response
-	respBody, _ := structToJSON(responseOneUser{User: user})
+	respBody, _ := structToJSON(responseOneUser{User: updatedUser})
 	return events.APIGatewayProxyResponse{StatusCode: http.StatusOK, Body: respBody}, nil
 }
 
 // createUser creates a new user.
-func (h Handler) createUser(request events.APIGatewayProxyRequest) (events.APIGatewayProxyResponse, error) {
+func (h Handler) createUser(ctx context.Context, request events.APIGatewayProxyRequest) (events.APIGatewayProxyResponse, error) {
 	// get and validate body as object
 	var inputUser entity.User
 	err := json.Unmarshal([]byte(request.Body), &inputUser)
 	if err != nil {
-		h.logger.Error("Error Unmarshalling request body", "error", err)
-		respBody, _ := structToJSON(responseError{Error: "missing values or malformed body"})
-		return events.APIGatewayProxyResponse{
-			StatusCode: http.StatusInternalServerError,
-			Body:       respBody,
-		}, nil
+		h.requestLogger(ctx).Error("Error Unmarshalling request body", "error", err)
+		return problemResponse(ctx, request, problem.New(http.StatusBadRequest, "missing values or malformed body")), nil
 	}
 
 	// create object in database
-	id, err := h.userService.Create(inputUser)
+	id, err := h.userService.Create(ctx, inputUser)
 	if err != nil {
-		h.logger.Error("error creating object in db", "error", err)
-		respBody, _ := structToJSON(responseError{Error: "Error creating object"})
-		return events.APIGatewayProxyResponse{
-			StatusCode: http.StatusInternalServerError,
-			Body:       respBody,
-		}, nil
+		if errors.Is(err, user.ErrUserIDConflict) {
+			h.requestLogger(ctx).Error("user_id already in use", "error", err)
+			return problemResponse(ctx, request, problem.New(http.StatusConflict, "user_id already in use")), nil
+		}
+		h.requestLogger(ctx).Error("error creating object in db", "error", err)
+		return problemResponse(ctx, request, problem.New(http.StatusInternalServerError, "Error creating object")), nil
 	}
 
 	// return response
@@ -159,52 +165,82 @@ func (h Handler) createUser(request events.APIGatewayProxyRequest) (events.APIGa
 	}, nil
 }
 
-// deleteUser deletes a user by ID.
-func (h Handler) deleteUser(request events.APIGatewayProxyRequest) (events.APIGatewayProxyResponse, error) {
+// deleteUser soft deletes a user by ID. If the purge query parameter is set, the user is deleted
+// permanently instead, which is only allowed for administrators.
+func (h Handler) deleteUser(ctx context.Context, request events.APIGatewayProxyRequest) (events.APIGatewayProxyResponse, error) {
 	// get and validate ID
 	idString := request.PathParameters["ID"]
 	ID, err := strconv.Atoi(idString)
 	if err != nil {
-		h.logger.Error("error getting ID", "error", err)
-		respBody, _ := structToJSON(responseError{Error: "Not a valid ID"})
-		return events.APIGatewayProxyResponse{
-			StatusCode: http.StatusInternalServerError,
-			Body:       respBody,
-		}, nil
+		h.requestLogger(ctx).Error("error getting ID", "error", err)
+		return problemResponse(ctx, request, problem.New(http.StatusBadRequest, "Not a valid ID")), nil
 	}
 
-	// check that object exists
-	user, err := h.userService.Fetch(ID)
+	// get and validate query params
+	purge, err := parseBoolQuery(request.QueryStringParameters["purge"])
 	if err != nil {
-		h.logger.Error("error getting object by ID", "error", err)
-		respBody, _ := structToJSON(responseError{Error: "Error validating object"})
-		return events.APIGatewayProxyResponse{
-			StatusCode: http.StatusInternalServerError,
-			Body:       respBody,
-		}, nil
+		h.requestLogger(ctx).Error("error parsing query", "error", err)
+		return problemResponse(ctx, request, problem.
+			New(http.StatusBadRequest, "One or more query parameters are invalid").
+			WithFieldErrors(map[string]string{"purge": "must be true or false"}),
+		), nil
 	}
-	if user.ID == 0 {
-		respBody, _ := structToJSON(responseError{Error: "Object does not exist"})
-		return events.APIGatewayProxyResponse{
-			StatusCode: http.StatusInternalServerError,
-			Body:       respBody,
-		}, nil
+	if purge && !h.isAdmin(request) {
+		h.requestLogger(ctx).Error("purge by non-admin", "ID", ID)
+		return problemResponse(ctx, request, problem.New(http.StatusForbidden, "Only administrators can purge users")), nil
 	}
 
 	// delete user
-	if err = h.userService.Delete(ID); err != nil {
-		h.logger.Error("error deleting object by ID", "error", err)
-		respBody, _ := structToJSON(responseError{Error: "Error deleting object."})
-		return events.APIGatewayProxyResponse{
-			StatusCode: http.StatusInternalServerError,
-			Body:       respBody,
-		}, nil
+	deleteUser, message := h.userService.Delete, "object successful deleted"
+	if purge {
+		deleteUser, message = h.userService.Purge, "object successful purged"
+	}
+	if err = deleteUser(ctx, ID); err != nil {
+		if errors.Is(err, user.ErrUserNotFound) {
+			h.requestLogger(ctx).Error("object does not exist", "ID", ID, "error", err)
+			return problemResponse(ctx, request, problem.New(http.StatusNotFound, "Object does not exist")), nil
+		}
+		h.requestLogger(ctx).Error("error deleting object by ID", "error", err)
+		return problemResponse(ctx, request, problem.New(http.StatusInternalServerError, "Error deleting object.")), nil
 	}
 
 	// return response
-	responseBody, _ := json.Marshal(responseMessage{Message: "object successful deleted"})
+	responseBody, _ := json.Marshal(responseMessage{Message: message})
 	return events.APIGatewayProxyResponse{
 		StatusCode: http.StatusOK,
 		Body:       string(responseBody),
 	}, nil
 }
+
+// restoreUser restores a soft deleted user by ID.
+func (h Handler) restoreUser(ctx context.Context, request events.APIGatewayProxyRequest) (events.APIGatewayProxyResponse, error) {
+	// get and validate ID
+	idString := request.PathParameters["ID"]
+	ID, err := strconv.Atoi(idString)
+	if err != nil {
+		h.requestLogger(ctx).Error("error getting ID", "error", err)
+		return problemResponse(ctx, request, problem.New(http.StatusBadRequest, "Not a valid ID")), nil
+	}
+
+	// restore user
+	restoredUser, err := h.userService.Restore(ctx, ID)
+	if err != nil {
+		switch {
+		case errors.Is(err, user.ErrUserNotFound):
+			h.requestLogger(ctx).Error("object does not exist", "ID", ID, "error", err)
+			return problemResponse(ctx, request, problem.New(http.StatusNotFound, "Object does not exist")), nil
+		case errors.Is(err, user.ErrUserNotDeleted):
+			h.requestLogger(ctx).Error("object is not deleted", "ID", ID, "error", err)
+			return problemResponse(ctx, request, problem.New(http.StatusConflict, "User is not deleted")), nil
+		case errors.Is(err, user.ErrUserIDConflict):
+			h.requestLogger(ctx).Error("user_id already in use", "ID", ID, "error", err)
+			return problemResponse(ctx, request, problem.New(http.StatusConflict, "user_id already in use")), nil
+		}
+		h.requestLogger(ctx).Error("error restoring object by ID", "error", err)
+		return problemResponse(ctx, request, problem.New(http.StatusInternalServerError, "Error restoring object")), nil
+	}
+
+	// return response
+	respBody, _ := structToJSON(responseOneUser{User: restoredUser})
+	return events.APIGatewayProxyResponse{StatusCode: http.StatusOK, Body: respBody}, nil
+}
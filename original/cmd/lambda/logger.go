@@ -1,11 +1,40 @@
 package main
 
 import (
+	"fmt"
 	"log/slog"
 	"os"
+	"strings"
+
+	"user-microservice/internal/logging"
 )
 
 func newLogger() *slog.Logger {
 	logger := slog.New(slog.NewJSONHandler(os.Stdout, nil))
 	return logger
 }
+
+// mustRedactPII returns logger with personal data redacted in LOG_REDACT_MODE, `mask` if it is not
+// set, when LOG_REDACT_PII is set. The attributes in LOG_REDACT_KEYS are redacted, or `first_name`
+// and `last_name` if it is not set. The hash mode is keyed by LOG_REDACT_SECRET. It panics if the
+// mode is unknown or the hash mode has no secret.
+func mustRedactPII(logger *slog.Logger, config configuration) *slog.Logger {
+	if !config.Log.RedactPII {
+		return logger
+	}
+
+	mode := config.Log.RedactMode
+	if mode == "" {
+		mode = logging.RedactMask
+	}
+	keys := []string{"first_name", "last_name"}
+	if config.Log.RedactKeys != "" {
+		keys = strings.Split(config.Log.RedactKeys, ",")
+	}
+
+	handler, err := logging.NewRedactHandler(logger.Handler(), mode, []byte(config.Log.RedactSecret), keys)
+	if err != nil {
+		panic(fmt.Sprintf("Error creating log redaction: %v", err))
+	}
+	return slog.New(handler)
+}
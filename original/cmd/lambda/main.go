@@ -1,13 +1,21 @@
 package main
 
 import (
+	"context"
 	"fmt"
+	"os"
 
 	"github.com/aws/aws-lambda-go/lambda"
+	"github.com/aws/aws-lambda-go/lambdacontext"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
 	"gorm.io/driver/postgres"
 
 	"user-microservice/cmd/lambda/handler"
 	"user-microservice/internal/database"
+	"user-microservice/internal/logging"
+	"user-microservice/internal/metrics"
+	"user-microservice/internal/policy"
+	"user-microservice/internal/tracing"
 	"user-microservice/internal/user"
 )
 
@@ -15,10 +23,12 @@ func main() {
 	lambda.Start(run())
 }
 
-func run() handler.APIGatewayHandler {
+func run() tracing.APIGatewayHandler {
 	config := mustNewConfiguration()
 
-	logger := newLogger()
+	logger := mustRedactPII(newLogger(), config)
+
+	provider := mustNewTracerProvider(config)
 
 	db := database.MustNewDatabase(
 		postgres.Open(
@@ -33,12 +43,63 @@ func run() handler.APIGatewayHandler {
 		),
 		database.WithLogger(logger),
 		database.WithRetryCount(5),
-		database.WithAutoMigrate(true),
+		database.WithMigrations(config.Database.MigrateOnStartup),
+		database.WithSchemaCheck(true),
+		database.WithTracing(provider),
 	)
 
-	us := user.NewService(db)
+	recorder := mustNewRecorder(db, config)
+	us := user.NewService(db, user.WithChangeCounter(recorder))
+
+	h := handler.New(us, logger, handler.WithAdminToken(config.AdminToken))
+
+	handle := handler.Run(h)
+	if config.Auth.Disabled {
+		logger.Warn("AUTH_DISABLED is set, requests are not authenticated")
+	} else {
+		rules, err := policy.New(config.Auth.PolicyFile)
+		if err != nil {
+			panic(fmt.Sprintf("Error loading AUTH_POLICY_FILE: %v", err))
+		}
+		handle = h.Authenticate(h.Authorize(rules, handle))
+	}
+
+	// the spans of every invocation are sent before it returns, so the provider needs no shutdown
+	return tracing.WrapAPIGateway(provider, metrics.WrapAPIGateway(recorder, logging.WrapAPIGateway(logger, handle)))
+}
+
+// mustNewRecorder returns the recorder that writes the metrics of the function to stdout in the
+// CloudWatch Embedded Metric Format, in the METRICS_NAMESPACE, which defaults to `UserMicroservice`.
+// It panics if the connection pool of db can not be read.
+func mustNewRecorder(db database.Database, config configuration) *metrics.EMF {
+	sqlDB, err := db.Session.DB()
+	if err != nil {
+		panic(fmt.Sprintf("Error getting database connection pool: %v", err))
+	}
+
+	namespace := config.Metrics.Namespace
+	if namespace == "" {
+		namespace = "UserMicroservice"
+	}
+	return metrics.NewEMF(os.Stdout, namespace, lambdacontext.FunctionName, sqlDB)
+}
+
+// mustNewTracerProvider returns the tracer provider configured by config, which sends spans to the
+// TRACING_EXPORTER. It panics if the exporter is unknown or can not be created.
+func mustNewTracerProvider(config configuration) *sdktrace.TracerProvider {
+	serviceName := config.Tracing.ServiceName
+	if serviceName == "" {
+		serviceName = "user-microservice"
+	}
 
-	h := handler.New(us, logger)
+	var options []tracing.Option
+	if config.Tracing.SampleRatio > 0 {
+		options = append(options, tracing.WithSampleRatio(config.Tracing.SampleRatio))
+	}
 
-	return handler.Run(h)
+	provider, err := tracing.New(context.Background(), config.Tracing.Exporter, serviceName, options...)
+	if err != nil {
+		panic(fmt.Sprintf("Error creating tracer provider: %v", err))
+	}
+	return provider
 }
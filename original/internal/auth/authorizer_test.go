@@ -0,0 +1,59 @@
+package auth
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFromAuthorizer(t *testing.T) {
+	tests := map[string]struct {
+		authorizer       map[string]any
+		expectedSubject  string
+		expectedAudience []string
+		expectedExpiry   time.Time
+		expectedError    error
+	}{
+		"Cognito authorizer claims": {
+			authorizer: map[string]any{"claims": map[string]any{
+				"sub":       "jdoe",
+				"iss":       testIssuer,
+				"aud":       testAudience,
+				"exp":       "Sat Jun 01 13:00:00 UTC 2024",
+				"cognito:x": "y",
+			}},
+			expectedSubject:  "jdoe",
+			expectedAudience: []string{testAudience},
+			expectedExpiry:   time.Date(2024, 6, 1, 13, 0, 0, 0, time.UTC),
+		},
+		"Lambda authorizer context": {
+			authorizer: map[string]any{
+				"principalId": "jdoe",
+				"aud":         "other " + testAudience,
+				"exp":         "1717246800",
+			},
+			expectedSubject:  "jdoe",
+			expectedAudience: []string{"other", testAudience},
+			expectedExpiry:   time.Unix(1717246800, 0),
+		},
+		"no authorizer": {
+			expectedError: ErrNoAuthorizerClaims,
+		},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			claims, err := FromAuthorizer(tc.authorizer)
+
+			if tc.expectedError != nil {
+				assert.ErrorIs(t, err, tc.expectedError)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tc.expectedSubject, claims.Subject, "Wrong subject")
+			assert.Equal(t, tc.expectedAudience, claims.Audience, "Wrong audience")
+			assert.True(t, tc.expectedExpiry.Equal(claims.ExpiresAt), "Wrong expiration time")
+		})
+	}
+}
@@ -0,0 +1,232 @@
+package auth
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// toJWK returns the JWK of the RSA or P-256 key with ID kid.
+func toJWK(kid string, key any) map[string]string {
+	encode := func(i *big.Int) string { return base64.RawURLEncoding.EncodeToString(i.Bytes()) }
+
+	switch key := key.(type) {
+	case *rsa.PublicKey:
+		return map[string]string{"kty": "RSA", "kid": kid, "use": "sig", "n": encode(key.N), "e": encode(big.NewInt(int64(key.E)))}
+	case *ecdsa.PublicKey:
+		return map[string]string{"kty": "EC", "kid": kid, "crv": "P-256", "x": encode(key.X), "y": encode(key.Y)}
+	}
+	return nil
+}
+
+func TestJWKSKey(t *testing.T) {
+	first, err := rsa.GenerateKey(rand.Reader, 2048)
+	assert.NoError(t, err)
+	second, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	assert.NoError(t, err)
+
+	// the issuer starts out publishing the first key, and rotates in the second
+	var (
+		published atomic.Value
+		fetches   atomic.Int32
+	)
+	published.Store([]map[string]string{toJWK("first", &first.PublicKey)})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		fetches.Add(1)
+		_ = json.NewEncoder(w).Encode(map[string]any{"keys": published.Load()})
+	}))
+	defer server.Close()
+
+	now := time.Date(2024, 6, 1, 12, 0, 0, 0, time.UTC)
+	jwks := NewJWKS(server.URL, WithRefreshInterval(time.Hour), WithMinRefreshInterval(time.Minute))
+	jwks.now = func() time.Time { return now }
+	ctx := context.Background()
+
+	key, err := jwks.Key(ctx, "first")
+	assert.NoError(t, err)
+	assert.Equal(t, &first.PublicKey, key, "Wrong key")
+	assert.EqualValues(t, 1, fetches.Load(), "Set not fetched on first use")
+
+	_, err = jwks.Key(ctx, "first")
+	assert.NoError(t, err)
+	assert.EqualValues(t, 1, fetches.Load(), "Set not cached")
+
+	published.Store([]map[string]string{
+		toJWK("first", &first.PublicKey),
+		toJWK("second", &second.PublicKey),
+		{"kty": "RSA", "kid": "encryption", "use": "enc", "n": "AQAB", "e": "AQAB"},
+	})
+
+	_, err = jwks.Key(ctx, "second")
+	assert.ErrorIs(t, err, ErrKeyNotFound, "Set fetched again before minimum refresh interval")
+	assert.EqualValues(t, 1, fetches.Load())
+
+	now = now.Add(time.Minute)
+	key, err = jwks.Key(ctx, "second")
+	assert.NoError(t, err, "Rotated key not picked up")
+	assert.Equal(t, &second.PublicKey, key, "Wrong key")
+	assert.EqualValues(t, 2, fetches.Load())
+
+	_, err = jwks.Key(ctx, "encryption")
+	assert.ErrorIs(t, err, ErrKeyNotFound, "Encryption key used for signatures")
+
+	published.Store([]map[string]string{toJWK("second", &second.PublicKey)})
+	now = now.Add(time.Hour)
+	_, err = jwks.Key(ctx, "first")
+	assert.ErrorIs(t, err, ErrKeyNotFound, "Rotated out key still used after refresh interval")
+	assert.EqualValues(t, 3, fetches.Load())
+}
+
+func TestJWKSKeyIssuerUnavailable(t *testing.T) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	assert.NoError(t, err)
+
+	var available atomic.Bool
+	available.Store(true)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		if !available.Load() {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		_ = json.NewEncoder(w).Encode(map[string]any{"keys": []map[string]string{toJWK("key", &key.PublicKey)}})
+	}))
+	defer server.Close()
+
+	now := time.Date(2024, 6, 1, 12, 0, 0, 0, time.UTC)
+	jwks := NewJWKS(server.URL, WithRefreshInterval(time.Hour))
+	jwks.now = func() time.Time { return now }
+
+	_, err = jwks.Key(context.Background(), "key")
+	assert.NoError(t, err)
+
+	available.Store(false)
+	now = now.Add(2 * time.Hour)
+	_, err = jwks.Key(context.Background(), "key")
+	assert.NoError(t, err, "Cached keys not used while issuer is unavailable")
+
+	unavailable := NewJWKS(server.URL)
+	_, err = unavailable.Key(context.Background(), "key")
+	assert.Error(t, err)
+	assert.NotErrorIs(t, err, ErrKeyNotFound, "Unavailable issuer reported as unknown key")
+}
+
+func TestJWKSKeyConcurrentFetch(t *testing.T) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	assert.NoError(t, err)
+
+	// the issuer answers once released, so that every request waits for the same fetch
+	var fetches atomic.Int32
+	release := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		fetches.Add(1)
+		<-release
+		_ = json.NewEncoder(w).Encode(map[string]any{"keys": []map[string]string{toJWK("key", &key.PublicKey)}})
+	}))
+	defer server.Close()
+
+	jwks := NewJWKS(server.URL)
+
+	// a request that gives up waiting does not cancel the fetch for the others
+	canceled, cancel := context.WithCancel(context.Background())
+	cancel()
+	_, err = jwks.Key(canceled, "key")
+	assert.ErrorIs(t, err, context.Canceled)
+
+	var wg sync.WaitGroup
+	errs := make([]error, 10)
+	for i := range errs {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, errs[i] = jwks.Key(context.Background(), "key")
+		}()
+	}
+	close(release)
+	wg.Wait()
+
+	for _, err := range errs {
+		assert.NoError(t, err)
+	}
+	assert.EqualValues(t, 1, fetches.Load(), "Set fetched once per request")
+}
+
+func TestLoadKeyFile(t *testing.T) {
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	assert.NoError(t, err)
+	ecKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	assert.NoError(t, err)
+	der, err := x509.MarshalPKIXPublicKey(&ecKey.PublicKey)
+	assert.NoError(t, err)
+	jwks, err := json.Marshal(map[string]any{"keys": []map[string]string{
+		toJWK("rsa", &rsaKey.PublicKey),
+		toJWK("ec", &ecKey.PublicKey),
+	}})
+	assert.NoError(t, err)
+
+	tests := map[string]struct {
+		contents      []byte
+		expectedKeys  StaticKeys
+		expectedError bool
+	}{
+		"PEM public key": {
+			contents:     pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: der}),
+			expectedKeys: StaticKeys{"": &ecKey.PublicKey},
+		},
+		"PEM RSA public key": {
+			contents:     pem.EncodeToMemory(&pem.Block{Type: "RSA PUBLIC KEY", Bytes: x509.MarshalPKCS1PublicKey(&rsaKey.PublicKey)}),
+			expectedKeys: StaticKeys{"": &rsaKey.PublicKey},
+		},
+		"JWK Set": {
+			contents:     jwks,
+			expectedKeys: StaticKeys{"rsa": &rsaKey.PublicKey, "ec": &ecKey.PublicKey},
+		},
+		"more than one PEM key": {
+			contents: append(
+				pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: der}),
+				pem.EncodeToMemory(&pem.Block{Type: "RSA PUBLIC KEY", Bytes: x509.MarshalPKCS1PublicKey(&rsaKey.PublicKey)})...,
+			),
+			expectedError: true,
+		},
+		"no keys": {
+			contents:      []byte(`{"keys": []}`),
+			expectedError: true,
+		},
+		"point not on curve": {
+			contents:      []byte(`{"keys": [{"kty": "EC", "crv": "P-256", "x": "AQ", "y": "AQ"}]}`),
+			expectedError: true,
+		},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "keys")
+			assert.NoError(t, os.WriteFile(path, tc.contents, 0o600))
+
+			keys, err := LoadKeyFile(path)
+
+			if tc.expectedError {
+				assert.Error(t, err)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tc.expectedKeys, keys, "Wrong keys")
+		})
+	}
+}
@@ -1,14 +1,20 @@
 package database
 
 import (
+	"context"
+	"database/sql"
 	"fmt"
 	"log/slog"
 	"os"
 	"time"
 
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/stdlib"
+	"go.opentelemetry.io/otel/trace"
+	"gorm.io/driver/postgres"
 	"gorm.io/gorm"
 
-	"user-microservice/internal/database/entity"
+	"user-microservice/internal/tracing"
 )
 
 type Database struct {
@@ -20,8 +26,10 @@ type Options func(*dbSetupOptions)
 type dbSetupOptions struct {
 	connectionRetry int
 	runMigrations   bool
+	schemaCheck     bool
 	gormConfig      gorm.Config
 	logger          *slog.Logger
+	tracerProvider  trace.TracerProvider
 }
 
 func WithRetryCount(count int) Options {
@@ -30,9 +38,19 @@ func WithRetryCount(count int) Options {
 	}
 }
 
-func WithAutoMigrate(runMigration bool) Options {
+// WithMigrations applies any pending migrations from the migrations directory once the database
+// connection is established.
+func WithMigrations(runMigrations bool) Options {
 	return func(setup *dbSetupOptions) {
-		setup.runMigrations = runMigration
+		setup.runMigrations = runMigrations
+	}
+}
+
+// WithSchemaCheck makes MustNewDatabase panic if the database has pending migrations. It is
+// ignored if migrations are run with WithMigrations.
+func WithSchemaCheck(check bool) Options {
+	return func(setup *dbSetupOptions) {
+		setup.schemaCheck = check
 	}
 }
 
@@ -48,7 +66,16 @@ func WithLogger(logger *slog.Logger) Options {
 	}
 }
 
-// MustNewDatabase Establish session connection and migrate tables before
+// WithTracing runs the statements of the session in spans of provider, as children of the span of
+// the context they are run with. It only applies to postgres dialectors opened from a DSN, such as
+// those of postgres.Open.
+func WithTracing(provider trace.TracerProvider) Options {
+	return func(setup *dbSetupOptions) {
+		setup.tracerProvider = provider
+	}
+}
+
+// MustNewDatabase Establish session connection and migrate or check tables before
 // returning database.Database struct.
 func MustNewDatabase(d gorm.Dialector, options ...Options) Database {
 	opts := dbSetupOptions{
@@ -68,6 +95,12 @@ func MustNewDatabase(d gorm.Dialector, options ...Options) Database {
 		retryCount int
 	)
 
+	if opts.tracerProvider != nil {
+		if d, err = tracedDialector(d, opts.tracerProvider); err != nil {
+			panic(fmt.Sprintf("dataBaseConnect: %v", err))
+		}
+	}
+
 	slog.Info("Attempting to connect to session")
 
 	err = func() error {
@@ -93,12 +126,44 @@ func MustNewDatabase(d gorm.Dialector, options ...Options) Database {
 
 	opts.logger.Info("Database connection established", "Retry count", retryCount)
 
-	if opts.runMigrations {
-		if err = DB.AutoMigrate(&entity.User{}); err != nil {
-			panic(fmt.Sprintf("autoMigrate error: %v", err))
+	if opts.runMigrations || opts.schemaCheck {
+		sqlDB, err := DB.DB()
+		if err != nil {
+			panic(fmt.Sprintf("migration error: %v", err))
+		}
+
+		migrator, err := NewMigrator(sqlDB, opts.logger)
+		if err != nil {
+			panic(fmt.Sprintf("migration error: %v", err))
+		}
+
+		if opts.runMigrations {
+			if err = migrator.Up(context.Background()); err != nil {
+				panic(fmt.Sprintf("migration error: %v", err))
+			}
+			opts.logger.Info("Database migration successful")
+		} else if err = migrator.Check(context.Background()); err != nil {
+			panic(fmt.Sprintf("schema check error: %v", err))
 		}
-		opts.logger.Info("Database migration successful")
 	}
 
 	return Database{Session: DB}
 }
+
+// tracedDialector returns a copy of d whose connections are traced with provider. Dialectors other
+// than postgres dialectors opened from a DSN are returned unchanged.
+func tracedDialector(d gorm.Dialector, provider trace.TracerProvider) (gorm.Dialector, error) {
+	pd, ok := d.(*postgres.Dialector)
+	if !ok || pd.Conn != nil {
+		return d, nil
+	}
+
+	connConfig, err := pgx.ParseConfig(pd.DSN)
+	if err != nil {
+		return nil, fmt.Errorf("in tracedDialector: %w", err)
+	}
+
+	config := *pd.Config
+	config.Conn = sql.OpenDB(tracing.WrapConnector(stdlib.GetConnector(*connConfig), provider))
+	return postgres.New(config), nil
+}
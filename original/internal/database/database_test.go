@@ -1,6 +1,7 @@
 package database
 
 import (
+	"context"
 	"database/sql"
 	"database/sql/driver"
 	"errors"
@@ -9,11 +10,14 @@ import (
 	"regexp"
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/DATA-DOG/go-sqlmock"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/suite"
+	"go.opentelemetry.io/otel/trace/noop"
 	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
 
 	"user-microservice/internal/database/entity"
 	"user-microservice/internal/testutil"
@@ -43,7 +47,7 @@ func (s *databaseSuit) SetupSuite() {
 			},
 		),
 		WithRetryCount(5),
-		WithAutoMigrate(false),
+		WithMigrations(false),
 	)
 
 	s.db = db
@@ -60,11 +64,17 @@ func (s *databaseSuit) TearDownSuite() {
 func (s *databaseSuit) TestListUsers() {
 	t := s.T()
 
+	deletedUser := testutil.NewUser()
+	deletedUser.DeletedAt = gorm.DeletedAt{Time: time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC), Valid: true}
+
 	testCases := map[string]struct {
-		expectedReturn []entity.User
-		expectedError  error
+		inputIncludeDeleted bool
+		expectedQuery       string
+		expectedReturn      []entity.User
+		expectedError       error
 	}{
 		"Good": {
+			expectedQuery: `SELECT * FROM "users" WHERE "users"."deleted_at" IS NULL`,
 			expectedReturn: []entity.User{
 				testutil.NewUser(),
 				testutil.NewUser(),
@@ -72,18 +82,27 @@ func (s *databaseSuit) TestListUsers() {
 			},
 			expectedError: nil,
 		},
+		"Good - include deleted": {
+			inputIncludeDeleted: true,
+			expectedQuery:       `SELECT * FROM "users"`,
+			expectedReturn: []entity.User{
+				testutil.NewUser(),
+				deletedUser,
+			},
+			expectedError: nil,
+		},
 		// "Bad": {}, // TODO: add bad case for test
 	}
 	for name, tc := range testCases {
 		t.Run(name, func(t *testing.T) {
 			rows := mustStructsToRows(tc.expectedReturn)
 
-			query := regexp.QuoteMeta(`SELECT * FROM "users"`)
+			query := regexp.QuoteMeta(tc.expectedQuery) + "$"
 			s.dbMock.
 				ExpectQuery(query).
 				WillReturnRows(rows)
 
-			actualReturn, err := s.session.ListUsers()
+			actualReturn, err := s.session.ListUsers(context.Background(), tc.inputIncludeDeleted)
 
 			assert.Equal(t, tc.expectedError, err, "error in ListUsers")
 			assert.Equal(t, tc.expectedReturn, actualReturn, "returned data does not match")
@@ -97,37 +116,51 @@ func (s *databaseSuit) TestFetchUser() {
 	t := s.T()
 
 	testUser := testutil.NewUser(testutil.WithID(1))
+	deletedUser := testutil.NewUser(testutil.WithID(3))
+	deletedUser.DeletedAt = gorm.DeletedAt{Time: time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC), Valid: true}
+
+	activeQuery := `SELECT * FROM "users" WHERE ID = $1 AND "users"."deleted_at" IS NULL ORDER BY "users"."id" LIMIT $2`
 
 	testCases := map[string]struct {
-		mockReturnRows *sqlmock.Rows
-		expectedReturn entity.User
-		userID         int
-		expectedError  error
+		mockReturnRows      *sqlmock.Rows
+		expectedQuery       string
+		expectedReturn      entity.User
+		userID              int
+		inputIncludeDeleted bool
+		expectedError       error
 	}{
 		"Good": {
 			mockReturnRows: mustStructsToRows([]entity.User{testUser}),
+			expectedQuery:  activeQuery,
 			expectedReturn: testUser,
 			userID:         int(testUser.ID),
 			expectedError:  nil,
 		},
+		"Good - include deleted": {
+			mockReturnRows:      mustStructsToRows([]entity.User{deletedUser}),
+			expectedQuery:       `SELECT * FROM "users" WHERE ID = $1 ORDER BY "users"."id" LIMIT $2`,
+			expectedReturn:      deletedUser,
+			userID:              int(deletedUser.ID),
+			inputIncludeDeleted: true,
+			expectedError:       nil,
+		},
 		"Bad": {
 			mockReturnRows: mustStructToEmptyRow(entity.User{}),
+			expectedQuery:  activeQuery,
 			expectedReturn: entity.User{},
 			userID:         2,
-			expectedError:  nil,
+			expectedError:  fmt.Errorf("in session.FetchUser: %w", gorm.ErrRecordNotFound),
 		},
 	}
 	for name, tc := range testCases {
 		t.Run(name, func(t *testing.T) {
-			query := regexp.QuoteMeta(
-				`SELECT * FROM "users" WHERE ID = $1 ORDER BY "users"."id" LIMIT $2`,
-			)
+			query := regexp.QuoteMeta(tc.expectedQuery)
 			s.dbMock.
 				ExpectQuery(query).
 				WithArgs(tc.userID, 1).
 				WillReturnRows(tc.mockReturnRows)
 
-			actualReturn, err := s.session.FetchUser(tc.userID)
+			actualReturn, err := s.session.FetchUser(context.Background(), tc.userID, tc.inputIncludeDeleted)
 
 			assert.Equal(t, tc.expectedError, err, "error in FetchUser")
 			assert.Equal(t, tc.expectedReturn, actualReturn, "returned data does not match")
@@ -137,15 +170,60 @@ func (s *databaseSuit) TestFetchUser() {
 	}
 }
 
+func (s *databaseSuit) TestFetchUserByUserID() {
+	t := s.T()
+
+	testUser := testutil.NewUser(testutil.WithID(1))
+
+	query := `SELECT * FROM "users" WHERE user_id = $1 AND "users"."deleted_at" IS NULL ORDER BY "users"."id" LIMIT $2`
+
+	testCases := map[string]struct {
+		mockReturnRows *sqlmock.Rows
+		expectedReturn entity.User
+		userID         uint
+		expectedError  error
+	}{
+		"Good": {
+			mockReturnRows: mustStructsToRows([]entity.User{testUser}),
+			expectedReturn: testUser,
+			userID:         testUser.UserID,
+			expectedError:  nil,
+		},
+		"Bad": {
+			mockReturnRows: mustStructToEmptyRow(entity.User{}),
+			expectedReturn: entity.User{},
+			userID:         testUser.UserID + 1,
+			expectedError:  fmt.Errorf("in session.FetchUserByUserID: %w", gorm.ErrRecordNotFound),
+		},
+	}
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			s.dbMock.
+				ExpectQuery(regexp.QuoteMeta(query)).
+				WithArgs(tc.userID, 1).
+				WillReturnRows(tc.mockReturnRows)
+
+			actualReturn, err := s.session.FetchUserByUserID(context.Background(), tc.userID)
+
+			assert.Equal(t, tc.expectedError, err, "error in FetchUserByUserID")
+			assert.Equal(t, tc.expectedReturn, actualReturn, "returned data does not match")
+
+			err = s.dbMock.ExpectationsWereMet()
+		})
+	}
+}
+
 func (s *databaseSuit) TestUpdateUser() {
 	t := s.T()
 
 	query := regexp.QuoteMeta(
-		`UPDATE "users" SET "first_name"=$1,"last_name"=$2,"role"=$3,"user_id"=$4 WHERE "id" = $5`,
+		`UPDATE "users" SET "first_name"=$1,"last_name"=$2,"role"=$3,"user_id"=$4 WHERE "users"."deleted_at" IS NULL AND "id" = $5`,
 	)
 
 	user := testutil.NewUser(testutil.WithID(1))
 
+	uniqueViolationErr := sqlStateError{code: "23505"}
+
 	testCases := map[string]struct {
 		mockDBFunc     func()
 		inputID        int
@@ -182,12 +260,47 @@ func (s *databaseSuit) TestUpdateUser() {
 				fmt.Errorf("in Transaction: %w", fmt.Errorf("some error")),
 			),
 		},
+		"Bad - no rows updated": {
+			func() {
+				s.dbMock.ExpectBegin()
+				s.dbMock.ExpectExec(query).
+					WithArgs(user.FirstName, user.LastName, user.Role, user.UserID, user.ID+1).
+					WillReturnResult(sqlmock.NewResult(0, 0))
+				s.dbMock.ExpectRollback()
+			},
+			int(user.ID + 1),
+			user,
+			entity.User{},
+			fmt.Errorf(
+				"in session.UpdateUser: %w",
+				fmt.Errorf("in Transaction: %w", gorm.ErrRecordNotFound),
+			),
+		},
+		"Bad - user_id already in use": {
+			func() {
+				s.dbMock.ExpectBegin()
+				s.dbMock.ExpectExec(query).
+					WithArgs(user.FirstName, user.LastName, user.Role, user.UserID, user.ID).
+					WillReturnError(uniqueViolationErr)
+				s.dbMock.ExpectRollback()
+			},
+			int(user.ID),
+			user,
+			entity.User{},
+			fmt.Errorf(
+				"in session.UpdateUser: %w",
+				fmt.Errorf(
+					"in Transaction: %w",
+					fmt.Errorf("%w: %w", gorm.ErrDuplicatedKey, uniqueViolationErr),
+				),
+			),
+		},
 	}
 	for name, tc := range testCases {
 		t.Run(name, func(t *testing.T) {
 			tc.mockDBFunc()
 
-			actualReturn, err := s.session.UpdateUser(tc.inputID, tc.inputUser)
+			actualReturn, err := s.session.UpdateUser(context.Background(), tc.inputID, tc.inputUser)
 
 			assert.Equal(t, tc.expectedError, err, "error in UpdateUser")
 			assert.Equal(t, tc.expectedReturn, actualReturn, "returned data does not match")
@@ -201,7 +314,7 @@ func (s *databaseSuit) TestCreateUser() {
 	t := s.T()
 
 	query := regexp.QuoteMeta(
-		`INSERT INTO "users" ("first_name","last_name","role","user_id") VALUES ($1,$2,$3,$4) RETURNING "id"`,
+		`INSERT INTO "users" ("first_name","last_name","role","user_id","deleted_at") VALUES ($1,$2,$3,$4,$5) RETURNING "id"`,
 	)
 
 	user := testutil.NewUser(testutil.WithID(1))
@@ -209,6 +322,7 @@ func (s *databaseSuit) TestCreateUser() {
 	duplicateUserErr := errors.New(
 		"duplicate key value violates unique constraint \"users_user_id_key\" (SQLSTATE 23505)",
 	)
+	uniqueViolationErr := sqlStateError{code: "23505"}
 
 	testCases := map[string]struct {
 		mockDBFunc     func(DBMock sqlmock.Sqlmock)
@@ -220,7 +334,7 @@ func (s *databaseSuit) TestCreateUser() {
 			func(DBMock sqlmock.Sqlmock) {
 				DBMock.ExpectBegin()
 				DBMock.ExpectQuery(query).
-					WithArgs(user.FirstName, user.LastName, user.Role, user.UserID).
+					WithArgs(user.FirstName, user.LastName, user.Role, user.UserID, nil).
 					WillReturnRows(mustStructsToRows([]entity.User{user}))
 				DBMock.ExpectCommit()
 			},
@@ -232,7 +346,7 @@ func (s *databaseSuit) TestCreateUser() {
 			func(DBMock sqlmock.Sqlmock) {
 				DBMock.ExpectBegin()
 				DBMock.ExpectQuery(query).
-					WithArgs(user.FirstName, user.LastName, user.Role, user.UserID).
+					WithArgs(user.FirstName, user.LastName, user.Role, user.UserID, nil).
 					WillReturnError(duplicateUserErr)
 				DBMock.ExpectRollback()
 			},
@@ -246,12 +360,30 @@ func (s *databaseSuit) TestCreateUser() {
 				),
 			),
 		},
+		"Bad - user_id already in use": {
+			func(DBMock sqlmock.Sqlmock) {
+				DBMock.ExpectBegin()
+				DBMock.ExpectQuery(query).
+					WithArgs(user.FirstName, user.LastName, user.Role, user.UserID, nil).
+					WillReturnError(uniqueViolationErr)
+				DBMock.ExpectRollback()
+			},
+			user,
+			entity.User{},
+			fmt.Errorf(
+				"in session.CreateUser: %w",
+				fmt.Errorf(
+					"in Transaction: %w",
+					fmt.Errorf("%w: %w", gorm.ErrDuplicatedKey, uniqueViolationErr),
+				),
+			),
+		},
 	}
 	for name, tc := range testCases {
 		t.Run(name, func(t *testing.T) {
 			tc.mockDBFunc(s.dbMock)
 
-			actualReturn, err := s.session.CreateUser(tc.inputUser)
+			actualReturn, err := s.session.CreateUser(context.Background(), tc.inputUser)
 
 			assert.Equal(t, tc.expectedError, err, "error in CreateUser")
 			assert.Equal(t, tc.expectedReturn, actualReturn, "returned data does not match")
@@ -264,7 +396,9 @@ func (s *databaseSuit) TestCreateUser() {
 func (s *databaseSuit) TestDeleteUser() {
 	t := s.T()
 
-	query := regexp.QuoteMeta(`DELETE FROM "users" WHERE "users"."id" = $1`)
+	query := regexp.QuoteMeta(
+		`UPDATE "users" SET "deleted_at"=$1 WHERE "users"."id" = $2 AND "users"."deleted_at" IS NULL`,
+	)
 
 	user := testutil.NewUser(testutil.WithID(1))
 
@@ -278,7 +412,7 @@ func (s *databaseSuit) TestDeleteUser() {
 			func(DBMock sqlmock.Sqlmock) {
 				DBMock.ExpectBegin()
 				DBMock.ExpectExec(query).
-					WithArgs(user.ID).
+					WithArgs(sqlmock.AnyArg(), user.ID).
 					WillReturnResult(sqlmock.NewResult(1, 1))
 				DBMock.ExpectCommit()
 			},
@@ -286,12 +420,24 @@ func (s *databaseSuit) TestDeleteUser() {
 			user,
 			nil,
 		},
+		"Bad - ID does not exist": {
+			func(DBMock sqlmock.Sqlmock) {
+				DBMock.ExpectBegin()
+				DBMock.ExpectExec(query).
+					WithArgs(sqlmock.AnyArg(), user.ID+1).
+					WillReturnResult(sqlmock.NewResult(0, 0))
+				DBMock.ExpectCommit()
+			},
+			int(user.ID + 1),
+			entity.User{},
+			fmt.Errorf("in session.DeleteUser: %w", gorm.ErrRecordNotFound),
+		},
 	}
 	for name, tc := range testCases {
 		t.Run(name, func(t *testing.T) {
 			tc.mockDBFunc(s.dbMock)
 
-			err := s.session.DeleteUser(tc.inputID)
+			err := s.session.DeleteUser(context.Background(), tc.inputID)
 
 			assert.Equal(t, tc.expectedError, err, "error in DeleteUser")
 
@@ -300,8 +446,220 @@ func (s *databaseSuit) TestDeleteUser() {
 	}
 }
 
+func (s *databaseSuit) TestRestoreUser() {
+	t := s.T()
+
+	selectQuery := regexp.QuoteMeta(
+		`SELECT * FROM "users" WHERE "users"."id" = $1 ORDER BY "users"."id" LIMIT $2 FOR UPDATE`,
+	)
+	updateQuery := regexp.QuoteMeta(
+		`UPDATE "users" SET "deleted_at"=$1 WHERE deleted_at IS NOT NULL AND "id" = $2`,
+	)
+
+	user := testutil.NewUser(testutil.WithID(1))
+	deletedUser := user
+	deletedUser.DeletedAt = gorm.DeletedAt{Time: time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC), Valid: true}
+
+	uniqueViolationErr := sqlStateError{code: "23505"}
+
+	testCases := map[string]struct {
+		mockDBFunc     func(DBMock sqlmock.Sqlmock)
+		inputID        int
+		expectedReturn entity.User
+		expectedError  error
+	}{
+		"Good": {
+			func(DBMock sqlmock.Sqlmock) {
+				DBMock.ExpectBegin()
+				DBMock.ExpectQuery(selectQuery).
+					WithArgs(user.ID, 1).
+					WillReturnRows(mustStructsToRows([]entity.User{deletedUser}))
+				DBMock.ExpectExec(updateQuery).
+					WithArgs(nil, user.ID).
+					WillReturnResult(sqlmock.NewResult(1, 1))
+				DBMock.ExpectCommit()
+			},
+			int(user.ID),
+			user,
+			nil,
+		},
+		"Bad - ID does not exist": {
+			func(DBMock sqlmock.Sqlmock) {
+				DBMock.ExpectBegin()
+				DBMock.ExpectQuery(selectQuery).
+					WithArgs(user.ID+1, 1).
+					WillReturnRows(mustStructToEmptyRow(entity.User{}))
+				DBMock.ExpectRollback()
+			},
+			int(user.ID + 1),
+			entity.User{},
+			fmt.Errorf(
+				"in session.RestoreUser: %w",
+				fmt.Errorf("in Transaction: %w", gorm.ErrRecordNotFound),
+			),
+		},
+		"Bad - user is not deleted": {
+			func(DBMock sqlmock.Sqlmock) {
+				DBMock.ExpectBegin()
+				DBMock.ExpectQuery(selectQuery).
+					WithArgs(user.ID, 1).
+					WillReturnRows(mustStructsToRows([]entity.User{user}))
+				DBMock.ExpectRollback()
+			},
+			int(user.ID),
+			entity.User{},
+			fmt.Errorf(
+				"in session.RestoreUser: %w",
+				fmt.Errorf("in Transaction: %w", ErrRecordNotDeleted),
+			),
+		},
+		"Bad - user_id already in use": {
+			func(DBMock sqlmock.Sqlmock) {
+				DBMock.ExpectBegin()
+				DBMock.ExpectQuery(selectQuery).
+					WithArgs(user.ID, 1).
+					WillReturnRows(mustStructsToRows([]entity.User{deletedUser}))
+				DBMock.ExpectExec(updateQuery).
+					WithArgs(nil, user.ID).
+					WillReturnError(uniqueViolationErr)
+				DBMock.ExpectRollback()
+			},
+			int(user.ID),
+			entity.User{},
+			fmt.Errorf(
+				"in session.RestoreUser: %w",
+				fmt.Errorf(
+					"in Transaction: %w",
+					fmt.Errorf("%w: %w", gorm.ErrDuplicatedKey, uniqueViolationErr),
+				),
+			),
+		},
+	}
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			tc.mockDBFunc(s.dbMock)
+
+			actualReturn, err := s.session.RestoreUser(context.Background(), tc.inputID)
+
+			assert.Equal(t, tc.expectedError, err, "error in RestoreUser")
+			assert.Equal(t, tc.expectedReturn, actualReturn, "returned data does not match")
+
+			err = s.dbMock.ExpectationsWereMet()
+			assert.NoError(t, err)
+		})
+	}
+}
+
+func (s *databaseSuit) TestPurgeUser() {
+	t := s.T()
+
+	query := regexp.QuoteMeta(`DELETE FROM "users" WHERE "users"."id" = $1`)
+
+	user := testutil.NewUser(testutil.WithID(1))
+
+	testCases := map[string]struct {
+		mockDBFunc    func(DBMock sqlmock.Sqlmock)
+		inputID       int
+		expectedError error
+	}{
+		"Good": {
+			func(DBMock sqlmock.Sqlmock) {
+				DBMock.ExpectBegin()
+				DBMock.ExpectExec(query).
+					WithArgs(user.ID).
+					WillReturnResult(sqlmock.NewResult(1, 1))
+				DBMock.ExpectCommit()
+			},
+			int(user.ID),
+			nil,
+		},
+		"Bad - ID does not exist": {
+			func(DBMock sqlmock.Sqlmock) {
+				DBMock.ExpectBegin()
+				DBMock.ExpectExec(query).
+					WithArgs(user.ID + 1).
+					WillReturnResult(sqlmock.NewResult(0, 0))
+				DBMock.ExpectCommit()
+			},
+			int(user.ID + 1),
+			fmt.Errorf("in session.PurgeUser: %w", gorm.ErrRecordNotFound),
+		},
+	}
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			tc.mockDBFunc(s.dbMock)
+
+			err := s.session.PurgeUser(context.Background(), tc.inputID)
+
+			assert.Equal(t, tc.expectedError, err, "error in PurgeUser")
+
+			err = s.dbMock.ExpectationsWereMet()
+			assert.NoError(t, err)
+		})
+	}
+}
+
+func TestTracedDialector(t *testing.T) {
+	provider := noop.NewTracerProvider()
+	db, _, _ := sqlmock.New()
+	defer db.Close()
+
+	testCases := map[string]struct {
+		dialector     gorm.Dialector
+		expectedTrace bool
+		expectedError bool
+	}{
+		"postgres DSN": {
+			postgres.Open("host=localhost user=user password=pw dbname=users port=5432 sslmode=disable"),
+			true,
+			false,
+		},
+		"postgres connection": {
+			postgres.New(postgres.Config{Conn: db}),
+			false,
+			false,
+		},
+		"invalid DSN": {
+			postgres.Open("port=port"),
+			false,
+			true,
+		},
+	}
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			traced, err := tracedDialector(tc.dialector, provider)
+			if tc.expectedError {
+				assert.Error(t, err)
+				return
+			}
+			assert.NoError(t, err)
+
+			if !tc.expectedTrace {
+				assert.Same(t, tc.dialector, traced, "dialector should be unchanged")
+				return
+			}
+			conn, ok := traced.(*postgres.Dialector).Conn.(*sql.DB)
+			if assert.True(t, ok, "dialector should have a connection pool") {
+				connector := reflect.ValueOf(conn).Elem().FieldByName("connector").Elem()
+				assert.Equal(t, "*tracing.tracedConnector", connector.Type().String())
+				_ = conn.Close()
+			}
+		})
+	}
+}
+
 // ━━ HELPERS ━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━
 
+// sqlStateError is a driver error that reports a Postgres SQLSTATE code in the same way as
+// pgconn.PgError.
+type sqlStateError struct {
+	code string
+}
+
+func (e sqlStateError) Error() string { return "SQLSTATE " + e.code }
+
+func (e sqlStateError) SQLState() string { return e.code }
+
 // structSliceToSQLMockRows converts a slice of structs to sqlmock.Rows using reflect.
 // It can also be used when only a single struct is needed by wrapping in a slice.
 func mustStructsToRows[T any](slice []T) *sqlmock.Rows {
@@ -337,7 +695,15 @@ func mustStructsToRows[T any](slice []T) *sqlmock.Rows {
 		var values []driver.Value
 		elem := v.Index(i)
 		for j := 0; j < elem.NumField(); j++ {
-			values = append(values, elem.Field(j).Interface())
+			value := elem.Field(j).Interface()
+			// use the driver value of fields such as gorm.DeletedAt so that they can be scanned
+			if valuer, ok := value.(driver.Valuer); ok {
+				var err error
+				if value, err = valuer.Value(); err != nil {
+					panic(fmt.Sprintf("converting field %s: %v", elemType.Field(j).Name, err))
+				}
+			}
+			values = append(values, value)
 		}
 		rows.AddRow(values...)
 	}
@@ -1,9 +1,14 @@
 package entity
 
+import "gorm.io/gorm"
+
+// User is a user of the service. Fields tagged `pii:"true"` hold personal data, which
+// logging.RedactHandler keeps out of logs.
 type User struct {
-	ID        uint   `gorm:"primary_key" json:"id,omitempty"`
-	FirstName string `                   json:"first_name,omitempty"`
-	LastName  string `                   json:"last_name,omitempty"`
-	Role      string `                   json:"role,omitempty"`
-	UserID    uint   `gorm:"unique"      json:"user_id,omitempty"`
+	ID        uint           `gorm:"primary_key" json:"id,omitempty"`
+	FirstName string         `                   json:"first_name,omitempty" pii:"true"`
+	LastName  string         `                   json:"last_name,omitempty" pii:"true"`
+	Role      string         `                   json:"role,omitempty"`
+	UserID    uint           `gorm:"unique"      json:"user_id,omitempty"`
+	DeletedAt gorm.DeletedAt `gorm:"index"       json:"deleted_at"`
 }
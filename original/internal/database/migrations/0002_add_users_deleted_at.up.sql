@@ -0,0 +1,6 @@
+ALTER TABLE users
+    ADD COLUMN deleted_at TIMESTAMPTZ;
+CREATE INDEX idx_users_deleted_at ON users (deleted_at);
+ALTER TABLE users
+    DROP CONSTRAINT users_user_id_key;
+CREATE UNIQUE INDEX users_user_id_key ON users (user_id) WHERE deleted_at IS NULL;
@@ -1,21 +1,29 @@
 package database
 
 import (
+	"context"
 	"errors"
 	"fmt"
-	"log/slog"
 
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 
 	"user-microservice/internal/database/entity"
 )
 
-// ListUsers returns a list of all entity.User objects from the database.
+// pgUniqueViolation is the Postgres SQLSTATE raised when a unique constraint is violated.
+const pgUniqueViolation = "23505"
+
+// ErrRecordNotDeleted is returned when a record that is not soft deleted is restored.
+var ErrRecordNotDeleted = errors.New("record not deleted")
+
+// ListUsers returns a list of all entity.User objects from the database. Soft deleted users are
+// only included if includeDeleted is set.
 //
-// SELECT * FROM "users"
-func (db Database) ListUsers() ([]entity.User, error) {
+// SELECT * FROM "users" WHERE "users"."deleted_at" IS NULL
+func (db Database) ListUsers(ctx context.Context, includeDeleted bool) ([]entity.User, error) {
 	var users []entity.User
-	err := db.Session.Find(&users).Error
+	err := scoped(db.Session.WithContext(ctx), includeDeleted).Find(&users).Error
 	if err != nil {
 		return users, fmt.Errorf("in session.ListUsers: %w", err)
 	}
@@ -23,33 +31,54 @@ func (db Database) ListUsers() ([]entity.User, error) {
 	return users, nil
 }
 
-// FetchUser returns am entity.User objects from the database by ID.
+// FetchUser returns am entity.User objects from the database by ID. gorm.ErrRecordNotFound is
+// returned if no user exists with the given ID, or if the user is soft deleted and includeDeleted
+// is not set.
 //
-// SELECT * FROM "users" WHERE ID = $1 ORDER BY "users"."id" LIMIT 1
-func (db Database) FetchUser(ID int) (entity.User, error) {
+// SELECT * FROM "users" WHERE ID = $1 AND "users"."deleted_at" IS NULL ORDER BY "users"."id" LIMIT 1
+func (db Database) FetchUser(ctx context.Context, ID int, includeDeleted bool) (entity.User, error) {
 	var user entity.User
-	err := db.Session.Where("ID = ?", ID).First(&user).Error
+	err := scoped(db.Session.WithContext(ctx), includeDeleted).Where("ID = ?", ID).First(&user).Error
 	if err != nil {
-		if errors.Is(err, gorm.ErrRecordNotFound) {
-			slog.Info("User not found", "ID", ID)
-			return user, nil
-		}
-		return user, fmt.Errorf("in session.FetchUser: %w", err)
+		return entity.User{}, fmt.Errorf("in session.FetchUser: %w", err)
+	}
+
+	return user, nil
+}
+
+// FetchUserByUserID returns the entity.User object from the database with the user_id userID.
+// gorm.ErrRecordNotFound is returned if no user has the user_id, or if the user is soft deleted.
+//
+// SELECT * FROM "users" WHERE user_id = $1 AND "users"."deleted_at" IS NULL ORDER BY "users"."id" LIMIT 1
+func (db Database) FetchUserByUserID(ctx context.Context, userID uint) (entity.User, error) {
+	var user entity.User
+	err := db.Session.WithContext(ctx).Where("user_id = ?", userID).First(&user).Error
+	if err != nil {
+		return entity.User{}, fmt.Errorf("in session.FetchUserByUserID: %w", err)
 	}
 
 	return user, nil
 }
 
-// UpdateUser updates am entity.User objects from the database by ID.
+// UpdateUser updates am entity.User objects from the database by ID. gorm.ErrRecordNotFound is
+// returned if no user exists with the given ID or the user is soft deleted, and
+// gorm.ErrDuplicatedKey if the user_id is already in use.
 //
-// UPDATE "users" SET "first_name"=$1,"last_name"=$2,"role"=$3,"user_id"=$4 WHERE "id" = $5
-func (db Database) UpdateUser(ID int, user entity.User) (entity.User, error) {
-	// set ID for user to ensure match
+// UPDATE "users" SET "first_name"=$1,"last_name"=$2,"role"=$3,"user_id"=$4 WHERE "users"."deleted_at" IS NULL AND "id" = $5
+func (db Database) UpdateUser(ctx context.Context, ID int, user entity.User) (entity.User, error) {
+	// set ID for user to ensure match, deleted_at is not updated
 	user.ID = uint(ID)
+	user.DeletedAt = gorm.DeletedAt{}
 
-	err := db.Session.Transaction(func(tx *gorm.DB) error {
-		if err := tx.Save(&user).Error; err != nil {
-			return fmt.Errorf("in Transaction: %w", err)
+	err := db.Session.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		// Select all columns so that zero values are written, and avoid Save as it inserts the row
+		// when no row with the ID exists.
+		result := tx.Model(&user).Select("*").Omit("id", "deleted_at").Updates(&user)
+		if result.Error != nil {
+			return fmt.Errorf("in Transaction: %w", translateError(result.Error))
+		}
+		if result.RowsAffected == 0 {
+			return fmt.Errorf("in Transaction: %w", gorm.ErrRecordNotFound)
 		}
 		return nil
 	})
@@ -60,16 +89,18 @@ func (db Database) UpdateUser(ID int, user entity.User) (entity.User, error) {
 	return user, nil
 }
 
-// CreateUser creates am entity.User objects in the database.
+// CreateUser creates am entity.User objects in the database. gorm.ErrDuplicatedKey is returned if
+// the user_id is already in use.
 //
 // INSERT INTO "users" ("first_name","last_name","role","user_id") VALUES ($1,$2,$3,$4) RETURNING "id"
-func (db Database) CreateUser(user entity.User) (entity.User, error) {
-	// set ID to 0 so that it is auto generated
+func (db Database) CreateUser(ctx context.Context, user entity.User) (entity.User, error) {
+	// set ID to 0 so that it is auto generated and make sure the user is not created deleted
 	user.ID = uint(0)
+	user.DeletedAt = gorm.DeletedAt{}
 
-	err := db.Session.Transaction(func(tx *gorm.DB) error {
+	err := db.Session.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
 		if err := tx.Create(&user).Error; err != nil {
-			return fmt.Errorf("in Transaction: %w", err)
+			return fmt.Errorf("in Transaction: %w", translateError(err))
 		}
 		return nil
 	})
@@ -80,13 +111,83 @@ func (db Database) CreateUser(user entity.User) (entity.User, error) {
 	return user, nil
 }
 
-// DeleteUser deletes am entity.User objects from the database by ID.
+// DeleteUser soft deletes am entity.User objects from the database by ID, so that it can be restored
+// with RestoreUser. gorm.ErrRecordNotFound is returned if no user exists with the given ID or the
+// user is already deleted.
+//
+// UPDATE "users" SET "deleted_at"=$1 WHERE "users"."id" = $2 AND "users"."deleted_at" IS NULL
+func (db Database) DeleteUser(ctx context.Context, ID int) error {
+	result := db.Session.WithContext(ctx).Delete(&entity.User{}, ID)
+	if result.Error != nil {
+		return fmt.Errorf("in session.DeleteUser: %w", result.Error)
+	}
+	if result.RowsAffected == 0 {
+		return fmt.Errorf("in session.DeleteUser: %w", gorm.ErrRecordNotFound)
+	}
+
+	return nil
+}
+
+// RestoreUser restores a soft deleted entity.User object by ID. gorm.ErrRecordNotFound is returned
+// if no user exists with the given ID, ErrRecordNotDeleted if the user is not deleted and
+// gorm.ErrDuplicatedKey if the user_id has been taken by another user since the user was deleted.
+//
+// UPDATE "users" SET "deleted_at"=$1 WHERE "users"."id" = $2 AND "users"."deleted_at" IS NOT NULL
+func (db Database) RestoreUser(ctx context.Context, ID int) (entity.User, error) {
+	var user entity.User
+	err := db.Session.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Unscoped().Clauses(clause.Locking{Strength: "UPDATE"}).First(&user, ID).Error; err != nil {
+			return fmt.Errorf("in Transaction: %w", err)
+		}
+		if !user.DeletedAt.Valid {
+			return fmt.Errorf("in Transaction: %w", ErrRecordNotDeleted)
+		}
+
+		user.DeletedAt = gorm.DeletedAt{}
+		err := tx.Unscoped().Model(&user).Where("deleted_at IS NOT NULL").Update("deleted_at", nil).Error
+		if err != nil {
+			return fmt.Errorf("in Transaction: %w", translateError(err))
+		}
+		return nil
+	})
+	if err != nil {
+		return entity.User{}, fmt.Errorf("in session.RestoreUser: %w", err)
+	}
+
+	return user, nil
+}
+
+// PurgeUser permanently deletes am entity.User objects from the database by ID, whether it is soft
+// deleted or not. gorm.ErrRecordNotFound is returned if no user exists with the given ID.
 //
 // DELETE FROM "users" WHERE "users"."id" = $1
-func (db Database) DeleteUser(ID int) error {
-	if err := db.Session.Delete(&entity.User{}, ID).Error; err != nil {
-		return fmt.Errorf("in session.DeleteUser: %w", err)
+func (db Database) PurgeUser(ctx context.Context, ID int) error {
+	result := db.Session.WithContext(ctx).Unscoped().Delete(&entity.User{}, ID)
+	if result.Error != nil {
+		return fmt.Errorf("in session.PurgeUser: %w", result.Error)
+	}
+	if result.RowsAffected == 0 {
+		return fmt.Errorf("in session.PurgeUser: %w", gorm.ErrRecordNotFound)
 	}
 
 	return nil
 }
+
+// scoped returns session without the soft delete scope if includeDeleted is set.
+func scoped(session *gorm.DB, includeDeleted bool) *gorm.DB {
+	if includeDeleted {
+		return session.Unscoped()
+	}
+	return session
+}
+
+// translateError wraps Postgres unique violations with gorm.ErrDuplicatedKey so that callers do
+// not need to depend on the driver. Other errors are returned unchanged.
+func translateError(err error) error {
+	var pgErr interface{ SQLState() string }
+	if errors.As(err, &pgErr) && pgErr.SQLState() == pgUniqueViolation {
+		return fmt.Errorf("%w: %w", gorm.ErrDuplicatedKey, err)
+	}
+
+	return err
+}
@@ -0,0 +1,124 @@
+package health
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+)
+
+// stubSchema is a schemaVersioner returning fixed versions.
+type stubSchema struct {
+	applied int
+	latest  int
+	err     error
+}
+
+func (s stubSchema) Version(context.Context) (int, int, error) {
+	return s.applied, s.latest, s.err
+}
+
+func TestCheckerReady(t *testing.T) {
+	tests := map[string]struct {
+		pingErr          error
+		schema           stubSchema
+		maxOpen          int
+		drain            bool
+		expectedStatus   Status
+		expectedDraining bool
+		expectedFailures map[string]string
+	}{
+		"ready": {
+			schema:           stubSchema{applied: 9, latest: 9},
+			expectedStatus:   StatusOK,
+			expectedFailures: map[string]string{},
+		},
+		"database down": {
+			pingErr:          errors.New("connection refused"),
+			schema:           stubSchema{applied: 9, latest: 9},
+			expectedStatus:   StatusFail,
+			expectedFailures: map[string]string{"database": "connection refused"},
+		},
+		"schema behind": {
+			schema:           stubSchema{applied: 8, latest: 9},
+			expectedStatus:   StatusFail,
+			expectedFailures: map[string]string{"migrations": "schema is at version 8, expected 9"},
+		},
+		"schema version unavailable": {
+			schema:           stubSchema{err: errors.New("relation does not exist")},
+			expectedStatus:   StatusFail,
+			expectedFailures: map[string]string{"migrations": "relation does not exist"},
+		},
+		"pool with limit": {
+			schema:           stubSchema{applied: 9, latest: 9},
+			maxOpen:          10,
+			expectedStatus:   StatusOK,
+			expectedFailures: map[string]string{},
+		},
+		"draining": {
+			drain:            true,
+			expectedStatus:   StatusFail,
+			expectedDraining: true,
+		},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			db, mock, err := sqlmock.New(sqlmock.MonitorPingsOption(true))
+			assert.NoError(t, err)
+			defer db.Close()
+			db.SetMaxOpenConns(tc.maxOpen)
+			if !tc.drain {
+				mock.ExpectPing().WillReturnError(tc.pingErr)
+			}
+
+			checker := NewChecker(db, tc.schema)
+			if tc.drain {
+				checker.Drain()
+			}
+
+			report := checker.Ready(context.Background())
+
+			assert.Equal(t, tc.expectedStatus, report.Status, "Wrong status")
+			assert.Equal(t, tc.expectedDraining, report.Draining, "Wrong draining")
+			if !tc.drain {
+				failures := map[string]string{}
+				for name, component := range report.Components {
+					if component.Status != StatusOK {
+						failures[name] = component.Error
+					}
+				}
+				assert.Equal(t, tc.expectedFailures, failures, "Wrong failed components")
+				assert.Contains(t, report.Components["pool"].Details, "in_use")
+				if tc.maxOpen > 0 {
+					assert.Contains(t, report.Components["pool"].Details, "saturation")
+				}
+			} else {
+				assert.Empty(t, report.Components, "Components checked while draining")
+			}
+			assert.NoError(t, mock.ExpectationsWereMet())
+		})
+	}
+}
+
+func TestCheckerPoolSaturation(t *testing.T) {
+	db, _, err := sqlmock.New()
+	assert.NoError(t, err)
+	defer db.Close()
+	db.SetMaxOpenConns(1)
+
+	// hold the only connection, so the pool is saturated
+	conn, err := db.Conn(context.Background())
+	assert.NoError(t, err)
+	defer conn.Close()
+
+	checker := NewChecker(db, stubSchema{applied: 9, latest: 9})
+
+	pool := checker.checkPool()
+
+	assert.Equal(t, StatusFail, pool.Status, "Wrong pool status")
+	assert.Equal(t, "1 of 1 connections in use", pool.Error, "Wrong pool error")
+	assert.Equal(t, float64(1), pool.Details["saturation"], "Wrong saturation")
+}
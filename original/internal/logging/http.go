@@ -0,0 +1,46 @@
+package logging
+
+import (
+	"log/slog"
+	"net/http"
+
+	"github.com/go-chi/chi/v5"
+)
+
+// Middleware returns a middleware that identifies every request and puts a logger into its context
+// that logs the request ID, the trace ID and the route of the request with every line. The ID is
+// taken from the X-Request-ID header of the request, or else generated, and is echoed in the
+// X-Request-ID header of the response. It is also where middleware.GetReqID finds it, so the access
+// log of middleware.Logger carries it too.
+//
+// The route is the pattern of router that the request matches, such as `/api/user/{ID}`, and is
+// left out for requests that match none.
+func Middleware(logger *slog.Logger, router chi.Routes) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			id := r.Header.Get(RequestIDHeader)
+			if !validRequestID(id) {
+				id = newRequestID()
+			}
+			w.Header().Set(RequestIDHeader, id)
+
+			ctx := withRequest(r.Context(), logger, id, matchRoute(router, r))
+			next.ServeHTTP(w, r.WithContext(ctx))
+		})
+	}
+}
+
+// matchRoute returns the pattern of router that r matches, or an empty string if it matches none.
+// Unlike tracing.ChiRoute, it finds the pattern before r is routed.
+func matchRoute(router chi.Routes, r *http.Request) string {
+	path := r.URL.RawPath
+	if path == "" {
+		path = r.URL.Path
+	}
+
+	rctx := chi.NewRouteContext()
+	if !router.Match(rctx, r.Method, path) {
+		return ""
+	}
+	return rctx.RoutePattern()
+}
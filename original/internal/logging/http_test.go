@@ -0,0 +1,106 @@
+package logging
+
+import (
+	"bytes"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/chi/v5/middleware"
+	"github.com/stretchr/testify/assert"
+	"go.opentelemetry.io/otel/trace"
+)
+
+func TestMiddleware(t *testing.T) {
+	traceID, _ := trace.TraceIDFromHex("4bf92f3577b34da6a3ce929d0e0e4736")
+	spanID, _ := trace.SpanIDFromHex("00f067aa0ba902b7")
+	spanContext := trace.NewSpanContext(trace.SpanContextConfig{TraceID: traceID, SpanID: spanID})
+
+	tests := map[string]struct {
+		path          string
+		header        string
+		traced        bool
+		expectedID    string
+		expectedRoute any
+		expectedTrace any
+	}{
+		"request id header": {
+			path:          "/api/user/1",
+			header:        "abc-123",
+			expectedID:    "abc-123",
+			expectedRoute: "/api/user/{ID}",
+		},
+		"invalid request id header": {
+			path:          "/api/user/1",
+			header:        "abc 123\n",
+			expectedRoute: "/api/user/{ID}",
+		},
+		"generated request id": {
+			path:          "/api/user/1",
+			expectedRoute: "/api/user/{ID}",
+		},
+		"trace id": {
+			path:          "/api/user/1",
+			header:        "abc-123",
+			traced:        true,
+			expectedID:    "abc-123",
+			expectedRoute: "/api/user/{ID}",
+			expectedTrace: "4bf92f3577b34da6a3ce929d0e0e4736",
+		},
+		"no route": {
+			path:       "/api/unknown",
+			header:     "abc-123",
+			expectedID: "abc-123",
+		},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			var out bytes.Buffer
+			logger := slog.New(slog.NewJSONHandler(&out, nil))
+
+			var contextID string
+			r := chi.NewRouter()
+			r.Use(Middleware(logger, r))
+			handler := func(w http.ResponseWriter, r *http.Request) {
+				contextID = middleware.GetReqID(r.Context())
+				requestLogger, ok := FromContext(r.Context())
+				if assert.True(t, ok, "request should carry a logger") {
+					requestLogger.Info("handled")
+				}
+			}
+			r.Get("/api/user/{ID}", handler)
+			r.NotFound(handler)
+
+			req := httptest.NewRequest(http.MethodGet, tc.path, nil)
+			if tc.header != "" {
+				req.Header.Set(RequestIDHeader, tc.header)
+			}
+			if tc.traced {
+				req = req.WithContext(trace.ContextWithSpanContext(req.Context(), spanContext))
+			}
+
+			w := httptest.NewRecorder()
+			r.ServeHTTP(w, req)
+
+			var line map[string]any
+			if !assert.NoError(t, json.Unmarshal(out.Bytes(), &line)) {
+				return
+			}
+
+			id := w.Header().Get(RequestIDHeader)
+			if tc.expectedID != "" {
+				assert.Equal(t, tc.expectedID, id)
+			} else {
+				assert.Len(t, id, 32, "request id should be generated")
+			}
+			assert.Equal(t, id, contextID)
+			assert.Equal(t, id, line["request_id"])
+			assert.Equal(t, tc.expectedRoute, line["route"])
+			assert.Equal(t, tc.expectedTrace, line["trace_id"])
+		})
+	}
+}
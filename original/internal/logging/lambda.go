@@ -0,0 +1,45 @@
+package logging
+
+import (
+	"context"
+	"log/slog"
+	"strings"
+
+	"github.com/aws/aws-lambda-go/events"
+)
+
+// WrapAPIGateway returns a handler that identifies every invocation of handler and puts a logger
+// into its context that logs the request ID, the trace ID and the resource of the event, such as
+// `/api/user/{ID}`, with every line. The ID is taken from the X-Request-ID header of the event, or
+// else the request ID of API Gateway, and is echoed in the X-Request-ID header of the response. It
+// has to be wrapped by tracing.WrapAPIGateway for the trace ID to be known.
+func WrapAPIGateway(
+	logger *slog.Logger,
+	handler func(ctx context.Context, req events.APIGatewayProxyRequest) (events.APIGatewayProxyResponse, error),
+) func(ctx context.Context, req events.APIGatewayProxyRequest) (events.APIGatewayProxyResponse, error) {
+	return func(ctx context.Context, req events.APIGatewayProxyRequest) (events.APIGatewayProxyResponse, error) {
+		id := eventRequestID(req)
+
+		resp, err := handler(withRequest(ctx, logger, id, req.Resource), req)
+		if resp.Headers == nil {
+			resp.Headers = map[string]string{}
+		}
+		resp.Headers[RequestIDHeader] = id
+
+		return resp, err
+	}
+}
+
+// eventRequestID returns the ID of req: its X-Request-ID header if that is a valid ID, or else the
+// request ID of API Gateway, or a new random ID if the event has none.
+func eventRequestID(req events.APIGatewayProxyRequest) string {
+	for key, value := range req.Headers {
+		if strings.EqualFold(key, RequestIDHeader) && validRequestID(value) {
+			return value
+		}
+	}
+	if req.RequestContext.RequestID != "" {
+		return req.RequestContext.RequestID
+	}
+	return newRequestID()
+}
@@ -0,0 +1,73 @@
+package logging
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"testing"
+
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWrapAPIGateway(t *testing.T) {
+	tests := map[string]struct {
+		headers    map[string]string
+		gatewayID  string
+		expectedID string
+	}{
+		"request id header": {
+			headers:    map[string]string{"x-request-id": "abc-123"},
+			gatewayID:  "gateway-456",
+			expectedID: "abc-123",
+		},
+		"invalid request id header": {
+			headers:    map[string]string{"X-Request-ID": "abc 123"},
+			gatewayID:  "gateway-456",
+			expectedID: "gateway-456",
+		},
+		"api gateway request id": {
+			gatewayID:  "gateway-456",
+			expectedID: "gateway-456",
+		},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			var out bytes.Buffer
+			logger := slog.New(slog.NewJSONHandler(&out, nil))
+
+			var contextID string
+			handler := WrapAPIGateway(logger, func(ctx context.Context, _ events.APIGatewayProxyRequest) (events.APIGatewayProxyResponse, error) {
+				contextID = RequestID(ctx)
+				ctx = With(ctx, "user_id", 1)
+				if requestLogger, ok := FromContext(ctx); assert.True(t, ok, "invocation should carry a logger") {
+					requestLogger.Info("handled")
+				}
+				return events.APIGatewayProxyResponse{StatusCode: http.StatusOK}, nil
+			})
+
+			resp, err := handler(context.Background(), events.APIGatewayProxyRequest{
+				HTTPMethod:     http.MethodGet,
+				Resource:       "/api/user/{ID}",
+				Path:           "/api/user/1",
+				Headers:        tc.headers,
+				RequestContext: events.APIGatewayProxyRequestContext{RequestID: tc.gatewayID},
+			})
+			assert.NoError(t, err)
+
+			var line map[string]any
+			if !assert.NoError(t, json.Unmarshal(out.Bytes(), &line)) {
+				return
+			}
+
+			assert.Equal(t, tc.expectedID, resp.Headers[RequestIDHeader])
+			assert.Equal(t, tc.expectedID, contextID)
+			assert.Equal(t, tc.expectedID, line["request_id"])
+			assert.Equal(t, "/api/user/{ID}", line["route"])
+			assert.Equal(t, 1.0, line["user_id"])
+		})
+	}
+}
@@ -0,0 +1,86 @@
+package logging
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"log/slog"
+
+	"github.com/go-chi/chi/v5/middleware"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// RequestIDHeader is the header the ID of a request is read from, and echoed in on its response.
+const RequestIDHeader = "X-Request-ID"
+
+// maxRequestIDLength is the maximum length of a request ID taken from a request header, longer IDs
+// are replaced.
+const maxRequestIDLength = 128
+
+type loggerKey struct{}
+
+// NewContext returns a copy of ctx carrying logger as the logger of its request.
+func NewContext(ctx context.Context, logger *slog.Logger) context.Context {
+	return context.WithValue(ctx, loggerKey{}, logger)
+}
+
+// FromContext returns the logger of the request of ctx, and whether ctx carries one.
+func FromContext(ctx context.Context) (*slog.Logger, bool) {
+	logger, ok := ctx.Value(loggerKey{}).(*slog.Logger)
+	return logger, ok
+}
+
+// With returns a copy of ctx whose request logger logs args with every line, such as the user of
+// the request once it is known. ctx is returned as is if it carries no logger.
+func With(ctx context.Context, args ...any) context.Context {
+	logger, ok := FromContext(ctx)
+	if !ok {
+		return ctx
+	}
+	return NewContext(ctx, logger.With(args...))
+}
+
+// RequestID returns the ID that Middleware or WrapAPIGateway gave the request of ctx, or an empty
+// string if it has none.
+func RequestID(ctx context.Context) string {
+	return middleware.GetReqID(ctx)
+}
+
+// withRequest returns a copy of ctx carrying id as the ID of its request, where middleware.GetReqID
+// finds it, and a logger that logs id, the trace ID of ctx and route with every line. route is left
+// out if it is empty.
+func withRequest(ctx context.Context, logger *slog.Logger, id string, route string) context.Context {
+	ctx = context.WithValue(ctx, middleware.RequestIDKey, id)
+
+	args := []any{"request_id", id}
+	if spanContext := trace.SpanContextFromContext(ctx); spanContext.HasTraceID() {
+		args = append(args, "trace_id", spanContext.TraceID().String())
+	}
+	if route != "" {
+		args = append(args, "route", route)
+	}
+
+	return NewContext(ctx, logger.With(args...))
+}
+
+// newRequestID returns a new random request ID.
+func newRequestID() string {
+	// crypto/rand.Read never fails
+	b := make([]byte, 16)
+	_, _ = rand.Read(b)
+	return hex.EncodeToString(b)
+}
+
+// validRequestID reports whether id can be used as a request ID: it is not empty, not too long and
+// only has printable ASCII characters other than spaces, so it can not forge log lines.
+func validRequestID(id string) bool {
+	if id == "" || len(id) > maxRequestIDLength {
+		return false
+	}
+	for i := 0; i < len(id); i++ {
+		if id[i] <= ' ' || id[i] > '~' {
+			return false
+		}
+	}
+	return true
+}
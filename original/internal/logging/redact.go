@@ -0,0 +1,288 @@
+package logging
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log/slog"
+	"reflect"
+	"strings"
+	"sync"
+)
+
+// The modes that a RedactHandler can redact values in.
+const (
+	// RedactMask replaces values with RedactedValue.
+	RedactMask = "mask"
+	// RedactHash replaces values with an HMAC of them keyed by a secret, so lines about the same
+	// value can still be told apart and correlated without revealing it. As the secret is needed to
+	// compute the HMAC, values can not be found by hashing guesses.
+	RedactHash = "hash"
+)
+
+// RedactedValue is what values are replaced with in the RedactMask mode.
+const RedactedValue = "[REDACTED]"
+
+var (
+	// ErrUnknownRedactMode is returned when a redact mode is not one of the known modes.
+	ErrUnknownRedactMode = errors.New("unknown redact mode")
+
+	// ErrMissingRedactSecret is returned when the RedactHash mode is used without a secret.
+	ErrMissingRedactSecret = errors.New("missing redact secret")
+)
+
+// RedactHandler is a slog.Handler that redacts personal data before passing records on to the
+// handler it wraps. It redacts the values of attributes whose key is one of its keys, at any depth
+// of groups, and of the entries of maps with such keys. Structs, and pointers, slices and maps of
+// them, that have fields tagged `pii:"true"` or fields named like one of its keys are logged as
+// maps of their fields by their JSON names, with those fields redacted. Other values are passed on
+// as they are.
+//
+// Messages are not redacted, so personal data must only be logged as attributes.
+type RedactHandler struct {
+	next   slog.Handler
+	mode   string
+	secret []byte
+	keys   map[string]bool
+	types  *sync.Map // reflect.Type to bool, whether values of the type hold personal data
+}
+
+// NewRedactHandler returns a RedactHandler that passes records on to next, with the values of keys
+// and of fields tagged `pii:"true"` redacted in mode, RedactMask or RedactHash. Keys are matched
+// case-insensitively. The RedactHash mode requires secret, which the RedactMask mode ignores.
+func NewRedactHandler(next slog.Handler, mode string, secret []byte, keys []string) (*RedactHandler, error) {
+	if mode != RedactMask && mode != RedactHash {
+		return nil, fmt.Errorf("in NewRedactHandler: %w: %q", ErrUnknownRedactMode, mode)
+	}
+	if mode == RedactHash && len(secret) == 0 {
+		return nil, fmt.Errorf("in NewRedactHandler: %w", ErrMissingRedactSecret)
+	}
+
+	h := &RedactHandler{
+		next:   next,
+		mode:   mode,
+		secret: secret,
+		keys:   make(map[string]bool, len(keys)),
+		types:  &sync.Map{},
+	}
+	for _, key := range keys {
+		if key = strings.TrimSpace(key); key != "" {
+			h.keys[strings.ToLower(key)] = true
+		}
+	}
+	return h, nil
+}
+
+// Enabled reports whether the wrapped handler handles records at level.
+func (h *RedactHandler) Enabled(ctx context.Context, level slog.Level) bool {
+	return h.next.Enabled(ctx, level)
+}
+
+// Handle passes a copy of r with its attributes redacted on to the wrapped handler.
+func (h *RedactHandler) Handle(ctx context.Context, r slog.Record) error {
+	redacted := slog.NewRecord(r.Time, r.Level, r.Message, r.PC)
+	r.Attrs(func(attr slog.Attr) bool {
+		redacted.AddAttrs(h.redactAttr(attr))
+		return true
+	})
+	return h.next.Handle(ctx, redacted)
+}
+
+// WithAttrs returns a RedactHandler whose wrapped handler logs attrs, redacted, with every record.
+func (h *RedactHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	redacted := make([]slog.Attr, len(attrs))
+	for i, attr := range attrs {
+		redacted[i] = h.redactAttr(attr)
+	}
+
+	clone := *h
+	clone.next = h.next.WithAttrs(redacted)
+	return &clone
+}
+
+// WithGroup returns a RedactHandler whose wrapped handler logs the attributes of records in the
+// group name.
+func (h *RedactHandler) WithGroup(name string) slog.Handler {
+	clone := *h
+	clone.next = h.next.WithGroup(name)
+	return &clone
+}
+
+// redactAttr returns attr with its value redacted if its key is one of the keys of h, or with the
+// personal data in its value redacted otherwise.
+func (h *RedactHandler) redactAttr(attr slog.Attr) slog.Attr {
+	attr.Value = attr.Value.Resolve()
+	if h.keys[strings.ToLower(attr.Key)] {
+		attr.Value = slog.StringValue(h.redact(attr.Value.String()))
+		return attr
+	}
+
+	switch attr.Value.Kind() {
+	case slog.KindGroup:
+		group := attr.Value.Group()
+		redacted := make([]slog.Attr, len(group))
+		for i, groupAttr := range group {
+			redacted[i] = h.redactAttr(groupAttr)
+		}
+		attr.Value = slog.GroupValue(redacted...)
+	case slog.KindAny:
+		v := reflect.ValueOf(attr.Value.Any())
+		if v.IsValid() && h.holdsPII(v.Type()) {
+			attr.Value = slog.AnyValue(h.redactValue(v))
+		}
+	}
+	return attr
+}
+
+// redact returns value masked or hashed, depending on the mode of h.
+func (h *RedactHandler) redact(value string) string {
+	if h.mode == RedactHash {
+		mac := hmac.New(sha256.New, h.secret)
+		mac.Write([]byte(value))
+		return "hmac-sha256:" + hex.EncodeToString(mac.Sum(nil)[:8])
+	}
+	return RedactedValue
+}
+
+// redactValue returns v as it is if it holds no personal data, or else as maps and slices of its
+// fields and elements with the personal data redacted.
+func (h *RedactHandler) redactValue(v reflect.Value) any {
+	if v.Kind() == reflect.Interface {
+		if v.IsNil() {
+			return nil
+		}
+		v = v.Elem()
+	}
+	if !h.holdsPII(v.Type()) {
+		return v.Interface()
+	}
+
+	switch v.Kind() {
+	case reflect.Pointer:
+		if v.IsNil() {
+			return nil
+		}
+		return h.redactValue(v.Elem())
+
+	case reflect.Struct:
+		fields := make(map[string]any, v.NumField())
+		for i := 0; i < v.NumField(); i++ {
+			field := v.Type().Field(i)
+			name, ok := fieldName(field)
+			if !ok {
+				continue
+			}
+			if h.isPIIField(field, name) {
+				fields[name] = h.redactField(v.Field(i))
+				continue
+			}
+			fields[name] = h.redactValue(v.Field(i))
+		}
+		return fields
+
+	case reflect.Map:
+		if v.IsNil() {
+			return nil
+		}
+		entries := make(map[string]any, v.Len())
+		iter := v.MapRange()
+		for iter.Next() {
+			key := fmt.Sprint(iter.Key().Interface())
+			if h.keys[strings.ToLower(key)] {
+				entries[key] = h.redact(fmt.Sprint(iter.Value().Interface()))
+				continue
+			}
+			entries[key] = h.redactValue(iter.Value())
+		}
+		return entries
+
+	case reflect.Slice, reflect.Array:
+		if v.Kind() == reflect.Slice && v.IsNil() {
+			return nil
+		}
+		elements := make([]any, v.Len())
+		for i := range elements {
+			elements[i] = h.redactValue(v.Index(i))
+		}
+		return elements
+
+	default:
+		return v.Interface()
+	}
+}
+
+// redactField returns the redacted value of a personal data field, which is nil if the field is a
+// nil pointer.
+func (h *RedactHandler) redactField(v reflect.Value) any {
+	for v.Kind() == reflect.Pointer || v.Kind() == reflect.Interface {
+		if v.IsNil() {
+			return nil
+		}
+		v = v.Elem()
+	}
+	return h.redact(fmt.Sprint(v.Interface()))
+}
+
+// isPIIField reports whether field, logged as name, holds personal data.
+func (h *RedactHandler) isPIIField(field reflect.StructField, name string) bool {
+	return field.Tag.Get("pii") == "true" || h.keys[strings.ToLower(name)] || h.keys[strings.ToLower(field.Name)]
+}
+
+// holdsPII reports whether values of t can hold personal data: structs with personal data fields,
+// maps with string keys, which may be one of the keys of h, interfaces, and pointers, slices and
+// maps of them. The answer is kept for every type, as checking it walks the fields of t.
+func (h *RedactHandler) holdsPII(t reflect.Type) bool {
+	if cached, ok := h.types.Load(t); ok {
+		return cached.(bool)
+	}
+	holds := h.typeHoldsPII(t, map[reflect.Type]bool{})
+	h.types.Store(t, holds)
+	return holds
+}
+
+// typeHoldsPII is holdsPII without the kept answers. seen holds the struct types being checked, to
+// stop at recursive types.
+func (h *RedactHandler) typeHoldsPII(t reflect.Type, seen map[reflect.Type]bool) bool {
+	switch t.Kind() {
+	case reflect.Pointer, reflect.Slice, reflect.Array:
+		return h.typeHoldsPII(t.Elem(), seen)
+	case reflect.Map:
+		return len(h.keys) > 0 && t.Key().Kind() == reflect.String || h.typeHoldsPII(t.Elem(), seen)
+	case reflect.Interface:
+		// the dynamic type is only known once there is a value, see redactValue
+		return true
+	case reflect.Struct:
+		if seen[t] {
+			return false
+		}
+		seen[t] = true
+
+		for i := 0; i < t.NumField(); i++ {
+			field := t.Field(i)
+			name, ok := fieldName(field)
+			if ok && (h.isPIIField(field, name) || h.typeHoldsPII(field.Type, seen)) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// fieldName returns the name field is logged as, its JSON name, and false if it is not logged
+// because it is unexported or left out of JSON.
+func fieldName(field reflect.StructField) (string, bool) {
+	if !field.IsExported() {
+		return "", false
+	}
+	name, _, _ := strings.Cut(field.Tag.Get("json"), ",")
+	switch name {
+	case "-":
+		return "", false
+	case "":
+		return field.Name, true
+	}
+	return name, true
+}
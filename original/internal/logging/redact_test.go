@@ -0,0 +1,108 @@
+package logging
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"log/slog"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"user-microservice/internal/database/entity"
+)
+
+func TestRedactHandler(t *testing.T) {
+	user := entity.User{ID: 1, FirstName: "Jane", LastName: "Doe", Role: "Admin", UserID: 42}
+	problems := map[string]string{"first_name": "Jane", "last_name": "Doe", "role": "Admin"}
+
+	levels := []slog.Level{slog.LevelDebug, slog.LevelInfo, slog.LevelWarn, slog.LevelError}
+
+	tests := map[string]struct {
+		mode       string
+		newHandler func(out *bytes.Buffer) slog.Handler
+	}{
+		"mask json": {
+			mode: RedactMask,
+			newHandler: func(out *bytes.Buffer) slog.Handler {
+				return slog.NewJSONHandler(out, &slog.HandlerOptions{Level: slog.LevelDebug})
+			},
+		},
+		"hash json": {
+			mode: RedactHash,
+			newHandler: func(out *bytes.Buffer) slog.Handler {
+				return slog.NewJSONHandler(out, &slog.HandlerOptions{Level: slog.LevelDebug})
+			},
+		},
+		"mask text": {
+			mode: RedactMask,
+			newHandler: func(out *bytes.Buffer) slog.Handler {
+				return slog.NewTextHandler(out, &slog.HandlerOptions{Level: slog.LevelDebug})
+			},
+		},
+		"hash text": {
+			mode: RedactHash,
+			newHandler: func(out *bytes.Buffer) slog.Handler {
+				return slog.NewTextHandler(out, &slog.HandlerOptions{Level: slog.LevelDebug})
+			},
+		},
+	}
+
+	for name, tc := range tests {
+		for _, level := range levels {
+			t.Run(name+" "+level.String(), func(t *testing.T) {
+				var out bytes.Buffer
+				handler, err := NewRedactHandler(tc.newHandler(&out), tc.mode, []byte("secret"), []string{"first_name", " Last_Name "})
+				if !assert.NoError(t, err) {
+					return
+				}
+				logger := slog.New(handler)
+				ctx := context.Background()
+
+				logger.Log(ctx, level, "user", "user", user)
+				logger.Log(ctx, level, "user pointer", "user", &user)
+				logger.Log(ctx, level, "users", "users", []entity.User{user})
+				logger.Log(ctx, level, "problems", "problems", problems)
+				logger.Log(ctx, level, "key", "first_name", "Jane", "last_name", "Doe")
+				logger.Log(ctx, level, "group", slog.Group("request", "last_name", "Doe", "user", user))
+				logger.Log(ctx, level, "error", "error", errors.New("failed"))
+				logger.With("user", user).Log(ctx, level, "with")
+				logger.WithGroup("request").With("last_name", "Doe").Log(ctx, level, "with group", "user", user)
+
+				output := out.String()
+				assert.Equal(t, 9, strings.Count(output, "\n"), "every line should be logged")
+				assert.NotContains(t, output, "Jane")
+				assert.NotContains(t, output, "Doe")
+				assert.Contains(t, output, "Admin", "other values should be logged as they are")
+				assert.Contains(t, output, "failed", "other values should be logged as they are")
+				if tc.mode == RedactMask {
+					assert.Contains(t, output, RedactedValue)
+				} else {
+					assert.Contains(t, output, "hmac-sha256:")
+				}
+			})
+		}
+	}
+}
+
+func TestNewRedactHandler(t *testing.T) {
+	_, err := NewRedactHandler(slog.NewJSONHandler(&bytes.Buffer{}, nil), "encrypt", nil, nil)
+	assert.ErrorIs(t, err, ErrUnknownRedactMode)
+
+	_, err = NewRedactHandler(slog.NewJSONHandler(&bytes.Buffer{}, nil), RedactHash, nil, nil)
+	assert.ErrorIs(t, err, ErrMissingRedactSecret)
+
+	_, err = NewRedactHandler(slog.NewJSONHandler(&bytes.Buffer{}, nil), RedactMask, nil, nil)
+	assert.NoError(t, err, "Secret required for mask mode")
+}
+
+func TestRedactHandlerHashSecret(t *testing.T) {
+	first, err := NewRedactHandler(slog.NewJSONHandler(&bytes.Buffer{}, nil), RedactHash, []byte("first"), nil)
+	assert.NoError(t, err)
+	second, err := NewRedactHandler(slog.NewJSONHandler(&bytes.Buffer{}, nil), RedactHash, []byte("second"), nil)
+	assert.NoError(t, err)
+
+	assert.Equal(t, first.redact("Doe"), first.redact("Doe"), "Same value hashed differently")
+	assert.NotEqual(t, first.redact("Doe"), second.redact("Doe"), "Hash not keyed by secret")
+}
@@ -0,0 +1,119 @@
+package metrics
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestEMF(t *testing.T) {
+	db, _, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+
+	tests := map[string]struct {
+		record         func(e *EMF)
+		expectedValues []map[string]any
+		expectedNames  [][]string
+	}{
+		"request": {
+			record: func(e *EMF) {
+				e.RequestStarted()
+				e.RequestFinished("GET", "/api/user/{ID}", http.StatusOK, 1500*time.Microsecond)
+			},
+			expectedValues: []map[string]any{{
+				"Function":         "users-fetch",
+				"Route":            "/api/user/{ID}",
+				"Method":           "GET",
+				"StatusCode":       200.0,
+				"Requests":         1.0,
+				"Errors":           0.0,
+				"Latency":          1.5,
+				"InFlightRequests": 1.0,
+				"DBWaitCount":      0.0,
+			}},
+			expectedNames: [][]string{{
+				"Requests", "Errors", "Latency",
+				"InFlightRequests", "DBOpenConnections", "DBInUseConnections", "DBIdleConnections", "DBWaitCount", "DBWaitDuration",
+			}},
+		},
+		"server error": {
+			record: func(e *EMF) {
+				e.RequestStarted()
+				e.RequestFinished("POST", "/api/user", http.StatusInternalServerError, time.Millisecond)
+			},
+			expectedValues: []map[string]any{{
+				"Route":    "/api/user",
+				"Requests": 1.0,
+				"Errors":   1.0,
+			}},
+			expectedNames: [][]string{{
+				"Requests", "Errors", "Latency",
+				"InFlightRequests", "DBOpenConnections", "DBInUseConnections", "DBIdleConnections", "DBWaitCount", "DBWaitDuration",
+			}},
+		},
+		"user changes": {
+			record: func(e *EMF) {
+				e.UsersCreated(2)
+				e.UsersUpdated(0)
+				e.UsersDeleted(1)
+			},
+			expectedValues: []map[string]any{
+				{"Function": "users-fetch", "UsersCreated": 2.0},
+				{"Function": "users-fetch", "UsersDeleted": 1.0},
+			},
+			expectedNames: [][]string{{"UsersCreated"}, {"UsersDeleted"}},
+		},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			var out bytes.Buffer
+			e := NewEMF(&out, "UserMicroservice", "users-fetch", db)
+			e.now = func() time.Time { return time.UnixMilli(1700000000000) }
+
+			tc.record(e)
+
+			lines := strings.Split(strings.TrimSpace(out.String()), "\n")
+			if !assert.Len(t, lines, len(tc.expectedValues)) {
+				return
+			}
+			for i, line := range lines {
+				var document map[string]any
+				if !assert.NoError(t, json.Unmarshal([]byte(line), &document)) {
+					return
+				}
+				for key, value := range tc.expectedValues[i] {
+					assert.Equal(t, value, document[key], key)
+				}
+
+				var metadata struct {
+					Aws emfMetadata `json:"_aws"`
+				}
+				_ = json.Unmarshal([]byte(line), &metadata)
+				assert.Equal(t, int64(1700000000000), metadata.Aws.Timestamp)
+
+				var names []string
+				for _, directive := range metadata.Aws.CloudWatchMetrics {
+					assert.Equal(t, "UserMicroservice", directive.Namespace)
+					for _, dimension := range directive.Dimensions[0] {
+						assert.Contains(t, document, dimension, "every dimension should have a value")
+					}
+					for _, metric := range directive.Metrics {
+						names = append(names, metric.Name)
+						assert.Contains(t, document, metric.Name, "every metric should have a value")
+					}
+				}
+				assert.Equal(t, tc.expectedNames[i], names)
+			}
+		})
+	}
+}
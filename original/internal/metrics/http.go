@@ -0,0 +1,38 @@
+package metrics
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/go-chi/chi/v5/middleware"
+)
+
+// Middleware returns a middleware that records every request with recorder, by its method and the
+// route pattern returned by route once the request is served. Requests for which route returns an
+// empty string are recorded as UnmatchedRoute.
+//
+// Routers such as chi only know the route pattern once they have routed a request, so such a
+// middleware has to be used on the router rather than wrapped around it.
+func Middleware(recorder Recorder, route func(r *http.Request) string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			start := time.Now()
+			recorder.RequestStarted()
+
+			ww := middleware.NewWrapResponseWriter(w, r.ProtoMajor)
+			defer func() {
+				pattern := route(r)
+				if pattern == "" {
+					pattern = UnmatchedRoute
+				}
+				status := ww.Status()
+				if status == 0 {
+					status = http.StatusOK
+				}
+				recorder.RequestFinished(r.Method, pattern, status, time.Since(start))
+			}()
+
+			next.ServeHTTP(ww, r)
+		})
+	}
+}
@@ -0,0 +1,88 @@
+package metrics
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/stretchr/testify/assert"
+)
+
+// request is a request recorded by recorderMock.
+type request struct {
+	method string
+	route  string
+	status int
+}
+
+// recorderMock is a Recorder that keeps the requests it records.
+type recorderMock struct {
+	inFlight int
+	requests []request
+}
+
+func (m *recorderMock) RequestStarted() {
+	m.inFlight++
+}
+
+func (m *recorderMock) RequestFinished(method string, route string, status int, _ time.Duration) {
+	m.inFlight--
+	m.requests = append(m.requests, request{method: method, route: route, status: status})
+}
+
+func (m *recorderMock) UsersCreated(int) {}
+func (m *recorderMock) UsersUpdated(int) {}
+func (m *recorderMock) UsersDeleted(int) {}
+
+// chiRoute returns the route pattern of a request routed by chi.
+func chiRoute(r *http.Request) string {
+	return chi.RouteContext(r.Context()).RoutePattern()
+}
+
+func TestMiddleware(t *testing.T) {
+	tests := map[string]struct {
+		method          string
+		path            string
+		expectedRequest request
+	}{
+		"routed request": {
+			method:          http.MethodGet,
+			path:            "/api/user/1",
+			expectedRequest: request{method: http.MethodGet, route: "/api/user/{ID}", status: http.StatusOK},
+		},
+		"server error": {
+			method:          http.MethodPost,
+			path:            "/api/user",
+			expectedRequest: request{method: http.MethodPost, route: "/api/user", status: http.StatusInternalServerError},
+		},
+		"no route": {
+			method:          http.MethodGet,
+			path:            "/api/unknown/1",
+			expectedRequest: request{method: http.MethodGet, route: UnmatchedRoute, status: http.StatusNotFound},
+		},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			recorder := &recorderMock{}
+
+			r := chi.NewRouter()
+			r.Use(Middleware(recorder, chiRoute))
+			r.Get("/api/user/{ID}", func(w http.ResponseWriter, r *http.Request) {
+				assert.Equal(t, 1, recorder.inFlight, "request should be in flight while served")
+				w.WriteHeader(http.StatusOK)
+			})
+			r.Post("/api/user", func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(http.StatusInternalServerError)
+			})
+
+			w := httptest.NewRecorder()
+			r.ServeHTTP(w, httptest.NewRequest(tc.method, tc.path, nil))
+
+			assert.Equal(t, 0, recorder.inFlight)
+			assert.Equal(t, []request{tc.expectedRequest}, recorder.requests)
+		})
+	}
+}
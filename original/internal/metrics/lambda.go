@@ -0,0 +1,36 @@
+package metrics
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	"github.com/aws/aws-lambda-go/events"
+)
+
+// WrapAPIGateway returns a handler that records every invocation of handler with recorder, by the
+// method and resource of the event, such as `/api/user/{ID}`. Invocations that fail are recorded
+// with a 500 status, as API Gateway responds to them with an error.
+func WrapAPIGateway(
+	recorder Recorder,
+	handler func(ctx context.Context, req events.APIGatewayProxyRequest) (events.APIGatewayProxyResponse, error),
+) func(ctx context.Context, req events.APIGatewayProxyRequest) (events.APIGatewayProxyResponse, error) {
+	return func(ctx context.Context, req events.APIGatewayProxyRequest) (events.APIGatewayProxyResponse, error) {
+		start := time.Now()
+		recorder.RequestStarted()
+
+		resp, err := handler(ctx, req)
+
+		route := req.Resource
+		if route == "" {
+			route = UnmatchedRoute
+		}
+		status := resp.StatusCode
+		if err != nil {
+			status = http.StatusInternalServerError
+		}
+		recorder.RequestFinished(req.HTTPMethod, route, status, time.Since(start))
+
+		return resp, err
+	}
+}
@@ -0,0 +1,56 @@
+package metrics
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWrapAPIGateway(t *testing.T) {
+	tests := map[string]struct {
+		resource        string
+		statusCode      int
+		err             error
+		expectedRequest request
+	}{
+		"success": {
+			resource:        "/api/user/{ID}",
+			statusCode:      http.StatusOK,
+			expectedRequest: request{method: http.MethodGet, route: "/api/user/{ID}", status: http.StatusOK},
+		},
+		"handler error": {
+			resource:        "/api/user/{ID}",
+			err:             errors.New("test"),
+			expectedRequest: request{method: http.MethodGet, route: "/api/user/{ID}", status: http.StatusInternalServerError},
+		},
+		"no resource": {
+			statusCode:      http.StatusNotFound,
+			expectedRequest: request{method: http.MethodGet, route: UnmatchedRoute, status: http.StatusNotFound},
+		},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			recorder := &recorderMock{}
+
+			handler := WrapAPIGateway(recorder, func(context.Context, events.APIGatewayProxyRequest) (events.APIGatewayProxyResponse, error) {
+				assert.Equal(t, 1, recorder.inFlight, "invocation should be in flight while served")
+				return events.APIGatewayProxyResponse{StatusCode: tc.statusCode}, tc.err
+			})
+
+			_, err := handler(context.Background(), events.APIGatewayProxyRequest{
+				HTTPMethod: http.MethodGet,
+				Resource:   tc.resource,
+				Path:       "/api/user/1",
+			})
+			assert.Equal(t, tc.err, err)
+
+			assert.Equal(t, 0, recorder.inFlight)
+			assert.Equal(t, []request{tc.expectedRequest}, recorder.requests)
+		})
+	}
+}
@@ -0,0 +1,58 @@
+package metrics
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPrometheus(t *testing.T) {
+	db, _, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+
+	p := NewPrometheus(db, "users")
+
+	p.RequestStarted()
+	p.RequestFinished("GET", "/api/user/{ID}", http.StatusOK, 20*time.Millisecond)
+	p.RequestStarted()
+	p.RequestFinished("GET", "/api/user/{ID}", http.StatusInternalServerError, 10*time.Millisecond)
+	p.RequestStarted()
+	p.UsersCreated(2)
+	p.UsersUpdated(1)
+	p.UsersDeleted(3)
+
+	assert.Equal(t, 1.0, testutil.ToFloat64(p.requests.WithLabelValues("GET", "/api/user/{ID}", "200")))
+	assert.Equal(t, 1.0, testutil.ToFloat64(p.requests.WithLabelValues("GET", "/api/user/{ID}", "500")))
+	assert.Equal(t, 0.0, testutil.ToFloat64(p.errors.WithLabelValues("GET", "/api/user/{ID}", "200")))
+	assert.Equal(t, 1.0, testutil.ToFloat64(p.errors.WithLabelValues("GET", "/api/user/{ID}", "500")))
+	assert.Equal(t, 1.0, testutil.ToFloat64(p.inFlight))
+	assert.Equal(t, 2.0, testutil.ToFloat64(p.created))
+	assert.Equal(t, 1.0, testutil.ToFloat64(p.updated))
+	assert.Equal(t, 3.0, testutil.ToFloat64(p.deleted))
+
+	w := httptest.NewRecorder()
+	p.Handler().ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	body, _ := io.ReadAll(w.Body)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	for _, series := range []string{
+		`http_request_duration_seconds_count{method="GET",route="/api/user/{ID}"} 2`,
+		`go_sql_open_connections{db_name="users"}`,
+		`go_sql_in_use_connections{db_name="users"}`,
+		`go_sql_idle_connections{db_name="users"}`,
+		`go_sql_wait_count_total{db_name="users"}`,
+		`go_sql_wait_duration_seconds_total{db_name="users"}`,
+	} {
+		assert.True(t, strings.Contains(string(body), series), "metrics should contain %s", series)
+	}
+}
@@ -0,0 +1,150 @@
+package policy
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParse(t *testing.T) {
+	tests := map[string]struct {
+		data           string
+		expectedPolicy Policy
+		expectedError  bool
+	}{
+		"new role": {
+			data: `{"Auditor": {"actions": {"list": "all", "read": "all"}}}`,
+			expectedPolicy: Policy{
+				"Auditor": {Actions: map[Action]Scope{ActionList: ScopeAll, ActionRead: ScopeAll}},
+			},
+		},
+		"read-only fields": {
+			data: `{"Customer": {"actions": {"update": "own"}, "read_only_fields": ["role"]}}`,
+			expectedPolicy: Policy{
+				"Customer": {Actions: map[Action]Scope{ActionUpdate: ScopeOwn}, ReadOnlyFields: []string{"role"}},
+			},
+		},
+		"unknown action": {
+			data:          `{"Customer": {"actions": {"purge": "all"}}}`,
+			expectedError: true,
+		},
+		"unknown scope": {
+			data:          `{"Customer": {"actions": {"read": "mine"}}}`,
+			expectedError: true,
+		},
+		"list limited to own record": {
+			data:          `{"Customer": {"actions": {"list": "own"}}}`,
+			expectedError: true,
+		},
+		"unknown rule field": {
+			data:          `{"Customer": {"actions": {"read": "own"}, "fields": ["role"]}}`,
+			expectedError: true,
+		},
+		"malformed": {
+			data:          `{"Customer": `,
+			expectedError: true,
+		},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			policy, err := Parse([]byte(tc.data))
+
+			if tc.expectedError {
+				assert.Error(t, err)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tc.expectedPolicy, policy, "Wrong policy")
+		})
+	}
+}
+
+func TestNew(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "policy.json")
+	err := os.WriteFile(path, []byte(`{"Auditor": {"actions": {"read": "all"}}}`), 0o600)
+	assert.NoError(t, err)
+
+	policy, err := New("")
+	assert.NoError(t, err)
+	assert.Equal(t, Default(), policy, "Empty path is not the default policy")
+
+	policy, err = New(path)
+	assert.NoError(t, err)
+	assert.Equal(t, Policy{"Auditor": {Actions: map[Action]Scope{ActionRead: ScopeAll}}}, policy, "Wrong policy from file")
+
+	_, err = New(filepath.Join(t.TempDir(), "missing.json"))
+	assert.Error(t, err)
+}
+
+func TestPrincipalAuthorize(t *testing.T) {
+	rules := Default()
+
+	tests := map[string]struct {
+		principal     Principal
+		action        Action
+		owner         uint
+		expectedError *ForbiddenError
+	}{
+		"employee deletes any user": {
+			principal: rules.Principal(2001, "Employee"),
+			action:    ActionDelete,
+			owner:     1001,
+		},
+		"customer reads own user": {
+			principal: rules.Principal(1001, "Customer"),
+			action:    ActionRead,
+			owner:     1001,
+		},
+		"customer updates other user": {
+			principal:     rules.Principal(1001, "Customer"),
+			action:        ActionUpdate,
+			owner:         1002,
+			expectedError: &ForbiddenError{Role: "Customer", Action: ActionUpdate, Owner: true},
+		},
+		"customer restores own user": {
+			principal:     rules.Principal(1001, "Customer"),
+			action:        ActionRestore,
+			owner:         1001,
+			expectedError: &ForbiddenError{Role: "Customer", Action: ActionRestore},
+		},
+		"unknown role": {
+			principal:     rules.Principal(3001, "Contractor"),
+			action:        ActionRead,
+			owner:         3001,
+			expectedError: &ForbiddenError{Role: "Contractor", Action: ActionRead},
+		},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			err := tc.principal.Authorize(tc.action, tc.owner)
+
+			if tc.expectedError == nil {
+				assert.NoError(t, err)
+				return
+			}
+			assert.Equal(t, tc.expectedError, err, "Wrong error")
+			assert.True(t, errors.Is(err, ErrForbidden), "Error does not wrap ErrForbidden")
+		})
+	}
+}
+
+func TestPrincipalAuthorizeChanges(t *testing.T) {
+	rules := Default()
+	customer := rules.Principal(1001, "Customer")
+	employee := rules.Principal(2001, "Employee")
+
+	assert.NoError(t, customer.AuthorizeChanges([]string{"first_name", "last_name"}))
+	assert.Equal(
+		t,
+		&ForbiddenError{Role: "Customer", Action: ActionUpdate, Field: "user_id"},
+		customer.AuthorizeChanges([]string{"last_name", "user_id"}),
+	)
+	assert.NoError(t, employee.AuthorizeChanges([]string{"role", "user_id"}))
+	assert.True(t, customer.HasReadOnlyFields())
+	assert.False(t, employee.HasReadOnlyFields())
+}
@@ -0,0 +1,87 @@
+package problem
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/go-chi/chi/v5/middleware"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWrite(t *testing.T) {
+	tests := map[string]struct {
+		problem  Problem
+		header   string
+		useReqID bool
+		expected Problem
+	}{
+		"defaults title, instance and request ID from header": {
+			problem: New(http.StatusNotFound, "Object does not exist"),
+			header:  "header-id",
+			expected: Problem{
+				Type:      "about:blank",
+				Title:     "Not Found",
+				Status:    http.StatusNotFound,
+				Detail:    "Object does not exist",
+				Instance:  "/api/user/1",
+				RequestID: "header-id",
+			},
+		},
+		"request ID from middleware is preferred over header": {
+			problem:  New(http.StatusInternalServerError, ""),
+			header:   "header-id",
+			useReqID: true,
+			expected: Problem{
+				Type:      "about:blank",
+				Title:     "Internal Server Error",
+				Status:    http.StatusInternalServerError,
+				Instance:  "/api/user/1",
+				RequestID: "middleware-id",
+			},
+		},
+		"field errors are sorted and instance is kept": {
+			problem: New(http.StatusBadRequest, "invalid").
+				WithInstance("/custom").
+				WithFieldErrors(map[string]string{"role": "bad role", "first_name": "blank"}),
+			expected: Problem{
+				Type:     "about:blank",
+				Title:    "Bad Request",
+				Status:   http.StatusBadRequest,
+				Detail:   "invalid",
+				Instance: "/custom",
+				Errors: []FieldError{
+					{Field: "first_name", Detail: "blank"},
+					{Field: "role", Detail: "bad role"},
+				},
+			},
+		},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/api/user/1", nil)
+			if tc.header != "" {
+				req.Header.Set(RequestIDHeader, tc.header)
+			}
+			if tc.useReqID {
+				ctx := context.WithValue(req.Context(), middleware.RequestIDKey, "middleware-id")
+				req = req.WithContext(ctx)
+			}
+
+			rr := httptest.NewRecorder()
+			err := Write(rr, req, tc.problem)
+			assert.NoError(t, err)
+
+			var actual Problem
+			err = json.Unmarshal(rr.Body.Bytes(), &actual)
+			assert.NoError(t, err)
+
+			assert.Equal(t, tc.expected.Status, rr.Code, "Wrong code received")
+			assert.Equal(t, ContentType, rr.Header().Get("Content-Type"), "Wrong content type")
+			assert.Equal(t, tc.expected, actual, "Wrong problem")
+		})
+	}
+}
@@ -0,0 +1,74 @@
+package tracing
+
+import (
+	"net/http"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/chi/v5/middleware"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// RouteFunc returns the route pattern that a request was routed to, such as `/api/user/{ID}`, or
+// an empty string if it matched no route.
+type RouteFunc func(r *http.Request) string
+
+// ChiRoute is a RouteFunc for requests routed by chi.
+func ChiRoute(r *http.Request) string {
+	if rctx := chi.RouteContext(r.Context()); rctx != nil {
+		return rctx.RoutePattern()
+	}
+	return ""
+}
+
+// Middleware returns a middleware that serves every request in a span of provider. The span
+// continues the trace of the W3C traceparent header of the request, unless the request context
+// already has a span, such as that of a Lambda invocation. Once the request is served, the span is
+// named after the method and the route pattern returned by route, and marked as failed if the
+// response status is 5xx.
+//
+// chi only knows the route pattern once it has routed a request, so with ChiRoute the middleware
+// has to be used on the router rather than wrapped around it.
+func Middleware(provider trace.TracerProvider, route RouteFunc) func(http.Handler) http.Handler {
+	tracer := provider.Tracer(instrumentationName)
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			ctx := r.Context()
+			kind := trace.SpanKindInternal
+			if !trace.SpanContextFromContext(ctx).IsValid() {
+				ctx = propagator.Extract(ctx, propagation.HeaderCarrier(r.Header))
+				kind = trace.SpanKindServer
+			}
+
+			ctx, span := tracer.Start(
+				ctx,
+				r.Method,
+				trace.WithSpanKind(kind),
+				trace.WithAttributes(
+					semconv.HTTPRequestMethodKey.String(r.Method),
+					semconv.URLPath(r.URL.Path),
+				),
+			)
+			defer span.End()
+
+			ww := middleware.NewWrapResponseWriter(w, r.ProtoMajor)
+			next.ServeHTTP(ww, r.WithContext(ctx))
+
+			if pattern := route(r); pattern != "" {
+				span.SetName(r.Method + " " + pattern)
+				span.SetAttributes(semconv.HTTPRoute(pattern))
+			}
+			status := ww.Status()
+			if status == 0 {
+				status = http.StatusOK
+			}
+			span.SetAttributes(semconv.HTTPResponseStatusCode(status))
+			if status >= http.StatusInternalServerError {
+				span.SetStatus(codes.Error, http.StatusText(status))
+			}
+		})
+	}
+}
@@ -0,0 +1,158 @@
+package tracing
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+	"go.opentelemetry.io/otel/codes"
+)
+
+// dsnConnector is a driver.Connector that opens dsn with driver.
+type dsnConnector struct {
+	dsn    string
+	driver driver.Driver
+}
+
+func (c dsnConnector) Connect(_ context.Context) (driver.Conn, error) {
+	return c.driver.Open(c.dsn)
+}
+
+func (c dsnConnector) Driver() driver.Driver {
+	return c.driver
+}
+
+func TestWrapConnector(t *testing.T) {
+	tests := map[string]struct {
+		mockCalled        func(mock sqlmock.Sqlmock)
+		run               func(ctx context.Context, db *sql.DB) error
+		expectedName      string
+		expectedStatement string
+		expectedRows      int64
+		expectedCode      codes.Code
+	}{
+		"query": {
+			mockCalled: func(mock sqlmock.Sqlmock) {
+				mock.ExpectQuery(`SELECT id FROM "users"`).
+					WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(1).AddRow(2))
+			},
+			run: func(ctx context.Context, db *sql.DB) error {
+				rows, err := db.QueryContext(ctx, `SELECT id FROM "users"`)
+				if err != nil {
+					return err
+				}
+				defer rows.Close()
+				for rows.Next() {
+				}
+				return rows.Err()
+			},
+			expectedName:      "SELECT",
+			expectedStatement: `SELECT id FROM "users"`,
+			expectedRows:      2,
+			expectedCode:      codes.Unset,
+		},
+		"exec": {
+			mockCalled: func(mock sqlmock.Sqlmock) {
+				mock.ExpectExec(`DELETE FROM "users"`).
+					WithArgs(1).
+					WillReturnResult(sqlmock.NewResult(0, 1))
+			},
+			run: func(ctx context.Context, db *sql.DB) error {
+				_, err := db.ExecContext(ctx, `DELETE FROM "users" WHERE id = $1`, 1)
+				return err
+			},
+			expectedName:      "DELETE",
+			expectedStatement: `DELETE FROM "users" WHERE id = $1`,
+			expectedRows:      1,
+			expectedCode:      codes.Unset,
+		},
+		"prepared statement": {
+			mockCalled: func(mock sqlmock.Sqlmock) {
+				mock.ExpectPrepare(`UPDATE "users"`).
+					ExpectExec().
+					WithArgs("Ann", 1).
+					WillReturnResult(sqlmock.NewResult(0, 1))
+			},
+			run: func(ctx context.Context, db *sql.DB) error {
+				stmt, err := db.PrepareContext(ctx, `UPDATE "users" SET first_name = $1 WHERE id = $2`)
+				if err != nil {
+					return err
+				}
+				defer stmt.Close()
+				_, err = stmt.ExecContext(ctx, "Ann", 1)
+				return err
+			},
+			expectedName:      "UPDATE",
+			expectedStatement: `UPDATE "users" SET first_name = $1 WHERE id = $2`,
+			expectedRows:      1,
+			expectedCode:      codes.Unset,
+		},
+		"error": {
+			mockCalled: func(mock sqlmock.Sqlmock) {
+				mock.ExpectQuery(`SELECT id FROM "users"`).
+					WillReturnError(errors.New("test"))
+			},
+			run: func(ctx context.Context, db *sql.DB) error {
+				_, err := db.QueryContext(ctx, `SELECT id FROM "users"`)
+				return err
+			},
+			expectedName:      "SELECT",
+			expectedStatement: `SELECT id FROM "users"`,
+			expectedCode:      codes.Error,
+		},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			mockDB, mock, err := sqlmock.NewWithDSN("sqlmock_tracing_" + name)
+			assert.NoError(t, err)
+			defer mockDB.Close()
+
+			provider, exporter := newTestProvider()
+			db := sql.OpenDB(WrapConnector(dsnConnector{dsn: "sqlmock_tracing_" + name, driver: mockDB.Driver()}, provider))
+			defer db.Close()
+
+			tc.mockCalled(mock)
+
+			ctx, parent := provider.Tracer("test").Start(context.Background(), "parent")
+			_ = tc.run(ctx, db)
+			parent.End()
+
+			spans := exporter.GetSpans()
+			if !assert.Len(t, spans, 2, "Wrong number of spans") {
+				return
+			}
+			span := spans[0]
+			attrs := attributes(span)
+
+			assert.Equal(t, tc.expectedName, span.Name, "Wrong span name")
+			assert.Equal(t, parent.SpanContext().SpanID(), span.Parent.SpanID(), "Wrong parent")
+			assert.Equal(t, tc.expectedCode, span.Status.Code, "Wrong span status")
+			assert.Equal(t, "postgresql", attrs["db.system"].AsString(), "Wrong db.system attribute")
+			assert.Equal(t, tc.expectedStatement, attrs["db.query.text"].AsString(), "Wrong statement attribute")
+			assert.Equal(t, tc.expectedRows, attrs[returnedRowsKey].AsInt64(), "Wrong row count")
+			assert.NoError(t, mock.ExpectationsWereMet(), "Expectations not met")
+		})
+	}
+}
+
+func TestWrapConnectorWithoutParent(t *testing.T) {
+	mockDB, mock, err := sqlmock.NewWithDSN("sqlmock_tracing_without_parent")
+	assert.NoError(t, err)
+	defer mockDB.Close()
+
+	provider, exporter := newTestProvider()
+	db := sql.OpenDB(WrapConnector(dsnConnector{dsn: "sqlmock_tracing_without_parent", driver: mockDB.Driver()}, provider))
+	defer db.Close()
+
+	mock.ExpectExec(`DELETE FROM "users"`).WillReturnResult(sqlmock.NewResult(0, 1))
+
+	_, err = db.ExecContext(context.Background(), `DELETE FROM "users"`)
+	assert.NoError(t, err)
+	assert.Empty(t, exporter.GetSpans(), "Span started without parent")
+	assert.NoError(t, mock.ExpectationsWereMet(), "Expectations not met")
+}
@@ -1,70 +1,169 @@
 package user
 
 import (
+	"context"
+	"errors"
 	"fmt"
 
+	"gorm.io/gorm"
+
+	"user-microservice/internal/database"
 	"user-microservice/internal/database/entity"
 )
 
+var (
+	// ErrUserNotFound is returned when no user exists with the requested ID.
+	ErrUserNotFound = errors.New("user not found")
+
+	// ErrUserIDConflict is returned when a user is created or updated with a user_id that already
+	// belongs to another user.
+	ErrUserIDConflict = errors.New("user_id already in use")
+
+	// ErrUserNotDeleted is returned when a user that is not soft deleted is restored.
+	ErrUserNotDeleted = errors.New("user not deleted")
+)
+
 type databaseSession interface {
-	ListUsers() ([]entity.User, error)
-	FetchUser(int) (entity.User, error)
-	UpdateUser(int, entity.User) (entity.User, error)
-	CreateUser(entity.User) (entity.User, error)
-	DeleteUser(int) error
+	ListUsers(context.Context, bool) ([]entity.User, error)
+	FetchUser(context.Context, int, bool) (entity.User, error)
+	FetchUserByUserID(context.Context, uint) (entity.User, error)
+	UpdateUser(context.Context, int, entity.User) (entity.User, error)
+	CreateUser(context.Context, entity.User) (entity.User, error)
+	DeleteUser(context.Context, int) error
+	RestoreUser(context.Context, int) (entity.User, error)
+	PurgeUser(context.Context, int) error
+}
+
+// changeCounter counts the users that a Service changes, such as metrics.Recorder.
+type changeCounter interface {
+	UsersCreated(count int)
+	UsersUpdated(count int)
+	UsersDeleted(count int)
 }
 
+// uncounted is the changeCounter of a Service that counts no changes.
+type uncounted struct{}
+
+func (uncounted) UsersCreated(int) {}
+func (uncounted) UsersUpdated(int) {}
+func (uncounted) UsersDeleted(int) {}
+
 type Service struct {
 	Database databaseSession
+	changes  changeCounter
+}
+
+type Option func(*Service)
+
+// WithChangeCounter counts every user that the Service creates, updates, restores, deletes or
+// purges with changes. Restores are counted as updates, and purges as deletes.
+func WithChangeCounter(changes changeCounter) Option {
+	return func(s *Service) {
+		s.changes = changes
+	}
 }
 
 // NewService returns a new instance of the Service struct.
-func NewService(db databaseSession) Service {
-	return Service{
+func NewService(db databaseSession, options ...Option) Service {
+	s := Service{
 		Database: db,
+		changes:  uncounted{},
+	}
+	for _, option := range options {
+		option(&s)
 	}
+	return s
 }
 
-// List returns a list of type []entity.User.
-func (s Service) List() ([]entity.User, error) {
-	users, err := s.Database.ListUsers()
+// List returns a list of type []entity.User. Soft deleted users are only included if
+// includeDeleted is set.
+func (s Service) List(ctx context.Context, includeDeleted bool) ([]entity.User, error) {
+	users, err := s.Database.ListUsers(ctx, includeDeleted)
 	if err != nil {
 		return []entity.User{}, fmt.Errorf("in user.List: %w", err)
 	}
 	return users, nil
 }
 
-// Fetch returns an object of type entity.User.
-func (s Service) Fetch(ID int) (entity.User, error) {
-	user, err := s.Database.FetchUser(ID)
+// Fetch returns an object of type entity.User. A soft deleted user is only returned if
+// includeDeleted is set.
+func (s Service) Fetch(ctx context.Context, ID int, includeDeleted bool) (entity.User, error) {
+	user, err := s.Database.FetchUser(ctx, ID, includeDeleted)
 	if err != nil {
-		return entity.User{}, fmt.Errorf("in user.Fetch: %w", err)
+		return entity.User{}, fmt.Errorf("in user.Fetch: %w", mapDatabaseError(err))
+	}
+	return user, nil
+}
+
+// FetchByUserID returns the entity.User with the user_id userID. Soft deleted users are not
+// returned.
+func (s Service) FetchByUserID(ctx context.Context, userID uint) (entity.User, error) {
+	user, err := s.Database.FetchUserByUserID(ctx, userID)
+	if err != nil {
+		return entity.User{}, fmt.Errorf("in user.FetchByUserID: %w", mapDatabaseError(err))
 	}
 	return user, nil
 }
 
 // Update updates a entity.User object bu ID.
-func (s Service) Update(ID int, user entity.User) (entity.User, error) {
-	user, err := s.Database.UpdateUser(ID, user)
+func (s Service) Update(ctx context.Context, ID int, user entity.User) (entity.User, error) {
+	user, err := s.Database.UpdateUser(ctx, ID, user)
 	if err != nil {
-		return entity.User{}, fmt.Errorf("in user.Update: %w", err)
+		return entity.User{}, fmt.Errorf("in user.Update: %w", mapDatabaseError(err))
 	}
+	s.changes.UsersUpdated(1)
 	return user, nil
 }
 
 // Create creates an entity.User object based on a entity.User passed in.
-func (s Service) Create(user entity.User) (int, error) {
-	user, err := s.Database.CreateUser(user)
+func (s Service) Create(ctx context.Context, user entity.User) (int, error) {
+	user, err := s.Database.CreateUser(ctx, user)
 	if err != nil {
-		return 0, fmt.Errorf("in user.Create: %w", err)
+		return 0, fmt.Errorf("in user.Create: %w", mapDatabaseError(err))
 	}
+	s.changes.UsersCreated(1)
 	return int(user.ID), nil
 }
 
-// Delete deletes a entity.User object by ID.
-func (s Service) Delete(ID int) error {
-	if err := s.Database.DeleteUser(ID); err != nil {
-		return fmt.Errorf("in user.Delete: %w", err)
+// Delete soft deletes a entity.User object by ID.
+func (s Service) Delete(ctx context.Context, ID int) error {
+	if err := s.Database.DeleteUser(ctx, ID); err != nil {
+		return fmt.Errorf("in user.Delete: %w", mapDatabaseError(err))
 	}
+	s.changes.UsersDeleted(1)
 	return nil
 }
+
+// Restore restores a soft deleted entity.User object by ID.
+func (s Service) Restore(ctx context.Context, ID int) (entity.User, error) {
+	user, err := s.Database.RestoreUser(ctx, ID)
+	if err != nil {
+		return entity.User{}, fmt.Errorf("in user.Restore: %w", mapDatabaseError(err))
+	}
+	s.changes.UsersUpdated(1)
+	return user, nil
+}
+
+// Purge permanently deletes a entity.User object by ID.
+func (s Service) Purge(ctx context.Context, ID int) error {
+	if err := s.Database.PurgeUser(ctx, ID); err != nil {
+		return fmt.Errorf("in user.Purge: %w", mapDatabaseError(err))
+	}
+	s.changes.UsersDeleted(1)
+	return nil
+}
+
+// mapDatabaseError converts gorm errors into the errors of this package. Other errors are returned
+// unchanged.
+func mapDatabaseError(err error) error {
+	switch {
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		return fmt.Errorf("%w: %w", ErrUserNotFound, err)
+	case errors.Is(err, gorm.ErrDuplicatedKey):
+		return fmt.Errorf("%w: %w", ErrUserIDConflict, err)
+	case errors.Is(err, database.ErrRecordNotDeleted):
+		return fmt.Errorf("%w: %w", ErrUserNotDeleted, err)
+	default:
+		return err
+	}
+}
@@ -1,13 +1,16 @@
 package user
 
 import (
+	"context"
 	"errors"
 	"fmt"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/suite"
+	"gorm.io/gorm"
 
+	"user-microservice/internal/database"
 	"user-microservice/internal/database/entity"
 	"user-microservice/internal/testutil"
 	"user-microservice/internal/user/mock"
@@ -18,9 +21,21 @@ import (
 type userSuit struct {
 	suite.Suite
 	databaseMock *mock.MockdatabaseSession
+	changes      *changeCounts
 	service      Service
 }
 
+// changeCounts is a changeCounter that keeps the number of users changed.
+type changeCounts struct {
+	created int
+	updated int
+	deleted int
+}
+
+func (c *changeCounts) UsersCreated(count int) { c.created += count }
+func (c *changeCounts) UsersUpdated(count int) { c.updated += count }
+func (c *changeCounts) UsersDeleted(count int) { c.deleted += count }
+
 func TestUserSuit(t *testing.T) {
 	suite.Run(t, new(userSuit))
 }
@@ -29,13 +44,15 @@ func (us *userSuit) SetupSuite() {
 	DBMock := new(mock.MockdatabaseSession)
 	us.databaseMock = DBMock
 
-	us.service = NewService(DBMock)
+	us.changes = &changeCounts{}
+	us.service = NewService(DBMock, WithChangeCounter(us.changes))
 }
 
 // ━━ TESTS ━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━
 
 func (us *userSuit) TestList() {
 	t := us.T()
+	ctx := context.Background()
 
 	users := []entity.User{
 		testutil.NewUser(),
@@ -57,14 +74,14 @@ func (us *userSuit) TestList() {
 	for name, tc := range testCases {
 		t.Run(name, func(t *testing.T) {
 			us.databaseMock.
-				On("ListUsers").
+				On("ListUsers", ctx, false).
 				Return(tc.mockReturnArgs...).
 				Once()
 
-			returnUsers, err := us.service.List()
+			returnUsers, err := us.service.List(ctx, false)
 			assert.NoError(t, err, "error listing returnUsers")
 
-			us.databaseMock.AssertCalled(t, "ListUsers")
+			us.databaseMock.AssertCalled(t, "ListUsers", ctx, false)
 			us.databaseMock.AssertExpectations(t)
 
 			assert.Equal(
@@ -73,13 +90,14 @@ func (us *userSuit) TestList() {
 				returnUsers,
 				"expected vs actual returnUsers did not match",
 			)
-			assert.Equal(t, tc.expectedReturn, err, "expected vs actual err did not match")
+			assert.Equal(t, tc.expectedError, err, "expected vs actual err did not match")
 		})
 	}
 }
 
 func (us *userSuit) TestFetch() {
 	t := us.T()
+	ctx := context.Background()
 
 	user := testutil.NewUser()
 
@@ -91,18 +109,21 @@ func (us *userSuit) TestFetch() {
 		expectedError  error
 	}{
 		"return a user": {
-			[]any{int(user.ID)},
+			[]any{ctx, int(user.ID), false},
 			[]any{user, nil},
 			int(user.ID),
 			user,
 			nil,
 		},
 		"fail to find user": {
-			[]any{int(user.ID) + 1},
-			[]any{entity.User{}, nil},
+			[]any{ctx, int(user.ID) + 1, false},
+			[]any{entity.User{}, gorm.ErrRecordNotFound},
 			int(user.ID) + 1,
 			entity.User{},
-			nil,
+			fmt.Errorf(
+				"in user.Fetch: %w",
+				fmt.Errorf("%w: %w", ErrUserNotFound, gorm.ErrRecordNotFound),
+			),
 		},
 	}
 	for testName, tc := range testCases {
@@ -112,7 +133,7 @@ func (us *userSuit) TestFetch() {
 				Return(tc.mockReturnArgs...).
 				Once()
 
-			returnedUser, err := us.service.Fetch(tc.fetchID)
+			returnedUser, err := us.service.Fetch(ctx, tc.fetchID, false)
 
 			us.databaseMock.AssertCalled(t, "FetchUser", tc.mockInputArgs...)
 			us.databaseMock.AssertExpectations(t)
@@ -128,8 +149,63 @@ func (us *userSuit) TestFetch() {
 	}
 }
 
+func (us *userSuit) TestFetchByUserID() {
+	t := us.T()
+	ctx := context.Background()
+
+	user := testutil.NewUser()
+
+	testCases := map[string]struct {
+		mockInputArgs  []any
+		mockReturnArgs []any
+		fetchUserID    uint
+		expectedReturn interface{}
+		expectedError  error
+	}{
+		"return a user": {
+			[]any{ctx, user.UserID},
+			[]any{user, nil},
+			user.UserID,
+			user,
+			nil,
+		},
+		"fail to find user": {
+			[]any{ctx, user.UserID + 1},
+			[]any{entity.User{}, gorm.ErrRecordNotFound},
+			user.UserID + 1,
+			entity.User{},
+			fmt.Errorf(
+				"in user.FetchByUserID: %w",
+				fmt.Errorf("%w: %w", ErrUserNotFound, gorm.ErrRecordNotFound),
+			),
+		},
+	}
+	for testName, tc := range testCases {
+		t.Run(testName, func(t *testing.T) {
+			us.databaseMock.
+				On("FetchUserByUserID", tc.mockInputArgs...).
+				Return(tc.mockReturnArgs...).
+				Once()
+
+			returnedUser, err := us.service.FetchByUserID(ctx, tc.fetchUserID)
+
+			us.databaseMock.AssertCalled(t, "FetchUserByUserID", tc.mockInputArgs...)
+			us.databaseMock.AssertExpectations(t)
+
+			assert.Equal(
+				t,
+				tc.expectedReturn,
+				returnedUser,
+				"expectedReturn vs actual returnedUser did not match",
+			)
+			assert.Equal(t, tc.expectedError, err, "expectedReturn vs actual err did not match")
+		})
+	}
+}
+
 func (us *userSuit) TestUpdate() {
 	t := us.T()
+	ctx := context.Background()
 
 	user := testutil.NewUser()
 
@@ -142,7 +218,7 @@ func (us *userSuit) TestUpdate() {
 		expectedError  error
 	}{
 		"update user": {
-			[]any{int(user.ID), user},
+			[]any{ctx, int(user.ID), user},
 			[]any{user, nil},
 			int(user.ID),
 			user,
@@ -150,13 +226,24 @@ func (us *userSuit) TestUpdate() {
 			nil,
 		},
 		"fail to update user - ID does not exist": {
-			[]any{int(user.ID) + 1, user},
+			[]any{ctx, int(user.ID) + 1, user},
 			[]any{entity.User{}, errors.New("test error")},
 			int(user.ID) + 1,
 			user,
 			entity.User{},
 			fmt.Errorf("in user.Update: %w", errors.New("test error")),
 		},
+		"fail to update user - user_id already in use": {
+			[]any{ctx, int(user.ID) + 2, user},
+			[]any{entity.User{}, gorm.ErrDuplicatedKey},
+			int(user.ID) + 2,
+			user,
+			entity.User{},
+			fmt.Errorf(
+				"in user.Update: %w",
+				fmt.Errorf("%w: %w", ErrUserIDConflict, gorm.ErrDuplicatedKey),
+			),
+		},
 	}
 	for testName, tc := range testCases {
 		t.Run(testName, func(t *testing.T) {
@@ -165,7 +252,7 @@ func (us *userSuit) TestUpdate() {
 				Return(tc.mockReturnArgs...).
 				Once()
 
-			returnedUser, err := us.service.Update(tc.userID, tc.user)
+			returnedUser, err := us.service.Update(ctx, tc.userID, tc.user)
 			assert.Equal(t, tc.expectedError, err, "expectedReturn vs actual error did not match")
 
 			us.databaseMock.AssertCalled(t, "UpdateUser", tc.mockInputArgs...)
@@ -183,22 +270,25 @@ func (us *userSuit) TestUpdate() {
 
 func (us *userSuit) TestCreate() {
 	t := us.T()
+	ctx := context.Background()
 
 	user := testutil.NewUser()
 
 	testCases := map[string]struct {
-		mockInputArgs  []any
-		mockReturnArgs []any
-		user           entity.User
-		expectedReturn interface{}
-		expectedError  error
+		mockInputArgs   []any
+		mockReturnArgs  []any
+		user            entity.User
+		expectedReturn  interface{}
+		expectedError   error
+		expectedChanges changeCounts
 	}{
 		"create user": {
-			[]any{user},
+			[]any{ctx, user},
 			[]any{user, nil},
 			user,
 			int(user.ID),
 			nil,
+			changeCounts{created: 1},
 		},
 	}
 	for testName, tc := range testCases {
@@ -207,9 +297,11 @@ func (us *userSuit) TestCreate() {
 				On("CreateUser", tc.mockInputArgs...).
 				Return(tc.mockReturnArgs...).
 				Once()
+			*us.changes = changeCounts{}
 
-			actualReturn, err := us.service.Create(tc.user)
+			actualReturn, err := us.service.Create(ctx, tc.user)
 			assert.Equal(t, tc.expectedError, err)
+			assert.Equal(t, tc.expectedChanges, *us.changes)
 
 			us.databaseMock.AssertCalled(t, "CreateUser", tc.mockInputArgs...)
 			us.databaseMock.AssertExpectations(t)
@@ -221,6 +313,56 @@ func (us *userSuit) TestCreate() {
 
 func (us *userSuit) TestDelete() {
 	t := us.T()
+	ctx := context.Background()
+
+	user := testutil.NewUser()
+
+	testCases := map[string]struct {
+		mockInputArgs   []any
+		mockReturnArgs  []any
+		input           int
+		expectedError   error
+		expectedChanges changeCounts
+	}{
+		"delete user": {
+			[]any{ctx, int(user.ID)},
+			[]any{nil},
+			int(user.ID),
+			nil,
+			changeCounts{deleted: 1},
+		},
+		"fail to delete user - ID does not exist": {
+			[]any{ctx, int(user.ID) + 1},
+			[]any{gorm.ErrRecordNotFound},
+			int(user.ID) + 1,
+			fmt.Errorf(
+				"in user.Delete: %w",
+				fmt.Errorf("%w: %w", ErrUserNotFound, gorm.ErrRecordNotFound),
+			),
+			changeCounts{},
+		},
+	}
+	for testName, tc := range testCases {
+		t.Run(testName, func(t *testing.T) {
+			us.databaseMock.
+				On("DeleteUser", tc.mockInputArgs...).
+				Return(tc.mockReturnArgs...).
+				Once()
+			*us.changes = changeCounts{}
+
+			err := us.service.Delete(ctx, tc.input)
+			assert.Equal(t, tc.expectedError, err)
+			assert.Equal(t, tc.expectedChanges, *us.changes)
+
+			us.databaseMock.AssertCalled(t, "DeleteUser", tc.mockInputArgs...)
+			us.databaseMock.AssertExpectations(t)
+		})
+	}
+}
+
+func (us *userSuit) TestRestore() {
+	t := us.T()
+	ctx := context.Background()
 
 	user := testutil.NewUser()
 
@@ -228,26 +370,94 @@ func (us *userSuit) TestDelete() {
 		mockInputArgs  []any
 		mockReturnArgs []any
 		input          int
+		expectedReturn entity.User
 		expectedError  error
 	}{
-		"create user": {
-			[]any{int(user.ID)},
+		"restore user": {
+			[]any{ctx, int(user.ID)},
+			[]any{user, nil},
+			int(user.ID),
+			user,
+			nil,
+		},
+		"fail to restore user - user is not deleted": {
+			[]any{ctx, int(user.ID) + 1},
+			[]any{entity.User{}, database.ErrRecordNotDeleted},
+			int(user.ID) + 1,
+			entity.User{},
+			fmt.Errorf(
+				"in user.Restore: %w",
+				fmt.Errorf("%w: %w", ErrUserNotDeleted, database.ErrRecordNotDeleted),
+			),
+		},
+		"fail to restore user - user_id already in use": {
+			[]any{ctx, int(user.ID) + 2},
+			[]any{entity.User{}, gorm.ErrDuplicatedKey},
+			int(user.ID) + 2,
+			entity.User{},
+			fmt.Errorf(
+				"in user.Restore: %w",
+				fmt.Errorf("%w: %w", ErrUserIDConflict, gorm.ErrDuplicatedKey),
+			),
+		},
+	}
+	for testName, tc := range testCases {
+		t.Run(testName, func(t *testing.T) {
+			us.databaseMock.
+				On("RestoreUser", tc.mockInputArgs...).
+				Return(tc.mockReturnArgs...).
+				Once()
+
+			returnedUser, err := us.service.Restore(ctx, tc.input)
+			assert.Equal(t, tc.expectedError, err)
+
+			us.databaseMock.AssertCalled(t, "RestoreUser", tc.mockInputArgs...)
+			us.databaseMock.AssertExpectations(t)
+
+			assert.Equal(t, tc.expectedReturn, returnedUser)
+		})
+	}
+}
+
+func (us *userSuit) TestPurge() {
+	t := us.T()
+	ctx := context.Background()
+
+	user := testutil.NewUser()
+
+	testCases := map[string]struct {
+		mockInputArgs  []any
+		mockReturnArgs []any
+		input          int
+		expectedError  error
+	}{
+		"purge user": {
+			[]any{ctx, int(user.ID)},
 			[]any{nil},
 			int(user.ID),
 			nil,
 		},
+		"fail to purge user - ID does not exist": {
+			[]any{ctx, int(user.ID) + 1},
+			[]any{gorm.ErrRecordNotFound},
+			int(user.ID) + 1,
+			fmt.Errorf(
+				"in user.Purge: %w",
+				fmt.Errorf("%w: %w", ErrUserNotFound, gorm.ErrRecordNotFound),
+			),
+		},
 	}
 	for testName, tc := range testCases {
 		t.Run(testName, func(t *testing.T) {
 			us.databaseMock.
-				On("DeleteUser", tc.mockInputArgs...).
+				On("PurgeUser", tc.mockInputArgs...).
 				Return(tc.mockReturnArgs...).
 				Once()
 
-			err := us.service.Delete(tc.input)
+			err := us.service.Purge(ctx, tc.input)
 			assert.Equal(t, tc.expectedError, err)
 
-			us.databaseMock.AssertCalled(t, "DeleteUser", tc.mockInputArgs...)
+			us.databaseMock.AssertCalled(t, "PurgeUser", tc.mockInputArgs...)
 			us.databaseMock.AssertExpectations(t)
 		})
 	}